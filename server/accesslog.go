@@ -0,0 +1,202 @@
+// Copyright 2026 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// AccessLogOpts configures logging of publish and subscribe attempts on selected subjects.
+type AccessLogOpts struct {
+	// Subjects are the subject patterns for which publish and subscribe attempts are logged.
+	Subjects []string `json:"subjects,omitempty"`
+	// File is where entries are appended to as JSON lines. If empty, entries go to the server log.
+	File string `json:"file,omitempty"`
+	// MaxRate is the maximum number of entries written per second, DefaultAccessLogMaxRate if not set.
+	// Entries above this rate are dropped, the number dropped is reported with the next entry written.
+	MaxRate int `json:"max_rate,omitempty"`
+}
+
+const (
+	// DefaultAccessLogMaxRate is the default maximum number of access log entries written per second.
+	DefaultAccessLogMaxRate = 1000
+
+	accessLogPub = "pub"
+	accessLogSub = "sub"
+)
+
+// AccessLogEntry is a structured entry for a publish or subscribe attempt on an access logged subject.
+type AccessLogEntry struct {
+	Time    time.Time `json:"time"`
+	Op      string    `json:"op"`
+	Subject string    `json:"subject"`
+	Queue   string    `json:"queue,omitempty"`
+	Allowed bool      `json:"allowed"`
+	Account string    `json:"account,omitempty"`
+	User    string    `json:"user,omitempty"`
+	Client  uint64    `json:"cid"`
+	Host    string    `json:"host,omitempty"`
+	// Dropped is the number of entries dropped due to the rate limit since the previous entry.
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// accessLog filters, rate limits and queues access log entries to be written.
+type accessLog struct {
+	subjects []string
+	maxRate  int
+	mu       sync.Mutex
+	window   int64  // The second for which entries are currently counted.
+	count    int    // The number of entries queued in the current window.
+	dropped  uint64 // The number of entries dropped since the last entry queued.
+	q        *ipQueue[*AccessLogEntry]
+	quit     chan struct{} // Closed to stop writing this access log, e.g. on reload.
+}
+
+// matches returns whether attempts on this subject should be logged.
+func (al *accessLog) matches(subject string) bool {
+	for _, filter := range al.subjects {
+		if SubjectsCollide(subject, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// record queues the entry to be written, unless we are above our rate limit.
+func (al *accessLog) record(e *AccessLogEntry) {
+	now := time.Now()
+	al.mu.Lock()
+	if sec := now.Unix(); sec != al.window {
+		al.window, al.count = sec, 0
+	}
+	if al.count >= al.maxRate {
+		al.dropped++
+		al.mu.Unlock()
+		return
+	}
+	al.count++
+	e.Time = now.UTC()
+	e.Dropped, al.dropped = al.dropped, 0
+	al.mu.Unlock()
+	al.q.push(e)
+}
+
+// startAccessLog will start writing access log entries if subjects are configured.
+func (s *Server) startAccessLog() error {
+	opts := s.getOpts()
+	if len(opts.AccessLog.Subjects) == 0 {
+		return nil
+	}
+	var f *os.File
+	if opts.AccessLog.File != _EMPTY_ {
+		var err error
+		if f, err = os.OpenFile(opts.AccessLog.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, defaultFilePerms); err != nil {
+			return err
+		}
+	}
+	al := &accessLog{
+		subjects: opts.AccessLog.Subjects,
+		maxRate:  opts.AccessLog.MaxRate,
+		q:        newIPQueue[*AccessLogEntry](s, "access log"),
+		quit:     make(chan struct{}),
+	}
+	if al.maxRate <= 0 {
+		al.maxRate = DefaultAccessLogMaxRate
+	}
+	s.accessLog.Store(al)
+
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+		defer al.q.unregister()
+
+		var w *bufio.Writer
+		if f != nil {
+			w = bufio.NewWriter(f)
+			defer f.Close()
+			defer w.Flush()
+		}
+		write := func() {
+			entries := al.q.pop()
+			for _, e := range entries {
+				b, err := json.Marshal(e)
+				if err != nil {
+					continue
+				}
+				if w != nil {
+					w.Write(b)
+					w.WriteByte('\n')
+				} else {
+					s.Noticef("[ACCESS] %s", b)
+				}
+			}
+			al.q.recycle(&entries)
+			if w != nil {
+				if err := w.Flush(); err != nil {
+					s.RateLimitWarnf("Error writing access log: %v", err)
+				}
+			}
+		}
+		for {
+			select {
+			case <-s.quitCh:
+				return
+			case <-al.quit:
+				// Write what was queued before we were replaced.
+				write()
+				return
+			case <-al.q.ch:
+				write()
+			}
+		}
+	})
+	return nil
+}
+
+// reloadAccessLog stops writing the current access log, if any,
+// and starts again with the current options.
+func (s *Server) reloadAccessLog() error {
+	if al := s.accessLog.Swap(nil); al != nil {
+		close(al.quit)
+	}
+	return s.startAccessLog()
+}
+
+// logAccess records a publish or subscribe attempt of a client if the subject is access logged.
+// This is called from the client's read loop, so it is safe to access the connect options.
+func (c *client) logAccess(op string, subject, queue []byte, allowed bool) {
+	if c.kind != CLIENT || c.srv == nil {
+		return
+	}
+	al := c.srv.accessLog.Load()
+	if al == nil || !al.matches(bytesToString(subject)) {
+		return
+	}
+	e := &AccessLogEntry{
+		Op:      op,
+		Subject: string(subject),
+		Queue:   string(queue),
+		Allowed: allowed,
+		User:    c.getAuthUserLabel(),
+		Client:  c.cid,
+		Host:    c.host,
+	}
+	if c.acc != nil {
+		e.Account = c.acc.Name
+	}
+	al.record(e)
+}
@@ -0,0 +1,251 @@
+// Copyright 2026 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func readAccessLog(t *testing.T, fn string) []*AccessLogEntry {
+	t.Helper()
+	f, err := os.Open(fn)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require_NoError(t, err)
+	defer f.Close()
+	var entries []*AccessLogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AccessLogEntry
+		require_NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		entries = append(entries, &e)
+	}
+	require_NoError(t, scanner.Err())
+	return entries
+}
+
+func TestAccessLogSubjects(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "access.log")
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		accounts {
+			A {
+				users = [{
+					user: u, password: p
+					permissions {
+						publish { allow: ["secure.allowed", "other"] }
+						subscribe { allow: ["secure.allowed", "other"] }
+					}
+				}]
+			}
+		}
+		access_log {
+			subjects: ["secure.>"]
+			file: %q
+		}
+	`, fn)))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	errCh := make(chan error, 10)
+	nc, err := nats.Connect(s.ClientURL(), nats.UserInfo("u", "p"),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) { errCh <- err }))
+	require_NoError(t, err)
+	defer nc.Close()
+
+	_, err = nc.QueueSubscribeSync("secure.allowed", "q")
+	require_NoError(t, err)
+	_, err = nc.SubscribeSync("secure.denied")
+	require_NoError(t, err)
+	require_NoError(t, nc.Publish("secure.allowed", nil))
+	require_NoError(t, nc.Publish("secure.denied", nil))
+	// Attempts on subjects not configured are not logged, whether allowed or not.
+	_, err = nc.SubscribeSync("other")
+	require_NoError(t, err)
+	require_NoError(t, nc.Publish("other", nil))
+	require_NoError(t, nc.Publish("denied", nil))
+	require_NoError(t, nc.Flush())
+
+	var entries []*AccessLogEntry
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if entries = readAccessLog(t, fn); len(entries) != 4 {
+			return fmt.Errorf("expected 4 entries, got %d", len(entries))
+		}
+		return nil
+	})
+
+	type expected struct {
+		op      string
+		subject string
+		queue   string
+		allowed bool
+	}
+	for i, exp := range []expected{
+		{accessLogSub, "secure.allowed", "q", true},
+		{accessLogSub, "secure.denied", _EMPTY_, false},
+		{accessLogPub, "secure.allowed", _EMPTY_, true},
+		{accessLogPub, "secure.denied", _EMPTY_, false},
+	} {
+		e := entries[i]
+		require_Equal(t, e.Op, exp.op)
+		require_Equal(t, e.Subject, exp.subject)
+		require_Equal(t, e.Queue, exp.queue)
+		require_Equal(t, e.Allowed, exp.allowed)
+		require_Equal(t, e.Account, "A")
+		require_Equal(t, e.User, "user:u")
+		require_True(t, e.Client > 0)
+		require_True(t, !e.Time.IsZero())
+		require_Equal(t, e.Dropped, 0)
+	}
+}
+
+func TestAccessLogMaxRate(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "access.log")
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		access_log {
+			subjects: "secure.>"
+			file: %q
+			max_rate: 5
+		}
+	`, fn)))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	nc := natsConnect(t, s.ClientURL())
+	defer nc.Close()
+
+	for i := 0; i < 100; i++ {
+		require_NoError(t, nc.Publish("secure.foo", nil))
+	}
+	require_NoError(t, nc.Flush())
+
+	// Wait for the next window so the dropped entries are reported.
+	al := s.accessLog.Load()
+	require_NotNil(t, al)
+	checkFor(t, 3*time.Second, 50*time.Millisecond, func() error {
+		require_NoError(t, nc.Publish("secure.foo", nil))
+		require_NoError(t, nc.Flush())
+		al.mu.Lock()
+		dropped := al.dropped
+		al.mu.Unlock()
+		if dropped != 0 {
+			return fmt.Errorf("still %d dropped entries not reported", dropped)
+		}
+		return nil
+	})
+
+	var entries []*AccessLogEntry
+	var dropped uint64
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		entries, dropped = readAccessLog(t, fn), 0
+		for _, e := range entries {
+			dropped += e.Dropped
+		}
+		if dropped == 0 {
+			return fmt.Errorf("expected dropped entries to be reported")
+		}
+		return nil
+	})
+	// Every attempt is either written or counted as dropped.
+	require_True(t, len(entries) < 100)
+	require_True(t, uint64(len(entries))+dropped >= 100)
+
+	// No more than max rate entries are written in any given second.
+	perSecond := make(map[int64]int)
+	for _, e := range entries {
+		perSecond[e.Time.Unix()]++
+	}
+	for sec, n := range perSecond {
+		if n > 5 {
+			t.Fatalf("Expected at most 5 entries in second %d, got %d", sec, n)
+		}
+	}
+}
+
+func TestAccessLogConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config string
+	}{
+		{"invalid subject", `access_log { subjects: ["foo..bar"] }`},
+		{"negative max rate", `access_log { subjects: ["foo"], max_rate: -1 }`},
+		{"unknown field", `access_log { subjects: ["foo"], bar: 1 }`},
+		{"not a map", `access_log: "foo"`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf := createConfFile(t, []byte(test.config))
+			_, err := ProcessConfigFile(conf)
+			require_Error(t, err)
+		})
+	}
+}
+
+func TestAccessLogReload(t *testing.T) {
+	dir := t.TempDir()
+	fn1, fn2 := filepath.Join(dir, "access1.log"), filepath.Join(dir, "access2.log")
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		access_log { subjects: ["foo"], file: %q }
+	`, fn1)))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	nc := natsConnect(t, s.ClientURL())
+	defer nc.Close()
+
+	require_NoError(t, nc.Publish("foo", nil))
+	require_NoError(t, nc.Publish("bar", nil))
+	require_NoError(t, nc.Flush())
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if entries := readAccessLog(t, fn1); len(entries) != 1 {
+			return fmt.Errorf("expected 1 entry, got %d", len(entries))
+		}
+		return nil
+	})
+
+	// Subjects and file can be changed on reload.
+	changeCurrentConfigContentWithNewContent(t, conf, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		access_log { subjects: ["bar"], file: %q }
+	`, fn2)))
+	require_NoError(t, s.Reload())
+
+	require_NoError(t, nc.Publish("foo", nil))
+	require_NoError(t, nc.Publish("bar", nil))
+	require_NoError(t, nc.Flush())
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if entries := readAccessLog(t, fn2); len(entries) != 1 || entries[0].Subject != "bar" {
+			return fmt.Errorf("expected 1 entry for bar, got %d", len(entries))
+		}
+		return nil
+	})
+	require_Len(t, len(readAccessLog(t, fn1)), 1)
+
+	// And the access log can be disabled.
+	changeCurrentConfigContentWithNewContent(t, conf, []byte(`
+		listen: 127.0.0.1:-1
+	`))
+	require_NoError(t, s.Reload())
+	require_True(t, s.accessLog.Load() == nil)
+}
@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"cmp"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -91,6 +92,8 @@ type Account struct {
 	exports      exportMap
 	js           *jsAccount
 	jsLimits     map[string]JetStreamAccountLimits
+	jsMinRepl    int // Minimum replicas for streams, 0 if not set
+	jsRepl       int // Required replicas for streams, 0 if not set
 	nrgAccount   string
 	limits
 	expired      atomic.Bool
@@ -113,6 +116,32 @@ type Account struct {
 	// and if it falls between 0 and that value, message tracing will be triggered.
 	traceDest         string
 	traceDestSampling int
+	// Messages published by clients without trace headers are selected for
+	// tracing to traceDest if they match traceSampleSubjects (when set), one
+	// out of every traceSampleEvery of them.
+	traceSampleEvery    int
+	traceSampleSubjects []string
+	traceSampleCount    atomic.Uint64
+	traceSampling       atomic.Bool
+	// Schemas used to validate the payload of messages published by
+	// clients to matching subjects.
+	schemas    []*subjectSchema
+	hasSchemas atomic.Bool
+	// Streams that restrict which client identities can publish to them.
+	streamPubs atomic.Pointer[streamPublishersIndex]
+	// Streams that allow their subjects to overlap with those of other streams.
+	overlapStreams atomic.Pointer[overlapStreamsIndex]
+	// When set, publish and subscribe permission violations of users in this
+	// account are logged but not enforced, to help with tuning permissions.
+	shadowPerms atomic.Bool
+	// When set, clients of this account can only connect over TLS, including
+	// websocket and MQTT clients, even if the server accepts non TLS connections.
+	tlsRequired atomic.Bool
+	// When set, deliveries to subscriptions of clients in this account need to be
+	// acknowledged. Holds the maximum number of outstanding deliveries tracked per subscription.
+	deliveryAcks atomic.Int64
+	// Rate limits the advisories published in this account, see limits.madvr.
+	advl advisoryLimiter
 	// Guarantee that only one goroutine can be running either checkJetStreamMigrate
 	// or clearObserverState at a given time for this account to prevent interleaving.
 	jscmMu sync.Mutex
@@ -130,6 +159,83 @@ type limits struct {
 	mconns         int32
 	mleafs         int32
 	disallowBearer bool
+	// Policy for wildcard subscriptions, only set from the configuration.
+	noWildcardSubs   bool
+	minWildcardDepth int
+	// Policy for reserved subject families such as "$SYS" or "$JS", only set from the configuration.
+	sysSubjectsAllow []string
+	sysSubjectsDeny  []string
+	// Limits on headers of messages published by clients, only set from the configuration.
+	mhdr    int32
+	mhdrval int32
+	// Maximum number of advisories published per second, only set from the configuration.
+	madvr int32
+}
+
+// checkHeaderSize returns an error if the header block of a published message exceeds
+// the account's limit on the total header size, or on the size of any single header value.
+func (a *Account) checkHeaderSize(hdr []byte) error {
+	if a.mhdr <= 0 && a.mhdrval <= 0 {
+		return nil
+	}
+	if a.mhdr > 0 && len(hdr) > int(a.mhdr) {
+		return fmt.Errorf("%w: %d bytes, limit is %d", ErrMaxHeaderSize, len(hdr), a.mhdr)
+	}
+	if a.mhdrval <= 0 {
+		return nil
+	}
+	// Skip the status line, then check the value of each "Key: Value" line.
+	i := bytes.Index(hdr, []byte(CR_LF))
+	if i < 0 {
+		return nil
+	}
+	for rest := hdr[i+LEN_CR_LF:]; len(rest) > 0; {
+		line := rest
+		if i = bytes.Index(rest, []byte(CR_LF)); i >= 0 {
+			line, rest = rest[:i], rest[i+LEN_CR_LF:]
+		} else {
+			rest = nil
+		}
+		if c := bytes.IndexByte(line, ':'); c >= 0 {
+			if v := bytes.TrimSpace(line[c+1:]); len(v) > int(a.mhdrval) {
+				return fmt.Errorf("%w: %q is %d bytes, limit is %d", ErrMaxHeaderValueSize, bytes.TrimSpace(line[:c]), len(v), a.mhdrval)
+			}
+		}
+	}
+	return nil
+}
+
+// wildcardSubAllowed returns whether the account's policy allows a subscription
+// to the given subject. A minimum wildcard depth means the first wildcard token
+// can not appear before that (1-based) token position.
+func (a *Account) wildcardSubAllowed(subject string) bool {
+	if !a.noWildcardSubs && a.minWildcardDepth <= 0 {
+		return true
+	}
+	for i, t := range strings.Split(subject, tsep) {
+		if t == pwcs || t == fwcs {
+			return !a.noWildcardSubs && i+1 >= a.minWildcardDepth
+		}
+	}
+	return true
+}
+
+// systemSubjectAllowed returns whether the account's policy allows clients to
+// publish or subscribe to the given subject. The policy applies to reserved
+// subject families, which are identified by a first token starting with "$".
+// If an allow list is set only those families can be used, deny always wins.
+func (a *Account) systemSubjectAllowed(subject string) bool {
+	if len(a.sysSubjectsAllow) == 0 && len(a.sysSubjectsDeny) == 0 {
+		return true
+	}
+	if len(subject) == 0 || subject[0] != '$' {
+		return true
+	}
+	family, _, _ := strings.Cut(subject, tsep)
+	if slices.Contains(a.sysSubjectsDeny, family) {
+		return false
+	}
+	return len(a.sysSubjectsAllow) == 0 || slices.Contains(a.sysSubjectsAllow, family)
 }
 
 // Used to track remote clients and leafnodes per remote server.
@@ -182,8 +288,10 @@ type serviceImport struct {
 	share       bool
 	tracking    bool
 	didDeliver  bool
-	atrc        bool        // allow trace (got from service export)
-	trackingHdr http.Header // header from request
+	atrc        bool          // allow trace (got from service export)
+	trackingHdr http.Header   // header from request
+	timeout     time.Duration // requestors get no responders if not responded to in time
+	ttmr        *time.Timer   // timer for the timeout of a response service import
 }
 
 // This is used to record when we create a mapping for implicit service
@@ -272,7 +380,7 @@ type importMap struct {
 func NewAccount(name string) *Account {
 	a := &Account{
 		Name:     name,
-		limits:   limits{-1, -1, -1, -1, false},
+		limits:   limits{-1, -1, -1, -1, false, false, 0, nil, nil, 0, 0, 0},
 		eventIds: nuid.New(),
 	}
 	return a
@@ -296,6 +404,34 @@ func (a *Account) getTraceDestAndSampling() (string, int) {
 	return dest, sampling
 }
 
+// hasTraceSampling returns if the account samples messages for tracing.
+func (a *Account) hasTraceSampling() bool {
+	if a == nil {
+		return false
+	}
+	return a.traceSampling.Load()
+}
+
+// sampleMsgTrace returns true if the message published on this subject
+// is selected for tracing by the account's sampling configuration.
+func (a *Account) sampleMsgTrace(subject string) bool {
+	a.mu.RLock()
+	dest, every, subjects := a.traceDest, a.traceSampleEvery, a.traceSampleSubjects
+	a.mu.RUnlock()
+	if dest == _EMPTY_ {
+		return false
+	}
+	if len(subjects) > 0 && !slices.ContainsFunc(subjects, func(filter string) bool {
+		return subjectIsSubsetMatch(subject, filter)
+	}) {
+		return false
+	}
+	if every <= 1 {
+		return true
+	}
+	return a.traceSampleCount.Add(1)%uint64(every) == 0
+}
+
 // Used to create shallow copies of accounts for transfer
 // from opts to real accounts in server struct.
 // Account `na` write lock is expected to be held on entry
@@ -305,7 +441,12 @@ func (a *Account) shallowCopy(na *Account) {
 	na.Nkey = a.Nkey
 	na.Issuer = a.Issuer
 	na.traceDest, na.traceDestSampling = a.traceDest, a.traceDestSampling
+	na.traceSampleEvery, na.traceSampleSubjects = a.traceSampleEvery, a.traceSampleSubjects
+	na.traceSampling.Store(a.traceSampling.Load())
 	na.nrgAccount = a.nrgAccount
+	na.shadowPerms.Store(a.shadowPerms.Load())
+	na.tlsRequired.Store(a.tlsRequired.Load())
+	na.deliveryAcks.Store(a.deliveryAcks.Load())
 
 	if a.imports.streams != nil {
 		na.imports.streams = make([]*streamImport, 0, len(a.imports.streams))
@@ -349,9 +490,12 @@ func (a *Account) shallowCopy(na *Account) {
 	}
 	na.mappings = a.mappings
 	na.hasMapped.Store(len(na.mappings) > 0)
+	na.schemas = a.schemas
+	na.hasSchemas.Store(len(na.schemas) > 0)
 
 	// JetStream
 	na.jsLimits = a.jsLimits
+	na.jsMinRepl, na.jsRepl = a.jsMinRepl, a.jsRepl
 	// Server config account limits.
 	na.limits = a.limits
 }
@@ -852,6 +996,12 @@ func (a *Account) hasMappings() bool {
 // This performs the logic to map to a new dest subject based on mappings.
 // Should only be called from processInboundClientMsg or service import processing.
 func (a *Account) selectMappedSubject(dest string) (string, bool) {
+	return a.selectMappedSubjectWithHeader(dest, nil)
+}
+
+// Same as selectMappedSubject but makes the message headers available
+// to the header mapping function.
+func (a *Account) selectMappedSubjectWithHeader(dest string, hdr []byte) (string, bool) {
 	if !a.hasMappings() {
 		return dest, false
 	}
@@ -922,7 +1072,7 @@ func (a *Account) selectMappedSubject(dest string) (string, bool) {
 		if len(d.tr.dtokmftokindexesargs) == 0 {
 			ndest = d.tr.dest
 		} else {
-			ndest = d.tr.TransformTokenizedSubject(tts)
+			ndest = d.tr.transformTokenizedSubjectWithHeader(tts, hdr)
 		}
 	}
 
@@ -1718,6 +1868,28 @@ func (a *Account) setServiceImportSharing(destination *Account, to string, check
 	return fmt.Errorf("service import not found")
 }
 
+// SetServiceImportTimeout sets the maximum time a requestor waits for a response through the service import.
+// Once it passes the requestor is sent a no responders status. A timeout of zero disables this.
+func (a *Account) SetServiceImportTimeout(destination *Account, to string, timeout time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.isClaimAccount() {
+		return fmt.Errorf("claim based accounts can not be updated directly")
+	}
+	if timeout < 0 {
+		return fmt.Errorf("service import timeout can not be negative")
+	}
+	for _, sis := range a.imports.services {
+		for _, si := range sis {
+			if si.acc.Name == destination.Name && si.to == to {
+				si.timeout = timeout
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("service import not found")
+}
+
 // AddServiceImport will add a route to an account to send published messages / requests
 // to the destination account. From is the local subject to map, To is the
 // subject that will appear on the destination account. Destination will need
@@ -1787,6 +1959,7 @@ func (a *Account) removeRespServiceImport(si *serviceImport, reason rsiReason) {
 	a.mu.Lock()
 	c := a.ic
 	delete(a.exports.responses, si.from)
+	stopAndClearTimer(&si.ttmr)
 	dest, to, tracking, rc, didDeliver := si.acc, si.to, si.tracking, si.rc, si.didDeliver
 	a.mu.Unlock()
 
@@ -1802,6 +1975,25 @@ func (a *Account) removeRespServiceImport(si *serviceImport, reason rsiReason) {
 	dest.checkForReverseEntry(to, si, false)
 }
 
+// timeoutRespServiceImport is called when the requestor was not responded to within the timeout
+// of the service import. The response mapping is removed and the requestor is sent a no responders status.
+func (a *Account) timeoutRespServiceImport(si *serviceImport) {
+	a.mu.Lock()
+	if si.ttmr == nil || a.exports.responses[si.from] != si {
+		// Already responded to or removed.
+		a.mu.Unlock()
+		return
+	}
+	si.ttmr = nil
+	s, dest, to := a.srv, si.acc, si.to
+	a.mu.Unlock()
+
+	a.removeRespServiceImport(si, rsiTimeout)
+	if s != nil {
+		s.sendInternalAccountMsgWithReply(dest, to, _EMPTY_, []byte("NATS/1.0 503\r\n\r\n"), nil, false)
+	}
+}
+
 func (a *Account) getServiceImportForAccountLocked(dstAccName, subject string) *serviceImport {
 	sis, ok := a.imports.services[subject]
 	if !ok {
@@ -2108,7 +2300,7 @@ func (a *Account) addServiceImport(dest *Account, from, to string, claim *jwt.Im
 	if claim != nil {
 		share = claim.Share
 	}
-	si := &serviceImport{dest, claim, se, nil, from, to, tr, 0, rt, lat, nil, nil, usePub, false, false, share, false, false, atrc, nil}
+	si := &serviceImport{dest, claim, se, nil, from, to, tr, 0, rt, lat, nil, nil, usePub, false, false, share, false, false, atrc, nil, 0, nil}
 	sis := a.imports.services[from]
 	sis = append(sis, si)
 	a.imports.services[from] = sis
@@ -2577,7 +2769,7 @@ func (a *Account) addRespServiceImport(dest *Account, to string, osi *serviceImp
 
 	// dest is the requestor's account. a is the service responder with the export.
 	// Marked as internal here, that is how we distinguish.
-	si := &serviceImport{dest, nil, osi.se, nil, nrr, to, nil, 0, rt, nil, nil, nil, false, true, false, osi.share, false, false, false, nil}
+	si := &serviceImport{dest, nil, osi.se, nil, nrr, to, nil, 0, rt, nil, nil, nil, false, true, false, osi.share, false, false, false, nil, 0, nil}
 
 	if a.exports.responses == nil {
 		a.exports.responses = make(map[string]*serviceImport)
@@ -2595,6 +2787,10 @@ func (a *Account) addRespServiceImport(dest *Account, to string, osi *serviceImp
 		si.tracking = true
 		si.trackingHdr = header
 	}
+	// Make sure the requestor hears back in time if the import has a timeout.
+	if osi.timeout > 0 {
+		si.ttmr = time.AfterFunc(osi.timeout, func() { a.timeoutRespServiceImport(si) })
+	}
 	a.mu.Unlock()
 
 	// We do add in the reverse map such that we can detect loss of interest and do proper
@@ -3377,6 +3573,23 @@ func (a *Account) isAllowedAcount(acc string) bool {
 	return false
 }
 
+// Account JWT tag used to limit the number of consumers across all streams of the account,
+// e.g. "js_max_total_consumers:100".
+const jwtMaxTotalConsumersTag = "js_max_total_consumers"
+
+// jwtTagLimit returns the value of a limit set through an account JWT tag
+// in the form "<name>:<value>".
+func jwtTagLimit(tags jwt.TagList, name string) (int, bool) {
+	for _, tag := range tags {
+		if k, v, ok := strings.Cut(tag, ":"); ok && strings.EqualFold(k, name) {
+			if n, err := strconv.Atoi(v); err == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
 // updateAccountClaimsWithRefresh will update an existing account with new claims.
 // If refreshImportingAccounts is true it will also update incomplete dependent accounts
 // This will replace any exports or imports previously defined.
@@ -3388,6 +3601,9 @@ func (s *Server) updateAccountClaimsWithRefresh(a *Account, ac *jwt.AccountClaim
 	s.Debugf("Updating account claims: %s/%s", a.Name, ac.Name)
 	a.checkExpiration(ac.Claims())
 
+	// Mappings pinned in the server configuration take precedence over the ones in the JWT.
+	pinned := s.getOpts().PinnedMappings[a.Name]
+
 	a.mu.Lock()
 	// Clone to update, only select certain fields.
 	old := &Account{Name: a.Name, exports: a.exports, limits: a.limits, signingKeys: a.signingKeys}
@@ -3469,12 +3685,17 @@ func (s *Server) updateAccountClaimsWithRefresh(a *Account, ac *jwt.AccountClaim
 	removeList := []string{}
 	for _, m := range a.mappings {
 		if _, ok := ac.Mappings[jwt.Subject(m.src)]; !ok {
-			removeList = append(removeList, m.src)
+			if _, ok := pinned[m.src]; !ok {
+				removeList = append(removeList, m.src)
+			}
 		}
 	}
 	a.mu.Unlock()
 
 	for sub, wm := range ac.Mappings {
+		if _, ok := pinned[string(sub)]; ok {
+			continue
+		}
 		mappings := make([]*MapDest, len(wm))
 		for i, m := range wm {
 			mappings[i] = &MapDest{
@@ -3486,6 +3707,9 @@ func (s *Server) updateAccountClaimsWithRefresh(a *Account, ac *jwt.AccountClaim
 		// This will overwrite existing entries
 		a.AddWeightedMappings(string(sub), mappings...)
 	}
+	for src, dests := range pinned {
+		a.AddWeightedMappings(src, dests...)
+	}
 	// remove mappings
 	for _, rmMapping := range removeList {
 		a.RemoveMapping(rmMapping)
@@ -3787,6 +4011,13 @@ func (s *Server) updateAccountClaimsWithRefresh(a *Account, ac *jwt.AccountClaim
 				}
 			}
 		}
+		// Limits the JWT has no field for are set through account tags.
+		if v, ok := jwtTagLimit(ac.Tags, jwtMaxTotalConsumersTag); ok {
+			for t, l := range a.jsLimits {
+				l.MaxTotalConsumers = v
+				a.jsLimits[t] = l
+			}
+		}
 	} else if a.jsLimits != nil {
 		// covers failed update followed by disable
 		a.jsLimits = nil
@@ -4155,6 +4386,36 @@ type DirAccResolver struct {
 	*Server
 	syncInterval time.Duration
 	fetchTimeout time.Duration
+	deletePolicy AccountDeletePolicy
+}
+
+// AccountDeletePolicy determines what happens to the connections and JetStream
+// assets of an account that gets deleted from a full resolver.
+type AccountDeletePolicy int
+
+const (
+	// AccountDeleteDisable disconnects clients and disables JetStream for the account,
+	// but leaves its JetStream assets in place. This is the default.
+	AccountDeleteDisable AccountDeletePolicy = iota
+	// AccountDeleteRefuse refuses to delete an account that still has connections
+	// or JetStream streams.
+	AccountDeleteRefuse
+	// AccountDeleteCascade deletes all JetStream streams and consumers of the account
+	// before deleting it and disconnecting its clients.
+	AccountDeleteCascade
+)
+
+func (p AccountDeletePolicy) String() string {
+	switch p {
+	case AccountDeleteDisable:
+		return "disable"
+	case AccountDeleteRefuse:
+		return "refuse"
+	case AccountDeleteCascade:
+		return "cascade"
+	default:
+		return "unknown"
+	}
 }
 
 func (dr *DirAccResolver) IsTrackingUpdate() bool {
@@ -4243,7 +4504,7 @@ func handleListRequest(store *DirJWTStore, s *Server, reply string) {
 	}
 }
 
-func handleDeleteRequest(store *DirJWTStore, s *Server, msg []byte, reply string) {
+func handleDeleteRequest(store *DirJWTStore, s *Server, policy AccountDeletePolicy, msg []byte, reply string) {
 	var accIds []any
 	var subj, sysAccName string
 	if sysAcc := s.SystemAccount(); sysAcc != nil {
@@ -4280,20 +4541,176 @@ func handleDeleteRequest(store *DirJWTStore, s *Server, msg []byte, reply string
 		respondToUpdate(s, reply, _EMPTY_, fmt.Sprintf("delete accounts request by %s failed", subj), err)
 		return
 	}
-	errs := []string{}
-	passCnt := 0
-	for _, acc := range accIds {
-		if err := store.delete(acc.(string)); err != nil {
-			errs = append(errs, err.Error())
+	deleteAccounts := func() {
+		errs := []string{}
+		passCnt := 0
+		for _, acc := range accIds {
+			if err := s.deleteAccount(store, policy, acc.(string)); err != nil {
+				errs = append(errs, err.Error())
+			} else {
+				passCnt++
+			}
+		}
+		if len(errs) == 0 {
+			respondToUpdate(s, reply, _EMPTY_, fmt.Sprintf("deleted %d accounts", passCnt), nil)
 		} else {
-			passCnt++
+			respondToUpdate(s, reply, _EMPTY_, fmt.Sprintf("deleted %d accounts, failed for %d", passCnt, len(errs)),
+				errors.New(strings.Join(errs, "\n")))
 		}
 	}
-	if len(errs) == 0 {
-		respondToUpdate(s, reply, _EMPTY_, fmt.Sprintf("deleted %d accounts", passCnt), nil)
-	} else {
-		respondToUpdate(s, reply, _EMPTY_, fmt.Sprintf("deleted %d accounts, failed for %d", passCnt, len(errs)),
-			errors.New(strings.Join(errs, "\n")))
+	if policy == AccountDeleteDisable {
+		deleteAccounts()
+		return
+	}
+	// Checking the connections across servers or removing the assets of an account has to
+	// wait on other servers, so do not hold up the system account's message processing.
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+		deleteAccounts()
+	})
+}
+
+const (
+	// accountDeleteTimeout bounds how long we wait for the JetStream assets
+	// of an account to be removed when cascading its deletion.
+	accountDeleteTimeout = 10 * time.Second
+	// accountDeleteConnsWait is how long we collect the connection counts
+	// of an account from the other servers when refusing its deletion.
+	accountDeleteConnsWait = 500 * time.Millisecond
+)
+
+// deleteAccount removes the account from the store, which will disconnect its
+// clients, after applying the delete policy to its connections and JetStream assets.
+func (s *Server) deleteAccount(store *DirJWTStore, policy AccountDeletePolicy, accName string) error {
+	switch policy {
+	case AccountDeleteRefuse:
+		if conns, streams := s.accountActiveAssets(accName); conns > 0 || streams > 0 {
+			return fmt.Errorf("account %s still has %d connections and %d streams", accName, conns, streams)
+		}
+	case AccountDeleteCascade:
+		if err := s.removeAccountJetStreamAssets(accName, accountDeleteTimeout); err != nil {
+			return fmt.Errorf("account %s: %v", accName, err)
+		}
+	}
+	return store.delete(accName)
+}
+
+// accountActiveAssets returns the number of connections and JetStream streams of the account.
+// Connections are counted across all servers, and in clustered mode streams are counted
+// from the meta layer.
+func (s *Server) accountActiveAssets(accName string) (conns, streams int) {
+	return s.accountClusterConnections(accName, accountDeleteConnsWait), s.accountStreams(accName)
+}
+
+// accountStreams returns the number of JetStream streams of the account.
+// In clustered mode streams are counted from the meta layer.
+func (s *Server) accountStreams(accName string) int {
+	if js, cc := s.getJetStreamCluster(); js != nil && cc != nil {
+		js.mu.RLock()
+		defer js.mu.RUnlock()
+		return len(cc.streams[accName])
+	}
+	if v, ok := s.accounts.Load(accName); ok {
+		return v.(*Account).numStreams()
+	}
+	return 0
+}
+
+// accountClusterConnections returns the number of client and leafnode connections of the
+// account across all servers. The other servers are asked for their account statz, since the
+// remote counts we track are only updated periodically, and only if we had the account loaded.
+func (s *Server) accountClusterConnections(accName string, wait time.Duration) int {
+	var conns int
+	if v, ok := s.accounts.Load(accName); ok {
+		acc := v.(*Account)
+		acc.mu.RLock()
+		conns = acc.numLocalConnections() + acc.numLocalLeafNodes()
+		acc.mu.RUnlock()
+	}
+
+	s.mu.Lock()
+	if s.sys == nil || s.sys.replies == nil {
+		s.mu.Unlock()
+		return conns
+	}
+	id, inbox := s.info.ID, s.newRespInbox()
+	var mu sync.Mutex
+	remote := make(map[string]int)
+	s.sys.replies[inbox] = func(_ *subscription, _ *client, _ *Account, _, _ string, msg []byte) {
+		var stz AccountStatz
+		resp := ServerAPIResponse{Data: &stz}
+		if err := json.Unmarshal(msg, &resp); err != nil || resp.Server == nil || resp.Server.ID == id {
+			return
+		}
+		var n int
+		for _, as := range stz.Accounts {
+			if as.Account == accName {
+				n += as.Conns + as.LeafNodes
+			}
+		}
+		mu.Lock()
+		remote[resp.Server.ID] = n
+		mu.Unlock()
+	}
+	s.mu.Unlock()
+
+	s.sendInternalMsgLocked(fmt.Sprintf(accDirectReqSubj, accName, "STATZ"), inbox, nil, nil)
+
+	select {
+	case <-s.quitCh:
+	case <-time.After(wait):
+	}
+
+	s.mu.Lock()
+	if s.sys != nil && s.sys.replies != nil {
+		delete(s.sys.replies, inbox)
+	}
+	s.mu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, n := range remote {
+		conns += n
+	}
+	return conns
+}
+
+// removeAccountJetStreamAssets deletes all JetStream streams and consumers of the account.
+// In clustered mode the meta leader proposes the removals and all servers wait for them
+// to be applied, so nothing is left behind once the account is gone.
+func (s *Server) removeAccountJetStreamAssets(accName string, timeout time.Duration) error {
+	js := s.getJetStream()
+	if js == nil {
+		return nil
+	}
+	if !s.JetStreamIsClustered() {
+		if _, apiErr := s.purgeAccountStreams(js, accName); apiErr != nil {
+			return apiErr
+		}
+		return nil
+	}
+
+	_, cc := s.getJetStreamCluster()
+	js.mu.Lock()
+	if cc.isLeader() {
+		ns, nc := js.proposeAccountPurge(accName, accDeleteReqSubj)
+		s.Noticef("Removing JetStream assets of deleted account %s (streams: %d, consumers: %d)", accName, ns, nc)
+	}
+	js.mu.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if s.accountStreams(accName) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("timeout waiting for JetStream assets to be removed")
+		}
+		select {
+		case <-s.quitCh:
+			return ErrServerNotRunning
+		case <-time.After(50 * time.Millisecond):
+		}
 	}
 }
 
@@ -4501,7 +4918,7 @@ func (dr *DirAccResolver) Start(s *Server) error {
 		// As this is a raw message, we need to extract payload and only decode claims from it,
 		// in case request is sent with headers.
 		_, msg = c.msgParts(msg)
-		handleDeleteRequest(dr.DirJWTStore, s, msg, reply)
+		handleDeleteRequest(dr.DirJWTStore, s, dr.deletePolicy, msg, reply)
 	}); err != nil {
 		return fmt.Errorf("error setting up delete request handling: %v", err)
 	}
@@ -4572,6 +4989,14 @@ func FetchTimeout(to time.Duration) DirResOption {
 	}
 }
 
+// determines what happens to the assets of deleted accounts
+func DeletePolicy(p AccountDeletePolicy) DirResOption {
+	return func(r *DirAccResolver) error {
+		r.deletePolicy = p
+		return nil
+	}
+}
+
 func (dr *DirAccResolver) apply(opts ...DirResOption) error {
 	for _, o := range opts {
 		if err := o(dr); err != nil {
@@ -4593,7 +5018,7 @@ func NewDirAccResolver(path string, limit int64, syncInterval time.Duration, del
 		return nil, err
 	}
 
-	res := &DirAccResolver{store, nil, syncInterval, DEFAULT_ACCOUNT_FETCH_TIMEOUT}
+	res := &DirAccResolver{store, nil, syncInterval, DEFAULT_ACCOUNT_FETCH_TIMEOUT, AccountDeleteDisable}
 	if err := res.apply(opts...); err != nil {
 		return nil, err
 	}
@@ -4679,7 +5104,7 @@ func NewCacheDirAccResolver(path string, limit int64, ttl time.Duration, opts ..
 	if err != nil {
 		return nil, err
 	}
-	res := &CacheDirAccResolver{DirAccResolver{store, nil, 0, DEFAULT_ACCOUNT_FETCH_TIMEOUT}, ttl}
+	res := &CacheDirAccResolver{DirAccResolver{store, nil, 0, DEFAULT_ACCOUNT_FETCH_TIMEOUT, AccountDeleteDisable}, ttl}
 	if err := res.apply(opts...); err != nil {
 		return nil, err
 	}
@@ -4771,7 +5196,7 @@ func (dr *CacheDirAccResolver) Start(s *Server) error {
 		// As this is a raw message, we need to extract payload and only decode claims from it,
 		// in case request is sent with headers.
 		_, msg = c.msgParts(msg)
-		handleDeleteRequest(dr.DirJWTStore, s, msg, reply)
+		handleDeleteRequest(dr.DirJWTStore, s, dr.deletePolicy, msg, reply)
 	}); err != nil {
 		return fmt.Errorf("error setting up list request handling: %v", err)
 	}
@@ -136,6 +136,9 @@ type SubjectPermission struct {
 type ResponsePermission struct {
 	MaxMsgs int           `json:"max"`
 	Expires time.Duration `json:"ttl"`
+	// MaxInFlight limits the number of requests a connection can have outstanding responses for.
+	// Requests above this limit are not delivered to the connection. Unlimited if not positive.
+	MaxInFlight int `json:"max_in_flight,omitempty"`
 }
 
 // Permissions are the allowed subjects on a per
@@ -186,8 +189,9 @@ func (p *Permissions) clone() *Permissions {
 	}
 	if p.Response != nil {
 		clone.Response = &ResponsePermission{
-			MaxMsgs: p.Response.MaxMsgs,
-			Expires: p.Response.Expires,
+			MaxMsgs:     p.Response.MaxMsgs,
+			Expires:     p.Response.Expires,
+			MaxInFlight: p.Response.MaxInFlight,
 		}
 	}
 	return clone
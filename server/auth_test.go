@@ -797,3 +797,78 @@ func TestAuthProxyRequired(t *testing.T) {
 	s.Shutdown()
 	drainLog()
 }
+
+func TestAuthConnectBlocklist(t *testing.T) {
+	nkUsr, err := nkeys.CreateUser()
+	require_NoError(t, err)
+	nkPub, err := nkUsr.PublicKey()
+	require_NoError(t, err)
+
+	conf := createConfFile(t, fmt.Appendf(nil, `
+		listen: "127.0.0.1:-1"
+		authorization {
+			users [
+				{user: user, password: pwd}
+				{nkey: %q}
+			]
+		}
+		connect_blocklist {
+			names: ["bad"]
+		}
+	`, nkPub))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	connect := func(name string, nkey bool) (*nats.Conn, error) {
+		auth := nats.UserInfo("user", "pwd")
+		if nkey {
+			auth = nats.Nkey(nkPub, func(nonce []byte) ([]byte, error) { return nkUsr.Sign(nonce) })
+		}
+		return nats.Connect(s.ClientURL(), nats.Name(name), auth, nats.NoReconnect())
+	}
+	requireBlocked := func(t *testing.T, name string, nkey bool) {
+		t.Helper()
+		_, err := connect(name, nkey)
+		require_Error(t, err)
+		require_Contains(t, strings.ToLower(err.Error()), ErrClientBlocked.Error())
+	}
+	requireAllowed := func(t *testing.T, name string, nkey bool) {
+		t.Helper()
+		nc, err := connect(name, nkey)
+		require_NoError(t, err)
+		nc.Close()
+	}
+
+	// Blocked by name, regardless of the user.
+	requireBlocked(t, "bad", false)
+	requireBlocked(t, "bad", true)
+	requireAllowed(t, "good", false)
+	requireAllowed(t, "good", true)
+
+	// The rejection has its own reason.
+	connz, err := s.Connz(&ConnzOptions{State: ConnClosed})
+	require_NoError(t, err)
+	var blocked int
+	for _, ci := range connz.Conns {
+		if ci.Reason == ClientBlocked.String() {
+			blocked++
+		}
+	}
+	require_Equal(t, blocked, 2)
+
+	// The blocklist can be changed at runtime.
+	s.SetConnectBlocklist(ConnectBlocklistOpts{Nkeys: []string{nkPub}})
+	requireAllowed(t, "bad", false)
+	requireBlocked(t, "good", true)
+
+	// Connected clients are not affected.
+	nc, err := connect("good", false)
+	require_NoError(t, err)
+	defer nc.Close()
+	s.SetConnectBlocklist(ConnectBlocklistOpts{Names: []string{"good"}})
+	require_NoError(t, nc.Flush())
+	requireBlocked(t, "good", false)
+
+	s.SetConnectBlocklist(ConnectBlocklistOpts{})
+	requireAllowed(t, "good", true)
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+// ConnectBlocklistOpts lists the client identifiers that are rejected when connecting.
+type ConnectBlocklistOpts struct {
+	// Names are the client names, as set by the client in its CONNECT.
+	Names []string `json:"names,omitempty"`
+	// Nkeys are the public keys of nkey users, or the subjects of user JWTs.
+	Nkeys []string `json:"nkeys,omitempty"`
+}
+
+// connectBlocklist is the lookup form of ConnectBlocklistOpts.
+type connectBlocklist struct {
+	names map[string]struct{}
+	nkeys map[string]struct{}
+}
+
+func newConnectBlocklist(opts *ConnectBlocklistOpts) *connectBlocklist {
+	if len(opts.Names) == 0 && len(opts.Nkeys) == 0 {
+		return nil
+	}
+	bl := &connectBlocklist{
+		names: make(map[string]struct{}, len(opts.Names)),
+		nkeys: make(map[string]struct{}, len(opts.Nkeys)),
+	}
+	for _, name := range opts.Names {
+		bl.names[name] = struct{}{}
+	}
+	for _, nkey := range opts.Nkeys {
+		bl.nkeys[nkey] = struct{}{}
+	}
+	return bl
+}
+
+// SetConnectBlocklist replaces the client identifiers that are rejected when connecting.
+// Clients that are already connected are not affected. An empty list blocks no one.
+func (s *Server) SetConnectBlocklist(opts ConnectBlocklistOpts) {
+	s.connBlocklist.Store(newConnectBlocklist(&opts))
+}
+
+// isClientBlocked returns whether an authenticated client is on the connect blocklist.
+func (s *Server) isClientBlocked(c *client) bool {
+	bl := s.connBlocklist.Load()
+	if bl == nil {
+		return false
+	}
+	c.mu.Lock()
+	name, nkey, pubKey := c.opts.Name, c.opts.Nkey, c.pubKey
+	c.mu.Unlock()
+
+	if _, ok := bl.names[name]; ok && name != _EMPTY_ {
+		return true
+	}
+	for _, key := range []string{nkey, pubKey} {
+		if _, ok := bl.nkeys[key]; ok && key != _EMPTY_ {
+			return true
+		}
+	}
+	return false
+}
@@ -225,6 +225,8 @@ const (
 	Kicked
 	ProxyNotTrusted
 	ProxyRequired
+	AccountRequiresTLS
+	ClientBlocked
 )
 
 // Some flags passed to processMsgResults
@@ -256,6 +258,32 @@ func (p WriteTimeoutPolicy) String() string {
 	}
 }
 
+// FanoutPolicy determines what happens to a client publish that matches
+// more subscriptions than the configured maximum fan-out.
+type FanoutPolicy uint8
+
+const (
+	// FanoutYield delivers the message but yields after each batch of
+	// deliveries so that other connections are not starved.
+	FanoutYield FanoutPolicy = iota
+	// FanoutAdvise delivers the message and logs a warning.
+	FanoutAdvise
+	// FanoutReject drops the message and reports an error to the publisher.
+	FanoutReject
+)
+
+// String returns a human-friendly value.
+func (p FanoutPolicy) String() string {
+	switch p {
+	case FanoutAdvise:
+		return "advise"
+	case FanoutReject:
+		return "reject"
+	default:
+		return "yield"
+	}
+}
+
 type client struct {
 	// Here first because of use of atomics, and memory alignment.
 	stats
@@ -270,6 +298,9 @@ type client struct {
 	rrTracking *rrTracking
 	mpay       int32
 	msubs      int32
+	churn      *rateCounter
+	mfan       int
+	fpol       FanoutPolicy
 	mcl        int32
 	mu         sync.RWMutex
 	cid        uint64
@@ -469,6 +500,7 @@ const (
 	routeTargetInit      = 8
 	replyPermLimit       = 4096
 	replyPruneTime       = time.Second
+	replyPruneAtLimit    = 100 * time.Millisecond
 )
 
 // Represent read cache booleans with a bitmask
@@ -477,6 +509,7 @@ type readCacheFlag uint16
 const (
 	hasMappings         readCacheFlag = 1 << iota // For account subject mappings.
 	switchToCompression readCacheFlag = 1 << 1
+	hasTraceSampling    readCacheFlag = 1 << 2 // For account message trace sampling.
 )
 
 const sysGroup = "_sys_"
@@ -651,6 +684,7 @@ type subscription struct {
 	qw      int32
 	closed  int32
 	mqtt    *mqttSub
+	dack    *deliveryAcks // Set if deliveries need to be acknowledged.
 }
 
 // Indicate that this subscription is closed.
@@ -842,6 +876,10 @@ func (c *client) reportErrRegisterAccount(acc *Account, err error) {
 	if err == ErrTooManyAccountConnections {
 		c.maxAccountConnExceeded()
 		return
+	} else if err == ErrAccountRequiresTLS {
+		c.sendErrAndErr(err.Error())
+		c.closeConnection(AccountRequiresTLS)
+		return
 	}
 	c.Errorf("Problem registering with account %q: %s", acc.Name, err)
 	c.sendErr("Failed Account Registration")
@@ -883,10 +921,16 @@ func (c *client) registerWithAccount(acc *Account) error {
 	}
 	kind := c.kind
 	srv := c.srv
+	_, isTLS := c.nc.(*tls.Conn)
 	c.acc = acc
 	c.applyAccountLimits()
 	c.mu.Unlock()
 
+	// Check if the account only allows TLS connections.
+	if kind == CLIENT && !isTLS && acc.tlsRequired.Load() {
+		return ErrAccountRequiresTLS
+	}
+
 	// Check if we have a max connections violation
 	if kind == CLIENT && acc.MaxTotalConnectionsReached() {
 		return ErrTooManyAccountConnections
@@ -1466,6 +1510,13 @@ func (c *client) readLoop(pre []byte) {
 				c.in.flags.clear(hasMappings)
 			}
 		}
+		if c.kind == CLIENT {
+			if acc.hasTraceSampling() {
+				c.in.flags.set(hasTraceSampling)
+			} else {
+				c.in.flags.clear(hasTraceSampling)
+			}
+		}
 
 		c.in.start = time.Now()
 
@@ -1858,6 +1909,10 @@ func (c *client) flushOutbound() bool {
 		close(c.out.stc)
 		c.out.stc = nil
 	}
+	// Check if a route that went over its pending soft limit has recovered.
+	if c.kind == ROUTER && c.route != nil && c.route.pendSoftHit {
+		c.checkRoutePendingRecovered()
+	}
 	// Check if the connection is recovering from being a slow consumer.
 	if !gotWriteTimeout && c.flags.isSet(isSlowConsumer) {
 		c.Noticef("Slow Consumer Recovered: Flush took %.3fs with %d chunks of %d total bytes.", time.Since(start).Seconds(), len(orig), attempted)
@@ -2307,6 +2362,12 @@ func (c *client) processConnect(arg []byte) error {
 			return ErrAuthentication
 		}
 
+		// Reject clients on the blocklist, now that we know who they are.
+		if kind == CLIENT && srv.isClientBlocked(c) {
+			c.clientBlocked()
+			return ErrClientBlocked
+		}
+
 		// Check for Account designation, we used to have this as an optional feature for dynamic
 		// sandbox environments. Now its considered an error.
 		if accountNew || account != _EMPTY_ {
@@ -2455,6 +2516,11 @@ func (c *client) authViolation() {
 	c.closeConnection(reason)
 }
 
+func (c *client) clientBlocked() {
+	c.sendErrAndErr(ErrClientBlocked.Error())
+	c.closeConnection(ClientBlocked)
+}
+
 func (c *client) maxAccountConnExceeded() {
 	c.sendErrAndErr(ErrTooManyAccountConnections.Error())
 	c.closeConnection(MaxAccountConnectionsExceeded)
@@ -2536,6 +2602,10 @@ func (c *client) queueOutbound(data []byte) {
 		c.Noticef("Slow Consumer Detected: MaxPending of %d Exceeded", c.out.mp)
 		c.markConnAsClosed(SlowConsumerPendingBytes)
 		return
+	} else if c.kind == ROUTER && c.route != nil && (c.route.pendSoft > 0 || c.route.pendHard > 0) {
+		if c.checkRoutePendingLimits(len(data)) {
+			return
+		}
 	}
 
 	// Check here if we should create a stall channel if we are falling behind.
@@ -2732,8 +2802,9 @@ func (c *client) processPong() {
 	}
 	// If compression is currently active for a route/leaf connection, if the
 	// compression configuration is s2_auto, check if we should change
-	// the compression level.
-	if c.kind == ROUTER && needsCompression(c.route.compression) {
+	// the compression level. This is skipped for a route that is over its
+	// pending soft limit since the level has been forced to the best one.
+	if c.kind == ROUTER && needsCompression(c.route.compression) && !c.route.pendSoftHit {
 		c.updateS2AutoCompressionLevel(&srv.getOpts().Cluster.Compression, &c.route.compression)
 	} else if c.kind == LEAF && needsCompression(c.leaf.compression) {
 		var co *CompressionOpts
@@ -3001,12 +3072,13 @@ func (c *client) processSubEx(subject, queue, bsid []byte, cb msgHandler, noForw
 		// allow = ["foo", "foo v1"]  -> can subscribe to 'foo' but can only queue subscribe to 'foo v1'
 		//
 		if sub.queue != nil {
-			if !c.canSubscribe(string(sub.subject), string(sub.queue)) || string(sub.queue) == sysGroup {
+			if string(sub.queue) == sysGroup ||
+				(!c.canSubscribe(string(sub.subject), string(sub.queue)) && !c.shadowPermitted(accessLogSub, sub.subject, sub.queue)) {
 				c.mu.Unlock()
 				c.subPermissionViolation(sub)
 				return nil, ErrSubscribePermissionViolation
 			}
-		} else if !c.canSubscribe(string(sub.subject)) {
+		} else if !c.canSubscribe(string(sub.subject)) && !c.shadowPermitted(accessLogSub, sub.subject, nil) {
 			c.mu.Unlock()
 			c.subPermissionViolation(sub)
 			return nil, ErrSubscribePermissionViolation
@@ -3019,6 +3091,40 @@ func (c *client) processSubEx(subject, queue, bsid []byte, cb msgHandler, noForw
 				return nil, ErrTooManySubTokens
 			}
 		}
+
+		if opts := srv.getOpts(); opts != nil && opts.MaxSubSubjectLen > 0 && len(sub.subject) > opts.MaxSubSubjectLen {
+			c.mu.Unlock()
+			c.maxSubjectLenViolation(sub, opts.MaxSubSubjectLen)
+			return nil, ErrSubSubjectTooLong
+		}
+
+		if acc != nil && subjectHasWildcard(bytesToString(sub.subject)) && !acc.wildcardSubAllowed(string(sub.subject)) {
+			c.mu.Unlock()
+			c.wildcardSubViolation(sub)
+			return nil, ErrWildcardSubNotAllowed
+		}
+
+		if acc != nil && !acc.systemSubjectAllowed(string(sub.subject)) {
+			c.mu.Unlock()
+			c.subPermissionViolation(sub)
+			return nil, ErrSubscribePermissionViolation
+		}
+
+		if c.churn != nil && !c.churn.allow() {
+			c.mu.Unlock()
+			c.subsChurnViolation(sub)
+			return nil, ErrSubsChurnExceeded
+		}
+
+		c.logAccess(accessLogSub, sub.subject, sub.queue, true)
+
+		// Track deliveries if the account requires them to be acknowledged. The subject
+		// to ack on is sent in a header, so this only applies to clients supporting headers.
+		if acc != nil && c.headers {
+			if max := acc.deliveryAcks.Load(); max > 0 {
+				sub.dack = &deliveryAcks{max: max}
+			}
+		}
 	}
 
 	// Check if we have a maximum on the number of subscriptions.
@@ -3443,6 +3549,13 @@ func (c *client) processUnsub(arg []byte) error {
 	srv := c.srv
 	var acc *Account
 
+	// Unsubscribes are never refused, otherwise the client would keep
+	// receiving messages it thinks it is no longer interested in, but
+	// they do count against the churn rate.
+	if c.churn != nil {
+		c.churn.allow()
+	}
+
 	updateGWs := false
 	if sub, ok = c.subs[string(sid)]; ok {
 		acc = c.acc
@@ -3688,7 +3801,15 @@ func (c *client) deliverMsg(prodIsMQTT bool, sub *subscription, acc *Account, su
 
 	// Check if we have a subscribe deny clause. This will trigger us to check the subject
 	// for a match against the denied subjects.
-	if client.mperms != nil && client.checkDenySub(string(subject)) {
+	if client.mperms != nil && client.checkDenySub(string(subject)) && !client.shadowPermitted(accessLogSub, subject, nil) {
+		mt.addEgressEvent(client, sub, errMsgTraceSubDeny)
+		client.mu.Unlock()
+		return false
+	}
+
+	// Wildcard subscriptions are not prevented by the account's policy for reserved
+	// system subjects, so we need to check at delivery time.
+	if client.kind == CLIENT && client.acc != nil && !client.acc.systemSubjectAllowed(bytesToString(subject)) {
 		mt.addEgressEvent(client, sub, errMsgTraceSubDeny)
 		client.mu.Unlock()
 		return false
@@ -3712,6 +3833,17 @@ func (c *client) deliverMsg(prodIsMQTT bool, sub *subscription, acc *Account, su
 		}
 	}
 
+	// If we are tracking dynamic reply permissions, do not deliver any more requests
+	// once the client has reached its limit of in-flight requests.
+	// Only reply subject permissions if the client is not already allowed to publish to the reply subject.
+	trackReply := client.replies != nil && len(reply) > 0 && !client.pubAllowedFullCheck(string(reply), true, true)
+	if trackReply && client.replyPermsAtLimit() {
+		mt.addEgressEvent(client, sub, errMsgTraceMaxInFlight)
+		client.mu.Unlock()
+		client.RateLimitDebugf("Maximum in-flight requests reached, not delivering %q", subject)
+		return false
+	}
+
 	var mtErr string
 	if mt != nil {
 		// For non internal subscription, and if the remote does not support
@@ -3901,6 +4033,14 @@ func (c *client) deliverMsg(prodIsMQTT bool, sub *subscription, acc *Account, su
 		}
 	}
 
+	// Deliveries that need to be acknowledged carry the subject to ack them on.
+	if sub.dack != nil && sub.icb == nil {
+		osz := len(msg)
+		mh, msg, hdrSize = sub.deliveryAckMsg(subject, reply, msg, hdrSize, prodIsMQTT)
+		// Account for the added header.
+		client.outBytes += int64(len(msg) - osz)
+	}
+
 	// Queue to outbound buffer
 	client.queueOutbound(mh)
 	client.queueOutbound(msg)
@@ -3911,8 +4051,7 @@ func (c *client) deliverMsg(prodIsMQTT bool, sub *subscription, acc *Account, su
 
 	// If we are tracking dynamic publish permissions that track reply subjects,
 	// do that accounting here. We only look at client.replies which will be non-nil.
-	// Only reply subject permissions if the client is not already allowed to publish to the reply subject.
-	if client.replies != nil && len(reply) > 0 && !client.pubAllowedFullCheck(string(reply), true, true) {
+	if trackReply {
 		client.replies[string(reply)] = &resp{time.Now(), 0}
 		client.repliesSincePrune++
 		if client.repliesSincePrune > replyPermLimit || time.Since(client.lastReplyPrune) > replyPruneTime {
@@ -4045,6 +4184,24 @@ func (c *client) pruneReplyPerms() {
 	c.lastReplyPrune = now
 }
 
+// replyPermsAtLimit returns whether the number of in-flight requests tracked in
+// our reply cache has reached the configured maximum. Stale entries are pruned
+// first, but not too often.
+// Lock must be held.
+func (c *client) replyPermsAtLimit() bool {
+	if c.perms == nil || c.perms.resp == nil {
+		return false
+	}
+	mif := c.perms.resp.MaxInFlight
+	if mif <= 0 || len(c.replies) < mif {
+		return false
+	}
+	if time.Since(c.lastReplyPrune) > replyPruneAtLimit {
+		c.pruneReplyPerms()
+	}
+	return len(c.replies) >= mif
+}
+
 // pruneDenyCache will prune the deny cache via randomly
 // deleting items. Doing so pruneSize items at a time.
 // Lock must be held for this one since it is shared under
@@ -4183,6 +4340,64 @@ func (c *client) pubAllowedFullCheck(subject string, fullCheck, hasLock bool) bo
 	return allowed
 }
 
+// streamPublishAllowed returns whether the client's identity is allowed to publish
+// to the subject by all streams in the account that restrict their publishers.
+// Lock should not be held.
+func (c *client) streamPublishAllowed(acc *Account, subject string) bool {
+	idx := acc.streamPubs.Load()
+	if idx == nil || idx.count.Load() == 0 {
+		return true
+	}
+	var user string
+	var tags []string
+	var loaded, trusted bool
+	allowed := true
+	idx.sl.Match(subject, func(sp *streamPublishers) {
+		if !allowed {
+			return
+		}
+		if !loaded {
+			user, tags, trusted = c.streamPublisherIdentity()
+			loaded = true
+		}
+		if !trusted && !sp.allows(user, tags) {
+			c.Debugf("Publish to %q not allowed by stream %q", subject, sp.stream)
+			allowed = false
+		}
+	})
+	return allowed
+}
+
+// streamPublisherIdentity returns the identity checked against the allowed
+// publishers of streams. Leafnodes and gateways are checked using the identity
+// they authenticated with, unless the remote is a JetStream server of our
+// domain, which already checked the publishing client itself.
+// Lock should not be held.
+func (c *client) streamPublisherIdentity() (user string, tags []string, trusted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.kind {
+	case ROUTER:
+		return _EMPTY_, nil, false
+	case GATEWAY:
+		if v, ok := c.srv.nodeToInfo.Load(getHash(c.gw.remoteName)); ok {
+			if ni := v.(nodeInfo); ni.js && !ni.offline {
+				return _EMPTY_, nil, true
+			}
+		}
+	case LEAF:
+		// The hub we have connected to is trusted.
+		if c.isSolicitedLeafNode() {
+			return _EMPTY_, nil, true
+		}
+	}
+	// Tokens do not identify a publisher.
+	if c.opts.Token == _EMPTY_ {
+		user = c.getRawAuthUser()
+	}
+	return user, c.tags, false
+}
+
 // Returns true if this subject matches a tracked dynamic reply permission.
 // Lock must be held.
 func (c *client) responseAllowed(subject string) bool {
@@ -4196,6 +4411,10 @@ func (c *client) responseAllowed(subject string) bool {
 		} else if c.perms.resp.Expires > 0 && time.Since(resp.t) > c.perms.resp.Expires {
 			delete(c.replies, subject)
 		} else {
+			// Once all responses have been sent the request is no longer in-flight.
+			if c.perms.resp.MaxInFlight > 0 && resp.n == c.perms.resp.MaxMsgs {
+				delete(c.replies, subject)
+			}
 			return true
 		}
 	}
@@ -4275,7 +4494,13 @@ func (c *client) processInboundMsg(msg []byte) {
 
 // selectMappedSubject will choose the mapped subject based on the client's inbound subject.
 func (c *client) selectMappedSubject() bool {
-	nsubj, changed := c.acc.selectMappedSubject(bytesToString(c.pa.subject))
+	return c.selectMappedSubjectWithHeader(nil)
+}
+
+// selectMappedSubjectWithHeader is like selectMappedSubject but allows header
+// mapping functions to use the values from the inbound message headers.
+func (c *client) selectMappedSubjectWithHeader(hdr []byte) bool {
+	nsubj, changed := c.acc.selectMappedSubjectWithHeader(bytesToString(c.pa.subject), hdr)
 	if changed {
 		c.pa.mapped = c.pa.subject
 		c.pa.subject = []byte(nsubj)
@@ -4311,9 +4536,16 @@ func (c *client) processInboundClientMsg(msg []byte) (bool, bool) {
 	acc := c.acc
 	genidAddr := &acc.sl.genid
 
+	// Acks for deliveries to our own subscriptions are consumed here and not routed.
+	if c.kind == CLIENT && bytes.HasPrefix(c.pa.subject, deliveryAckPrefix) && acc.deliveryAcks.Load() > 0 {
+		c.processDeliveryAck(c.pa.subject[len(deliveryAckPrefix):])
+		c.mu.Unlock()
+		return false, false
+	}
+
 	// Check pub permissions
 	if c.perms != nil && (c.perms.pub.allow != nil || c.perms.pub.deny != nil) {
-		if !c.pubAllowedFullCheck(string(c.pa.subject), true, true) {
+		if !c.pubAllowedFullCheck(string(c.pa.subject), true, true) && !c.shadowPermitted(accessLogPub, c.pa.subject, nil) {
 			c.mu.Unlock()
 			c.pubPermissionViolation(c.pa.subject)
 			return false, true
@@ -4335,12 +4567,50 @@ func (c *client) processInboundClientMsg(msg []byte) (bool, bool) {
 		return false, true
 	}
 
+	// Check the account's policy for reserved system subjects, regardless of user permissions.
+	if c.kind == CLIENT && !acc.systemSubjectAllowed(bytesToString(c.pa.subject)) {
+		c.pubPermissionViolation(c.pa.subject)
+		return false, true
+	}
+
+	// Check the account's limits on the size of the message headers.
+	if c.kind == CLIENT && c.pa.hdr > 0 {
+		if err := acc.checkHeaderSize(msg[:c.pa.hdr]); err != nil {
+			c.pubLimitViolation(c.pa.subject, err.Error(), c.Debugf)
+			return false, false
+		}
+	}
+
+	// Check the allowed publishers of any stream capturing this subject.
+	if c.kind == CLIENT && !c.streamPublishAllowed(acc, bytesToString(c.pa.subject)) {
+		c.pubPermissionViolation(c.pa.subject)
+		return false, true
+	}
+
 	// Now check for reserved replies. These are used for service imports.
 	if c.kind == CLIENT && len(c.pa.reply) > 0 && isReservedReply(c.pa.reply) {
 		c.replySubjectViolation(c.pa.reply)
 		return false, true
 	}
 
+	c.logAccess(accessLogPub, c.pa.subject, nil, true)
+
+	// Validate the payload if the account has a schema for this subject.
+	if c.kind == CLIENT && acc.hasSchemas.Load() {
+		var ok bool
+		if msg, ok = c.checkSchema(acc, msg); !ok {
+			return false, false
+		}
+	}
+
+	// Reject if a remote gateway does not know about this account and we are configured to do so.
+	if c.kind == CLIENT && c.srv.gateway.uacc == GatewayUnknownAccountReject {
+		if gwName := c.srv.gatewayAccountUnknownBy(acc.Name); gwName != _EMPTY_ {
+			c.pubLimitViolation(c.pa.subject, fmt.Sprintf("account %q is not known by gateway %q", acc.Name, gwName), nil)
+			return false, false
+		}
+	}
+
 	if c.opts.Verbose {
 		c.sendOK()
 	}
@@ -4429,6 +4699,17 @@ func (c *client) processInboundClientMsg(msg []byte) (bool, bool) {
 		}
 	}
 
+	// Check if this publish goes above the maximum fan-out.
+	if c.mfan > 0 && c.fpol != FanoutYield {
+		if fanout := len(r.psubs) + len(r.qsubs); fanout > c.mfan {
+			if c.fpol == FanoutReject {
+				c.pubLimitViolation(c.pa.subject, fmt.Sprintf("fan-out of %d exceeds maximum of %d", fanout, c.mfan), c.rateLimitFormatWarnf)
+				return false, false
+			}
+			c.rateLimitFormatWarnf("High fan-out publish to %q matched %d subscriptions", c.pa.subject, fanout)
+		}
+	}
+
 	// Indication if we attempted to deliver the message to anyone.
 	var didDeliver bool
 	var qnames [][]byte
@@ -4644,6 +4925,28 @@ func (c *client) setHeader(key, value string, msg []byte) []byte {
 	return bb.Bytes()
 }
 
+// removeHeader returns a copy of msg without the header key, and updates the pub args.
+// If no other headers are left, the header is removed altogether.
+func (c *client) removeHeader(key string, msg []byte) []byte {
+	if c.pa.hdr <= LEN_CR_LF {
+		return msg
+	}
+	nmsg := removeHeaderIfPresent(copyBytes(msg[:c.pa.hdr-LEN_CR_LF]), key)
+	if len(nmsg) > 0 {
+		nmsg = append(nmsg, _CRLF_...)
+	}
+	nhdr := len(nmsg)
+	nmsg = append(nmsg, msg[c.pa.hdr:]...)
+	nsize := len(nmsg) - LEN_CR_LF
+	if nhdr > 0 {
+		c.pa.hdr, c.pa.hdb = nhdr, []byte(strconv.Itoa(nhdr))
+	} else {
+		c.pa.hdr, c.pa.hdb = -1, nil
+	}
+	c.pa.size, c.pa.szb = nsize, []byte(strconv.Itoa(nsize))
+	return nmsg
+}
+
 // Will return a copy of the value for the header denoted by key or nil if it does not exist.
 // If you know that it is safe to refer to the underlying hdr slice for the period that the
 // return value is used, then sliceHeader() will be faster.
@@ -4884,6 +5187,12 @@ func (c *client) processServiceImport(si *serviceImport, acc *Account, msg []byt
 		to = nsubj
 	}
 
+	// Check the allowed publishers of any stream in the exporting account capturing the subject.
+	if !isResponse && (c.kind == CLIENT || c.kind == LEAF) && !c.streamPublishAllowed(siAcc, to) {
+		c.pa = pacopy
+		return false
+	}
+
 	// Set previous service import to detect chaining.
 	lpsi := len(c.pa.psi)
 	hadPrevSi, share := lpsi > 0, si.share
@@ -5042,6 +5351,8 @@ func (c *client) processServiceImport(si *serviceImport, acc *Account, msg []byt
 	if si.rt != Singleton && didDeliver {
 		acc.mu.Lock()
 		si.ts = time.Now().UnixNano()
+		// The requestor got a response, so will not be timed out.
+		stopAndClearTimer(&si.ttmr)
 		acc.mu.Unlock()
 	}
 
@@ -5205,8 +5516,20 @@ func (c *client) processMsgResults(acc *Account, r *SublistResult, msg, deliver,
 
 	mt, traceOnly := c.isMsgTraceEnabled()
 
+	// Only set for client connections configured to yield on high fan-out.
+	var yield int
+	if c.fpol == FanoutYield {
+		yield = c.mfan
+	}
+
 	// Loop over all normal subscriptions that match.
-	for _, sub := range r.psubs {
+	for i, sub := range r.psubs {
+		// Flush what we have so far and let other go routines run
+		// before delivering the next batch.
+		if yield > 0 && i > 0 && i%yield == 0 {
+			c.flushClients(0)
+			runtime.Gosched()
+		}
 		// Check if this is a send to a ROUTER. We now process
 		// these after everything else.
 		switch sub.client.kind {
@@ -5252,6 +5575,12 @@ func (c *client) processMsgResults(acc *Account, r *SublistResult, msg, deliver,
 			} else {
 				dsubj = append(_dsubj[:0], sub.im.to...)
 			}
+			// Check the allowed publishers of any stream in the importing account capturing the subject.
+			// Messages from routes don't carry the identity of the publisher, so are not allowed.
+			if (c.kind == CLIENT || c.kind == LEAF || c.kind == ROUTER) && sub.client != nil && sub.client.acc != nil &&
+				!c.streamPublishAllowed(sub.client.acc, bytesToString(dsubj)) {
+				continue
+			}
 
 			if mt != nil {
 				mt.addStreamExportEvent(sub.client, dsubj)
@@ -5609,6 +5938,14 @@ sendToRoutesOrLeafs:
 			if len(c.pa.reply) > 0 && c.pa.hdr >= 0 {
 				dmsg, hset = c.checkLeafClientInfoHeader(msg)
 			}
+
+			// Tag the message with the clusters it passed through, to detect loops.
+			if c.srv != nil && c.srv.leafLoopMaxPath > 0 && dc.headers {
+				dmsg, hset = c.setLeafNodePathHeader(dmsg), true
+			}
+		} else if dc.kind == ROUTER && len(c.pa.leafPath) > 0 && dc.headers {
+			// Pass on the path of a message from a leafnode, for when it's sent to leafnodes from there.
+			dmsg, hset = c.setHeader(LeafNodePathHdr, string(c.pa.leafPath), dmsg), true
 		}
 
 		if mt != nil {
@@ -5667,6 +6004,155 @@ func (c *client) checkLeafClientInfoHeader(msg []byte) (dmsg []byte, setHdr bool
 	return dmsg, setHdr
 }
 
+// DeliveryAckHdr is set on messages delivered to subscriptions in accounts that require
+// delivery acks. The subscriber acknowledges the delivery by publishing to its value.
+const DeliveryAckHdr = "Nats-Delivery-Ack"
+
+// DefaultDeliveryAcksMaxOutstanding is the default maximum number of outstanding
+// deliveries tracked per subscription when an account requires delivery acks.
+const DefaultDeliveryAcksMaxOutstanding = 1024
+
+// deliveryAckPrefix is the prefix of the subjects deliveries are acknowledged on,
+// followed by the delivery sequence and the subscription's sid.
+var deliveryAckPrefix = []byte("$DACK.")
+
+// deliveryAcks tracks the deliveries to a subscription that still need to be acknowledged.
+// This is best-effort and in-memory only. Once max deliveries are outstanding, the oldest
+// one is no longer tracked. Protected by the subscription's client lock.
+type deliveryAcks struct {
+	max       int64
+	seq       uint64              // Sequence of the last delivery.
+	floor     uint64              // All deliveries up to here are either acked or untracked.
+	pending   map[uint64]struct{} // Outstanding deliveries.
+	acked     uint64
+	untracked uint64
+	// Scratch buffers for the rewritten message and its header, reused
+	// across deliveries since the outbound queue copies them.
+	buf []byte
+	mh  []byte
+}
+
+// Returns the sequence for a new delivery.
+func (da *deliveryAcks) track() uint64 {
+	if da.pending == nil {
+		da.pending = make(map[uint64]struct{})
+	}
+	if int64(len(da.pending)) >= da.max {
+		for seq := da.floor + 1; seq <= da.seq; seq++ {
+			if _, ok := da.pending[seq]; ok {
+				delete(da.pending, seq)
+				da.untracked++
+				da.floor = seq
+				break
+			}
+		}
+	}
+	da.seq++
+	da.pending[da.seq] = struct{}{}
+	return da.seq
+}
+
+// Returns true if the delivery was outstanding.
+func (da *deliveryAcks) ack(seq uint64) bool {
+	if _, ok := da.pending[seq]; !ok {
+		return false
+	}
+	delete(da.pending, seq)
+	da.acked++
+	for da.floor < da.seq {
+		if _, ok := da.pending[da.floor+1]; ok {
+			break
+		}
+		da.floor++
+	}
+	return true
+}
+
+// deliveryAckMsg tracks a delivery to the subscription, and returns the message header and
+// message with the subject to ack the delivery on added as a header, and the new header size.
+// The returned slices are only valid until the next delivery to the subscription.
+// Client lock should be held.
+func (sub *subscription) deliveryAckMsg(subject, reply, msg []byte, hdr int, prodIsMQTT bool) ([]byte, []byte, int) {
+	da := sub.dack
+	seq := da.track()
+
+	if hdr < 0 {
+		hdr = 0
+	}
+	buf := da.buf[:0]
+	if hdr > LEN_CR_LF {
+		// Removing the header in place is fine since we own the buffer.
+		buf = removeHeaderIfPresent(append(buf, msg[:hdr-LEN_CR_LF]...), DeliveryAckHdr)
+	}
+	if len(buf) == 0 {
+		buf = append(da.buf[:0], hdrLine...)
+	}
+	buf = append(buf, DeliveryAckHdr...)
+	buf = append(buf, ": "...)
+	buf = append(buf, deliveryAckPrefix...)
+	buf = strconv.AppendUint(buf, seq, 10)
+	buf = append(buf, '.')
+	buf = append(buf, sub.sid...)
+	buf = append(buf, _CRLF_+_CRLF_...)
+	nhdr := len(buf)
+	buf = append(buf, msg[hdr:]...)
+	da.buf = buf
+	size := len(buf)
+	if !prodIsMQTT {
+		size -= LEN_CR_LF
+	}
+
+	mh := append(da.mh[:0], "HMSG "...)
+	mh = append(mh, subject...)
+	mh = append(mh, ' ')
+	mh = append(mh, sub.sid...)
+	mh = append(mh, ' ')
+	if len(reply) > 0 {
+		mh = append(mh, reply...)
+		mh = append(mh, ' ')
+	}
+	mh = strconv.AppendInt(mh, int64(nhdr), 10)
+	mh = append(mh, ' ')
+	mh = strconv.AppendInt(mh, int64(size), 10)
+	mh = append(mh, _CRLF_...)
+	da.mh = mh
+	return mh, buf, nhdr
+}
+
+// processDeliveryAck processes an ack for a delivery to one of our subscriptions,
+// the subject is stripped from the ack prefix.
+// Lock should be held.
+func (c *client) processDeliveryAck(subject []byte) {
+	i := bytes.IndexByte(subject, btsep)
+	if i <= 0 {
+		return
+	}
+	seq := parseInt64(subject[:i])
+	if seq <= 0 {
+		return
+	}
+	if sub := c.subs[bytesToString(subject[i+1:])]; sub != nil && sub.dack != nil {
+		sub.dack.ack(uint64(seq))
+	}
+}
+
+// shadowPermitted returns true if the client's account has shadow permissions enabled,
+// in which case an operation denied by the user's permissions is logged but allowed.
+// Lock should be held.
+func (c *client) shadowPermitted(op string, subject, queue []byte) bool {
+	if c.kind != CLIENT || c.acc == nil || !c.acc.shadowPerms.Load() {
+		return false
+	}
+	if op == accessLogPub {
+		c.RateLimitWarnf("Shadow Permissions - Would deny Publish to %q", subject)
+	} else if queue != nil {
+		c.RateLimitWarnf("Shadow Permissions - Would deny Subscription to %q using queue %q", subject, queue)
+	} else {
+		c.RateLimitWarnf("Shadow Permissions - Would deny Subscription to %q", subject)
+	}
+	return true
+}
+
 func (c *client) pubPermissionViolation(subject []byte) {
 	errTxt := fmt.Sprintf("Permissions Violation for Publish to %q", subject)
 	if mt, _ := c.isMsgTraceEnabled(); mt != nil {
@@ -5674,6 +6160,21 @@ func (c *client) pubPermissionViolation(subject []byte) {
 	}
 	c.sendErr(errTxt)
 	c.Errorf("Publish Violation - Subject %q", subject)
+	c.logAccess(accessLogPub, subject, nil, false)
+}
+
+// pubLimitViolation rejects a publish for the given reason, logging it with logf if set.
+// Reported as a permissions violation since clients treat any other
+// error as fatal and close the connection.
+func (c *client) pubLimitViolation(subject []byte, reason string, logf func(format string, v ...any)) {
+	errTxt := fmt.Sprintf("Permissions Violation for Publish to %q, %s", subject, reason)
+	if mt, _ := c.isMsgTraceEnabled(); mt != nil {
+		mt.setIngressError(errTxt)
+	}
+	c.sendErr(errTxt)
+	if logf != nil {
+		logf("Publish Violation - Subject %q, %s", subject, reason)
+	}
 }
 
 func (c *client) subPermissionViolation(sub *subscription) {
@@ -5687,6 +6188,7 @@ func (c *client) subPermissionViolation(sub *subscription) {
 
 	c.sendErr(errTxt)
 	c.Errorf(logTxt)
+	c.logAccess(accessLogSub, sub.subject, sub.queue, false)
 }
 
 func (c *client) replySubjectViolation(reply []byte) {
@@ -5705,6 +6207,26 @@ func (c *client) maxTokensViolation(sub *subscription) {
 	c.Errorf(logTxt)
 }
 
+func (c *client) maxSubjectLenViolation(sub *subscription, limit int) {
+	errTxt := fmt.Sprintf("Permissions Violation for Subscription, subject length %d exceeds limit of %d", len(sub.subject), limit)
+	logTxt := fmt.Sprintf("Subscription Violation Subject Too Long - Length %d, Limit %d, SID %s", len(sub.subject), limit, sub.sid)
+	c.sendErr(errTxt)
+	c.Errorf(logTxt)
+}
+
+func (c *client) wildcardSubViolation(sub *subscription) {
+	errTxt := fmt.Sprintf("Permissions Violation for Subscription to %q, wildcard not allowed by account", sub.subject)
+	logTxt := fmt.Sprintf("Subscription Violation Wildcard Not Allowed - Subject %q, SID %s", sub.subject, sub.sid)
+	c.sendErr(errTxt)
+	c.Errorf(logTxt)
+}
+
+func (c *client) subsChurnViolation(sub *subscription) {
+	errTxt := fmt.Sprintf("Permissions Violation for Subscription to %q, subscription churn rate exceeded", sub.subject)
+	c.sendErr(errTxt)
+	c.rateLimitFormatWarnf("Subscription Violation Churn Rate Exceeded - Subject %q, SID %s", sub.subject, sub.sid)
+}
+
 func (c *client) processPingTimer() {
 	c.mu.Lock()
 	c.ping.tmr = nil
@@ -1123,6 +1123,213 @@ func TestClientSetPermissionsClearsStaleMsgDenyState(t *testing.T) {
 	require_True(t, c.mperms == nil)
 }
 
+func TestClientShadowPermissions(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		listen: 127.0.0.1:-1
+		accounts {
+			A {
+				shadow_permissions: true
+				users = [{
+					user: a, password: p
+					permissions {
+						publish { allow: "allowed" }
+						subscribe { allow: ["allowed", "secret.*"], deny: "secret.foo" }
+					}
+				}]
+			}
+			B {
+				users = [{
+					user: b, password: p
+					permissions {
+						publish { allow: "allowed" }
+						subscribe { allow: "allowed" }
+					}
+				}]
+			}
+		}
+	`))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	l := &captureWarnLogger{warn: make(chan string, 10)}
+	s.SetLogger(l, false, false)
+
+	expectWarn := func(expected string) {
+		t.Helper()
+		select {
+		case w := <-l.warn:
+			if !strings.Contains(w, expected) {
+				t.Fatalf("Expected warning %q, got %q", expected, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Did not get warning %q", expected)
+		}
+	}
+
+	// Operations denied by permissions succeed in the shadowed account, but are logged.
+	nc := natsConnect(t, s.ClientURL(), nats.UserInfo("a", "p"))
+	defer nc.Close()
+	sub := natsSubSync(t, nc, "denied")
+	expectWarn(`Would deny Subscription to "denied"`)
+	qsub := natsQueueSubSync(t, nc, "denied", "q")
+	expectWarn(`Would deny Subscription to "denied" using queue "q"`)
+	natsPub(t, nc, "denied", []byte("ok"))
+	expectWarn(`Would deny Publish to "denied"`)
+	natsNexMsg(t, sub, time.Second)
+	natsNexMsg(t, qsub, time.Second)
+
+	// Deliveries matching a deny clause are allowed and logged as well.
+	wsub := natsSubSync(t, nc, "secret.*")
+	natsPub(t, nc, "secret.foo", []byte("ok"))
+	expectWarn(`Would deny Publish to "secret.foo"`)
+	expectWarn(`Would deny Subscription to "secret.foo"`)
+	natsNexMsg(t, wsub, time.Second)
+
+	// Permissions are still enforced in other accounts.
+	errCh := make(chan error, 10)
+	ncb := natsConnect(t, s.ClientURL(), nats.UserInfo("b", "p"),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) { errCh <- err }))
+	defer ncb.Close()
+	natsSubSync(t, ncb, "denied")
+	natsFlush(t, ncb)
+	select {
+	case err := <-errCh:
+		require_Contains(t, err.Error(), "Permissions Violation for Subscription")
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a permissions violation")
+	}
+	select {
+	case w := <-l.warn:
+		t.Fatalf("Unexpected warning %q", w)
+	default:
+	}
+}
+
+func TestClientDeliveryAcks(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		listen: 127.0.0.1:-1
+		accounts {
+			A {
+				delivery_acks { max_outstanding: 4 }
+				users = [{user: a, password: p}]
+			}
+			B {
+				users = [{user: b, password: p}]
+			}
+		}
+	`))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	nc := natsConnect(t, s.ClientURL(), nats.UserInfo("a", "p"))
+	defer nc.Close()
+	sub := natsSubSync(t, nc, "foo")
+	natsFlush(t, nc)
+
+	pub := natsConnect(t, s.ClientURL(), nats.UserInfo("a", "p"))
+	defer pub.Close()
+	for i := range 5 {
+		m := nats.NewMsg("foo")
+		m.Data = []byte("ok")
+		if i == 0 {
+			// Publishers can not set the ack subject themselves.
+			m.Header.Set(DeliveryAckHdr, "bar")
+			m.Header.Set("X-Keep", "1")
+		}
+		require_NoError(t, pub.PublishMsg(m))
+	}
+	natsFlush(t, pub)
+
+	var acks []string
+	for i := range 5 {
+		m := natsNexMsg(t, sub, time.Second)
+		require_Equal(t, string(m.Data), "ok")
+		ack := m.Header.Get(DeliveryAckHdr)
+		require_Equal(t, ack, fmt.Sprintf("$DACK.%d.%s", i+1, "1"))
+		if i == 0 {
+			require_Len(t, len(m.Header.Values(DeliveryAckHdr)), 1)
+			require_Equal(t, m.Header.Get("X-Keep"), "1")
+		}
+		acks = append(acks, ack)
+	}
+
+	// The added header is accounted for in the outbound bytes.
+	cz, err := s.Connz(nil)
+	require_NoError(t, err)
+	pcid, err := pub.GetClientID()
+	require_NoError(t, err)
+	scid, err := nc.GetClientID()
+	require_NoError(t, err)
+	var in, out int64
+	for _, ci := range cz.Conns {
+		switch ci.Cid {
+		case pcid:
+			in = ci.InBytes
+		case scid:
+			out = ci.OutBytes
+		}
+	}
+	added := -len(DeliveryAckHdr + ": bar\r\n")
+	for _, ack := range acks {
+		added += len(DeliveryAckHdr + ": " + ack + "\r\n")
+	}
+	// Only the first message was published with headers.
+	added += 4 * len(hdrLine+_CRLF_)
+	require_Equal(t, out, in+int64(added))
+
+	deliveryAcks := func() *SubDeliveryAcks {
+		t.Helper()
+		cz, err := s.Connz(&ConnzOptions{SubscriptionsDetail: true})
+		require_NoError(t, err)
+		for _, ci := range cz.Conns {
+			for _, sd := range ci.SubsDetail {
+				if sd.Subject == "foo" {
+					require_NotNil(t, sd.DeliveryAcks)
+					return sd.DeliveryAcks
+				}
+			}
+		}
+		t.Fatal("Subscription not found")
+		return nil
+	}
+
+	// Only the last 4 deliveries are tracked.
+	da := deliveryAcks()
+	require_Equal(t, da.Delivered, 5)
+	require_Equal(t, da.Acked, 0)
+	require_Equal(t, da.Outstanding, 4)
+	require_Equal(t, da.Untracked, 1)
+
+	// Acks are consumed by the server, an untracked or duplicate ack is ignored.
+	ackSub := natsSubSync(t, pub, "$DACK.>")
+	natsFlush(t, pub)
+	for _, ack := range []string{acks[0], acks[1], acks[3], acks[3]} {
+		natsPub(t, nc, ack, nil)
+	}
+	natsFlush(t, nc)
+	da = deliveryAcks()
+	require_Equal(t, da.Acked, 2)
+	require_Equal(t, da.Outstanding, 2)
+	_, err = ackSub.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	// Deliveries in other accounts are not tracked.
+	ncb := natsConnect(t, s.ClientURL(), nats.UserInfo("b", "p"))
+	defer ncb.Close()
+	bsub := natsSubSync(t, ncb, "foo")
+	natsFlush(t, ncb)
+	natsPub(t, ncb, "foo", []byte("ok"))
+	m := natsNexMsg(t, bsub, time.Second)
+	require_True(t, m.Header == nil || m.Header.Get(DeliveryAckHdr) == _EMPTY_)
+
+	conf = createConfFile(t, []byte(`
+		accounts { A { delivery_acks { max_outstanding: 0 } } }
+	`))
+	_, err = ProcessConfigFile(conf)
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "Expected a positive number")
+}
+
 func TestClientPubWithQueueSubNoEcho(t *testing.T) {
 	opts := DefaultOptions()
 	s := RunServer(opts)
@@ -2156,6 +2363,78 @@ func TestResponsePermissions(t *testing.T) {
 	}
 }
 
+func TestResponsePermissionsMaxInFlight(t *testing.T) {
+	opts := DefaultOptions()
+	u1 := &User{
+		Username:    "service",
+		Password:    "pwd",
+		Permissions: &Permissions{Response: &ResponsePermission{MaxMsgs: 1, Expires: time.Hour, MaxInFlight: 5}},
+	}
+	u2 := &User{Username: "ivan", Password: "pwd"}
+	opts.Users = []*User{u1, u2}
+	s := RunServer(opts)
+	defer s.Shutdown()
+
+	svcNC := natsConnect(t, fmt.Sprintf("nats://service:pwd@%s:%d", opts.Host, opts.Port))
+	defer svcNC.Close()
+	reqSub := natsSubSync(t, svcNC, "request")
+	natsFlush(t, svcNC)
+
+	nc := natsConnect(t, fmt.Sprintf("nats://ivan:pwd@%s:%d", opts.Host, opts.Port))
+	defer nc.Close()
+
+	// Requests within the limit are answered as usual.
+	for i := 0; i < 20; i++ {
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := nc.Request("request", []byte("req"), time.Second)
+			errCh <- err
+		}()
+		req := natsNexMsg(t, reqSub, time.Second)
+		require_NoError(t, req.Respond([]byte("reply")))
+		require_NoError(t, <-errCh)
+	}
+
+	// Fill up the in-flight requests without responding.
+	replySub := natsSubSync(t, nc, "reply.*")
+	for i := 0; i < 10; i++ {
+		natsPubReq(t, nc, "request", fmt.Sprintf("reply.%d", i), []byte("req"))
+	}
+	natsFlush(t, nc)
+	var reqs []*nats.Msg
+	for i := 0; i < 5; i++ {
+		reqs = append(reqs, natsNexMsg(t, reqSub, time.Second))
+	}
+	if msg, err := reqSub.NextMsg(100 * time.Millisecond); err == nil {
+		t.Fatalf("Expected no more than 5 requests to be delivered, got %q", msg.Reply)
+	}
+	// The others got a no responders status.
+	for i := 0; i < 5; i++ {
+		_, err := replySub.NextMsg(time.Second)
+		require_Error(t, err, nats.ErrNoResponders)
+	}
+
+	// Requests above the limit get a no responders status.
+	_, err := nc.Request("request", []byte("req"), time.Second)
+	require_Error(t, err, nats.ErrNoResponders)
+
+	// Responding frees up room for new requests.
+	for _, req := range reqs[:2] {
+		require_NoError(t, req.Respond([]byte("reply")))
+		natsNexMsg(t, replySub, time.Second)
+	}
+	natsFlush(t, svcNC)
+	for i := 0; i < 3; i++ {
+		natsPubReq(t, nc, "request", fmt.Sprintf("reply.new%d", i), []byte("req"))
+	}
+	natsFlush(t, nc)
+	natsNexMsg(t, reqSub, time.Second)
+	natsNexMsg(t, reqSub, time.Second)
+	if msg, err := reqSub.NextMsg(100 * time.Millisecond); err == nil {
+		t.Fatalf("Expected no more than 2 requests to be delivered, got %q", msg.Reply)
+	}
+}
+
 func TestPingNotSentTooSoon(t *testing.T) {
 	opts := DefaultOptions()
 	s := RunServer(opts)
@@ -2819,6 +3098,64 @@ func TestClientAuthRequiredNoAuthUser(t *testing.T) {
 	}
 }
 
+func TestClientNoAuthUserGuestAccount(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		listen: 127.0.0.1:-1
+		accounts: {
+			APP: { users: [ { user: app, password: pass } ] }
+			GUEST: { users: [ { user: guest, permissions: { publish: "public.>", subscribe: "public.>" } } ] }
+		}
+		no_auth_user: guest
+	`))
+
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	// Connecting with no credentials lands in the guest account.
+	nc, err := nats.Connect(s.ClientURL(), nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, _ error) {}))
+	require_NoError(t, err)
+	defer nc.Close()
+
+	cz, err := s.Connz(&ConnzOptions{Username: true})
+	require_NoError(t, err)
+	require_Len(t, len(cz.Conns), 1)
+	require_Equal(t, cz.Conns[0].Account, "GUEST")
+	require_Equal(t, cz.Conns[0].AuthorizedUser, "guest")
+
+	// Credentials are still honored, and bad ones are still rejected.
+	anc, err := nats.Connect(s.ClientURL(), nats.UserInfo("app", "pass"))
+	require_NoError(t, err)
+	defer anc.Close()
+	_, err = nats.Connect(s.ClientURL(), nats.UserInfo("app", "wrong"))
+	require_Error(t, err)
+
+	// The application account is isolated from the guest account.
+	asub := natsSubSync(t, anc, "public.>")
+	natsFlush(t, anc)
+
+	sub := natsSubSync(t, nc, "public.>")
+	natsPub(t, nc, "public.foo", []byte("hello"))
+	natsNexMsg(t, sub, time.Second)
+	_, err = asub.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	// The guest permissions are enforced.
+	natsPub(t, nc, "private.foo", []byte("hello"))
+	nc.Flush()
+	err = nc.LastError()
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "Permissions Violation")
+
+	nc2, err := nats.Connect(s.ClientURL(), nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, _ error) {}))
+	require_NoError(t, err)
+	defer nc2.Close()
+	natsSubSync(t, nc2, "private.>")
+	nc2.Flush()
+	err = nc2.LastError()
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "Permissions Violation")
+}
+
 func TestClientUserInfoReq(t *testing.T) {
 	conf := createConfFile(t, []byte(`
 		listen: 127.0.0.1:-1
@@ -3405,6 +3742,65 @@ func TestTLSHandshakeTimerTimeoutLogLevel(t *testing.T) {
 	}
 }
 
+func TestTLSHandshakeTimeoutPerConnectionType(t *testing.T) {
+	tlsBlock := func(timeout string) string {
+		return fmt.Sprintf(`tls {
+			cert_file: "../test/configs/certs/server-cert.pem"
+			key_file: "../test/configs/certs/server-key.pem"
+			timeout: %s
+		}`, timeout)
+	}
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: "127.0.0.1:-1"
+		accounts { SYS: {} }
+		system_account: SYS
+		%s
+		cluster {
+			name: "A"
+			listen: "127.0.0.1:-1"
+			%s
+		}
+		gateway {
+			name: "A"
+			listen: "127.0.0.1:-1"
+			%s
+		}
+		leafnodes {
+			listen: "127.0.0.1:-1"
+			%s
+		}
+	`, tlsBlock("0.25"), tlsBlock("0.5"), tlsBlock("0.75"), tlsBlock("1"))))
+	s, o := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	for _, test := range []struct {
+		name    string
+		port    int
+		timeout time.Duration
+	}{
+		{"client", o.Port, 250 * time.Millisecond},
+		{"route", o.Cluster.Port, 500 * time.Millisecond},
+		{"gateway", o.Gateway.Port, 750 * time.Millisecond},
+		{"leafnode", o.LeafNode.Port, time.Second},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Connect, but never start the TLS handshake.
+			conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", test.port))
+			require_NoError(t, err)
+			defer conn.Close()
+			start := time.Now()
+
+			// Drain whatever the server sends until it closes the connection.
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, err = io.Copy(io.Discard, conn)
+			require_NoError(t, err)
+			if elapsed := time.Since(start); elapsed < test.timeout || elapsed > test.timeout+500*time.Millisecond {
+				t.Fatalf("Expected handshake to be aborted after %v, took %v", test.timeout, elapsed)
+			}
+		})
+	}
+}
+
 func TestRemoveHeaderIfPrefixPresent(t *testing.T) {
 	hdr := []byte("NATS/1.0\r\n\r\n")
 
@@ -4592,3 +4988,167 @@ func TestClientMsgsMetric(t *testing.T) {
 		t.Fatalf("Did not get expected outClientMsg/Bytes for message sent on qsub")
 	}
 }
+
+func TestClientSubscriptionChurnLimit(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		listen: 127.0.0.1:-1
+		max_subscription_churn: 20
+	`))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	errCh := make(chan error, 100)
+	nc, err := nats.Connect(s.ClientURL(), nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+		errCh <- err
+	}))
+	require_NoError(t, err)
+	defer nc.Close()
+
+	// Regular usage stays below the limit.
+	var subs []*nats.Subscription
+	for i := 0; i < 5; i++ {
+		subs = append(subs, natsSubSync(t, nc, fmt.Sprintf("foo.%d", i)))
+	}
+	natsFlush(t, nc)
+	for i, sub := range subs {
+		natsPub(t, nc, fmt.Sprintf("foo.%d", i), []byte("hello"))
+		natsNexMsg(t, sub, time.Second)
+	}
+	select {
+	case err := <-errCh:
+		t.Fatalf("Unexpected error: %v", err)
+	default:
+	}
+
+	// Churning subscriptions trips the limiter, the connection stays usable.
+	for i := 0; i < 50; i++ {
+		sub := natsSubSync(t, nc, "churn")
+		require_NoError(t, sub.Unsubscribe())
+	}
+	natsFlush(t, nc)
+	select {
+	case err := <-errCh:
+		require_Contains(t, err.Error(), "permissions violation", "churn rate exceeded")
+	case <-time.After(2 * time.Second):
+		t.Fatal("Did not get churn rate error")
+	}
+	require_True(t, nc.IsConnected())
+
+	// Existing subscriptions are unaffected.
+	natsPub(t, nc, "foo.0", []byte("hello"))
+	natsNexMsg(t, subs[0], time.Second)
+
+	// Once the interval has passed subscriptions are accepted again.
+	time.Sleep(1100 * time.Millisecond)
+	for len(errCh) > 0 {
+		<-errCh
+	}
+	sub := natsSubSync(t, nc, "bar")
+	natsFlush(t, nc)
+	natsPub(t, nc, "bar", []byte("hello"))
+	natsNexMsg(t, sub, time.Second)
+	select {
+	case err := <-errCh:
+		t.Fatalf("Unexpected error: %v", err)
+	default:
+	}
+
+	// Unsubscribes are not refused.
+	for i := 0; i < 50; i++ {
+		natsSubSync(t, nc, "churn")
+	}
+	require_NoError(t, sub.Unsubscribe())
+	natsFlush(t, nc)
+	cid, err := nc.GetClientID()
+	require_NoError(t, err)
+	c := s.getClient(cid)
+	require_True(t, c != nil)
+	c.mu.Lock()
+	for _, csub := range c.subs {
+		if string(csub.subject) == "bar" {
+			c.mu.Unlock()
+			t.Fatal("Subscription still registered after unsubscribe")
+		}
+	}
+	c.mu.Unlock()
+}
+
+func TestClientMaxFanoutPolicy(t *testing.T) {
+	for _, policy := range []string{"yield", "advise", "reject"} {
+		t.Run(policy, func(t *testing.T) {
+			conf := createConfFile(t, []byte(fmt.Sprintf(`
+				listen: 127.0.0.1:-1
+				max_fanout: 100
+				fanout_policy: %s
+			`, policy)))
+			s, opts := RunServerWithConfig(conf)
+			defer s.Shutdown()
+			require_Equal(t, opts.FanoutPolicy.String(), policy)
+
+			// Spread a large fan-out over a few connections.
+			var count atomic.Int32
+			for i := 0; i < 5; i++ {
+				nc := natsConnect(t, s.ClientURL())
+				defer nc.Close()
+				for j := 0; j < 200; j++ {
+					natsSub(t, nc, "fanout", func(_ *nats.Msg) { count.Add(1) })
+				}
+				// A queue group only counts once.
+				natsQueueSub(t, nc, "fanout", "queue", func(_ *nats.Msg) { count.Add(1) })
+				natsFlush(t, nc)
+			}
+
+			errCh := make(chan error, 10)
+			nc, err := nats.Connect(s.ClientURL(), nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+				errCh <- err
+			}))
+			require_NoError(t, err)
+			defer nc.Close()
+
+			// Publishes below the maximum are never affected.
+			natsSub(t, nc, "small", func(m *nats.Msg) { m.Respond(nil) })
+			natsFlush(t, nc)
+			_, err = nc.Request("small", nil, time.Second)
+			require_NoError(t, err)
+
+			// While the fan-out is delivered, other connections keep being served.
+			other := natsConnect(t, s.ClientURL())
+			defer other.Close()
+			for i := 0; i < 5; i++ {
+				natsPub(t, nc, "fanout", []byte("hello"))
+			}
+			_, err = other.Request("small", nil, time.Second)
+			require_NoError(t, err)
+			natsFlush(t, nc)
+
+			if policy == "reject" {
+				select {
+				case err := <-errCh:
+					require_Contains(t, err.Error(), "permissions violation", "fan-out of 1001 exceeds maximum of 100")
+				case <-time.After(2 * time.Second):
+					t.Fatal("Did not get fan-out error")
+				}
+				time.Sleep(100 * time.Millisecond)
+				require_Equal(t, count.Load(), 0)
+				require_True(t, nc.IsConnected())
+				return
+			}
+			checkFor(t, 2*time.Second, 15*time.Millisecond, func() error {
+				if n := count.Load(); n != 5*1001 {
+					return fmt.Errorf("Expected %d messages, got %d", 5*1001, n)
+				}
+				return nil
+			})
+			select {
+			case err := <-errCh:
+				t.Fatalf("Unexpected error: %v", err)
+			default:
+			}
+		})
+	}
+
+	conf := createConfFile(t, []byte(`fanout_policy: drop`))
+	_, err := ProcessConfigFile(conf)
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "fanout_policy must be")
+}
@@ -1299,6 +1299,26 @@ func TestConfigCheck(t *testing.T) {
 			errorLine: 2,
 			errorPos:  5,
 		},
+		{
+			name: "invalid lame_duck_redirect_urls entry",
+			config: `
+				lame_duck_redirect_urls: ["127.0.0.1:4222", "127.0.0.1:abc"]
+			`,
+			err:       errors.New(`invalid lame_duck_redirect_urls entry "127.0.0.1:abc", expected host:port`),
+			errorLine: 2,
+			errorPos:  5,
+		},
+		{
+			name: "leafnode max_remotes_per_account should be positive",
+			config: `
+				leafnodes {
+					max_remotes_per_account: -1
+				}
+			`,
+			err:       errors.New(`invalid max_remotes_per_account of -1, needs to be positive`),
+			errorLine: 3,
+			errorPos:  6,
+		},
 		{
 			name: "when only setting TLS timeout for a leafnode remote",
 			config: `
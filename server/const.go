@@ -104,6 +104,10 @@ const (
 	// DEFAULT_MAX_CONNECTIONS is the default maximum connections allowed.
 	DEFAULT_MAX_CONNECTIONS = (64 * 1024)
 
+	// DEFAULT_MAX_CONNECT_BACKLOG is the default number of accepted client connections
+	// waiting to be processed when the connect rate is limited.
+	DEFAULT_MAX_CONNECT_BACKLOG = 4096
+
 	// TLS_TIMEOUT is the TLS wait time.
 	TLS_TIMEOUT = 2 * time.Second
 
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"math/rand"
 	"os"
@@ -115,10 +116,36 @@ type ConsumerConfig struct {
 	DeliverSubject string        `json:"deliver_subject,omitempty"`
 	DeliverGroup   string        `json:"deliver_group,omitempty"`
 	Heartbeat      time.Duration `json:"idle_heartbeat,omitempty"`
+	// MaxInFlight limits the number of unacknowledged deliveries in flight for the
+	// consumer as a whole, across all subscribers of the deliver group. Messages that
+	// are waiting to be redelivered are not counted as being in flight.
+	MaxInFlight int `json:"max_in_flight,omitempty"`
+	// NoInterestPolicy determines what happens when the deliver subject has no subscribers.
+	NoInterestPolicy NoInterestPolicy `json:"no_interest_policy,omitempty"`
+	// DeliverBatchInterval accumulates deliveries and sends them as a single batch message every interval,
+	// or earlier once DeliverBatchMaxMsgs deliveries are batched. Every message in the batch is acked on its own.
+	DeliverBatchInterval time.Duration `json:"deliver_batch_interval,omitempty"`
+	DeliverBatchMaxMsgs  int           `json:"deliver_batch_max_msgs,omitempty"`
 
 	// Ephemeral inactivity threshold.
 	InactiveThreshold time.Duration `json:"inactive_threshold,omitempty"`
 
+	// UnackedRetention is how long messages of an interest based stream that the consumer did not
+	// acknowledge are retained after it is deleted, in case it is recreated. When zero the consumer's
+	// interest is released immediately on deletion, allowing those messages to be removed.
+	UnackedRetention time.Duration `json:"unacked_retention,omitempty"`
+
+	// DeliverDedupHeader is a header whose value identifies logically identical messages. Messages with
+	// the same value as a message delivered before them within DeliverDedupWindow are not delivered.
+	DeliverDedupHeader string        `json:"deliver_dedup_header,omitempty"`
+	DeliverDedupWindow time.Duration `json:"deliver_dedup_window,omitempty"`
+
+	// RetentionSkipPolicy determines what happens when the stream's retention removes messages
+	// the consumer did not deliver yet.
+	RetentionSkipPolicy RetentionSkipPolicy `json:"retention_skip_policy,omitempty"`
+	// RetentionSkipSubject is an optional subject such skips are also notified on.
+	RetentionSkipSubject string `json:"retention_skip_subject,omitempty"`
+
 	// Generally inherited by parent stream and other markers, now can be configured directly.
 	Replicas int `json:"num_replicas"`
 	// Force memory storage.
@@ -152,6 +179,8 @@ type CreateConsumerRequest struct {
 	Config   ConsumerConfig `json:"config"`
 	Action   ConsumerAction `json:"action"`
 	Pedantic bool           `json:"pedantic,omitempty"`
+	// ValidateOnly runs all checks of the create or update request without applying it.
+	ValidateOnly bool `json:"validate_only,omitempty"`
 }
 
 type ConsumerAction int
@@ -290,6 +319,121 @@ func (pp *PriorityPolicy) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// NoInterestPolicy determines how a push consumer behaves when its deliver subject has no subscribers.
+type NoInterestPolicy int
+
+const (
+	// NoInterestPause stops delivering messages until a subscriber appears. This is the default.
+	NoInterestPause NoInterestPolicy = iota
+	// NoInterestContinue sends an advisory when the last subscriber goes away, and keeps delivering messages.
+	NoInterestContinue
+)
+
+const (
+	NoInterestPauseJSONString    = `"pause"`
+	NoInterestContinueJSONString = `"continue"`
+)
+
+var (
+	NoInterestPauseJSONBytes    = []byte(NoInterestPauseJSONString)
+	NoInterestContinueJSONBytes = []byte(NoInterestContinueJSONString)
+)
+
+func (np NoInterestPolicy) String() string {
+	switch np {
+	case NoInterestContinue:
+		return NoInterestContinueJSONString
+	default:
+		return NoInterestPauseJSONString
+	}
+}
+
+func (np NoInterestPolicy) MarshalJSON() ([]byte, error) {
+	switch np {
+	case NoInterestPause:
+		return NoInterestPauseJSONBytes, nil
+	case NoInterestContinue:
+		return NoInterestContinueJSONBytes, nil
+	default:
+		return nil, fmt.Errorf("unknown no interest policy: %v", np)
+	}
+}
+
+func (np *NoInterestPolicy) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case NoInterestPauseJSONString:
+		*np = NoInterestPause
+	case NoInterestContinueJSONString:
+		*np = NoInterestContinue
+	default:
+		return fmt.Errorf("unknown no interest policy: %v", string(data))
+	}
+	return nil
+}
+
+// RetentionSkipPolicy determines how a consumer behaves when the stream's retention
+// removed messages it did not deliver yet, for example due to limits or max age.
+type RetentionSkipPolicy int
+
+const (
+	// RetentionSkipIgnore silently skips the removed messages. This is the default.
+	RetentionSkipIgnore RetentionSkipPolicy = iota
+	// RetentionSkipAdvise sends an advisory and skips the removed messages.
+	RetentionSkipAdvise
+	// RetentionSkipPause sends an advisory and stops delivering messages until the consumer is updated.
+	RetentionSkipPause
+)
+
+const (
+	RetentionSkipIgnoreJSONString = `"ignore"`
+	RetentionSkipAdviseJSONString = `"advise"`
+	RetentionSkipPauseJSONString  = `"pause"`
+)
+
+var (
+	RetentionSkipIgnoreJSONBytes = []byte(RetentionSkipIgnoreJSONString)
+	RetentionSkipAdviseJSONBytes = []byte(RetentionSkipAdviseJSONString)
+	RetentionSkipPauseJSONBytes  = []byte(RetentionSkipPauseJSONString)
+)
+
+func (rp RetentionSkipPolicy) String() string {
+	switch rp {
+	case RetentionSkipAdvise:
+		return RetentionSkipAdviseJSONString
+	case RetentionSkipPause:
+		return RetentionSkipPauseJSONString
+	default:
+		return RetentionSkipIgnoreJSONString
+	}
+}
+
+func (rp RetentionSkipPolicy) MarshalJSON() ([]byte, error) {
+	switch rp {
+	case RetentionSkipIgnore:
+		return RetentionSkipIgnoreJSONBytes, nil
+	case RetentionSkipAdvise:
+		return RetentionSkipAdviseJSONBytes, nil
+	case RetentionSkipPause:
+		return RetentionSkipPauseJSONBytes, nil
+	default:
+		return nil, fmt.Errorf("unknown retention skip policy: %v", rp)
+	}
+}
+
+func (rp *RetentionSkipPolicy) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case RetentionSkipIgnoreJSONString:
+		*rp = RetentionSkipIgnore
+	case RetentionSkipAdviseJSONString:
+		*rp = RetentionSkipAdvise
+	case RetentionSkipPauseJSONString:
+		*rp = RetentionSkipPause
+	default:
+		return fmt.Errorf("unknown retention skip policy: %v", string(data))
+	}
+	return nil
+}
+
 // DeliverPolicy determines how the consumer should select the first message to deliver.
 type DeliverPolicy int
 
@@ -430,6 +574,7 @@ type consumer struct {
 	sseq              uint64             // next stream sequence
 	subjf             subjectFilters     // subject filters and their sequences
 	filters           *gsl.SimpleSublist // When we have multiple filters we will use LoadNextMsgMulti and pass this in.
+	rg                *consumerReadGroup // Read group shared with consumers having the same filters, if any.
 	dseq              uint64             // delivered consumer sequence
 	adflr             uint64             // ack delivery floor
 	asflr             uint64             // ack store floor
@@ -456,6 +601,8 @@ type consumer struct {
 	nextMsgReqs       *ipQueue[*nextMsgReq]
 	resetSubj         string
 	maxp              int
+	maxif             int
+	ddup              *deliveryDedup
 	pblimit           int
 	maxpb             int
 	pbytes            int
@@ -472,8 +619,13 @@ type consumer struct {
 	rdc               map[uint64]uint64
 	replies           map[uint64]string
 	pendingDeliveries map[uint64]*jsPubMsg        // Messages that can be delivered after achieving quorum.
+	dbatch            []*jsPubMsg                 // Deliveries waiting to be sent in the next batch.
+	dbatchSz          int                         // Size of the framed deliveries in the next batch.
+	dbatchTmr         *time.Timer                 // Sends the next batch once the batch interval passes.
 	waitingDeliveries map[string]*waitingDelivery // (Optional) request timeout messages that need to wait for replicated deliveries first.
 	maxdc             uint64
+	rskip             uint64 // First sequence of the removed messages we are holding at, if paused on skip.
+	rsfseq            uint64 // Lowest sequence of the removed messages matching our filters we did not deliver yet.
 	waiting           *waitQueue
 	cfg               ConsumerConfig
 	ici               *ConsumerInfo
@@ -493,10 +645,15 @@ type consumer struct {
 	ackEventT         string
 	nakEventT         string
 	deliveryExcEventT string
+	firstDelEventT    string
+	firstDelivered    bool
 	created           time.Time
 	ldt               time.Time
 	lat               time.Time
 	lwqic             time.Time
+	htmr              *time.Timer // Hibernation check timer
+	hact              time.Time   // Last wake up, used for hibernation
+	hibernated        atomic.Bool // Go routines stopped while idle
 	closed            bool
 
 	// Clustered.
@@ -505,7 +662,7 @@ type consumer struct {
 	infoSub   *subscription
 	lqsent    time.Time
 	prm       map[string]struct{}
-	rsm       map[string]bool // Reset requests that need to be responded to on the internal sys account (if true).
+	rsm       map[string]bool // Reset and restore requests that need to be responded to on the internal sys account (if true).
 	prOk      bool
 	uch       chan struct{}
 	retention RetentionPolicy
@@ -578,8 +735,19 @@ const (
 	// JsDefaultPinnedTTL is the default grace period for the pinned consumer to send a new request before a new pin
 	// is picked by a server.
 	JsDefaultPinnedTTL = 2 * time.Minute
+	// JsDefaultDeliverDedupWindow is the default delivery dedup window if a dedup header is set.
+	JsDefaultDeliverDedupWindow = 2 * time.Minute
+	// JsMaxDeliverDedupWindow is the maximum delivery dedup window, to bound the dedup state kept.
+	JsMaxDeliverDedupWindow = time.Hour
+	// JsDefaultDeliverBatchMaxMsgs is the default maximum number of messages in a delivery batch.
+	JsDefaultDeliverBatchMaxMsgs = 1000
 )
 
+// JSDeliverBatchCount is the header holding the number of messages in a delivery batch.
+// The batch body holds the messages, each framed as
+// "<subject> <reply> <hdr_len> <total_len>\r\n<hdr><msg>\r\n".
+const JSDeliverBatchCount = "Nats-Deliver-Batch-Count"
+
 // Helper function to set consumer config defaults from above.
 func setConsumerConfigDefaults(config *ConsumerConfig, streamCfg *StreamConfig, lim *JSLimitOpts, accLim *JetStreamAccountLimits, pedantic bool) *ApiError {
 	// Setup default of -1, meaning no limit for MaxDeliver.
@@ -638,6 +806,18 @@ func setConsumerConfigDefaults(config *ConsumerConfig, streamCfg *StreamConfig,
 		}
 		config.PinnedTTL = 0
 	}
+	if config.DeliverDedupWindow < 0 {
+		if pedantic {
+			return NewJSPedanticError(errors.New("deliver_dedup_window must not be negative"))
+		}
+		config.DeliverDedupWindow = 0
+	}
+	if config.DeliverBatchInterval < 0 {
+		if pedantic {
+			return NewJSPedanticError(errors.New("deliver_batch_interval must not be negative"))
+		}
+		config.DeliverBatchInterval = 0
+	}
 
 	// Set to default if not specified.
 	if config.DeliverSubject == _EMPTY_ && config.MaxWaiting == 0 {
@@ -689,6 +869,14 @@ func setConsumerConfigDefaults(config *ConsumerConfig, streamCfg *StreamConfig,
 	if config.PriorityPolicy == PriorityPinnedClient && config.PinnedTTL == 0 {
 		config.PinnedTTL = JsDefaultPinnedTTL
 	}
+	// Likewise only if delivery dedup is used.
+	if config.DeliverDedupHeader != _EMPTY_ && config.DeliverDedupWindow == 0 {
+		config.DeliverDedupWindow = JsDefaultDeliverDedupWindow
+	}
+	// And delivery batching.
+	if config.DeliverBatchInterval > 0 && config.DeliverBatchMaxMsgs == 0 {
+		config.DeliverBatchMaxMsgs = JsDefaultDeliverBatchMaxMsgs
+	}
 
 	// Set default values for flow control policy.
 	if config.AckPolicy == AckFlowControl && !pedantic {
@@ -750,6 +938,40 @@ func checkConsumerCfg(
 	if config.AckWait < 0 {
 		return NewJSConsumerAckWaitNegativeError()
 	}
+	if config.UnackedRetention < 0 {
+		return NewJSConsumerUnackedRetentionNegativeError()
+	}
+	if config.DeliverDedupHeader == _EMPTY_ && config.DeliverDedupWindow > 0 {
+		return NewJSConsumerDeliverDedupInvalidError(errors.New("window requires a header"))
+	}
+	if config.DeliverDedupWindow > JsMaxDeliverDedupWindow {
+		return NewJSConsumerDeliverDedupInvalidError(fmt.Errorf("window can not exceed %v", JsMaxDeliverDedupWindow))
+	}
+	if config.DeliverBatchMaxMsgs < 0 {
+		return NewJSConsumerDeliverBatchInvalidError(errors.New("max messages can not be negative"))
+	}
+	if config.DeliverBatchInterval > 0 {
+		if config.DeliverSubject == _EMPTY_ {
+			return NewJSConsumerDeliverBatchInvalidError(errors.New("requires a push consumer"))
+		}
+		if config.FlowControl {
+			return NewJSConsumerDeliverBatchInvalidError(errors.New("can not be used with flow control"))
+		}
+		// Batched messages are already pending, so need to be sent before they are redelivered.
+		if config.AckWait > 0 && config.DeliverBatchInterval >= config.AckWait {
+			return NewJSConsumerDeliverBatchInvalidError(errors.New("interval must be less than ack wait"))
+		}
+	} else if config.DeliverBatchMaxMsgs > 0 {
+		return NewJSConsumerDeliverBatchInvalidError(errors.New("max messages requires an interval"))
+	}
+	if config.RetentionSkipSubject != _EMPTY_ {
+		if config.RetentionSkipPolicy == RetentionSkipIgnore {
+			return NewJSConsumerRetentionSkipInvalidError(errors.New("subject requires a policy other than ignore"))
+		}
+		if !IsValidPublishSubject(config.RetentionSkipSubject) {
+			return NewJSConsumerRetentionSkipInvalidError(errors.New("subject must be a valid literal subject"))
+		}
+	}
 
 	// Ack Flow Control policy requires push-based flow-controlled consumer.
 	if config.AckPolicy == AckFlowControl {
@@ -802,6 +1024,12 @@ func checkConsumerCfg(
 		if config.MaxAckPending > 0 && config.AckPolicy == AckNone {
 			return NewJSConsumerMaxPendingAckPolicyRequiredError()
 		}
+		if config.MaxInFlight < 0 {
+			return NewJSConsumerMaxInFlightNegativeError()
+		}
+		if config.MaxInFlight > 0 && config.AckPolicy == AckNone {
+			return NewJSConsumerMaxInFlightAckPolicyRequiredError()
+		}
 		if config.Heartbeat > 0 && config.Heartbeat < 100*time.Millisecond {
 			return NewJSConsumerSmallHeartbeatError()
 		}
@@ -816,6 +1044,12 @@ func checkConsumerCfg(
 		if config.MaxWaiting < 0 {
 			return NewJSConsumerMaxWaitingNegativeError()
 		}
+		if config.MaxInFlight != 0 {
+			return NewJSConsumerMaxInFlightRequiresPushError()
+		}
+		if config.NoInterestPolicy != NoInterestPause {
+			return NewJSConsumerNoInterestPolicyRequiresPushError()
+		}
 		if config.Heartbeat > 0 {
 			return NewJSConsumerHBRequiresPushError()
 		}
@@ -1004,14 +1238,20 @@ func checkConsumerCfg(
 }
 
 func (mset *stream) addConsumerWithAction(config *ConsumerConfig, action ConsumerAction, pedantic bool) (*consumer, error) {
-	return mset.addConsumerWithAssignment(config, _EMPTY_, nil, false, action, pedantic)
+	return mset.addConsumerWithAssignment(config, _EMPTY_, nil, false, action, pedantic, false)
+}
+
+// checkAddConsumer runs all checks of adding or updating a consumer, without applying it.
+func (mset *stream) checkAddConsumer(config *ConsumerConfig, action ConsumerAction, pedantic bool) error {
+	_, err := mset.addConsumerWithAssignment(config, _EMPTY_, nil, false, action, pedantic, true)
+	return err
 }
 
 func (mset *stream) addConsumer(config *ConsumerConfig) (*consumer, error) {
 	return mset.addConsumerWithAction(config, ActionCreateOrUpdate, false)
 }
 
-func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname string, ca *consumerAssignment, isRecovering bool, action ConsumerAction, pedantic bool) (*consumer, error) {
+func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname string, ca *consumerAssignment, isRecovering bool, action ConsumerAction, pedantic, validateOnly bool) (*consumer, error) {
 	// Check if this stream has closed.
 	if mset.closed.Load() {
 		return nil, NewJSStreamInvalidError()
@@ -1032,7 +1272,7 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 		return nil, NewJSConsumerConfigRequiredError()
 	}
 
-	selectedLimits, _, _, _ := acc.selectLimits(config.replicas(&cfg))
+	selectedLimits, tier, _, _ := acc.selectLimits(config.replicas(&cfg))
 	if selectedLimits == nil {
 		return nil, NewJSNoLimitsError()
 	}
@@ -1069,6 +1309,23 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 	}
 	c.mu.Unlock()
 
+	standalone := !s.JetStreamIsClustered() && s.standAloneMode()
+
+	// If we're standalone and not recovering, check the limit on the number of consumers across all
+	// streams of the account. This is done before grabbing the stream lock since it looks at all streams.
+	if standalone && !isRecovering && selectedLimits.MaxTotalConsumers > 0 && !config.Direct && !config.Sourcing {
+		// Don't count the consumer if it already exists, since this is likely an update.
+		oname := config.Name
+		if isDurableConsumer(config) {
+			oname = config.Durable
+		}
+		if oname == _EMPTY_ || mset.lookupConsumer(oname) == nil {
+			if jsa.countConsumers(tier, config.replicas(&cfg)) >= selectedLimits.MaxTotalConsumers {
+				return nil, NewJSMaximumTotalConsumersLimitError()
+			}
+		}
+	}
+
 	// Hold mset lock here.
 	mset.mu.Lock()
 	if mset.client == nil || mset.store == nil || mset.consumers == nil {
@@ -1102,8 +1359,13 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 				}
 			}
 			mset.mu.Unlock()
-			err := eo.updateConfig(config)
-			if err == nil {
+			var err error
+			if validateOnly {
+				ocfg := eo.config()
+				if err = eo.acc.checkNewConsumerConfig(&ocfg, config); err == nil {
+					return nil, nil
+				}
+			} else if err = eo.updateConfig(config); err == nil {
 				return eo, nil
 			}
 			return nil, NewJSConsumerCreateError(err, Unless(err))
@@ -1114,8 +1376,6 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 		return nil, NewJSConsumerDoesNotExistError()
 	}
 
-	standalone := !s.JetStreamIsClustered() && s.standAloneMode()
-
 	// If we're clustered we've already done this check, only do this if we're a standalone server.
 	// But if we're standalone, only enforce if we're not recovering, since the MaxConsumers could've
 	// been updated while we already had more consumers on disk.
@@ -1174,6 +1434,11 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 		}
 	}
 
+	if validateOnly {
+		mset.mu.Unlock()
+		return nil, nil
+	}
+
 	// Set name, which will be durable name if set, otherwise we create one at random.
 	o := &consumer{
 		mset:      mset,
@@ -1193,6 +1458,7 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 		sfreq:     int32(sampleFreq),
 		maxdc:     uint64(max(config.MaxDeliver, 0)), // MaxDeliver is negative (-1) when infinite.
 		maxp:      config.MaxAckPending,
+		maxif:     config.MaxInFlight,
 		retention: cfg.Retention,
 		created:   time.Now().UTC(),
 	}
@@ -1247,6 +1513,7 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 	o.ackEventT = JSMetricConsumerAckPre + "." + o.stream + "." + o.name
 	o.nakEventT = JSAdvisoryConsumerMsgNakPre + "." + o.stream + "." + o.name
 	o.deliveryExcEventT = JSAdvisoryConsumerMaxDeliveryExceedPre + "." + o.stream + "." + o.name
+	o.firstDelEventT = JSAdvisoryConsumerFirstDeliveryPre + "." + o.stream + "." + o.name
 
 	if !isValidAssetName(o.name) {
 		mset.mu.Unlock()
@@ -1672,6 +1939,10 @@ func (o *consumer) setLeader(isLeader bool) error {
 		o.mu.Lock()
 		o.rdq = nil
 		o.rdqi.Empty()
+		// Share our reads with consumers having the same filters.
+		if o.rg == nil {
+			o.rg = mset.joinReadGroup(o.subjf)
+		}
 
 		// Restore our saved state.
 		// During non-leader status we just update our underlying store when not clustered.
@@ -1789,25 +2060,13 @@ func (o *consumer) setLeader(isLeader bool) error {
 			"consumer": o.name,
 		}
 
-		// Now start up Go routine to deliver msgs.
-		go func() {
-			setGoRoutineLabels(labels)
-			o.loopAndGatherMsgs(qch)
-		}()
-
-		// Now start up Go routine to process acks.
-		go func() {
-			setGoRoutineLabels(labels)
-			o.processInboundAcks(qch)
-		}()
+		// Now start up Go routines to deliver msgs and process acks and requests.
+		o.startRoutines(qch, labels, pullMode)
 
-		if pullMode {
-			// Now start up Go routine to process inbound next message requests.
-			go func() {
-				setGoRoutineLabels(labels)
-				o.processInboundNextMsgReqs(qch)
-			}()
-		}
+		// Check periodically if we can hibernate.
+		o.mu.Lock()
+		o.setHibernateTimer()
+		o.mu.Unlock()
 
 		// If we are R>1 spin up our proposal loop.
 		if node != nil {
@@ -1828,10 +2087,14 @@ func (o *consumer) setLeader(isLeader bool) error {
 			close(o.qch)
 			o.qch = nil
 		}
+		o.leaveReadGroup()
 		// Stop any inactivity timers. Should only be running on leaders.
 		stopAndClearTimer(&o.dtmr)
 		// Stop any unpause timers. Should only be running on leaders.
 		stopAndClearTimer(&o.uptmr)
+		// Stop the hibernation timer. Should only be running on leaders.
+		stopAndClearTimer(&o.htmr)
+		o.hibernated.Store(false)
 		// Make sure to clear out any re-deliver queues
 		o.stopAndClearPtmr()
 		o.rdc = nil
@@ -1840,6 +2103,7 @@ func (o *consumer) setLeader(isLeader bool) error {
 		o.pending = nil
 		o.rsm = nil
 		o.resetPendingDeliveries()
+		o.resetDeliveryBatch()
 		// Reset num pending, these are only authoritative on the leader.
 		o.npc, o.npf = 0, 0
 		// ok if they are nil, we protect inside unsubscribe()
@@ -1921,6 +2185,11 @@ func (o *consumer) sendAdvisory(subject string, e any) {
 		return
 	}
 
+	// Drop the advisory if the account is over its advisory rate limit.
+	if !o.acc.allowAdvisory(o.srv, subject) {
+		return
+	}
+
 	j, err := json.Marshal(e)
 	if err != nil {
 		return
@@ -2026,6 +2295,91 @@ func (o *consumer) sendPauseAdvisoryLocked(cfg *ConsumerConfig) {
 	o.sendAdvisory(subj, e)
 }
 
+func (o *consumer) sendNoInterestAdvisoryLocked() {
+	e := JSConsumerNoInterestAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSConsumerNoInterestAdvisoryType,
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Stream:         o.stream,
+		Consumer:       o.name,
+		DeliverSubject: o.cfg.DeliverSubject,
+		Domain:         o.srv.getOpts().JetStreamDomain,
+	}
+
+	subj := JSAdvisoryConsumerNoInterestPre + "." + o.stream + "." + o.name
+	o.sendAdvisory(subj, e)
+}
+
+func (o *consumer) sendRetentionSkipAdvisoryLocked(first, last uint64, paused bool) {
+	e := JSConsumerRetentionSkipAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSConsumerRetentionSkipAdvisoryType,
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Stream:   o.stream,
+		Consumer: o.name,
+		FirstSeq: first,
+		LastSeq:  last,
+		Paused:   paused,
+		Domain:   o.srv.getOpts().JetStreamDomain,
+	}
+
+	subj := JSAdvisoryConsumerRetentionSkipPre + "." + o.stream + "." + o.name
+	o.sendAdvisory(subj, e)
+	if o.cfg.RetentionSkipSubject != _EMPTY_ {
+		o.sendAdvisory(o.cfg.RetentionSkipSubject, e)
+	}
+}
+
+// checkRetentionSkip checks if the stream's retention removed messages we did not deliver yet,
+// and handles it according to our retention skip policy. For filtered consumers only removed
+// messages that matched our filters count, others are skipped silently.
+// Returns true if we should hold instead of skipping.
+// Lock should be held.
+func (o *consumer) checkRetentionSkip() bool {
+	if o.rskip > 0 {
+		return true
+	}
+	var state StreamState
+	o.mset.store.FastState(&state)
+	if o.sseq == 0 || o.sseq >= state.FirstSeq {
+		return false
+	}
+	if o.isFiltered() && (o.rsfseq < o.sseq || o.rsfseq >= state.FirstSeq) {
+		o.skipRetentionGap()
+		return false
+	}
+	if o.cfg.RetentionSkipPolicy == RetentionSkipPause {
+		o.rskip = o.sseq
+		o.sendRetentionSkipAdvisoryLocked(o.sseq, state.FirstSeq-1, true)
+		return true
+	}
+	o.sendRetentionSkipAdvisoryLocked(o.sseq, state.FirstSeq-1, false)
+	o.skipRetentionGap()
+	return false
+}
+
+// skipRetentionGap moves us past messages removed by the stream's retention.
+// Lock should be held.
+func (o *consumer) skipRetentionGap() {
+	var state StreamState
+	o.mset.store.FastState(&state)
+	if o.sseq < state.FirstSeq {
+		o.sseq = state.FirstSeq
+		o.updateSkipped(o.sseq)
+	}
+}
+
+// Returns whether we should deliver messages in push mode, which we
+// also do without interest when configured to continue regardless.
+// Lock should be held.
+func (o *consumer) pushDeliveryActive() bool {
+	return o.active || o.cfg.NoInterestPolicy == NoInterestContinue
+}
+
 // Created returns created time.
 func (o *consumer) createdTime() time.Time {
 	o.mu.Lock()
@@ -2096,6 +2450,8 @@ func (o *consumer) updateDeliveryInterest(localInterest bool) bool {
 
 	if interest && !o.active {
 		o.signalNewMessages()
+	} else if !interest && o.active && o.cfg.NoInterestPolicy == NoInterestContinue && o.isLeader() {
+		o.sendNoInterestAdvisoryLocked()
 	}
 	// Update active status, if not active clear any queue group we captured.
 	if o.active = interest; !o.active {
@@ -2123,6 +2479,160 @@ func (o *consumer) updateDeliveryInterest(localInterest bool) bool {
 	return false
 }
 
+// startRoutines starts the go routines that deliver messages, process
+// inbound acks and, in pull mode, process inbound next message requests.
+func (o *consumer) startRoutines(qch chan struct{}, labels pprofLabels, pullMode bool) {
+	// Now start up Go routine to deliver msgs.
+	go func() {
+		setGoRoutineLabels(labels)
+		o.loopAndGatherMsgs(qch)
+	}()
+
+	// Now start up Go routine to process acks.
+	go func() {
+		setGoRoutineLabels(labels)
+		o.processInboundAcks(qch)
+	}()
+
+	if pullMode {
+		// Now start up Go routine to process inbound next message requests.
+		go func() {
+			setGoRoutineLabels(labels)
+			o.processInboundNextMsgReqs(qch)
+		}()
+	}
+}
+
+// hibernateThreshold returns how long a consumer needs to be idle before
+// it hibernates, or 0 if it never does. Only non-clustered durables hibernate.
+// Lock should be held.
+func (o *consumer) hibernateThreshold() time.Duration {
+	if o.srv == nil || o.node != nil || !o.isDurable() {
+		return 0
+	}
+	return o.srv.getOpts().JetStreamConsumerHibernate
+}
+
+// Lock should be held.
+func (o *consumer) setHibernateTimer() {
+	stopAndClearTimer(&o.htmr)
+	if thresh := o.hibernateThreshold(); thresh > 0 {
+		o.hact = time.Now()
+		o.htmr = time.AfterFunc(thresh, o.checkHibernate)
+	}
+}
+
+// isIdle returns true if there is nothing pending or in flight for us.
+// Lock should be held.
+func (o *consumer) isIdle() bool {
+	if len(o.pending) > 0 || len(o.rdq) > 0 || o.replay || atomic.LoadInt64(&o.awl) > 0 || o.ackMsgs.len() > 0 {
+		return false
+	}
+	if o.isPullMode() {
+		return (o.waiting == nil || o.waiting.isEmpty()) && o.nextMsgReqs.len() == 0
+	}
+	return !o.active
+}
+
+// checkHibernate runs from the hibernation timer. If we have been idle for
+// the configured time we stop our go routines and release our delivery
+// state until the next ack, pull request or delivery interest wakes us up.
+func (o *consumer) checkHibernate() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed || o.qch == nil || o.htmr == nil || o.hibernated.Load() || !o.isLeader() {
+		return
+	}
+	thresh := o.hibernateThreshold()
+	if thresh <= 0 {
+		return
+	}
+	last := o.hact
+	if o.ldt.After(last) {
+		last = o.ldt
+	}
+	if o.lat.After(last) {
+		last = o.lat
+	}
+	if o.waiting != nil && o.waiting.last.After(last) {
+		last = o.waiting.last
+	}
+	idle := time.Since(last)
+	if idle < thresh || !o.isIdle() {
+		next := thresh - idle
+		if next <= 0 {
+			next = thresh
+		}
+		o.htmr.Reset(next)
+		return
+	}
+
+	// Mark us first so that anything queued from now on wakes us up,
+	// then make sure nothing slipped in before that.
+	o.hibernated.Store(true)
+	if !o.isIdle() {
+		o.hibernated.Store(false)
+		o.htmr.Reset(thresh)
+		return
+	}
+	close(o.qch)
+	o.qch = make(chan struct{})
+	// Nothing is pending, so drop our delivery state.
+	o.pending, o.rdc, o.rdq = nil, nil, nil
+	o.rdqi.Empty()
+	o.stopAndClearPtmr()
+	if o.isPushMode() {
+		go o.watchHibernatedInterest(o.inch, o.qch)
+	}
+	o.srv.Debugf("JetStream consumer '%s > %s > %s' hibernating after being idle for %v",
+		o.acc.Name, o.stream, o.name, idle.Round(time.Millisecond))
+}
+
+// watchHibernatedInterest wakes up a hibernated push consumer once there
+// is interest on the delivery subject again.
+func (o *consumer) watchHibernatedInterest(inch chan bool, qch chan struct{}) {
+	for {
+		select {
+		case interest := <-inch:
+			if o.updateDeliveryInterest(interest); interest {
+				o.wake()
+				return
+			}
+		case <-qch:
+			return
+		}
+	}
+}
+
+// wake restarts the go routines of a hibernated consumer.
+func (o *consumer) wake() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed || o.qch == nil || !o.hibernated.Swap(false) {
+		return
+	}
+	close(o.qch)
+	o.qch = make(chan struct{})
+	labels := pprofLabels{
+		"type":     "consumer",
+		"account":  o.acc.Name,
+		"stream":   o.stream,
+		"consumer": o.name,
+	}
+	o.startRoutines(o.qch, labels, o.isPullMode())
+	o.setHibernateTimer()
+	// Pick up any messages that arrived while we were hibernating.
+	o.signalNewMessages()
+	o.srv.Debugf("JetStream consumer '%s > %s > %s' woke up from hibernation", o.acc.Name, o.stream, o.name)
+}
+
+// isHibernated returns true if our go routines are currently stopped.
+func (o *consumer) isHibernated() bool {
+	return o.hibernated.Load()
+}
+
 const (
 	defaultConsumerNotActiveStartInterval = 30 * time.Second
 	defaultConsumerNotActiveMaxInterval   = 5 * time.Minute
@@ -2524,6 +3034,11 @@ func (o *consumer) updateConfig(cfg *ConsumerConfig) error {
 		}
 	}
 
+	// Send what was batched under the old settings.
+	if cfg.DeliverSubject != o.cfg.DeliverSubject || cfg.DeliverBatchInterval != o.cfg.DeliverBatchInterval ||
+		cfg.DeliverBatchMaxMsgs != o.cfg.DeliverBatchMaxMsgs {
+		o.flushDeliveryBatch()
+	}
 	// DeliverSubject
 	if cfg.DeliverSubject != o.cfg.DeliverSubject {
 		o.updateDeliverSubjectLocked(cfg.DeliverSubject)
@@ -2539,6 +3054,25 @@ func (o *consumer) updateConfig(cfg *ConsumerConfig) error {
 			o.resetPendingDeliveries()
 		}
 	}
+	// Delivery dedup state only applies to the header and window it was built for.
+	if cfg.DeliverDedupHeader != o.cfg.DeliverDedupHeader || cfg.DeliverDedupWindow != o.cfg.DeliverDedupWindow {
+		o.ddup = nil
+	}
+	// NoInterestPolicy
+	if cfg.NoInterestPolicy != o.cfg.NoInterestPolicy {
+		o.signalNewMessages()
+	}
+	// Any update resumes a consumer holding at messages removed by retention, past them.
+	if o.rskip > 0 {
+		o.rskip = 0
+		o.skipRetentionGap()
+		o.signalNewMessages()
+	}
+	// MaxInFlight
+	if cfg.MaxInFlight != o.cfg.MaxInFlight {
+		o.maxif = cfg.MaxInFlight
+		o.signalNewMessages()
+	}
 	// AckWait
 	if cfg.AckWait != o.cfg.AckWait {
 		if o.ptmr != nil {
@@ -2608,6 +3142,8 @@ func (o *consumer) updateConfig(cfg *ConsumerConfig) error {
 		o.mu.Lock()
 
 		// When we're done with signaling, we can replace the subjects.
+		// We will join the read group for the new subjects on our next read.
+		o.leaveReadGroup()
 		// If filters were removed, set `o.subjf` to nil.
 		if len(newSubjf) == 0 {
 			o.subjf = nil
@@ -2724,6 +3260,9 @@ func (am *jsAckMsg) returnToPool() {
 func (o *consumer) pushAck(_ *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	atomic.AddInt64(&o.awl, 1)
 	o.ackMsgs.push(newJSAckMsg(subject, reply, c.pa.hdr, copyBytes(rmsg)))
+	if o.hibernated.Load() {
+		o.wake()
+	}
 }
 
 // Processes a message for the ack reply subject delivered with a message.
@@ -3677,7 +4216,7 @@ func (o *consumer) processAckMsgLocked(sseq, dseq, dc uint64, reply string, doSa
 			if doSample {
 				o.sampleAck(sseq, dseq, dc)
 			}
-			if o.maxp > 0 && len(o.pending) >= o.maxp {
+			if (o.maxp > 0 && len(o.pending) >= o.maxp) || o.atMaxInFlight() {
 				needSignal = true
 			}
 			delete(o.pending, sseq)
@@ -3694,7 +4233,7 @@ func (o *consumer) processAckMsgLocked(sseq, dseq, dc uint64, reply string, doSa
 			// Return true to let caller respond back to the client.
 			return true
 		}
-		if o.maxp > 0 && len(o.pending) >= o.maxp {
+		if (o.maxp > 0 && len(o.pending) >= o.maxp) || o.atMaxInFlight() {
 			needSignal = true
 		}
 		sgap = sseq - o.asflr
@@ -3754,17 +4293,72 @@ func (o *consumer) processAckMsgLocked(sseq, dseq, dc uint64, reply string, doSa
 	return ackInPlace
 }
 
+// deliveryDedup tracks the dedup header values of messages delivered within the dedup window.
+type deliveryDedup struct {
+	ids   map[string]deliveryDedupEntry
+	order []string // In order of delivery, to expire them.
+}
+
+// deliveryDedupEntry is the first message delivered with a dedup header value.
+type deliveryDedupEntry struct {
+	seq uint64
+	ts  int64
+}
+
+// isDeliveryDuplicate returns true if the message has the same dedup header value as an other
+// message delivered before it within the dedup window, and should therefore not be delivered.
+// The window is based on message timestamps, so a replay of the stream makes the same decisions.
 // Lock should be held.
-func (o *consumer) moveAckFloor(dseq, sseq uint64) {
-	// Only move floors if we matched an existing pending.
-	if len(o.pending) == 0 {
-		o.adflr = o.dseq - 1
-		o.asflr = o.sseq - 1
-	} else if dseq == o.adflr+1 {
-		o.adflr, o.asflr = dseq, sseq
-		for ss := sseq + 1; ss < o.sseq; ss++ {
-			if p, ok := o.pending[ss]; ok {
-				if p.Sequence > 0 {
+func (o *consumer) isDeliveryDuplicate(pmsg *jsPubMsg) bool {
+	if o.cfg.DeliverDedupHeader == _EMPTY_ || len(pmsg.hdr) == 0 {
+		return false
+	}
+	id := sliceHeader(o.cfg.DeliverDedupHeader, pmsg.hdr)
+	if len(id) == 0 {
+		return false
+	}
+	dd := o.ddup
+	if dd == nil {
+		dd = &deliveryDedup{ids: make(map[string]deliveryDedupEntry)}
+		o.ddup = dd
+	}
+	// Expire what is outside of the window.
+	window := int64(o.cfg.DeliverDedupWindow)
+	for len(dd.order) > 0 {
+		if e, ok := dd.ids[dd.order[0]]; ok && pmsg.ts-e.ts < window {
+			break
+		}
+		delete(dd.ids, dd.order[0])
+		dd.order = dd.order[1:]
+	}
+	// The same message could come through here again if it couldn't be delivered the first time.
+	if e, ok := dd.ids[bytesToString(id)]; ok {
+		return e.seq != pmsg.seq
+	}
+	sid := string(id)
+	dd.ids[sid] = deliveryDedupEntry{seq: pmsg.seq, ts: pmsg.ts}
+	dd.order = append(dd.order, sid)
+	return false
+}
+
+// Returns true if we have reached the maximum number of deliveries in flight.
+// Messages waiting to be redelivered are pending, but not in flight.
+// Lock should be held.
+func (o *consumer) atMaxInFlight() bool {
+	return o.maxif > 0 && len(o.pending)-len(o.rdq) >= o.maxif
+}
+
+// Lock should be held.
+func (o *consumer) moveAckFloor(dseq, sseq uint64) {
+	// Only move floors if we matched an existing pending.
+	if len(o.pending) == 0 {
+		o.adflr = o.dseq - 1
+		o.asflr = o.sseq - 1
+	} else if dseq == o.adflr+1 {
+		o.adflr, o.asflr = dseq, sseq
+		for ss := sseq + 1; ss < o.sseq; ss++ {
+			if p, ok := o.pending[ss]; ok {
+				if p.Sequence > 0 {
 					o.adflr, o.asflr = p.Sequence-1, ss-1
 				}
 				break
@@ -4252,6 +4846,64 @@ func (o *consumer) pendingRequests() map[string]*waitingRequest {
 	return m
 }
 
+// PullRequestInfo describes an outstanding pull request waiting on a consumer.
+type PullRequestInfo struct {
+	Account       string        `json:"account"`
+	Reply         string        `json:"reply_subject"`
+	Interest      string        `json:"interest_subject,omitempty"`
+	Batch         int           `json:"batch"`
+	Delivered     int           `json:"delivered"`
+	PendingMsgs   int           `json:"pending_msgs"`
+	PendingBytes  int           `json:"pending_bytes,omitempty"`
+	Received      time.Time     `json:"received"`
+	Expires       *time.Time    `json:"expires,omitempty"`
+	Heartbeat     time.Duration `json:"idle_heartbeat,omitempty"`
+	NoWait        bool          `json:"no_wait,omitempty"`
+	PriorityGroup string        `json:"group,omitempty"`
+}
+
+// pullRequests returns the pull requests currently waiting on this consumer
+// in the order they will be served. Expired requests are left out even if
+// they were not yet removed from the waiting queue.
+func (o *consumer) pullRequests() []*PullRequestInfo {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	reqs := []*PullRequestInfo{}
+	if o.waiting == nil {
+		return reqs
+	}
+	now := time.Now()
+	for wr := o.waiting.head; wr != nil; wr = wr.next {
+		if wr.n <= 0 || (!wr.expires.IsZero() && now.After(wr.expires)) {
+			continue
+		}
+		pr := &PullRequestInfo{
+			Account:      wr.acc.GetName(),
+			Reply:        wr.reply,
+			Batch:        wr.n + wr.d,
+			Delivered:    wr.d,
+			PendingMsgs:  wr.n,
+			PendingBytes: wr.b,
+			Received:     wr.received.UTC(),
+			Heartbeat:    wr.hb,
+			NoWait:       wr.noWait,
+		}
+		if wr.interest != wr.reply {
+			pr.Interest = wr.interest
+		}
+		if !wr.expires.IsZero() {
+			expires := wr.expires.UTC()
+			pr.Expires = &expires
+		}
+		if wr.priorityGroup != nil {
+			pr.PriorityGroup = wr.priorityGroup.Group
+		}
+		reqs = append(reqs, pr)
+	}
+	return reqs
+}
+
 func (o *consumer) setPinnedTimer(priorityGroup string) {
 	if o.pinnedTtl != nil {
 		o.pinnedTtl.Reset(o.cfg.PinnedTTL)
@@ -4490,6 +5142,9 @@ func (o *consumer) processNextMsgReq(_ *subscription, c *client, _ *Account, _,
 		return
 	}
 	o.nextMsgReqs.push(newNextMsgReq(reply, copyBytes(msg)))
+	if o.hibernated.Load() {
+		o.wake()
+	}
 }
 
 // processResetReq will reset a consumer to a new starting sequence.
@@ -4740,6 +5395,24 @@ func (o *consumer) notifyDeliveryExceeded(sseq, dc uint64) {
 	o.sendAdvisory(o.deliveryExcEventT, e)
 }
 
+// Lock should be held.
+func (o *consumer) notifyFirstDelivery(dseq, sseq uint64) {
+	e := JSConsumerFirstDeliveryAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSConsumerFirstDeliveryAdvisoryType,
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Stream:      o.stream,
+		Consumer:    o.name,
+		ConsumerSeq: dseq,
+		StreamSeq:   sseq,
+		Domain:      o.srv.getOpts().JetStreamDomain,
+	}
+
+	o.sendAdvisory(o.firstDelEventT, e)
+}
+
 // Check if the candidate subject matches a filter if its present.
 // Lock should be held.
 func (o *consumer) isFilteredMatch(subj string) bool {
@@ -4785,6 +5458,7 @@ func (o *consumer) isEqualOrSubsetMatch(subj string) bool {
 
 var (
 	errMaxAckPending = errors.New("max ack pending reached")
+	errMaxInFlight   = errors.New("max in flight reached")
 	errBadConsumer   = errors.New("consumer not valid")
 	errNoInterest    = errors.New("consumer requires interest for delivery subject when ephemeral")
 )
@@ -4796,6 +5470,10 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 	if o.mset == nil || o.mset.store == nil {
 		return nil, 0, errBadConsumer
 	}
+	// Check if we have max in flight, this includes redeliveries.
+	if o.atMaxInFlight() {
+		return nil, 0, errMaxInFlight
+	}
 	// Process redelivered messages before looking at possibly "skip list" (deliver last per subject)
 	if o.hasRedeliveries() {
 		var seq, dc uint64
@@ -4868,6 +5546,17 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 		return pmsg, 1, err
 	}
 
+	// Check if the stream's retention removed messages we did not deliver yet.
+	if o.cfg.RetentionSkipPolicy != RetentionSkipIgnore && o.checkRetentionSkip() {
+		return nil, 0, ErrStoreEOF
+	}
+
+	// When delivering after commit, only messages stored by a quorum of replicas can be delivered.
+	dac, qseq := o.mset.dac.Load(), o.mset.qseq.Load()
+	if dac && o.sseq > qseq {
+		return nil, 0, ErrStoreEOF
+	}
+
 	var sseq uint64
 	var err error
 	var sm *StoreMsg
@@ -4875,8 +5564,15 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 
 	// Grab next message applicable to us.
 	filters, subjf, fseq := o.filters, o.subjf, o.sseq
-	// Check if we are multi-filtered or not.
-	if filters != nil {
+	// Check if another consumer with the same filters has already loaded it.
+	if o.rg == nil {
+		o.rg = o.mset.joinReadGroup(subjf)
+	}
+	shared := o.rg.load(o.mset.store, fseq, &pmsg.StoreMsg)
+	if shared {
+		sm, sseq = &pmsg.StoreMsg, pmsg.StoreMsg.seq
+	} else if filters != nil {
+		// Check if we are multi-filtered or not.
 		sm, sseq, err = o.mset.store.LoadNextMsgMulti(filters, fseq, &pmsg.StoreMsg)
 	} else if len(subjf) > 0 { // Means single filtered subject since o.filters means > 1.
 		filter, wc := subjf[0].subject, subjf[0].hasWildcard
@@ -4885,9 +5581,16 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 		// No filter here.
 		sm, sseq, err = o.mset.store.LoadNextMsg(_EMPTY_, false, fseq, &pmsg.StoreMsg)
 	}
+	if !shared && sm != nil && err == nil {
+		o.rg.store(fseq, sm)
+	}
 	if sm == nil {
 		pmsg.returnToPool()
 		pmsg = nil
+	} else if dac && sseq > qseq {
+		// Not yet stored by a quorum, we will be signaled once it is.
+		pmsg.returnToPool()
+		return nil, 0, ErrStoreEOF
 	}
 	// Check if we should move our o.sseq.
 	if sseq >= o.sseq {
@@ -4901,6 +5604,170 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 	return pmsg, 1, err
 }
 
+const (
+	// consumerReadGroupMaxReads is the number of recently loaded messages shared within a read group.
+	consumerReadGroupMaxReads = 256
+	// consumerReadGroupMaxBytes is the size of recently loaded messages shared within a read group.
+	consumerReadGroupMaxBytes = 8 * 1024 * 1024
+)
+
+// consumerReadGroup lets consumers with the same filter subjects share their reads from the store.
+// A message loaded by one of them is kept for a while, so the others reading from the same
+// sequence get it without scanning the store again. Only the read path is shared, the delivery,
+// ack and redelivery state remains with each consumer.
+type consumerReadGroup struct {
+	mu    sync.Mutex
+	key   string
+	refs  int                  // Number of consumers in the group.
+	reads map[uint64]*StoreMsg // Recently loaded messages by the sequence the read started from.
+	seqs  map[uint64]uint64    // Sequence the read started from by the sequence of the loaded message.
+	order []uint64             // Sequences the reads started from, oldest first.
+	bytes int                  // Size of the recently loaded messages.
+	loads uint64               // Number of messages loaded from the store.
+	hits  uint64               // Number of messages served from the group.
+}
+
+// load will copy the message read starting from seq into sm, if another consumer in the group loaded it.
+// The message is only served if the store still holds it, since removals are only signaled afterwards.
+func (rg *consumerReadGroup) load(store StreamStore, seq uint64, sm *StoreMsg) bool {
+	rg.mu.Lock()
+	if rg.refs < 2 {
+		rg.mu.Unlock()
+		return false
+	}
+	rsm, ok := rg.reads[seq]
+	if !ok {
+		rg.mu.Unlock()
+		return false
+	}
+	rsm.copy(sm)
+	rg.mu.Unlock()
+
+	// Check after copying, so a removal racing with us is either seen here or happens after our read.
+	if !store.MsgExists(sm.seq) {
+		rg.invalidate(sm.seq)
+		sm.clear()
+		return false
+	}
+	rg.mu.Lock()
+	rg.hits++
+	rg.mu.Unlock()
+	return true
+}
+
+// store will keep a copy of the message read starting from seq for the other consumers in the group.
+func (rg *consumerReadGroup) store(seq uint64, sm *StoreMsg) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	rg.loads++
+	if rg.refs < 2 {
+		return
+	}
+	if _, ok := rg.reads[seq]; ok {
+		return
+	}
+	size := len(sm.hdr) + len(sm.msg)
+	if size > consumerReadGroupMaxBytes {
+		return
+	}
+	if rg.reads == nil {
+		rg.reads, rg.seqs = make(map[uint64]*StoreMsg), make(map[uint64]uint64)
+	}
+	rsm := &StoreMsg{subj: copyString(sm.subj), seq: sm.seq, ts: sm.ts}
+	rsm.buf = make([]byte, 0, len(sm.hdr)+len(sm.msg))
+	rsm.buf = append(rsm.buf, sm.hdr...)
+	rsm.buf = append(rsm.buf, sm.msg...)
+	rsm.hdr, rsm.msg = rsm.buf[:len(sm.hdr)], rsm.buf[len(sm.hdr):]
+	rg.reads[seq], rg.seqs[sm.seq] = rsm, seq
+	rg.order = append(rg.order, seq)
+	rg.bytes += size
+	// Drop the oldest reads if we have too many, or they take up too much space.
+	for len(rg.order) > consumerReadGroupMaxReads || rg.bytes > consumerReadGroupMaxBytes {
+		oseq := rg.order[0]
+		rg.order = rg.order[1:]
+		if osm, ok := rg.reads[oseq]; ok {
+			delete(rg.reads, oseq)
+			delete(rg.seqs, osm.seq)
+			rg.bytes -= len(osm.buf)
+		}
+	}
+}
+
+// invalidate drops the message with the given sequence, or all messages if seq is zero.
+func (rg *consumerReadGroup) invalidate(seq uint64) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	if seq == 0 {
+		rg.reads, rg.seqs, rg.order, rg.bytes = nil, nil, nil, 0
+	} else if rseq, ok := rg.seqs[seq]; ok {
+		if rsm, ok := rg.reads[rseq]; ok {
+			rg.bytes -= len(rsm.buf)
+		}
+		delete(rg.seqs, seq)
+		delete(rg.reads, rseq)
+	}
+}
+
+// readGroupKey returns the key consumers with the same filter subjects share.
+func readGroupKey(subjf subjectFilters) string {
+	subjects := subjf.subjects()
+	slices.Sort(subjects)
+	return strings.Join(subjects, " ")
+}
+
+// joinReadGroup returns the read group for consumers with these filter subjects, creating it if needed.
+func (mset *stream) joinReadGroup(subjf subjectFilters) *consumerReadGroup {
+	key := readGroupKey(subjf)
+	mset.rgMu.Lock()
+	defer mset.rgMu.Unlock()
+	rg, ok := mset.rgs[key]
+	if !ok {
+		if mset.rgs == nil {
+			mset.rgs = make(map[string]*consumerReadGroup)
+		}
+		rg = &consumerReadGroup{key: key}
+		mset.rgs[key] = rg
+	}
+	rg.mu.Lock()
+	rg.refs++
+	rg.mu.Unlock()
+	return rg
+}
+
+// leaveReadGroup removes the consumer from the read group, removing the group once empty.
+func (mset *stream) leaveReadGroup(rg *consumerReadGroup) {
+	mset.rgMu.Lock()
+	defer mset.rgMu.Unlock()
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	if rg.refs--; rg.refs <= 0 {
+		if mset.rgs[rg.key] == rg {
+			delete(mset.rgs, rg.key)
+		}
+	} else if rg.refs < 2 {
+		// Nothing left to share with.
+		rg.reads, rg.seqs, rg.order, rg.bytes = nil, nil, nil, 0
+	}
+}
+
+// invalidateReadGroups makes sure a removed message, or all messages if seq is zero, is no longer shared.
+func (mset *stream) invalidateReadGroups(seq uint64) {
+	mset.rgMu.Lock()
+	defer mset.rgMu.Unlock()
+	for _, rg := range mset.rgs {
+		rg.invalidate(seq)
+	}
+}
+
+// leaveReadGroup removes the consumer from its read group, if any.
+// Lock should be held.
+func (o *consumer) leaveReadGroup() {
+	if o.rg != nil && o.mset != nil {
+		o.mset.leaveReadGroup(o.rg)
+	}
+	o.rg = nil
+}
+
 // Will check for expiration and lack of interest on waiting requests.
 // Will also do any heartbeats and return the next expiration or HB interval.
 func (o *consumer) processWaiting(eos bool) (int, int, int, time.Time) {
@@ -5281,7 +6148,7 @@ func (o *consumer) loopAndGatherMsgs(qch chan struct{}) {
 
 		// If we are in push mode and not active or under flowcontrol let's stop sending.
 		if o.isPushMode() {
-			if !o.active || (o.maxpb > 0 && o.pbytes > o.maxpb) {
+			if !o.pushDeliveryActive() || (o.maxpb > 0 && o.pbytes > o.maxpb) {
 				goto waitForMsgs
 			}
 		} else if o.waiting.isEmpty() {
@@ -5304,7 +6171,7 @@ func (o *consumer) loopAndGatherMsgs(qch chan struct{}) {
 			if err == ErrStoreEOF {
 				o.checkNumPendingOnEOF()
 			}
-			if err == ErrStoreMsgNotFound || err == errDeletedMsg || err == ErrStoreEOF || err == errMaxAckPending {
+			if err == ErrStoreMsgNotFound || err == errDeletedMsg || err == ErrStoreEOF || err == errMaxAckPending || err == errMaxInFlight {
 				goto waitForMsgs
 			} else {
 				if pmsg != nil {
@@ -5318,6 +6185,15 @@ func (o *consumer) loopAndGatherMsgs(qch chan struct{}) {
 			}
 		}
 
+		// Skip duplicates of messages delivered within the dedup window, as if already acknowledged.
+		if dc == 1 && o.isDeliveryDuplicate(pmsg) {
+			o.npc--
+			o.updateSkipped(o.sseq)
+			pmsg.returnToPool()
+			o.mu.Unlock()
+			continue
+		}
+
 		// Update our cached num pending here first.
 		if dc == 1 {
 			o.npc--
@@ -5655,7 +6531,13 @@ func (o *consumer) deliverMsg(dsubj, ackReply string, pmsg *jsPubMsg, dc uint64,
 	if o.replicateDeliveries() {
 		o.addReplicatedQueuedMsg(pmsg)
 	} else {
-		o.outq.send(pmsg)
+		o.sendDelivery(pmsg)
+	}
+
+	// Liveness signal for the first message delivered by this consumer.
+	if !o.firstDelivered {
+		o.firstDelivered = true
+		o.notifyFirstDelivery(dseq, seq)
 	}
 
 	// Flow control.
@@ -5678,6 +6560,65 @@ func (o *consumer) deliverMsg(dsubj, ackReply string, pmsg *jsPubMsg, dc uint64,
 	}
 }
 
+// sendDelivery sends the delivered message, or adds it to the next batch if delivering in batches.
+// Lock should be held.
+func (o *consumer) sendDelivery(pmsg *jsPubMsg) {
+	if o.cfg.DeliverBatchInterval <= 0 {
+		o.outq.send(pmsg)
+		return
+	}
+	// Make sure a batch does not exceed the max payload, leaving room for the frame line.
+	sz := len(pmsg.subj) + len(pmsg.reply) + len(pmsg.hdr) + len(pmsg.msg) + 32
+	if len(o.dbatch) > 0 && o.dbatchSz+sz > int(o.srv.getOpts().MaxPayload) {
+		o.flushDeliveryBatch()
+	}
+	o.dbatch = append(o.dbatch, pmsg)
+	o.dbatchSz += sz
+	if len(o.dbatch) >= o.cfg.DeliverBatchMaxMsgs {
+		o.flushDeliveryBatch()
+	} else if o.dbatchTmr == nil {
+		o.dbatchTmr = time.AfterFunc(o.cfg.DeliverBatchInterval, o.processDeliveryBatchTimer)
+	}
+}
+
+func (o *consumer) processDeliveryBatchTimer() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.flushDeliveryBatch()
+}
+
+// flushDeliveryBatch sends all batched deliveries as a single message, see JSDeliverBatchCount.
+// Lock should be held.
+func (o *consumer) flushDeliveryBatch() {
+	stopAndClearTimer(&o.dbatchTmr)
+	if len(o.dbatch) == 0 {
+		return
+	}
+	dsubj := o.dbatch[0].dsubj
+	buf := make([]byte, 0, o.dbatchSz)
+	for i, pmsg := range o.dbatch {
+		buf = fmt.Appendf(buf, "%s %s %d %d\r\n", pmsg.subj, pmsg.reply, len(pmsg.hdr), len(pmsg.hdr)+len(pmsg.msg))
+		buf = append(buf, pmsg.hdr...)
+		buf = append(buf, pmsg.msg...)
+		buf = append(buf, _CRLF_...)
+		pmsg.returnToPool()
+		o.dbatch[i] = nil
+	}
+	hdr := genHeader(nil, JSDeliverBatchCount, strconv.Itoa(len(o.dbatch)))
+	o.outq.send(newJSPubMsg(dsubj, _EMPTY_, _EMPTY_, hdr, buf, nil, 0))
+	o.dbatch, o.dbatchSz = o.dbatch[:0], 0
+}
+
+// resetDeliveryBatch drops all batched deliveries, these will be redelivered.
+// Lock should be held.
+func (o *consumer) resetDeliveryBatch() {
+	stopAndClearTimer(&o.dbatchTmr)
+	for _, pmsg := range o.dbatch {
+		pmsg.returnToPool()
+	}
+	o.dbatch, o.dbatchSz = nil, 0
+}
+
 // replicateDeliveries returns whether deliveries should be replicated before sending them.
 // If we're replicated we MUST only send the message AFTER we've got quorum for updating
 // delivered state. Otherwise, we could be in an invalid state after a leader change.
@@ -5843,12 +6784,18 @@ func (o *consumer) didNotDeliver(seq uint64, subj string) {
 		o.mu.Unlock()
 		return
 	}
-	// Adjust back deliver count.
-	o.decDeliveryCount(seq)
+	// Adjust back deliver count, unless we deliver regardless of interest.
+	continueWithoutInterest := o.isPushMode() && o.cfg.NoInterestPolicy == NoInterestContinue
+	if !continueWithoutInterest {
+		o.decDeliveryCount(seq)
+	}
 
 	var checkDeliveryInterest bool
 	if o.isPushMode() {
-		o.active = false
+		// If we continue without interest, the interest update will advise when it was lost.
+		if !continueWithoutInterest {
+			o.active = false
+		}
 		checkDeliveryInterest = true
 	} else if o.pending != nil {
 		// Good chance we did not deliver because no interest so force a check.
@@ -6596,6 +7543,7 @@ func (o *consumer) stopWithFlags(dflag, sdflag, doSignal, advisory bool) error {
 		o.qch = nil
 	}
 
+	o.leaveReadGroup()
 	a := o.acc
 	store := o.store
 	mset := o.mset
@@ -6624,6 +7572,8 @@ func (o *consumer) stopWithFlags(dflag, sdflag, doSignal, advisory bool) error {
 	o.stopAndClearPtmr()
 	stopAndClearTimer(&o.dtmr)
 	stopAndClearTimer(&o.gwdtmr)
+	stopAndClearTimer(&o.htmr)
+	o.resetDeliveryBatch()
 	delivery := o.cfg.DeliverSubject
 	o.waiting = nil
 	// Break us out of the readLoop.
@@ -6643,6 +7593,7 @@ func (o *consumer) stopWithFlags(dflag, sdflag, doSignal, advisory bool) error {
 		ca = o.ca
 	}
 	js := o.js
+	retention := o.cfg.UnackedRetention
 	o.mu.Unlock()
 
 	if c != nil {
@@ -6665,9 +7616,13 @@ func (o *consumer) stopWithFlags(dflag, sdflag, doSignal, advisory bool) error {
 		mset.mu.Unlock()
 	}
 
-	// Cleanup messages that lost interest.
+	// Cleanup messages that lost interest, unless we should retain them for a while.
 	if dflag && rp == InterestPolicy {
-		o.cleanupNoInterestMessages(mset, true)
+		if retention > 0 && !sdflag {
+			o.retainUnackedMessages(mset, retention)
+		} else {
+			o.cleanupNoInterestMessages(mset, true)
+		}
 	}
 
 	// Cluster cleanup.
@@ -6725,6 +7680,31 @@ func (o *consumer) cleanupNoInterestMessages(mset *stream, ignoreInterest bool)
 	start := o.asflr
 	o.mu.Unlock()
 
+	// Consumer's interests are ignored by default. If we should not ignore interest, unset.
+	co := o
+	if !ignoreInterest {
+		co = nil
+	}
+	mset.cleanupNoInterestMessages(start, co)
+}
+
+// retainUnackedMessages will have the stream retain the messages this deleted consumer
+// has not acknowledged, until the retention period expires.
+func (o *consumer) retainUnackedMessages(mset *stream, retention time.Duration) {
+	o.mu.Lock()
+	if !o.isLeader() {
+		o.readStoredState()
+	}
+	name, floor := o.name, o.asflr
+	filters := gatherSubjectFilters(o.cfg.FilterSubject, slices.Clone(o.cfg.FilterSubjects))
+	o.mu.Unlock()
+
+	mset.retainInterest(name, floor, filters, retention)
+}
+
+// cleanupNoInterestMessages will remove messages from start onwards that no longer have interest.
+// The consumer passed is optional, its interest is ignored if set.
+func (mset *stream) cleanupNoInterestMessages(start uint64, co *consumer) {
 	// Make sure we start at worst with first sequence in the stream.
 	state := mset.state()
 	if start < state.FirstSeq {
@@ -6732,12 +7712,6 @@ func (o *consumer) cleanupNoInterestMessages(mset *stream, ignoreInterest bool)
 	}
 	stop := state.LastSeq
 
-	// Consumer's interests are ignored by default. If we should not ignore interest, unset.
-	co := o
-	if !ignoreInterest {
-		co = nil
-	}
-
 	var rmseqs []uint64
 	mset.mu.RLock()
 
@@ -6749,7 +7723,7 @@ func (o *consumer) cleanupNoInterestMessages(mset *stream, ignoreInterest bool)
 	const bailThresh = 100_000
 
 	// Check if we would be spending too much time here and defer to separate go routine.
-	if len(mset.consumers) == 0 {
+	if len(mset.consumers) == 0 && len(mset.retained) == 0 {
 		mset.mu.RUnlock()
 		mset.mu.Lock()
 		defer mset.mu.Unlock()
@@ -6823,6 +7797,10 @@ func (o *consumer) decStreamPending(sseq uint64, subj string) {
 	// Update our cached num pending only if we think deliverMsg has not done so.
 	if sseq >= o.sseq && o.isFilteredMatch(subj) {
 		o.npc--
+		// Track the lowest removed message we did not deliver yet, for the retention skip policy.
+		if o.cfg.RetentionSkipPolicy != RetentionSkipIgnore && (o.rsfseq < o.sseq || sseq < o.rsfseq) {
+			o.rsfseq = sseq
+		}
 	}
 
 	// Check if this message was pending.
@@ -6898,7 +7876,7 @@ func (o *consumer) processStreamSignal(seq uint64) {
 	if seq < o.sseq {
 		return
 	}
-	if o.isPushMode() && o.active || o.isPullMode() && !o.waiting.isEmpty() {
+	if o.isPushMode() && o.pushDeliveryActive() || o.isPullMode() && !o.waiting.isEmpty() {
 		o.signalNewMessages()
 	}
 }
@@ -7132,3 +8110,435 @@ func (o *consumer) resetPendingDeliveries() {
 	}
 	o.waitingDeliveries = nil
 }
+
+// estimateConsumer returns the number of messages a consumer with the given
+// configuration would have pending if created now, and the stream sequence of
+// the first of them, without creating anything.
+func (mset *stream) estimateConsumer(config *ConsumerConfig) (uint64, uint64, error) {
+	if mset.closed.Load() {
+		return 0, 0, NewJSStreamInvalidError()
+	}
+	if config == nil {
+		return 0, 0, NewJSConsumerConfigRequiredError()
+	}
+
+	mset.mu.RLock()
+	s, cfg, acc := mset.srv, mset.cfg, mset.acc
+	mset.mu.RUnlock()
+
+	selectedLimits, _, _, _ := acc.selectLimits(config.replicas(&cfg))
+	if selectedLimits == nil {
+		return 0, 0, NewJSNoLimitsError()
+	}
+	// Work on a copy since defaults are applied to the config.
+	ccfg := *config
+	srvLim := &s.getOpts().JetStreamLimits
+	mset.js.mu.Lock()
+	apiErr := setConsumerConfigDefaults(&ccfg, &cfg, srvLim, selectedLimits, false)
+	mset.js.mu.Unlock()
+	if apiErr != nil {
+		return 0, 0, apiErr
+	}
+	if apiErr := checkConsumerCfg(&ccfg, srvLim, &cfg, acc, selectedLimits, false); apiErr != nil {
+		return 0, 0, apiErr
+	}
+
+	// A transient consumer, never registered, is enough to select the starting
+	// sequence and calculate the pending count the same way a real one would.
+	o := &consumer{mset: mset, cfg: ccfg}
+	for _, filter := range gatherSubjectFilters(ccfg.FilterSubject, ccfg.FilterSubjects) {
+		o.subjf = append(o.subjf, &subjectFilter{
+			subject:          filter,
+			hasWildcard:      subjectHasWildcard(filter),
+			tokenizedSubject: tokenizeSubjectIntoSlice(nil, filter),
+		})
+	}
+	if len(o.subjf) > 1 {
+		o.filters = gsl.NewSublist[struct{}]()
+		for _, filter := range o.subjf {
+			o.filters.Insert(filter.subject, struct{}{})
+		}
+	}
+	if err := o.selectStartingSeqNo(); err != nil {
+		return 0, 0, err
+	}
+	npc, _, err := o.calculateNumPending()
+	if err != nil || npc == 0 {
+		return 0, 0, err
+	}
+
+	// Find the first matching message from the starting sequence.
+	var smv StoreMsg
+	var first uint64
+	if o.lss != nil && len(o.lss.seqs) > 0 {
+		first = o.lss.seqs[0]
+	} else if o.filters != nil {
+		_, first, err = mset.store.LoadNextMsgMulti(o.filters, o.sseq, &smv)
+	} else if len(o.subjf) == 1 {
+		_, first, err = mset.store.LoadNextMsg(o.subjf[0].subject, o.subjf[0].hasWildcard, o.sseq, &smv)
+	} else {
+		_, first, err = mset.store.LoadNextMsg(fwcs, true, o.sseq, &smv)
+	}
+	if err != nil {
+		if err == ErrStoreEOF || err == ErrStoreMsgNotFound {
+			return npc, 0, nil
+		}
+		return 0, 0, err
+	}
+	return npc, first, nil
+}
+
+// Limits of a single replay, also applied when the request does not set its own.
+const (
+	maxReplayMsgs  = 10_000
+	maxReplayBytes = 64 * 1024 * 1024
+	// The replay waits while the stream's outbound queue holds at least this many messages.
+	replayMaxPending = 1024
+)
+
+// replayWindow will send the messages this consumer would have delivered between start and end
+// to the deliver subject. This is read only and does not touch any of the consumer's state.
+// The window is checked up front, after which the messages are sent from a separate Go routine
+// that paces itself on the stream's outbound queue and stops at maxMsgs or maxBytes.
+// The done callback is called with the number of messages sent, and whether limits were hit.
+func (o *consumer) replayWindow(start, end time.Time, deliver string, maxMsgs, maxBytes int, done func(sent uint64, truncated bool)) error {
+	if start.IsZero() || end.IsZero() || !start.Before(end) {
+		return NewJSConsumerReplayInvalidWindowError()
+	}
+	if !IsValidPublishSubject(deliver) {
+		return NewJSConsumerInvalidDeliverSubjectError()
+	}
+	if maxMsgs <= 0 || maxMsgs > maxReplayMsgs {
+		maxMsgs = maxReplayMsgs
+	}
+	if maxBytes <= 0 || maxBytes > maxReplayBytes {
+		maxBytes = maxReplayBytes
+	}
+
+	o.mu.RLock()
+	s, mset, filters, subjf, qch := o.srv, o.mset, o.filters, o.subjf, o.qch
+	o.mu.RUnlock()
+	if mset == nil {
+		return NewJSConsumerNotFoundError()
+	}
+
+	// Do not allow the replay to be stored back into the stream.
+	cfg := mset.config()
+	for _, subj := range cfg.Subjects {
+		if SubjectsCollide(subj, deliver) {
+			return NewJSConsumerReplayDeliverSubjectCycleError()
+		}
+	}
+
+	// The scan reads through the store, which does its own locking, so we do not
+	// hold the stream lock and block writes for the duration of the replay.
+	mset.mu.RLock()
+	store, outq, name := mset.store, mset.outq, cfg.Name
+	mset.mu.RUnlock()
+	if store == nil || outq == nil {
+		return NewJSStreamNotFoundError()
+	}
+	seq, ets := store.GetSeqFromTime(start), end.UnixNano()
+
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+
+		var sent uint64
+		var nb int
+		for {
+			var (
+				svp StoreMsg
+				sm  *StoreMsg
+				err error
+			)
+			if filters != nil {
+				sm, seq, err = store.LoadNextMsgMulti(filters, seq, &svp)
+			} else if len(subjf) > 0 {
+				sm, seq, err = store.LoadNextMsg(subjf[0].subject, subjf[0].hasWildcard, seq, &svp)
+			} else {
+				sm, seq, err = store.LoadNextMsg(fwcs, true, seq, &svp)
+			}
+			if err != nil || sm == nil || sm.ts > ets {
+				break
+			}
+			sz := len(sm.hdr) + len(sm.msg)
+			if sent >= uint64(maxMsgs) || nb+sz > maxBytes {
+				done(sent, true)
+				return
+			}
+			nb += sz
+			seq++
+
+			// Don't let the replay flood the stream's outbound queue.
+			for outq.len() >= replayMaxPending {
+				select {
+				case <-qch:
+					done(sent, true)
+					return
+				case <-s.quitCh:
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+
+			ts := time.Unix(0, sm.ts).UTC()
+			var hdr []byte
+			if len(sm.hdr) == 0 {
+				hdr = fmt.Appendf(nil, dg, name, sm.subj, sm.seq, ts.Format(time.RFC3339Nano))
+			} else {
+				hdr = copyBytes(sm.hdr)
+				hdr = genHeader(hdr, JSStream, name)
+				hdr = genHeader(hdr, JSSubject, sm.subj)
+				hdr = genHeader(hdr, JSSequence, strconv.FormatUint(sm.seq, 10))
+				hdr = genHeader(hdr, JSTimeStamp, ts.Format(time.RFC3339Nano))
+			}
+			outq.send(newJSPubMsg(deliver, _EMPTY_, _EMPTY_, hdr, sm.msg, nil, 0))
+			sent++
+		}
+		done(sent, false)
+	})
+	return nil
+}
+
+// Delivery states reported for a single stream message of a consumer.
+const (
+	// ConsumerMsgUndelivered has not been delivered yet.
+	ConsumerMsgUndelivered = "undelivered"
+	// ConsumerMsgPending has been delivered and is waiting to be acknowledged.
+	ConsumerMsgPending = "pending"
+	// ConsumerMsgAcked has been acknowledged or terminated.
+	ConsumerMsgAcked = "acked"
+	// ConsumerMsgDelivered has been delivered by a consumer that does not track acks.
+	ConsumerMsgDelivered = "delivered"
+	// ConsumerMsgMaxDeliveries has reached the maximum number of deliveries without an ack.
+	ConsumerMsgMaxDeliveries = "max_deliveries"
+	// ConsumerMsgFiltered does not match the consumer's filter subjects.
+	ConsumerMsgFiltered = "filtered"
+	// ConsumerMsgDeleted was removed from the stream before it was delivered.
+	ConsumerMsgDeleted = "deleted"
+)
+
+// ConsumerMsgHistory is the delivery history of a stream message for a consumer.
+// Delivery counts and times are only known while the message is pending or once
+// it has reached the maximum number of deliveries.
+type ConsumerMsgHistory struct {
+	Stream        uint64     `json:"stream_seq"`
+	Consumer      uint64     `json:"consumer_seq,omitempty"`
+	State         string     `json:"state"`
+	Delivered     uint64     `json:"num_delivered,omitempty"`
+	LastDelivered *time.Time `json:"last_delivered,omitempty"`
+}
+
+// msgHistory returns the delivery history of the stream sequence for this consumer.
+func (o *consumer) msgHistory(seq uint64) (*ConsumerMsgHistory, error) {
+	o.mu.RLock()
+	mset := o.mset
+	o.mu.RUnlock()
+	if mset == nil {
+		return nil, NewJSConsumerNotFoundError()
+	}
+
+	var state StreamState
+	mset.store.FastState(&state)
+	if seq == 0 || seq > state.LastSeq {
+		return nil, NewJSConsumerMsgHistoryInvalidSeqError()
+	}
+	var smv StoreMsg
+	sm, err := mset.store.LoadMsg(seq, &smv)
+	if err != nil && err != ErrStoreMsgNotFound && err != errDeletedMsg && err != ErrStoreEOF {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	mh := &ConsumerMsgHistory{Stream: seq}
+	if p, ok := o.pending[seq]; ok && p != nil {
+		ts := time.Unix(0, p.Timestamp).UTC()
+		// The redelivery count does not include the first delivery.
+		mh.State, mh.Consumer, mh.Delivered, mh.LastDelivered = ConsumerMsgPending, p.Sequence, o.rdc[seq]+1, &ts
+		return mh, nil
+	}
+	if o.maxdc > 0 && o.rdc[seq] >= o.maxdc {
+		mh.State, mh.Delivered = ConsumerMsgMaxDeliveries, o.maxdc
+		return mh, nil
+	}
+	switch {
+	case sm != nil && !o.isFilteredMatch(sm.subj):
+		mh.State = ConsumerMsgFiltered
+	case seq >= o.sseq && sm == nil:
+		mh.State = ConsumerMsgDeleted
+	case seq >= o.sseq:
+		mh.State = ConsumerMsgUndelivered
+	case o.cfg.AckPolicy == AckNone:
+		mh.State = ConsumerMsgDelivered
+	default:
+		mh.State = ConsumerMsgAcked
+	}
+	return mh, nil
+}
+
+// ConsumerExport is the configuration and optionally the delivery and ack state
+// of a consumer, which can be imported again onto a matching stream.
+type ConsumerExport struct {
+	Stream string          `json:"stream_name"`
+	Config *ConsumerConfig `json:"config"`
+	State  *ConsumerState  `json:"state,omitempty"`
+}
+
+// export returns our configuration, and a copy of our state if requested.
+func (o *consumer) export(withState bool) *ConsumerExport {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	cfg := o.cfg
+	ce := &ConsumerExport{Stream: o.stream, Config: &cfg}
+	if withState {
+		ce.State = o.currentState()
+	}
+	return ce
+}
+
+// currentState returns a copy of our delivery and ack state.
+// Lock should be held.
+func (o *consumer) currentState() *ConsumerState {
+	state := &ConsumerState{
+		Delivered: SequencePair{
+			Consumer: o.dseq - 1,
+			Stream:   o.sseq - 1,
+		},
+		AckFloor: SequencePair{
+			Consumer: o.adflr,
+			Stream:   o.asflr,
+		},
+	}
+	if len(o.pending) > 0 {
+		state.Pending = make(map[uint64]*Pending, len(o.pending))
+		for seq, p := range o.pending {
+			state.Pending[seq] = &Pending{p.Sequence, p.Timestamp}
+		}
+	}
+	if len(o.rdc) > 0 {
+		state.Redelivered = maps.Clone(o.rdc)
+	}
+	return state
+}
+
+// snapshotState returns our delivery and ack state encoded as a portable blob,
+// which can be restored onto this or another consumer with restoreState.
+func (o *consumer) snapshotState() []byte {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return encodeConsumerState(o.currentState())
+}
+
+// restoreState replaces our delivery and ack state with the given one.
+// The replicated path requires quorum first, the reply will be answered once the
+// state has been applied. Returns true if the state was applied directly.
+func (o *consumer) restoreState(state *ConsumerState, reply string) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return false, errConsumerClosed
+	}
+	if o.node != nil {
+		if !o.isLeader() {
+			return false, errNotLeader
+		}
+		o.propose(encodeConsumerRestore(state, reply))
+		if reply != _EMPTY_ {
+			if o.rsm == nil {
+				o.rsm = make(map[string]bool, 1)
+			}
+			o.rsm[reply] = true
+		}
+		return false, nil
+	}
+	if err := o.applyRestoredState(state); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// applyRestoredState replaces our state, both in memory and in the store.
+// Lock should be held.
+func (o *consumer) applyRestoredState(state *ConsumerState) error {
+	if o.store != nil {
+		// The restored state may be behind our current one, so reset first.
+		if err := o.store.Reset(0); err != nil {
+			return err
+		}
+		if err := o.store.Update(state); err != nil {
+			return err
+		}
+	}
+	o.rdq = nil
+	o.rdqi.Empty()
+	o.ldt, o.lat = time.Time{}, time.Time{}
+	o.applyState(state)
+	if len(o.rdc) > 0 {
+		o.checkRedelivered()
+	}
+
+	// Cleanup messages that lost interest.
+	if o.retention == InterestPolicy {
+		if mset := o.mset; mset != nil {
+			o.mu.Unlock()
+			ss := mset.state()
+			o.checkStateForInterestStream(&ss)
+			o.mu.Lock()
+		}
+	}
+
+	// Recalculate pending, and re-trigger message delivery.
+	if o.isLeader() {
+		o.streamNumPending()
+		o.signalNewMessages()
+	}
+	return nil
+}
+
+// checkConsumerImportState makes sure an imported state is consistent
+// with itself and can be used with the consumer configuration.
+func checkConsumerImportState(cfg *ConsumerConfig, state *ConsumerState) error {
+	if state.AckFloor.Stream > state.Delivered.Stream || state.AckFloor.Consumer > state.Delivered.Consumer {
+		return errors.New("ack floor is above delivered")
+	}
+	if cfg.AckPolicy == AckNone && len(state.Pending) > 0 {
+		return errors.New("pending messages require an ack policy")
+	}
+	for seq, p := range state.Pending {
+		if p == nil || seq <= state.AckFloor.Stream || seq > state.Delivered.Stream {
+			return fmt.Errorf("pending stream sequence %d is not between ack floor and delivered", seq)
+		}
+	}
+	return nil
+}
+
+// moveConfig returns our configuration to recreate us on another stream. Stream sequences
+// don't carry over between streams, so our position is mapped by time instead. If we have
+// acknowledged messages, we start from the messages stored after our ack floor.
+// Only the ack floor carries over, so moving is refused while messages are pending acknowledgement.
+func (o *consumer) moveConfig() (*ConsumerConfig, error) {
+	o.mu.RLock()
+	cfg, mset, asflr, pending := o.cfg, o.mset, o.asflr, len(o.pending)
+	o.mu.RUnlock()
+
+	if pending > 0 {
+		return nil, fmt.Errorf("consumer has %d messages pending acknowledgement", pending)
+	}
+	if mset == nil {
+		return &cfg, nil
+	}
+	var start time.Time
+	if asflr > 0 {
+		start = mset.startTimeAfter(asflr)
+	} else if cfg.DeliverPolicy == DeliverByStartSequence && cfg.OptStartSeq > 0 {
+		start = mset.startTimeAfter(cfg.OptStartSeq - 1)
+	} else {
+		return &cfg, nil
+	}
+	cfg.DeliverPolicy, cfg.OptStartSeq, cfg.OptStartTime = DeliverByStartTime, 0, &start
+	return &cfg, nil
+}
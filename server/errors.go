@@ -42,6 +42,12 @@ var (
 	// ErrMaxPayload represents an error condition when the payload is too big.
 	ErrMaxPayload = errors.New("maximum payload exceeded")
 
+	// ErrMaxHeaderSize represents an error condition when the message headers are too big.
+	ErrMaxHeaderSize = errors.New("maximum header size exceeded")
+
+	// ErrMaxHeaderValueSize represents an error condition when a message header value is too big.
+	ErrMaxHeaderValueSize = errors.New("maximum header value size exceeded")
+
 	// ErrMaxControlLine represents an error condition when the control line is too big.
 	ErrMaxControlLine = errors.New("maximum control line exceeded")
 
@@ -68,6 +74,13 @@ var (
 	// connections.
 	ErrTooManyAccountConnections = errors.New("maximum account active connections exceeded")
 
+	// ErrAccountRequiresTLS signals that a client tried to connect to an account that only
+	// allows TLS connections without using TLS.
+	ErrAccountRequiresTLS = errors.New("account requires a TLS connection")
+
+	// ErrClientBlocked signals that a client is on the connect blocklist.
+	ErrClientBlocked = errors.New("client is blocked")
+
 	// ErrLeafNodeLoop signals a leafnode is trying to register for a cluster we already have registered.
 	ErrLeafNodeLoop = errors.New("leafnode loop detected")
 
@@ -78,6 +91,15 @@ var (
 	// ErrTooManySubTokens signals a client that the subject has too many tokens.
 	ErrTooManySubTokens = errors.New("subject has exceeded number of tokens limit")
 
+	// ErrSubSubjectTooLong signals a client that the subscription subject exceeds the length limit.
+	ErrSubSubjectTooLong = errors.New("subscription subject has exceeded length limit")
+
+	// ErrWildcardSubNotAllowed signals a client that the account does not allow this wildcard subscription.
+	ErrWildcardSubNotAllowed = errors.New("wildcard subscription not allowed by account")
+
+	// ErrSubsChurnExceeded signals a client that it is subscribing and unsubscribing too fast.
+	ErrSubsChurnExceeded = errors.New("subscription churn rate exceeded")
+
 	// ErrClientConnectedToRoutePort represents an error condition when a client
 	// attempted to connect to the route listen port.
 	ErrClientConnectedToRoutePort = errors.New("attempted to connect to route port")
@@ -94,6 +94,9 @@ const (
 
 	ocspPeerRejectEventSubj           = "$SYS.SERVER.%s.OCSP.PEER.CONN.REJECT"
 	ocspPeerChainlinkInvalidEventSubj = "$SYS.SERVER.%s.OCSP.PEER.LINK.INVALID"
+
+	leafNodeLoopEventSubj = "$SYS.SERVER.%s.LEAFNODE.LOOP"
+	raftApplySkippedSubj  = "$SYS.SERVER.%s.RAFT.APPLY.SKIPPED"
 )
 
 // FIXME(dlc) - make configurable.
@@ -204,6 +207,33 @@ type OCSPPeerChainlinkInvalidEventMsg struct {
 // OCSPPeerChainlinkInvalidEventMsgType is the schema type for OCSPPeerChainlinkInvalidEventMsg
 const OCSPPeerChainlinkInvalidEventMsgType = "io.nats.server.advisory.v1.ocsp_peer_link_invalid"
 
+// LeafNodeLoopEventMsg is sent when messages received from a leafnode connection were dropped,
+// because they already passed through this server's cluster or through too many clusters.
+type LeafNodeLoopEventMsg struct {
+	TypedEvent
+	Server  ServerInfo `json:"server"`
+	Account string     `json:"account"`
+	Subject string     `json:"subject"`
+	Path    []string   `json:"path"`
+	Dropped uint64     `json:"dropped"`
+}
+
+// LeafNodeLoopEventMsgType is the schema type for LeafNodeLoopEventMsg
+const LeafNodeLoopEventMsgType = "io.nats.server.advisory.v1.leafnode_loop"
+
+// RaftApplySkippedEventMsg is sent when a committed entry of a Raft group kept failing to
+// apply, and was skipped following the group's apply error policy.
+type RaftApplySkippedEventMsg struct {
+	TypedEvent
+	Server ServerInfo `json:"server"`
+	Group  string     `json:"group"`
+	Index  uint64     `json:"index"`
+	Error  string     `json:"error"`
+}
+
+// RaftApplySkippedEventMsgType is the schema type for RaftApplySkippedEventMsg
+const RaftApplySkippedEventMsgType = "io.nats.server.advisory.v1.raft_apply_skipped"
+
 // AccountNumConns is an event that will be sent from a server that is tracking
 // a given account when the number of connections changes. It will also HB
 // updates in the absence of any changes.
@@ -329,7 +359,8 @@ type ClientInfo struct {
 	ClientType string        `json:"client_type,omitempty"`
 	MQTTClient string        `json:"client_id,omitempty"` // This is the MQTT client ID
 	Nonce      string        `json:"nonce,omitempty"`
-	Reply      string        `json:"reply,omitempty"` // Original reply subject after a service import (only when needed).
+	Reply      string        `json:"reply,omitempty"`   // Original reply subject after a service import (only when needed).
+	APIEnc     string        `json:"api_enc,omitempty"` // Requested JetStream API response encoding (only when not JSON).
 }
 
 // forAssignmentSnap returns the minimum amount of ClientInfo we need for assignment snapshots.
@@ -360,6 +391,7 @@ func (ci *ClientInfo) forAdvisory() *ClientInfo {
 	cci := *ci
 	cci.Jwt = _EMPTY_
 	cci.Alternates = nil
+	cci.APIEnc = _EMPTY_
 	return &cci
 }
 
@@ -778,6 +810,27 @@ func (s *Server) sendInternalResponse(subj string, response *ServerAPIResponse)
 	s.mu.RUnlock()
 }
 
+// sendRaftApplySkippedEvent sends an advisory about a committed entry of a Raft group
+// that was skipped, because it kept failing to apply.
+func (s *Server) sendRaftApplySkippedEvent(group string, index uint64, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.eventsEnabled() {
+		return
+	}
+	m := RaftApplySkippedEventMsg{
+		TypedEvent: TypedEvent{
+			Type: RaftApplySkippedEventMsgType,
+			ID:   s.nextEventID(),
+			Time: time.Now().UTC(),
+		},
+		Group: group,
+		Index: index,
+		Error: err.Error(),
+	}
+	s.sendInternalMsg(fmt.Sprintf(raftApplySkippedSubj, s.info.ID), _EMPTY_, &m.Server, &m)
+}
+
 // Used to send internal messages from other system clients to avoid no echo issues.
 func (c *client) sendInternalMsg(subj, rply string, si *ServerInfo, msg any) {
 	if c == nil {
@@ -16,6 +16,7 @@ package server
 import (
 	"archive/tar"
 	"bytes"
+	"cmp"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -73,6 +74,9 @@ type FileStoreConfig struct {
 	Cipher StoreCipher
 	// Compression is the algorithm to use when compressing.
 	Compression StoreCompression
+	// TombstoneMaxAge is how long after the timestamp of a removed message its tombstone
+	// is kept in a later block. Zero means tombstones are only removed when no longer needed.
+	TombstoneMaxAge time.Duration
 
 	// Internal reference to our server.
 	srv *Server
@@ -110,6 +114,9 @@ type StoreCompression uint8
 const (
 	NoCompression StoreCompression = iota
 	S2Compression
+	// s2DictCompression is never configured directly, it is only recorded in the
+	// metadata of blocks compressed with the stream's compression dictionary.
+	s2DictCompression
 )
 
 func (alg StoreCompression) String() string {
@@ -118,6 +125,8 @@ func (alg StoreCompression) String() string {
 		return "None"
 	case S2Compression:
 		return "S2"
+	case s2DictCompression:
+		return "S2 Dictionary"
 	default:
 		return "Unknown StoreCompression"
 	}
@@ -189,6 +198,7 @@ type fileStore struct {
 	prf         keyGen
 	oldprf      keyGen
 	aek         cipher.AEAD
+	dict        *s2.Dict // Compression dictionary, immutable once the store is created.
 	lmb         *msgBlock
 	blks        []*msgBlock
 	bim         map[uint32]*msgBlock
@@ -212,6 +222,8 @@ type fileStore struct {
 	firstMoved  bool
 	ttls        *thw.HashWheel
 	scheduling  *MsgScheduling
+	ssq         subjectSequences
+	lrw         *subjectRecency
 	sdm         *SDMMeta
 	lpex        time.Time // Last PurgeEx call.
 }
@@ -262,6 +274,7 @@ type msgBlock struct {
 	syncAlways bool
 	noCompact  bool
 	closed     bool
+	tombExp    int64  // When the next tombstone for a prior block expires, 0 if unknown.
 	ttls       uint64 // How many msgs have TTLs?
 	schedules  uint64 // How many msgs have schedules?
 
@@ -294,6 +307,8 @@ const (
 	hdrLen = 2
 	// This is where we keep the streams.
 	streamsDir = "streams"
+	// This prefixes the directory a renamed stream's store is staged in, recovery removes these.
+	renameStagingPrefix = ".rename."
 	// This is where we keep inflight batches for streams.
 	batchesDir = "batches"
 	// This is where we keep the message store blocks.
@@ -302,6 +317,8 @@ const (
 	purgeDir = "__msgs__"
 	// This is where we temporarily move the new message block during purge.
 	newMsgDir = "__new_msgs__"
+	// This is where rewritten message blocks are staged before being swapped in.
+	compactedDir = "__compacted__"
 	// used to scan blk file names.
 	blkScan = "%d.blk"
 	// suffix of a block file
@@ -347,6 +364,9 @@ const (
 	// This is the encoded message scheduling file.
 	msgSchedulingStreamStateFile = "sched.db"
 
+	// This is the encoded per-subject sequences file.
+	subjSeqStreamStateFile = "ssq.db"
+
 	// AEK key sizes
 	minMetaKeySize = 64
 	minBlkKeySize  = 64
@@ -387,6 +407,26 @@ func newFileStore(fcfg FileStoreConfig, cfg StreamConfig) (*fileStore, error) {
 	return newFileStoreWithCreated(fcfg, cfg, time.Now().UTC(), nil, nil)
 }
 
+// swapCompactedMsgs swaps message blocks staged in compactedDir in for the current ones.
+// Once staged, the swap is only finished here, so it is safe to go down at any point.
+func swapCompactedMsgs(storeDir string) error {
+	cdir := filepath.Join(storeDir, compactedDir)
+	if _, err := os.Stat(cdir); os.IsNotExist(err) {
+		return nil
+	}
+	// If the staged blocks were already moved in we only need to cleanup.
+	if _, err := os.Stat(filepath.Join(cdir, msgDir)); err == nil {
+		mdir := filepath.Join(storeDir, msgDir)
+		if err := os.RemoveAll(mdir); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(cdir, msgDir), mdir); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(cdir)
+}
+
 func newFileStoreWithCreated(fcfg FileStoreConfig, cfg StreamConfig, created time.Time, prf, oldprf keyGen) (fs *fileStore, err error) {
 	if cfg.Name == _EMPTY_ {
 		return nil, fmt.Errorf("name required")
@@ -429,6 +469,11 @@ func newFileStoreWithCreated(fcfg FileStoreConfig, cfg StreamConfig, created tim
 	os.Remove(tmpfile.Name())
 	dios <- struct{}{}
 
+	// Finish swapping in rewritten message blocks if we went down half way.
+	if err := swapCompactedMsgs(fcfg.StoreDir); err != nil {
+		return nil, fmt.Errorf("could not swap in compacted message blocks - %v", err)
+	}
+
 	fs = &fileStore{
 		fcfg:   fcfg,
 		psim:   stree.NewSubjectTree[psi](),
@@ -440,6 +485,11 @@ func newFileStoreWithCreated(fcfg FileStoreConfig, cfg StreamConfig, created tim
 		fsld:   make(chan struct{}),
 		srv:    fcfg.srv,
 	}
+	if len(cfg.CompressionDict) > 0 {
+		if fs.dict = s2.MakeDict(cfg.CompressionDict, nil); fs.dict == nil {
+			return nil, fmt.Errorf("invalid compression dictionary")
+		}
+	}
 
 	// Register with access time service.
 	ats.Register()
@@ -563,6 +613,13 @@ func newFileStoreWithCreated(fcfg FileStoreConfig, cfg StreamConfig, created tim
 		}
 	}
 
+	// See if we can bring back our per-subject sequences from disk.
+	if cfg.SubjectSequence {
+		if err = fs.recoverSubjectSequenceState(); err != nil && !os.IsNotExist(err) {
+			fs.warn("Recovering subject sequence state from index errored: %v", err)
+		}
+	}
+
 	// Also make sure we get rid of old idx and fss files on return.
 	// Do this in separate go routine vs inline and at end of processing.
 	defer func() {
@@ -722,6 +779,15 @@ func (fs *fileStore) UpdateConfig(cfg *StreamConfig) error {
 	} else if !cfg.AllowMsgSchedules && fs.scheduling != nil {
 		fs.scheduling = nil
 	}
+	// Create or delete the per-subject sequences if needed.
+	if cfg.SubjectSequence && fs.ssq == nil {
+		if err := fs.recoverSubjectSequenceState(); err != nil {
+			fs.mu.Unlock()
+			return err
+		}
+	} else if !cfg.SubjectSequence && fs.ssq != nil {
+		fs.ssq = nil
+	}
 
 	// Limits checks and enforcement.
 	if err := fs.enforceMsgLimit(); err != nil {
@@ -749,6 +815,10 @@ func (fs *fileStore) UpdateConfig(cfg *StreamConfig) error {
 			return err
 		}
 	}
+	if err := fs.enforceSubjectLimit(_EMPTY_); err != nil {
+		fs.mu.Unlock()
+		return err
+	}
 
 	if lmb := fs.lmb; lmb != nil {
 		// Enable/disable async flush depending on if it's supported and already initialized.
@@ -2328,6 +2398,81 @@ func (fs *fileStore) recoverMsgSchedulingState() error {
 	return nil
 }
 
+// Lock should be held.
+func (fs *fileStore) recoverSubjectSequenceState() error {
+	<-dios
+	fn := filepath.Join(fs.fcfg.StoreDir, msgDir, subjSeqStreamStateFile)
+	buf, err := os.ReadFile(fn)
+	dios <- struct{}{}
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	fs.ssq = make(subjectSequences)
+
+	var ssqSeq uint64
+	if err == nil {
+		ssqSeq, err = fs.ssq.decode(buf)
+		if err != nil {
+			fs.warn("Error decoding subject sequence state: %s", err)
+			fs.ssq = make(subjectSequences)
+			ssqSeq = 0
+			_ = os.Remove(fn)
+		}
+	}
+
+	if ssqSeq < fs.state.FirstSeq {
+		ssqSeq = fs.state.FirstSeq
+	}
+
+	if fs.state.Msgs > 0 && ssqSeq <= fs.state.LastSeq {
+		fs.warn("Subject sequence state is outdated; attempting to recover using linear scan (seq %d to %d)", ssqSeq, fs.state.LastSeq)
+		var (
+			mb     *msgBlock
+			sm     StoreMsg
+			mblseq uint64
+		)
+		for seq := ssqSeq; seq <= fs.state.LastSeq; seq++ {
+		retry:
+			if mb == nil {
+				if mb = fs.selectMsgBlock(seq); mb == nil {
+					// Selecting the message block should return a block that contains this sequence,
+					// or a later block if it can't be found.
+					// It's an error if we can't find any block within the bounds of first and last seq.
+					fs.warn("Error loading msg block with seq %d for recovering subject sequences", seq)
+					continue
+				}
+				seq = max(seq, atomic.LoadUint64(&mb.first.seq))
+				mblseq = atomic.LoadUint64(&mb.last.seq)
+			}
+			if seq > mblseq {
+				// We've reached the end of the loaded block, so let's go back to the
+				// beginning and process the next block.
+				mb.tryForceExpireCache()
+				mb = nil
+				if seq <= fs.state.LastSeq {
+					goto retry
+				}
+				// Done.
+				break
+			}
+			mb.mu.Lock()
+			msg, _, err := mb.fetchMsgNoCopyLocked(seq, &sm)
+			if err != nil {
+				mb.finishedWithCache()
+				mb.mu.Unlock()
+				// Deleted messages are expected here.
+				continue
+			}
+			fs.ssq.track(msg.subj, msg.hdr)
+			mb.finishedWithCache()
+			mb.mu.Unlock()
+		}
+	}
+	return nil
+}
+
 // Grabs last checksum for the named block file.
 // Takes into account encryption etc.
 func (mb *msgBlock) lastChecksum() []byte {
@@ -2747,6 +2892,50 @@ func copyMsgBlocks(src []*msgBlock) []*msgBlock {
 	return dst
 }
 
+// LastSubjectSequence returns the highest per-subject sequence stored for the subject.
+func (fs *fileStore) LastSubjectSequence(subject string) uint64 {
+	fs.mu.RLock()
+	if fs.ssq != nil {
+		seq := fs.ssq[subject]
+		fs.mu.RUnlock()
+		return seq
+	}
+	fs.mu.RUnlock()
+	return lastSubjectSequence(fs, subject)
+}
+
+// ExpiringMsgs returns the messages that will expire, either due to MaxAge or their TTL,
+// after from and up to and including to, ordered by sequence.
+func (fs *fileStore) ExpiringMsgs(from, to time.Time) []ExpiringMsg {
+	expiring := make(map[uint64]int64)
+	fs.mu.RLock()
+	maxAge := fs.cfg.MaxAge
+	if fs.ttls != nil {
+		fs.ttls.Expiring(from.UnixNano(), to.UnixNano(), func(seq uint64, expires int64) {
+			expiring[seq] = expires
+		})
+	}
+	fs.mu.RUnlock()
+
+	if maxAge > 0 {
+		// Messages that expire in the window were stored MaxAge before it.
+		start := fs.GetSeqFromTime(from.Add(-maxAge).Add(time.Nanosecond))
+		end := fs.GetSeqFromTime(to.Add(-maxAge).Add(time.Nanosecond))
+		var smv StoreMsg
+		for seq := start; seq > 0 && seq < end; seq++ {
+			sm, nseq, err := fs.LoadNextMsg(fwcs, true, seq, &smv)
+			if err != nil || sm == nil || nseq >= end {
+				break
+			}
+			if expires := sm.ts + int64(maxAge); expiring[nseq] == 0 || expires < expiring[nseq] {
+				expiring[nseq] = expires
+			}
+			seq = nseq
+		}
+	}
+	return sortedExpiringMsgs(expiring)
+}
+
 // GetSeqFromTime looks for the first sequence number that has
 // the message with >= timestamp.
 func (fs *fileStore) GetSeqFromTime(t time.Time) uint64 {
@@ -4901,6 +5090,12 @@ func (fs *fileStore) storeRawMsg(subj string, hdr, msg []byte, seq uint64, ts, t
 			}
 		}
 	}
+	// Check if we are discarding new subjects when we reach the subject limit.
+	if discardNewCheck && fs.cfg.DiscardNewSubjects && fs.cfg.MaxSubjects > 0 && len(subj) > 0 && fs.psim != nil {
+		if _, ok := fs.psim.Find(stringToBytes(subj)); !ok && int64(fs.psim.Size()) >= fs.cfg.MaxSubjects {
+			return ErrMaxSubjects
+		}
+	}
 
 	// Check sequence.
 	if seq != fs.state.LastSeq+1 {
@@ -5009,6 +5204,14 @@ func (fs *fileStore) storeRawMsg(subj string, hdr, msg []byte, seq uint64, ts, t
 	if err = fs.enforceBytesLimit(); err != nil {
 		return err
 	}
+	if err = fs.enforceSubjectLimit(subj); err != nil {
+		return err
+	}
+
+	// Per-subject sequence.
+	if fs.ssq != nil {
+		fs.ssq.track(subj, hdr)
+	}
 
 	// Per-message TTL.
 	if ttl > 0 {
@@ -5312,6 +5515,93 @@ func (fs *fileStore) SkipMsgs(seq uint64, num uint64) error {
 	return nil
 }
 
+// renameFileStore rewrites the stopped stream store at fcfg into a new store for the
+// renamed stream, along with the state of the given file based consumers.
+// Message and consumer checksums are keyed by the stream name, so the store can not
+// simply be moved. The new store is written into a "." prefixed staging directory,
+// which recovery removes if we are killed midway, and is only moved into place once
+// complete. The old store is removed after that.
+func renameFileStore(fcfg FileStoreConfig, ocfg, ncfg FileStreamInfo, consumers []*FileConsumerInfo) error {
+	sdir := filepath.Dir(fcfg.StoreDir)
+	ndir := filepath.Join(sdir, ncfg.Name)
+	if _, err := os.Stat(ndir); err == nil {
+		return fmt.Errorf("store directory for stream %q already exists", ncfg.Name)
+	}
+
+	ofs, err := newFileStoreWithCreated(fcfg, ocfg.StreamConfig, ocfg.Created, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer ofs.Stop()
+
+	nfcfg := fcfg
+	nfcfg.StoreDir = filepath.Join(sdir, renameStagingPrefix+ncfg.Name)
+	// Could be left over from an earlier attempt.
+	if err := os.RemoveAll(nfcfg.StoreDir); err != nil {
+		return err
+	}
+	nfs, err := newFileStoreWithCreated(nfcfg, ncfg.StreamConfig, ncfg.Created, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err = copyFileStore(ofs, nfs, consumers); err == nil {
+		err = nfs.Stop()
+	} else {
+		nfs.Stop()
+	}
+	if err == nil {
+		err = os.Rename(nfcfg.StoreDir, ndir)
+	}
+	if err != nil {
+		os.RemoveAll(nfcfg.StoreDir)
+		return err
+	}
+	ofs.Stop()
+
+	// Same as deleting the store, move it out of the way first so it is not recovered
+	// next to the renamed one if we are killed before it is removed.
+	odir := filepath.Join(sdir, tsep+filepath.Base(fcfg.StoreDir))
+	if err := os.Rename(fcfg.StoreDir, odir); err != nil {
+		return err
+	}
+	return removeAllWithRetry(odir)
+}
+
+// copyFileStore copies all messages from ofs into the empty store nfs, preserving
+// sequences, timestamps and any gaps, followed by the consumer states.
+func copyFileStore(ofs, nfs *fileStore, consumers []*FileConsumerInfo) error {
+	if err := copyStoreMsgs(ofs, nfs); err != nil {
+		return err
+	}
+	for _, cfg := range consumers {
+		// Memory based consumers do not persist any state.
+		if cfg.MemoryStorage {
+			continue
+		}
+		ocs, err := ofs.ConsumerStore(cfg.Name, cfg.Created, &cfg.ConsumerConfig)
+		if err != nil {
+			return err
+		}
+		cstate, err := ocs.State()
+		ocs.Stop()
+		if err != nil {
+			return err
+		}
+		ncs, err := nfs.ConsumerStore(cfg.Name, cfg.Created, &cfg.ConsumerConfig)
+		if err != nil {
+			return err
+		}
+		err = ncs.Update(cstate)
+		if serr := ncs.Stop(); err == nil {
+			err = serr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // FlushAllPending flushes all data that was still pending to be written.
 func (fs *fileStore) FlushAllPending() error {
 	fs.mu.Lock()
@@ -5323,6 +5613,63 @@ func (fs *fileStore) FlushAllPending() error {
 	return fs.checkAndFlushLastBlock()
 }
 
+// Sync flushes all pending data and syncs the blocks that were written to since their last sync.
+// Unlike syncBlocks, this does not compact or close idle blocks, so it's cheap enough to be
+// called by the Raft layer before acknowledging entries as durable.
+func (fs *fileStore) Sync() error {
+	fs.mu.Lock()
+	if fs.werr != nil {
+		err := fs.werr
+		fs.mu.Unlock()
+		return err
+	}
+	blks := append([]*msgBlock(nil), fs.blks...)
+	fs.mu.Unlock()
+
+	for _, mb := range blks {
+		mb.mu.Lock()
+		if mb.closed {
+			mb.mu.Unlock()
+			continue
+		}
+		if _, err := mb.flushPendingMsgsLocked(); err != nil {
+			mb.mu.Unlock()
+			return err
+		}
+		if !mb.needSync {
+			mb.mu.Unlock()
+			continue
+		}
+		fd, didOpen := mb.mfd, false
+		if fd == nil {
+			var err error
+			<-dios
+			fd, err = os.OpenFile(mb.mfn, os.O_RDWR, defaultFilePerms)
+			dios <- struct{}{}
+			if err != nil {
+				mb.mu.Unlock()
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			didOpen = true
+		}
+		err := fd.Sync()
+		if didOpen {
+			_ = fd.Close()
+		}
+		if err == nil {
+			mb.needSync = false
+		}
+		mb.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Lock should be held.
 func (fs *fileStore) rebuildFirst() error {
 	if len(fs.blks) == 0 {
@@ -5650,6 +5997,114 @@ func (fs *fileStore) removeMsgViaLimits(seq uint64) (bool, error) {
 	return fs.removeMsg(seq, false, true, false)
 }
 
+// enforceSubjectLimit will evict the least recently written subjects while we are above
+// the max subjects limit, unless we discard new subjects instead. The keep subject,
+// which is the one we just stored a message for, is never evicted.
+// Lock should be held.
+func (fs *fileStore) enforceSubjectLimit(keep string) error {
+	if fs.cfg.MaxSubjects <= 0 || fs.cfg.DiscardNewSubjects || fs.psim == nil {
+		fs.lrw = nil
+		return nil
+	}
+	if fs.lrw == nil {
+		if err := fs.rebuildSubjectRecency(); err != nil {
+			return err
+		}
+	} else if keep != _EMPTY_ {
+		fs.lrw.touch(keep)
+	}
+	for n := fs.psim.Size(); int64(n) > fs.cfg.MaxSubjects; {
+		subj := fs.lrw.oldest(keep)
+		if subj == _EMPTY_ {
+			return nil
+		}
+		// The subject could have lost all its messages by other means, like a purge.
+		if _, ok := fs.psim.Find(stringToBytes(subj)); !ok {
+			fs.lrw.remove(subj)
+			continue
+		}
+		if err := fs.removeSubjectViaLimits(subj); err != nil {
+			return err
+		}
+		// Make sure we made progress.
+		if nn := fs.psim.Size(); nn < n {
+			n = nn
+		} else {
+			break
+		}
+	}
+	return nil
+}
+
+// rebuildSubjectRecency orders all subjects by their last message, oldest first.
+// Lock should be held.
+func (fs *fileStore) rebuildSubjectRecency() error {
+	type subjLast struct {
+		subj string
+		last uint64
+	}
+	byBlk := make(map[uint32][]string)
+	fs.psim.IterFast(func(subj []byte, info *psi) bool {
+		byBlk[info.lblk] = append(byBlk[info.lblk], bytesToString(subj))
+		return true
+	})
+	subjs := make([]subjLast, 0, fs.psim.Size())
+	for bi, bsubjs := range byBlk {
+		mb := fs.bim[bi]
+		if mb == nil {
+			for _, subj := range bsubjs {
+				subjs = append(subjs, subjLast{subj, 0})
+			}
+			continue
+		}
+		mb.mu.Lock()
+		if err := mb.ensurePerSubjectInfoLoaded(); err != nil {
+			mb.mu.Unlock()
+			return err
+		}
+		for _, subj := range bsubjs {
+			var last uint64
+			if ss, ok := mb.fss.Find(stringToBytes(subj)); ok && ss != nil {
+				if ss.lastNeedsUpdate {
+					if err := mb.recalculateForSubj(subj, ss); err != nil {
+						mb.mu.Unlock()
+						return err
+					}
+				}
+				last = ss.Last
+			}
+			subjs = append(subjs, subjLast{subj, last})
+		}
+		mb.mu.Unlock()
+	}
+	slices.SortFunc(subjs, func(a, b subjLast) int { return cmp.Compare(a.last, b.last) })
+	fs.lrw = newSubjectRecency()
+	for _, sl := range subjs {
+		fs.lrw.touch(sl.subj)
+	}
+	return nil
+}
+
+// removeSubjectViaLimits will remove all messages for the subject.
+// Lock should be held.
+func (fs *fileStore) removeSubjectViaLimits(subj string) error {
+	bsubj := stringToBytes(subj)
+	for _, ok := fs.psim.Find(bsubj); ok; _, ok = fs.psim.Find(bsubj) {
+		seq, err := fs.firstSeqForSubj(subj)
+		if err != nil {
+			return err
+		} else if seq == 0 {
+			break
+		}
+		if removed, err := fs.removeMsgViaLimits(seq); err != nil {
+			return err
+		} else if !removed {
+			break
+		}
+	}
+	return nil
+}
+
 // RemoveMsg will remove the message from this store.
 // Will return the number of bytes removed.
 func (fs *fileStore) RemoveMsg(seq uint64) (bool, error) {
@@ -5673,6 +6128,9 @@ func (fs *fileStore) removePerSubject(subj string) uint64 {
 		if info.total == 0 {
 			if _, ok = fs.psim.Delete(bsubj); ok {
 				fs.tsl -= len(subj)
+				if fs.lrw != nil {
+					fs.lrw.remove(subj)
+				}
 				return 0
 			}
 		}
@@ -6106,7 +6564,7 @@ func (mb *msgBlock) compactWithFloor(floor uint64, fsDmap *avl.SequenceSet) erro
 	if mb.cmp != NoCompression && len(nbuf) > 0 {
 		originalSize := len(nbuf)
 		var err error
-		if nbuf, err = mb.cmp.Compress(nbuf); err != nil {
+		if nbuf, err = mb.cmp.compress(nbuf, mb.fs.dict); err != nil {
 			return err
 		}
 		meta := &CompressionInfo{
@@ -7515,7 +7973,7 @@ func (mb *msgBlock) recompressOnDiskIfNeeded() error {
 		return nil
 	}
 
-	alg := mb.fs.fcfg.Compression
+	alg := mb.fs.blockCompression()
 
 	// Open up the file block and read in the entire contents into memory.
 	// One of two things will happen:
@@ -7557,7 +8015,7 @@ func (mb *msgBlock) recompressOnDiskIfNeeded() error {
 		// The block is already compressed using some algorithm, so we need
 		// to decompress the block using the existing algorithm before we can
 		// recompress it with the new one.
-		if origBuf, err = meta.Algorithm.Decompress(origBuf); err != nil {
+		if origBuf, err = meta.Algorithm.decompress(origBuf, mb.fs.dict); err != nil {
 			return fmt.Errorf("failed to decompress original block: %w", err)
 		}
 	}
@@ -7594,13 +8052,13 @@ func (mb *msgBlock) atomicOverwriteFile(buf []byte, allowCompress bool) error {
 	}
 
 	alg := NoCompression
-	if calg := mb.fs.fcfg.Compression; calg != NoCompression && allowCompress {
+	if calg := mb.fs.blockCompression(); calg != NoCompression && allowCompress {
 		alg = calg
 		// The original buffer at this point is uncompressed, so we will now compress
 		// it if needed. Note that if the selected algorithm is NoCompression, the
 		// Compress function will just return the input buffer unmodified.
 		originalSize := len(buf)
-		if buf, err = alg.Compress(buf); err != nil {
+		if buf, err = alg.compress(buf, mb.fs.dict); err != nil {
 			return errorCleanup(fmt.Errorf("failed to compress block: %w", err))
 		}
 
@@ -7666,7 +8124,7 @@ func (mb *msgBlock) decompressIfNeeded(buf []byte) ([]byte, error) {
 		// are compressed. If by any chance the metadata claims that the
 		// block is uncompressed, then the input slice is just returned
 		// unmodified.
-		return meta.Algorithm.Decompress(buf[n:])
+		return meta.Algorithm.decompress(buf[n:], mb.fs.dict)
 	}
 }
 
@@ -7760,6 +8218,14 @@ func (fs *fileStore) syncBlocks() {
 			needsCompact = true
 			markDirty = true
 		}
+		// Check if we hold tombstones for messages in prior blocks that are past their max age.
+		var expiredTombs []uint64
+		if mb != lmb && fs.fcfg.TombstoneMaxAge > 0 {
+			if expiredTombs = mb.expiredTombstones(fs.fcfg.TombstoneMaxAge, firstSeq); len(expiredTombs) > 0 {
+				needsCompact = true
+				markDirty = true
+			}
+		}
 
 		// Flush anything that may be pending.
 		if _, err := mb.flushPendingMsgsLocked(); err != nil {
@@ -7785,6 +8251,17 @@ func (fs *fileStore) syncBlocks() {
 				fsDmapLoaded = true
 				fsDmap = fs.deleteMap()
 			}
+			// The blocks holding the messages of expired tombstones need to be compacted first,
+			// after that the sequence gaps left behind are enough to know these were removed.
+			if len(expiredTombs) > 0 {
+				if err := fs.compactTombstonedBlocks(mb, expiredTombs); err != nil {
+					storeFsWerr(err)
+					continue
+				}
+				for _, seq := range expiredTombs {
+					fsDmap.Delete(seq)
+				}
+			}
 			fs.mu.RLock()
 			mb.mu.Lock()
 			// If the block has already been removed in the meantime, we can simply skip.
@@ -8936,6 +9413,62 @@ func (fs *fileStore) SubjectForSeq(seq uint64) (string, error) {
 	return _EMPTY_, ErrStoreMsgNotFound
 }
 
+// MsgExists returns whether the message is still stored, without loading it.
+func (fs *fileStore) MsgExists(seq uint64) bool {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	mb := fs.selectMsgBlock(seq)
+	if mb == nil {
+		return false
+	}
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	return seq >= atomic.LoadUint64(&mb.first.seq) && seq <= atomic.LoadUint64(&mb.last.seq) && !mb.dmap.Exists(seq)
+}
+
+// DeletedRanges calls f in order with the ranges of sequences no longer in the store, starting
+// from the one holding or following start, until f returns false. Interior deletes are walked
+// from the block holding start onward. The store is read locked, so f must not call into it.
+func (fs *fileStore) DeletedRanges(start uint64, f func(first, last uint64) bool) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	// Everything below the first sequence was removed.
+	if fs.state.FirstSeq > 1 && start < fs.state.FirstSeq && !f(1, fs.state.FirstSeq-1) {
+		return
+	}
+	bi, _ := fs.selectMsgBlockWithIndex(max(start, fs.state.FirstSeq))
+	if bi < 0 {
+		return
+	}
+	var prevLast uint64
+	if bi > 0 {
+		prevLast = atomic.LoadUint64(&fs.blks[bi-1].last.seq)
+	}
+	for _, mb := range fs.blks[bi:] {
+		// Detect if we have a gap between these blocks.
+		if fseq := atomic.LoadUint64(&mb.first.seq); prevLast > 0 && prevLast+1 < fseq && fseq > start {
+			if !f(prevLast+1, fseq-1) {
+				return
+			}
+		}
+		more := true
+		mb.mu.RLock()
+		mb.dmap.Range(func(seq uint64) bool {
+			if seq < start {
+				return true
+			}
+			more = f(seq, seq)
+			return more
+		})
+		mb.mu.RUnlock()
+		if !more {
+			return
+		}
+		prevLast = atomic.LoadUint64(&mb.last.seq)
+	}
+}
+
 // LoadMsg will lookup the message by sequence number and return it if found.
 func (fs *fileStore) LoadMsg(seq uint64, sm *StoreMsg) (*StoreMsg, error) {
 	return fs.msgForSeq(seq, sm)
@@ -10474,7 +11007,7 @@ func (fs *fileStore) compact(seq uint64) (purged uint64, rerr error) {
 			}
 			// Recompress if necessary (smb.cmp contains the algorithm used when
 			// the block was loaded from disk, or defaults to NoCompression if not)
-			if nbuf, err = smb.cmp.Compress(nbuf); err != nil {
+			if nbuf, err = smb.cmp.compress(nbuf, smb.fs.dict); err != nil {
 				smb.mu.Unlock()
 				fs.mu.Unlock()
 				return purged, err
@@ -10673,6 +11206,61 @@ func (mb *msgBlock) tombsLocked() []msgId {
 	return tombs
 }
 
+// Return the tombstones for messages prior to this msgBlock that are older than maxAge.
+// Tombstones below the floor are ignored since those are always compacted.
+// Write lock should be held.
+func (mb *msgBlock) expiredTombstones(maxAge time.Duration, floor uint64) []uint64 {
+	now := ats.AccessTime()
+	if mb.tombExp != 0 && now < mb.tombExp {
+		return nil
+	}
+	var expired []uint64
+	next := int64(math.MaxInt64)
+	fseq := atomic.LoadUint64(&mb.first.seq)
+	for _, tomb := range mb.tombsLocked() {
+		if tomb.seq >= fseq || tomb.seq < floor {
+			continue
+		}
+		if exp := tomb.ts + int64(maxAge); exp <= now {
+			expired = append(expired, tomb.seq)
+		} else if exp < next {
+			next = exp
+		}
+	}
+	mb.tombExp = next
+	return expired
+}
+
+// Compacts the blocks holding the messages for these tombstones, such that the messages
+// are not recovered again once the tombstones are removed from this msgBlock.
+func (fs *fileStore) compactTombstonedBlocks(mb *msgBlock, seqs []uint64) error {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	compacted := make(map[*msgBlock]struct{})
+	for _, seq := range seqs {
+		tmb := fs.selectMsgBlock(seq)
+		if tmb == nil || tmb == mb || tmb == fs.lmb {
+			continue
+		}
+		if _, ok := compacted[tmb]; ok {
+			continue
+		}
+		compacted[tmb] = struct{}{}
+		tmb.mu.Lock()
+		var err error
+		// If the last compaction did not change anything there are no removed messages left.
+		if !tmb.closed && !tmb.noCompact {
+			err = tmb.compact()
+		}
+		tmb.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // fs lock should be held.
 func (mb *msgBlock) numPriorTombs() int {
 	mb.mu.Lock()
@@ -11900,13 +12488,16 @@ func (fs *fileStore) _writeFullState(force bool) error {
 	fs.dirty -= priorDirty
 	fs.mu.Unlock()
 
-	// Attempt to write both files, an error in one should not prevent the other from being written.
+	// Attempt to write all files, an error in one should not prevent the others from being written.
 	ttlErr := fs.writeTTLState()
 	schedErr := fs.writeMsgSchedulingState()
+	ssqErr := fs.writeSubjectSequenceState()
 	if ttlErr != nil {
 		return ttlErr
 	} else if schedErr != nil {
 		return schedErr
+	} else if ssqErr != nil {
+		return ssqErr
 	}
 	return nil
 }
@@ -11939,6 +12530,20 @@ func (fs *fileStore) writeMsgSchedulingState() error {
 	return fs.writeFileWithOptionalSync(fn, buf, defaultFilePerms)
 }
 
+func (fs *fileStore) writeSubjectSequenceState() error {
+	fs.mu.RLock()
+	if fs.ssq == nil {
+		fs.mu.RUnlock()
+		return nil
+	}
+	fn := filepath.Join(fs.fcfg.StoreDir, msgDir, subjSeqStreamStateFile)
+	// Must be lseq+1 to identify up to which sequence the subject sequences are valid.
+	buf := fs.ssq.encode(fs.state.LastSeq + 1)
+	fs.mu.RUnlock()
+
+	return fs.writeFileWithOptionalSync(fn, buf, defaultFilePerms)
+}
+
 // Stop the current filestore.
 func (fs *fileStore) Stop() error {
 	return fs.stop(false, true)
@@ -12396,6 +13001,94 @@ func (fs *fileStore) deleteMap() (dmap avl.SequenceSet) {
 	return dmap
 }
 
+// RebuildTotals rescans all message blocks and corrects the message and byte
+// totals, returning by how much they changed. Blocks are scanned one at a time
+// so writers are only held off for the duration of a single block scan.
+func (fs *fileStore) RebuildTotals() (int64, int64, error) {
+	fs.mu.RLock()
+	blks := slices.Clone(fs.blks)
+	fs.mu.RUnlock()
+
+	var md, bd int64
+	for _, mb := range blks {
+		fs.mu.Lock()
+		if fs.isClosed() {
+			fs.mu.Unlock()
+			return md, bd, ErrStoreClosed
+		}
+		// Skip blocks removed while we were scanning.
+		if fs.bim[mb.index] != mb {
+			fs.mu.Unlock()
+			continue
+		}
+		mb.mu.Lock()
+		msgs, bytes, err := mb.countTotalsLocked()
+		if err == nil {
+			bmd, bbd := int64(msgs)-int64(mb.msgs), int64(bytes)-int64(mb.bytes)
+			mb.msgs, mb.bytes = msgs, bytes
+			fs.state.Msgs = uint64(int64(fs.state.Msgs) + bmd)
+			fs.state.Bytes = uint64(int64(fs.state.Bytes) + bbd)
+			md, bd = md+bmd, bd+bbd
+		}
+		mb.mu.Unlock()
+		fs.mu.Unlock()
+		if err != nil {
+			return md, bd, err
+		}
+	}
+
+	// The stream totals could also have drifted from the sum of the blocks.
+	fs.mu.Lock()
+	var msgs, bytes uint64
+	for _, mb := range fs.blks {
+		mb.mu.RLock()
+		msgs, bytes = msgs+mb.msgs, bytes+mb.bytes
+		mb.mu.RUnlock()
+	}
+	md += int64(msgs) - int64(fs.state.Msgs)
+	bd += int64(bytes) - int64(fs.state.Bytes)
+	fs.state.Msgs, fs.state.Bytes = msgs, bytes
+	cb := fs.scb
+	fs.mu.Unlock()
+
+	if cb != nil && (md != 0 || bd != 0) {
+		cb(md, bd, 0, _EMPTY_)
+	}
+	return md, bd, nil
+}
+
+// countTotalsLocked counts the messages and bytes held in this block.
+// Lock should be held.
+func (mb *msgBlock) countTotalsLocked() (uint64, uint64, error) {
+	fseq, lseq := atomic.LoadUint64(&mb.first.seq), atomic.LoadUint64(&mb.last.seq)
+	if fseq == 0 || lseq < fseq {
+		return 0, 0, nil
+	}
+	if mb.cacheNotLoaded() {
+		if err := mb.loadMsgsWithLock(); err != nil {
+			return 0, 0, err
+		}
+		defer mb.finishedWithCache()
+	}
+	var msgs, bytes uint64
+	var smv StoreMsg
+	for seq := fseq; seq <= lseq; seq++ {
+		if mb.dmap.Exists(seq) {
+			continue
+		}
+		sm, err := mb.cacheLookupNoCopy(seq, &smv)
+		if err != nil {
+			if err == ErrStoreMsgNotFound || err == errDeletedMsg {
+				continue
+			}
+			return 0, 0, err
+		}
+		msgs++
+		bytes += fileStoreMsgSize(sm.subj, sm.hdr, sm.msg)
+	}
+	return msgs, bytes, nil
+}
+
 // SyncDeleted will make sure this stream has same deleted state as dbs.
 // This will only process deleted state within our current state.
 func (fs *fileStore) SyncDeleted(dbs DeleteBlocks) error {
@@ -13676,7 +14369,20 @@ func (c *CompressionInfo) UnmarshalMetadata(b []byte) (int, error) {
 	return 4 + n, nil
 }
 
+// blockCompression returns the algorithm to use when compressing blocks, taking
+// into account whether we have a compression dictionary.
+func (fs *fileStore) blockCompression() StoreCompression {
+	if alg := fs.fcfg.Compression; alg != S2Compression || fs.dict == nil {
+		return alg
+	}
+	return s2DictCompression
+}
+
 func (alg StoreCompression) Compress(buf []byte) ([]byte, error) {
+	return alg.compress(buf, nil)
+}
+
+func (alg StoreCompression) compress(buf []byte, dict *s2.Dict) ([]byte, error) {
 	if len(buf) < checksumSize {
 		return nil, fmt.Errorf("uncompressed buffer is too short")
 	}
@@ -13688,6 +14394,11 @@ func (alg StoreCompression) Compress(buf []byte) ([]byte, error) {
 		return buf, nil
 	case S2Compression:
 		writer = s2.NewWriter(&output)
+	case s2DictCompression:
+		if dict == nil {
+			return nil, fmt.Errorf("compression dictionary not available")
+		}
+		writer = &s2DictWriter{dict: dict, w: &output}
 	default:
 		return nil, fmt.Errorf("compression algorithm not known")
 	}
@@ -13717,6 +14428,10 @@ func (alg StoreCompression) Compress(buf []byte) ([]byte, error) {
 }
 
 func (alg StoreCompression) Decompress(buf []byte) ([]byte, error) {
+	return alg.decompress(buf, nil)
+}
+
+func (alg StoreCompression) decompress(buf []byte, dict *s2.Dict) ([]byte, error) {
 	if len(buf) < checksumSize {
 		return nil, fmt.Errorf("compressed buffer is too short")
 	}
@@ -13729,6 +14444,11 @@ func (alg StoreCompression) Decompress(buf []byte) ([]byte, error) {
 		return buf, nil
 	case S2Compression:
 		reader = io.NopCloser(s2.NewReader(input))
+	case s2DictCompression:
+		if dict == nil {
+			return nil, fmt.Errorf("compression dictionary not available")
+		}
+		reader = io.NopCloser(&s2DictReader{dict: dict, r: input})
 	default:
 		return nil, fmt.Errorf("compression algorithm not known")
 	}
@@ -13745,6 +14465,79 @@ func (alg StoreCompression) Decompress(buf []byte) ([]byte, error) {
 	return output, reader.Close()
 }
 
+// s2DictChunkSize matches the default block size of the s2 stream format.
+const s2DictChunkSize = 1 << 20
+
+// s2DictWriter splits the input into chunks that are each encoded as an s2 block using
+// the dictionary. Each is written as its uvarint encoded length followed by the block.
+type s2DictWriter struct {
+	dict *s2.Dict
+	w    io.Writer
+	buf  []byte
+}
+
+func (w *s2DictWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		take := min(len(p), s2DictChunkSize-len(w.buf))
+		w.buf, p = append(w.buf, p[:take]...), p[take:]
+		if len(w.buf) == s2DictChunkSize {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *s2DictWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	enc := w.dict.Encode(nil, w.buf)
+	if _, err := w.w.Write(binary.AppendUvarint(nil, uint64(len(enc)))); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(enc); err != nil {
+		return err
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
+
+func (w *s2DictWriter) Close() error {
+	return w.flush()
+}
+
+// s2DictReader decodes the chunks written by s2DictWriter.
+type s2DictReader struct {
+	dict *s2.Dict
+	r    *bytes.Reader
+	buf  []byte
+}
+
+func (r *s2DictReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		l, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return 0, err
+		}
+		if l > uint64(r.r.Len()) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		enc := make([]byte, l)
+		if _, err = io.ReadFull(r.r, enc); err != nil {
+			return 0, err
+		}
+		if r.buf, err = r.dict.Decode(nil, enc); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
 // writeFileWithOptionalSync is equivalent to os.WriteFile() but optionally
 // sets O_SYNC on the open file if SyncAlways is set. The dios semaphore is
 // handled automatically by this function, so don't wrap calls to it in dios.
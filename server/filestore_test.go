@@ -11371,6 +11371,80 @@ func TestFileStoreTombstonesSelectNextFirstCleanupOnRecovery(t *testing.T) {
 	})
 }
 
+func TestFileStoreTombstoneMaxAge(t *testing.T) {
+	testFileStoreAllPermutations(t, func(t *testing.T, fcfg FileStoreConfig) {
+		fcfg.BlockSize = 1024
+		fcfg.TombstoneMaxAge = 250 * time.Millisecond
+		cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo"}, Storage: FileStorage}
+		created := time.Now()
+		fs, err := newFileStoreWithCreated(fcfg, cfg, created, prf(&fcfg), nil)
+		require_NoError(t, err)
+		defer fs.Stop()
+
+		numTombs := func() int {
+			fs.mu.RLock()
+			defer fs.mu.RUnlock()
+			var tombs int
+			for _, mb := range fs.blks {
+				tombs += mb.numPriorTombs()
+			}
+			return tombs
+		}
+
+		msg := bytes.Repeat([]byte("X"), 100)
+		for i := 0; i < 50; i++ {
+			_, _, err = fs.StoreMsg("foo", nil, msg, 0)
+			require_NoError(t, err)
+		}
+		// Interior deletes in prior blocks place tombstones in the last block.
+		for seq := uint64(2); seq <= 40; seq += 2 {
+			removed, err := fs.RemoveMsg(seq)
+			require_NoError(t, err)
+			require_True(t, removed)
+		}
+		// Make sure the tombstones are no longer in the last block.
+		for i := 0; i < 20; i++ {
+			_, _, err = fs.StoreMsg("foo", nil, msg, 0)
+			require_NoError(t, err)
+		}
+		before := fs.State()
+		require_Equal(t, before.Msgs, 50)
+
+		// Tombstones still needed are kept until they are past their max age.
+		fs.syncBlocks()
+		require_True(t, numTombs() > 0)
+
+		time.Sleep(2 * fcfg.TombstoneMaxAge)
+		fs.syncBlocks()
+		require_Equal(t, numTombs(), 0)
+
+		checkState := func() {
+			t.Helper()
+			if state := fs.State(); !reflect.DeepEqual(state, before) {
+				t.Fatalf("Expected state\n of %+v, \ngot %+v", before, state)
+			}
+			for seq := uint64(1); seq <= before.LastSeq; seq++ {
+				_, err = fs.LoadMsg(seq, nil)
+				if seq <= 40 && seq%2 == 0 {
+					require_Error(t, err, errDeletedMsg, ErrStoreMsgNotFound)
+				} else {
+					require_NoError(t, err)
+				}
+			}
+		}
+		checkState()
+
+		// Make sure we recover the same sequence gaps with no index.db present.
+		fs.Stop()
+		os.Remove(filepath.Join(fs.fcfg.StoreDir, msgDir, streamStreamStateFile))
+
+		fs, err = newFileStoreWithCreated(fcfg, cfg, created, prf(&fcfg), nil)
+		require_NoError(t, err)
+		defer fs.Stop()
+		checkState()
+	})
+}
+
 func TestFileStoreDetectDeleteGapWithLastSkipMsg(t *testing.T) {
 	testFileStoreAllPermutations(t, func(t *testing.T, fcfg FileStoreConfig) {
 		cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo"}, Storage: FileStorage}
@@ -14358,3 +14432,290 @@ loop:
 			n, gotSeqVal.Load(), corrupt, subjects[gotSeqVal.Load()-1])
 	}
 }
+
+func TestFileStoreCompressionDictionary(t *testing.T) {
+	payload := func(i int) []byte {
+		return fmt.Appendf(nil, `{"id":%d,"type":"sensor.reading","location":"warehouse-north","status":"ok","unit":"celsius","tags":["temperature","humidity"]}`, i)
+	}
+	// The dictionary is sample content which looks like the payloads.
+	var dict []byte
+	for i := 0; i < 8; i++ {
+		dict = append(dict, payload(i)...)
+	}
+
+	const numMsgs = 500
+
+	storeAndMeasure := func(t *testing.T, dict []byte) uint64 {
+		t.Helper()
+		sd := t.TempDir()
+		fcfg := FileStoreConfig{StoreDir: sd, BlockSize: 4 * 1024, Compression: S2Compression}
+		cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo"}, Storage: FileStorage, Compression: S2Compression, CompressionDict: dict}
+		fs, err := newFileStore(fcfg, cfg)
+		require_NoError(t, err)
+		defer fs.Stop()
+
+		for i := 0; i < numMsgs; i++ {
+			_, _, err = fs.StoreMsg("foo", nil, payload(i), 0)
+			require_NoError(t, err)
+		}
+
+		// Wait for all but the last block to be compressed.
+		expected := S2Compression
+		if dict != nil {
+			expected = s2DictCompression
+		}
+		fs.mu.RLock()
+		blks := slices.Clone(fs.blks[:len(fs.blks)-1])
+		fs.mu.RUnlock()
+		require_True(t, len(blks) > 1)
+
+		var total uint64
+		checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+			total = 0
+			for _, mb := range blks {
+				mb.mu.Lock()
+				buf, err := mb.loadBlock(nil)
+				mb.mu.Unlock()
+				if err != nil {
+					return err
+				}
+				var meta CompressionInfo
+				if _, err := meta.UnmarshalMetadata(buf); err != nil {
+					return err
+				}
+				if meta.Algorithm != expected {
+					return fmt.Errorf("block %d has compression %v, expected %v", mb.index, meta.Algorithm, expected)
+				}
+				total += uint64(len(buf))
+			}
+			return nil
+		})
+
+		checkMsgs := func(fs *fileStore) {
+			t.Helper()
+			for i := 0; i < numMsgs; i++ {
+				sm, err := fs.LoadMsg(uint64(i+1), nil)
+				require_NoError(t, err)
+				require_True(t, bytes.Equal(sm.msg, payload(i)))
+			}
+		}
+		// Make sure we read back from disk and not from the cache.
+		fs.mu.RLock()
+		for _, mb := range fs.blks {
+			mb.mu.Lock()
+			mb.clearCacheAndOffset()
+			mb.mu.Unlock()
+		}
+		fs.mu.RUnlock()
+		checkMsgs(fs)
+
+		// Old blocks must remain readable after a restart.
+		fs.Stop()
+		fs, err = newFileStore(fcfg, cfg)
+		require_NoError(t, err)
+		defer fs.Stop()
+		checkMsgs(fs)
+
+		return total
+	}
+
+	without := storeAndMeasure(t, nil)
+	with := storeAndMeasure(t, dict)
+	require_True(t, with < without)
+
+	// A dictionary that can not be built is rejected.
+	_, err := newFileStore(
+		FileStoreConfig{StoreDir: t.TempDir(), Compression: S2Compression},
+		StreamConfig{Name: "zzz", Subjects: []string{"foo"}, Storage: FileStorage, Compression: S2Compression, CompressionDict: []byte("short")},
+	)
+	require_Error(t, err)
+
+	// Without the dictionary the blocks can not be decoded.
+	_, err = s2DictCompression.Decompress(make([]byte, 2*checksumSize))
+	require_Error(t, err)
+}
+
+func TestFileStoreRebuildTotals(t *testing.T) {
+	testFileStoreAllPermutations(t, func(t *testing.T, fcfg FileStoreConfig) {
+		fcfg.BlockSize = 512
+		fs, err := newFileStoreWithCreated(fcfg, StreamConfig{Name: "zzz", Subjects: []string{"foo.*"}, Storage: FileStorage}, time.Now(), prf(&fcfg), nil)
+		require_NoError(t, err)
+		defer fs.Stop()
+
+		var cbMsgs, cbBytes int64
+		fs.RegisterStorageUpdates(func(md, bd int64, _ uint64, _ string) {
+			cbMsgs, cbBytes = cbMsgs+md, cbBytes+bd
+		})
+
+		for i := 0; i < 100; i++ {
+			_, _, err = fs.StoreMsg(fmt.Sprintf("foo.%d", i%5), nil, []byte("hello world"), 0)
+			require_NoError(t, err)
+		}
+		for _, seq := range []uint64{1, 10, 50, 99} {
+			_, err = fs.RemoveMsg(seq)
+			require_NoError(t, err)
+		}
+		before := fs.State()
+		require_Equal(t, before.Msgs, 96)
+		require_True(t, fs.numMsgBlocks() > 2)
+
+		// Nothing to correct.
+		md, bd, err := fs.RebuildTotals()
+		require_NoError(t, err)
+		require_Equal(t, md, 0)
+		require_Equal(t, bd, 0)
+
+		// Corrupt the counters of some blocks, and the stream totals.
+		fs.mu.Lock()
+		fb, lb := fs.blks[0], fs.lmb
+		fb.mu.Lock()
+		fb.msgs, fb.bytes = fb.msgs+3, fb.bytes+300
+		fb.mu.Unlock()
+		lb.mu.Lock()
+		lb.msgs, lb.bytes = lb.msgs-1, lb.bytes-10
+		lb.mu.Unlock()
+		fs.state.Msgs, fs.state.Bytes = fs.state.Msgs+7, fs.state.Bytes+290
+		fs.mu.Unlock()
+		require_Equal(t, fs.State().Msgs, before.Msgs+7)
+		cbMsgs, cbBytes = 0, 0
+
+		md, bd, err = fs.RebuildTotals()
+		require_NoError(t, err)
+		require_Equal(t, md, -7)
+		require_Equal(t, bd, -290)
+		require_Equal(t, cbMsgs, md)
+		require_Equal(t, cbBytes, bd)
+
+		after := fs.State()
+		require_Equal(t, after.Msgs, before.Msgs)
+		require_Equal(t, after.Bytes, before.Bytes)
+		fs.mu.RLock()
+		for _, mb := range fs.blks {
+			mb.mu.RLock()
+			msgs, bytes, err := mb.countTotalsLocked()
+			require_NoError(t, err)
+			require_Equal(t, mb.msgs, msgs)
+			require_Equal(t, mb.bytes, bytes)
+			mb.mu.RUnlock()
+		}
+		fs.mu.RUnlock()
+
+		// Totals keep being tracked correctly afterward.
+		_, _, err = fs.StoreMsg("foo.1", nil, []byte("hello world"), 0)
+		require_NoError(t, err)
+		_, err = fs.RemoveMsg(2)
+		require_NoError(t, err)
+		require_Equal(t, fs.State().Msgs, before.Msgs)
+	})
+}
+
+func TestFileStoreDeletedRanges(t *testing.T) {
+	testFileStoreAllPermutations(t, func(t *testing.T, fcfg FileStoreConfig) {
+		fcfg.BlockSize = 512
+		fs, err := newFileStoreWithCreated(fcfg, StreamConfig{Name: "zzz", Subjects: []string{"foo"}, Storage: FileStorage}, time.Now(), prf(&fcfg), nil)
+		require_NoError(t, err)
+		defer fs.Stop()
+
+		for i := 0; i < 100; i++ {
+			_, _, err = fs.StoreMsg("foo", nil, []byte("hello world"), 0)
+			require_NoError(t, err)
+		}
+		// Removing a long run drops whole blocks, leaving a gap between the remaining ones.
+		for seq := uint64(30); seq <= 60; seq++ {
+			_, err = fs.RemoveMsg(seq)
+			require_NoError(t, err)
+		}
+		for _, seq := range []uint64{5, 6, 70, 99} {
+			_, err = fs.RemoveMsg(seq)
+			require_NoError(t, err)
+		}
+		_, err = fs.Compact(3)
+		require_NoError(t, err)
+		require_True(t, fs.numMsgBlocks() > 2)
+
+		// Collect the ranges from start, merging adjacent ones, and compare with what is stored.
+		ranges := func(start uint64) [][2]uint64 {
+			var rs [][2]uint64
+			fs.DeletedRanges(start, func(first, last uint64) bool {
+				if n := len(rs); n > 0 && first <= rs[n-1][1]+1 {
+					rs[n-1][1] = max(rs[n-1][1], last)
+				} else {
+					rs = append(rs, [2]uint64{first, last})
+				}
+				return true
+			})
+			return rs
+		}
+		for _, start := range []uint64{1, 4, 6, 29, 45, 61, 71, 100} {
+			var expected [][2]uint64
+			for seq := uint64(1); seq <= 100; seq++ {
+				if fs.MsgExists(seq) {
+					continue
+				}
+				if n := len(expected); n > 0 && expected[n-1][1]+1 == seq {
+					expected[n-1][1] = seq
+				} else {
+					expected = append(expected, [2]uint64{seq, seq})
+				}
+			}
+			// Ranges before the start are skipped, the one holding it may start before it.
+			for len(expected) > 0 && expected[0][1] < start {
+				expected = expected[1:]
+			}
+			got := ranges(start)
+			require_Len(t, len(got), len(expected))
+			for i := range got {
+				require_Equal(t, max(got[i][0], start), max(expected[i][0], start))
+				require_Equal(t, got[i][1], expected[i][1])
+			}
+		}
+
+		// Stops when asked to.
+		var calls int
+		fs.DeletedRanges(0, func(first, last uint64) bool {
+			calls++
+			return false
+		})
+		require_Equal(t, calls, 1)
+	})
+}
+
+func TestFileStoreSwapCompactedMsgsOnStartup(t *testing.T) {
+	sd, cd := t.TempDir(), t.TempDir()
+	cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo"}, Storage: FileStorage}
+
+	open := func(dir string) *fileStore {
+		t.Helper()
+		fs, err := newFileStore(FileStoreConfig{StoreDir: dir}, cfg)
+		require_NoError(t, err)
+		return fs
+	}
+	store := func(dir string, n int) {
+		t.Helper()
+		fs := open(dir)
+		for range n {
+			_, _, err := fs.StoreMsg("foo", nil, []byte("ok"), 0)
+			require_NoError(t, err)
+		}
+		require_NoError(t, fs.Stop())
+	}
+	store(sd, 10)
+	store(cd, 5)
+
+	// Went down right after the staged blocks were committed.
+	require_NoError(t, os.MkdirAll(filepath.Join(sd, compactedDir), defaultDirPerms))
+	require_NoError(t, os.Rename(filepath.Join(cd, msgDir), filepath.Join(sd, compactedDir, msgDir)))
+	fs := open(sd)
+	require_Equal(t, fs.State().Msgs, 5)
+	require_NoError(t, fs.Stop())
+	_, err := os.Stat(filepath.Join(sd, compactedDir))
+	require_True(t, os.IsNotExist(err))
+
+	// Went down after the staged blocks were moved in, but before cleanup.
+	require_NoError(t, os.MkdirAll(filepath.Join(sd, compactedDir), defaultDirPerms))
+	fs = open(sd)
+	require_Equal(t, fs.State().Msgs, 5)
+	require_NoError(t, fs.Stop())
+	_, err = os.Stat(filepath.Join(sd, compactedDir))
+	require_True(t, os.IsNotExist(err))
+}
@@ -88,8 +88,36 @@ const (
 	gatewayCmdGossip          byte = 1
 	gatewayCmdAllSubsStart    byte = 2
 	gatewayCmdAllSubsComplete byte = 3
+	gatewayCmdAccountsSynced  byte = 4
 )
 
+// GatewayUnknownAccountPolicy determines what a server does with messages on
+// an account that a remote gateway does not know about.
+type GatewayUnknownAccountPolicy uint8
+
+const (
+	// GatewayUnknownAccountDrop does not send the messages to that gateway.
+	GatewayUnknownAccountDrop GatewayUnknownAccountPolicy = iota
+	// GatewayUnknownAccountLog is the same as drop, but a warning is
+	// logged the first time it happens for an account and gateway.
+	GatewayUnknownAccountLog
+	// GatewayUnknownAccountReject rejects client publishes on the account
+	// while any remote gateway does not know about it.
+	GatewayUnknownAccountReject
+)
+
+// String returns a human-friendly value.
+func (p GatewayUnknownAccountPolicy) String() string {
+	switch p {
+	case GatewayUnknownAccountLog:
+		return "log"
+	case GatewayUnknownAccountReject:
+		return "reject"
+	default:
+		return "drop"
+	}
+}
+
 // GatewayInterestMode represents an account interest mode for a gateway connection
 type GatewayInterestMode byte
 
@@ -147,6 +175,15 @@ type srvGateway struct {
 	runknown bool                   // Rejects unknown (not configured) gateway connections
 	replyPfx []byte                 // Will be "$GNR.<1:reserved>.<8:cluster hash>.<8:server hash>."
 
+	// What to do with messages on accounts unknown to a remote gateway.
+	uacc GatewayUnknownAccountPolicy
+	// Outbound gateways whose remote has told us about all its accounts.
+	// Replaced under the lock, loaded without it on the publish path.
+	uaccOut atomic.Pointer[[]*client]
+
+	// Subjects allowed to cross gateways, nil if not restricted. Immutable.
+	perms *gwPerms
+
 	// For backward compatibility
 	oldReplyPfx []byte
 	oldHash     []byte
@@ -223,6 +260,12 @@ type gateway struct {
 	interestOnlyMode bool
 	// Name of the remote server
 	remoteName string
+	// Set when the remote has indicated that it has switched all its accounts
+	// to interest-only mode, so that an account without an entry in outsim is
+	// an account that the remote does not know about (outbound conn).
+	accountsSynced atomic.Bool
+	// Accounts we have warned about not being known by the remote (outbound conn).
+	uaccWarned map[string]struct{}
 }
 
 // Outbound subject interest entry.
@@ -343,6 +386,60 @@ func getOldHash(name string) []byte {
 	return fullHash[:4]
 }
 
+// gwPerms restricts the subjects crossing gateways, independent of account permissions.
+// Import applies to messages and interest coming from remote gateways, and export to
+// messages and interest going to remote gateways.
+type gwPerms struct {
+	imp perm
+	exp perm
+}
+
+// Returns the gateway permissions for the configured ones, nil if none are configured.
+func newGatewayPerms(perms *RoutePermissions) *gwPerms {
+	if perms == nil || (perms.Import == nil && perms.Export == nil) {
+		return nil
+	}
+	newPerm := func(sp *SubjectPermission) perm {
+		var p perm
+		if sp == nil {
+			return p
+		}
+		if sp.Allow != nil {
+			p.allow = NewSublistWithCache()
+			for _, subj := range sp.Allow {
+				p.allow.Insert(&subscription{subject: []byte(subj)})
+			}
+		}
+		if len(sp.Deny) > 0 {
+			p.deny = NewSublistWithCache()
+			for _, subj := range sp.Deny {
+				p.deny.Insert(&subscription{subject: []byte(subj)})
+			}
+		}
+		return p
+	}
+	return &gwPerms{imp: newPerm(perms.Import), exp: newPerm(perms.Export)}
+}
+
+// Returns whether the subject is allowed by the given gateway permission.
+func (p *perm) gatewayAllowed(subject []byte) bool {
+	subj := bytesToString(subject)
+	if p.allow != nil && len(p.allow.Match(subj).psubs) == 0 {
+		return false
+	}
+	return p.deny == nil || len(p.deny.Match(subj).psubs) == 0
+}
+
+// canImport returns whether messages or interest on this subject can come from remote gateways.
+func (p *gwPerms) canImport(subject []byte) bool {
+	return p == nil || p.imp.gatewayAllowed(subject)
+}
+
+// canExport returns whether messages or interest on this subject can go to remote gateways.
+func (p *gwPerms) canExport(subject []byte) bool {
+	return p == nil || p.exp.gatewayAllowed(subject)
+}
+
 // Initialize the s.gateway structure. We do this even if the server
 // does not have a gateway configured. In some part of the code, the
 // server will check the number of outbound gateways, etc.. and so
@@ -357,6 +454,8 @@ func (s *Server) newGateway(opts *Options) error {
 		URLs:     make(refCountedUrlSet),
 		resolver: opts.Gateway.resolver,
 		runknown: opts.Gateway.RejectUnknown,
+		uacc:     opts.Gateway.UnknownAccount,
+		perms:    newGatewayPerms(opts.Gateway.Permissions),
 		oldHash:  getOldHash(opts.Gateway.Name),
 	}
 	gateway.Lock()
@@ -546,6 +645,7 @@ func (s *Server) startGatewayAcceptLoop() {
 		MaxPayload:   s.info.MaxPayload,
 		Gateway:      opts.Gateway.Name,
 		GatewayNRP:   true,
+		GatewayASC:   true,
 		Headers:      s.supportsHeaders(),
 		Proto:        s.getServerProto(),
 	}
@@ -1168,6 +1268,13 @@ func (c *client) processGatewayInfo(info *Info) {
 			case gatewayCmdAllSubsComplete:
 				c.gatewayAllSubsReceiveComplete(info)
 				return
+			case gatewayCmdAccountsSynced:
+				c.gw.accountsSynced.Store(true)
+				s.gateway.Lock()
+				s.gateway.updateAccountsSyncedOutboundsLocked()
+				s.gateway.Unlock()
+				c.Debugf("Gateway %q: all accounts switched to %s mode", info.Gateway, InterestOnly)
+				return
 			default:
 				s.Warnf("Received unknown command %v from gateway %q", info.GatewayCmd, gwName)
 				return
@@ -1241,6 +1348,14 @@ func (c *client) processGatewayInfo(info *Info) {
 				s.switchAccountToInterestMode(acc.GetName())
 				return true
 			})
+			// Let the remote know that it now knows all our accounts, so it can
+			// tell which ones we do not have.
+			if info.GatewayASC {
+				b, _ := json.Marshal(&Info{Gateway: s.gateway.name, GatewayCmd: gatewayCmdAccountsSynced})
+				c.mu.Lock()
+				c.enqueueProto([]byte(fmt.Sprintf(InfoProto, b)))
+				c.mu.Unlock()
+			}
 		}
 	}
 }
@@ -1318,9 +1433,16 @@ func (s *Server) sendAccountSubsToGateway(c *client, accName string) {
 	s.sendSubsToGateway(c, accName)
 }
 
-func gwBuildSubProto(buf *bytes.Buffer, accName string, acc map[string]*sitally, doQueues bool) {
+func gwBuildSubProto(buf *bytes.Buffer, accName string, acc map[string]*sitally, doQueues bool, perms *gwPerms) {
 	for saq, si := range acc {
 		if doQueues && si.q || !doQueues && !si.q {
+			// Don't ask for messages this cluster does not import.
+			if perms != nil {
+				subj, _, _ := strings.Cut(saq, " ")
+				if !perms.canImport(stringToBytes(subj)) {
+					continue
+				}
+			}
 			buf.Write(rSubBytes)
 			buf.WriteString(accName)
 			buf.WriteByte(' ')
@@ -1352,7 +1474,7 @@ func (s *Server) sendSubsToGateway(c *client, accountName string) {
 	// If account is specified...
 	if accountName != _EMPTY_ {
 		// Simply send all plain subs (no queues) for this specific account
-		gwBuildSubProto(bbuf, accountName, gw.pasi.m[accountName], false)
+		gwBuildSubProto(bbuf, accountName, gw.pasi.m[accountName], false, gw.perms)
 		// Instruct to send all subs (RS+/-) for this account from now on.
 		c.mu.Lock()
 		e := c.gw.insim[accountName]
@@ -1365,7 +1487,7 @@ func (s *Server) sendSubsToGateway(c *client, accountName string) {
 	} else {
 		// Send queues for all accounts
 		for accName, acc := range gw.pasi.m {
-			gwBuildSubProto(bbuf, accName, acc, true)
+			gwBuildSubProto(bbuf, accName, acc, true, gw.perms)
 		}
 	}
 
@@ -1732,6 +1854,7 @@ func (s *Server) registerOutboundGatewayConnection(name string, gwc *client) boo
 	s.gateway.out[name] = gwc
 	s.gateway.outo = append(s.gateway.outo, gwc)
 	s.gateway.orderOutboundConnectionsLocked()
+	s.gateway.updateAccountsSyncedOutboundsLocked()
 	s.gateway.Unlock()
 	return true
 }
@@ -1818,6 +1941,7 @@ func (s *Server) removeRemoteGatewayConnection(c *client) {
 		if reorder {
 			gw.orderOutboundConnectionsLocked()
 		}
+		gw.updateAccountsSyncedOutboundsLocked()
 	} else {
 		delete(gw.in, cid)
 	}
@@ -2045,6 +2169,12 @@ func (c *client) processGatewayRSub(arg []byte) error {
 	accName := args[0]
 	subject := args[1]
 
+	// Ignore interest in subjects this cluster does not export.
+	if !c.srv.gateway.perms.canExport(subject) {
+		c.Debugf("Can not export %q, ignoring remote subscription request", subject)
+		return nil
+	}
+
 	var (
 		e          *outsie
 		useSl      bool
@@ -2204,6 +2334,69 @@ func (c *client) gatewayInterest(acc string, subj []byte) (bool, *SublistResult)
 	return psi, r
 }
 
+// Returns true if the remote of this outbound gateway connection has told
+// us about all its accounts and the given account is not one of them.
+func (c *client) gatewayAccountUnknown(accName string) bool {
+	if !c.gw.interestOnlyMode || !c.gw.accountsSynced.Load() {
+		return false
+	}
+	_, ok := c.gw.outsim.Load(accName)
+	return !ok
+}
+
+// Logs a warning the first time a message can not be sent to the remote
+// of this outbound gateway connection because it does not know the account.
+func (c *client) warnIfGatewayAccountUnknown(accName string) {
+	if !c.gatewayAccountUnknown(accName) {
+		return
+	}
+	c.mu.Lock()
+	if _, warned := c.gw.uaccWarned[accName]; warned {
+		c.mu.Unlock()
+		return
+	}
+	if c.gw.uaccWarned == nil {
+		c.gw.uaccWarned = make(map[string]struct{})
+	}
+	c.gw.uaccWarned[accName] = struct{}{}
+	gwName := c.gw.name
+	c.mu.Unlock()
+	c.Warnf("Gateway %q does not know about account %q, messages on this account are not sent to it", gwName, accName)
+}
+
+// Rebuilds the list of outbound gateways whose remote has told us about all
+// its accounts.
+// Gateway lock is held on entry.
+func (g *srvGateway) updateAccountsSyncedOutboundsLocked() {
+	var synced []*client
+	for _, c := range g.outo {
+		if c.gw.accountsSynced.Load() {
+			synced = append(synced, c)
+		}
+	}
+	g.uaccOut.Store(&synced)
+}
+
+// Returns the name of the first remote gateway that does not know about
+// the account, or an empty string if all of them do.
+// This is called for every client publish when rejecting, so it does not
+// acquire the gateway lock.
+func (s *Server) gatewayAccountUnknownBy(accName string) string {
+	out := s.gateway.uaccOut.Load()
+	if out == nil {
+		return _EMPTY_
+	}
+	for _, c := range *out {
+		if c.gatewayAccountUnknown(accName) {
+			c.mu.Lock()
+			gwName := c.gw.name
+			c.mu.Unlock()
+			return gwName
+		}
+	}
+	return _EMPTY_
+}
+
 // switchAccountToInterestMode will switch an account over to interestMode.
 // Lock should NOT be held.
 func (s *Server) switchAccountToInterestMode(accName string) {
@@ -2233,7 +2426,7 @@ func (s *Server) switchAccountToInterestMode(accName string) {
 // GWs inbound connections, we will check if we need to send an RS+ or A+
 // protocol.
 func (s *Server) maybeSendSubOrUnsubToGateways(accName string, sub *subscription, added bool) {
-	if sub.queue != nil {
+	if sub.queue != nil || !s.gateway.perms.canImport(sub.subject) {
 		return
 	}
 	gwsa := [16]*client{}
@@ -2331,7 +2524,7 @@ func (s *Server) maybeSendSubOrUnsubToGateways(accName string, sub *subscription
 // given subject/group is registered (or unregistered). Sent to all
 // inbound gateways.
 func (s *Server) sendQueueSubOrUnsubToGateways(accName string, qsub *subscription, added bool) {
-	if qsub.queue == nil {
+	if qsub.queue == nil || !s.gateway.perms.canImport(qsub.subject) {
 		return
 	}
 
@@ -2559,6 +2752,13 @@ func (c *client) sendMsgToGateways(acc *Account, msg, subject, reply []byte, qgr
 		return false
 	}
 
+	// Check that the subject is allowed to leave this cluster.
+	if gw.perms != nil {
+		if subj, _ := getGWRoutedSubjectOrSelf(subject); !gw.perms.canExport(subj) {
+			return false
+		}
+	}
+
 	// Copy off original pa in case it changes.
 	pa := c.pa
 
@@ -2620,6 +2820,9 @@ func (c *client) sendMsgToGateways(acc *Account, msg, subject, reply []byte, qgr
 			// Plain sub interest and queue sub results for this account/subject
 			psi, qr := gwc.gatewayInterest(accName, subject)
 			if !psi && qr == nil {
+				if gw.uacc == GatewayUnknownAccountLog {
+					gwc.warnIfGatewayAccountUnknown(accName)
+				}
 				continue
 			}
 			queues = queuesa[:0]
@@ -3128,6 +3331,14 @@ func (c *client) processInboundGatewayMsg(msg []byte) {
 		return
 	}
 
+	// Drop messages on subjects this cluster does not import.
+	if perms := c.srv.gateway.perms; perms != nil {
+		if subj, _ := getGWRoutedSubjectOrSelf(c.pa.subject); !perms.canImport(subj) {
+			c.Debugf("Can not import %q, dropping gateway message", subj)
+			return
+		}
+	}
+
 	// If the subject (c.pa.subject) has the gateway prefix, this function will
 	// handle it.
 	if c.handleGatewayReply(msg) {
@@ -3142,6 +3353,11 @@ func (c *client) processInboundGatewayMsg(msg []byte) {
 		return
 	}
 
+	// Check the allowed publishers of any stream capturing this subject.
+	if !c.streamPublishAllowed(acc, bytesToString(c.pa.subject)) {
+		return
+	}
+
 	acc.stats.Lock()
 	acc.stats.inMsgs++
 	acc.stats.inBytes += int64(size)
@@ -7773,3 +7773,241 @@ func TestGatewayProcessRSubNoBlockingAccountFetch(t *testing.T) {
 	require_True(t, ok)
 	require_True(t, ei.(*outsie).sl.CacheEnabled())
 }
+
+func TestGatewayUnknownAccountPolicy(t *testing.T) {
+	for _, policy := range []GatewayUnknownAccountPolicy{
+		GatewayUnknownAccountDrop,
+		GatewayUnknownAccountLog,
+		GatewayUnknownAccountReject,
+	} {
+		t.Run(policy.String(), func(t *testing.T) {
+			confA := createConfFile(t, []byte(fmt.Sprintf(`
+				listen: 127.0.0.1:-1
+				server_name: A
+				accounts {
+					ONLYA { users: [{user: a, password: pwd}] }
+					BOTH { users: [{user: b, password: pwd}] }
+				}
+				gateway {
+					name: A
+					listen: 127.0.0.1:-1
+					unknown_account: %s
+				}
+			`, policy)))
+			sa, oa := RunServerWithConfig(confA)
+			defer sa.Shutdown()
+			require_Equal(t, oa.Gateway.UnknownAccount, policy)
+
+			l := &captureWarnLogger{warn: make(chan string, 10)}
+			sa.SetLogger(l, false, false)
+
+			confB := createConfFile(t, []byte(fmt.Sprintf(`
+				listen: 127.0.0.1:-1
+				server_name: B
+				accounts {
+					BOTH { users: [{user: b, password: pwd}] }
+				}
+				gateway {
+					name: B
+					listen: 127.0.0.1:-1
+					gateways: [{name: A, url: "nats://127.0.0.1:%d"}]
+				}
+			`, oa.Gateway.Port)))
+			sb, _ := RunServerWithConfig(confB)
+			defer sb.Shutdown()
+
+			waitForOutboundGateways(t, sa, 1, 2*time.Second)
+			waitForOutboundGateways(t, sb, 1, 2*time.Second)
+
+			// Wait for B to have told A about all its accounts.
+			gwc := sa.getOutboundGatewayConnection("B")
+			require_True(t, gwc != nil)
+			checkFor(t, 2*time.Second, 15*time.Millisecond, func() error {
+				if !gwc.gatewayAccountUnknown("ONLYA") {
+					return fmt.Errorf("account not yet reported unknown")
+				}
+				return nil
+			})
+			require_False(t, gwc.gatewayAccountUnknown("BOTH"))
+			require_Equal(t, sa.gatewayAccountUnknownBy("ONLYA"), "B")
+			require_Equal(t, sa.gatewayAccountUnknownBy("BOTH"), _EMPTY_)
+
+			errCh := make(chan error, 10)
+			nca, err := nats.Connect(sa.ClientURL(), nats.UserInfo("a", "pwd"),
+				nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+					errCh <- err
+				}))
+			require_NoError(t, err)
+			defer nca.Close()
+			sub := natsSubSync(t, nca, "foo")
+			natsFlush(t, nca)
+
+			for i := 0; i < 3; i++ {
+				natsPub(t, nca, "foo", []byte("hello"))
+			}
+			natsFlush(t, nca)
+
+			switch policy {
+			case GatewayUnknownAccountDrop, GatewayUnknownAccountLog:
+				for i := 0; i < 3; i++ {
+					natsNexMsg(t, sub, time.Second)
+				}
+				select {
+				case err := <-errCh:
+					t.Fatalf("Unexpected error: %v", err)
+				default:
+				}
+			case GatewayUnknownAccountReject:
+				select {
+				case err := <-errCh:
+					require_Contains(t, err.Error(), "permissions violation", `account "ONLYA" is not known by gateway "B"`)
+				case <-time.After(2 * time.Second):
+					t.Fatal("Did not get an error")
+				}
+				if m, err := sub.NextMsg(100 * time.Millisecond); err == nil {
+					t.Fatalf("Rejected message was delivered: %+v", m)
+				}
+				require_True(t, nca.IsConnected())
+			}
+
+			// Warnings are only logged once per account and gateway.
+			var warnings []string
+			for done := false; !done; {
+				select {
+				case w := <-l.warn:
+					if strings.Contains(w, "does not know about account") {
+						warnings = append(warnings, w)
+					}
+				case <-time.After(250 * time.Millisecond):
+					done = true
+				}
+			}
+			if policy == GatewayUnknownAccountLog {
+				require_Len(t, len(warnings), 1)
+				require_Contains(t, warnings[0], `Gateway "B"`, `account "ONLYA"`)
+			} else {
+				require_Len(t, len(warnings), 0)
+			}
+
+			// Accounts known by both sides are not affected.
+			ncb := natsConnect(t, sb.ClientURL(), nats.UserInfo("b", "pwd"))
+			defer ncb.Close()
+			subb := natsSubSync(t, ncb, "bar")
+			natsFlush(t, ncb)
+			checkGWInterestOnlyModeInterestOn(t, sa, "B", "BOTH", "bar")
+
+			nc := natsConnect(t, sa.ClientURL(), nats.UserInfo("b", "pwd"))
+			defer nc.Close()
+			natsPub(t, nc, "bar", []byte("hello"))
+			natsNexMsg(t, subb, time.Second)
+		})
+	}
+}
+
+func TestGatewayUnknownAccountPolicyConfig(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		gateway {
+			name: A
+			listen: 127.0.0.1:-1
+			unknown_account: ignore
+		}
+	`))
+	_, err := ProcessConfigFile(conf)
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "unknown_account must be 'drop', 'log' or 'reject'")
+
+	conf = createConfFile(t, []byte(`
+		gateway {
+			name: A
+			listen: 127.0.0.1:-1
+			unknown_account: advise
+		}
+	`))
+	opts, err := ProcessConfigFile(conf)
+	require_NoError(t, err)
+	require_Equal(t, opts.Gateway.UnknownAccount, GatewayUnknownAccountLog)
+}
+
+func TestGatewayPermissions(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		gateway {
+			name: "A"
+			port: -1
+			permissions {
+				import { deny: "in.secret" }
+				export { allow: "out.>" }
+			}
+		}
+	`))
+	opts, err := ProcessConfigFile(conf)
+	require_NoError(t, err)
+	require_NotNil(t, opts.Gateway.Permissions)
+	require_Equal(t, strings.Join(opts.Gateway.Permissions.Import.Deny, ","), "in.secret")
+	require_Equal(t, strings.Join(opts.Gateway.Permissions.Export.Allow, ","), "out.>")
+
+	ob := testDefaultOptionsForGateway("B")
+	sb := runGatewayServer(ob)
+	defer sb.Shutdown()
+
+	oa := testGatewayOptionsFromToWithServers(t, "A", "B", sb)
+	oa.Gateway.Permissions = opts.Gateway.Permissions
+	sa := runGatewayServer(oa)
+	defer sa.Shutdown()
+
+	waitForOutboundGateways(t, sa, 1, time.Second)
+	waitForOutboundGateways(t, sb, 1, time.Second)
+	waitForInboundGateways(t, sa, 1, time.Second)
+	waitForInboundGateways(t, sb, 1, time.Second)
+
+	nca := natsConnect(t, sa.ClientURL())
+	defer nca.Close()
+	ncb := natsConnect(t, sb.ClientURL())
+	defer ncb.Close()
+
+	checkNoMsg := func(t *testing.T, sub *nats.Subscription) {
+		t.Helper()
+		_, err := sub.NextMsg(100 * time.Millisecond)
+		require_Error(t, err, nats.ErrTimeout)
+	}
+
+	// Queue interest only crosses the gateway on permitted subjects.
+	natsQueueSubSync(t, nca, "in.ok", "q")
+	natsQueueSubSync(t, nca, "in.secret", "q")
+	natsQueueSubSync(t, ncb, "out.ok", "q")
+	natsQueueSubSync(t, ncb, "other", "q")
+	natsFlush(t, nca)
+	natsFlush(t, ncb)
+	checkForRegisteredQSubInterest(t, sb, "A", globalAccountName, "in.ok", 1, time.Second)
+	checkForRegisteredQSubInterest(t, sa, "B", globalAccountName, "out.ok", 1, time.Second)
+	checkForRegisteredQSubInterest(t, sb, "A", globalAccountName, "in.secret", 0, 250*time.Millisecond)
+	checkForRegisteredQSubInterest(t, sa, "B", globalAccountName, "other", 0, 250*time.Millisecond)
+
+	// From A to B, only exported subjects cross.
+	outOK := natsSubSync(t, ncb, "out.ok")
+	other := natsSubSync(t, ncb, "other")
+	natsFlush(t, ncb)
+	natsPub(t, nca, "out.ok", []byte("hello"))
+	natsPub(t, nca, "other", []byte("hello"))
+	natsNexMsg(t, outOK, time.Second)
+	checkNoMsg(t, other)
+
+	// From B to A, everything crosses except for what is not imported.
+	inOK := natsSubSync(t, nca, "in.ok")
+	inSecret := natsSubSync(t, nca, "in.secret")
+	natsFlush(t, nca)
+	natsPub(t, ncb, "in.ok", []byte("hello"))
+	natsPub(t, ncb, "in.secret", []byte("hello"))
+	natsNexMsg(t, inOK, time.Second)
+	checkNoMsg(t, inSecret)
+
+	// Replies to requests crossing the gateway are subject to the same permissions.
+	natsSub(t, nca, "in.req", func(m *nats.Msg) { m.Respond([]byte("reply")) })
+	natsFlush(t, nca)
+	_, err = ncb.Request("in.req", nil, 250*time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+	ncb2 := natsConnect(t, sb.ClientURL(), nats.CustomInboxPrefix("out.inbox"))
+	defer ncb2.Close()
+	resp, err := ncb2.Request("in.req", nil, time.Second)
+	require_NoError(t, err)
+	require_Equal(t, string(resp.Data), "reply")
+}
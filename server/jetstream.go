@@ -20,7 +20,10 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"math"
 	"os"
 	"path/filepath"
@@ -72,6 +75,12 @@ type JetStreamAccountLimits struct {
 	MemoryMaxStreamBytes int64 `json:"memory_max_stream_bytes"`
 	StoreMaxStreamBytes  int64 `json:"storage_max_stream_bytes"`
 	MaxBytesRequired     bool  `json:"max_bytes_required"`
+	MaxTotalConsumers    int   `json:"max_total_consumers,omitempty"`
+	// DiscardOld evicts the oldest messages across the account's streams to stay within
+	// MaxMemory and MaxStore, instead of rejecting new messages. Sealed streams, streams that
+	// deny deletes and streams not using limits retention are left alone. Not supported when
+	// clustered.
+	DiscardOld bool `json:"discard_old,omitempty"`
 }
 
 type JetStreamTier struct {
@@ -114,6 +123,7 @@ type jetStream struct {
 	storeUsed      int64
 	queueLimit     int64
 	infoQueueLimit int64
+	applyBacklog   int64
 	clustered      int32
 	mu             sync.RWMutex
 	srv            *Server
@@ -133,6 +143,9 @@ type jetStream struct {
 	oos            bool
 	shuttingDown   bool
 
+	// Only one store directory rotation at a time.
+	rotateMu sync.Mutex
+
 	// Atomic versions
 	disabled atomic.Bool
 }
@@ -144,8 +157,9 @@ type remoteUsage struct {
 }
 
 type jsaStorage struct {
-	total jsaUsage
-	local jsaUsage
+	total    jsaUsage
+	local    jsaUsage
+	reserved jsaUsage // Reserved for bulk publishes, but not used yet.
 }
 
 // This represents a jetstream enabled account.
@@ -180,6 +194,14 @@ type jsAccount struct {
 	updatesSub *subscription
 	lupdate    time.Time
 	utimer     *time.Timer
+	stores     map[string]jsaStreamStore // indexed by stream name
+}
+
+// Store of a stream along with its tier, used to evict the oldest messages across streams.
+type jsaStreamStore struct {
+	tier      string
+	store     StreamStore
+	evictable bool // Can have its oldest messages evicted, see DiscardOld.
 }
 
 // Track general usage for this account.
@@ -419,14 +441,28 @@ func (s *Server) enableJetStream(cfg JetStreamConfig) error {
 	if s.gcbOutMax = s.getOpts().JetStreamMaxCatchup; s.gcbOutMax == 0 {
 		s.gcbOutMax = defaultMaxTotalCatchupOutBytes
 	}
+	if mc := s.getOpts().JetStreamMaxConcurrentCatchups; mc > 0 {
+		s.gcbSem = make(chan struct{}, mc)
+	} else {
+		s.gcbSem = nil
+	}
 	s.gcbMu.Unlock()
 
+	s.snapMu.Lock()
+	if ms := s.getOpts().JetStreamMaxSnapshots; ms > 0 {
+		s.snapSem = make(chan struct{}, ms)
+	} else {
+		s.snapSem = nil
+	}
+	s.snapMu.Unlock()
+
 	atomic.StoreInt64(&js.memMax, cfg.MaxMemory)
 	atomic.StoreInt64(&js.storeMax, cfg.MaxStore)
 
 	// TODO: Not currently reloadable.
 	atomic.StoreInt64(&js.queueLimit, s.getOpts().JetStreamRequestQueueLimit)
 	atomic.StoreInt64(&js.infoQueueLimit, s.getOpts().JetStreamInfoQueueLimit)
+	atomic.StoreInt64(&js.applyBacklog, s.getOpts().JetStreamMaxApplyBacklog)
 
 	s.js.Store(js)
 
@@ -716,7 +752,14 @@ func (s *Server) disableJetStream(deleteState bool) error {
 func (s *Server) enableJetStreamAccounts() error {
 	// Reuse the same task workers across all accounts, so that we don't explode
 	// with a large number of goroutines on multi-account systems.
-	tq := parallelTaskQueue(len(dios))
+	// Unless configured to throttle recovery, in which case we recover a limited number of streams at a time.
+	var tq chan<- func()
+	if rc := s.getOpts().JetStreamMaxRecoveries; rc > 0 {
+		s.Noticef("  Recovering at most %d streams concurrently", rc)
+		tq = fixedTaskQueue(rc)
+	} else {
+		tq = parallelTaskQueue(len(dios))
+	}
 	defer close(tq)
 
 	// If we have no configured accounts setup then setup imports on global account.
@@ -1095,9 +1138,191 @@ func (s *Server) StoreDir() string {
 	if js == nil {
 		return _EMPTY_
 	}
+	js.mu.RLock()
+	defer js.mu.RUnlock()
 	return js.config.StoreDir
 }
 
+// RotateJetStreamStoreDir moves all JetStream data into storeDir, which is used the same as
+// the store_dir option. Streams are moved one at a time, each only being stopped while its
+// files are copied, after which it's recovered from the new location together with its consumers.
+// The old directory is left as is and can be removed afterwards. If moving fails, the streams
+// moved already are moved back and the old directory remains in use. The configuration should be
+// updated as well, so a restart picks up the new location. Not supported in clustered mode.
+func (s *Server) RotateJetStreamStoreDir(storeDir string) error {
+	js := s.getJetStream()
+	if js == nil {
+		return NewJSNotEnabledError()
+	}
+	if js.isClustered() {
+		return errors.New("store directory rotation is not supported in clustered mode")
+	}
+	if storeDir == _EMPTY_ {
+		return errors.New("store directory can not be empty")
+	}
+	if !js.rotateMu.TryLock() {
+		return errors.New("store directory rotation already in progress")
+	}
+	defer js.rotateMu.Unlock()
+
+	js.mu.RLock()
+	odir := js.config.StoreDir
+	jsas := make([]*jsAccount, 0, len(js.accounts))
+	for _, jsa := range js.accounts {
+		jsas = append(jsas, jsa)
+	}
+	js.mu.RUnlock()
+
+	ndir, err := filepath.Abs(filepath.Join(storeDir, JetStreamStoreDir))
+	if err != nil {
+		return err
+	}
+	if adir, err := filepath.Abs(odir); err != nil {
+		return err
+	} else if ndir == adir || strings.HasPrefix(ndir, adir+string(os.PathSeparator)) {
+		return fmt.Errorf("store directory %q can not be within the current one", storeDir)
+	}
+	if fis, _ := os.ReadDir(ndir); len(fis) > 0 {
+		return fmt.Errorf("store directory %q is not empty", ndir)
+	}
+	if err := os.MkdirAll(ndir, defaultDirPerms); err != nil {
+		return fmt.Errorf("could not create storage directory - %v", err)
+	}
+	s.Noticef("Rotating JetStream store directory from %q to %q", odir, ndir)
+
+	// Stream directories that were moved, these are not copied again afterwards.
+	moved := make(map[string]struct{})
+	// Previous store directory of the accounts switched over, to switch back on failure.
+	switched := make(map[*jsAccount]string, len(jsas))
+	for _, jsa := range jsas {
+		jsa.mu.Lock()
+		acc, oadir := jsa.account, jsa.storeDir
+		jsa.storeDir = filepath.Join(ndir, acc.Name)
+		streams := make([]*stream, 0, len(jsa.streams))
+		for _, mset := range jsa.streams {
+			streams = append(streams, mset)
+		}
+		jsa.mu.Unlock()
+		switched[jsa] = oadir
+
+		for _, mset := range streams {
+			mset.mu.RLock()
+			name, stype, offline := mset.cfg.Name, mset.cfg.Storage, mset.offlineReason != _EMPTY_
+			mset.mu.RUnlock()
+			// Memory based streams have nothing to move, and offline streams are copied as is below.
+			if stype != FileStorage || offline {
+				continue
+			}
+			if _, err := mset.relocate(oadir); err != nil {
+				err = fmt.Errorf("error moving stream '%s > %s': %v", acc.Name, name, err)
+				return s.rollbackJetStreamStoreDir(ndir, switched, err)
+			}
+			moved[filepath.Join(oadir, streamsDir, name)] = struct{}{}
+		}
+	}
+
+	// Copy over everything else, like offline streams or accounts that aren't enabled right now.
+	if err := copyStoreDir(odir, ndir, moved); err != nil {
+		return s.rollbackJetStreamStoreDir(ndir, switched, err)
+	}
+
+	js.mu.Lock()
+	js.config.StoreDir = ndir
+	js.mu.Unlock()
+
+	s.Noticef("Rotated JetStream store directory to %q", ndir)
+	return nil
+}
+
+// rollbackJetStreamStoreDir switches the accounts back to their previous store directory after
+// rotating into ndir failed with err. Streams already moved into ndir are moved back, replacing
+// their outdated files in the previous location, after which ndir is removed.
+func (s *Server) rollbackJetStreamStoreDir(ndir string, switched map[*jsAccount]string, err error) error {
+	s.Warnf("Rotating JetStream store directory failed, moving back: %v", err)
+	clean := true
+	for jsa, oadir := range switched {
+		jsa.mu.Lock()
+		accName, nadir := jsa.account.Name, jsa.storeDir
+		jsa.storeDir = oadir
+		streams := make([]*stream, 0, len(jsa.streams))
+		for _, mset := range jsa.streams {
+			streams = append(streams, mset)
+		}
+		jsa.mu.Unlock()
+
+		// This includes streams that were created in the new location in the meantime.
+		for _, mset := range streams {
+			mset.mu.RLock()
+			name := mset.cfg.Name
+			fs, ok := mset.store.(*fileStore)
+			mset.mu.RUnlock()
+			if !ok {
+				continue
+			}
+			fs.mu.RLock()
+			sdir := fs.fcfg.StoreDir
+			fs.mu.RUnlock()
+			if !strings.HasPrefix(sdir, ndir+string(os.PathSeparator)) {
+				continue
+			}
+			rerr := os.RemoveAll(filepath.Join(oadir, streamsDir, name))
+			if rerr == nil {
+				_, rerr = mset.relocate(nadir)
+			}
+			if rerr != nil {
+				s.Errorf("Error moving stream '%s > %s' back to %q: %v", accName, name, oadir, rerr)
+				clean = false
+			}
+		}
+	}
+	// Only remove the new directory if nothing is left behind in it.
+	if clean {
+		os.RemoveAll(ndir)
+	}
+	return err
+}
+
+// copyStoreDir recursively copies src into dst, skipping the paths in skip and files that already exist.
+func copyStoreDir(src, dst string, skip map[string]struct{}) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if _, ok := skip[path]; ok {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, defaultDirPerms)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, defaultFilePerms)
+		if os.IsExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err = io.Copy(out, in); err == nil {
+			err = out.Sync()
+		}
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	})
+}
+
 // JetStreamNumAccounts returns the number of enabled accounts this server is tracking.
 func (s *Server) JetStreamNumAccounts() int {
 	js := s.getJetStream()
@@ -1181,6 +1406,10 @@ func (a *Account) EnableJetStream(limits map[string]JetStreamAccountLimits, tq c
 		return a.enableAllJetStreamServiceImportsAndMappings()
 	}
 
+	if err := js.checkDiscardOld(limits); err != nil {
+		js.mu.Unlock()
+		return err
+	}
 	// Check the limits against existing reservations.
 	if err := js.sufficientResources(limits); err != nil {
 		js.mu.Unlock()
@@ -1214,6 +1443,16 @@ func (a *Account) EnableJetStream(limits map[string]JetStreamAccountLimits, tq c
 		return err
 	}
 
+	// When clustered the account's streams might already be assigned.
+	if js.isClustered() {
+		js.mu.RLock()
+		for _, sa := range js.cluster.streams[a.Name] {
+			a.setStreamPublishers(sa.Config)
+			a.setOverlapStream(sa.Config)
+		}
+		js.mu.RUnlock()
+	}
+
 	s.Debugf("Enabled JetStream for account %q", a.Name)
 	if l, ok := limits[_EMPTY_]; ok {
 		s.Debugf("  Max Memory:      %s", friendlyBytes(l.MaxMemory))
@@ -1351,7 +1590,7 @@ func (a *Account) EnableJetStream(limits map[string]JetStreamAccountLimits, tq c
 				// the consumer can reconnect. We will create it as a durable and switch it.
 				cfg.ConsumerConfig.Durable = ofi.Name()
 			}
-			obs, err := mset.addConsumerWithAssignment(&cfg.ConsumerConfig, _EMPTY_, nil, true, ActionCreateOrUpdate, false)
+			obs, err := mset.addConsumerWithAssignment(&cfg.ConsumerConfig, _EMPTY_, nil, true, ActionCreateOrUpdate, false, false)
 			if err != nil {
 				s.Warnf("    Error adding consumer '%s > %s > %s': %v", a.Name, mset.name(), cfg.Name, err)
 				continue
@@ -1370,7 +1609,7 @@ func (a *Account) EnableJetStream(limits map[string]JetStreamAccountLimits, tq c
 	doStream := func(fi os.DirEntry) error {
 		plaintext := true
 		mdir := filepath.Join(sdir, fi.Name())
-		// Check for partially deleted streams. They are marked with "." prefix.
+		// Check for partially deleted or renamed streams. They are marked with "." prefix.
 		if strings.HasPrefix(fi.Name(), tsep) {
 			go os.RemoveAll(mdir)
 			return nil
@@ -1691,6 +1930,24 @@ func (a *Account) maxBytesLimits(cfg *StreamConfig) (bool, int64) {
 	return selectedLimits.MaxBytesRequired, maxStreamBytes
 }
 
+// checkStreamReplicas returns an error if the account's replication
+// policy does not allow a stream with the given replicas.
+func (a *Account) checkStreamReplicas(replicas int) *ApiError {
+	a.mu.RLock()
+	minRepl, repl := a.jsMinRepl, a.jsRepl
+	a.mu.RUnlock()
+	if replicas <= 0 {
+		replicas = 1
+	}
+	if repl > 0 && replicas != repl {
+		return NewJSStreamReplicasNotAllowedError(fmt.Errorf("account requires %d replicas", repl))
+	}
+	if replicas < minRepl {
+		return NewJSStreamReplicasNotAllowedError(fmt.Errorf("account requires at least %d replicas", minRepl))
+	}
+	return nil
+}
+
 // NumStreams will return how many streams we have.
 func (a *Account) numStreams() int {
 	a.mu.RLock()
@@ -1787,6 +2044,10 @@ func (a *Account) UpdateJetStreamLimits(limits map[string]JetStreamAccountLimits
 	jsa.usageMu.RUnlock()
 
 	js.mu.Lock()
+	if err := js.checkDiscardOld(limits); err != nil {
+		js.mu.Unlock()
+		return err
+	}
 	// Check the limits against existing reservations.
 	if err := js.sufficientResources(dl); err != nil {
 		js.mu.Unlock()
@@ -2391,6 +2652,36 @@ func (jsa *jsAccount) countStreams(tier string, cfg *StreamConfig) (streams int)
 	return streams
 }
 
+// countConsumers returns the number of consumers across all streams of the account that
+// fall into the given tier. Direct and sourcing consumers are not counted.
+// Lock should not be held.
+func (jsa *jsAccount) countConsumers(tier string, replicas int) (consumers int) {
+	jsa.mu.RLock()
+	streams := make([]*stream, 0, len(jsa.streams))
+	for _, mset := range jsa.streams {
+		streams = append(streams, mset)
+	}
+	jsa.mu.RUnlock()
+
+	for _, mset := range streams {
+		mset.cfgMu.RLock()
+		scfg := mset.cfg
+		mset.cfgMu.RUnlock()
+		for _, o := range mset.getConsumers() {
+			o.mu.RLock()
+			direct, sourcing, oreplicas := o.cfg.Direct, o.cfg.Sourcing, o.cfg.replicas(&scfg)
+			o.mu.RUnlock()
+			if direct || sourcing {
+				continue
+			}
+			if tier == _EMPTY_ || isSameTier(oreplicas, replicas) {
+				consumers++
+			}
+		}
+	}
+	return consumers
+}
+
 // jsa.usageMu read lock (at least) should be held.
 func (jsa *jsAccount) storageTotals() (uint64, uint64) {
 	mem := uint64(0)
@@ -2432,12 +2723,12 @@ func (jsa *jsAccount) wouldExceedLimits(storeType StorageType, tierName string,
 
 	// Since tiers are flat we need to scale limit up by replicas when checking.
 	if storeType == MemoryStorage {
-		totalMem := inUse.total.mem + (int64(memStoreMsgSize(subj, hdr, msg)) * r)
+		totalMem := inUse.total.mem + inUse.reserved.mem + (int64(memStoreMsgSize(subj, hdr, msg)) * r)
 		if selectedLimits.MaxMemory >= 0 && totalMem > selectedLimits.MaxMemory*lr {
 			return true, nil
 		}
 	} else {
-		totalStore := inUse.total.store + (int64(fileStoreMsgSize(subj, hdr, msg)) * r)
+		totalStore := inUse.total.store + inUse.reserved.store + (int64(fileStoreMsgSize(subj, hdr, msg)) * r)
 		if selectedLimits.MaxStore >= 0 && totalStore > selectedLimits.MaxStore*lr {
 			return true, nil
 		}
@@ -2446,6 +2737,122 @@ func (jsa *jsAccount) wouldExceedLimits(storeType StorageType, tierName string,
 	return false, nil
 }
 
+// trackStore registers the store of a stream for evicting the oldest messages across streams.
+// Only limits based streams that allow deleting messages are evicted from.
+// The stream lock may be held, so this only takes jsa.usageMu.
+func (jsa *jsAccount) trackStore(cfg *StreamConfig, tierName string, store StreamStore) {
+	jsa.usageMu.Lock()
+	defer jsa.usageMu.Unlock()
+	if jsa.stores == nil {
+		jsa.stores = make(map[string]jsaStreamStore)
+	}
+	evictable := cfg.Retention == LimitsPolicy && !cfg.Sealed && !cfg.DenyDelete
+	jsa.stores[cfg.Name] = jsaStreamStore{tier: tierName, store: store, evictable: evictable}
+}
+
+// untrackStore removes the store of a stream, unless it was replaced in the meantime.
+func (jsa *jsAccount) untrackStore(name string, store StreamStore) {
+	jsa.usageMu.Lock()
+	defer jsa.usageMu.Unlock()
+	if ss, ok := jsa.stores[name]; ok && ss.store == store {
+		delete(jsa.stores, name)
+	}
+}
+
+// evictOldest makes room for a message within the account limits by removing the oldest
+// messages across the account's streams of the same storage type and tier, if the limits
+// are set to discard old messages. Returns false if it couldn't make enough room.
+// The stream lock is held, so we can only rely on jsa.usageMu and not on jsa.mu here.
+func (jsa *jsAccount) evictOldest(storeType StorageType, tierName string, replicas int, subj string, hdr, msg []byte) bool {
+	jsa.usageMu.RLock()
+	if selectedLimits, ok := jsa.limits[tierName]; !ok || !selectedLimits.DiscardOld {
+		jsa.usageMu.RUnlock()
+		return false
+	}
+	stores := make([]StreamStore, 0, len(jsa.stores))
+	for _, ss := range jsa.stores {
+		if ss.evictable && ss.tier == tierName && ss.store.Type() == storeType {
+			stores = append(stores, ss.store)
+		}
+	}
+	jsa.usageMu.RUnlock()
+
+	var ss StreamState
+	for {
+		if exceeded, _ := jsa.wouldExceedLimits(storeType, tierName, replicas, subj, hdr, msg); !exceeded {
+			return true
+		}
+		// Remove the oldest message of all streams, which updates our usage.
+		var oldest StreamStore
+		var seq uint64
+		var ts time.Time
+		for _, store := range stores {
+			store.FastState(&ss)
+			if ss.Msgs > 0 && (oldest == nil || ss.FirstTime.Before(ts)) {
+				oldest, seq, ts = store, ss.FirstSeq, ss.FirstTime
+			}
+		}
+		if oldest == nil {
+			return false
+		}
+		if removed, err := oldest.RemoveMsg(seq); err != nil || !removed {
+			return false
+		}
+	}
+}
+
+// reserveStorage reserves bytes of the account's storage for a bulk publish to a stream with the given replicas,
+// a negative delta releases them. Returns false if reserving would exceed the account limits, unless forced.
+func (jsa *jsAccount) reserveStorage(tierName string, storeType StorageType, replicas int, delta int64, force bool) bool {
+	jsa.usageMu.Lock()
+	defer jsa.usageMu.Unlock()
+
+	inUse, ok := jsa.usage[tierName]
+	if !ok {
+		inUse = &jsaStorage{}
+		jsa.usage[tierName] = inUse
+	}
+	delta *= int64(max(replicas, 1))
+	if delta > 0 && !force && !jsa.canReserveStorageLocked(inUse, tierName, storeType, replicas, delta) {
+		return false
+	}
+	if storeType == MemoryStorage {
+		inUse.reserved.mem += delta
+	} else {
+		inUse.reserved.store += delta
+	}
+	return true
+}
+
+// canReserveStorage returns whether bytes of the account's storage can be reserved for a bulk
+// publish to a stream with the given replicas, without exceeding the account limits.
+func (jsa *jsAccount) canReserveStorage(tierName string, storeType StorageType, replicas int, bytes int64) bool {
+	jsa.usageMu.RLock()
+	defer jsa.usageMu.RUnlock()
+	inUse := jsa.usage[tierName]
+	if inUse == nil {
+		inUse = &jsaStorage{}
+	}
+	return jsa.canReserveStorageLocked(inUse, tierName, storeType, replicas, bytes*int64(max(replicas, 1)))
+}
+
+// Same as wouldExceedLimits, the limits of a tier are scaled up by its replicas.
+// Usage lock should be held.
+func (jsa *jsAccount) canReserveStorageLocked(inUse *jsaStorage, tierName string, storeType StorageType, replicas int, delta int64) bool {
+	selectedLimits, ok := jsa.limits[tierName]
+	if !ok {
+		return true
+	}
+	lr := int64(max(replicas, 1))
+	if tierName == _EMPTY_ {
+		lr = 1
+	}
+	if storeType == MemoryStorage {
+		return selectedLimits.MaxMemory < 0 || inUse.total.mem+inUse.reserved.mem+delta <= selectedLimits.MaxMemory*lr
+	}
+	return selectedLimits.MaxStore < 0 || inUse.total.store+inUse.reserved.store+delta <= selectedLimits.MaxStore*lr
+}
+
 // Check account limits.
 // Read Lock should be held
 func (js *jetStream) checkAccountLimits(selected *JetStreamAccountLimits, tier string, config *StreamConfig, currentRes int64) error {
@@ -2589,6 +2996,20 @@ func (js *jetStream) usageStats() *JetStreamStats {
 	return &stats
 }
 
+// checkDiscardOld returns an error if the limits evict the oldest messages across streams,
+// which is only supported for a single server. Lock should be held.
+func (js *jetStream) checkDiscardOld(limits map[string]JetStreamAccountLimits) error {
+	if js.standAlone {
+		return nil
+	}
+	for _, l := range limits {
+		if l.DiscardOld {
+			return errors.New("discard_old account limit is not supported when JetStream is clustered")
+		}
+	}
+	return nil
+}
+
 // Check to see if we have enough system resources for this account.
 // Lock should be held.
 func (js *jetStream) sufficientResources(limits map[string]JetStreamAccountLimits) error {
@@ -2862,6 +3283,16 @@ func validateJetStreamOptions(o *Options) error {
 			}
 		}
 	}
+	// Evicting the oldest messages across streams is only supported for a single server.
+	if o.Cluster.Port != 0 || o.Gateway.Port != 0 {
+		for _, acc := range o.Accounts {
+			for _, l := range acc.jsLimits {
+				if l.DiscardOld {
+					return fmt.Errorf("account %q: discard_old is not supported when JetStream is clustered", acc.GetName())
+				}
+			}
+		}
+	}
 	if o.JetStreamDomain != _EMPTY_ {
 		if subj := fmt.Sprintf(jsDomainAPI, o.JetStreamDomain); !IsValidSubject(subj) {
 			return fmt.Errorf("invalid domain name: derived %q is not a valid subject", subj)
@@ -2893,6 +3324,27 @@ func validateJetStreamOptions(o *Options) error {
 	if o.JetStreamMaxCatchup < 0 {
 		return fmt.Errorf("jetstream max catchup cannot be negative")
 	}
+	if o.JetStreamMaxConcurrentCatchups < 0 {
+		return fmt.Errorf("jetstream max concurrent catchups cannot be negative")
+	}
+	if o.JetStreamMaxRecoveries < 0 {
+		return fmt.Errorf("jetstream max concurrent recoveries cannot be negative")
+	}
+	if o.JetStreamMaxSnapshots < 0 {
+		return fmt.Errorf("jetstream max concurrent snapshots cannot be negative")
+	}
+	if o.JetStreamMaxApplyBacklog < 0 {
+		return fmt.Errorf("jetstream max apply backlog cannot be negative")
+	}
+	if o.JetStreamBalanceInterval < 0 {
+		return fmt.Errorf("jetstream auto balance interval cannot be negative")
+	}
+	if o.JetStreamBalanceThreshold < 0 {
+		return fmt.Errorf("jetstream auto balance threshold cannot be negative")
+	}
+	if o.JetStreamOrphanInterval < 0 {
+		return fmt.Errorf("jetstream orphan check interval cannot be negative")
+	}
 	return nil
 }
 
@@ -16,6 +16,7 @@ package server
 import (
 	"bytes"
 	"cmp"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,6 +26,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -73,6 +75,11 @@ const (
 	JSApiStreamDelete  = "$JS.API.STREAM.DELETE.*"
 	JSApiStreamDeleteT = "$JS.API.STREAM.DELETE.%s"
 
+	// JSApiStreamRename is the endpoint to rename streams.
+	// Will return JSON response.
+	JSApiStreamRename  = "$JS.API.STREAM.RENAME.*"
+	JSApiStreamRenameT = "$JS.API.STREAM.RENAME.%s"
+
 	// JSApiStreamPurge is the endpoint to purge streams.
 	// Will return JSON response.
 	JSApiStreamPurge  = "$JS.API.STREAM.PURGE.*"
@@ -85,6 +92,11 @@ const (
 	JSApiStreamSnapshot  = "$JS.API.STREAM.SNAPSHOT.*"
 	JSApiStreamSnapshotT = "$JS.API.STREAM.SNAPSHOT.%s"
 
+	// JSApiStreamMultiSnapshot is the endpoint to snapshot several streams at a consistent point.
+	// Will return the chunks of each stream's snapshot like JSApiStreamSnapshot, on the deliver
+	// prefix followed by the stream name. Each can be restored using JSApiStreamRestore.
+	JSApiStreamMultiSnapshot = "$JS.API.STREAM.MULTI_SNAPSHOT"
+
 	// JSApiStreamRestore is the endpoint to restore a stream from a snapshot.
 	// Caller should respond to each chunk with a nil body response.
 	JSApiStreamRestore  = "$JS.API.STREAM.RESTORE.*"
@@ -100,6 +112,63 @@ const (
 	JSApiMsgGet  = "$JS.API.STREAM.MSG.GET.*"
 	JSApiMsgGetT = "$JS.API.STREAM.MSG.GET.%s"
 
+	// JSApiMsgReplication is the endpoint to get a message along with its replication status in a clustered stream.
+	// Will return JSON response.
+	JSApiMsgReplication  = "$JS.API.STREAM.MSG.REPLICATION.*"
+	JSApiMsgReplicationT = "$JS.API.STREAM.MSG.REPLICATION.%s"
+
+	// JSApiStreamTimeRange is the endpoint to get the timestamps of the first and last messages in a stream.
+	// Will return JSON response.
+	JSApiStreamTimeRange  = "$JS.API.STREAM.TIME_RANGE.*"
+	JSApiStreamTimeRangeT = "$JS.API.STREAM.TIME_RANGE.%s"
+
+	// JSApiStreamRebuildTotals is the endpoint to recount the messages and bytes held by a stream.
+	// Will return JSON response.
+	JSApiStreamRebuildTotals  = "$JS.API.STREAM.REBUILD_TOTALS.*"
+	JSApiStreamRebuildTotalsT = "$JS.API.STREAM.REBUILD_TOTALS.%s"
+
+	// JSApiStreamConsumerLag is the endpoint to get the aggregate lag of all consumers of a stream.
+	// Will return JSON response.
+	JSApiStreamConsumerLag  = "$JS.API.STREAM.CONSUMER_LAG.*"
+	JSApiStreamConsumerLagT = "$JS.API.STREAM.CONSUMER_LAG.%s"
+
+	// JSApiStreamPeek is the endpoint to list the metadata of the next messages matching a filter without consuming them.
+	// Will return JSON response.
+	JSApiStreamPeek  = "$JS.API.STREAM.PEEK.*"
+	JSApiStreamPeekT = "$JS.API.STREAM.PEEK.%s"
+
+	// JSApiStreamChecksum is the endpoint to compute a checksum over the contents of a stream.
+	// Will return JSON response.
+	JSApiStreamChecksum  = "$JS.API.STREAM.CHECKSUM.*"
+	JSApiStreamChecksumT = "$JS.API.STREAM.CHECKSUM.%s"
+
+	// JSApiStreamGaps is the endpoint to list the ranges of sequences missing from a stream.
+	// Will return JSON response.
+	JSApiStreamGaps  = "$JS.API.STREAM.GAPS.*"
+	JSApiStreamGapsT = "$JS.API.STREAM.GAPS.%s"
+
+	// JSApiStreamSubjectCounts is the endpoint to get the number of messages per subject, or subject prefix, of a stream.
+	// Will return JSON response.
+	JSApiStreamSubjectCounts  = "$JS.API.STREAM.SUBJECT_COUNTS.*"
+	JSApiStreamSubjectCountsT = "$JS.API.STREAM.SUBJECT_COUNTS.%s"
+
+	// JSApiStreamReserve is the endpoint to reserve capacity of a stream for a bulk publish.
+	// Will return JSON response.
+	JSApiStreamReserve  = "$JS.API.STREAM.RESERVE.*"
+	JSApiStreamReserveT = "$JS.API.STREAM.RESERVE.%s"
+
+	// JSApiKVTransaction is the endpoint to atomically apply a batch of operations to a key-value bucket.
+	// The operations are published as an atomic batch to the bucket's stream, which needs to allow atomic publish.
+	// Will return a JSPubAckResponse, like for the commit of an atomic batch.
+	JSApiKVTransaction  = "$JS.API.KV.TXN.*"
+	JSApiKVTransactionT = "$JS.API.KV.TXN.%s"
+
+	// JSApiStreamScale is the endpoint to change the replication factor of a stream,
+	// optionally returning it to its original replication factor after some time.
+	// Will return JSON response.
+	JSApiStreamScale  = "$JS.API.STREAM.SCALE.*"
+	JSApiStreamScaleT = "$JS.API.STREAM.SCALE.%s"
+
 	// JSDirectMsgGet is the template for non-api layer direct requests for a message by its stream sequence number or last by subject.
 	// Will return the message similar to how a consumer receives the message, no JSON processing.
 	// If the message can not be found we will use a status header of 404. If the stream does not exist the client will get a no-responders or timeout.
@@ -162,6 +231,51 @@ const (
 	JSApiConsumerUnpin  = "$JS.API.CONSUMER.UNPIN.*.*"
 	JSApiConsumerUnpinT = "$JS.API.CONSUMER.UNPIN.%s.%s"
 
+	// JSApiConsumerReplay is the endpoint to replay what a consumer would have delivered for a time window.
+	// Will return JSON response.
+	JSApiConsumerReplay  = "$JS.API.CONSUMER.REPLAY.*.*"
+	JSApiConsumerReplayT = "$JS.API.CONSUMER.REPLAY.%s.%s"
+
+	// JSApiConsumerEstimate is the endpoint to estimate the backlog of a consumer without creating it.
+	// Will return JSON response.
+	JSApiConsumerEstimate  = "$JS.API.CONSUMER.ESTIMATE.*"
+	JSApiConsumerEstimateT = "$JS.API.CONSUMER.ESTIMATE.%s"
+
+	// JSApiConsumerMsgHistory is the endpoint to get the delivery history of a stream message for a consumer.
+	// Will return JSON response.
+	JSApiConsumerMsgHistory  = "$JS.API.CONSUMER.HISTORY.*.*"
+	JSApiConsumerMsgHistoryT = "$JS.API.CONSUMER.HISTORY.%s.%s"
+
+	// JSApiConsumerPullRequests is the endpoint to list the pull requests waiting on a consumer.
+	// Will return JSON response.
+	JSApiConsumerPullRequests  = "$JS.API.CONSUMER.PULL_REQUESTS.*.*"
+	JSApiConsumerPullRequestsT = "$JS.API.CONSUMER.PULL_REQUESTS.%s.%s"
+
+	// JSApiConsumerExport is the endpoint to export a consumer's configuration and optionally its state.
+	// Will return JSON response.
+	JSApiConsumerExport  = "$JS.API.CONSUMER.EXPORT.*.*"
+	JSApiConsumerExportT = "$JS.API.CONSUMER.EXPORT.%s.%s"
+
+	// JSApiConsumerImport is the endpoint to create a consumer from an exported configuration and state.
+	// Will return JSON response.
+	JSApiConsumerImport  = "$JS.API.CONSUMER.IMPORT.*.*"
+	JSApiConsumerImportT = "$JS.API.CONSUMER.IMPORT.%s.%s"
+
+	// JSApiConsumerSnapshot is the endpoint to snapshot a consumer's delivery and ack state.
+	// Will return JSON response.
+	JSApiConsumerSnapshot  = "$JS.API.CONSUMER.SNAPSHOT.*.*"
+	JSApiConsumerSnapshotT = "$JS.API.CONSUMER.SNAPSHOT.%s.%s"
+
+	// JSApiConsumerRestore is the endpoint to restore a consumer's delivery and ack state from a snapshot.
+	// Will return JSON response.
+	JSApiConsumerRestore  = "$JS.API.CONSUMER.RESTORE.*.*"
+	JSApiConsumerRestoreT = "$JS.API.CONSUMER.RESTORE.%s.%s"
+
+	// JSApiConsumerMove is the endpoint to move a durable consumer to another stream.
+	// Will return JSON response.
+	JSApiConsumerMove  = "$JS.API.CONSUMER.MOVE.*.*"
+	JSApiConsumerMoveT = "$JS.API.CONSUMER.MOVE.%s.%s"
+
 	// jsRequestNextPre
 	jsRequestNextPre = "$JS.API.CONSUMER.MSG.NEXT."
 
@@ -184,6 +298,16 @@ const (
 	JSApiConsumerLeaderStepDown  = "$JS.API.CONSUMER.LEADER.STEPDOWN.*.*"
 	JSApiConsumerLeaderStepDownT = "$JS.API.CONSUMER.LEADER.STEPDOWN.%s.%s"
 
+	// JSApiStreamPlacement is the endpoint to get the servers hosting each replica of a stream.
+	// Will return JSON response.
+	JSApiStreamPlacement  = "$JS.API.STREAM.PLACEMENT.*"
+	JSApiStreamPlacementT = "$JS.API.STREAM.PLACEMENT.%s"
+
+	// JSApiConsumerPlacement is the endpoint to get the servers hosting each replica of a consumer.
+	// Will return JSON response.
+	JSApiConsumerPlacement  = "$JS.API.CONSUMER.PLACEMENT.*.*"
+	JSApiConsumerPlacementT = "$JS.API.CONSUMER.PLACEMENT.%s.%s"
+
 	// JSApiLeaderStepDown is the endpoint to have our metaleader stepdown.
 	// Only works from system account.
 	// Will return JSON response.
@@ -194,6 +318,11 @@ const (
 	// Will return JSON response.
 	JSApiRemoveServer = "$JS.API.SERVER.REMOVE"
 
+	// JSApiServerEvacuate is the endpoint to move all streams and consumers off a server.
+	// Only works from system account.
+	// Will return JSON response.
+	JSApiServerEvacuate = "$JS.API.SERVER.EVACUATE"
+
 	// JSApiAccountPurge is the endpoint to purge the js content of an account
 	// Only works from system account.
 	// Will return JSON response.
@@ -215,6 +344,12 @@ const (
 	// The prefix for system level account API.
 	jsAPIAccountPre = "$JS.API.ACCOUNT."
 
+	// The prefix for key-value transactions.
+	jsAPIKVTransactionPre = "$JS.API.KV.TXN."
+
+	// The prefix for consumer replays.
+	jsAPIConsumerReplayPre = "$JS.API.CONSUMER.REPLAY."
+
 	// jsAckT is the template for the ack message stream coming back from a consumer
 	// when they ACK/NAK, etc a message.
 	jsAckT      = "$JS.ACK.%s.%s"
@@ -246,6 +381,10 @@ const (
 	// JSAdvisoryConsumerMsgTerminatedPre is a notification published when a message has been terminated.
 	JSAdvisoryConsumerMsgTerminatedPre = "$JS.EVENT.ADVISORY.CONSUMER.MSG_TERMINATED"
 
+	// JSAdvisoryConsumerFirstDeliveryPre is a notification published when a consumer delivers
+	// its first message after being created or restarted.
+	JSAdvisoryConsumerFirstDeliveryPre = "$JS.EVENT.ADVISORY.CONSUMER.FIRST_DELIVERY"
+
 	// JSAdvisoryStreamCreatedPre notification that a stream was created.
 	JSAdvisoryStreamCreatedPre = "$JS.EVENT.ADVISORY.STREAM.CREATED"
 
@@ -255,12 +394,21 @@ const (
 	// JSAdvisoryStreamUpdatedPre notification that a stream was updated.
 	JSAdvisoryStreamUpdatedPre = "$JS.EVENT.ADVISORY.STREAM.UPDATED"
 
+	// JSAdvisoryStreamConfigChangedPre notification that a stream's configuration was changed, including the changes.
+	JSAdvisoryStreamConfigChangedPre = "$JS.EVENT.ADVISORY.STREAM.CONFIG_CHANGED"
+
 	// JSAdvisoryConsumerCreatedPre notification that a consumer was created.
 	JSAdvisoryConsumerCreatedPre = "$JS.EVENT.ADVISORY.CONSUMER.CREATED"
 
 	// JSAdvisoryConsumerDeletedPre notification that a consumer was deleted.
 	JSAdvisoryConsumerDeletedPre = "$JS.EVENT.ADVISORY.CONSUMER.DELETED"
 
+	// JSAdvisoryConsumerNoInterestPre notification that a push consumer lost interest on its deliver subject.
+	JSAdvisoryConsumerNoInterestPre = "$JS.EVENT.ADVISORY.CONSUMER.NO_INTEREST"
+
+	// JSAdvisoryConsumerRetentionSkipPre notification that the stream's retention removed messages a consumer did not deliver yet.
+	JSAdvisoryConsumerRetentionSkipPre = "$JS.EVENT.ADVISORY.CONSUMER.RETENTION_SKIP"
+
 	// JSAdvisoryConsumerPausePre notification that a consumer paused/unpaused.
 	JSAdvisoryConsumerPausePre = "$JS.EVENT.ADVISORY.CONSUMER.PAUSE"
 
@@ -291,9 +439,18 @@ const (
 	// JSAdvisoryStreamQuorumLostPre notification that a stream and its consumers are stalled.
 	JSAdvisoryStreamQuorumLostPre = "$JS.EVENT.ADVISORY.STREAM.QUORUM_LOST"
 
+	// JSAdvisoryStreamIOErrorPre notification that a stream encountered an I/O error.
+	JSAdvisoryStreamIOErrorPre = "$JS.EVENT.ADVISORY.STREAM.IO_ERROR"
+
 	// JSAdvisoryStreamBatchAbandonedPre notification that a stream's batch was abandoned.
 	JSAdvisoryStreamBatchAbandonedPre = "$JS.EVENT.ADVISORY.STREAM.BATCH_ABANDONED"
 
+	// JSAdvisoryStreamMsgsExpiringPre notification that a stream's messages are about to expire.
+	JSAdvisoryStreamMsgsExpiringPre = "$JS.EVENT.ADVISORY.STREAM.MSGS_EXPIRING"
+
+	// JSAdvisoryStreamPublishRejectedPre notification that a publish to a sealed or read-only stream was rejected.
+	JSAdvisoryStreamPublishRejectedPre = "$JS.EVENT.ADVISORY.STREAM.PUBLISH_REJECTED"
+
 	// JSAdvisoryConsumerLeaderElectedPre notification that a replicated consumer has elected a leader.
 	JSAdvisoryConsumerLeaderElectedPre = "$JS.EVENT.ADVISORY.CONSUMER.LEADER_ELECTED"
 
@@ -309,6 +466,10 @@ const (
 	// JSAdvisoryAPILimitReached notification that a server has reached the JS API hard limit.
 	JSAdvisoryAPILimitReached = "$JS.EVENT.ADVISORY.API.LIMIT_REACHED"
 
+	// JSAdvisoryRateLimited is a notification that advisories of an account were dropped
+	// because they exceeded the account's advisory rate limit.
+	JSAdvisoryRateLimited = "$JS.EVENT.ADVISORY.RATE_LIMITED"
+
 	// JSAuditAdvisory is a notification about JetStream API access.
 	// FIXME - Add in details about who..
 	JSAuditAdvisory = "$JS.EVENT.ADVISORY.API"
@@ -431,6 +592,20 @@ type JSApiStreamDeleteResponse struct {
 
 const JSApiStreamDeleteResponseType = "io.nats.jetstream.api.v1.stream_delete_response"
 
+// JSApiStreamRenameRequest is used to rename a stream.
+type JSApiStreamRenameRequest struct {
+	// Name is the new name of the stream.
+	Name string `json:"name"`
+}
+
+// JSApiStreamRenameResponse stream rename.
+type JSApiStreamRenameResponse struct {
+	ApiResponse
+	Success bool `json:"success,omitempty"`
+}
+
+const JSApiStreamRenameResponseType = "io.nats.jetstream.api.v1.stream_rename_response"
+
 // JSMaxSubjectDetails The limit of the number of subject details we will send in a stream info response.
 const JSMaxSubjectDetails = 100_000
 
@@ -519,6 +694,112 @@ type JSApiConsumerUnpinResponse struct {
 
 const JSApiConsumerUnpinResponseType = "io.nats.jetstream.api.v1.consumer_unpin_response"
 
+// JSApiConsumerReplayRequest asks for the messages a consumer would have delivered
+// between StartTime and EndTime to be sent to DeliverSubject. The replay stops after
+// MaxMsgs messages or MaxBytes bytes, which can not exceed the server's own limits.
+type JSApiConsumerReplayRequest struct {
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	DeliverSubject string    `json:"deliver_subject"`
+	MaxMsgs        int       `json:"max_msgs,omitempty"`
+	MaxBytes       int       `json:"max_bytes,omitempty"`
+}
+
+type JSApiConsumerReplayResponse struct {
+	ApiResponse
+	Delivered uint64 `json:"delivered"`
+	// Truncated is set if the replay hit its limits before the end of the window.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+const JSApiConsumerReplayResponseType = "io.nats.jetstream.api.v1.consumer_replay_response"
+
+// JSApiConsumerEstimateRequest holds the configuration of the consumer to estimate.
+type JSApiConsumerEstimateRequest struct {
+	Config ConsumerConfig `json:"config"`
+}
+
+// JSApiConsumerEstimateResponse reports how many messages a consumer would have pending
+// if created now, and the stream sequence of the first one.
+type JSApiConsumerEstimateResponse struct {
+	ApiResponse
+	NumPending uint64 `json:"num_pending"`
+	FirstSeq   uint64 `json:"first_seq,omitempty"`
+}
+
+const JSApiConsumerEstimateResponseType = "io.nats.jetstream.api.v1.consumer_estimate_response"
+
+// JSApiConsumerMsgHistoryRequest asks for the delivery history of a stream sequence.
+type JSApiConsumerMsgHistoryRequest struct {
+	Seq uint64 `json:"seq"`
+}
+
+type JSApiConsumerMsgHistoryResponse struct {
+	ApiResponse
+	*ConsumerMsgHistory
+}
+
+const JSApiConsumerMsgHistoryResponseType = "io.nats.jetstream.api.v1.consumer_msg_history_response"
+
+// JSApiConsumerPullRequestsResponse lists the pull requests waiting on a consumer.
+type JSApiConsumerPullRequestsResponse struct {
+	ApiResponse
+	Requests []*PullRequestInfo `json:"requests"`
+}
+
+const JSApiConsumerPullRequestsResponseType = "io.nats.jetstream.api.v1.consumer_pull_requests_response"
+
+// JSApiConsumerExportRequest asks to export a consumer, optionally with its state.
+type JSApiConsumerExportRequest struct {
+	State bool `json:"state,omitempty"`
+}
+
+type JSApiConsumerExportResponse struct {
+	ApiResponse
+	*ConsumerExport
+}
+
+const JSApiConsumerExportResponseType = "io.nats.jetstream.api.v1.consumer_export_response"
+
+// JSApiConsumerImportRequest creates a consumer from an export. The stream is
+// taken from the subject, so a consumer can be imported onto any matching stream.
+// Will return a JSApiConsumerCreateResponse.
+type JSApiConsumerImportRequest struct {
+	Stream string         `json:"stream_name,omitempty"` // Stream it was exported from, informational only
+	Config ConsumerConfig `json:"config"`
+	State  *ConsumerState `json:"state,omitempty"`
+}
+
+// JSApiConsumerSnapshotResponse holds a consumer's delivery and ack state as an opaque blob.
+// It can be restored onto this or any other consumer with a JSApiConsumerRestoreRequest.
+type JSApiConsumerSnapshotResponse struct {
+	ApiResponse
+	Stream   string `json:"stream_name,omitempty"`
+	Consumer string `json:"name,omitempty"`
+	State    []byte `json:"state,omitempty"`
+}
+
+const JSApiConsumerSnapshotResponseType = "io.nats.jetstream.api.v1.consumer_snapshot_response"
+
+// JSApiConsumerRestoreRequest replaces the state of an existing consumer with a snapshot.
+type JSApiConsumerRestoreRequest struct {
+	State []byte `json:"state"`
+}
+
+type JSApiConsumerRestoreResponse struct {
+	ApiResponse
+	*ConsumerInfo
+}
+
+const JSApiConsumerRestoreResponseType = "io.nats.jetstream.api.v1.consumer_restore_response"
+
+// JSApiConsumerMoveRequest moves a durable consumer to the target stream. The consumer is
+// created on the target stream first and only then deleted from its current stream.
+// Will return a JSApiConsumerCreateResponse with the consumer on the target stream.
+type JSApiConsumerMoveRequest struct {
+	Stream string `json:"stream_name"`
+}
+
 // JSApiStreamUpdateResponse for updating a stream.
 type JSApiStreamUpdateResponse struct {
 	ApiResponse
@@ -571,12 +852,89 @@ type JSApiStreamSnapshotResponse struct {
 
 const JSApiStreamSnapshotResponseType = "io.nats.jetstream.api.v1.stream_snapshot_response"
 
+// JSApiStreamMultiSnapshotRequest asks for snapshots of several streams, all taken at the same point.
+// In clustered mode, the streams need to be led by the same server.
+type JSApiStreamMultiSnapshotRequest struct {
+	// Streams to snapshot.
+	Streams []string `json:"streams"`
+	// Prefix of the subjects to deliver the chunks to, followed by the stream name.
+	DeliverPrefix string `json:"deliver_prefix"`
+	// Do not include consumers in the snapshots.
+	NoConsumers bool `json:"no_consumers,omitempty"`
+	// Optional chunk and window size preferences, as for a single stream snapshot.
+	ChunkSize  int `json:"chunk_size,omitempty"`
+	WindowSize int `json:"window_size,omitempty"`
+}
+
+// StreamSnapshotInfo describes the snapshot of a single stream of a multi-stream snapshot.
+type StreamSnapshotInfo struct {
+	// Subject the chunks of the snapshot are delivered to.
+	DeliverSubject string `json:"deliver_subject"`
+	// Configuration of the stream.
+	Config StreamConfig `json:"config"`
+	// State of the stream at the point the snapshot was taken.
+	State StreamState `json:"state"`
+}
+
+// JSApiStreamMultiSnapshotResponse is the direct response to the multi-stream snapshot request.
+type JSApiStreamMultiSnapshotResponse struct {
+	ApiResponse
+	Snapshots []*StreamSnapshotInfo `json:"snapshots,omitempty"`
+}
+
+const JSApiStreamMultiSnapshotResponseType = "io.nats.jetstream.api.v1.stream_multi_snapshot_response"
+
 // JSApiStreamRestoreRequest is the required restore request.
 type JSApiStreamRestoreRequest struct {
 	// Configuration of the given stream.
 	Config StreamConfig `json:"config"`
 	// Current State for the given stream.
 	State StreamState `json:"state"`
+	// Recreate the consumers in the snapshot from their configuration
+	// only, discarding their saved state.
+	ConsumerReset *ConsumerRestoreReset `json:"consumer_reset,omitempty"`
+}
+
+// ConsumerRestoreReset determines where consumers recreated on a stream restore
+// start from. If no deliver policy is set, each consumer starts from the start
+// position in its own configuration.
+type ConsumerRestoreReset struct {
+	DeliverPolicy *DeliverPolicy `json:"deliver_policy,omitempty"`
+	OptStartSeq   uint64         `json:"opt_start_seq,omitempty"`
+	OptStartTime  *time.Time     `json:"opt_start_time,omitempty"`
+}
+
+func (r *ConsumerRestoreReset) validate() error {
+	if r.DeliverPolicy == nil {
+		if r.OptStartSeq > 0 || r.OptStartTime != nil {
+			return errors.New("consumer reset start position requires a deliver policy")
+		}
+		return nil
+	}
+	switch *r.DeliverPolicy {
+	case DeliverByStartSequence:
+		if r.OptStartSeq == 0 || r.OptStartTime != nil {
+			return errors.New("consumer reset by start sequence requires only an optional start sequence")
+		}
+	case DeliverByStartTime:
+		if r.OptStartTime == nil || r.OptStartSeq > 0 {
+			return errors.New("consumer reset by start time requires only an optional start time")
+		}
+	default:
+		if r.OptStartSeq > 0 || r.OptStartTime != nil {
+			return fmt.Errorf("consumer reset with deliver policy %q can not have an optional start position", r.DeliverPolicy)
+		}
+	}
+	return nil
+}
+
+// apply overrides the start position in the consumer configuration, if needed.
+func (r *ConsumerRestoreReset) apply(cfg *ConsumerConfig) {
+	if r.DeliverPolicy == nil {
+		return
+	}
+	cfg.DeliverPolicy = *r.DeliverPolicy
+	cfg.OptStartSeq, cfg.OptStartTime = r.OptStartSeq, r.OptStartTime
 }
 
 // JSApiStreamRestoreResponse is the direct response to the restore request.
@@ -618,6 +976,38 @@ type JSApiConsumerLeaderStepDownResponse struct {
 
 const JSApiConsumerLeaderStepDownResponseType = "io.nats.jetstream.api.v1.consumer_leader_stepdown_response"
 
+// AssetPlacement describes the servers hosting the replicas of a stream or consumer.
+type AssetPlacement struct {
+	Cluster  string              `json:"cluster,omitempty"`
+	Leader   string              `json:"leader,omitempty"`
+	Replicas []*ReplicaPlacement `json:"replicas"`
+}
+
+// ReplicaPlacement is a server hosting a replica of a stream or consumer.
+type ReplicaPlacement struct {
+	Name    string `json:"name"`
+	ID      string `json:"id,omitempty"`
+	Peer    string `json:"peer"`
+	Leader  bool   `json:"leader,omitempty"`
+	Offline bool   `json:"offline,omitempty"`
+}
+
+// JSApiStreamPlacementResponse is the response to a stream placement request.
+type JSApiStreamPlacementResponse struct {
+	ApiResponse
+	*AssetPlacement
+}
+
+const JSApiStreamPlacementResponseType = "io.nats.jetstream.api.v1.stream_placement_response"
+
+// JSApiConsumerPlacementResponse is the response to a consumer placement request.
+type JSApiConsumerPlacementResponse struct {
+	ApiResponse
+	*AssetPlacement
+}
+
+const JSApiConsumerPlacementResponseType = "io.nats.jetstream.api.v1.consumer_placement_response"
+
 // JSApiLeaderStepdownRequest allows placement control over the meta leader placement.
 type JSApiLeaderStepdownRequest struct {
 	Placement *Placement `json:"placement,omitempty"`
@@ -648,6 +1038,44 @@ type JSApiMetaServerRemoveResponse struct {
 
 const JSApiMetaServerRemoveResponseType = "io.nats.jetstream.api.v1.meta_server_remove_response"
 
+// JSApiMetaServerEvacuateRequest will move all streams and consumers off a server.
+// The request can be repeated until the response reports the server is evacuated.
+type JSApiMetaServerEvacuateRequest struct {
+	// Server name of the peer to be evacuated.
+	Server string `json:"server"`
+	// Cluster the server is in
+	Cluster string `json:"cluster,omitempty"`
+	// Domain the sever is in
+	Domain string `json:"domain,omitempty"`
+	// MaxMoves is the maximum number of stream moves in progress at the same time, defaults to 1.
+	MaxMoves int `json:"max_moves,omitempty"`
+}
+
+// JSApiMetaServerEvacuateStream is a stream that still has a replica on the server being evacuated.
+type JSApiMetaServerEvacuateStream struct {
+	Account string `json:"account"`
+	Stream  string `json:"stream"`
+	// Moving is set if the stream is being moved off the server.
+	Moving bool `json:"moving,omitempty"`
+	// Error is set if the stream can not be moved off the server right now.
+	Error string `json:"error,omitempty"`
+}
+
+// JSApiMetaServerEvacuateResponse reports on the progress of evacuating a server.
+type JSApiMetaServerEvacuateResponse struct {
+	ApiResponse
+	// Streams that still have a replica on the server.
+	Streams []*JSApiMetaServerEvacuateStream `json:"streams,omitempty"`
+	// Consumers is the number of consumers that still have a replica on the server.
+	Consumers int `json:"consumers,omitempty"`
+	// Started is the number of stream moves started by this request.
+	Started int `json:"started,omitempty"`
+	// Done is set once the server hosts no more streams or consumers.
+	Done bool `json:"done,omitempty"`
+}
+
+const JSApiMetaServerEvacuateResponseType = "io.nats.jetstream.api.v1.meta_server_evacuate_response"
+
 // JSApiMetaServerStreamMoveRequest will move a stream on a server to another
 // response to this will come as JSApiStreamUpdateResponse/JSApiStreamUpdateResponseType
 type JSApiMetaServerStreamMoveRequest struct {
@@ -701,6 +1129,244 @@ type JSApiMsgGetResponse struct {
 
 const JSApiMsgGetResponseType = "io.nats.jetstream.api.v1.stream_msg_get_response"
 
+// MsgReplicaStatus reports whether a single replica has stored a message.
+type MsgReplicaStatus struct {
+	Name   string `json:"name"`
+	Leader bool   `json:"leader,omitempty"`
+	Stored bool   `json:"stored"`
+}
+
+// MsgReplication is the replication status of a message, as known by the stream leader.
+type MsgReplication struct {
+	// Committed is set once a quorum of replicas has stored the message.
+	Committed bool `json:"committed"`
+	// Stored is the number of replicas that have stored the message.
+	Stored   int                 `json:"stored"`
+	Replicas []*MsgReplicaStatus `json:"replicas"`
+}
+
+// JSApiMsgReplicationResponse returns a message along with its replication status.
+// The request is a JSApiMsgGetRequest, only supporting a sequence or last by subject.
+type JSApiMsgReplicationResponse struct {
+	ApiResponse
+	Message     *StoredMsg      `json:"message,omitempty"`
+	Replication *MsgReplication `json:"replication,omitempty"`
+}
+
+const JSApiMsgReplicationResponseType = "io.nats.jetstream.api.v1.stream_msg_replication_response"
+
+// JSApiStreamTimeRangeResponse reports the timestamps of the first and last messages in a stream.
+// Both are omitted when the stream holds no messages.
+type JSApiStreamTimeRangeResponse struct {
+	ApiResponse
+	FirstTime *time.Time `json:"first_ts,omitempty"`
+	LastTime  *time.Time `json:"last_ts,omitempty"`
+}
+
+const JSApiStreamTimeRangeResponseType = "io.nats.jetstream.api.v1.stream_time_range_response"
+
+// JSApiStreamRebuildTotalsResponse reports the stream totals after they have been
+// recounted from the store, and by how much they were corrected.
+type JSApiStreamRebuildTotalsResponse struct {
+	ApiResponse
+	Msgs       uint64 `json:"messages"`
+	Bytes      uint64 `json:"bytes"`
+	MsgsDelta  int64  `json:"messages_delta"`
+	BytesDelta int64  `json:"bytes_delta"`
+}
+
+const JSApiStreamRebuildTotalsResponseType = "io.nats.jetstream.api.v1.stream_rebuild_totals_response"
+
+// JSApiStreamConsumerLagResponse reports how far behind the stream's last sequence its consumers are.
+type JSApiStreamConsumerLagResponse struct {
+	ApiResponse
+	Consumers      int      `json:"consumers"`
+	MaxLag         uint64   `json:"max_lag"`
+	MaxLagConsumer string   `json:"max_lag_consumer,omitempty"`
+	TotalLag       uint64   `json:"total_lag"`
+	Missing        []string `json:"missing,omitempty"`
+}
+
+const JSApiStreamConsumerLagResponseType = "io.nats.jetstream.api.v1.stream_consumer_lag_response"
+
+// JSApiStreamPeekDefaultLimit is the number of messages returned by a peek request when no limit is set.
+const JSApiStreamPeekDefaultLimit = 256
+
+// JSApiStreamPeekMaxLimit is the maximum number of messages returned by a single peek request.
+const JSApiStreamPeekMaxLimit = 4096
+
+// JSApiStreamPeekRequest selects the messages whose metadata is returned by a peek request.
+type JSApiStreamPeekRequest struct {
+	// Filter is the subject filter, all subjects if not set. Can include wildcards.
+	Filter string `json:"filter,omitempty"`
+	// StartSeq is the first sequence to consider, the first sequence in the stream if not set.
+	StartSeq uint64 `json:"start_seq,omitempty"`
+	// Limit is the maximum number of messages returned, JSApiStreamPeekDefaultLimit if not set.
+	Limit int `json:"limit,omitempty"`
+}
+
+// StreamPeekMsg is the metadata of a message returned by a peek request.
+type StreamPeekMsg struct {
+	Sequence uint64    `json:"seq"`
+	Subject  string    `json:"subject"`
+	Size     int       `json:"size"`
+	Time     time.Time `json:"time"`
+}
+
+// JSApiStreamPeekResponse lists the metadata of the messages matching a peek request.
+// NextSeq is set when more messages match, and can be used as the start sequence of the next page.
+type JSApiStreamPeekResponse struct {
+	ApiResponse
+	Messages []*StreamPeekMsg `json:"messages"`
+	NextSeq  uint64           `json:"next_seq,omitempty"`
+}
+
+const JSApiStreamPeekResponseType = "io.nats.jetstream.api.v1.stream_peek_response"
+
+const (
+	// StreamChecksumSHA256 computes a SHA-256 stream checksum, the default.
+	StreamChecksumSHA256 = "sha256"
+	// StreamChecksumHighwayHash computes a 64-bit HighwayHash stream checksum,
+	// which is faster but not meant to protect against tampering.
+	StreamChecksumHighwayHash = "highwayhash"
+)
+
+// JSApiStreamChecksumRequest selects the messages a stream checksum is computed over, and how.
+type JSApiStreamChecksumRequest struct {
+	// StartSeq is the first sequence to include, the first sequence in the stream if not set.
+	StartSeq uint64 `json:"start_seq,omitempty"`
+	// EndSeq is the last sequence to include, the last sequence in the stream if not set.
+	EndSeq uint64 `json:"end_seq,omitempty"`
+	// Algorithm is the hash to use, StreamChecksumSHA256 if not set.
+	Algorithm string `json:"algorithm,omitempty"`
+	// Peer is the name of the server hosting the replica to compute the checksum on.
+	// The stream leader answers if not set.
+	Peer string `json:"peer,omitempty"`
+}
+
+// JSApiStreamChecksumResponse reports a checksum over the sequence, subject, headers and data
+// of the messages in a range, as stored by the replica on Server. It's deterministic, so can
+// be compared across the replicas, mirrors or restored backups of a stream. Replicas may not
+// have applied the same messages yet, so an EndSeq they all have should be used to compare them.
+type JSApiStreamChecksumResponse struct {
+	ApiResponse
+	Server    string `json:"server"`
+	Algorithm string `json:"algorithm"`
+	FirstSeq  uint64 `json:"first_seq"`
+	LastSeq   uint64 `json:"last_seq"`
+	Msgs      uint64 `json:"messages"`
+	Checksum  string `json:"checksum"`
+}
+
+const JSApiStreamChecksumResponseType = "io.nats.jetstream.api.v1.stream_checksum_response"
+
+// JSApiStreamGapsDefaultLimit is the number of gaps returned by a gaps request when no limit is set.
+const JSApiStreamGapsDefaultLimit = 256
+
+// JSApiStreamGapsMaxLimit is the maximum number of gaps returned by a single gaps request.
+const JSApiStreamGapsMaxLimit = 4096
+
+// JSApiStreamGapsRequest selects the range of sequences a gaps request looks at.
+type JSApiStreamGapsRequest struct {
+	// StartSeq is the first sequence to consider, sequence 1 if not set.
+	StartSeq uint64 `json:"start_seq,omitempty"`
+	// EndSeq is the last sequence to consider, the last sequence in the stream if not set.
+	EndSeq uint64 `json:"end_seq,omitempty"`
+	// Limit is the maximum number of gaps returned, JSApiStreamGapsDefaultLimit if not set.
+	Limit int `json:"limit,omitempty"`
+}
+
+// StreamGap is an inclusive range of sequences that are no longer in a stream,
+// because they were deleted, expired, purged or removed due to limits.
+type StreamGap struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+}
+
+// JSApiStreamGapsResponse lists the gaps in a range of sequences, in order.
+// NextSeq is set when more gaps follow, and can be used as the start sequence of the next page.
+type JSApiStreamGapsResponse struct {
+	ApiResponse
+	Gaps    []*StreamGap `json:"gaps"`
+	NextSeq uint64       `json:"next_seq,omitempty"`
+}
+
+const JSApiStreamGapsResponseType = "io.nats.jetstream.api.v1.stream_gaps_response"
+
+// JSApiStreamSubjectCountsRequest selects the subjects a subject counts request reports on.
+type JSApiStreamSubjectCountsRequest struct {
+	// Filter selects the subjects to count, all subjects if not set.
+	Filter string `json:"filter,omitempty"`
+	// Depth aggregates the counts by the first depth tokens of the subjects, no aggregation if not set.
+	Depth int `json:"depth,omitempty"`
+	// Offset pages through the counts, in subject order, if there are more than JSMaxSubjectDetails.
+	Offset int `json:"offset,omitempty"`
+}
+
+// JSApiStreamSubjectCountsResponse holds the number of messages per subject, or subject prefix,
+// along with the total number of messages matching the filter.
+type JSApiStreamSubjectCountsResponse struct {
+	ApiResponse
+	ApiPaged
+	Counts map[string]uint64 `json:"counts"`
+	Msgs   uint64            `json:"messages"`
+}
+
+const JSApiStreamSubjectCountsResponseType = "io.nats.jetstream.api.v1.stream_subject_counts_response"
+
+// JSApiStreamReserveDefaultTTL is the time capacity is reserved for, if not set in the request.
+const JSApiStreamReserveDefaultTTL = time.Minute
+
+// JSApiStreamReserveMaxTTL is the maximum time capacity can be reserved for.
+const JSApiStreamReserveMaxTTL = time.Hour
+
+// JSApiStreamReserveRequest reserves capacity of the stream and account limits for a bulk publish.
+// At least one of messages or bytes needs to be set. When clustered, the stream leader replicates the
+// reservation and its expiry to all replicas, and stream snapshots hold the reservations left at the
+// time, so a replica that catches up or recovers from a snapshot knows about them as well.
+type JSApiStreamReserveRequest struct {
+	Msgs  uint64        `json:"msgs,omitempty"`
+	Bytes uint64        `json:"bytes,omitempty"`
+	TTL   time.Duration `json:"ttl,omitempty"`
+}
+
+// JSApiStreamReserveResponse holds the id publishes need to set in the Nats-Reservation-Id header
+// to use the reserved capacity. Whatever is not used by then is released at the expiry.
+type JSApiStreamReserveResponse struct {
+	ApiResponse
+	ID      string    `json:"id,omitempty"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+const JSApiStreamReserveResponseType = "io.nats.jetstream.api.v1.stream_reserve_response"
+
+// JSApiKVOperation is a single operation of a key-value transaction.
+type JSApiKVOperation struct {
+	// Op is either "put", "del" or "purge", defaults to "put".
+	Op    string `json:"op,omitempty"`
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+	// Revision, if set, is the revision the key needs to be at for the transaction
+	// to be applied. Zero means the key must not exist.
+	Revision *uint64 `json:"revision,omitempty"`
+}
+
+// JSApiKVTransactionRequest applies either all of its operations, or none of them
+// if any of the revisions doesn't match.
+type JSApiKVTransactionRequest struct {
+	Ops []JSApiKVOperation `json:"ops"`
+}
+
+// JSApiStreamScaleRequest changes the replication factor of a stream.
+// The response to this will come as JSApiStreamUpdateResponse/JSApiStreamUpdateResponseType.
+type JSApiStreamScaleRequest struct {
+	// Replicas is the new replication factor.
+	Replicas int `json:"num_replicas"`
+	// RevertAfter, if set, returns the stream to its original replication factor once elapsed.
+	// Only allowed when elevating the replication factor.
+	RevertAfter time.Duration `json:"revert_after,omitempty"`
+}
+
 // JSWaitQueueDefaultMax is the default max number of outstanding requests for pull consumers.
 const JSWaitQueueDefaultMax = 512
 
@@ -800,6 +1466,7 @@ func (js *jetStream) apiDispatch(sub *subscription, c *client, acc *Account, sub
 	// Ignore system level directives meta stepdown and peer remove requests here.
 	if subject == JSApiLeaderStepDown ||
 		subject == JSApiRemoveServer ||
+		subject == JSApiServerEvacuate ||
 		strings.HasPrefix(subject, jsAPIAccountPre) {
 		return
 	}
@@ -826,6 +1493,16 @@ func (js *jetStream) apiDispatch(sub *subscription, c *client, acc *Account, sub
 		}
 	}
 
+	// Key-value transactions publish on behalf of the requester, whose permissions are
+	// only known to the server it is connected to. That server checks and forwards them.
+	if strings.HasPrefix(subject, jsAPIKVTransactionPre) && !s.checkKVTransactionRequest(c, subject, reply, rmsg) {
+		return
+	}
+	// Same for consumer replays, which publish to a deliver subject of the requester's choosing.
+	if strings.HasPrefix(subject, jsAPIConsumerReplayPre) && !s.checkConsumerReplayRequest(c, subject, reply, rmsg) {
+		return
+	}
+
 	// Short circuit for no interest.
 	if len(rr.psubs)+len(rr.qsubs) == 0 {
 		if (c.kind == CLIENT || c.kind == LEAF) && acc != s.SystemAccount() {
@@ -963,6 +1640,16 @@ func (s *Server) setJetStreamExportSubs() error {
 		return err
 	}
 
+	// Key-value transactions forwarded to the stream leader by the server the requester is connected to.
+	if _, err := s.sysSubscribe(clusterKVTransaction, js.apiDispatch); err != nil {
+		return err
+	}
+
+	// Consumer replays forwarded to the consumer leader by the server the requester is connected to.
+	if _, err := s.sysSubscribe(clusterConsumerReplay, js.apiDispatch); err != nil {
+		return err
+	}
+
 	if err := s.SystemAccount().AddServiceExport(jsAllAPI, nil); err != nil {
 		s.Warnf("Error setting up jetstream service exports: %v", err)
 		return err
@@ -977,20 +1664,44 @@ func (s *Server) setJetStreamExportSubs() error {
 		{JSApiStreamCreate, s.jsStreamCreateRequest},
 		{JSApiStreamUpdate, s.jsStreamUpdateRequest},
 		{JSApiStreamDelete, s.jsStreamDeleteRequest},
+		{JSApiStreamRename, s.jsStreamRenameRequest},
 		{JSApiStreamPurge, s.jsStreamPurgeRequest},
 		{JSApiStreamSnapshot, s.jsStreamSnapshotRequest},
+		{JSApiStreamMultiSnapshot, s.jsStreamMultiSnapshotRequest},
 		{JSApiStreamRestore, s.jsStreamRestoreRequest},
 		{JSApiStreamRemovePeer, s.jsStreamRemovePeerRequest},
 		{JSApiStreamLeaderStepDown, s.jsStreamLeaderStepDownRequest},
 		{JSApiConsumerLeaderStepDown, s.jsConsumerLeaderStepDownRequest},
 		{JSApiMsgDelete, s.jsMsgDeleteRequest},
 		{JSApiMsgGet, s.jsMsgGetRequest},
+		{JSApiMsgReplication, s.jsMsgReplicationRequest},
+		{JSApiStreamTimeRange, s.jsStreamTimeRangeRequest},
+		{JSApiStreamRebuildTotals, s.jsStreamRebuildTotalsRequest},
+		{JSApiStreamConsumerLag, s.jsStreamConsumerLagRequest},
+		{JSApiStreamPeek, s.jsStreamPeekRequest},
+		{JSApiStreamChecksum, s.jsStreamChecksumRequest},
+		{JSApiStreamGaps, s.jsStreamGapsRequest},
+		{JSApiStreamSubjectCounts, s.jsStreamSubjectCountsRequest},
+		{JSApiStreamReserve, s.jsStreamReserveRequest},
+		{JSApiKVTransaction, s.jsKVTransactionRequest},
+		{clusterKVTransaction, s.jsKVTransactionRequest},
+		{JSApiStreamScale, s.jsStreamScaleRequest},
 		{JSApiConsumerCreateEx, s.jsConsumerCreateRequest},
 		{JSApiConsumerCreate, s.jsConsumerCreateRequest},
 		{JSApiDurableCreate, s.jsConsumerCreateRequest},
 		{JSApiConsumerDelete, s.jsConsumerDeleteRequest},
 		{JSApiConsumerPause, s.jsConsumerPauseRequest},
 		{JSApiConsumerUnpin, s.jsConsumerUnpinRequest},
+		{JSApiConsumerReplay, s.jsConsumerReplayRequest},
+		{clusterConsumerReplay, s.jsConsumerReplayRequest},
+		{JSApiConsumerEstimate, s.jsConsumerEstimateRequest},
+		{JSApiConsumerMsgHistory, s.jsConsumerMsgHistoryRequest},
+		{JSApiConsumerPullRequests, s.jsConsumerPullRequestsRequest},
+		{JSApiConsumerExport, s.jsConsumerExportRequest},
+		{JSApiConsumerImport, s.jsConsumerImportRequest},
+		{JSApiConsumerSnapshot, s.jsConsumerSnapshotRequest},
+		{JSApiConsumerRestore, s.jsConsumerRestoreRequest},
+		{JSApiConsumerMove, s.jsConsumerMoveRequest},
 	}
 	infopairs := []struct {
 		subject string
@@ -1003,6 +1714,8 @@ func (s *Server) setJetStreamExportSubs() error {
 		{JSApiConsumers, s.jsConsumerNamesRequest},
 		{JSApiConsumerList, s.jsConsumerListRequest},
 		{JSApiConsumerInfo, s.jsConsumerInfoRequest},
+		{JSApiStreamPlacement, s.jsStreamPlacementRequest},
+		{JSApiConsumerPlacement, s.jsConsumerPlacementRequest},
 	}
 
 	js.mu.Lock()
@@ -1029,7 +1742,8 @@ func (s *Server) setJetStreamExportSubs() error {
 func (s *Server) sendAPIResponse(ci *ClientInfo, acc *Account, subject, reply, request, response string) {
 	acc.trackAPI()
 	if reply != _EMPTY_ {
-		s.sendInternalAccountMsg(nil, reply, response)
+		hdr, payload := s.encodeAPIResponse(ci, response)
+		s.sendInternalAccountMsgWithReply(nil, reply, _EMPTY_, hdr, payload, false)
 	}
 	s.sendJetStreamAPIAuditAdvisory(ci, acc, subject, request, response)
 }
@@ -1037,7 +1751,8 @@ func (s *Server) sendAPIResponse(ci *ClientInfo, acc *Account, subject, reply, r
 func (s *Server) sendAPIErrResponse(ci *ClientInfo, acc *Account, subject, reply, request, response string) {
 	acc.trackAPIErr()
 	if reply != _EMPTY_ {
-		s.sendInternalAccountMsg(nil, reply, response)
+		hdr, payload := s.encodeAPIResponse(ci, response)
+		s.sendInternalAccountMsgWithReply(nil, reply, _EMPTY_, hdr, payload, false)
 	}
 	s.sendJetStreamAPIAuditAdvisory(ci, acc, subject, request, response)
 }
@@ -1190,6 +1905,7 @@ func (s *Server) getRequestInfo(c *client, raw []byte) (pci *ClientInfo, acc *Ac
 		if err := json.Unmarshal(sliceHeader(ClientInfoHdr, hdr), &ci); err != nil {
 			return nil, nil, nil, nil, err
 		}
+		ci.APIEnc = requestedAPIEncoding(hdr)
 	}
 
 	if ci.Service != _EMPTY_ {
@@ -1449,6 +2165,13 @@ func (s *Server) jsStreamCreateRequest(sub *subscription, c *client, _ *Account,
 		return
 	}
 
+	// Check the account's replication policy.
+	if apiErr := acc.checkStreamReplicas(cfg.Replicas); apiErr != nil {
+		resp.Error = apiErr
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
 	// Hand off to cluster for processing.
 	if s.JetStreamIsClustered() {
 		s.jsClusteredStreamRequest(ci, acc, subject, reply, rmsg, &cfg)
@@ -1461,13 +2184,29 @@ func (s *Server) jsStreamCreateRequest(sub *subscription, c *client, _ *Account,
 		return
 	}
 
-	mset, err := acc.addStreamPedantic(&cfg.StreamConfig, cfg.Pedantic)
-	if err != nil {
-		if IsNatsErr(err, JSStreamStoreFailedF) {
-			s.Warnf("Stream create failed for '%s > %s': %v", acc, streamName, err)
-			err = errStreamStoreFailed
+	if cfg.ValidateOnly {
+		if err := acc.checkAddStream(&cfg.StreamConfig, cfg.Pedantic, cfg.Exclusive); err != nil {
+			resp.Error = NewJSStreamCreateError(err, Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
 		}
-		resp.Error = NewJSStreamCreateError(err, Unless(err))
+		// Respond with the config the stream would be created with.
+		vcfg, _ := s.checkStreamCfg(&cfg.StreamConfig, acc, cfg.Pedantic)
+		resp.StreamInfo = &StreamInfo{
+			Config:    *setDynamicStreamMetadata(&vcfg),
+			TimeStamp: time.Now().UTC(),
+		}
+		s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+		return
+	}
+
+	mset, err := acc.addStreamPedantic(&cfg.StreamConfig, cfg.Pedantic, cfg.Exclusive)
+	if err != nil {
+		if IsNatsErr(err, JSStreamStoreFailedF) {
+			s.Warnf("Stream create failed for '%s > %s': %v", acc, streamName, err)
+			err = errStreamStoreFailed
+		}
+		resp.Error = NewJSStreamCreateError(err, Unless(err))
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
@@ -1549,9 +2288,16 @@ func (s *Server) jsStreamUpdateRequest(sub *subscription, c *client, _ *Account,
 		return
 	}
 
+	// Check the account's replication policy.
+	if apiErr := acc.checkStreamReplicas(cfg.Replicas); apiErr != nil {
+		resp.Error = apiErr
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
 	// Handle clustered version here.
 	if s.JetStreamIsClustered() {
-		s.jsClusteredStreamUpdateRequest(ci, acc, subject, reply, copyBytes(rmsg), &cfg, nil, ncfg.Pedantic)
+		s.jsClusteredStreamUpdateRequest(ci, acc, subject, reply, copyBytes(rmsg), &cfg, nil, ncfg.Pedantic, ncfg.ValidateOnly, ncfg.MigrateStorage)
 		return
 	}
 
@@ -1570,7 +2316,25 @@ func (s *Server) jsStreamUpdateRequest(sub *subscription, c *client, _ *Account,
 	// Update asset version metadata.
 	setStaticStreamMetadata(&cfg)
 
-	if err := mset.updatePedantic(&cfg, ncfg.Pedantic); err != nil {
+	if ncfg.ValidateOnly {
+		_, vcfg, err := mset.checkUpdate(&cfg, ncfg.Pedantic, ncfg.MigrateStorage)
+		if err != nil {
+			resp.Error = NewJSStreamUpdateError(err, Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		// Respond with the config the stream would be updated to.
+		resp.StreamInfo = &StreamInfo{
+			Created:   mset.createdTime(),
+			Config:    *setDynamicStreamMetadata(vcfg),
+			Domain:    s.getOpts().JetStreamDomain,
+			TimeStamp: time.Now().UTC(),
+		}
+		s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+		return
+	}
+
+	if err := mset.updateWithAdvisory(&cfg, true, ncfg.Pedantic, ncfg.MigrateStorage); err != nil {
 		resp.Error = NewJSStreamUpdateError(err, Unless(err))
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
@@ -1589,6 +2353,118 @@ func (s *Server) jsStreamUpdateRequest(sub *subscription, c *client, _ *Account,
 	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
 }
 
+// Request to change the replication factor of a stream, optionally only for some time.
+func (s *Server) jsStreamScaleRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	var resp = JSApiStreamUpdateResponse{ApiResponse: ApiResponse{Type: JSApiStreamUpdateResponseType}}
+
+	// Scaling only makes sense in clustered mode.
+	if !s.JetStreamIsClustered() {
+		if errorOnRequiredApiLevel(hdr) {
+			resp.Error = NewJSRequiredApiLevelError()
+		} else {
+			resp.Error = NewJSClusterRequiredError()
+		}
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+	if js.isLeaderless() {
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	// Make sure we are meta leader.
+	if !s.JetStreamIsLeader() {
+		return
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	var req JSApiStreamScaleRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.Replicas < 1 || req.Replicas > StreamMaxReplicas {
+		resp.Error = NewJSStreamReplicasNotSupportedError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.RevertAfter < 0 {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// Check the account's replication policy.
+	if apiErr := acc.checkStreamReplicas(req.Replicas); apiErr != nil {
+		resp.Error = apiErr
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	streamName := streamNameFromSubject(subject)
+	js.mu.RLock()
+	var cfg StreamConfig
+	var revert *streamScaleRevert
+	sa := js.streamAssignmentOrInflight(acc.Name, streamName)
+	if sa != nil {
+		cfg, revert = *sa.Config, sa.ScaleRevert
+	}
+	js.mu.RUnlock()
+	if sa == nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if req.RevertAfter > 0 {
+		if req.Replicas <= cfg.Replicas {
+			resp.Error = NewJSStreamUpdateError(errors.New("revert after requires increasing the number of replicas"))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		// When already elevated, keep returning to the original replication factor.
+		original := cfg.Replicas
+		if revert != nil {
+			original = revert.Replicas
+		}
+		revert = &streamScaleRevert{Replicas: original, Deadline: time.Now().Add(req.RevertAfter).UTC()}
+	} else {
+		// A permanent change drops any pending revert.
+		revert = nil
+	}
+
+	cfg.Replicas = req.Replicas
+	s.jsClusteredStreamScaleRequest(ci, acc, subject, reply, copyBytes(rmsg), &cfg, revert)
+}
+
 // Request for the list of all stream names.
 func (s *Server) jsStreamNamesRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	if c == nil || !s.JetStreamEnabled() {
@@ -2321,8 +3197,36 @@ func (s *Server) jsConsumerLeaderStepDownRequest(sub *subscription, c *client, _
 	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
 }
 
-// Request to remove a peer from a clustered stream.
-func (s *Server) jsStreamRemovePeerRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+// assetPlacement returns the servers hosting the replicas of a raft group as assigned by the
+// meta layer. This is called by the group leader, so we are the one marked as leader.
+// Lock should be held.
+func (js *jetStream) assetPlacement(rg *raftGroup) *AssetPlacement {
+	s, ourID := js.srv, js.cluster.meta.ID()
+	ap := &AssetPlacement{Cluster: rg.Cluster, Replicas: make([]*ReplicaPlacement, 0, len(rg.Peers))}
+	for _, peer := range rg.Peers {
+		rp := &ReplicaPlacement{Peer: peer, Leader: peer == ourID}
+		if si, ok := s.nodeToInfo.Load(peer); ok && si != nil {
+			ni := si.(nodeInfo)
+			rp.Name, rp.ID, rp.Offline = ni.name, ni.id, ni.offline
+		}
+		if rp.Leader {
+			ap.Leader = rp.Name
+		}
+		ap.Replicas = append(ap.Replicas, rp)
+	}
+	return ap
+}
+
+// standalonePlacement returns this server as the only host of an asset when not clustered.
+func (s *Server) standalonePlacement() *AssetPlacement {
+	return &AssetPlacement{
+		Leader:   s.Name(),
+		Replicas: []*ReplicaPlacement{{Name: s.Name(), ID: s.ID(), Peer: s.NodeName(), Leader: true}},
+	}
+}
+
+// Request to report which servers host the replicas of a stream.
+func (s *Server) jsStreamPlacementRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	if c == nil || !s.JetStreamEnabled() {
 		return
 	}
@@ -2332,19 +3236,35 @@ func (s *Server) jsStreamRemovePeerRequest(sub *subscription, c *client, _ *Acco
 		return
 	}
 
-	// Have extra token for this one.
-	name := tokenAt(subject, 6)
+	name := tokenAt(subject, 5)
 
-	var resp = JSApiStreamRemovePeerResponse{ApiResponse: ApiResponse{Type: JSApiStreamRemovePeerResponseType}}
+	var resp = JSApiStreamPlacementResponse{ApiResponse: ApiResponse{Type: JSApiStreamPlacementResponseType}}
 
-	// If we are not in clustered mode this is a failed request.
-	if !s.JetStreamIsClustered() {
-		resp.Error = NewJSClusterRequiredError()
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
-	// If we are here we are clustered. See if we are the stream leader in order to proceed.
+	// If not clustered we are the only host.
+	if !s.JetStreamIsClustered() {
+		if hasJS, doErr := acc.checkJetStream(); !hasJS {
+			if doErr {
+				resp.Error = NewJSNotEnabledForAccountError()
+				s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			}
+			return
+		}
+		if _, err := acc.lookupStream(name); err != nil {
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		resp.AssetPlacement = s.standalonePlacement()
+		s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+		return
+	}
+
 	js, cc := s.getJetStreamCluster()
 	if js == nil || cc == nil {
 		return
@@ -2359,15 +3279,12 @@ func (s *Server) jsStreamRemovePeerRequest(sub *subscription, c *client, _ *Acco
 	isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, name)
 	js.mu.RUnlock()
 
-	// Make sure we are meta leader.
-	if !isLeader {
-		return
-	}
-
-	if errorOnRequiredApiLevel(hdr) {
-		resp.Error = NewJSRequiredApiLevelError()
+	if isLeader && sa == nil {
+		resp.Error = NewJSStreamNotFoundError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
+	} else if sa == nil {
+		return
 	}
 
 	if hasJS, doErr := acc.checkJetStream(); !hasJS {
@@ -2377,117 +3294,298 @@ func (s *Server) jsStreamRemovePeerRequest(sub *subscription, c *client, _ *Acco
 		}
 		return
 	}
-	if isEmptyRequest(msg) {
-		resp.Error = NewJSBadRequestError()
-		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
-		return
-	}
 
-	var req JSApiStreamRemovePeerRequest
-	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
-		resp.Error = NewJSInvalidJSONError(err)
-		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
-		return
-	}
-	if req.Peer == _EMPTY_ {
-		resp.Error = NewJSBadRequestError()
+	// Check to see if we are a member of the group and if the group has no leader.
+	if js.isGroupLeaderless(sa.Group) {
+		resp.Error = NewJSClusterNotAvailError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
-	if sa == nil {
-		// No stream present.
-		resp.Error = NewJSStreamNotFoundError()
-		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+	// Only the stream leader should answer, as it knows the group is led by us.
+	if !acc.JetStreamIsStreamLeader(name) {
 		return
 	}
 
 	js.mu.RLock()
-	rg := sa.Group
-
-	// Check to see if we are a member of the group.
-	// Peer here is either a peer ID or a server name, convert to node name.
-	nodeName := getHash(req.Peer)
-	isMember := rg.isMember(nodeName)
-	if !isMember {
-		nodeName = req.Peer
-		isMember = rg.isMember(nodeName)
-	}
+	resp.AssetPlacement = js.assetPlacement(sa.Group)
 	js.mu.RUnlock()
-
-	// Make sure we are a member.
-	if !isMember {
-		resp.Error = NewJSClusterPeerNotMemberError()
-		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
-		return
-	}
-
-	// If we are here we have a valid peer member set for removal.
-	if !js.removePeerFromStream(sa, nodeName) {
-		resp.Error = NewJSPeerRemapError()
-		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
-		return
-	}
-
-	resp.Success = true
 	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
 }
 
-// Request to have the metaleader remove a peer from the system.
-func (s *Server) jsLeaderServerRemoveRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+// Request to report which servers host the replicas of a consumer.
+func (s *Server) jsConsumerPlacementRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	if c == nil || !s.JetStreamEnabled() {
 		return
 	}
-
 	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
 	if err != nil {
 		s.Warnf(badAPIRequestT, msg)
 		return
 	}
-	if acc != s.SystemAccount() {
-		return
-	}
-
-	js, cc := s.getJetStreamCluster()
-	if js == nil || cc == nil {
-		return
-	}
 
-	js.mu.RLock()
-	isLeader := cc.isLeader()
-	meta := cc.meta
-	js.mu.RUnlock()
+	stream := tokenAt(subject, 5)
+	consumer := tokenAt(subject, 6)
 
-	// Extra checks here but only leader is listening.
-	if !isLeader {
-		return
-	}
+	var resp = JSApiConsumerPlacementResponse{ApiResponse: ApiResponse{Type: JSApiConsumerPlacementResponseType}}
 
-	var resp = JSApiMetaServerRemoveResponse{ApiResponse: ApiResponse{Type: JSApiMetaServerRemoveResponseType}}
 	if errorOnRequiredApiLevel(hdr) {
 		resp.Error = NewJSRequiredApiLevelError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
-	if isEmptyRequest(msg) {
-		resp.Error = NewJSBadRequestError()
-		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+	// If not clustered we are the only host.
+	if !s.JetStreamIsClustered() {
+		if hasJS, doErr := acc.checkJetStream(); !hasJS {
+			if doErr {
+				resp.Error = NewJSNotEnabledForAccountError()
+				s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			}
+			return
+		}
+		mset, err := acc.lookupStream(stream)
+		if err != nil {
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if mset.lookupConsumer(consumer) == nil {
+			resp.Error = NewJSConsumerNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		resp.AssetPlacement = s.standalonePlacement()
+		s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
 		return
 	}
 
-	var req JSApiMetaServerRemoveRequest
-	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
-		resp.Error = NewJSInvalidJSONError(err)
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+	if js.isLeaderless() {
+		resp.Error = NewJSClusterNotAvailError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
-	js.mu.Lock()
-	defer js.mu.Unlock()
-
-	// Another peer-remove is already in progress, don't allow multiple concurrent changes.
-	if cc.peerRemoveReply != nil {
+	js.mu.RLock()
+	isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+	var ca *consumerAssignment
+	if sa != nil && sa.consumers != nil {
+		ca = sa.consumers[consumer]
+	}
+	js.mu.RUnlock()
+
+	if isLeader && sa == nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	} else if sa == nil {
+		return
+	}
+	if ca == nil {
+		if isLeader {
+			resp.Error = NewJSConsumerNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	// Check to see if we are a member of the group and if the group has no leader.
+	if js.isGroupLeaderless(ca.Group) {
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// Only the consumer leader should answer, as it knows the group is led by us.
+	if !acc.JetStreamIsConsumerLeader(stream, consumer) {
+		return
+	}
+
+	js.mu.RLock()
+	resp.AssetPlacement = js.assetPlacement(ca.Group)
+	js.mu.RUnlock()
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to remove a peer from a clustered stream.
+func (s *Server) jsStreamRemovePeerRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	// Have extra token for this one.
+	name := tokenAt(subject, 6)
+
+	var resp = JSApiStreamRemovePeerResponse{ApiResponse: ApiResponse{Type: JSApiStreamRemovePeerResponseType}}
+
+	// If we are not in clustered mode this is a failed request.
+	if !s.JetStreamIsClustered() {
+		resp.Error = NewJSClusterRequiredError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// If we are here we are clustered. See if we are the stream leader in order to proceed.
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+	if js.isLeaderless() {
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	js.mu.RLock()
+	isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, name)
+	js.mu.RUnlock()
+
+	// Make sure we are meta leader.
+	if !isLeader {
+		return
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiStreamRemovePeerRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.Peer == _EMPTY_ {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if sa == nil {
+		// No stream present.
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	js.mu.RLock()
+	rg := sa.Group
+
+	// Check to see if we are a member of the group.
+	// Peer here is either a peer ID or a server name, convert to node name.
+	nodeName := getHash(req.Peer)
+	isMember := rg.isMember(nodeName)
+	if !isMember {
+		nodeName = req.Peer
+		isMember = rg.isMember(nodeName)
+	}
+	js.mu.RUnlock()
+
+	// Make sure we are a member.
+	if !isMember {
+		resp.Error = NewJSClusterPeerNotMemberError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// If we are here we have a valid peer member set for removal.
+	if !js.removePeerFromStream(sa, nodeName) {
+		resp.Error = NewJSPeerRemapError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	resp.Success = true
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to have the metaleader remove a peer from the system.
+func (s *Server) jsLeaderServerRemoveRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+	if acc != s.SystemAccount() {
+		return
+	}
+
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+
+	js.mu.RLock()
+	isLeader := cc.isLeader()
+	meta := cc.meta
+	js.mu.RUnlock()
+
+	// Extra checks here but only leader is listening.
+	if !isLeader {
+		return
+	}
+
+	var resp = JSApiMetaServerRemoveResponse{ApiResponse: ApiResponse{Type: JSApiMetaServerRemoveResponseType}}
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiMetaServerRemoveRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	// Another peer-remove is already in progress, don't allow multiple concurrent changes.
+	if cc.peerRemoveReply != nil {
 		resp.Error = NewJSClusterServerMemberChangeInflightError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
@@ -2688,34 +3786,11 @@ func (s *Server) jsLeaderServerStreamMoveRequest(sub *subscription, c *client, _
 		cfg.Placement.Tags = append(cfg.Placement.Tags, req.Tags...)
 	}
 
-	peers, e := cc.selectPeerGroup(cfg.Replicas+1, currCluster, &cfg, currPeers, 1, nil)
-	if len(peers) <= cfg.Replicas {
-		// since expanding in the same cluster did not yield a result, try in different cluster
-		peers = nil
-
-		clusters := map[string]struct{}{}
-		s.nodeToInfo.Range(func(_, ni any) bool {
-			if currCluster != ni.(nodeInfo).cluster {
-				clusters[ni.(nodeInfo).cluster] = struct{}{}
-			}
-			return true
-		})
-		errs := &selectPeerError{}
-		errs.accumulate(e)
-		for cluster := range clusters {
-			newPeers, e := cc.selectPeerGroup(cfg.Replicas, cluster, &cfg, nil, 0, nil)
-			if len(newPeers) >= cfg.Replicas {
-				peers = append([]string{}, currPeers...)
-				peers = append(peers, newPeers[:cfg.Replicas]...)
-				break
-			}
-			errs.accumulate(e)
-		}
-		if peers == nil {
-			resp.Error = NewJSClusterNoPeersError(errs)
-			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
-			return
-		}
+	peers, apiErr := s.selectStreamMovePeers(cc, &cfg, currPeers, currCluster)
+	if apiErr != nil {
+		resp.Error = apiErr
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
 	}
 
 	cfg.Placement = origPlacement
@@ -2725,11 +3800,41 @@ func (s *Server) jsLeaderServerStreamMoveRequest(sub *subscription, c *client, _
 
 	// We will always have peers and therefore never do a callout, therefore it is safe to call inline
 	// We should be fine ignoring pedantic mode here. as we do not touch configuration.
-	s.jsClusteredStreamUpdateRequest(&ciNew, targetAcc.(*Account), subject, reply, rmsg, &cfg, peers, false)
+	s.jsClusteredStreamUpdateRequest(&ciNew, targetAcc.(*Account), subject, reply, rmsg, &cfg, peers, false, false, false)
 }
 
-// Request to have the metaleader move a stream on a peer to another
-func (s *Server) jsLeaderServerStreamCancelMoveRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+// selectStreamMovePeers selects the peers to move a stream to. The current peers are kept
+// in front of the new peers, such that they will be removed once the move completes.
+func (s *Server) selectStreamMovePeers(cc *jetStreamCluster, cfg *StreamConfig, currPeers []string, currCluster string) ([]string, *ApiError) {
+	peers, e := cc.selectPeerGroup(cfg.Replicas+1, currCluster, cfg, currPeers, 1, nil)
+	if len(peers) > cfg.Replicas {
+		return peers, nil
+	}
+
+	// since expanding in the same cluster did not yield a result, try in different cluster
+	clusters := map[string]struct{}{}
+	s.nodeToInfo.Range(func(_, ni any) bool {
+		if currCluster != ni.(nodeInfo).cluster {
+			clusters[ni.(nodeInfo).cluster] = struct{}{}
+		}
+		return true
+	})
+	errs := &selectPeerError{}
+	errs.accumulate(e)
+	for cluster := range clusters {
+		newPeers, e := cc.selectPeerGroup(cfg.Replicas, cluster, cfg, nil, 0, nil)
+		if len(newPeers) >= cfg.Replicas {
+			peers = append([]string{}, currPeers...)
+			peers = append(peers, newPeers[:cfg.Replicas]...)
+			return peers, nil
+		}
+		errs.accumulate(e)
+	}
+	return nil, NewJSClusterNoPeersError(errs)
+}
+
+// Request to have the metaleader move all streams and consumers off a server.
+func (s *Server) jsLeaderServerEvacuateRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	if c == nil || !s.JetStreamEnabled() {
 		return
 	}
@@ -2739,6 +3844,9 @@ func (s *Server) jsLeaderServerStreamCancelMoveRequest(sub *subscription, c *cli
 		s.Warnf(badAPIRequestT, msg)
 		return
 	}
+	if acc != s.SystemAccount() {
+		return
+	}
 
 	js, cc := s.getJetStreamCluster()
 	if js == nil || cc == nil {
@@ -2754,29 +3862,188 @@ func (s *Server) jsLeaderServerStreamCancelMoveRequest(sub *subscription, c *cli
 		return
 	}
 
-	var resp = JSApiStreamUpdateResponse{ApiResponse: ApiResponse{Type: JSApiStreamUpdateResponseType}}
+	var resp = JSApiMetaServerEvacuateResponse{ApiResponse: ApiResponse{Type: JSApiMetaServerEvacuateResponseType}}
 	if errorOnRequiredApiLevel(hdr) {
 		resp.Error = NewJSRequiredApiLevelError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
-	accName := tokenAt(subject, 6)
-	streamName := tokenAt(subject, 7)
-
-	if acc.GetName() != accName && acc != s.SystemAccount() {
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
-	targetAcc, ok := s.accounts.Load(accName)
-	if !ok {
-		resp.Error = NewJSNoAccountError()
+	var req JSApiMetaServerEvacuateRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
-	streamFound := false
-	cfg := StreamConfig{}
+	srcPeer := s.nameToPeer(js, req.Server, req.Cluster, req.Domain)
+	if srcPeer == _EMPTY_ {
+		resp.Error = NewJSClusterServerNotMemberError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	maxMoves := max(req.MaxMoves, 1)
+
+	type evacuateStream struct {
+		info    *JSApiMetaServerEvacuateStream
+		cfg     *StreamConfig
+		peers   []string
+		cluster string
+	}
+	var pending []*evacuateStream
+	var moving int
+
+	// Collect all streams and consumers that still have a replica on the server.
+	js.mu.RLock()
+	for accName, asa := range cc.streams {
+		for _, sa := range asa {
+			for _, ca := range sa.consumers {
+				if ca.Group != nil && ca.Group.isMember(srcPeer) {
+					resp.Consumers++
+				}
+			}
+			if sa.Group == nil || !sa.Group.isMember(srcPeer) {
+				continue
+			}
+			info := &JSApiMetaServerEvacuateStream{Account: accName, Stream: sa.Config.Name}
+			resp.Streams = append(resp.Streams, info)
+			// If we have more peers than replicas the stream is already being moved.
+			if len(sa.Group.Peers) > sa.Config.Replicas {
+				info.Moving = true
+				moving++
+				continue
+			}
+			pending = append(pending, &evacuateStream{info, sa.Config.clone(), slices.Clone(sa.Group.Peers), sa.Group.Cluster})
+		}
+	}
+	isMetaLeader := cc.meta.ID() == srcPeer
+	js.mu.RUnlock()
+
+	slices.SortFunc(resp.Streams, func(a, b *JSApiMetaServerEvacuateStream) int {
+		return cmp.Or(cmp.Compare(a.Account, b.Account), cmp.Compare(a.Stream, b.Stream))
+	})
+	slices.SortFunc(pending, func(a, b *evacuateStream) int {
+		return cmp.Or(cmp.Compare(a.info.Account, b.info.Account), cmp.Compare(a.info.Stream, b.info.Stream))
+	})
+
+	// Start moving streams off the server, as long as we are below the maximum number of moves.
+	for _, es := range pending {
+		if moving >= maxMoves {
+			break
+		}
+		// Only move streams for which a majority of the peers is online, otherwise the move could stall.
+		var online int
+		for _, peer := range es.peers {
+			if si, ok := s.nodeToInfo.Load(peer); ok && !si.(nodeInfo).offline {
+				online++
+			}
+		}
+		if online*2 <= len(es.peers) {
+			es.info.Error = "stream has no quorum"
+			continue
+		}
+		targetAcc, ok := s.accounts.Load(es.info.Account)
+		if !ok {
+			es.info.Error = NewJSNoAccountError().Error()
+			continue
+		}
+		// The server being evacuated should no longer be preferred as the leader.
+		if es.cfg.Placement != nil && es.cfg.Placement.Preferred == req.Server {
+			es.cfg.Placement.Preferred = _EMPTY_
+		}
+		// Make sure the server is in the first position, removal will drop peers from the left.
+		if i := slices.Index(es.peers, srcPeer); i > 0 {
+			copy(es.peers[1:], es.peers[:i])
+			es.peers[0] = srcPeer
+		}
+		peers, apiErr := s.selectStreamMovePeers(cc, es.cfg, es.peers, es.cluster)
+		if apiErr != nil {
+			es.info.Error = apiErr.Error()
+			continue
+		}
+
+		s.Noticef("Requested move for stream '%s > %s' R=%d from %+v to %+v to evacuate server %q",
+			es.info.Account, es.info.Stream, es.cfg.Replicas, s.peerSetToNames(es.peers), s.peerSetToNames(peers), req.Server)
+
+		// Make sure client is scoped to the stream's account.
+		ciNew := *(ci)
+		ciNew.Account = es.info.Account
+		// We will always have peers and therefore never do a callout, therefore it is safe to call inline.
+		// There is no reply, progress is reported by repeating the evacuate request.
+		s.jsClusteredStreamUpdateRequest(&ciNew, targetAcc.(*Account), subject, _EMPTY_, nil, es.cfg, peers, false, false, false)
+		es.info.Moving = true
+		moving++
+		resp.Started++
+	}
+
+	resp.Done = len(resp.Streams) == 0 && resp.Consumers == 0
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+
+	// Leadership of the meta layer is moved last, once there is nothing left to move.
+	if resp.Done && isMetaLeader {
+		s.Noticef("Stepping down as meta leader to evacuate server %q", req.Server)
+		js.mu.RLock()
+		meta := cc.meta
+		js.mu.RUnlock()
+		meta.StepDown()
+	}
+}
+
+// Request to have the metaleader move a stream on a peer to another
+func (s *Server) jsLeaderServerStreamCancelMoveRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+
+	// Extra checks here but only leader is listening.
+	js.mu.RLock()
+	isLeader := cc.isLeader()
+	js.mu.RUnlock()
+
+	if !isLeader {
+		return
+	}
+
+	var resp = JSApiStreamUpdateResponse{ApiResponse: ApiResponse{Type: JSApiStreamUpdateResponseType}}
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	accName := tokenAt(subject, 6)
+	streamName := tokenAt(subject, 7)
+
+	if acc.GetName() != accName && acc != s.SystemAccount() {
+		return
+	}
+
+	targetAcc, ok := s.accounts.Load(accName)
+	if !ok {
+		resp.Error = NewJSNoAccountError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	streamFound := false
+	cfg := StreamConfig{}
 	currPeers := []string{}
 	js.mu.Lock()
 	streams, ok := cc.streams[accName]
@@ -2840,7 +4107,7 @@ func (s *Server) jsLeaderServerStreamCancelMoveRequest(sub *subscription, c *cli
 		cfg.Replicas, accName, streamName, s.peerSetToNames(currPeers), s.peerSetToNames(peers))
 
 	// We will always have peers and therefore never do a callout, therefore it is safe to call inline
-	s.jsClusteredStreamUpdateRequest(&ciNew, targetAcc.(*Account), subject, reply, rmsg, &cfg, peers, false)
+	s.jsClusteredStreamUpdateRequest(&ciNew, targetAcc.(*Account), subject, reply, rmsg, &cfg, peers, false, false, false)
 }
 
 // Request to have an account purged
@@ -2875,25 +4142,8 @@ func (s *Server) jsLeaderAccountPurgeRequest(sub *subscription, c *client, _ *Ac
 	}
 
 	if !s.JetStreamIsClustered() {
-		var streams []*stream
-		var ac *Account
-		if ac, err = s.lookupAccount(accName); err == nil && ac != nil {
-			streams = ac.streams()
-		}
-
-		s.Noticef("Purge request for account %s (streams: %d, hasAccount: %t)",
-			accName, len(streams), ac != nil)
-
-		for _, mset := range streams {
-			err := mset.delete()
-			if err != nil {
-				resp.Error = NewJSStreamDeleteError(err)
-				s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
-				return
-			}
-		}
-		if err := os.RemoveAll(filepath.Join(js.config.StoreDir, accName)); err != nil {
-			resp.Error = NewJSStreamGeneralError(err)
+		if _, apiErr := s.purgeAccountStreams(js, accName); apiErr != nil {
+			resp.Error = apiErr
 			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 			return
 		}
@@ -2906,7 +4156,6 @@ func (s *Server) jsLeaderAccountPurgeRequest(sub *subscription, c *client, _ *Ac
 
 	js.mu.RLock()
 	isLeader := cc.isLeader()
-	meta := cc.meta
 	js.mu.RUnlock()
 
 	if !isLeader {
@@ -2927,7 +4176,45 @@ func (s *Server) jsLeaderAccountPurgeRequest(sub *subscription, c *client, _ *Ac
 	}
 
 	js.mu.Lock()
-	ns, nc := 0, 0
+	ns, nc := js.proposeAccountPurge(accName, subject)
+	js.mu.Unlock()
+
+	hasAccount := ns > 0
+	s.Noticef("Purge request for account %s (streams: %d, consumer: %d, hasAccount: %t)", accName, ns, nc, hasAccount)
+
+	resp.Initiated = true
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+}
+
+// purgeAccountStreams deletes all streams of the account along with its JetStream storage.
+// Only used when not clustered.
+func (s *Server) purgeAccountStreams(js *jetStream, accName string) (int, *ApiError) {
+	var streams []*stream
+	ac, err := s.lookupAccount(accName)
+	if err == nil && ac != nil {
+		streams = ac.streams()
+	}
+
+	s.Noticef("Purge request for account %s (streams: %d, hasAccount: %t)",
+		accName, len(streams), ac != nil)
+
+	for _, mset := range streams {
+		if err := mset.delete(); err != nil {
+			return 0, NewJSStreamDeleteError(err)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(js.config.StoreDir, accName)); err != nil {
+		return 0, NewJSStreamGeneralError(err)
+	}
+	return len(streams), nil
+}
+
+// proposeAccountPurge proposes the removal of all streams and consumers of the account.
+// Returns the number of streams and consumers.
+// Lock should be held, and we should be the meta leader.
+func (js *jetStream) proposeAccountPurge(accName, subject string) (ns, nc int) {
+	cc := js.cluster
+	meta := cc.meta
 	for osa := range js.streamAssignmentsOrInflightSeq(accName) {
 		for oca := range js.consumerAssignmentsOrInflightSeq(accName, osa.Config.Name) {
 			ca := &consumerAssignment{Group: oca.Group, Stream: oca.Stream, Name: oca.Name, Config: oca.Config, Subject: subject, Client: oca.Client, Created: oca.Created}
@@ -2940,13 +4227,7 @@ func (s *Server) jsLeaderAccountPurgeRequest(sub *subscription, c *client, _ *Ac
 		cc.trackInflightStreamProposal(accName, sa, true)
 		ns++
 	}
-	js.mu.Unlock()
-
-	hasAccount := ns > 0
-	s.Noticef("Purge request for account %s (streams: %d, consumer: %d, hasAccount: %t)", accName, ns, nc, hasAccount)
-
-	resp.Initiated = true
-	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+	return ns, nc
 }
 
 // Request to have the meta leader stepdown.
@@ -3203,6 +4484,104 @@ func (s *Server) jsStreamDeleteRequest(sub *subscription, c *client, _ *Account,
 	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
 }
 
+// Request to rename a stream.
+func (s *Server) jsStreamRenameRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	var resp = JSApiStreamRenameResponse{ApiResponse: ApiResponse{Type: JSApiStreamRenameResponseType}}
+
+	// Determine if we should proceed here when we are in clustered mode.
+	if s.JetStreamIsClustered() {
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		// Make sure we are meta leader.
+		if !s.JetStreamIsLeader() {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	var req JSApiStreamRenameRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	stream := streamNameFromSubject(subject)
+
+	// Clustered.
+	if s.JetStreamIsClustered() {
+		s.jsClusteredStreamRenameRequest(ci, acc, stream, req.Name, subject, reply, msg)
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	cfg := mset.config()
+	ncfg, apiErr := s.checkStreamRename(&cfg, req.Name)
+	if apiErr == nil {
+		_, _, jsa := acc.getJetStreamFromAccount()
+		jsa.mu.RLock()
+		if _, ok := jsa.streams[ncfg.Name]; ok {
+			apiErr = NewJSStreamRenameNotAllowedError(errors.New("stream name already in use"))
+		} else if jsa.subjectsOverlap(ncfg.Subjects, ncfg.AllowSubjectOverlap, mset) {
+			apiErr = NewJSStreamSubjectOverlapError()
+		} else {
+			for _, omset := range jsa.streams {
+				if streamReferences(&omset.cfg, stream) {
+					apiErr = NewJSStreamRenameNotAllowedError(fmt.Errorf("stream is referenced by stream %q", omset.cfg.Name))
+					break
+				}
+			}
+		}
+		jsa.mu.RUnlock()
+	}
+	if apiErr != nil {
+		resp.Error = apiErr
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if _, err := mset.rename(ncfg); err != nil {
+		resp.Error = NewJSStreamGeneralError(err, Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	resp.Success = true
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
 // Request to delete a message.
 // This expects a stream sequence number as the msg body.
 func (s *Server) jsMsgDeleteRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
@@ -3377,19 +4756,2590 @@ func (s *Server) jsMsgGetRequest(sub *subscription, c *client, _ *Account, subje
 			resp.Error = NewJSStreamNotFoundError()
 			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 			return
-		} else if sa == nil {
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	var req JSApiMsgGetRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// This version does not support batch.
+	if req.Batch > 0 || req.MaxBytes > 0 {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// Validate non-conflicting options. Seq, LastFor, and AsOfTime are mutually exclusive.
+	// NextFor can be paired with Seq or AsOfTime indicating a filter subject.
+	if (req.Seq > 0 && req.LastFor != _EMPTY_) ||
+		(req.Seq == 0 && req.LastFor == _EMPTY_ && req.NextFor == _EMPTY_ && req.StartTime == nil) ||
+		(req.Seq > 0 && req.StartTime != nil) ||
+		(req.StartTime != nil && req.LastFor != _EMPTY_) ||
+		(req.LastFor != _EMPTY_ && req.NextFor != _EMPTY_) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	var svp StoreMsg
+	var sm *StoreMsg
+
+	// Ensure this read request is isolated and doesn't interleave with writes.
+	mset.mu.RLock()
+	defer mset.mu.RUnlock()
+
+	// If AsOfTime is set, perform this first to get the sequence.
+	var seq uint64
+	if req.StartTime != nil {
+		seq = mset.store.GetSeqFromTime(*req.StartTime)
+	} else {
+		seq = req.Seq
+	}
+
+	if seq > 0 && req.NextFor == _EMPTY_ {
+		sm, err = mset.store.LoadMsg(seq, &svp)
+	} else if req.NextFor != _EMPTY_ {
+		sm, _, err = mset.store.LoadNextMsg(req.NextFor, subjectHasWildcard(req.NextFor), seq, &svp)
+	} else {
+		sm, err = mset.store.LoadLastMsg(req.LastFor, &svp)
+	}
+	if err != nil {
+		resp.Error = NewJSNoMessageFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	resp.Message = &StoredMsg{
+		Subject:  sm.subj,
+		Sequence: sm.seq,
+		Data:     sm.msg,
+		Time:     time.Unix(0, sm.ts).UTC(),
+	}
+	if !req.NoHeaders {
+		resp.Message.Header = sm.hdr
+	}
+
+	// Don't send response through API layer for this call.
+	hdr, payload := s.encodeAPIResponse(ci, s.jsonResponse(resp))
+	s.sendInternalAccountMsgWithReply(nil, reply, _EMPTY_, hdr, payload, false)
+}
+
+// Request to get a stream message along with its replication status.
+func (s *Server) jsMsgReplicationRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := tokenAt(subject, 6)
+
+	var resp = JSApiMsgReplicationResponse{ApiResponse: ApiResponse{Type: JSApiMsgReplicationResponseType}}
+
+	// Replication status only makes sense in clustered mode.
+	if !s.JetStreamIsClustered() {
+		if errorOnRequiredApiLevel(hdr) {
+			resp.Error = NewJSRequiredApiLevelError()
+		} else {
+			resp.Error = NewJSClusterRequiredError()
+		}
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// Check to make sure the stream is assigned.
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+	if js.isLeaderless() {
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	js.mu.RLock()
+	isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+	js.mu.RUnlock()
+
+	if isLeader && sa == nil {
+		// We can't find the stream, so mimic what would be the errors below.
+		if hasJS, doErr := acc.checkJetStream(); !hasJS {
+			if doErr {
+				resp.Error = NewJSNotEnabledForAccountError()
+				s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			}
+			return
+		}
+		// No stream present.
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	} else if sa == nil {
+		return
+	}
+
+	// Check to see if we are a member of the group and if the group has no leader.
+	if js.isGroupLeaderless(sa.Group) {
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// We have the stream assigned and a leader, so only the stream leader should answer.
+	if !acc.JetStreamIsStreamLeader(stream) {
+		return
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	var req JSApiMsgGetRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// Only a single message by sequence or last by subject is supported.
+	if (req.Seq > 0) == (req.LastFor != _EMPTY_) || req.NextFor != _EMPTY_ || req.StartTime != nil ||
+		req.Batch > 0 || req.MaxBytes > 0 || len(req.MultiLastFor) > 0 {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	var svp StoreMsg
+	var sm *StoreMsg
+
+	mset.mu.RLock()
+	if req.Seq > 0 {
+		sm, err = mset.store.LoadMsg(req.Seq, &svp)
+	} else {
+		sm, err = mset.store.LoadLastMsg(req.LastFor, &svp)
+	}
+	if err != nil {
+		mset.mu.RUnlock()
+		resp.Error = NewJSNoMessageFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	resp.Message = &StoredMsg{
+		Subject:  sm.subj,
+		Sequence: sm.seq,
+		Data:     sm.msg,
+		Time:     time.Unix(0, sm.ts).UTC(),
+	}
+	if !req.NoHeaders {
+		resp.Message.Header = sm.hdr
+	}
+	resp.Replication, err = mset.replicationStatus(mset.raftIndexForSeq(sm.seq))
+	mset.mu.RUnlock()
+
+	if err != nil {
+		// We lost leadership in the meantime, the new leader will not have seen this request.
+		resp.Message = nil
+		resp.Error = NewJSClusterNotLeaderError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to get the timestamps of the first and last messages in a stream.
+func (s *Server) jsStreamTimeRangeRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+
+	var resp = JSApiStreamTimeRangeResponse{ApiResponse: ApiResponse{Type: JSApiStreamTimeRangeResponseType}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if isLeader && sa == nil {
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if !isEmptyRequest(msg) {
+		resp.Error = NewJSNotEmptyRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	// The store tracks these from the first and last blocks, so no message needs to be loaded.
+	var state StreamState
+	mset.store.FastState(&state)
+	if state.Msgs > 0 {
+		resp.FirstTime, resp.LastTime = &state.FirstTime, &state.LastTime
+	}
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to recount the messages and bytes held by a stream and correct its totals.
+// In clustered mode the request is proposed so that every replica rebuilds its store.
+func (s *Server) jsStreamRebuildTotalsRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+
+	var resp = JSApiStreamRebuildTotalsResponse{ApiResponse: ApiResponse{Type: JSApiStreamRebuildTotalsResponseType}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if isLeader && sa == nil {
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if !isEmptyRequest(msg) {
+		resp.Error = NewJSNotEmptyRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	if s.JetStreamIsClustered() {
+		s.jsClusteredStreamRebuildTotalsRequest(ci, acc, stream, subject, reply, msg)
+		return
+	}
+
+	md, bd, err := mset.store.RebuildTotals()
+	if err != nil {
+		resp.Error = NewJSStreamGeneralError(err, Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if md != 0 || bd != 0 {
+		s.Warnf("JetStream stream '%s > %s' totals corrected by %d messages and %d bytes", acc.Name, stream, md, bd)
+	}
+	var state StreamState
+	mset.store.FastState(&state)
+	resp.Msgs, resp.Bytes, resp.MsgsDelta, resp.BytesDelta = state.Msgs, state.Bytes, md, bd
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to estimate the backlog of a consumer without creating it.
+func (s *Server) jsConsumerEstimateRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+
+	var resp = JSApiConsumerEstimateResponse{ApiResponse: ApiResponse{Type: JSApiConsumerEstimateResponseType}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if isLeader && sa == nil {
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiConsumerEstimateRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	if resp.NumPending, resp.FirstSeq, err = mset.estimateConsumer(&req.Config); err != nil {
+		resp.Error = NewJSConsumerCreateError(err, Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to get the aggregate lag of all consumers for a stream.
+func (s *Server) jsStreamConsumerLagRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+
+	var resp = JSApiStreamConsumerLagResponse{ApiResponse: ApiResponse{Type: JSApiStreamConsumerLagResponseType}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if isLeader && sa == nil {
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if !isEmptyRequest(msg) {
+		resp.Error = NewJSNotEmptyRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	// Consumers have their own leaders when clustered, so ask them for their delivered state.
+	if s.JetStreamIsClustered() {
+		s.startGoRoutine(func() { s.jsClusteredStreamConsumerLagRequest(ci, acc, mset, subject, reply, msg) })
+		return
+	}
+
+	resp.Consumers, resp.MaxLag, resp.TotalLag, resp.MaxLagConsumer = mset.consumerLag(mset.deliveredSeqs())
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to list the metadata of the next messages matching a filter.
+// This does not create a consumer or change any state, so can be used to page through a stream.
+func (s *Server) jsStreamPeekRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+
+	var resp = JSApiStreamPeekResponse{ApiResponse: ApiResponse{Type: JSApiStreamPeekResponseType}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if isLeader && sa == nil {
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	var req JSApiStreamPeekRequest
+	if !isEmptyRequest(msg) {
+		if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+			resp.Error = NewJSInvalidJSONError(err)
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+	}
+	if req.Limit < 0 || (req.Filter != _EMPTY_ && !IsValidSubject(req.Filter)) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = JSApiStreamPeekDefaultLimit
+	} else if req.Limit > JSApiStreamPeekMaxLimit {
+		req.Limit = JSApiStreamPeekMaxLimit
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	resp.Messages, resp.NextSeq = mset.peekMsgs(req.Filter, req.StartSeq, req.Limit)
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+func (s *Server) jsStreamChecksumRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+
+	var resp = JSApiStreamChecksumResponse{ApiResponse: ApiResponse{Type: JSApiStreamChecksumResponseType}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if isLeader && sa == nil {
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// A replica can be asked by name, so the replicas can be compared with each other.
+		// Otherwise we have the stream assigned and a leader, so only the stream leader should answer.
+		if peer := streamChecksumPeer(msg); peer != _EMPTY_ {
+			js.mu.RLock()
+			isMember := sa.Group != nil && sa.Group.isMember(cc.meta.ID())
+			js.mu.RUnlock()
+			if peer != s.Name() || !isMember {
+				return
+			}
+		} else if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	var req JSApiStreamChecksumRequest
+	if !isEmptyRequest(msg) {
+		if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+			resp.Error = NewJSInvalidJSONError(err)
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+	}
+	if req.Algorithm == _EMPTY_ {
+		req.Algorithm = StreamChecksumSHA256
+	}
+	hh := newStreamChecksumHash(req.Algorithm)
+	if hh == nil || (req.EndSeq > 0 && req.EndSeq < req.StartSeq) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	resp.Server, resp.Algorithm = s.Name(), req.Algorithm
+	resp.FirstSeq, resp.LastSeq, resp.Msgs = mset.checksum(req.StartSeq, req.EndSeq, hh)
+	resp.Checksum = hex.EncodeToString(hh.Sum(nil))
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// streamChecksumPeer returns the replica a stream checksum request is addressed to, if any.
+func streamChecksumPeer(msg []byte) string {
+	if isEmptyRequest(msg) {
+		return _EMPTY_
+	}
+	var req struct {
+		Peer string `json:"peer"`
+	}
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return _EMPTY_
+	}
+	return req.Peer
+}
+
+func (s *Server) jsStreamGapsRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+
+	var resp = JSApiStreamGapsResponse{ApiResponse: ApiResponse{Type: JSApiStreamGapsResponseType}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if isLeader && sa == nil {
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	var req JSApiStreamGapsRequest
+	if !isEmptyRequest(msg) {
+		if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+			resp.Error = NewJSInvalidJSONError(err)
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+	}
+	if req.Limit < 0 || (req.EndSeq > 0 && req.EndSeq < req.StartSeq) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = JSApiStreamGapsDefaultLimit
+	} else if req.Limit > JSApiStreamGapsMaxLimit {
+		req.Limit = JSApiStreamGapsMaxLimit
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	resp.Gaps, resp.NextSeq = mset.gaps(req.StartSeq, req.EndSeq, req.Limit)
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+func (s *Server) jsStreamSubjectCountsRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+
+	var resp = JSApiStreamSubjectCountsResponse{ApiResponse: ApiResponse{Type: JSApiStreamSubjectCountsResponseType}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if isLeader && sa == nil {
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	var req JSApiStreamSubjectCountsRequest
+	if !isEmptyRequest(msg) {
+		if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+			resp.Error = NewJSInvalidJSONError(err)
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+	}
+	if req.Filter == _EMPTY_ {
+		req.Filter = fwcs
+	}
+	if req.Depth < 0 || req.Offset < 0 || !IsValidSubject(req.Filter) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	// The first page collects the counts, these are only sorted and kept if paging is needed.
+	var counts map[string]uint64
+	var total uint64
+	if req.Offset == 0 {
+		counts, total = mset.subjectCounts(req.Filter, req.Depth)
+	}
+	if req.Offset == 0 && len(counts) <= JSMaxSubjectDetails {
+		resp.Counts = counts
+	} else {
+		// Page through the counts in subject order.
+		var subjs []string
+		subjs, counts, total = mset.sortedSubjectCounts(req.Filter, req.Depth, counts, total)
+		start := min(req.Offset, len(subjs))
+		end := min(start+JSMaxSubjectDetails, len(subjs))
+		resp.Counts = make(map[string]uint64, end-start)
+		for _, subj := range subjs[start:end] {
+			resp.Counts[subj] = counts[subj]
+		}
+	}
+	resp.Msgs, resp.Total, resp.Offset, resp.Limit = total, len(counts), req.Offset, JSMaxSubjectDetails
+	if resp.Counts == nil {
+		resp.Counts = map[string]uint64{}
+	}
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to reserve capacity of a stream for a bulk publish.
+func (s *Server) jsStreamReserveRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+
+	var resp = JSApiStreamReserveResponse{ApiResponse: ApiResponse{Type: JSApiStreamReserveResponseType}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if isLeader && sa == nil {
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		} else if sa == nil {
+			return
+		}
+
+		// Check to see if we are a member of the group and if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	var req JSApiStreamReserveRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.Msgs == 0 && req.Bytes == 0 {
+		resp.Error = NewJSStreamReservationInvalidError(errors.New("messages or bytes required"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.TTL < 0 || req.TTL > JSApiStreamReserveMaxTTL {
+		resp.Error = NewJSStreamReservationInvalidError(fmt.Errorf("ttl must be between 0 and %v", JSApiStreamReserveMaxTTL))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.TTL == 0 {
+		req.TTL = JSApiStreamReserveDefaultTTL
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	if s.JetStreamIsClustered() {
+		s.jsClusteredStreamReserveRequest(ci, acc, mset, stream, subject, reply, msg, &req)
+		return
+	}
+
+	id, expires := nuid.Next(), time.Now().Add(req.TTL).UTC()
+	if err = mset.reserveCapacity(id, req.Msgs, req.Bytes, expires); err != nil {
+		resp.Error = NewJSStreamReservationInvalidError(err, Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	resp.ID, resp.Expires = id, expires
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// checkKVTransactionRequest is called for a key-value transaction on the server the requester is
+// connected to, before the request is queued. It checks that the requester is allowed to publish
+// to all keys, which only this server can, and forwards the request to the stream leader if that
+// is not us. Returns true if the request should be processed here.
+func (s *Server) checkKVTransactionRequest(c *client, subject, reply string, rmsg []byte) bool {
+	// Checked and forwarded by the server the requester is connected to.
+	if c.kind == ROUTER || c.kind == GATEWAY {
+		return false
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil || isEmptyRequest(msg) {
+		return true
+	}
+	var req JSApiKVTransactionRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return true
+	}
+
+	bucket := tokenAt(subject, 5)
+	stream := "KV_" + bucket
+
+	var subjects []string
+	var sa *streamAssignment
+	js, cc := s.getJetStreamCluster()
+	if cc != nil {
+		js.mu.RLock()
+		if sa = js.streamAssignment(acc.Name, stream); sa != nil {
+			subjects = sa.Config.Subjects
+		}
+		js.mu.RUnlock()
+	} else if mset, err := acc.lookupStream(stream); err == nil {
+		mset.mu.RLock()
+		subjects = mset.cfg.Subjects
+		mset.mu.RUnlock()
+	}
+	// Anything invalid is responded to when processing the request.
+	prefix, ok := kvKeyPrefix(subjects)
+	if !ok {
+		return true
+	}
+	for _, op := range req.Ops {
+		subj := prefix + op.Key
+		if op.Key == _EMPTY_ || !IsValidLiteralSubject(subj) {
+			continue
+		}
+		if !c.pubAllowed(subj) || !c.streamPublishAllowed(acc, subj) {
+			var resp = JSPubAckResponse{PubAck: &PubAck{Stream: stream}}
+			resp.Error = NewJSKVTransactionNotPermittedError(fmt.Errorf("publish to %q not allowed", subj))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return false
+		}
+	}
+
+	if sa != nil && !acc.JetStreamIsStreamLeader(stream) && !js.isGroupLeaderless(sa.Group) {
+		fsubj := fmt.Sprintf(clusterKVTransactionT, acc.Name, bucket)
+		s.sendInternalAccountMsgWithReply(nil, fsubj, reply, copyBytes(hdr), copyBytes(msg), false)
+		return false
+	}
+	return true
+}
+
+// kvKeyPrefix returns the prefix of the keys of a key-value bucket based on the subjects
+// of its stream, normally "$KV.<bucket>.".
+func kvKeyPrefix(subjects []string) (string, bool) {
+	if len(subjects) != 1 || !strings.HasSuffix(subjects[0], ".>") {
+		return _EMPTY_, false
+	}
+	return strings.TrimSuffix(subjects[0], ">"), true
+}
+
+// Request to atomically apply a batch of operations to a key-value bucket.
+func (s *Server) jsKVTransactionRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	// Requests can be forwarded to us as the stream leader, after the permissions
+	// of the requester were checked by the server it is connected to.
+	var bucket string
+	forwarded := !strings.HasPrefix(subject, jsAPIKVTransactionPre)
+	if forwarded {
+		bucket = tokenAt(subject, 4)
+		subject = fmt.Sprintf(JSApiKVTransactionT, bucket)
+	} else {
+		bucket = tokenAt(subject, 5)
+	}
+	stream := "KV_" + bucket
+
+	var resp = JSPubAckResponse{PubAck: &PubAck{Stream: stream}}
+
+	// If we are in clustered mode we need to be the stream leader to proceed.
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		// Errors are only responded to by the server the requester is connected to.
+		if js.isLeaderless() {
+			if !forwarded {
+				resp.Error = NewJSClusterNotAvailError()
+				s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			}
+			return
+		}
+
+		js.mu.RLock()
+		sa := js.streamAssignmentOrInflight(acc.Name, stream)
+		js.mu.RUnlock()
+
+		if sa == nil {
+			if forwarded {
+				return
+			}
+			// We can't find the stream, so mimic what would be the errors below.
+			if hasJS, doErr := acc.checkJetStream(); !hasJS {
+				if doErr {
+					resp.Error = NewJSNotEnabledForAccountError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+				}
+				return
+			}
+			// No stream present.
+			resp.Error = NewJSStreamNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+
+		// Check to see if the group has no leader.
+		if js.isGroupLeaderless(sa.Group) {
+			if !forwarded {
+				resp.Error = NewJSClusterNotAvailError()
+				s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			}
+			return
+		}
+
+		// We have the stream assigned and a leader, so only the stream leader should answer.
+		if !acc.JetStreamIsStreamLeader(stream) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	var req JSApiKVTransactionRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if len(req.Ops) == 0 {
+		resp.Error = NewJSKVTransactionInvalidError(errors.New("no operations"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	// Keys live under the single subject of the bucket's stream.
+	mset.mu.RLock()
+	subjects, allowAtomic, store := mset.cfg.Subjects, mset.cfg.AllowAtomicPublish, mset.store
+	mset.mu.RUnlock()
+	prefix, ok := kvKeyPrefix(subjects)
+	if !ok {
+		resp.Error = NewJSKVTransactionInvalidError(fmt.Errorf("stream %q is not a key-value bucket", stream))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	// The operations are applied as an atomic batch, which the bucket needs to allow.
+	if !allowAtomic {
+		resp.Error = NewJSKVTransactionInvalidError(fmt.Errorf("stream %q does not allow atomic publish", stream))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// Turn all operations into messages of a single atomic batch. The batch is only
+	// committed if all revisions match, so either all operations are applied or none.
+	batchId := nuid.Next()
+	subjs, hdrs := make([]string, 0, len(req.Ops)), make([][]byte, 0, len(req.Ops))
+	var smv StoreMsg
+	for i, op := range req.Ops {
+		subj := prefix + op.Key
+		if op.Key == _EMPTY_ || !IsValidLiteralSubject(subj) {
+			resp.Error = NewJSKVTransactionInvalidError(fmt.Errorf("invalid key %q", op.Key))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		var ohdr []byte
+		switch op.Op {
+		case _EMPTY_, "put":
+		case "del":
+			ohdr = genHeader(ohdr, KVOperation, string(KVOperationValueDelete))
+		case "purge":
+			ohdr = genHeader(ohdr, KVOperation, string(KVOperationValuePurge))
+			ohdr = genHeader(ohdr, JSMsgRollup, JSMsgRollupSubject)
+		default:
+			resp.Error = NewJSKVTransactionInvalidError(fmt.Errorf("unknown operation %q", op.Op))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if rev := op.Revision; rev != nil {
+			// Creating a key that was deleted or purged needs to expect its marker.
+			expected := *rev
+			if expected == 0 {
+				if sm, err := store.LoadLastMsg(subj, &smv); err == nil {
+					if kvop := sliceHeader(KVOperation, sm.hdr); bytes.Equal(kvop, KVOperationValueDelete) || bytes.Equal(kvop, KVOperationValuePurge) {
+						expected = sm.seq
+					}
+				}
+			}
+			ohdr = genHeader(ohdr, JSExpectedLastSubjSeq, strconv.FormatUint(expected, 10))
+		}
+		ohdr = genHeader(ohdr, JSBatchId, batchId)
+		ohdr = genHeader(ohdr, JSBatchSeq, strconv.Itoa(i+1))
+		if i == len(req.Ops)-1 {
+			ohdr = genHeader(ohdr, JSBatchCommit, "1")
+		}
+		subjs, hdrs = append(subjs, subj), append(hdrs, ohdr)
+	}
+
+	// Only the commit responds, with the pub ack for the batch or why it was rejected. It responds
+	// from the stream's account, so we capture it there and pass it on as our response.
+	respCh := make(chan []byte, 1)
+	rsub, err := mset.subscribeInternal(syncSubject("$JSC.KVT"), func(_ *subscription, c *client, _ *Account, _, _ string, rmsg []byte) {
+		_, pmsg := c.msgParts(rmsg)
+		select {
+		case respCh <- copyBytes(pmsg):
+		default:
+		}
+	})
+	if err != nil {
+		resp.Error = NewJSStreamGeneralError(err, Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	for i, op := range req.Ops {
+		var rply string
+		if i == len(req.Ops)-1 {
+			rply = string(rsub.subject)
+		}
+		mset.queueInbound(mset.msgs, subjs[i], rply, hdrs[i], op.Value, nil, nil)
+	}
+
+	go func() {
+		defer mset.unsubscribe(rsub)
+		select {
+		case pubAck := <-respCh:
+			if err := json.Unmarshal(pubAck, &resp); err == nil && resp.Error != nil {
+				s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), string(pubAck))
+			} else {
+				s.sendAPIResponse(ci, acc, subject, reply, string(msg), string(pubAck))
+			}
+		case <-time.After(streamApiRequestTimeout):
+		case <-s.quitCh:
+		}
+	}()
+}
+
+func (s *Server) jsConsumerUnpinRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+	consumer := consumerNameFromSubject(subject)
+
+	var resp = JSApiConsumerUnpinResponse{ApiResponse: ApiResponse{Type: JSApiConsumerUnpinResponseType}}
+
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+
+		// First check if the stream and consumer is there.
+		js.mu.RLock()
+		sa := js.streamAssignment(acc.Name, stream)
+		if sa == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if sa.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+
+		ca, ok := sa.consumers[consumer]
+		if !ok || ca == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSConsumerNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if ca.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+		js.mu.RUnlock()
+
+		// Then check if we are the leader.
+		mset, err := acc.lookupStream(stream)
+		if err != nil {
+			return
+		}
+
+		o := mset.lookupConsumer(consumer)
+		if o == nil {
+			return
+		}
+		if !o.isLeader() {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiConsumerUnpinRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if req.Group == _EMPTY_ {
+		resp.Error = NewJSInvalidJSONError(errors.New("consumer group not specified"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if !validGroupName.MatchString(req.Group) {
+		resp.Error = NewJSConsumerInvalidGroupNameError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+	o := mset.lookupConsumer(consumer)
+	if o == nil {
+		resp.Error = NewJSConsumerNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if o.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	var foundPriority bool
+	for _, group := range o.config().PriorityGroups {
+		if group == req.Group {
+			foundPriority = true
+			break
+		}
+	}
+	if !foundPriority {
+		resp.Error = NewJSConsumerInvalidPriorityGroupError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	o.mu.Lock()
+	o.unassignPinId()
+	o.sendUnpinnedAdvisoryLocked(req.Group, "admin")
+	o.mu.Unlock()
+	o.signalNewMessages()
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// checkConsumerReplayRequest is called for a consumer replay on the server the requester is
+// connected to, before the request is queued. It checks that the requester is allowed to publish
+// to the deliver subject, which only this server can, and forwards the request to the consumer
+// leader if that is not us. Returns true if the request should be processed here.
+func (s *Server) checkConsumerReplayRequest(c *client, subject, reply string, rmsg []byte) bool {
+	// Checked and forwarded by the server the requester is connected to.
+	if c.kind == ROUTER || c.kind == GATEWAY {
+		return false
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil || isEmptyRequest(msg) {
+		return true
+	}
+	var req JSApiConsumerReplayRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return true
+	}
+	// Anything invalid is responded to when processing the request.
+	if IsValidPublishSubject(req.DeliverSubject) && !c.pubAllowed(req.DeliverSubject) {
+		var resp = JSApiConsumerReplayResponse{ApiResponse: ApiResponse{Type: JSApiConsumerReplayResponseType}}
+		resp.Error = NewJSConsumerReplayNotPermittedError(fmt.Errorf("publish to %q not allowed", req.DeliverSubject))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return false
+	}
+
+	js, cc := s.getJetStreamCluster()
+	if cc == nil {
+		return true
+	}
+	stream, consumer := streamNameFromSubject(subject), consumerNameFromSubject(subject)
+	var rg *raftGroup
+	js.mu.RLock()
+	if sa := js.streamAssignment(acc.Name, stream); sa != nil {
+		if ca := sa.consumers[consumer]; ca != nil {
+			rg = ca.Group
+		}
+	}
+	js.mu.RUnlock()
+
+	if rg != nil && !acc.JetStreamIsConsumerLeader(stream, consumer) && !js.isGroupLeaderless(rg) {
+		fsubj := fmt.Sprintf(clusterConsumerReplayT, acc.Name, stream, consumer)
+		s.sendInternalAccountMsgWithReply(nil, fsubj, reply, copyBytes(hdr), copyBytes(msg), false)
+		return false
+	}
+	return true
+}
+
+// Request to replay what a consumer would have delivered for a time window.
+func (s *Server) jsConsumerReplayRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	// Requests can be forwarded to us as the consumer leader, after the permissions
+	// of the requester were checked by the server it is connected to.
+	var stream, consumer string
+	if strings.HasPrefix(subject, jsAPIConsumerReplayPre) {
+		stream, consumer = streamNameFromSubject(subject), consumerNameFromSubject(subject)
+	} else {
+		stream, consumer = tokenAt(subject, 4), tokenAt(subject, 5)
+		subject = fmt.Sprintf(JSApiConsumerReplayT, stream, consumer)
+	}
+
+	var resp = JSApiConsumerReplayResponse{ApiResponse: ApiResponse{Type: JSApiConsumerReplayResponseType}}
+
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+
+		// First check if the stream and consumer is there.
+		js.mu.RLock()
+		sa := js.streamAssignment(acc.Name, stream)
+		if sa == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if sa.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+
+		ca, ok := sa.consumers[consumer]
+		if !ok || ca == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSConsumerNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if ca.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+		js.mu.RUnlock()
+
+		// Then check if we are the leader.
+		mset, err := acc.lookupStream(stream)
+		if err != nil {
+			return
+		}
+
+		o := mset.lookupConsumer(consumer)
+		if o == nil {
+			return
+		}
+		if !o.isLeader() {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiConsumerReplayRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+	o := mset.lookupConsumer(consumer)
+	if o == nil {
+		resp.Error = NewJSConsumerNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if o.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	// The replay is sent from its own Go routine, which responds once done.
+	err = o.replayWindow(req.StartTime, req.EndTime, req.DeliverSubject, req.MaxMsgs, req.MaxBytes, func(delivered uint64, truncated bool) {
+		resp.Delivered, resp.Truncated = delivered, truncated
+		s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+	})
+	if err != nil {
+		resp.Error = NewJSConsumerReplayInvalidWindowError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+	}
+}
+
+// Request for the delivery history of a stream message for a consumer.
+func (s *Server) jsConsumerMsgHistoryRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+	consumer := consumerNameFromSubject(subject)
+
+	var resp = JSApiConsumerMsgHistoryResponse{ApiResponse: ApiResponse{Type: JSApiConsumerMsgHistoryResponseType}}
+
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+
+		// First check if the stream and consumer is there.
+		js.mu.RLock()
+		sa := js.streamAssignment(acc.Name, stream)
+		if sa == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if sa.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+
+		ca, ok := sa.consumers[consumer]
+		if !ok || ca == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSConsumerNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if ca.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+		js.mu.RUnlock()
+
+		// Then check if we are the leader.
+		mset, err := acc.lookupStream(stream)
+		if err != nil {
+			return
+		}
+
+		o := mset.lookupConsumer(consumer)
+		if o == nil {
+			return
+		}
+		if !o.isLeader() {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiConsumerMsgHistoryRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+	o := mset.lookupConsumer(consumer)
+	if o == nil {
+		resp.Error = NewJSConsumerNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if o.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	if resp.ConsumerMsgHistory, err = o.msgHistory(req.Seq); err != nil {
+		resp.Error = NewJSConsumerMsgHistoryInvalidSeqError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to list the pull requests currently waiting on a consumer.
+func (s *Server) jsConsumerPullRequestsRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+	consumer := consumerNameFromSubject(subject)
+
+	var resp = JSApiConsumerPullRequestsResponse{ApiResponse: ApiResponse{Type: JSApiConsumerPullRequestsResponseType}}
+
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+
+		// First check if the stream and consumer is there.
+		js.mu.RLock()
+		sa := js.streamAssignment(acc.Name, stream)
+		if sa == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if sa.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+
+		ca, ok := sa.consumers[consumer]
+		if !ok || ca == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSConsumerNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if ca.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+		js.mu.RUnlock()
+
+		// Then check if we are the leader, only the leader tracks pull requests.
+		mset, err := acc.lookupStream(stream)
+		if err != nil {
+			return
+		}
+
+		o := mset.lookupConsumer(consumer)
+		if o == nil {
+			return
+		}
+		if !o.isLeader() {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if !isEmptyRequest(msg) {
+		resp.Error = NewJSNotEmptyRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+	o := mset.lookupConsumer(consumer)
+	if o == nil {
+		resp.Error = NewJSConsumerNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if o.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	resp.Requests = o.pullRequests()
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to export a consumer's configuration and optionally its state.
+func (s *Server) jsConsumerExportRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+	consumer := consumerNameFromSubject(subject)
+
+	var resp = JSApiConsumerExportResponse{ApiResponse: ApiResponse{Type: JSApiConsumerExportResponseType}}
+
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+
+		// First check if the stream and consumer is there.
+		js.mu.RLock()
+		sa := js.streamAssignment(acc.Name, stream)
+		if sa == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if sa.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+
+		ca, ok := sa.consumers[consumer]
+		if !ok || ca == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSConsumerNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if ca.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+		js.mu.RUnlock()
+
+		// Then check if we are the leader.
+		mset, err := acc.lookupStream(stream)
+		if err != nil {
+			return
+		}
+
+		o := mset.lookupConsumer(consumer)
+		if o == nil {
+			return
+		}
+		if !o.isLeader() {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	// An empty request only exports the configuration.
+	var req JSApiConsumerExportRequest
+	if !isEmptyRequest(msg) {
+		if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+			resp.Error = NewJSInvalidJSONError(err)
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+	o := mset.lookupConsumer(consumer)
+	if o == nil {
+		resp.Error = NewJSConsumerNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if o.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	resp.ConsumerExport = o.export(req.State)
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to create a consumer from an exported configuration and optionally its state.
+// The consumer must not exist yet.
+func (s *Server) jsConsumerImportRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	var resp = JSApiConsumerCreateResponse{ApiResponse: ApiResponse{Type: JSApiConsumerCreateResponseType}}
+
+	isClustered := s.JetStreamIsClustered()
+	if isClustered {
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		if js.isLeaderless() {
+			resp.Error = NewJSClusterNotAvailError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		// Make sure we are meta leader.
+		if !s.JetStreamIsLeader() {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiConsumerImportRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	streamName := streamNameFromSubject(subject)
+	consumerName := consumerNameFromSubject(subject)
+
+	// Check for path like separators in the name.
+	if strings.ContainsAny(consumerName, `\/`) {
+		resp.Error = NewJSConsumerNameContainsPathSeparatorsError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// The consumer is imported under the name in the subject.
+	if req.Config.Durable != _EMPTY_ {
+		req.Config.Durable = consumerName
+	}
+	req.Config.Name = consumerName
+
+	if req.State != nil {
+		if err := checkConsumerImportState(&req.Config, req.State); err != nil {
+			resp.Error = NewJSConsumerImportInvalidStateError(err)
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+	}
+
+	if isClustered {
+		s.jsClusteredConsumerRequest(ci, acc, subject, reply, rmsg, streamName, &req.Config, ActionCreate, false, false, req.State)
+		return
+	}
+
+	// If we are here we are single server mode.
+	if req.Config.Replicas > 1 {
+		resp.Error = NewJSStreamReplicasNotSupportedError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	mset, err := acc.lookupStream(streamName)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		resp.Error = NewJSStreamOfflineReasonError(errors.New(mset.offlineReason))
+		s.sendDelayedAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp), nil, errRespDelay)
+		return
+	}
+	if o := mset.lookupConsumer(consumerName); o != nil {
+		resp.Error = NewJSConsumerAlreadyExistsError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.State != nil {
+		var state StreamState
+		mset.store.FastState(&state)
+		if req.State.Delivered.Stream > state.LastSeq {
+			resp.Error = NewJSConsumerImportInvalidStateError(errors.New("delivered is beyond the last sequence of the stream"))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+	}
+
+	// Initialize/update asset version metadata.
+	setStaticConsumerMetadata(&req.Config)
+
+	o, err := mset.addConsumerWithAction(&req.Config, ActionCreate, false)
+	if err == nil && req.State != nil {
+		o.mu.Lock()
+		if err = o.setStoreState(req.State); err == nil {
+			// Recalculate pending, and re-trigger message delivery.
+			o.streamNumPending()
+			o.signalNewMessages()
+		}
+		o.mu.Unlock()
+		if err != nil {
+			o.delete()
+		}
+		// The initial info was taken before the state was applied.
+		o.clearInitialInfo()
+	}
+	if err != nil {
+		if IsNatsErr(err, JSConsumerStoreFailedErrF) {
+			s.Warnf("Consumer import failed for '%s > %s > %s': %v", acc, streamName, consumerName, err)
+			err = errConsumerStoreFailed
+		}
+		resp.Error = NewJSConsumerCreateError(err, Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	resp.ConsumerInfo = setDynamicConsumerInfoMetadata(o.initialInfo())
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to snapshot a consumer's delivery and ack state.
+// Handled by the consumer leader, since it has the most recent state.
+func (s *Server) jsConsumerSnapshotRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+	consumer := consumerNameFromSubject(subject)
+
+	var resp = JSApiConsumerSnapshotResponse{ApiResponse: ApiResponse{Type: JSApiConsumerSnapshotResponseType}}
+
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+
+		// First check if the stream and consumer is there.
+		js.mu.RLock()
+		sa := js.streamAssignment(acc.Name, stream)
+		if sa == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if sa.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+
+		ca, ok := sa.consumers[consumer]
+		if !ok || ca == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSConsumerNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if ca.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+		js.mu.RUnlock()
+
+		// Then check if we are the leader.
+		mset, err := acc.lookupStream(stream)
+		if err != nil {
+			return
+		}
+
+		o := mset.lookupConsumer(consumer)
+		if o == nil {
+			return
+		}
+		if !o.isLeader() {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+	o := mset.lookupConsumer(consumer)
+	if o == nil {
+		resp.Error = NewJSConsumerNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if o.offlineReason != _EMPTY_ {
+		// Just let the request time out.
+		return
+	}
+
+	resp.Stream, resp.Consumer = stream, consumer
+	resp.State = o.snapshotState()
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
+// Request to restore a consumer's delivery and ack state from a snapshot.
+// Handled by the consumer leader. When replicated, the response is sent once the
+// restored state has been committed and applied.
+func (s *Server) jsConsumerRestoreRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+	consumer := consumerNameFromSubject(subject)
+
+	var resp = JSApiConsumerRestoreResponse{ApiResponse: ApiResponse{Type: JSApiConsumerRestoreResponseType}}
+
+	if s.JetStreamIsClustered() {
+		// Check to make sure the stream is assigned.
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+
+		// First check if the stream and consumer is there.
+		js.mu.RLock()
+		sa := js.streamAssignment(acc.Name, stream)
+		if sa == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if sa.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
+			return
+		}
+
+		ca, ok := sa.consumers[consumer]
+		if !ok || ca == nil {
+			js.mu.RUnlock()
+			resp.Error = NewJSConsumerNotFoundError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		if ca.unsupported != nil {
+			js.mu.RUnlock()
+			// Just let the request time out.
 			return
 		}
+		js.mu.RUnlock()
 
-		// Check to see if we are a member of the group and if the group has no leader.
-		if js.isGroupLeaderless(sa.Group) {
-			resp.Error = NewJSClusterNotAvailError()
-			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		// Then check if we are the leader.
+		mset, err := acc.lookupStream(stream)
+		if err != nil {
 			return
 		}
 
-		// We have the stream assigned and a leader, so only the stream leader should answer.
-		if !acc.JetStreamIsStreamLeader(stream) {
+		o := mset.lookupConsumer(consumer)
+		if o == nil {
+			return
+		}
+		if !o.isLeader() {
 			return
 		}
 	}
@@ -3412,85 +7362,73 @@ func (s *Server) jsMsgGetRequest(sub *subscription, c *client, _ *Account, subje
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
-	var req JSApiMsgGetRequest
+
+	var req JSApiConsumerRestoreRequest
 	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
 		resp.Error = NewJSInvalidJSONError(err)
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
-	// This version does not support batch.
-	if req.Batch > 0 || req.MaxBytes > 0 {
-		resp.Error = NewJSBadRequestError()
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
-
-	// Validate non-conflicting options. Seq, LastFor, and AsOfTime are mutually exclusive.
-	// NextFor can be paired with Seq or AsOfTime indicating a filter subject.
-	if (req.Seq > 0 && req.LastFor != _EMPTY_) ||
-		(req.Seq == 0 && req.LastFor == _EMPTY_ && req.NextFor == _EMPTY_ && req.StartTime == nil) ||
-		(req.Seq > 0 && req.StartTime != nil) ||
-		(req.StartTime != nil && req.LastFor != _EMPTY_) ||
-		(req.LastFor != _EMPTY_ && req.NextFor != _EMPTY_) {
-		resp.Error = NewJSBadRequestError()
-		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+	if mset.offlineReason != _EMPTY_ {
+		// Just let the request time out.
 		return
 	}
-
-	mset, err := acc.lookupStream(stream)
-	if err != nil {
-		resp.Error = NewJSStreamNotFoundError()
+	o := mset.lookupConsumer(consumer)
+	if o == nil {
+		resp.Error = NewJSConsumerNotFoundError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
-	if mset.offlineReason != _EMPTY_ {
+	if o.offlineReason != _EMPTY_ {
 		// Just let the request time out.
 		return
 	}
 
-	var svp StoreMsg
-	var sm *StoreMsg
-
-	// Ensure this read request is isolated and doesn't interleave with writes.
-	mset.mu.RLock()
-	defer mset.mu.RUnlock()
-
-	// If AsOfTime is set, perform this first to get the sequence.
-	var seq uint64
-	if req.StartTime != nil {
-		seq = mset.store.GetSeqFromTime(*req.StartTime)
-	} else {
-		seq = req.Seq
+	state, err := decodeConsumerState(req.State)
+	if err == nil {
+		cfg := o.config()
+		err = checkConsumerImportState(&cfg, state)
 	}
-
-	if seq > 0 && req.NextFor == _EMPTY_ {
-		sm, err = mset.store.LoadMsg(seq, &svp)
-	} else if req.NextFor != _EMPTY_ {
-		sm, _, err = mset.store.LoadNextMsg(req.NextFor, subjectHasWildcard(req.NextFor), seq, &svp)
-	} else {
-		sm, err = mset.store.LoadLastMsg(req.LastFor, &svp)
+	if err == nil {
+		var ss StreamState
+		mset.store.FastState(&ss)
+		if state.Delivered.Stream > ss.LastSeq {
+			err = errors.New("delivered is beyond the last sequence of the stream")
+		}
 	}
 	if err != nil {
-		resp.Error = NewJSNoMessageFoundError()
+		resp.Error = NewJSConsumerRestoreFailedError(err)
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
-	resp.Message = &StoredMsg{
-		Subject:  sm.subj,
-		Sequence: sm.seq,
-		Data:     sm.msg,
-		Time:     time.Unix(0, sm.ts).UTC(),
-	}
-	if !req.NoHeaders {
-		resp.Message.Header = sm.hdr
+
+	// Older replicas would not know how to apply the replicated restore.
+	if _, node := o.streamAndNode(); node != nil && !s.peersSupportApiLevel(peerIDs(node.Peers()), 5) {
+		resp.Error = NewJSClusterPeersApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
 	}
 
-	// Don't send response through API layer for this call.
-	s.sendInternalAccountMsg(nil, reply, s.jsonResponse(resp))
+	// When replicated the response is sent once the restore has been applied.
+	if applied, err := o.restoreState(state, reply); err != nil {
+		resp.Error = NewJSConsumerRestoreFailedError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+	} else if applied {
+		resp.ConsumerInfo = setDynamicConsumerInfoMetadata(o.info())
+		s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+	}
 }
 
-func (s *Server) jsConsumerUnpinRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+// Request to move a durable consumer to another stream.
+// Handled by the consumer leader, since it knows how far the consumer got.
+func (s *Server) jsConsumerMoveRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	if c == nil || !s.JetStreamEnabled() {
 		return
 	}
@@ -3504,21 +7442,19 @@ func (s *Server) jsConsumerUnpinRequest(sub *subscription, c *client, _ *Account
 	stream := streamNameFromSubject(subject)
 	consumer := consumerNameFromSubject(subject)
 
-	var resp = JSApiConsumerUnpinResponse{ApiResponse: ApiResponse{Type: JSApiConsumerUnpinResponseType}}
+	var resp = JSApiConsumerCreateResponse{ApiResponse: ApiResponse{Type: JSApiConsumerCreateResponseType}}
 
 	if s.JetStreamIsClustered() {
-		// Check to make sure the stream is assigned.
 		js, cc := s.getJetStreamCluster()
 		if js == nil || cc == nil {
 			return
 		}
 
-		// First check if the stream and consumer is there.
 		js.mu.RLock()
 		sa := js.streamAssignment(acc.Name, stream)
 		if sa == nil {
 			js.mu.RUnlock()
-			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			resp.Error = NewJSStreamNotFoundError()
 			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 			return
 		}
@@ -3527,7 +7463,6 @@ func (s *Server) jsConsumerUnpinRequest(sub *subscription, c *client, _ *Account
 			// Just let the request time out.
 			return
 		}
-
 		ca, ok := sa.consumers[consumer]
 		if !ok || ca == nil {
 			js.mu.RUnlock()
@@ -3547,12 +7482,7 @@ func (s *Server) jsConsumerUnpinRequest(sub *subscription, c *client, _ *Account
 		if err != nil {
 			return
 		}
-
-		o := mset.lookupConsumer(consumer)
-		if o == nil {
-			return
-		}
-		if !o.isLeader() {
+		if o := mset.lookupConsumer(consumer); o == nil || !o.isLeader() {
 			return
 		}
 	}
@@ -3563,30 +7493,28 @@ func (s *Server) jsConsumerUnpinRequest(sub *subscription, c *client, _ *Account
 		return
 	}
 
-	var req JSApiConsumerUnpinRequest
-	if err := json.Unmarshal(msg, &req); err != nil {
-		resp.Error = NewJSInvalidJSONError(err)
-		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
 		return
 	}
-
-	if req.Group == _EMPTY_ {
-		resp.Error = NewJSInvalidJSONError(errors.New("consumer group not specified"))
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
-	if !validGroupName.MatchString(req.Group) {
-		resp.Error = NewJSConsumerInvalidGroupNameError()
+	var req JSApiConsumerMoveRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
-
-	if hasJS, doErr := acc.checkJetStream(); !hasJS {
-		if doErr {
-			resp.Error = NewJSNotEnabledForAccountError()
-			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
-		}
+	if !isValidAssetName(req.Stream) || req.Stream == stream {
+		resp.Error = NewJSConsumerMoveFailedError(errors.New("target stream must be a valid stream other than the current one"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
 
@@ -3610,26 +7538,59 @@ func (s *Server) jsConsumerUnpinRequest(sub *subscription, c *client, _ *Account
 		// Just let the request time out.
 		return
 	}
-
-	var foundPriority bool
-	for _, group := range o.config().PriorityGroups {
-		if group == req.Group {
-			foundPriority = true
-			break
-		}
+	if !o.isDurable() {
+		resp.Error = NewJSConsumerMoveFailedError(errors.New("only durable consumers can be moved"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
 	}
-	if !foundPriority {
-		resp.Error = NewJSConsumerInvalidPriorityGroupError()
+
+	// The creation and deletion go through the JetStream API, which we can not wait on from here.
+	cfg, err := o.moveConfig()
+	if err != nil {
+		resp.Error = NewJSConsumerMoveFailedError(err)
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
+	go s.moveConsumer(ci, acc, subject, reply, string(msg), mset, req.Stream, cfg)
+}
 
-	o.mu.Lock()
-	o.unassignPinId()
-	o.sendUnpinnedAdvisoryLocked(req.Group, "admin")
-	o.mu.Unlock()
-	o.signalNewMessages()
-	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+// moveConsumer creates the consumer on the target stream, and once that succeeded deletes it from
+// its current stream. If that delete fails, the consumer created on the target stream is deleted
+// again, so that the consumer ends up on either one of the streams.
+func (s *Server) moveConsumer(ci *ClientInfo, acc *Account, subject, reply, request string, mset *stream, target string, cfg *ConsumerConfig) {
+	var resp = JSApiConsumerCreateResponse{ApiResponse: ApiResponse{Type: JSApiConsumerCreateResponseType}}
+
+	var cresp JSApiConsumerCreateResponse
+	creq := &CreateConsumerRequest{Stream: target, Config: *cfg, Action: ActionCreate}
+	if err := mset.apiRequest(fmt.Sprintf(JSApiDurableCreateT, target, cfg.Durable), creq, &cresp); err != nil {
+		resp.Error = NewJSConsumerMoveFailedError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, request, s.jsonResponse(&resp))
+		return
+	}
+	if cresp.Error != nil {
+		resp.Error = cresp.Error
+		s.sendAPIErrResponse(ci, acc, subject, reply, request, s.jsonResponse(&resp))
+		return
+	}
+
+	var dresp JSApiConsumerDeleteResponse
+	err := mset.apiRequest(fmt.Sprintf(JSApiConsumerDeleteT, mset.name(), cfg.Durable), nil, &dresp)
+	if err == nil && dresp.Error != nil {
+		err = dresp.Error
+	}
+	if err != nil {
+		var uresp JSApiConsumerDeleteResponse
+		if uerr := mset.apiRequest(fmt.Sprintf(JSApiConsumerDeleteT, target, cfg.Durable), nil, &uresp); uerr != nil || uresp.Error != nil {
+			s.Warnf("Consumer move of '%s > %s > %s' could not delete consumer on target stream '%s'",
+				acc, mset.name(), cfg.Durable, target)
+		}
+		resp.Error = NewJSConsumerMoveFailedError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, request, s.jsonResponse(&resp))
+		return
+	}
+
+	resp.ConsumerInfo = cresp.ConsumerInfo
+	s.sendAPIResponse(ci, acc, subject, reply, request, s.jsonResponse(resp))
 }
 
 // Request to purge a stream.
@@ -3838,6 +7799,14 @@ func (s *Server) jsStreamRestoreRequest(sub *subscription, c *client, _ *Account
 		return
 	}
 
+	if req.ConsumerReset != nil {
+		if err := req.ConsumerReset.validate(); err != nil {
+			resp.Error = NewJSConsumerInvalidPolicyError(err)
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+	}
+
 	if s.JetStreamIsClustered() {
 		s.jsClusteredStreamRestoreRequest(ci, acc, &req, subject, reply, rmsg)
 		return
@@ -3871,10 +7840,10 @@ func (s *Server) jsStreamRestoreRequest(sub *subscription, c *client, _ *Account
 		return
 	}
 
-	s.processStreamRestore(ci, acc, &cfg, subject, reply, string(msg))
+	s.processStreamRestore(ci, acc, &cfg, req.ConsumerReset, subject, reply, string(msg))
 }
 
-func (s *Server) processStreamRestore(ci *ClientInfo, acc *Account, cfg *StreamConfig, subject, reply, msg string) <-chan error {
+func (s *Server) processStreamRestore(ci *ClientInfo, acc *Account, cfg *StreamConfig, creset *ConsumerRestoreReset, subject, reply, msg string) <-chan error {
 	var resp = JSApiStreamRestoreResponse{ApiResponse: ApiResponse{Type: JSApiStreamRestoreResponseType}}
 
 	streamName := cfg.Name
@@ -3929,7 +7898,7 @@ func (s *Server) processStreamRestore(ci *ClientInfo, acc *Account, cfg *StreamC
 
 	s.startGoRoutine(func() {
 		defer s.grWG.Done()
-		mset, err := acc.RestoreStream(cfg, pr)
+		mset, err := acc.restoreStream(cfg, pr, creset)
 		if err != nil {
 			pr.CloseWithError(err)
 		} else {
@@ -4251,6 +8220,152 @@ func (s *Server) jsStreamSnapshotRequest(sub *subscription, c *client, _ *Accoun
 	}()
 }
 
+// Process a request to snapshot several streams at a consistent point.
+func (s *Server) jsStreamMultiSnapshotRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, hdr, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	smsg := string(msg)
+	var resp = JSApiStreamMultiSnapshotResponse{ApiResponse: ApiResponse{Type: JSApiStreamMultiSnapshotResponseType}}
+
+	var req JSApiStreamMultiSnapshotRequest
+	var reqErr *ApiError
+	if isEmptyRequest(msg) {
+		reqErr = NewJSBadRequestError()
+	} else if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		reqErr = NewJSInvalidJSONError(err)
+	} else if len(req.Streams) == 0 {
+		reqErr = NewJSBadRequestError()
+	}
+
+	// If we are in clustered mode we need to be the leader of the first stream to proceed,
+	// the meta leader responds if the request is invalid or the stream does not exist.
+	if s.JetStreamIsClustered() {
+		js, cc := s.getJetStreamCluster()
+		if js == nil || cc == nil {
+			return
+		}
+		var first string
+		if reqErr == nil {
+			first = req.Streams[0]
+		}
+		js.mu.RLock()
+		isLeader, sa := cc.isLeader(), js.streamAssignment(acc.Name, first)
+		js.mu.RUnlock()
+		if reqErr != nil || sa == nil {
+			if !isLeader {
+				return
+			}
+		} else if !acc.JetStreamIsStreamLeader(first) {
+			return
+		}
+	}
+
+	if errorOnRequiredApiLevel(hdr) {
+		resp.Error = NewJSRequiredApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, smsg, s.jsonResponse(&resp))
+		return
+	}
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, smsg, s.jsonResponse(&resp))
+		}
+		return
+	}
+	if reqErr == nil {
+		reqErr = validateMultiSnapshotRequest(&req)
+	}
+	if reqErr != nil {
+		resp.Error = reqErr
+		s.sendAPIErrResponse(ci, acc, subject, reply, smsg, s.jsonResponse(&resp))
+		return
+	}
+
+	msets := make([]*stream, 0, len(req.Streams))
+	for _, name := range req.Streams {
+		mset, err := acc.lookupStream(name)
+		if err != nil {
+			resp.Error = NewJSStreamNotFoundError(Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, smsg, s.jsonResponse(&resp))
+			return
+		}
+		// All streams need to be led by us, so that their groups can be quiesced together.
+		if s.JetStreamIsClustered() && !acc.JetStreamIsStreamLeader(name) {
+			resp.Error = NewJSStreamMultiSnapshotLeaderError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, smsg, s.jsonResponse(&resp))
+			return
+		}
+		msets = append(msets, mset)
+	}
+
+	// Capturing the snapshots waits on the Raft groups, so do it in a go routine.
+	go func() {
+		s.Noticef("Starting snapshot of %d streams in account '%s': %s",
+			len(msets), acc.Name, strings.Join(req.Streams, ", "))
+
+		start := time.Now().UTC()
+
+		srs, err := snapshotStreams(msets, !req.NoConsumers)
+		if err != nil {
+			s.Warnf("Snapshot of %d streams in account '%s' failed: %v", len(msets), acc.Name, err)
+			resp.Error = NewJSStreamSnapshotError(err, Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, smsg, s.jsonResponse(&resp))
+			return
+		}
+
+		var bytes uint64
+		for i, mset := range msets {
+			resp.Snapshots = append(resp.Snapshots, &StreamSnapshotInfo{
+				DeliverSubject: req.DeliverPrefix + tsep + mset.name(),
+				Config:         mset.config(),
+				State:          srs[i].State,
+			})
+			bytes += srs[i].State.Bytes
+		}
+		s.sendAPIResponse(ci, acc, subject, reply, smsg, s.jsonResponse(resp))
+
+		// Now stream out all the snapshots concurrently.
+		var wg sync.WaitGroup
+		for i, mset := range msets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.streamSnapshot(acc, mset, srs[i], &JSApiStreamSnapshotRequest{
+					DeliverSubject: resp.Snapshots[i].DeliverSubject,
+					ChunkSize:      req.ChunkSize,
+					WindowSize:     req.WindowSize,
+				})
+			}()
+		}
+		wg.Wait()
+
+		s.Noticef("Completed snapshot of %s for %d streams in account '%s' in %v",
+			friendlyBytes(int64(bytes)), len(msets), acc.Name, time.Since(start))
+	}()
+}
+
+// validateMultiSnapshotRequest checks the request for a multi-stream snapshot.
+func validateMultiSnapshotRequest(req *JSApiStreamMultiSnapshotRequest) *ApiError {
+	if !IsValidLiteralSubject(req.DeliverPrefix) {
+		return NewJSSnapshotDeliverSubjectInvalidError()
+	}
+	seen := make(map[string]struct{}, len(req.Streams))
+	for _, name := range req.Streams {
+		if _, ok := seen[name]; ok || !isValidName(name) {
+			return NewJSBadRequestError()
+		}
+		seen[name] = struct{}{}
+	}
+	return nil
+}
+
 // Default chunk size for now.
 const defaultSnapshotChunkSize = 128 * 1024       // 128KiB
 const defaultSnapshotWindowSize = 8 * 1024 * 1024 // 8MiB
@@ -4569,7 +8684,7 @@ func (s *Server) jsConsumerCreateRequest(sub *subscription, c *client, a *Accoun
 	}
 
 	if isClustered && !direct {
-		s.jsClusteredConsumerRequest(ci, acc, subject, reply, rmsg, req.Stream, &req.Config, req.Action, req.Pedantic)
+		s.jsClusteredConsumerRequest(ci, acc, subject, reply, rmsg, req.Stream, &req.Config, req.Action, req.Pedantic, req.ValidateOnly, nil)
 		return
 	}
 
@@ -4631,6 +8746,23 @@ func (s *Server) jsConsumerCreateRequest(sub *subscription, c *client, a *Accoun
 	// Initialize/update asset version metadata.
 	setStaticConsumerMetadata(&req.Config)
 
+	if req.ValidateOnly {
+		if err := stream.checkAddConsumer(&req.Config, req.Action, req.Pedantic); err != nil {
+			resp.Error = NewJSConsumerCreateError(err, Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		// Respond with the config the consumer would be created or updated with.
+		resp.ConsumerInfo = &ConsumerInfo{
+			Stream:    req.Stream,
+			Name:      consumerName,
+			Config:    setDynamicConsumerMetadata(&req.Config),
+			TimeStamp: time.Now().UTC(),
+		}
+		s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+		return
+	}
+
 	o, err := stream.addConsumerWithAction(&req.Config, req.Action, req.Pedantic)
 
 	if err != nil {
@@ -0,0 +1,190 @@
+// Copyright 2026 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+const (
+	// JSApiEncodingHdr is the header a requestor sets to select the encoding
+	// of the JetStream API response. The response carries the same header
+	// when an encoding other than JSON has been used.
+	JSApiEncodingHdr = "Nats-Api-Encoding"
+
+	// JSApiEncodingJSON is the default encoding.
+	JSApiEncodingJSON = "json"
+	// JSApiEncodingMsgPack encodes the response as MessagePack.
+	JSApiEncodingMsgPack = "msgpack"
+)
+
+// requestedAPIEncoding returns the response encoding asked for in the request
+// header, or empty when the default JSON encoding should be used. It is kept
+// on the request's ClientInfo so that it travels with the request, including
+// through assignment proposals when another server sends the response.
+func requestedAPIEncoding(hdr []byte) string {
+	if enc := sliceHeader(JSApiEncodingHdr, hdr); bytes.Equal(enc, []byte(JSApiEncodingMsgPack)) {
+		return JSApiEncodingMsgPack
+	}
+	return _EMPTY_
+}
+
+// encodeAPIResponse returns the header and payload to use when responding to
+// the request. The header is nil and the JSON response is returned as is
+// unless a different encoding was requested.
+func (s *Server) encodeAPIResponse(ci *ClientInfo, response string) ([]byte, any) {
+	if ci == nil || ci.APIEnc != JSApiEncodingMsgPack {
+		return nil, response
+	}
+	b, err := jsonToMsgPack([]byte(response))
+	if err != nil {
+		s.Warnf("Problem encoding JetStream API response as MessagePack: %v", err)
+		return nil, response
+	}
+	return genHeader(nil, JSApiEncodingHdr, JSApiEncodingMsgPack), b
+}
+
+// jsonToMsgPack converts a JSON document to its MessagePack representation.
+// Objects keep their JSON field names, and map keys are sorted so that the
+// output is deterministic.
+func jsonToMsgPack(js []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return appendMsgPack(make([]byte, 0, len(js)), v)
+}
+
+func appendMsgPack(b []byte, v any) ([]byte, error) {
+	var err error
+	switch v := v.(type) {
+	case nil:
+		b = append(b, 0xc0)
+	case bool:
+		if v {
+			b = append(b, 0xc3)
+		} else {
+			b = append(b, 0xc2)
+		}
+	case json.Number:
+		if i, perr := strconv.ParseInt(string(v), 10, 64); perr == nil {
+			b = appendMsgPackInt(b, i)
+		} else if u, perr := strconv.ParseUint(string(v), 10, 64); perr == nil {
+			b = appendMsgPackUint(b, u)
+		} else if f, perr := v.Float64(); perr == nil {
+			b = append(b, 0xcb)
+			b = binary.BigEndian.AppendUint64(b, math.Float64bits(f))
+		} else {
+			return nil, perr
+		}
+	case string:
+		switch l := len(v); {
+		case l < 32:
+			b = append(b, 0xa0|byte(l))
+		case l <= math.MaxUint8:
+			b = append(b, 0xd9, byte(l))
+		case l <= math.MaxUint16:
+			b = append(b, 0xda)
+			b = binary.BigEndian.AppendUint16(b, uint16(l))
+		default:
+			b = append(b, 0xdb)
+			b = binary.BigEndian.AppendUint32(b, uint32(l))
+		}
+		b = append(b, v...)
+	case []any:
+		switch l := len(v); {
+		case l < 16:
+			b = append(b, 0x90|byte(l))
+		case l <= math.MaxUint16:
+			b = append(b, 0xdc)
+			b = binary.BigEndian.AppendUint16(b, uint16(l))
+		default:
+			b = append(b, 0xdd)
+			b = binary.BigEndian.AppendUint32(b, uint32(l))
+		}
+		for _, e := range v {
+			if b, err = appendMsgPack(b, e); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]any:
+		switch l := len(v); {
+		case l < 16:
+			b = append(b, 0x80|byte(l))
+		case l <= math.MaxUint16:
+			b = append(b, 0xde)
+			b = binary.BigEndian.AppendUint16(b, uint16(l))
+		default:
+			b = append(b, 0xdf)
+			b = binary.BigEndian.AppendUint32(b, uint32(l))
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if b, err = appendMsgPack(b, k); err != nil {
+				return nil, err
+			}
+			if b, err = appendMsgPack(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+	return b, nil
+}
+
+func appendMsgPackInt(b []byte, i int64) []byte {
+	if i >= 0 {
+		return appendMsgPackUint(b, uint64(i))
+	}
+	switch {
+	case i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+func appendMsgPackUint(b []byte, u uint64) []byte {
+	switch {
+	case u <= 0x7f:
+		return append(b, byte(u))
+	case u <= math.MaxUint8:
+		return append(b, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(u))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), u)
+	}
+}
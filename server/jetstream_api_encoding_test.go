@@ -0,0 +1,294 @@
+// Copyright 2026 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skip_js_tests
+
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Minimal MessagePack decoder for the subset produced by jsonToMsgPack.
+func decodeMsgPack(t *testing.T, b []byte) any {
+	t.Helper()
+	v, rest := decodeMsgPackValue(t, b)
+	require_Len(t, len(rest), 0)
+	return v
+}
+
+func decodeMsgPackValue(t *testing.T, b []byte) (any, []byte) {
+	t.Helper()
+	require_True(t, len(b) > 0)
+	c, b := b[0], b[1:]
+	str := func(l int) (any, []byte) { return string(b[:l]), b[l:] }
+	arr := func(l int, b []byte) (any, []byte) {
+		a := make([]any, 0, l)
+		for i := 0; i < l; i++ {
+			var e any
+			e, b = decodeMsgPackValue(t, b)
+			a = append(a, e)
+		}
+		return a, b
+	}
+	obj := func(l int, b []byte) (any, []byte) {
+		m := make(map[string]any, l)
+		for i := 0; i < l; i++ {
+			var k, v any
+			k, b = decodeMsgPackValue(t, b)
+			v, b = decodeMsgPackValue(t, b)
+			m[k.(string)] = v
+		}
+		return m, b
+	}
+	switch {
+	case c <= 0x7f:
+		return json.Number(fmt.Sprint(c)), b
+	case c >= 0xe0:
+		return json.Number(fmt.Sprint(int8(c))), b
+	case c&0xe0 == 0xa0:
+		return str(int(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return arr(int(c&0x0f), b)
+	case c&0xf0 == 0x80:
+		return obj(int(c&0x0f), b)
+	}
+	switch c {
+	case 0xc0:
+		return nil, b
+	case 0xc2:
+		return false, b
+	case 0xc3:
+		return true, b
+	case 0xcc:
+		return json.Number(fmt.Sprint(b[0])), b[1:]
+	case 0xcd:
+		return json.Number(fmt.Sprint(binary.BigEndian.Uint16(b))), b[2:]
+	case 0xce:
+		return json.Number(fmt.Sprint(binary.BigEndian.Uint32(b))), b[4:]
+	case 0xcf:
+		return json.Number(fmt.Sprint(binary.BigEndian.Uint64(b))), b[8:]
+	case 0xd0:
+		return json.Number(fmt.Sprint(int8(b[0]))), b[1:]
+	case 0xd1:
+		return json.Number(fmt.Sprint(int16(binary.BigEndian.Uint16(b)))), b[2:]
+	case 0xd2:
+		return json.Number(fmt.Sprint(int32(binary.BigEndian.Uint32(b)))), b[4:]
+	case 0xd3:
+		return json.Number(fmt.Sprint(int64(binary.BigEndian.Uint64(b)))), b[8:]
+	case 0xcb:
+		f := math.Float64frombits(binary.BigEndian.Uint64(b))
+		return json.Number(fmt.Sprint(f)), b[8:]
+	case 0xd9:
+		l := int(b[0])
+		b = b[1:]
+		return str(l)
+	case 0xda:
+		l := int(binary.BigEndian.Uint16(b))
+		b = b[2:]
+		return str(l)
+	case 0xdb:
+		l := int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		return str(l)
+	case 0xdc:
+		return arr(int(binary.BigEndian.Uint16(b)), b[2:])
+	case 0xdd:
+		return arr(int(binary.BigEndian.Uint32(b)), b[4:])
+	case 0xde:
+		return obj(int(binary.BigEndian.Uint16(b)), b[2:])
+	case 0xdf:
+		return obj(int(binary.BigEndian.Uint32(b)), b[4:])
+	}
+	t.Fatalf("Unexpected MessagePack type byte %x", c)
+	return nil, nil
+}
+
+// Decodes a MessagePack encoded API response into v by going through JSON.
+func unmarshalMsgPackResponse(t *testing.T, b []byte, v any) {
+	t.Helper()
+	js, err := json.Marshal(decodeMsgPack(t, b))
+	require_NoError(t, err)
+	require_NoError(t, json.Unmarshal(js, v))
+}
+
+func TestJetStreamApiEncodingMsgPackRoundTrip(t *testing.T) {
+	for _, test := range []string{
+		`null`,
+		`true`,
+		`{"a":false,"b":null}`,
+		`[0,1,127,128,255,256,65535,65536,4294967295,4294967296,18446744073709551615]`,
+		`[-1,-32,-33,-128,-129,-32768,-32769,-2147483648,-2147483649,-9223372036854775808]`,
+		`[1.5,-0.25,1e+300]`,
+		fmt.Sprintf(`["","%s","%s","%s"]`, strings.Repeat("a", 31), strings.Repeat("b", 255), strings.Repeat("c", 70000)),
+		`{"nested":{"list":[{"x":1},{"y":[]}],"m":{}}}`,
+	} {
+		b, err := jsonToMsgPack([]byte(test))
+		require_NoError(t, err)
+		var expected, got any
+		require_NoError(t, json.Unmarshal([]byte(test), &expected))
+		js, err := json.Marshal(decodeMsgPack(t, b))
+		require_NoError(t, err)
+		require_NoError(t, json.Unmarshal(js, &got))
+		require_Equal(t, fmt.Sprint(got), fmt.Sprint(expected))
+	}
+
+	// Larger arrays and maps use the 16 bit length forms.
+	arr := make([]int, 20)
+	m := make(map[string]int, 20)
+	for i := range arr {
+		arr[i] = i
+		m[fmt.Sprintf("k%d", i)] = i
+	}
+	js, err := json.Marshal(map[string]any{"arr": arr, "m": m})
+	require_NoError(t, err)
+	b, err := jsonToMsgPack(js)
+	require_NoError(t, err)
+	var got struct {
+		Arr []int
+		M   map[string]int
+	}
+	unmarshalMsgPackResponse(t, b, &got)
+	require_Len(t, len(got.Arr), 20)
+	require_Len(t, len(got.M), 20)
+	require_Equal(t, got.M["k19"], 19)
+
+	_, err = jsonToMsgPack([]byte(`{"bad"`))
+	require_Error(t, err)
+}
+
+func TestJetStreamApiEncodingMsgPackResponses(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = js.Publish("foo", []byte("hello"))
+		require_NoError(t, err)
+	}
+
+	request := func(subj, enc string, body []byte) *nats.Msg {
+		t.Helper()
+		m := nats.NewMsg(subj)
+		if enc != _EMPTY_ {
+			m.Header.Set(JSApiEncodingHdr, enc)
+		}
+		m.Data = body
+		resp, err := nc.RequestMsg(m, time.Second)
+		require_NoError(t, err)
+		return resp
+	}
+
+	// Default is JSON, with no header.
+	resp := request(fmt.Sprintf(JSApiStreamInfoT, "TEST"), _EMPTY_, nil)
+	require_Equal(t, resp.Header.Get(JSApiEncodingHdr), _EMPTY_)
+	var jsonInfo JSApiStreamInfoResponse
+	require_NoError(t, json.Unmarshal(resp.Data, &jsonInfo))
+	require_True(t, jsonInfo.StreamInfo != nil)
+
+	// Same request with the binary encoding.
+	resp = request(fmt.Sprintf(JSApiStreamInfoT, "TEST"), JSApiEncodingMsgPack, nil)
+	require_Equal(t, resp.Header.Get(JSApiEncodingHdr), JSApiEncodingMsgPack)
+	require_True(t, !json.Valid(resp.Data))
+	var binInfo JSApiStreamInfoResponse
+	unmarshalMsgPackResponse(t, resp.Data, &binInfo)
+	require_Equal(t, binInfo.Type, jsonInfo.Type)
+	require_True(t, binInfo.StreamInfo != nil)
+	require_Equal(t, binInfo.Config.Name, jsonInfo.Config.Name)
+	require_Equal(t, binInfo.Config.Subjects[0], jsonInfo.Config.Subjects[0])
+	require_Equal(t, binInfo.Config.Storage, jsonInfo.Config.Storage)
+	require_True(t, binInfo.Created.Equal(jsonInfo.Created))
+	require_Equal(t, binInfo.State.Msgs, 5)
+	require_Equal(t, binInfo.State.Msgs, jsonInfo.State.Msgs)
+	require_Equal(t, binInfo.State.Bytes, jsonInfo.State.Bytes)
+	require_Equal(t, binInfo.State.LastSeq, jsonInfo.State.LastSeq)
+
+	// Error responses are encoded too.
+	resp = request(fmt.Sprintf(JSApiStreamInfoT, "NOT_FOUND"), JSApiEncodingMsgPack, nil)
+	require_Equal(t, resp.Header.Get(JSApiEncodingHdr), JSApiEncodingMsgPack)
+	var errResp JSApiStreamInfoResponse
+	unmarshalMsgPackResponse(t, resp.Data, &errResp)
+	require_True(t, errResp.Error != nil)
+	require_Equal(t, errResp.Error.ErrCode, uint16(JSStreamNotFoundErr))
+
+	// Requests with a body work the same.
+	body, err := json.Marshal(&JSApiMsgGetRequest{Seq: 3})
+	require_NoError(t, err)
+	resp = request(fmt.Sprintf(JSApiMsgGetT, "TEST"), JSApiEncodingMsgPack, body)
+	var msgResp JSApiMsgGetResponse
+	unmarshalMsgPackResponse(t, resp.Data, &msgResp)
+	require_True(t, msgResp.Message != nil)
+	require_Equal(t, msgResp.Message.Sequence, 3)
+	require_Equal(t, string(msgResp.Message.Data), "hello")
+
+	// Explicit or unknown encodings fall back to JSON.
+	for _, enc := range []string{JSApiEncodingJSON, "cbor"} {
+		resp = request(fmt.Sprintf(JSApiStreamInfoT, "TEST"), enc, nil)
+		require_Equal(t, resp.Header.Get(JSApiEncodingHdr), _EMPTY_)
+		require_True(t, json.Valid(resp.Data))
+	}
+}
+
+func TestJetStreamClusterApiEncodingMsgPackResponses(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, _ := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	// Stream creation is answered by the stream leader after the assignment
+	// goes through the meta layer, which may not be the server we are on.
+	cfg, err := json.Marshal(&StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3, Storage: FileStorage})
+	require_NoError(t, err)
+	m := nats.NewMsg(fmt.Sprintf(JSApiStreamCreateT, "TEST"))
+	m.Header.Set(JSApiEncodingHdr, JSApiEncodingMsgPack)
+	m.Data = cfg
+	resp, err := nc.RequestMsg(m, 5*time.Second)
+	require_NoError(t, err)
+	require_Equal(t, resp.Header.Get(JSApiEncodingHdr), JSApiEncodingMsgPack)
+	var scResp JSApiStreamCreateResponse
+	unmarshalMsgPackResponse(t, resp.Data, &scResp)
+	require_True(t, scResp.Error == nil)
+	require_True(t, scResp.StreamInfo != nil)
+	require_Equal(t, scResp.Config.Name, "TEST")
+
+	// Reusing the same reply subject for a JSON request gets JSON back.
+	inbox := nats.NewInbox()
+	sub, err := nc.SubscribeSync(inbox)
+	require_NoError(t, err)
+	defer sub.Unsubscribe()
+	m = nats.NewMsg(fmt.Sprintf(JSApiStreamInfoT, "TEST"))
+	m.Reply = inbox
+	m.Header.Set(JSApiEncodingHdr, JSApiEncodingMsgPack)
+	require_NoError(t, nc.PublishMsg(m))
+	resp, err = sub.NextMsg(5 * time.Second)
+	require_NoError(t, err)
+	require_Equal(t, resp.Header.Get(JSApiEncodingHdr), JSApiEncodingMsgPack)
+	require_NoError(t, nc.PublishRequest(fmt.Sprintf(JSApiStreamInfoT, "TEST"), inbox, nil))
+	resp, err = sub.NextMsg(5 * time.Second)
+	require_NoError(t, err)
+	require_Equal(t, resp.Header.Get(JSApiEncodingHdr), _EMPTY_)
+	require_True(t, json.Valid(resp.Data))
+}
@@ -0,0 +1,157 @@
+// Copyright 2026 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultJetStreamBalanceThreshold is the default number of stream and consumer leaders
+	// a server can have above its fair share before it starts handing leadership over.
+	DefaultJetStreamBalanceThreshold = 1
+
+	// Upper bound on the leaderships a server hands over in a single balancing round.
+	maxLeaderBalanceStepDowns = 32
+)
+
+// monitorLeaderBalance periodically checks whether this server leads more than its fair share
+// of the replicated streams and consumers it hosts, and if so hands some of that leadership over.
+// Every server does this for itself, so over time leadership evens out across the cluster.
+func (js *jetStream) monitorLeaderBalance(interval time.Duration, threshold int) {
+	s := js.srv
+	defer s.grWG.Done()
+
+	if threshold <= 0 {
+		threshold = DefaultJetStreamBalanceThreshold
+	}
+	qch := js.clusterQuitC()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.quitCh:
+			return
+		case <-qch:
+			return
+		case <-t.C:
+			if js.isShuttingDown() || s.isLameDuckMode() {
+				return
+			}
+			js.balanceLeaders(threshold)
+		}
+	}
+}
+
+// balanceLeaders hands over leadership of replicated streams and consumers if this server
+// leads more than its fair share plus the threshold. A group that is replicated over N servers
+// adds 1/N to the fair share of each of them. New leaders are picked among the peers that lead
+// the least compared to their fair share, as far as we can tell from the groups we are part of.
+// To stay safe, we only hand over leadership of groups where all peers are current.
+// Streams with a preferred server in their placement keep their leader where it was placed.
+func (js *jetStream) balanceLeaders(threshold int) {
+	s := js.srv
+	meta := js.getMetaGroup()
+	if meta == nil {
+		return
+	}
+	ourID := meta.ID()
+
+	// Groups of streams that have a preferred leader.
+	pinned := make(map[string]struct{})
+	js.mu.RLock()
+	if cc := js.cluster; cc != nil {
+		for _, asa := range cc.streams {
+			for _, sa := range asa {
+				if sa.Group != nil && sa.Config != nil && sa.Config.Placement != nil && sa.Config.Placement.Preferred != _EMPTY_ {
+					pinned[sa.Group.Name] = struct{}{}
+				}
+			}
+		}
+	}
+	js.mu.RUnlock()
+
+	s.rnMu.RLock()
+	nodes := make([]RaftNode, 0, len(s.raftNodes))
+	for _, n := range s.raftNodes {
+		if n != meta && n.State() != Closed {
+			nodes = append(nodes, n)
+		}
+	}
+	s.rnMu.RUnlock()
+
+	// Leaders and fair share per peer, for the groups we are part of.
+	leading := make(map[string]int)
+	share := make(map[string]float64)
+	var led []RaftNode
+	for _, n := range nodes {
+		peers := n.Peers()
+		if len(peers) <= 1 {
+			continue
+		}
+		for _, p := range peers {
+			share[p.ID] += 1 / float64(len(peers))
+		}
+		if leader := n.GroupLeader(); leader != noLeader {
+			leading[leader]++
+		}
+		if n.Leader() {
+			led = append(led, n)
+		}
+	}
+
+	excess := int(math.Floor(float64(leading[ourID]) - share[ourID] - float64(threshold)))
+	if excess <= 0 {
+		return
+	}
+	excess = min(excess, maxLeaderBalanceStepDowns)
+	s.Debugf("JetStream leading %d streams and consumers, fair share is %.1f, handing over %d",
+		leading[ourID], share[ourID], excess)
+
+	for _, n := range led {
+		if excess == 0 {
+			return
+		}
+		if _, ok := pinned[n.Group()]; ok || !n.Healthy() {
+			continue
+		}
+		// Pick the peer that is furthest below its fair share, all peers must be current.
+		var preferred string
+		var lowest float64
+		for _, p := range n.Peers() {
+			if !p.Current || p.Learner || p.Slow {
+				preferred = _EMPTY_
+				break
+			}
+			if p.ID == ourID {
+				continue
+			}
+			if load := float64(leading[p.ID]) - share[p.ID]; preferred == _EMPTY_ || load < lowest {
+				preferred, lowest = p.ID, load
+			}
+		}
+		// Only hand over to a peer that would not end up above its own fair share.
+		if preferred == _EMPTY_ || lowest+1 > float64(threshold) {
+			continue
+		}
+		if err := n.StepDown(preferred); err != nil {
+			continue
+		}
+		leading[ourID]--
+		leading[preferred]++
+		excess--
+	}
+}
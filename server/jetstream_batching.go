@@ -452,6 +452,7 @@ func (diff *batchStagedDiff) commit(mset *stream) {
 				c.bytes += i.bytes
 				c.ops += i.ops
 				c.schedule = i.schedule
+				c.subjSeq = i.subjSeq
 			} else {
 				mset.inflight[subj] = i
 			}
@@ -527,12 +528,35 @@ func (batch *batchApply) rejectBatchState(mset *stream) {
 // mset.clMu lock must be held.
 func checkMsgHeadersPreClusteredProposal(
 	diff *batchStagedDiff, mset *stream, subject, rsubject string, hdr []byte, msg []byte, sourced bool, name string,
-	jsa *jsAccount, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules bool,
+	jsa *jsAccount, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules, subjectSeq bool,
 	discard DiscardPolicy, discardNewPer bool, maxMsgSize int, maxMsgs int64, maxMsgsPer int64, maxBytes int64,
 ) ([]byte, []byte, uint64, *ApiError, error) {
 	var incr *big.Int
 	var hasSchedule bool
 
+	// Messages must be signed by the stream's signing key, if one is set.
+	if !sourced {
+		mset.cfgMu.RLock()
+		signingKey := mset.cfg.SigningKey
+		mset.cfgMu.RUnlock()
+		if signingKey != _EMPTY_ && !verifyMsgSignature(signingKey, hdr, msg) {
+			apiErr := NewJSMessageSignatureInvalidError()
+			return hdr, msg, 0, apiErr, apiErr
+		}
+	}
+
+	// Strip or reject headers that are not allowed.
+	if !sourced {
+		mset.cfgMu.RLock()
+		allowedHeaders, disallowedHeaders := mset.cfg.AllowedHeaders, mset.cfg.DisallowedHeaders
+		mset.cfgMu.RUnlock()
+		var header string
+		if hdr, header = filterAllowedHeaders(hdr, allowedHeaders, disallowedHeaders); header != _EMPTY_ {
+			apiErr := NewJSStreamHeaderNotAllowedError(header)
+			return hdr, msg, 0, apiErr, apiErr
+		}
+	}
+
 	// Some header checks must be checked pre proposal.
 	if len(hdr) > 0 {
 		// Since we encode header len as u16 make sure we do not exceed.
@@ -728,6 +752,21 @@ func checkMsgHeadersPreClusteredProposal(
 		}
 	}
 
+	// Assign the next per-subject sequence.
+	// If we have inflight proposals for this subject, continue from the last one assigned.
+	var subjSeq uint64
+	if subjectSeq {
+		if i, ok := diff.inflight[subject]; ok && i.subjSeq > 0 {
+			subjSeq = i.subjSeq
+		} else if i, ok = mset.inflight[subject]; ok && i.subjSeq > 0 {
+			subjSeq = i.subjSeq
+		} else {
+			subjSeq = mset.store.LastSubjectSequence(subject)
+		}
+		subjSeq++
+		hdr = setSubjectSequence(hdr, subjSeq)
+	}
+
 	if len(hdr) > 0 {
 		// Expected last sequence.
 		if seq, exists := getExpectedLastSeq(hdr); exists && seq != mset.clseq-mset.clfs {
@@ -954,11 +993,43 @@ func checkMsgHeadersPreClusteredProposal(
 		i.bytes += sz
 		i.ops++
 		i.schedule = hasSchedule
+		i.subjSeq = subjSeq
 	} else {
-		i = &inflightSubjectRunningTotal{bytes: sz, ops: 1, schedule: hasSchedule}
+		i = &inflightSubjectRunningTotal{bytes: sz, ops: 1, schedule: hasSchedule, subjSeq: subjSeq}
 		diff.inflight[subject] = i
 	}
 
+	// Check if a new subject would exceed the max subjects, if we are to reject those.
+	// We need to deny here for the same reason as for discard new below.
+	mset.cfgMu.RLock()
+	maxSubjects, discardNewSubjects := mset.cfg.MaxSubjects, mset.cfg.DiscardNewSubjects
+	mset.cfgMu.RUnlock()
+	if discardNewSubjects && maxSubjects > 0 && i.ops == 1 {
+		isNew := func(subj string) bool {
+			return len(mset.store.SubjectsTotals(subj)) == 0
+		}
+		if _, ok = mset.inflight[subject]; !ok && isNew(subject) {
+			// Count the subjects stored, and the new subjects inflight for this stream and in this batch.
+			var state StreamState
+			mset.store.FastState(&state)
+			numSubjects := int64(state.NumSubjects)
+			for subj := range mset.inflight {
+				if isNew(subj) {
+					numSubjects++
+				}
+			}
+			for subj := range diff.inflight {
+				if _, ok = mset.inflight[subj]; !ok && subj != subject && isNew(subj) {
+					numSubjects++
+				}
+			}
+			if numSubjects >= maxSubjects {
+				err = ErrMaxSubjects
+				return hdr, msg, 0, NewJSStreamStoreFailedError(err, Unless(err)), err
+			}
+		}
+	}
+
 	// Subject transform.
 	if subject != rsubject {
 		// The 'subject' is a transformed subject used for consistency checks.
@@ -1649,7 +1649,7 @@ func TestJetStreamAtomicBatchPublishStageAndCommit(t *testing.T) {
 				if m.rsubject != _EMPTY_ {
 					rsubject = m.rsubject
 				}
-				_, _, _, _, err = checkMsgHeadersPreClusteredProposal(diff, mset, m.subject, rsubject, hdr, m.msg, false, "TEST", nil, test.allowRollup, test.denyPurge, test.allowTTL, test.allowMsgCounter, test.allowMsgSchedules, discard, discardNewPer, -1, maxMsgs, maxMsgsPer, maxBytes)
+				_, _, _, _, err = checkMsgHeadersPreClusteredProposal(diff, mset, m.subject, rsubject, hdr, m.msg, false, "TEST", nil, test.allowRollup, test.denyPurge, test.allowTTL, test.allowMsgCounter, test.allowMsgSchedules, false, discard, discardNewPer, -1, maxMsgs, maxMsgsPer, maxBytes)
 				if m.err != nil {
 					require_Error(t, err, m.err)
 				} else if err != nil {
@@ -4801,3 +4801,207 @@ func TestJetStreamFastBatchPublishLimits(t *testing.T) {
 	t.Run("R1", func(t *testing.T) { test(t, 1) })
 	t.Run("R3", func(t *testing.T) { test(t, 3) })
 }
+
+func TestJetStreamKVTransaction(t *testing.T) {
+	test := func(t *testing.T, replicas int) {
+		c := createJetStreamClusterExplicit(t, "R3S", 3)
+		defer c.shutdown()
+
+		nc, js := jsClientConnect(t, c.randomServer())
+		defer nc.Close()
+
+		cfg := &StreamConfig{
+			Name:        "KV_TEST",
+			Subjects:    []string{"$KV.TEST.>"},
+			Storage:     FileStorage,
+			Replicas:    replicas,
+			MaxMsgsPer:  5,
+			Discard:     DiscardNew,
+			AllowRollup: true,
+			DenyDelete:  true,
+			AllowDirect: true,
+		}
+		_, err := jsStreamCreate(t, nc, cfg)
+		require_NoError(t, err)
+
+		pa, err := js.Publish("$KV.TEST.a", []byte("1"))
+		require_NoError(t, err)
+		revA := pa.Sequence
+
+		txn := func(ops ...JSApiKVOperation) *JSPubAckResponse {
+			t.Helper()
+			req, err := json.Marshal(&JSApiKVTransactionRequest{Ops: ops})
+			require_NoError(t, err)
+			rmsg, err := nc.Request(fmt.Sprintf(JSApiKVTransactionT, "TEST"), req, 2*time.Second)
+			require_NoError(t, err)
+			var resp JSPubAckResponse
+			require_NoError(t, json.Unmarshal(rmsg.Data, &resp))
+			return &resp
+		}
+		value := func(key string) (string, string) {
+			t.Helper()
+			rsm, err := js.GetLastMsg("KV_TEST", "$KV.TEST."+key)
+			require_NoError(t, err)
+			return string(rsm.Data), rsm.Header.Get(KVOperation)
+		}
+		rev := func(r uint64) *uint64 { return &r }
+
+		// The bucket's stream needs to allow atomic publish.
+		resp := txn(JSApiKVOperation{Key: "a", Value: []byte("2")})
+		require_NotNil(t, resp.Error)
+		require_Equal(t, ErrorIdentifier(resp.Error.ErrCode), JSKVTransactionInvalidErrF)
+		cfg.AllowAtomicPublish = true
+		_, err = jsStreamUpdate(t, nc, cfg)
+		require_NoError(t, err)
+
+		// Operations are validated up front.
+		resp = txn(JSApiKVOperation{Op: "swap", Key: "a"})
+		require_NotNil(t, resp.Error)
+		require_Equal(t, ErrorIdentifier(resp.Error.ErrCode), JSKVTransactionInvalidErrF)
+		resp = txn(JSApiKVOperation{Key: "a.*", Value: []byte("2")})
+		require_NotNil(t, resp.Error)
+		require_Equal(t, ErrorIdentifier(resp.Error.ErrCode), JSKVTransactionInvalidErrF)
+
+		// All revisions match, so all operations are applied.
+		resp = txn(
+			JSApiKVOperation{Key: "a", Value: []byte("2"), Revision: rev(revA)},
+			JSApiKVOperation{Key: "b", Value: []byte("1"), Revision: rev(0)},
+			JSApiKVOperation{Op: "del", Key: "c"},
+		)
+		require_True(t, resp.Error == nil)
+		require_Equal(t, resp.BatchSize, 3)
+		require_Equal(t, resp.Sequence, 4)
+		data, op := value("a")
+		require_Equal(t, data, "2")
+		require_Equal(t, op, _EMPTY_)
+		data, _ = value("b")
+		require_Equal(t, data, "1")
+		_, op = value("c")
+		require_Equal(t, op, string(KVOperationValueDelete))
+
+		// One revision doesn't match, so none of the operations are applied.
+		resp = txn(
+			JSApiKVOperation{Key: "a", Value: []byte("3"), Revision: rev(2)},
+			JSApiKVOperation{Key: "b", Value: []byte("2"), Revision: rev(0)},
+		)
+		require_NotNil(t, resp.Error)
+		require_Equal(t, ErrorIdentifier(resp.Error.ErrCode), JSStreamWrongLastSequenceErrF)
+		data, _ = value("a")
+		require_Equal(t, data, "2")
+		data, _ = value("b")
+		require_Equal(t, data, "1")
+		si, err := js.StreamInfo("KV_TEST")
+		require_NoError(t, err)
+		require_Equal(t, si.State.LastSeq, 4)
+
+		// Purging a key rolls up its history.
+		resp = txn(JSApiKVOperation{Op: "purge", Key: "a"}, JSApiKVOperation{Key: "b", Value: []byte("2"), Revision: rev(3)})
+		require_True(t, resp.Error == nil)
+		_, op = value("a")
+		require_Equal(t, op, string(KVOperationValuePurge))
+		data, _ = value("b")
+		require_Equal(t, data, "2")
+		si, err = js.StreamInfo("KV_TEST", &nats.StreamInfoRequest{SubjectsFilter: "$KV.TEST.a"})
+		require_NoError(t, err)
+		require_Equal(t, si.State.Subjects["$KV.TEST.a"], 1)
+
+		// Keys that were deleted or purged can be created again.
+		resp = txn(
+			JSApiKVOperation{Key: "a", Value: []byte("3"), Revision: rev(0)},
+			JSApiKVOperation{Key: "c", Value: []byte("1"), Revision: rev(0)},
+		)
+		require_True(t, resp.Error == nil)
+		data, op = value("a")
+		require_Equal(t, data, "3")
+		require_Equal(t, op, _EMPTY_)
+		data, _ = value("c")
+		require_Equal(t, data, "1")
+
+		// But not if they exist.
+		resp = txn(JSApiKVOperation{Key: "c", Value: []byte("2"), Revision: rev(0)})
+		require_NotNil(t, resp.Error)
+		require_Equal(t, ErrorIdentifier(resp.Error.ErrCode), JSStreamWrongLastSequenceErrF)
+	}
+
+	for _, replicas := range []int{1, 3} {
+		t.Run(fmt.Sprintf("R%d", replicas), func(t *testing.T) { test(t, replicas) })
+	}
+}
+
+func TestJetStreamKVTransactionPermissions(t *testing.T) {
+	conf := `
+	listen: 127.0.0.1:-1
+	server_name: %s
+	jetstream: {
+		store_dir: '%s',
+	}
+	cluster {
+		name: %s
+		listen: 127.0.0.1:%d
+		routes = [%s]
+	}
+	system_account: sys
+	no_auth_user: js
+	accounts {
+	  sys {
+	    users = [
+	      { user: sys, pass: sys }
+	    ]
+	  }
+	  js {
+	    jetstream = enabled
+	    users = [
+	      { user: js, pass: js }
+	      { user: limited, pass: limited, permissions: { publish: { deny: "$KV.TEST.secret" } } }
+	    ]
+	  }
+	}`
+	c := createJetStreamClusterWithTemplate(t, conf, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:               "KV_TEST",
+		Subjects:           []string{"$KV.TEST.>"},
+		Storage:            FileStorage,
+		Replicas:           3,
+		AllowRollup:        true,
+		AllowDirect:        true,
+		AllowAtomicPublish: true,
+	})
+	require_NoError(t, err)
+	c.waitOnStreamLeader("js", "KV_TEST")
+
+	// Permissions are checked by the server the requester is connected to,
+	// so check them on the stream leader as well as on the followers.
+	for _, s := range c.servers {
+		lnc, _ := jsClientConnect(t, s, nats.UserInfo("limited", "limited"))
+		defer lnc.Close()
+
+		txn := func(ops ...JSApiKVOperation) *JSPubAckResponse {
+			t.Helper()
+			req, err := json.Marshal(&JSApiKVTransactionRequest{Ops: ops})
+			require_NoError(t, err)
+			rmsg, err := lnc.Request(fmt.Sprintf(JSApiKVTransactionT, "TEST"), req, 2*time.Second)
+			require_NoError(t, err)
+			var resp JSPubAckResponse
+			require_NoError(t, json.Unmarshal(rmsg.Data, &resp))
+			return &resp
+		}
+
+		resp := txn(JSApiKVOperation{Key: "a", Value: []byte("1")}, JSApiKVOperation{Key: "secret", Value: []byte("1")})
+		require_NotNil(t, resp.Error)
+		require_Equal(t, ErrorIdentifier(resp.Error.ErrCode), JSKVTransactionNotPermittedErrF)
+
+		resp = txn(JSApiKVOperation{Key: "a", Value: []byte("1")})
+		require_True(t, resp.Error == nil)
+	}
+
+	_, err = js.GetLastMsg("KV_TEST", "$KV.TEST.secret")
+	require_Error(t, err, nats.ErrMsgNotFound)
+	si, err := js.StreamInfo("KV_TEST")
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 3)
+}
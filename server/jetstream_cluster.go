@@ -77,6 +77,10 @@ type jetStreamCluster struct {
 	peerStreamMove *subscription
 	// System level request to cancel a stream move
 	peerStreamCancelMove *subscription
+	// System level request to move all streams and consumers off a server
+	peerEvacuate *subscription
+	// System level request to confirm raft groups are orphaned before removing them.
+	orphanGroups *subscription
 	// To pop out the monitorCluster before the raft layer.
 	qch chan struct{}
 	// To notify others that monitorCluster has actually stopped.
@@ -84,6 +88,9 @@ type jetStreamCluster struct {
 	// Track last meta snapshot time and duration for monitoring.
 	lastMetaSnapTime     int64 // Unix nanoseconds
 	lastMetaSnapDuration int64 // Duration in nanoseconds
+	// Timer to revert temporarily scaled streams, and when it's armed to fire.
+	srtmr  *time.Timer
+	srnext time.Time
 }
 
 // Used to track inflight stream create/update/delete requests that have been proposed but not yet applied.
@@ -115,6 +122,19 @@ type Placement struct {
 	Preferred string   `json:"preferred,omitempty"`
 }
 
+// isPlacementMove reports whether going from the old to the new placement
+// requires the stream to be moved. A change of the preferred server alone
+// only transfers leadership within the current peer set.
+func isPlacementMove(o, n *Placement) bool {
+	if n == nil {
+		return false
+	}
+	if o == nil {
+		return n.Cluster != _EMPTY_ || len(n.Tags) > 0
+	}
+	return o.Cluster != n.Cluster || !slices.Equal(o.Tags, n.Tags)
+}
+
 // Define types of the entry.
 type entryOp uint8
 
@@ -150,6 +170,15 @@ const (
 	batchCommitMsgOp
 	// Consumer rest to specific starting sequence.
 	resetSeqOp
+	// Rename Stream.
+	renameStreamOp
+	// Consumer state restore from a snapshot.
+	restoreStateOp
+	// Recount stream totals.
+	rebuildTotalsOp
+	// Stream capacity reservations.
+	reserveCapacityOp
+	releaseReservationOp
 )
 
 // raftGroups are controlled by the metagroup controller.
@@ -162,7 +191,8 @@ type raftGroup struct {
 	Preferred string      `json:"preferred,omitempty"`
 	ScaleUp   bool        `json:"scale_up,omitempty"`
 	// Internal
-	node RaftNode
+	node     RaftNode
+	renaming chan struct{} // Closed once the stream's store was renamed, or the rename was reverted.
 }
 
 // streamAssignment is what the meta controller uses to assign streams to peers.
@@ -176,6 +206,13 @@ type streamAssignment struct {
 	Subject    string          `json:"subject,omitempty"`
 	Reply      string          `json:"reply,omitempty"`
 	Restore    *StreamState    `json:"restore_state,omitempty"`
+
+	// Recreate consumers from their configuration only when restoring.
+	ConsumerReset *ConsumerRestoreReset `json:"restore_consumer_reset,omitempty"`
+
+	// Set when the replication factor was temporarily elevated.
+	ScaleRevert *streamScaleRevert `json:"scale_revert,omitempty"`
+
 	// Internal
 	consumers   map[string]*consumerAssignment
 	responded   atomic.Bool // copied via clone() to satisfy go vet's noCopy check
@@ -184,6 +221,14 @@ type streamAssignment struct {
 	resetting   bool // i.e. there was an error, and we're stopping and starting the stream
 	err         error
 	unsupported *unsupportedStreamAssignment
+	rename      *pendingStreamRename
+}
+
+// streamScaleRevert records the replication factor a temporarily scaled
+// stream will be returned to by the meta leader once the deadline passes.
+type streamScaleRevert struct {
+	Replicas int       `json:"replicas"`
+	Deadline time.Time `json:"deadline"`
 }
 
 func (sa *streamAssignment) hasResponded() bool {
@@ -221,10 +266,29 @@ func (sa *streamAssignment) clone() *streamAssignment {
 		err:         sa.err,
 		unsupported: sa.unsupported,
 	}
+	csa.ConsumerReset = sa.ConsumerReset
+	csa.ScaleRevert = sa.ScaleRevert
 	csa.responded.Store(sa.responded.Load())
 	return csa
 }
 
+// renamed returns a copy of the stream assignment for the renamed stream config cfg,
+// with its consumer assignments pointing to the new stream name.
+func (sa *streamAssignment) renamed(cfg *StreamConfig) *streamAssignment {
+	nsa := sa.clone()
+	nsa.Config = cfg
+	nsa.ConfigJSON, _ = json.Marshal(cfg)
+	if len(sa.consumers) > 0 {
+		nsa.consumers = make(map[string]*consumerAssignment, len(sa.consumers))
+		for name, ca := range sa.consumers {
+			nca := ca.clone()
+			nca.Stream = cfg.Name
+			nsa.consumers[name] = nca
+		}
+	}
+	return nsa
+}
+
 type unsupportedStreamAssignment struct {
 	reason  string
 	info    StreamInfo
@@ -432,6 +496,31 @@ type writeableConsumerAssignment struct {
 	Group      *raftGroup      `json:"group"`
 }
 
+// streamRename is what the meta leader will replicate when renaming a stream.
+type streamRename struct {
+	Client  *ClientInfo   `json:"client,omitempty"`
+	Stream  string        `json:"stream"`
+	Config  *StreamConfig `json:"config"`
+	Subject string        `json:"subject"`
+	Reply   string        `json:"reply"`
+	// Set when reverting a rename that failed on one of the stream's peers.
+	Error *ApiError `json:"error,omitempty"`
+}
+
+// pendingStreamRename is tracked by the meta leader until a quorum of the stream's
+// peers have renamed their store, one of them failed to, or it timed out.
+type pendingStreamRename struct {
+	sr     *streamRename
+	ocfg   *StreamConfig
+	peers  map[string]struct{} // Peers that did not report back yet.
+	acks   int
+	quorum int
+	tmr    *time.Timer
+}
+
+// How long the meta leader waits for a quorum of the stream's peers to rename their store.
+const streamRenameTimeout = 30 * time.Second
+
 // streamPurge is what the stream leader will replicate when purging a stream.
 type streamPurge struct {
 	Client  *ClientInfo              `json:"client,omitempty"`
@@ -452,6 +541,27 @@ type streamMsgDelete struct {
 	Reply   string      `json:"reply"`
 }
 
+// streamReservation is what the stream leader will replicate when reserving capacity,
+// or when releasing it once expired.
+type streamReservation struct {
+	Client  *ClientInfo `json:"client,omitempty"`
+	Stream  string      `json:"stream"`
+	ID      string      `json:"id"`
+	Msgs    uint64      `json:"msgs,omitempty"`
+	Bytes   uint64      `json:"bytes,omitempty"`
+	Expires time.Time   `json:"expires,omitzero"`
+	Subject string      `json:"subject,omitempty"`
+	Reply   string      `json:"reply,omitempty"`
+}
+
+// streamRebuildTotals is what the stream leader will replicate when recounting stream totals.
+type streamRebuildTotals struct {
+	Client  *ClientInfo `json:"client,omitempty"`
+	Stream  string      `json:"stream"`
+	Subject string      `json:"subject"`
+	Reply   string      `json:"reply"`
+}
+
 const (
 	defaultStoreDirName  = "_js_"
 	defaultMetaGroupName = "_meta_"
@@ -459,6 +569,35 @@ const (
 	jsExcludePlacement   = "!jetstream"
 )
 
+// peersSupportApiLevel returns whether all the given peers are known to support
+// the API level. This needs to be checked before proposing new entry types, since
+// older servers will not know how to apply them.
+func (s *Server) peersSupportApiLevel(peers []string, level int) bool {
+	ourID := s.NodeName()
+	for _, peer := range peers {
+		if peer == ourID {
+			continue
+		}
+		v, ok := s.nodeToInfo.Load(peer)
+		if !ok {
+			return false
+		}
+		if ni := v.(nodeInfo); ni.stats == nil || ni.stats.API.Level < level {
+			return false
+		}
+	}
+	return true
+}
+
+// peerIDs returns the IDs of the given raft peers.
+func peerIDs(peers []*Peer) []string {
+	ids := make([]string, 0, len(peers))
+	for _, p := range peers {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
 // Returns information useful in mixed mode.
 func (s *Server) trackedJetStreamServers() (js, total int) {
 	s.mu.RLock()
@@ -866,14 +1005,18 @@ func (js *jetStream) isConsumerHealthy(mset *stream, consumer string, ca *consum
 }
 
 // subjectsOverlap checks all existing stream assignments for the account cross-cluster for subject overlap
+// If allowOverlap is set, streams that allow overlap as well are skipped.
 // Use only for clustered JetStream
 // Read lock should be held.
-func (js *jetStream) subjectsOverlap(acc string, subjects []string, osa *streamAssignment) bool {
+func (js *jetStream) subjectsOverlap(acc string, subjects []string, allowOverlap bool, osa *streamAssignment) bool {
 	for sa := range js.streamAssignmentsOrInflightSeq(acc) {
 		// can't overlap yourself, assume osa pre-checked for deep equal if passed
 		if osa != nil && sa.Config.Name == osa.Config.Name {
 			continue
 		}
+		if allowOverlap && sa.Config.AllowSubjectOverlap {
+			continue
+		}
 		for _, subj := range sa.Config.Subjects {
 			for _, tsubj := range subjects {
 				if SubjectsCollide(tsubj, subj) {
@@ -1024,6 +1167,9 @@ func (js *jetStream) setupMetaGroup() error {
 	}
 
 	cfg := &RaftConfig{Name: defaultMetaGroupName, Store: storeDir, Log: fs, Recovering: true}
+	jsr := &s.getOpts().JetStreamRaft
+	cfg.MaxAppendEntryDecodeFailures = jsr.MaxAppendEntryDecodeFailures
+	cfg.ApplyErrorRetries, cfg.ApplyErrorBackoff, cfg.ApplyErrorPolicy = jsr.ApplyErrorRetries, jsr.ApplyErrorBackoff, jsr.ApplyErrorPolicy
 
 	// If we are soliciting leafnode connections and we are sharing a system account and do not disable it with a hint,
 	// we want to move to observer mode so that we extend the solicited cluster or supercluster but do not form our own.
@@ -1109,6 +1255,15 @@ func (js *jetStream) setupMetaGroup() error {
 			"account": sysAcc.Name,
 		},
 	)
+	// Automatically balance stream and consumer leadership if configured.
+	if opts := s.getOpts(); opts.JetStreamBalanceInterval > 0 {
+		interval, threshold := opts.JetStreamBalanceInterval, opts.JetStreamBalanceThreshold
+		js.srv.startGoRoutine(func() { js.monitorLeaderBalance(interval, threshold) })
+	}
+	// Automatically cleanup Raft groups the meta layer no longer knows about if configured.
+	if interval := s.getOpts().JetStreamOrphanInterval; interval > 0 {
+		js.srv.startGoRoutine(func() { js.monitorOrphanGroups(interval) })
+	}
 	return nil
 }
 
@@ -1471,6 +1626,32 @@ func (ru *recoveryUpdates) updateStream(sa *streamAssignment) {
 	ru.updateStreams[key] = sa
 }
 
+// renameStream moves any staged stream and consumer operations over to the new stream name.
+// Stream removals are left alone, they belong to a prior incarnation of the old stream.
+func (ru *recoveryUpdates) renameStream(sr *streamRename) {
+	accName := sr.Client.serviceAccount()
+	okey, nkey := accName+ksep+sr.Stream, accName+ksep+sr.Config.Name
+	for _, streams := range []map[string]*streamAssignment{ru.addStreams, ru.updateStreams} {
+		if sa, ok := streams[okey]; ok {
+			delete(streams, okey)
+			sa.Config = sr.Config
+			sa.ConfigJSON, _ = json.Marshal(sr.Config)
+			streams[nkey] = sa
+		}
+	}
+	for _, consumers := range []map[string]map[string]*consumerAssignment{ru.updateConsumers, ru.removeConsumers} {
+		if cas, ok := consumers[okey]; ok {
+			delete(consumers, okey)
+			ncas := make(map[string]*consumerAssignment, len(cas))
+			for _, ca := range cas {
+				ca.Stream = sr.Config.Name
+				ncas[ca.recoveryKey()] = ca
+			}
+			consumers[nkey] = ncas
+		}
+	}
+}
+
 func (ru *recoveryUpdates) removeConsumer(ca *consumerAssignment) {
 	key := ca.recoveryKey()
 	skey := ca.streamRecoveryKey()
@@ -1572,10 +1753,210 @@ func (js *jetStream) getOrphans() (streams []*stream, consumers []*consumer) {
 	return streams, consumers
 }
 
+// monitorOrphanGroups periodically checks for Raft groups hosted by this server that don't
+// belong to any stream or consumer assignment, for instance after a crash during a delete,
+// and removes them to reclaim their memory and disk space.
+func (js *jetStream) monitorOrphanGroups(interval time.Duration) {
+	s := js.srv
+	defer s.grWG.Done()
+
+	qch := js.clusterQuitC()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var suspects map[string]struct{}
+	for {
+		select {
+		case <-s.quitCh:
+			return
+		case <-qch:
+			return
+		case <-t.C:
+			if js.isShuttingDown() || s.isLameDuckMode() {
+				return
+			}
+			suspects = js.checkForOrphanGroups(suspects)
+		}
+	}
+}
+
+// checkForOrphanGroups removes stream and consumer Raft groups, running or only left on disk,
+// that have no corresponding assignment. A group is only removed if it was also detected as
+// orphaned on the previous check, passed in as suspects, so assignments that are in flight have
+// time to be applied. Returns the orphans detected for the first time.
+func (js *jetStream) checkForOrphanGroups(suspects map[string]struct{}) map[string]struct{} {
+	js.mu.RLock()
+	s, cc := js.srv, js.cluster
+	if cc == nil || cc.meta == nil || js.metaRecovering {
+		js.mu.RUnlock()
+		return nil
+	}
+	// We only want to cleanup any orphans if we know we are current with the meta-leader.
+	if meta := cc.meta; meta.Leaderless() || !meta.Healthy() {
+		js.mu.RUnlock()
+		s.Debugf("JetStream cluster skipping check for orphaned raft groups, not current with the meta-leader")
+		return nil
+	}
+	known, isLeader := js.assignedGroups(), cc.isLeader()
+	// Groups still in use by a stream or consumer are left to the asset's own orphan checks.
+	for _, jsa := range js.accounts {
+		jsa.mu.RLock()
+		for _, mset := range jsa.streams {
+			if n := mset.raftNode(); n != nil {
+				known[n.Group()] = struct{}{}
+			}
+			for _, o := range mset.getPublicConsumers() {
+				if n := o.raftNode(); n != nil {
+					known[n.Group()] = struct{}{}
+				}
+			}
+		}
+		jsa.mu.RUnlock()
+	}
+	storeDir := js.config.StoreDir
+	js.mu.RUnlock()
+
+	sysAcc := s.SystemAccount()
+	if sysAcc == nil {
+		return nil
+	}
+	isOrphan := func(group string) bool {
+		if !strings.HasPrefix(group, "S-") && !strings.HasPrefix(group, "C-") {
+			return false
+		}
+		_, ok := known[group]
+		return !ok
+	}
+
+	// Running groups, and groups that are only left on disk.
+	orphans := make(map[string]RaftNode)
+	s.rnMu.RLock()
+	for group, n := range s.raftNodes {
+		if isOrphan(group) {
+			orphans[group] = n
+		}
+	}
+	s.rnMu.RUnlock()
+	groupsDir := filepath.Join(storeDir, sysAcc.Name, defaultStoreDirName)
+	if entries, err := os.ReadDir(groupsDir); err == nil {
+		for _, e := range entries {
+			if group := e.Name(); e.IsDir() && isOrphan(group) {
+				if _, ok := orphans[group]; !ok {
+					orphans[group] = nil
+				}
+			}
+		}
+	}
+
+	var detected map[string]struct{}
+	var confirm []string
+	for group := range orphans {
+		if _, ok := suspects[group]; !ok {
+			if detected == nil {
+				detected = make(map[string]struct{})
+			}
+			detected[group] = struct{}{}
+			s.Debugf("JetStream cluster detected orphaned raft group '%s'", group)
+			continue
+		}
+		confirm = append(confirm, group)
+	}
+	if len(confirm) == 0 {
+		return detected
+	}
+
+	// Our view of the assignments could be lagging, so only remove the groups
+	// the meta-leader also knows nothing about.
+	confirmed, err := &confirm, error(nil)
+	if !isLeader {
+		confirmed, err = sysRequestMsg[[]string](s, orphanGroupsSubj, confirm)
+	}
+	if err != nil {
+		s.Debugf("JetStream cluster could not confirm orphaned raft groups with the meta-leader: %v", err)
+		// Keep them as suspects for the next check.
+		if detected == nil {
+			detected = make(map[string]struct{})
+		}
+		for _, group := range confirm {
+			detected[group] = struct{}{}
+		}
+		return detected
+	}
+	for _, group := range *confirmed {
+		n, ok := orphans[group]
+		if !ok {
+			continue
+		}
+		s.Warnf("Detected orphaned raft group '%s', will cleanup", group)
+		if n != nil {
+			n.Delete()
+		} else if err := os.RemoveAll(filepath.Join(groupsDir, group)); err != nil {
+			s.Warnf("Deleting raft group encountered an error: %v", err)
+		}
+	}
+	return detected
+}
+
+// assignedGroups returns the names of all raft groups that belong to a stream or
+// consumer assignment, or that are being created.
+// Lock should be held.
+func (js *jetStream) assignedGroups() map[string]struct{} {
+	cc := js.cluster
+	known := make(map[string]struct{})
+	for _, asa := range cc.streams {
+		for _, sa := range asa {
+			if sa.Group != nil {
+				known[sa.Group.Name] = struct{}{}
+			}
+			for _, ca := range sa.consumers {
+				if ca.Group != nil {
+					known[ca.Group.Name] = struct{}{}
+				}
+			}
+		}
+	}
+	for group := range cc.creatingRaftGroups {
+		known[group] = struct{}{}
+	}
+	return known
+}
+
+// processOrphanGroupsRequest is called on the meta-leader to confirm which of the
+// requested raft groups have no assignment and can be removed by the requester.
+func (js *jetStream) processOrphanGroupsRequest(sub *subscription, c *client, _ *Account, subject, reply string, msg []byte) {
+	if reply == _EMPTY_ {
+		return
+	}
+	var groups []string
+	if err := json.Unmarshal(msg, &groups); err != nil {
+		return
+	}
+
+	js.mu.RLock()
+	if !js.cluster.isLeader() || js.metaRecovering {
+		js.mu.RUnlock()
+		return
+	}
+	known := js.assignedGroups()
+	s := js.srv
+	js.mu.RUnlock()
+
+	orphans := []string{}
+	for _, group := range groups {
+		if _, ok := known[group]; !ok {
+			orphans = append(orphans, group)
+		}
+	}
+	s.sendInternalMsgLocked(reply, _EMPTY_, nil, orphans)
+}
+
 func (js *jetStream) monitorCluster() {
 	s, n := js.server(), js.getMetaGroup()
 	qch, stopped, rqch, lch, aq := js.clusterQuitC(), js.clusterStoppedC(), n.QuitC(), n.LeadChangeC(), n.ApplyQ()
 
+	// Pin the apply worker to its own OS thread if the group uses a dedicated worker.
+	defer n.PinApplyWorker()()
+
 	defer s.grWG.Done()
 	defer close(stopped)
 
@@ -1658,7 +2039,7 @@ func (js *jetStream) monitorCluster() {
 		byNeither := !byEntries && !bySize
 		// For the meta layer we want to snapshot when over the above threshold (which could be 0 by default).
 		ne, nsz := n.Size()
-		createSnapshot := force || byNeither || (byEntries && ne > ethresh) || (bySize && nsz > szthresh) || n.NeedSnapshot()
+		createSnapshot := force || byNeither || (byEntries && ne > ethresh) || (bySize && nsz > szthresh) || n.NeedSnapshot() || n.ExceedsRecoveryTime() || n.ExceedsMaxWAL()
 		if !createSnapshot {
 			snapMu.Unlock()
 			return
@@ -1825,7 +2206,8 @@ func (js *jetStream) monitorCluster() {
 			return
 		case <-aq.ch:
 			ces := aq.pop()
-			for _, ce := range ces {
+			for i := 0; i < len(ces); i++ {
+				ce := ces[i]
 				if recovering && ru == nil {
 					ru = &recoveryUpdates{
 						removeStreams:   make(map[string]*streamAssignment),
@@ -1890,12 +2272,20 @@ func (js *jetStream) monitorCluster() {
 					}
 					if js.hasPeerEntries(ce.Entries) || (didSnap && !isLeader) {
 						doSnapshot(true)
-					} else if nb > compactSizeMin && time.Since(lastSnapTime) > minSnapDelta {
+					} else if (nb > compactSizeMin || n.ExceedsRecoveryTime() || n.ExceedsMaxWAL()) && time.Since(lastSnapTime) > minSnapDelta {
 						doSnapshot(false)
 					}
 					recovering = isRecovering
 				} else {
 					s.Warnf("Error applying JetStream cluster entries: %v", err)
+					if retry, halted := waitApplyRetry(n, ce.Index, err, rqch); retry {
+						i--
+						continue
+					} else if halted {
+						ce.ReturnToPool()
+						aq.recycle(&ces)
+						return
+					}
 				}
 				ce.ReturnToPool()
 			}
@@ -1944,6 +2334,100 @@ func (js *jetStream) monitorCluster() {
 	}
 }
 
+// scheduleStreamScaleRevertLocked arms the timer to revert temporarily scaled streams at
+// deadline, unless it's already armed to fire earlier. Only the meta leader reverts streams.
+// Lock should be held.
+func (js *jetStream) scheduleStreamScaleRevertLocked(deadline time.Time) {
+	cc := js.cluster
+	if cc == nil || cc.meta == nil || !cc.meta.Leader() {
+		return
+	}
+	if !cc.srnext.IsZero() && !deadline.Before(cc.srnext) {
+		return
+	}
+	cc.srnext = deadline
+	if cc.srtmr == nil {
+		cc.srtmr = time.AfterFunc(time.Until(deadline), js.runStreamScaleReverts)
+	} else {
+		cc.srtmr.Reset(time.Until(deadline))
+	}
+}
+
+// stopStreamScaleRevertsLocked stops the timer to revert temporarily scaled streams.
+// Lock should be held.
+func (js *jetStream) stopStreamScaleRevertsLocked() {
+	if cc := js.cluster; cc != nil && cc.srtmr != nil {
+		cc.srtmr.Stop()
+		cc.srtmr, cc.srnext = nil, time.Time{}
+	}
+}
+
+// runStreamScaleReverts reverts the temporarily scaled streams that are due,
+// and schedules the next revert if any are left.
+func (js *jetStream) runStreamScaleReverts() {
+	js.mu.Lock()
+	if cc := js.cluster; cc != nil {
+		cc.srnext = time.Time{}
+	}
+	js.mu.Unlock()
+
+	if next := js.checkStreamScaleReverts(); !next.IsZero() {
+		js.mu.Lock()
+		js.scheduleStreamScaleRevertLocked(next)
+		js.mu.Unlock()
+	}
+}
+
+// checkStreamScaleReverts returns temporarily scaled streams to their
+// original replication factor once their revert deadline has passed.
+// Returns the earliest deadline of the ones that are not due yet, if any.
+// Should only be called by the meta leader.
+func (js *jetStream) checkStreamScaleReverts() (next time.Time) {
+	s := js.server()
+	now := time.Now()
+
+	type revert struct {
+		accName string
+		cfg     StreamConfig
+	}
+	var reverts []revert
+
+	js.mu.RLock()
+	if cc := js.cluster; cc != nil && cc.meta != nil && cc.meta.Leader() {
+		for accName, asa := range cc.streams {
+			for streamName := range asa {
+				// Look at the latest proposal, it may already have been reverted.
+				sa := js.streamAssignmentOrInflight(accName, streamName)
+				if sa == nil || sa.ScaleRevert == nil {
+					continue
+				}
+				if deadline := sa.ScaleRevert.Deadline; now.Before(deadline) {
+					if next.IsZero() || deadline.Before(next) {
+						next = deadline
+					}
+					continue
+				}
+				cfg := *sa.Config
+				cfg.Replicas = sa.ScaleRevert.Replicas
+				reverts = append(reverts, revert{accName, cfg})
+			}
+		}
+	}
+	js.mu.RUnlock()
+
+	for _, r := range reverts {
+		acc, err := s.lookupAccount(r.accName)
+		if err != nil {
+			continue
+		}
+		s.Noticef("Reverting stream '%s > %s' to %d replicas", r.accName, r.cfg.Name, r.cfg.Replicas)
+		// There is no reply, the revert is visible in the stream's configuration.
+		ci := &ClientInfo{Account: r.accName, Cluster: s.cachedClusterName()}
+		s.jsClusteredStreamScaleRequest(ci, acc, fmt.Sprintf(JSApiStreamScaleT, r.cfg.Name), _EMPTY_, nil, &r.cfg, nil)
+	}
+	return next
+}
+
 // This is called on first leader transition to double check the peers and cluster set size.
 func (js *jetStream) checkClusterSize() {
 	s, n := js.server(), js.getMetaGroup()
@@ -1990,12 +2474,13 @@ func (js *jetStream) checkClusterSize() {
 
 // Represents our stable meta state that we can write out.
 type writeableStreamAssignment struct {
-	Client     *ClientInfo     `json:"client,omitempty"`
-	Created    time.Time       `json:"created"`
-	ConfigJSON json.RawMessage `json:"stream"`
-	Group      *raftGroup      `json:"group"`
-	Sync       string          `json:"sync"`
-	Consumers  []*writeableConsumerAssignment
+	Client      *ClientInfo        `json:"client,omitempty"`
+	Created     time.Time          `json:"created"`
+	ConfigJSON  json.RawMessage    `json:"stream"`
+	Group       *raftGroup         `json:"group"`
+	Sync        string             `json:"sync"`
+	ScaleRevert *streamScaleRevert `json:"scale_revert,omitempty"`
+	Consumers   []*writeableConsumerAssignment
 }
 
 func (js *jetStream) clusterStreamConfig(accName, streamName string) (StreamConfig, bool) {
@@ -2168,7 +2653,7 @@ func (js *jetStream) decodeMetaSnapshot(buf []byte) (map[string]map[string]*stre
 			as = make(map[string]*streamAssignment)
 			streams[wsa.Client.serviceAccount()] = as
 		}
-		sa := &streamAssignment{Client: wsa.Client, Created: wsa.Created, ConfigJSON: wsa.ConfigJSON, Group: wsa.Group, Sync: wsa.Sync}
+		sa := &streamAssignment{Client: wsa.Client, Created: wsa.Created, ConfigJSON: wsa.ConfigJSON, Group: wsa.Group, Sync: wsa.Sync, ScaleRevert: wsa.ScaleRevert}
 		if err := decodeStreamAssignmentConfig(js.srv, sa); err != nil {
 			return nil, err
 		}
@@ -2203,12 +2688,13 @@ func (js *jetStream) encodeMetaSnapshot(streams map[string]map[string]*streamAss
 	for _, asa := range streams {
 		for _, sa := range asa {
 			wsa := writeableStreamAssignment{
-				Client:     sa.Client.forAssignmentSnap(),
-				Created:    sa.Created,
-				ConfigJSON: sa.ConfigJSON,
-				Group:      sa.Group,
-				Sync:       sa.Sync,
-				Consumers:  make([]*writeableConsumerAssignment, 0, len(sa.consumers)),
+				Client:      sa.Client.forAssignmentSnap(),
+				Created:     sa.Created,
+				ConfigJSON:  sa.ConfigJSON,
+				Group:       sa.Group,
+				Sync:        sa.Sync,
+				ScaleRevert: sa.ScaleRevert,
+				Consumers:   make([]*writeableConsumerAssignment, 0, len(sa.consumers)),
 			}
 			for _, ca := range sa.consumers {
 				wca := writeableConsumerAssignment{
@@ -2311,6 +2797,19 @@ func (js *jetStream) collectStreamAndConsumerChanges(c RaftNodeCheckpoint, strea
 						panic(err)
 					}
 					ru.removeConsumer(ca)
+				case renameStreamOp:
+					sr, err := decodeStreamRename(buf[1:])
+					if err != nil {
+						js.srv.Errorf("JetStream cluster failed to decode stream rename: %q", buf[1:])
+						panic(err)
+					}
+					ru.renameStream(sr)
+					if asa, ok := streams[sr.Client.serviceAccount()]; ok {
+						if sa, ok := asa[sr.Stream]; ok && asa[sr.Config.Name] == nil {
+							delete(asa, sr.Stream)
+							asa[sr.Config.Name] = sa.renamed(sr.Config)
+						}
+					}
 				default:
 					panic(fmt.Sprintf("JetStream Cluster Unknown meta entry op type: %v", entryOp(buf[0])))
 				}
@@ -2725,6 +3224,18 @@ func (js *jetStream) applyMetaEntries(entries []*Entry, ru *recoveryUpdates) (bo
 				} else {
 					js.processUpdateStreamAssignment(sa)
 				}
+			case renameStreamOp:
+				sr, err := decodeStreamRename(buf[1:])
+				if err != nil {
+					js.srv.Errorf("JetStream cluster failed to decode stream rename: %q", buf[1:])
+					return isRecovering, didSnap, err
+				}
+				// Staged operations need to follow the stream, but the stream itself
+				// is renamed right away since it needs to keep its data.
+				if isRecovering {
+					ru.renameStream(sr)
+				}
+				js.processStreamRename(sr, isRecovering)
 			default:
 				panic(fmt.Sprintf("JetStream Cluster Unknown meta entry op type: %v", entryOp(buf[0])))
 			}
@@ -2780,6 +3291,26 @@ func (rg *raftGroup) setPreferred(s *Server) {
 	}
 }
 
+// setPreferredServer sets the preferred leader to the peer of the group
+// running on the server with the given name, if it is online.
+// Returns false if no such peer was found.
+func (rg *raftGroup) setPreferredServer(s *Server, name string) bool {
+	if rg == nil || name == _EMPTY_ {
+		return false
+	}
+	for _, p := range rg.Peers {
+		si, ok := s.nodeToInfo.Load(p)
+		if !ok || si == nil {
+			continue
+		}
+		if ni := si.(nodeInfo); ni.name == name && !ni.offline {
+			rg.Preferred = p
+			return true
+		}
+	}
+	return false
+}
+
 // createRaftGroup is called to spin up this raft group if needed.
 func (js *jetStream) createRaftGroup(accName string, rg *raftGroup, recovering bool, storage StorageType, labels pprofLabels) (RaftNode, error) {
 	// js.mu protects the lookup/registration of raft groups so that two parallel
@@ -2922,6 +3453,7 @@ retry:
 		}
 
 		cfg := &RaftConfig{Name: rgName, Store: storeDir, Log: store, Track: true, Recovering: recovering, ScaleUp: rgScaleUp}
+		s.getOpts().JetStreamRaft.apply(cfg)
 
 		if _, err := readPeerState(storeDir); err != nil {
 			s.bootstrapRaftNode(cfg, rgPeers, true)
@@ -3056,6 +3588,39 @@ func (mset *stream) waitOnConsumerAssignments() {
 	}
 }
 
+// waitApplyRetry reports the committed entry at index that failed to apply to the
+// group. It returns retry if the entry should be applied again, after having waited
+// for the backoff. Otherwise the entry was either skipped, or halted is returned if
+// the group was stopped and the caller should exit.
+func waitApplyRetry(n RaftNode, index uint64, err error, qch <-chan struct{}) (retry, halted bool) {
+	backoff, aerr := n.ApplyFailed(index, err)
+	if aerr != nil {
+		return false, true
+	}
+	if backoff == 0 {
+		return false, false
+	}
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true, false
+	case <-qch:
+		return false, true
+	}
+}
+
+// isStreamApplyErrHandled returns whether monitorStream has specific handling for
+// an error returned when applying stream entries.
+func isStreamApplyErrHandled(err error) bool {
+	return err == errStreamClosed || err == errCatchupStreamStopped || err == ErrServerNotRunning ||
+		isClusterResetErr(err) || isOutOfSpaceErr(err)
+}
+
+// How often a stream leader checks if leadership should move back to
+// the preferred server from the stream placement.
+var preferredLeaderCheckInterval = 5 * time.Second
+
 // Monitor our stream node for this stream.
 func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnapshot bool) {
 	s, cc := js.server(), js.cluster
@@ -3093,6 +3658,9 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 
 	qch, mqch, lch, aq, uch, ourPeerId := n.QuitC(), mset.monitorQuitC(), n.LeadChangeC(), n.ApplyQ(), mset.updateC(), meta.ID()
 
+	// Pin the apply worker to its own OS thread if the group uses a dedicated worker.
+	defer n.PinApplyWorker()()
+
 	s.Debugf("Starting stream monitor for '%s > %s' [%s]", sa.Client.serviceAccount(), sa.Config.Name, n.Group())
 	defer s.Debugf("Exiting stream monitor for '%s > %s' [%s]", sa.Client.serviceAccount(), sa.Config.Name, n.Group())
 
@@ -3118,6 +3686,11 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 	t := time.NewTicker(compactInterval + rci)
 	defer t.Stop()
 
+	// Retries a snapshot that was skipped because the server was writing as many as it allows.
+	srt := time.NewTimer(0)
+	srt.Stop()
+	defer srt.Stop()
+
 	js.mu.RLock()
 	isLeader := cc.isStreamLeader(sa.Client.serviceAccount(), sa.Config.Name)
 	isRestore := sa.Restore != nil
@@ -3130,6 +3703,13 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 	}
 	accName := acc.GetName()
 
+	// Followers hold back delivery after commit until signaled by the leader.
+	if mset != nil && !isLeader {
+		mset.mu.Lock()
+		mset.setupDeliverAfterCommit(false)
+		mset.mu.Unlock()
+	}
+
 	// Don't allow the upper layer to install snapshots until we have
 	// fully recovered from disk.
 	isRecovering := true
@@ -3207,6 +3787,10 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 			} else {
 				c.Abort()
 
+				if err == errSnapBusy {
+					srt.Reset(snapBusyRetryDelay())
+					return
+				}
 				if err == errNoSnapAvailable || err == errNodeClosed || err == errCatchupsRunning || err == errSnapAborted {
 					return
 				}
@@ -3303,6 +3887,10 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 		cistc = cist.C
 	}
 
+	// For moving leadership back to the preferred server from the placement, if any.
+	plt := time.NewTicker(preferredLeaderCheckInterval)
+	defer plt.Stop()
+
 	// This is triggered during a scale up from R1 to clustered mode. We need the new followers to catchup,
 	// similar to how we trigger the catchup mechanism post a backup/restore.
 	// We can arrive here NOT being the leader, so we send the snapshot only if we are, and in this case
@@ -3342,7 +3930,8 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 			pclfs := mset.getCLFS()
 
 			ces := aq.pop()
-			for _, ce := range ces {
+			for i := 0; i < len(ces); i++ {
+				ce := ces[i]
 				// No special processing needed for when we are caught up on restart.
 				if ce == nil {
 					if !isRecovering {
@@ -3381,7 +3970,11 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 
 				// Apply our entries.
 				if maxApplied, err := js.applyStreamEntries(mset, ce, isRecovering); err == nil {
-					// Update our applied.
+					// Remember where our last sequence was stored, to report on its replication.
+					if mset != nil {
+						mset.trackSeqIndex(ce.Index)
+					}
+					// Update our applied.
 					if maxApplied > 0 {
 						// Indicate we've processed (but not applied) everything up to this point.
 						ne, nb = n.Processed(ce.Index, min(maxApplied, ce.Index))
@@ -3391,6 +3984,18 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 						ce.ReturnToPool()
 					}
 				} else {
+					// Encountered an unexpected error, retry the entry and follow the apply error
+					// policy once out of retries, so we don't silently diverge from the group.
+					if !isStreamApplyErrHandled(err) {
+						s.Errorf("Error applying entries to '%s > %s': %v", accName, sa.Config.Name, err)
+						if retry, halted := waitApplyRetry(n, ce.Index, err, qch); retry {
+							i--
+							continue
+						} else if !halted {
+							ce.ReturnToPool()
+							continue
+						}
+					}
 					// Make sure to clean up.
 					ce.ReturnToPool()
 					// Our stream was closed out from underneath of us, simply return here.
@@ -3398,8 +4003,8 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 						aq.recycle(&ces)
 						return
 					}
-					s.Errorf("Error applying entries to '%s > %s': %v", accName, sa.Config.Name, err)
 					if isClusterResetErr(err) {
+						s.Errorf("Error applying entries to '%s > %s': %v", accName, sa.Config.Name, err)
 						if mset.isMirror() && mset.IsLeader() {
 							mset.retryMirrorConsumer()
 							continue
@@ -3418,10 +4023,12 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 							return
 						}
 					} else if isOutOfSpaceErr(err) {
+						s.Errorf("Error applying entries to '%s > %s': %v", accName, sa.Config.Name, err)
 						// If applicable this will tear all of this down, but don't assume so and return.
 						s.handleOutOfSpace(mset)
 					} else {
-						// Encountered an unexpected error, can't continue.
+						// The group was halted, so can't continue. Our I/O error policy
+						// decides if we remain available as read-only.
 						mset.setWriteErr(err)
 						aq.recycle(&ces)
 						return
@@ -3430,9 +4037,19 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 			}
 			aq.recycle(&ces)
 
+			// When delivering after commit, track or report how far we have stored.
+			if mset != nil {
+				if isLeader {
+					mset.checkQuorumStoredSeq()
+				} else {
+					mset.sendStoredSeq()
+				}
+			}
+
 			// Check about snapshotting
 			// If we have at least min entries to compact, go ahead and try to snapshot/compact.
-			if ne >= compactNumMin || nb > compactSizeMin || mset.getCLFS() > pclfs {
+			// Also if replaying our log on restart would take too long, or it's getting too large.
+			if ne >= compactNumMin || nb > compactSizeMin || mset.getCLFS() > pclfs || n.ExceedsRecoveryTime() || n.ExceedsMaxWAL() {
 				doSnapshot(false)
 			}
 
@@ -3449,7 +4066,7 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 				}
 				if isRestore {
 					acc, _ := s.LookupAccount(sa.Client.serviceAccount())
-					restoreDoneCh = s.processStreamRestore(sa.Client, acc, sa.Config, _EMPTY_, sa.Reply, _EMPTY_)
+					restoreDoneCh = s.processStreamRestore(sa.Client, acc, sa.Config, sa.ConsumerReset, _EMPTY_, sa.Reply, _EMPTY_)
 					continue
 				} else if n != nil && n.NeedSnapshot() {
 					doSnapshot(false)
@@ -3534,6 +4151,14 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 			snapMu.Unlock()
 			doSnapshot(forceIfFailed)
 
+		case <-srt.C:
+			doSnapshot(false)
+
+		case <-plt.C:
+			if isLeader && mset != nil && !isRecovering {
+				mset.checkPreferredLeader()
+			}
+
 		case <-uch:
 			// keep stream assignment current
 			sa = mset.streamAssignment()
@@ -4210,6 +4835,78 @@ func (js *jetStream) applyStreamEntries(mset *stream, ce *CommittedEntry, isReco
 						s.sendAPIResponse(sp.Client, mset.account(), sp.Subject, sp.Reply, _EMPTY_, s.jsonResponse(resp))
 					}
 				}
+			case rebuildTotalsOp:
+				rt, err := decodeStreamRebuildTotals(buf[1:])
+				if err != nil {
+					if node := mset.raftNode(); node != nil {
+						s := js.srv
+						s.Errorf("JetStream cluster could not decode rebuild totals for '%s > %s' [%s]",
+							mset.account(), mset.name(), node.Group())
+					}
+					panic(err.Error())
+				}
+				s := js.server()
+				md, bd, err := mset.store.RebuildTotals()
+				if err != nil {
+					s.Warnf("JetStream cluster failed to rebuild totals for stream %q for account %q: %v", rt.Stream, rt.Client.serviceAccount(), err)
+				} else if md != 0 || bd != 0 {
+					s.Warnf("JetStream stream '%s > %s' totals corrected by %d messages and %d bytes", rt.Client.serviceAccount(), rt.Stream, md, bd)
+				}
+
+				js.mu.RLock()
+				isLeader := js.cluster.isStreamLeader(rt.Client.serviceAccount(), rt.Stream)
+				js.mu.RUnlock()
+
+				if isLeader && !isRecovering {
+					var resp = JSApiStreamRebuildTotalsResponse{ApiResponse: ApiResponse{Type: JSApiStreamRebuildTotalsResponseType}}
+					if err != nil {
+						resp.Error = NewJSStreamGeneralError(err, Unless(err))
+						s.sendAPIErrResponse(rt.Client, mset.account(), rt.Subject, rt.Reply, _EMPTY_, s.jsonResponse(resp))
+					} else {
+						var state StreamState
+						mset.store.FastState(&state)
+						resp.Msgs, resp.Bytes, resp.MsgsDelta, resp.BytesDelta = state.Msgs, state.Bytes, md, bd
+						s.sendAPIResponse(rt.Client, mset.account(), rt.Subject, rt.Reply, _EMPTY_, s.jsonResponse(resp))
+					}
+				}
+			case reserveCapacityOp:
+				sr, err := decodeStreamReservation(buf[1:])
+				if err != nil {
+					if node := mset.raftNode(); node != nil {
+						s := js.srv
+						s.Errorf("JetStream cluster could not decode capacity reservation for '%s > %s' [%s]",
+							mset.account(), mset.name(), node.Group())
+					}
+					panic(err.Error())
+				}
+				s := js.server()
+				err = mset.reserveCapacity(sr.ID, sr.Msgs, sr.Bytes, sr.Expires)
+
+				js.mu.RLock()
+				isLeader := js.cluster.isStreamLeader(sr.Client.serviceAccount(), sr.Stream)
+				js.mu.RUnlock()
+
+				if isLeader && !isRecovering {
+					var resp = JSApiStreamReserveResponse{ApiResponse: ApiResponse{Type: JSApiStreamReserveResponseType}}
+					if err != nil {
+						resp.Error = NewJSStreamReservationInvalidError(err, Unless(err))
+						s.sendAPIErrResponse(sr.Client, mset.account(), sr.Subject, sr.Reply, _EMPTY_, s.jsonResponse(resp))
+					} else {
+						resp.ID, resp.Expires = sr.ID, sr.Expires
+						s.sendAPIResponse(sr.Client, mset.account(), sr.Subject, sr.Reply, _EMPTY_, s.jsonResponse(resp))
+					}
+				}
+			case releaseReservationOp:
+				sr, err := decodeStreamReservation(buf[1:])
+				if err != nil {
+					if node := mset.raftNode(); node != nil {
+						s := js.srv
+						s.Errorf("JetStream cluster could not decode reservation release for '%s > %s' [%s]",
+							mset.account(), mset.name(), node.Group())
+					}
+					panic(err.Error())
+				}
+				mset.releaseReservationID(sr.ID)
 			default:
 				panic(fmt.Sprintf("JetStream Cluster Unknown group entry op type: %v", op))
 			}
@@ -4229,17 +4926,28 @@ func (js *jetStream) applyStreamEntries(mset *stream, ce *CommittedEntry, isReco
 				}
 			}
 
+			// Take off the capacity reservations, if the snapshot holds any.
+			data := e.Data
+			var rsvs []*streamReservation
+			if len(data) > 0 && data[0] == streamSnapshotReservationsMagic {
+				var err error
+				if data, rsvs, err = decodeStreamSnapshotReservations(data); err != nil {
+					onBadState(err)
+					return 0, err
+				}
+			}
+
 			// Check if we are the new binary encoding.
-			if IsEncodedStreamState(e.Data) {
+			if IsEncodedStreamState(data) {
 				var err error
-				ss, err = DecodeStreamState(e.Data)
+				ss, err = DecodeStreamState(data)
 				if err != nil {
 					onBadState(err)
 					return 0, err
 				}
 			} else {
 				var snap streamSnapshot
-				if err := json.Unmarshal(e.Data, &snap); err != nil {
+				if err := json.Unmarshal(data, &snap); err != nil {
 					onBadState(err)
 					return 0, err
 				}
@@ -4256,6 +4964,8 @@ func (js *jetStream) applyStreamEntries(mset *stream, ce *CommittedEntry, isReco
 				}
 			}
 
+			// The reservations are the ones as of the snapshot, replacing any we had.
+			mset.setReservations(rsvs)
 			if err := mset.processSnapshot(ss, ce.Index); err != nil {
 				return 0, err
 			}
@@ -4523,7 +5233,7 @@ func (js *jetStream) applyStreamMsgOp(mset *stream, op entryOp, mbuf []byte, isR
 			mset.accountLocked(needLock), mset.nameLocked(needLock), err)
 
 		// There are some errors that we can't recover from.
-		if err != ErrMaxMsgs && err != ErrMaxBytes && err != ErrMaxMsgsPerSubject && err != ErrMsgTooLarge && err != ErrStoreClosed {
+		if err != ErrMaxMsgs && err != ErrMaxBytes && err != ErrMaxMsgsPerSubject && err != ErrMaxSubjects && err != ErrMsgTooLarge && err != ErrStoreClosed {
 			return err
 		}
 	}
@@ -4922,6 +5632,10 @@ func (js *jetStream) processStreamAssignment(sa *streamAssignment) {
 		return
 	}
 
+	// Publishers can connect to any server, so check them here regardless of membership.
+	acc.setStreamPublishers(sa.Config)
+	acc.setOverlapStream(sa.Config)
+
 	// Check if this is for us..
 	if isMember {
 		js.processClusterCreateStream(acc, sa)
@@ -5045,6 +5759,9 @@ func (js *jetStream) processUpdateStreamAssignment(sa *streamAssignment) {
 		return
 	}
 
+	acc.setStreamPublishers(sa.Config)
+	acc.setOverlapStream(sa.Config)
+
 	// Check if this is for us..
 	if isMember {
 		js.processClusterUpdateStream(acc, osa, sa)
@@ -5168,7 +5885,7 @@ func (js *jetStream) processClusterUpdateStream(acc *Account, osa, sa *streamAss
 		mset.setStreamAssignment(sa)
 
 		// Call update.
-		err = mset.updateWithAdvisory(cfg, !recovering, false)
+		err = mset.updateWithAdvisory(cfg, !recovering, false, true)
 	}
 
 	// If not found we must be expanding into this node since if we are here we know we are a member.
@@ -5326,7 +6043,7 @@ func (js *jetStream) processClusterCreateStream(acc *Account, sa *streamAssignme
 			// Check if our config has really been updated.
 			cfg := mset.config()
 			if !reflect.DeepEqual(&cfg, sa.Config) {
-				if err = mset.updateWithAdvisory(sa.Config, false, false); err != nil {
+				if err = mset.updateWithAdvisory(sa.Config, false, false, true); err != nil {
 					s.Warnf("JetStream cluster error updating stream %q for account %q: %v", sa.Config.Name, acc.Name, err)
 					if osa != nil {
 						// Process the raft group and make sure it's running if needed.
@@ -5345,7 +6062,7 @@ func (js *jetStream) processClusterCreateStream(acc *Account, sa *streamAssignme
 			}
 		} else if err == NewJSStreamNotFoundError() {
 			// Add in the stream here.
-			mset, err = acc.addStreamWithAssignment(sa.Config, nil, sa, false, true)
+			mset, err = acc.addStreamWithAssignment(sa.Config, nil, sa, false, true, false, false)
 		}
 		if mset != nil {
 			mset.setCreatedTime(created)
@@ -5430,7 +6147,7 @@ func (js *jetStream) processClusterCreateStream(acc *Account, sa *streamAssignme
 		// If we are restoring, process that first.
 		if sa.Restore != nil {
 			// We are restoring a stream here.
-			restoreDoneCh := s.processStreamRestore(sa.Client, acc, sa.Config, _EMPTY_, sa.Reply, _EMPTY_)
+			restoreDoneCh := s.processStreamRestore(sa.Client, acc, sa.Config, sa.ConsumerReset, _EMPTY_, sa.Reply, _EMPTY_)
 			s.startGoRoutine(func() {
 				defer s.grWG.Done()
 				select {
@@ -5554,6 +6271,13 @@ func (js *jetStream) processStreamRemoval(sa *streamAssignment) {
 	cc.removeInflightStreamProposal(accName, sa.Config.Name)
 	js.mu.Unlock()
 
+	if needDelete {
+		if acc, err := s.lookupOrFetchAccount(accName, false); err == nil {
+			acc.removeStreamPublishers(sa.Config.Name)
+			acc.removeOverlapStream(sa.Config.Name)
+		}
+	}
+
 	// During initial/startup recovery we'll not have registered the stream assignment,
 	// but might have recovered the stream from disk. We'll need to make sure that we only
 	// delete the stream if it wasn't created after this delete.
@@ -5570,6 +6294,320 @@ func (js *jetStream) processStreamRemoval(sa *streamAssignment) {
 	}
 }
 
+// processStreamRename moves the stream assignment over to its new name, and if we
+// are a member will restart the stream and its consumers under that name.
+func (js *jetStream) processStreamRename(sr *streamRename, isRecovering bool) {
+	js.mu.Lock()
+	s, cc := js.srv, js.cluster
+	if s == nil || cc == nil || cc.meta == nil {
+		js.mu.Unlock()
+		return
+	}
+	accName, oname, nname := sr.Client.serviceAccount(), sr.Stream, sr.Config.Name
+
+	var sa *streamAssignment
+	var ocfg *StreamConfig
+	var isMember bool
+	var consumers []*consumerAssignment
+	var pr *pendingStreamRename
+	accStreams := cc.streams[accName]
+	hasAssignment := accStreams != nil && (accStreams[oname] != nil || accStreams[nname] != nil)
+	if osa := accStreams[oname]; osa != nil && osa.rename != nil && osa.rename.sr.Config.Name == nname && sr.Error == nil {
+		pr, osa.rename = osa.rename, nil
+	}
+	if accStreams != nil && accStreams[nname] == nil {
+		if osa := accStreams[oname]; osa != nil {
+			ocfg = osa.Config
+			sa = osa.renamed(sr.Config)
+			delete(accStreams, oname)
+			accStreams[nname] = sa
+			ourID := cc.meta.ID()
+			if isMember = sa.Group.isMember(ourID); isMember {
+				for _, ca := range sa.consumers {
+					if ca.unsupported != nil {
+						continue
+					}
+					if rg := ca.Group; rg != nil && rg.isMember(ourID) {
+						consumers = append(consumers, ca)
+					}
+				}
+			}
+		}
+	}
+	// The meta leader tracks the rename from when it was proposed, moving it over to the
+	// renamed assignment it responds from once a quorum of peers have renamed their store.
+	if pr != nil {
+		if sa != nil {
+			sa.rename = pr
+		} else {
+			pr.tmr.Stop()
+		}
+	}
+	isLeader := cc.isLeader()
+	js.mu.Unlock()
+
+	acc, err := s.lookupOrFetchAccount(accName, isMember)
+	if err != nil {
+		return
+	}
+
+	if !hasAssignment {
+		// During initial/startup recovery we'll not have registered the stream assignment,
+		// but might have recovered the stream from disk under its old name.
+		if mset, err := acc.lookupStream(oname); err == nil {
+			if _, err := acc.lookupStream(nname); err != nil {
+				if _, err := mset.renameStore(sr.Config); err != nil {
+					s.Warnf("JetStream cluster error renaming stream '%s > %s' to %q: %v", accName, oname, nname, err)
+				}
+			}
+		}
+	} else if isMember {
+		js.processClusterRenameStream(acc, oname, ocfg, sa, consumers, sr.Error != nil)
+	}
+	if sa != nil {
+		acc.removeStreamPublishers(oname)
+		acc.setStreamPublishers(sa.Config)
+		acc.removeOverlapStream(oname)
+		acc.setOverlapStream(sa.Config)
+	}
+
+	// The meta leader responds right away if the rename can't go ahead or was reverted.
+	if isLeader && !isRecovering && sr.Reply != _EMPTY_ && (sa == nil || sr.Error != nil) {
+		var resp = JSApiStreamRenameResponse{ApiResponse: ApiResponse{Type: JSApiStreamRenameResponseType}}
+		if sa == nil {
+			resp.Error = NewJSStreamNotFoundError()
+		} else {
+			resp.Error = sr.Error
+		}
+		s.sendAPIErrResponse(sr.Client, acc, sr.Subject, sr.Reply, _EMPTY_, s.jsonResponse(&resp))
+	}
+}
+
+// processClusterRenameStream stops our stream and consumers, rewrites the stream's
+// store under the new name and starts them up again from the renamed assignments.
+// The store is copied in the background, after which the result is sent to the meta
+// leader. If the copy failed the original store is kept, our stream and consumers are
+// started again under the old name ocfg, and the meta leader will revert the rename,
+// unless this already is a revert.
+func (js *jetStream) processClusterRenameStream(acc *Account, oname string, ocfg *StreamConfig, sa *streamAssignment, consumers []*consumerAssignment, revert bool) {
+	s := js.srv
+	// A revert needs to wait for the rename it reverts.
+	js.mu.Lock()
+	prev, done := sa.Group.renaming, make(chan struct{})
+	sa.Group.renaming = done
+	js.mu.Unlock()
+
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+		defer close(done)
+		if prev != nil {
+			select {
+			case <-prev:
+			case <-s.quitCh:
+				return
+			}
+		}
+
+		var err error
+		mset, lerr := acc.lookupStream(oname)
+		if lerr != nil && revert {
+			// We failed renaming our store and already run under the original name again,
+			// only need to pick up the reverted assignments.
+			if mset, lerr = acc.lookupStream(sa.Config.Name); lerr == nil {
+				js.reattachRenamedStream(mset, sa, consumers)
+				return
+			}
+		}
+		if lerr == nil {
+			if node := mset.raftNode(); node != nil {
+				node.StepDown()
+			}
+			mset.stopMonitoring()
+			mset.resetAndWaitOnConsumers()
+			if _, err = mset.renameStore(sa.Config); err != nil {
+				s.Warnf("JetStream cluster error renaming stream '%s > %s' to %q: %v", acc.Name, oname, sa.Config.Name, err)
+				// Make sure it's stopped, it's recreated from the store we kept below.
+				if !mset.closed.Load() {
+					mset.stop(false, false)
+				}
+			}
+		}
+		if !revert {
+			js.sendStreamRenameResult(sa, err)
+		}
+		if err != nil {
+			// Don't wait on the meta leader to revert, it may have changed in the meantime.
+			js.mu.RLock()
+			osa := sa.renamed(ocfg)
+			ocas := make([]*consumerAssignment, 0, len(consumers))
+			for _, ca := range consumers {
+				if oca := osa.consumers[ca.Name]; oca != nil {
+					ocas = append(ocas, oca)
+				}
+			}
+			js.mu.RUnlock()
+			sa, consumers = osa, ocas
+		}
+
+		// Now wipe groups from assignments, they will be recreated.
+		js.mu.Lock()
+		sa.Group.node = nil
+		for _, ca := range consumers {
+			ca.Group.node = nil
+		}
+		js.mu.Unlock()
+
+		js.processClusterCreateStream(acc, sa)
+		for _, ca := range consumers {
+			js.processClusterCreateConsumer(nil, ca, nil, false)
+		}
+	})
+}
+
+// reattachRenamedStream points our running stream and consumers at their reverted assignments,
+// keeping the raft nodes they already run.
+func (js *jetStream) reattachRenamedStream(mset *stream, sa *streamAssignment, consumers []*consumerAssignment) {
+	node := mset.raftNode()
+	cnodes := make(map[string]RaftNode, len(consumers))
+	for _, ca := range consumers {
+		if o := mset.lookupConsumer(ca.Name); o != nil {
+			cnodes[ca.Name] = o.raftNode()
+		}
+	}
+
+	js.mu.Lock()
+	sa.Group.node = node
+	for _, ca := range consumers {
+		ca.Group.node = cnodes[ca.Name]
+	}
+	js.mu.Unlock()
+
+	mset.setStreamAssignment(sa)
+	for _, ca := range consumers {
+		if o := mset.lookupConsumer(ca.Name); o != nil {
+			o.setConsumerAssignment(ca)
+		} else {
+			js.processClusterCreateConsumer(nil, ca, nil, false)
+		}
+	}
+}
+
+// sendStreamRenameResult lets the meta leader know whether we renamed our store.
+func (js *jetStream) sendStreamRenameResult(sa *streamAssignment, err error) {
+	js.mu.RLock()
+	s, cc := js.srv, js.cluster
+	if cc == nil || cc.meta == nil {
+		js.mu.RUnlock()
+		return
+	}
+	result := &streamAssignmentResult{
+		Account: sa.Client.serviceAccount(),
+		Stream:  sa.Config.Name,
+		Peer:    cc.meta.ID(),
+		Rename:  &JSApiStreamRenameResponse{ApiResponse: ApiResponse{Type: JSApiStreamRenameResponseType}},
+	}
+	js.mu.RUnlock()
+
+	if err != nil {
+		result.Rename.Error = NewJSStreamGeneralError(err, Unless(err))
+	} else {
+		result.Rename.Success = true
+	}
+	b, _ := json.Marshal(result) // Avoids auto-processing and doing fancy json with newlines.
+	s.sendInternalMsgLocked(streamAssignmentSubj, _EMPTY_, nil, b)
+}
+
+// processStreamRenameResult responds once a quorum of peers renamed their store, or reverts
+// the rename as soon as one of them failed to. Lock should be held.
+func (js *jetStream) processStreamRenameResult(acc *Account, result *streamAssignmentResult) {
+	s, cc := js.srv, js.cluster
+	sa := js.pendingStreamRename(result.Account, result.Stream)
+	if sa == nil {
+		return
+	}
+	pr := sa.rename
+	if _, ok := pr.peers[result.Peer]; !ok {
+		return
+	}
+	delete(pr.peers, result.Peer)
+	if result.Rename.Error == nil {
+		if pr.acks++; pr.acks < pr.quorum {
+			return
+		}
+		pr.tmr.Stop()
+		sa.rename = nil
+		if pr.sr.Reply != _EMPTY_ {
+			s.sendAPIResponse(pr.sr.Client, acc, pr.sr.Subject, pr.sr.Reply, _EMPTY_, s.jsonResponse(result.Rename))
+		}
+		return
+	}
+
+	pr.tmr.Stop()
+	sa.rename = nil
+	s.Warnf("Stream rename for '%s > %s' to %q failed on peer %q, reverting: %v",
+		result.Account, pr.sr.Stream, result.Stream, result.Peer, result.Rename.Error)
+	rsr := &streamRename{
+		Client:  pr.sr.Client,
+		Stream:  result.Stream,
+		Config:  pr.ocfg,
+		Subject: pr.sr.Subject,
+		Reply:   pr.sr.Reply,
+		Error:   result.Rename.Error,
+	}
+	cc.meta.Propose(encodeStreamRename(rsr))
+}
+
+// pendingStreamRename returns the assignment tracking the pending rename to stream.
+// That is still the original assignment if we did not apply the rename yet.
+// Lock should be held.
+func (js *jetStream) pendingStreamRename(accName, stream string) *streamAssignment {
+	for _, sa := range js.cluster.streams[accName] {
+		if pr := sa.rename; pr != nil && pr.sr.Config.Name == stream {
+			return sa
+		}
+	}
+	return nil
+}
+
+// expireStreamRename gives up waiting on the stream's peers to rename their store,
+// if the rename pr is still pending.
+func (js *jetStream) expireStreamRename(accName string, pr *pendingStreamRename) {
+	js.mu.Lock()
+	sa := js.pendingStreamRename(accName, pr.sr.Config.Name)
+	if sa == nil || sa.rename != pr {
+		js.mu.Unlock()
+		return
+	}
+	sa.rename = nil
+	s := js.srv
+	js.mu.Unlock()
+
+	s.Warnf("Stream rename for '%s > %s' to %q timed out waiting on its peers", accName, pr.sr.Stream, pr.sr.Config.Name)
+	if pr.sr.Reply == _EMPTY_ {
+		return
+	}
+	acc, err := s.LookupAccount(accName)
+	if err != nil {
+		return
+	}
+	var resp = JSApiStreamRenameResponse{ApiResponse: ApiResponse{Type: JSApiStreamRenameResponseType}}
+	resp.Error = NewJSStreamGeneralError(errors.New("timed out waiting on peers to rename the stream"))
+	s.sendAPIErrResponse(pr.sr.Client, acc, pr.sr.Subject, pr.sr.Reply, _EMPTY_, s.jsonResponse(&resp))
+}
+
+// clearStreamRenamesLocked stops waiting on pending stream renames, a new meta leader will not respond to them.
+// Lock should be held.
+func (js *jetStream) clearStreamRenamesLocked() {
+	for _, asa := range js.cluster.streams {
+		for _, sa := range asa {
+			if pr := sa.rename; pr != nil {
+				pr.tmr.Stop()
+				sa.rename = nil
+			}
+		}
+	}
+}
+
 func (js *jetStream) processClusterDeleteStream(sa *streamAssignment, isMember, wasLeader bool) {
 	if sa == nil {
 		return
@@ -5953,7 +6991,7 @@ func (js *jetStream) processClusterCreateConsumer(oca, ca *consumerAssignment, s
 	var didCreate, isConfigUpdate, needsLocalResponse bool
 	if o == nil {
 		// Add in the consumer if needed.
-		if o, err = mset.addConsumerWithAssignment(ca.Config, ca.Name, ca, js.isMetaRecovering(), ActionCreateOrUpdate, false); err == nil {
+		if o, err = mset.addConsumerWithAssignment(ca.Config, ca.Name, ca, js.isMetaRecovering(), ActionCreateOrUpdate, false, false); err == nil {
 			didCreate = true
 		}
 	} else {
@@ -6454,6 +7492,9 @@ func (js *jetStream) monitorConsumer(o *consumer, ca *consumerAssignment) {
 
 	qch, mqch, lch, aq, uch, ourPeerId := n.QuitC(), o.monitorQuitC(), n.LeadChangeC(), n.ApplyQ(), o.updateC(), meta.ID()
 
+	// Pin the apply worker to its own OS thread if the group uses a dedicated worker.
+	defer n.PinApplyWorker()()
+
 	s.Debugf("Starting consumer monitor for '%s > %s > %s' [%s]", o.acc.Name, ca.Stream, ca.Name, n.Group())
 	defer s.Debugf("Exiting consumer monitor for '%s > %s > %s' [%s]", o.acc.Name, ca.Stream, ca.Name, n.Group())
 
@@ -6470,6 +7511,11 @@ func (js *jetStream) monitorConsumer(o *consumer, ca *consumerAssignment) {
 	t := time.NewTicker(compactInterval + rci)
 	defer t.Stop()
 
+	// Retries a snapshot that was skipped because the server was writing as many as it allows.
+	srt := time.NewTimer(0)
+	srt.Stop()
+	defer srt.Stop()
+
 	// Highwayhash key for generating hashes.
 	key := make([]byte, 32)
 	crand.Read(key)
@@ -6501,6 +7547,8 @@ func (js *jetStream) monitorConsumer(o *consumer, ca *consumerAssignment) {
 					t.Reset(compactInterval + rci)
 				}
 				failedSnapshots = 0
+			} else if err == errSnapBusy {
+				srt.Reset(snapBusyRetryDelay())
 			} else if err != errNoSnapAvailable && err != errNodeClosed && err != errCatchupsRunning {
 				s.RateLimitWarnf("Failed to install snapshot for '%s > %s > %s' [%s]: %v", o.acc.Name, ca.Stream, ca.Name, n.Group(), err)
 				// If this is the first failure, reduce the interval of the snapshot timer.
@@ -6556,7 +7604,8 @@ func (js *jetStream) monitorConsumer(o *consumer, ca *consumerAssignment) {
 			return
 		case <-aq.ch:
 			ces := aq.pop()
-			for _, ce := range ces {
+			for i := 0; i < len(ces); i++ {
+				ce := ces[i]
 				// No special processing needed for when we are caught up on restart.
 				if ce == nil {
 					if !recovering {
@@ -6575,11 +7624,20 @@ func (js *jetStream) monitorConsumer(o *consumer, ca *consumerAssignment) {
 						ne, nb = n.Applied(ce.Index)
 					}
 					// If we have at least min entries to compact, go ahead and snapshot/compact.
-					if nb > 0 && ne >= compactNumMin || nb > compactSizeMin {
+					// Also if replaying our log on restart would take too long, or it's getting too large.
+					if nb > 0 && ne >= compactNumMin || nb > compactSizeMin || n.ExceedsRecoveryTime() || n.ExceedsMaxWAL() {
 						doSnapshot(false)
 					}
 				} else if err != errConsumerClosed {
-					s.Warnf("Error applying consumer entries to '%s > %s'", ca.Client.serviceAccount(), ca.Name)
+					s.Warnf("Error applying consumer entries to '%s > %s': %v", ca.Client.serviceAccount(), ca.Name, err)
+					if retry, halted := waitApplyRetry(n, ce.Index, err, qch); retry {
+						i--
+						continue
+					} else if halted {
+						ce.ReturnToPool()
+						aq.recycle(&ces)
+						return
+					}
 				}
 				ce.ReturnToPool()
 			}
@@ -6685,6 +7743,9 @@ func (js *jetStream) monitorConsumer(o *consumer, ca *consumerAssignment) {
 			// Start forcing snapshots if they failed previously.
 			forceIfFailed := failedSnapshots > 0
 			doSnapshot(forceIfFailed)
+
+		case <-srt.C:
+			doSnapshot(false)
 		}
 	}
 }
@@ -6772,7 +7833,7 @@ func (js *jetStream) applyConsumerEntries(o *consumer, ce *CommittedEntry, isLea
 				if pmsg, ok := o.pendingDeliveries[sseq]; ok {
 					// Copy delivery subject and sequence first, as the send returns it to the pool and clears it.
 					dsubj, seq := pmsg.dsubj, pmsg.seq
-					o.outq.send(pmsg)
+					o.sendDelivery(pmsg)
 					delete(o.pendingDeliveries, sseq)
 
 					// Might need to send a request timeout after sending the last replicated delivery.
@@ -6860,21 +7921,57 @@ func (js *jetStream) applyConsumerEntries(o *consumer, ce *CommittedEntry, isLea
 						s.sendInternalAccountMsg(a, reply, s.jsonResponse(&resp))
 					}
 				}
-			case addPendingRequest:
-				o.mu.Lock()
-				if !o.isLeader() {
-					if o.prm == nil {
-						o.prm = make(map[string]struct{})
+			case restoreStateOp:
+				state, reply, err := decodeConsumerRestore(buf[1:])
+				if err != nil {
+					// Skipping the entry would diverge from the other replicas, so stop this
+					// consumer's group instead of taking down the whole server.
+					if mset, node := o.streamAndNode(); mset != nil && node != nil {
+						s := js.srv
+						s.Errorf("JetStream cluster could not decode consumer state restore for '%s > %s > %s' [%s], stopping: %v",
+							mset.account(), mset.name(), o, node.Group(), err)
+						node.Stop()
 					}
-					o.prm[string(buf[1:])] = struct{}{}
+					return errConsumerClosed
 				}
-				o.mu.Unlock()
-			case removePendingRequest:
 				o.mu.Lock()
-				if !o.isLeader() {
-					if o.prm != nil {
-						delete(o.prm, string(buf[1:]))
-					}
+				if err = o.applyRestoredState(state); err != nil {
+					js.srv.Warnf("JetStream cluster failed to restore state for consumer '%s > %s > %s': %v", o.acc, o.stream, o.name, err)
+				}
+				if !o.isLeader() || reply == _EMPTY_ {
+					o.mu.Unlock()
+				} else if _, ok := o.rsm[reply]; !ok {
+					o.mu.Unlock()
+				} else {
+					delete(o.rsm, reply)
+					s, acc, subject := o.srv, o.acc, fmt.Sprintf(JSApiConsumerRestoreT, o.stream, o.name)
+					o.mu.Unlock()
+
+					// API requests are answered on the system account.
+					var resp = JSApiConsumerRestoreResponse{ApiResponse: ApiResponse{Type: JSApiConsumerRestoreResponseType}}
+					if err != nil {
+						resp.Error = NewJSConsumerRestoreFailedError(err)
+						s.sendAPIErrResponse(nil, acc, subject, reply, _EMPTY_, s.jsonResponse(&resp))
+					} else {
+						resp.ConsumerInfo = setDynamicConsumerInfoMetadata(o.info())
+						s.sendAPIResponse(nil, acc, subject, reply, _EMPTY_, s.jsonResponse(&resp))
+					}
+				}
+			case addPendingRequest:
+				o.mu.Lock()
+				if !o.isLeader() {
+					if o.prm == nil {
+						o.prm = make(map[string]struct{})
+					}
+					o.prm[string(buf[1:])] = struct{}{}
+				}
+				o.mu.Unlock()
+			case removePendingRequest:
+				o.mu.Lock()
+				if !o.isLeader() {
+					if o.prm != nil {
+						delete(o.prm, string(buf[1:]))
+					}
 				}
 				o.mu.Unlock()
 			default:
@@ -6941,6 +8038,7 @@ func (o *consumer) processReplicatedAck(dseq, sseq uint64) error {
 
 var errBadAckUpdate = errors.New("jetstream cluster bad replicated ack update")
 var errBadDeliveredUpdate = errors.New("jetstream cluster bad replicated delivered update")
+var errBadConsumerRestore = errors.New("jetstream cluster bad replicated consumer restore")
 
 func decodeAckUpdate(buf []byte) (dseq, sseq uint64, err error) {
 	var bi, n int
@@ -6974,6 +8072,32 @@ func decodeDeliveredUpdate(buf []byte) (dseq, sseq, dc uint64, ts int64, err err
 	return dseq, sseq, dc, ts, nil
 }
 
+// Encode a replicated state restore, the reply is appended to the encoded state.
+func encodeConsumerRestore(state *ConsumerState, reply string) []byte {
+	buf := encodeConsumerState(state)
+	b := make([]byte, 1+4+len(buf)+len(reply))
+	b[0] = byte(restoreStateOp)
+	binary.LittleEndian.PutUint32(b[1:], uint32(len(buf)))
+	copy(b[5:], buf)
+	copy(b[5+len(buf):], reply)
+	return b
+}
+
+func decodeConsumerRestore(buf []byte) (*ConsumerState, string, error) {
+	if len(buf) < 4 {
+		return nil, _EMPTY_, errBadConsumerRestore
+	}
+	n := int(binary.LittleEndian.Uint32(buf))
+	if len(buf) < 4+n {
+		return nil, _EMPTY_, errBadConsumerRestore
+	}
+	state, err := decodeConsumerState(buf[4 : 4+n])
+	if err != nil {
+		return nil, _EMPTY_, err
+	}
+	return state, string(buf[4+n:]), nil
+}
+
 func (js *jetStream) processConsumerLeaderChange(o *consumer, isLeader bool) error {
 	return js.processConsumerLeaderChangeWithAssignment(o, nil, isLeader)
 }
@@ -7157,7 +8281,9 @@ type streamAssignmentResult struct {
 	Stream   string                      `json:"stream"`
 	Response *JSApiStreamCreateResponse  `json:"create_response,omitempty"`
 	Restore  *JSApiStreamRestoreResponse `json:"restore_response,omitempty"`
+	Rename   *JSApiStreamRenameResponse  `json:"rename_response,omitempty"`
 	Update   bool                        `json:"is_update,omitempty"`
+	Peer     string                      `json:"peer,omitempty"`
 }
 
 // Determine if this is an insufficient resources' error type.
@@ -7187,6 +8313,11 @@ func (js *jetStream) processStreamAssignmentResults(sub *subscription, c *client
 		return
 	}
 
+	if result.Rename != nil {
+		js.processStreamRenameResult(acc, &result)
+		return
+	}
+
 	if sa := js.streamAssignmentOrInflight(result.Account, result.Stream); sa != nil && !sa.reassigning {
 		canDelete := !result.Update && time.Since(sa.Created) < 5*time.Second
 
@@ -7293,6 +8424,7 @@ func (js *jetStream) processConsumerAssignmentResults(sub *subscription, c *clie
 const (
 	streamAssignmentSubj   = "$SYS.JSC.STREAM.ASSIGNMENT.RESULT"
 	consumerAssignmentSubj = "$SYS.JSC.CONSUMER.ASSIGNMENT.RESULT"
+	orphanGroupsSubj       = "$SYS.JSC.ORPHAN.GROUPS"
 )
 
 // Lock should be held.
@@ -7316,9 +8448,15 @@ func (js *jetStream) startUpdatesSub() {
 	if cc.peerStreamCancelMove == nil {
 		cc.peerStreamCancelMove, _ = s.systemSubscribe(JSApiServerStreamCancelMove, _EMPTY_, false, c, s.jsLeaderServerStreamCancelMoveRequest)
 	}
+	if cc.peerEvacuate == nil {
+		cc.peerEvacuate, _ = s.systemSubscribe(JSApiServerEvacuate, _EMPTY_, false, c, s.jsLeaderServerEvacuateRequest)
+	}
 	if js.accountPurge == nil {
 		js.accountPurge, _ = s.systemSubscribe(JSApiAccountPurge, _EMPTY_, false, c, s.jsLeaderAccountPurgeRequest)
 	}
+	if cc.orphanGroups == nil {
+		cc.orphanGroups, _ = s.systemSubscribe(orphanGroupsSubj, _EMPTY_, false, c, js.processOrphanGroupsRequest)
+	}
 }
 
 // Lock should be held.
@@ -7348,6 +8486,14 @@ func (js *jetStream) stopUpdatesSub() {
 		cc.s.sysUnsubscribe(cc.peerStreamCancelMove)
 		cc.peerStreamCancelMove = nil
 	}
+	if cc.peerEvacuate != nil {
+		cc.s.sysUnsubscribe(cc.peerEvacuate)
+		cc.peerEvacuate = nil
+	}
+	if cc.orphanGroups != nil {
+		cc.s.sysUnsubscribe(cc.orphanGroups)
+		cc.orphanGroups = nil
+	}
 	if js.accountPurge != nil {
 		cc.s.sysUnsubscribe(js.accountPurge)
 		js.accountPurge = nil
@@ -7423,6 +8569,9 @@ func (js *jetStream) processLeaderChange(isLeader bool) {
 	js.cluster.inflightStreams = nil
 	js.cluster.inflightConsumers = nil
 
+	// Clear pending stream renames.
+	js.clearStreamRenamesLocked()
+
 	if isLeader {
 		if meta := js.cluster.meta; meta != nil && meta.IsObserver() {
 			meta.StepDown()
@@ -7432,8 +8581,11 @@ func (js *jetStream) processLeaderChange(isLeader bool) {
 
 	if isLeader {
 		js.startUpdatesSub()
+		// Pick up reverting the temporarily scaled streams.
+		go js.runStreamScaleReverts()
 	} else {
 		js.stopUpdatesSub()
+		js.stopStreamScaleRevertsLocked()
 		// TODO(dlc) - stepdown.
 	}
 
@@ -7502,6 +8654,7 @@ type selectPeerError struct {
 	uniqueTag   bool
 	misc        bool
 	noJsClust   bool
+	noPreferred bool
 	noMatchTags map[string]struct{}
 	excludeTags map[string]struct{}
 }
@@ -7522,6 +8675,7 @@ func (e *selectPeerError) Error() string {
 	writeBoolErrReason(e.uniqueTag, "server tag not unique")
 	writeBoolErrReason(e.misc, "miscellaneous issue")
 	writeBoolErrReason(e.noJsClust, "jetstream not enabled in cluster")
+	writeBoolErrReason(e.noPreferred, "preferred server not eligible")
 	if len(e.noMatchTags) != 0 {
 		b.WriteString(", tags not matched [")
 		var firstTagWritten bool
@@ -7583,6 +8737,7 @@ func (e *selectPeerError) accumulate(eAdd *selectPeerError) {
 	acc(&e.uniqueTag, eAdd.uniqueTag)
 	acc(&e.misc, eAdd.misc)
 	acc(&e.noJsClust, eAdd.noJsClust)
+	acc(&e.noPreferred, eAdd.noPreferred)
 	for tag := range eAdd.noMatchTags {
 		e.addMissingTag(tag)
 	}
@@ -7627,6 +8782,12 @@ func (cc *jetStreamCluster) selectPeerGroup(r int, cluster string, cfg *StreamCo
 		off   bool
 		ha    int
 		ns    int
+		pref  bool
+	}
+
+	var preferred string
+	if cfg.Placement != nil {
+		preferred = cfg.Placement.Preferred
 	}
 
 	var nodes []wn
@@ -7669,6 +8830,12 @@ func (cc *jetStreamCluster) selectPeerGroup(r int, cluster string, cfg *StreamCo
 		ep = make(map[string]struct{})
 		for i, p := range existing {
 			ep[p] = struct{}{}
+			if preferred != _EMPTY_ {
+				if si, ok := s.nodeToInfo.Load(p); ok && si != nil && si.(nodeInfo).name == preferred {
+					// Already part of the peer set.
+					preferred = _EMPTY_
+				}
+			}
 			if uniqueTagPrefix == _EMPTY_ {
 				continue
 			}
@@ -7831,7 +8998,7 @@ func (cc *jetStreamCluster) selectPeerGroup(r int, cluster string, cfg *StreamCo
 			}
 		}
 		// Add to our list of potential nodes.
-		nodes = append(nodes, wn{p.ID, available, ni.offline, peerHA[p.ID], peerStreams[p.ID]})
+		nodes = append(nodes, wn{p.ID, available, ni.offline, peerHA[p.ID], peerStreams[p.ID], preferred != _EMPTY_ && ni.name == preferred})
 		if !ni.offline {
 			onlinePeers++
 		}
@@ -7880,6 +9047,19 @@ func (cc *jetStreamCluster) selectPeerGroup(r int, cluster string, cfg *StreamCo
 		})
 	}
 
+	// If a preferred server is specified, it has to be selected.
+	if preferred != _EMPTY_ {
+		pi := slices.IndexFunc(nodes, func(n wn) bool { return n.pref })
+		if pi < 0 {
+			err.noPreferred = true
+			s.Debugf("Peer selection: preferred server %s not eligible", preferred)
+			return nil, &err
+		}
+		pn := nodes[pi]
+		copy(nodes[1:pi+1], nodes[:pi])
+		nodes[0] = pn
+	}
+
 	var results []string
 	if len(existing) > 0 {
 		results = append(results, existing...)
@@ -7924,6 +9104,27 @@ func (js *jetStream) tieredStreamAndReservationCount(accName, tier string, cfg *
 	return numStreams, reservation
 }
 
+// tieredConsumerCount returns the number of consumers across all streams of the account
+// that fall into the given tier. Direct and sourcing consumers are not counted.
+// Lock should be held.
+func (js *jetStream) tieredConsumerCount(accName, tier string, replicas int) int {
+	var numConsumers int
+	for sa := range js.streamAssignmentsOrInflightSeq(accName) {
+		if sa.Config == nil {
+			continue
+		}
+		for ca := range js.consumerAssignmentsOrInflightSeq(accName, sa.Config.Name) {
+			if ca.unsupported != nil || ca.Config == nil || ca.Config.Direct || ca.Config.Sourcing {
+				continue
+			}
+			if tier == _EMPTY_ || isSameTier(ca.Config.replicas(sa.Config), replicas) {
+				numConsumers++
+			}
+		}
+	}
+	return numConsumers
+}
+
 // createGroupForStream will create a group for assignment for the stream.
 // Lock should be held.
 func (js *jetStream) createGroupForStream(ci *ClientInfo, cfg *StreamConfig) (*raftGroup, *selectPeerError) {
@@ -8025,6 +9226,17 @@ func (s *Server) jsClusteredStreamRequest(ci *ClientInfo, acc *Account, subject,
 
 	// Capture if we have existing/inflight assignment first.
 	if osa := js.streamAssignmentOrInflight(acc.Name, cfg.Name); osa != nil {
+		// Concurrent creates are serialized by our lock, so only the first exclusive create
+		// gets proposed. Let the others know whether the stream exists or is still being created.
+		if config.Exclusive {
+			if sa := cc.streams[acc.Name][cfg.Name]; sa != nil {
+				resp.Error = NewJSStreamAlreadyExistsError()
+			} else {
+				resp.Error = NewJSStreamCreateInProgressError()
+			}
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+			return
+		}
 		copyStreamMetadata(cfg, osa.Config)
 		// Set the index name on both to ensure the DeepEqual works
 		currentIName := make(map[string]struct{})
@@ -8053,7 +9265,7 @@ func (s *Server) jsClusteredStreamRequest(ci *ClientInfo, acc *Account, subject,
 	}
 
 	// Check for subject collisions here.
-	if js.subjectsOverlap(acc.Name, cfg.Subjects, self) {
+	if js.subjectsOverlap(acc.Name, cfg.Subjects, cfg.AllowSubjectOverlap, self) {
 		resp.Error = NewJSStreamSubjectOverlapError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
 		return
@@ -8076,8 +9288,20 @@ func (s *Server) jsClusteredStreamRequest(ci *ClientInfo, acc *Account, subject,
 			return
 		}
 		rg = nrg
-		// Pick a preferred leader.
-		rg.setPreferred(s)
+		// Pick a preferred leader, honoring the one from the placement if any.
+		if cfg.Placement == nil || !rg.setPreferredServer(s, cfg.Placement.Preferred) {
+			rg.setPreferred(s)
+		}
+	}
+
+	if config.ValidateOnly {
+		// Respond with the config the stream would be created with.
+		resp.StreamInfo = &StreamInfo{
+			Config:    *setDynamicStreamMetadata(cfg),
+			TimeStamp: time.Now().UTC(),
+		}
+		s.sendAPIResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
 	}
 
 	if syncSubject == _EMPTY_ {
@@ -8101,8 +9325,11 @@ var (
 // blocking utility call to perform requests on the system account
 // returns (synchronized) v or error
 func sysRequest[T any](s *Server, subjFormat string, args ...any) (*T, error) {
-	isubj := fmt.Sprintf(subjFormat, args...)
+	return sysRequestMsg[T](s, fmt.Sprintf(subjFormat, args...), nil)
+}
 
+// sysRequestMsg is like sysRequest, but sends msg as the request body.
+func sysRequestMsg[T any](s *Server, isubj string, msg any) (*T, error) {
 	s.mu.Lock()
 	if s.sys == nil {
 		s.mu.Unlock()
@@ -8124,7 +9351,7 @@ func sysRequest[T any](s *Server, subjFormat string, args ...any) (*T, error) {
 	}
 	s.mu.Unlock()
 
-	s.sendInternalMsgLocked(isubj, inbox, nil, nil)
+	s.sendInternalMsgLocked(isubj, inbox, nil, msg)
 
 	defer func() {
 		s.mu.Lock()
@@ -8147,7 +9374,34 @@ func sysRequest[T any](s *Server, subjFormat string, args ...any) (*T, error) {
 	}
 }
 
-func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, subject, reply string, rmsg []byte, cfg *StreamConfig, peerSet []string, pedantic bool) {
+func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, subject, reply string, rmsg []byte, cfg *StreamConfig, peerSet []string, pedantic, validateOnly, migrateStorage bool) {
+	s.jsClusteredStreamUpdate(ci, acc, subject, reply, rmsg, cfg, &streamUpdateOpts{
+		peerSet:        peerSet,
+		pedantic:       pedantic,
+		validateOnly:   validateOnly,
+		migrateStorage: migrateStorage,
+	})
+}
+
+// jsClusteredStreamScaleRequest changes the replication factor of a stream.
+// If revert is set the meta leader will return the stream to revert.Replicas once its deadline passes,
+// otherwise any pending revert is dropped.
+func (s *Server) jsClusteredStreamScaleRequest(ci *ClientInfo, acc *Account, subject, reply string, rmsg []byte, cfg *StreamConfig, revert *streamScaleRevert) {
+	s.jsClusteredStreamUpdate(ci, acc, subject, reply, rmsg, cfg, &streamUpdateOpts{scale: true, revert: revert})
+}
+
+// streamUpdateOpts holds how a clustered stream update is to be applied.
+type streamUpdateOpts struct {
+	peerSet        []string           // Peers to move the stream to, if any.
+	pedantic       bool               // Reject the update instead of applying defaults.
+	validateOnly   bool               // Only validate the update, don't propose it.
+	migrateStorage bool               // Allow changing the storage type.
+	scale          bool               // Only the replication factor changes, recording revert.
+	revert         *streamScaleRevert // The replication factor to return to, for a temporary scale.
+}
+
+func (s *Server) jsClusteredStreamUpdate(ci *ClientInfo, acc *Account, subject, reply string, rmsg []byte, cfg *StreamConfig, opts *streamUpdateOpts) {
+	peerSet := opts.peerSet
 	js, cc := s.getJetStreamCluster()
 	if js == nil || cc == nil {
 		return
@@ -8183,7 +9437,7 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 	var newCfg *StreamConfig
 	if jsa := js.accounts[acc.Name]; jsa != nil {
 		js.mu.Unlock()
-		ncfg, err := jsa.configUpdateCheck(osa.Config, cfg, s, pedantic)
+		ncfg, err := jsa.configUpdateCheck(osa.Config, cfg, s, opts.pedantic, opts.migrateStorage)
 		js.mu.Lock()
 		if err != nil {
 			resp.Error = NewJSStreamUpdateError(err, Unless(err))
@@ -8206,7 +9460,7 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 	}
 
 	// Check for subject collisions here.
-	if js.subjectsOverlap(acc.Name, cfg.Subjects, osa) {
+	if js.subjectsOverlap(acc.Name, cfg.Subjects, cfg.AllowSubjectOverlap, osa) {
 		resp.Error = NewJSStreamSubjectOverlapError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
 		return
@@ -8215,6 +9469,17 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 	// Make copy so to not change original.
 	rg := osa.copyGroup().Group
 
+	// Migrating the storage has the replicas move their messages and logs over,
+	// which older servers would not do.
+	if newCfg.Storage != osa.Config.Storage {
+		if !s.peersSupportApiLevel(rg.Peers, 5) {
+			resp.Error = NewJSClusterPeersApiLevelError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+			return
+		}
+		rg.Storage = newCfg.Storage
+	}
+
 	// Check for a move request.
 	var isMoveRequest, isMoveCancel bool
 	if lPeerSet := len(peerSet); lPeerSet > 0 {
@@ -8231,7 +9496,16 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 			}
 		}
 	} else {
-		isMoveRequest = newCfg.Placement != nil && !reflect.DeepEqual(osa.Config.Placement, newCfg.Placement)
+		isMoveRequest = isPlacementMove(osa.Config.Placement, newCfg.Placement)
+	}
+
+	// If not moving, a preferred server must be part of the current peer set.
+	if !isMoveRequest && newCfg.Placement != nil && newCfg.Placement.Preferred != _EMPTY_ {
+		if !slices.Contains(s.peerSetToNames(rg.Peers), newCfg.Placement.Preferred) {
+			resp.Error = NewJSClusterNoPeersError(fmt.Errorf("preferred server %q not in peer set", newCfg.Placement.Preferred))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+			return
+		}
 	}
 
 	// Check for replica changes.
@@ -8498,15 +9772,36 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 		rg.Preferred = _EMPTY_
 	}
 
+	if opts.validateOnly {
+		// Respond with the config the stream would be updated to.
+		resp.StreamInfo = &StreamInfo{
+			Created:   osa.Created,
+			Config:    *setDynamicStreamMetadata(newCfg),
+			Domain:    s.getOpts().JetStreamDomain,
+			TimeStamp: time.Now().UTC(),
+		}
+		s.sendAPIResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
 	syncSubject := osa.Sync
 	if syncSubject == _EMPTY_ {
 		syncSubject = syncSubjForStream()
 	}
 	sa := &streamAssignment{Group: rg, Sync: syncSubject, Created: osa.Created, Config: newCfg, Subject: subject, Reply: reply, Client: ci}
+	if opts.scale {
+		sa.ScaleRevert = opts.revert
+	} else if !isReplicaChange {
+		// Keep a pending scale revert unless the replication factor was changed by this update.
+		sa.ScaleRevert = osa.ScaleRevert
+	}
 	if err := meta.Propose(encodeUpdateStreamAssignment(sa)); err != nil {
 		return
 	}
 	cc.trackInflightStreamProposal(acc.Name, sa, false)
+	if sa.ScaleRevert != nil {
+		js.scheduleStreamScaleRevertLocked(sa.ScaleRevert.Deadline)
+	}
 
 	// Process any staged consumers.
 	for _, ca := range consumers {
@@ -8542,7 +9837,142 @@ func (s *Server) jsClusteredStreamDeleteRequest(ci *ClientInfo, acc *Account, st
 	}
 }
 
+func (s *Server) jsClusteredStreamRenameRequest(ci *ClientInfo, acc *Account, stream, name, subject, reply string, rmsg []byte) {
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if cc.meta == nil {
+		return
+	}
+
+	var resp = JSApiStreamRenameResponse{ApiResponse: ApiResponse{Type: JSApiStreamRenameResponseType}}
+	osa := js.streamAssignment(acc.Name, stream)
+	if osa == nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
+	cfg, apiErr := s.checkStreamRename(osa.Config, name)
+	if apiErr == nil {
+		if osa.unsupported != nil {
+			apiErr = NewJSStreamRenameNotAllowedError(errors.New("stream is not supported"))
+		} else if js.streamAssignmentOrInflight(acc.Name, name) != nil {
+			apiErr = NewJSStreamRenameNotAllowedError(errors.New("stream name already in use"))
+		} else if js.subjectsOverlap(acc.Name, cfg.Subjects, cfg.AllowSubjectOverlap, osa) {
+			apiErr = NewJSStreamSubjectOverlapError()
+		} else if osa.rename != nil {
+			apiErr = NewJSStreamRenameNotAllowedError(errors.New("stream rename in progress"))
+		} else if !s.peersSupportApiLevel(peerIDs(cc.meta.Peers()), 5) {
+			// All servers need to know how to apply the rename.
+			apiErr = NewJSClusterPeersApiLevelError()
+		} else {
+			for sa := range js.streamAssignmentsOrInflightSeq(acc.Name) {
+				if streamReferences(sa.Config, stream) {
+					apiErr = NewJSStreamRenameNotAllowedError(fmt.Errorf("stream is referenced by stream %q", sa.Config.Name))
+					break
+				}
+			}
+		}
+	}
+	if apiErr != nil {
+		resp.Error = apiErr
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
+	sr := &streamRename{Client: ci.forProposal(), Stream: stream, Config: cfg, Subject: subject, Reply: reply}
+	// Track the rename before proposing it, peers may report back before we applied it ourselves.
+	peers := make(map[string]struct{}, len(osa.Group.Peers))
+	for _, peer := range osa.Group.Peers {
+		peers[peer] = struct{}{}
+	}
+	pr := &pendingStreamRename{sr: sr, ocfg: osa.Config, peers: peers, quorum: len(peers)/2 + 1}
+	pr.tmr = time.AfterFunc(streamRenameTimeout, func() { js.expireStreamRename(acc.Name, pr) })
+	osa.rename = pr
+	cc.meta.Propose(encodeStreamRename(sr))
+}
+
 // Process a clustered purge request.
+// jsClusteredStreamRebuildTotalsRequest proposes a recount of the stream totals
+// so that every replica rebuilds its own store.
+func (s *Server) jsClusteredStreamRebuildTotalsRequest(ci *ClientInfo, acc *Account, stream, subject, reply string, rmsg []byte) {
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	var resp = JSApiStreamRebuildTotalsResponse{ApiResponse: ApiResponse{Type: JSApiStreamRebuildTotalsResponseType}}
+	sa := js.streamAssignment(acc.Name, stream)
+	if sa == nil || sa.Group == nil || sa.Group.node == nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+	// Older servers don't know about this entry and would not be able to apply it.
+	if !s.peersSupportApiLevel(sa.Group.Peers, 5) {
+		resp.Error = NewJSClusterPeersApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+	rt := &streamRebuildTotals{Client: ci, Stream: stream, Subject: subject, Reply: reply}
+	sa.Group.node.Propose(encodeStreamRebuildTotals(rt))
+}
+
+// jsClusteredStreamReserveRequest proposes a capacity reservation, so that every
+// replica tracks it and checks publishes using it the same way.
+func (s *Server) jsClusteredStreamReserveRequest(ci *ClientInfo, acc *Account, mset *stream, stream, subject, reply string, rmsg []byte, req *JSApiStreamReserveRequest) {
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	var resp = JSApiStreamReserveResponse{ApiResponse: ApiResponse{Type: JSApiStreamReserveResponseType}}
+	sa := js.streamAssignment(acc.Name, stream)
+	if sa == nil || sa.Group == nil || sa.Group.node == nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+	// Older servers don't know about this entry and would not be able to apply it.
+	if !s.peersSupportApiLevel(sa.Group.Peers, 5) {
+		resp.Error = NewJSClusterPeersApiLevelError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+	// Replicas reserve without checking the account limits, to not diverge on their own view of the usage.
+	mset.mu.RLock()
+	jsa, tier, stype, replicas := mset.jsa, mset.tier, mset.stype, mset.cfg.Replicas
+	mset.mu.RUnlock()
+	if req.Bytes > 0 && !jsa.canReserveStorage(tier, stype, replicas, int64(req.Bytes)) {
+		resp.Error = NewJSStreamReservationExceedsLimitsError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+	sr := &streamReservation{
+		Client:  ci,
+		Stream:  stream,
+		ID:      nuid.Next(),
+		Msgs:    req.Msgs,
+		Bytes:   req.Bytes,
+		Expires: time.Now().Add(req.TTL).UTC(),
+		Subject: subject,
+		Reply:   reply,
+	}
+	sa.Group.node.Propose(encodeStreamReservation(reserveCapacityOp, sr))
+}
+
 func (s *Server) jsClusteredStreamPurgeRequest(
 	ci *ClientInfo,
 	acc *Account,
@@ -8641,6 +10071,7 @@ func (s *Server) jsClusteredStreamRestoreRequest(
 	sa := &streamAssignment{Group: rg, Sync: syncSubjForStream(), Config: &cfg, Subject: subject, Reply: reply, Client: ci, Created: time.Now().UTC()}
 	// Now add in our restore state and pre-select a peer to handle the actual receipt of the snapshot.
 	sa.Restore = &req.State
+	sa.ConsumerReset = req.ConsumerReset
 	if err := cc.meta.Propose(encodeAddStreamAssignment(sa)); err == nil {
 		cc.trackInflightStreamProposal(ci.serviceAccount(), sa, false)
 	}
@@ -9018,6 +10449,58 @@ LOOP:
 	s.sendAPIResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(resp))
 }
 
+// jsClusteredStreamConsumerLagRequest computes the lag of the consumers of a stream from
+// the delivered state reported by each of their leaders. Consumers that didn't respond
+// in time are reported as missing and not included.
+func (s *Server) jsClusteredStreamConsumerLagRequest(ci *ClientInfo, acc *Account, mset *stream, subject, reply string, rmsg []byte) {
+	defer s.grWG.Done()
+
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+
+	stream := mset.name()
+	var consumers []*consumerAssignment
+	js.mu.RLock()
+	if sa := js.streamAssignment(acc.Name, stream); sa != nil {
+		for _, ca := range sa.consumers {
+			if ca.Config != nil && !ca.Config.Direct {
+				consumers = append(consumers, ca)
+			}
+		}
+	}
+	js.mu.RUnlock()
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		delivered = make(map[string]uint64, len(consumers))
+		missing   []string
+	)
+	for _, ca := range consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := sysRequest[ConsumerInfo](s, clusterConsumerInfoT, ca.Client.serviceAccount(), stream, ca.Name)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil || info == nil {
+				missing = append(missing, ca.Name)
+				return
+			}
+			delivered[ca.Name] = info.Delivered.Stream
+		}()
+	}
+	wg.Wait()
+
+	var resp = JSApiStreamConsumerLagResponse{ApiResponse: ApiResponse{Type: JSApiStreamConsumerLagResponseType}}
+	resp.Consumers, resp.MaxLag, resp.TotalLag, resp.MaxLagConsumer = mset.consumerLag(delivered)
+	slices.Sort(missing)
+	resp.Missing = missing
+	s.sendAPIResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(resp))
+}
+
 func encodeStreamPurge(sp *streamPurge) []byte {
 	var bb bytes.Buffer
 	bb.WriteByte(byte(purgeStreamOp))
@@ -9031,6 +10514,50 @@ func decodeStreamPurge(buf []byte) (*streamPurge, error) {
 	return &sp, err
 }
 
+func encodeStreamRebuildTotals(rt *streamRebuildTotals) []byte {
+	var bb bytes.Buffer
+	bb.WriteByte(byte(rebuildTotalsOp))
+	json.NewEncoder(&bb).Encode(rt)
+	return bb.Bytes()
+}
+
+func decodeStreamRebuildTotals(buf []byte) (*streamRebuildTotals, error) {
+	var rt streamRebuildTotals
+	err := json.Unmarshal(buf, &rt)
+	return &rt, err
+}
+
+func encodeStreamReservation(op entryOp, sr *streamReservation) []byte {
+	var bb bytes.Buffer
+	bb.WriteByte(byte(op))
+	json.NewEncoder(&bb).Encode(sr)
+	return bb.Bytes()
+}
+
+func decodeStreamReservation(buf []byte) (*streamReservation, error) {
+	var sr streamReservation
+	err := json.Unmarshal(buf, &sr)
+	return &sr, err
+}
+
+func encodeStreamRename(sr *streamRename) []byte {
+	var bb bytes.Buffer
+	bb.WriteByte(byte(renameStreamOp))
+	json.NewEncoder(&bb).Encode(sr)
+	return bb.Bytes()
+}
+
+func decodeStreamRename(buf []byte) (*streamRename, error) {
+	var sr streamRename
+	if err := json.Unmarshal(buf, &sr); err != nil {
+		return nil, err
+	}
+	if sr.Config == nil {
+		return nil, errors.New("missing stream config")
+	}
+	return &sr, nil
+}
+
 func (s *Server) jsClusteredConsumerDeleteRequest(ci *ClientInfo, acc *Account, stream, consumer, subject, reply string, rmsg []byte) {
 	js, cc := s.getJetStreamCluster()
 	if js == nil || cc == nil {
@@ -9249,7 +10776,9 @@ func (cc *jetStreamCluster) createGroupForConsumer(cfg *ConsumerConfig, sa *stre
 }
 
 // jsClusteredConsumerRequest is first point of entry to create a consumer in clustered mode.
-func (s *Server) jsClusteredConsumerRequest(ci *ClientInfo, acc *Account, subject, reply string, rmsg []byte, stream string, cfg *ConsumerConfig, action ConsumerAction, pedantic bool) {
+// The optional state is only used when creating a new consumer, it is applied
+// by every member once the consumer is created.
+func (s *Server) jsClusteredConsumerRequest(ci *ClientInfo, acc *Account, subject, reply string, rmsg []byte, stream string, cfg *ConsumerConfig, action ConsumerAction, pedantic, validateOnly bool, state *ConsumerState) {
 	js, cc := s.getJetStreamCluster()
 	if js == nil || cc == nil {
 		return
@@ -9274,7 +10803,7 @@ func (s *Server) jsClusteredConsumerRequest(ci *ClientInfo, acc *Account, subjec
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
 		return
 	}
-	selectedLimits, _, _, apiErr := acc.selectLimits(cfg.replicas(&streamCfg))
+	selectedLimits, tier, _, apiErr := acc.selectLimits(cfg.replicas(&streamCfg))
 	if apiErr != nil {
 		resp.Error = apiErr
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
@@ -9351,6 +10880,17 @@ func (s *Server) jsClusteredConsumerRequest(ci *ClientInfo, acc *Account, subjec
 		}
 	}
 
+	// Check for max consumers across all streams of the account, if a limit is set.
+	if (action == ActionCreate || action == ActionCreateOrUpdate) && selectedLimits.MaxTotalConsumers > 0 && !cfg.Direct && !cfg.Sourcing {
+		if oname == _EMPTY_ || js.consumerAssignmentOrInflight(acc.Name, stream, oname) == nil {
+			if js.tieredConsumerCount(acc.Name, tier, cfg.replicas(&streamCfg)) >= selectedLimits.MaxTotalConsumers {
+				resp.Error = NewJSMaximumTotalConsumersLimitError()
+				s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+				return
+			}
+		}
+	}
+
 	// Also short circuit if DeliverLastPerSubject is set with no FilterSubject.
 	if cfg.DeliverPolicy == DeliverLastPerSubject {
 		if cfg.FilterSubject == _EMPTY_ && len(cfg.FilterSubjects) == 0 {
@@ -9392,7 +10932,7 @@ func (s *Server) jsClusteredConsumerRequest(ci *ClientInfo, acc *Account, subjec
 				cfg.OptStartTime = ca.Config.OptStartTime
 			}
 
-			if action == ActionCreate && !reflect.DeepEqual(cfg, ca.Config) {
+			if action == ActionCreate && (state != nil || !reflect.DeepEqual(cfg, ca.Config)) {
 				resp.Error = NewJSConsumerAlreadyExistsError()
 				s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
 				return
@@ -9519,6 +11059,7 @@ func (s *Server) jsClusteredConsumerRequest(ci *ClientInfo, acc *Account, subjec
 			Reply:   reply,
 			Client:  ci,
 			Created: time.Now().UTC(),
+			State:   state,
 		}
 	} else {
 		// If the consumer already exists then don't allow updating the PauseUntil, just set
@@ -9606,6 +11147,18 @@ func (s *Server) jsClusteredConsumerRequest(ci *ClientInfo, acc *Account, subjec
 		ca = nca
 	}
 
+	if validateOnly {
+		// Respond with the config the consumer would be created or updated with.
+		resp.ConsumerInfo = &ConsumerInfo{
+			Stream:    stream,
+			Name:      ca.Name,
+			Config:    setDynamicConsumerMetadata(ca.Config),
+			TimeStamp: time.Now().UTC(),
+		}
+		s.sendAPIResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
 	// Do formal proposal.
 	if err := cc.meta.Propose(encodeAddConsumerAssignment(ca)); err == nil {
 		cc.trackInflightConsumerProposal(acc.Name, stream, ca, false)
@@ -9900,6 +11453,37 @@ type streamSnapshot struct {
 	Deleted  []uint64 `json:"deleted,omitempty"`
 }
 
+// Identifies a stream snapshot that holds the stream's capacity reservations
+// ahead of the binary encoded stream state.
+const streamSnapshotReservationsMagic = uint8(43)
+
+// encodeStreamSnapshotReservations prefixes the encoded stream state with the reservations.
+func encodeStreamSnapshotReservations(state []byte, rsvs []*streamReservation) []byte {
+	b, _ := json.Marshal(rsvs)
+	buf := make([]byte, 1, 1+binary.MaxVarintLen64+len(b)+len(state))
+	buf[0] = streamSnapshotReservationsMagic
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	buf = append(buf, b...)
+	return append(buf, state...)
+}
+
+// decodeStreamSnapshotReservations returns the reservations and encoded stream state of a snapshot.
+func decodeStreamSnapshotReservations(buf []byte) ([]byte, []*streamReservation, error) {
+	if len(buf) < 1 || buf[0] != streamSnapshotReservationsMagic {
+		return nil, nil, ErrBadStreamStateEncoding
+	}
+	l, n := binary.Uvarint(buf[1:])
+	if n <= 0 || l > uint64(len(buf)-1-n) {
+		return nil, nil, ErrCorruptStreamState
+	}
+	bi := 1 + n
+	var rsvs []*streamReservation
+	if err := json.Unmarshal(buf[bi:bi+int(l)], &rsvs); err != nil {
+		return nil, nil, ErrCorruptStreamState
+	}
+	return buf[bi+int(l):], rsvs, nil
+}
+
 // Grab a snapshot of a stream for clustered mode.
 func (mset *stream) stateSnapshot() []byte {
 	mset.mu.RLock()
@@ -9916,6 +11500,16 @@ func (mset *stream) stateSnapshotLocked() []byte {
 		if err != nil {
 			return nil
 		}
+		// Reservations are only possible when all peers support API level 5,
+		// so only then can the snapshot carry them.
+		if len(mset.reservations) > 0 {
+			rsvs := make([]*streamReservation, 0, len(mset.reservations))
+			for _, r := range mset.reservations {
+				rsvs = append(rsvs, &streamReservation{Stream: mset.cfg.Name, ID: r.id, Msgs: r.msgs, Bytes: r.bytes, Expires: r.expires})
+			}
+			slices.SortFunc(rsvs, func(a, b *streamReservation) int { return strings.Compare(a.ID, b.ID) })
+			snap = encodeStreamSnapshotReservations(snap, rsvs)
+		}
 		return snap
 	}
 
@@ -9955,6 +11549,9 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 	s, js, jsa, st, r, tierName, outq, node := mset.srv, mset.js, mset.jsa, mset.cfg.Storage, mset.cfg.Replicas, mset.tier, mset.outq, mset.node
 	maxMsgSize, lseq := int(mset.cfg.MaxMsgSize), mset.lseq
 	isLeader, isSealed, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules := mset.isLeader(), mset.cfg.Sealed, mset.cfg.AllowRollup, mset.cfg.DenyPurge, mset.cfg.AllowMsgTTL, mset.cfg.AllowMsgCounter, mset.cfg.AllowMsgSchedules
+	subjectSeq, writeConcern := mset.cfg.SubjectSequence, mset.cfg.WriteConcern
+	roErr := mset.readOnlyErr()
+	reservedBytes := mset.hasBytesReservation(hdr)
 
 	// Apply the input subject transform if any
 	csubject := subject
@@ -10000,6 +11597,16 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 		return NewJSStreamSealedError()
 	}
 
+	// Bail here if a storage error made us read-only.
+	if roErr != nil {
+		err := NewJSStreamReadOnlyError(roErr)
+		if canRespond {
+			b, _ := json.Marshal(&JSPubAckResponse{PubAck: &PubAck{Stream: name}, Error: err})
+			outq.send(newJSPubMsg(reply, _EMPTY_, _EMPTY_, nil, b, nil, 0))
+		}
+		return err
+	}
+
 	// Check here pre-emptively if we have exceeded this server limits.
 	if js.limitsExceeded(stype) {
 		s.resourcesExceededError(stype)
@@ -10015,7 +11622,8 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 	}
 
 	// Check here pre-emptively if we have exceeded our account limits.
-	if exceeded, err := jsa.wouldExceedLimits(st, tierName, r, csubject, hdr, msg); exceeded {
+	// Reserved bytes were already checked against the account limits.
+	if exceeded, err := jsa.wouldExceedLimits(st, tierName, r, csubject, hdr, msg); exceeded && !reservedBytes {
 		if err == nil {
 			err = NewJSAccountResourcesExceededError()
 		}
@@ -10052,13 +11660,27 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 		lseq = recalculateClusteredSeq(mset, true)
 	}
 
+	// If configured, bound how far proposals can run ahead of what we have applied.
+	// A stream that is slow to apply pushes back on its own publishers this way.
+	if maxLag := atomic.LoadInt64(&js.applyBacklog); maxLag > 0 && mset.clseq-(lseq+mset.clfs) >= uint64(maxLag) {
+		mset.clMu.Unlock()
+		err := NewJSStreamTooManyRequestsError()
+		if canRespond {
+			var resp = &JSPubAckResponse{PubAck: &PubAck{Stream: name}}
+			resp.Error = err
+			response, _ = json.Marshal(resp)
+			outq.sendMsg(reply, response)
+		}
+		return err
+	}
+
 	var (
 		dseq   uint64
 		apiErr *ApiError
 		err    error
 	)
 	diff := &batchStagedDiff{}
-	if hdr, msg, dseq, apiErr, err = checkMsgHeadersPreClusteredProposal(diff, mset, csubject, subject, hdr, msg, sourced, name, jsa, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules, discard, discardNewPer, maxMsgSize, maxMsgs, maxMsgsPer, maxBytes); err != nil {
+	if hdr, msg, dseq, apiErr, err = checkMsgHeadersPreClusteredProposal(diff, mset, csubject, subject, hdr, msg, sourced, name, jsa, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules, subjectSeq, discard, discardNewPer, maxMsgSize, maxMsgs, maxMsgsPer, maxBytes); err != nil {
 		mset.clMu.Unlock()
 		if err == errMsgIdDuplicate && dseq > 0 {
 			var buf [256]byte
@@ -10077,6 +11699,22 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 		return err
 	}
 
+	// With the fast write concern we acknowledge as soon as the message is proposed,
+	// so the reply is left out of the proposal to not acknowledge again once applied.
+	if canRespond && writeConcern == WriteConcernFast {
+		err = commitSingleMsg(diff, mset, subject, _EMPTY_, hdr, msg, name, jsa, mt, node, r, lseq)
+		seq := mset.clseq
+		mset.clMu.Unlock()
+		if err == nil {
+			var buf [256]byte
+			pubAck := append(buf[:0], mset.pubAck...)
+			response = append(pubAck, strconv.FormatUint(seq, 10)...)
+			response = append(response, '}')
+			outq.sendMsg(reply, response)
+		}
+		return err
+	}
+
 	err = commitSingleMsg(diff, mset, subject, reply, hdr, msg, name, jsa, mt, node, r, lseq)
 	mset.clMu.Unlock()
 	return err
@@ -10230,6 +11868,42 @@ func (mset *stream) clearCatchingUp() {
 	mset.catchup.Store(false)
 }
 
+// checkPreferredLeader transfers leadership to the preferred server from
+// the stream placement, if any, when that server is a current member of
+// the group. Should be called by the stream leader.
+func (mset *stream) checkPreferredLeader() {
+	mset.mu.RLock()
+	node, s, accName, name := mset.node, mset.srv, mset.acc.Name, mset.cfg.Name
+	var preferred string
+	if mset.cfg.Placement != nil {
+		preferred = mset.cfg.Placement.Preferred
+	}
+	mset.mu.RUnlock()
+
+	if node == nil || preferred == _EMPTY_ || preferred == s.Name() || !node.Leader() {
+		return
+	}
+	ourID := node.ID()
+	for _, p := range node.Peers() {
+		if p.ID == ourID {
+			continue
+		}
+		si, ok := s.nodeToInfo.Load(p.ID)
+		if !ok || si == nil {
+			continue
+		}
+		ni := si.(nodeInfo)
+		if ni.name != preferred {
+			continue
+		}
+		if !ni.offline && p.Current && time.Since(p.Last) <= 2*hbInterval {
+			s.Noticef("Transferring stream leader for '%s > %s' to preferred server %q", accName, name, preferred)
+			node.StepDown(p.ID)
+		}
+		return
+	}
+}
+
 func (mset *stream) isCatchingUp() bool {
 	return mset.catchup.Load()
 }
@@ -10322,6 +11996,16 @@ func (mset *stream) processSnapshot(snap *StreamReplicatedState, index uint64) (
 	mset.setCatchingUp()
 	defer mset.clearCatchingUp()
 
+	// Report our progress against what we had when we started.
+	startSeq, total := sreq.FirstSeq-1, sreq.LastSeq-sreq.FirstSeq+1
+	trackProgress := func() {
+		mset.mu.RLock()
+		lseq := mset.lseq
+		mset.mu.RUnlock()
+		n.TrackSnapshotCatchup(min(lseq-min(lseq, startSeq), total), total)
+	}
+	trackProgress()
+
 	var sub *subscription
 	var err error
 
@@ -10491,6 +12175,7 @@ RETRY:
 						// We MUST ensure all data is flushed up to this point, if the store hadn't already.
 						// Because the snapshot needs to represent what has been persisted.
 						err = mset.flushAllPending()
+						trackProgress()
 						if err == nil {
 							s.Noticef("Catchup for stream '%s > %s' complete (took %v)", mset.account(), mset.name(), time.Since(start).Round(time.Millisecond))
 						} else {
@@ -10555,6 +12240,7 @@ RETRY:
 			}
 			notActive.Reset(activityInterval)
 			msgsQ.recycle(&mrecs)
+			trackProgress()
 		case <-notActive.C:
 			if mrecs := msgsQ.pop(); len(mrecs) > 0 {
 				mrec := mrecs[0]
@@ -10680,6 +12366,190 @@ func (mset *stream) handleClusterSyncRequest(sub *subscription, c *client, _ *Ac
 	mset.srv.startGoRoutine(func() { mset.runCatchup(reply, &sreq) })
 }
 
+// sendStoredSeq lets the leader know up to which sequence we have stored messages,
+// so it can deliver them to consumers once a quorum of replicas has stored them.
+// We also report the quorum sequence we know of, so the leader can signal it if we're behind.
+func (mset *stream) sendStoredSeq() {
+	mset.mu.Lock()
+	defer mset.mu.Unlock()
+	if !mset.cfg.DeliverAfterCommit || mset.node == nil || mset.sa == nil || mset.closed.Load() {
+		return
+	}
+	// Followers that never were the leader start holding back delivery here.
+	if mset.quorumSub == nil {
+		mset.setupDeliverAfterCommit(false)
+	}
+	id := mset.node.ID()
+	msg := make([]byte, 16, 16+len(id))
+	binary.LittleEndian.PutUint64(msg, mset.lseq)
+	binary.LittleEndian.PutUint64(msg[8:], mset.qseq.Load())
+	msg = append(msg, id...)
+	mset.srv.sendInternalMsgLocked(fmt.Sprintf(clusterStreamReportT, mset.sa.Group.Name), _EMPTY_, nil, msg)
+}
+
+// handleClusterStoredSeq processes the stored sequence reported by a replica.
+func (mset *stream) handleClusterStoredSeq(sub *subscription, c *client, _ *Account, subject, reply string, msg []byte) {
+	if len(msg) <= 16 {
+		return
+	}
+	seq, qseq, peer := binary.LittleEndian.Uint64(msg), binary.LittleEndian.Uint64(msg[8:]), string(msg[16:])
+	mset.mu.Lock()
+	if mset.stored == nil {
+		mset.mu.Unlock()
+		return
+	}
+	if seq > mset.stored[peer] {
+		mset.stored[peer] = seq
+	}
+	mset.mu.Unlock()
+	if !mset.checkQuorumStoredSeq() && qseq < mset.qseq.Load() {
+		mset.sendQuorumSeq()
+	}
+}
+
+// checkQuorumStoredSeq will update the sequence stored by a quorum of replicas,
+// and signal it to our consumers and followers. Returns whether it was updated.
+func (mset *stream) checkQuorumStoredSeq() bool {
+	mset.mu.RLock()
+	if !mset.dac.Load() || mset.node == nil || mset.stored == nil {
+		mset.mu.RUnlock()
+		return false
+	}
+	id, quorum := mset.node.ID(), mset.node.ClusterSize()/2+1
+	seqs := []uint64{mset.lseq}
+	for _, p := range mset.node.Peers() {
+		if p.ID != id {
+			seqs = append(seqs, mset.stored[p.ID])
+		}
+	}
+	mset.mu.RUnlock()
+
+	if len(seqs) < quorum {
+		return false
+	}
+	slices.SortFunc(seqs, func(i, j uint64) int { return cmp.Compare(j, i) })
+	if !mset.advanceQuorumSeq(seqs[quorum-1]) {
+		return false
+	}
+	mset.sendQuorumSeq()
+	return true
+}
+
+// sendQuorumSeq signals the sequence stored by a quorum of replicas to our followers.
+// Only the leader signals, followers can't know what other replicas stored.
+func (mset *stream) sendQuorumSeq() {
+	mset.mu.RLock()
+	defer mset.mu.RUnlock()
+	if mset.stored == nil || mset.sa == nil || mset.closed.Load() {
+		return
+	}
+	var msg [8]byte
+	binary.LittleEndian.PutUint64(msg[:], mset.qseq.Load())
+	mset.srv.sendInternalMsgLocked(fmt.Sprintf(clusterStreamStoredT, mset.sa.Group.Name), _EMPTY_, nil, msg[:])
+}
+
+// handleClusterQuorumSeq processes the quorum sequence signaled by the leader.
+func (mset *stream) handleClusterQuorumSeq(sub *subscription, c *client, _ *Account, subject, reply string, msg []byte) {
+	if len(msg) != 8 || !mset.dac.Load() {
+		return
+	}
+	mset.advanceQuorumSeq(binary.LittleEndian.Uint64(msg))
+}
+
+// advanceQuorumSeq moves the quorum sequence forward, and kicks our consumers
+// if more messages can now be delivered. Returns whether it was moved.
+func (mset *stream) advanceQuorumSeq(qseq uint64) bool {
+	for {
+		cur := mset.qseq.Load()
+		if qseq <= cur {
+			return false
+		}
+		if mset.qseq.CompareAndSwap(cur, qseq) {
+			break
+		}
+	}
+	for _, o := range mset.getPublicConsumers() {
+		o.signalNewMessages()
+	}
+	return true
+}
+
+// seqIndex records the last sequence of a stream once the entry at a given Raft log index was applied.
+type seqIndex struct {
+	index uint64
+	seq   uint64
+}
+
+// Maximum number of entries kept to map stream sequences back to Raft log indexes.
+const maxSeqIndexes = 1024
+
+// trackSeqIndex records our last sequence after applying the entry at the given Raft log index.
+func (mset *stream) trackSeqIndex(index uint64) {
+	mset.mu.Lock()
+	defer mset.mu.Unlock()
+	lseq := mset.lseq
+	// If our sequences were truncated, drop anything that no longer applies.
+	for n := len(mset.seqIdx); n > 0 && mset.seqIdx[n-1].seq > lseq; n-- {
+		mset.seqIdx = mset.seqIdx[:n-1]
+	}
+	if n := len(mset.seqIdx); n > 0 && mset.seqIdx[n-1].seq == lseq {
+		return
+	}
+	if len(mset.seqIdx) >= maxSeqIndexes {
+		mset.seqIdx = append(mset.seqIdx[:0], mset.seqIdx[maxSeqIndexes/2:]...)
+	}
+	mset.seqIdx = append(mset.seqIdx, seqIndex{index, lseq})
+}
+
+// raftIndexForSeq returns the Raft log index that stored the message with the given sequence.
+// If the message is older than what we track, this returns an upper bound instead.
+// Lock should be held.
+func (mset *stream) raftIndexForSeq(seq uint64) uint64 {
+	i, _ := slices.BinarySearchFunc(mset.seqIdx, seq, func(si seqIndex, seq uint64) int {
+		return cmp.Compare(si.seq, seq)
+	})
+	if i < len(mset.seqIdx) {
+		return mset.seqIdx[i].index
+	}
+	// Nothing tracked yet, e.g. after a restart, everything we have was applied.
+	if mset.node == nil {
+		return 0
+	}
+	_, _, applied := mset.node.Progress()
+	return applied
+}
+
+// replicationStatus reports which replicas have stored the entry at the given Raft log index,
+// and whether it is committed. Only the stream leader can report on its replicas.
+// Lock should be held.
+func (mset *stream) replicationStatus(index uint64) (*MsgReplication, error) {
+	s, node := mset.srv, mset.node
+	if node == nil {
+		// Not replicated, so what we have stored is all there is.
+		return &MsgReplication{
+			Committed: true,
+			Stored:    1,
+			Replicas:  []*MsgReplicaStatus{{Name: s.Name(), Leader: true, Stored: true}},
+		}, nil
+	}
+	mi, err := node.MatchIndexes()
+	if err != nil {
+		return nil, err
+	}
+	_, commit, _ := node.Progress()
+	mr := &MsgReplication{Committed: commit >= index}
+	id := node.ID()
+	for _, p := range node.Peers() {
+		rs := &MsgReplicaStatus{Name: s.serverNameForNode(p.ID), Leader: p.ID == id, Stored: mi[p.ID] >= index}
+		if rs.Stored {
+			mr.Stored++
+		}
+		mr.Replicas = append(mr.Replicas, rs)
+	}
+	slices.SortFunc(mr.Replicas, func(i, j *MsgReplicaStatus) int { return cmp.Compare(i.Name, j.Name) })
+	return mr, nil
+}
+
 // Lock should be held.
 func (js *jetStream) offlineClusterInfo(rg *raftGroup) *ClusterInfo {
 	s := js.srv
@@ -10954,6 +12824,34 @@ func (s *Server) cbKickChan() <-chan struct{} {
 	return s.gcbKick
 }
 
+// Waits for a slot among the ones limiting the number of catchups this server
+// runs concurrently. Returns the semaphore to release the slot to, which is nil
+// if not limited, and false if no slot was obtained within `maxWait` or if one
+// of the quit channels was closed.
+func (s *Server) gcbAcquireSlot(qch <-chan struct{}, maxWait time.Duration) (chan struct{}, bool) {
+	s.gcbMu.RLock()
+	sem := s.gcbSem
+	s.gcbMu.RUnlock()
+	if sem == nil {
+		return nil, true
+	}
+	select {
+	case sem <- struct{}{}:
+		return sem, true
+	default:
+	}
+	t := time.NewTimer(maxWait)
+	defer t.Stop()
+	select {
+	case sem <- struct{}{}:
+		return sem, true
+	case <-t.C:
+	case <-qch:
+	case <-s.quitCh:
+	}
+	return nil, false
+}
+
 func (mset *stream) runCatchup(sendSubject string, sreq *streamSyncRequest) {
 	s := mset.srv
 	defer s.grWG.Done()
@@ -11039,6 +12937,25 @@ func (mset *stream) runCatchup(sendSubject string, sreq *streamSyncRequest) {
 		}
 	}
 
+	// Wait for our turn if the number of concurrent catchups is limited. If we
+	// waited longer than the remote's activity interval, it will have retried
+	// already, so abandon this request.
+	mset.mu.RLock()
+	qch := mset.qch
+	mset.mu.RUnlock()
+	if qch == nil {
+		return
+	}
+	sem, ok := s.gcbAcquireSlot(qch, activityInterval)
+	if !ok {
+		s.Debugf("Catchup for stream '%s > %s' abandoned while waiting for a catchup slot", mset.account(), mset.name())
+		return
+	}
+	if sem != nil {
+		defer func() { <-sem }()
+	}
+	notActive.Reset(activityInterval)
+
 	start := time.Now()
 	mset.setCatchupPeer(sreq.Peer, last-seq)
 
@@ -11221,14 +13138,6 @@ func (mset *stream) runCatchup(sendSubject string, sreq *streamSyncRequest) {
 		return true
 	}
 
-	// Check is this stream got closed.
-	mset.mu.RLock()
-	qch := mset.qch
-	mset.mu.RUnlock()
-	if qch == nil {
-		return
-	}
-
 	// Run as long as we are still active and need catchup.
 	// FIXME(dlc) - Purge event? Stream delete?
 	retryTimer := time.NewTimer(500 * time.Millisecond)
@@ -11305,8 +13214,14 @@ func syncSubject(pre string) string {
 }
 
 const (
-	clusterStreamInfoT   = "$JSC.SI.%s.%s"
-	clusterConsumerInfoT = "$JSC.CI.%s.%s.%s"
-	jsaUpdatesSubT       = "$JSC.ARU.%s.*"
-	jsaUpdatesPubT       = "$JSC.ARU.%s.%s"
+	clusterStreamInfoT     = "$JSC.SI.%s.%s"
+	clusterConsumerInfoT   = "$JSC.CI.%s.%s.%s"
+	clusterStreamStoredT   = "$JSC.SS.%s"
+	clusterStreamReportT   = "$JSC.SR.%s"
+	jsaUpdatesSubT         = "$JSC.ARU.%s.*"
+	jsaUpdatesPubT         = "$JSC.ARU.%s.%s"
+	clusterKVTransaction   = "$JSC.KVTXN.*.*"
+	clusterKVTransactionT  = "$JSC.KVTXN.%s.%s"
+	clusterConsumerReplay  = "$JSC.REPLAY.*.*.*"
+	clusterConsumerReplayT = "$JSC.REPLAY.%s.%s.%s"
 )
@@ -5029,6 +5029,7 @@ func TestJetStreamClusterStreamAdvisories(t *testing.T) {
 		_, err = js.UpdateStream(&si.Config)
 		require_NoError(t, err)
 		checkAdv(t, sub, JSAdvisoryStreamUpdatedPre)
+		checkAdv(t, sub, JSAdvisoryStreamConfigChangedPre)
 
 		snapreq := &JSApiStreamSnapshotRequest{
 			DeliverSubject: nats.NewInbox(),
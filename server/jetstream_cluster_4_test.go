@@ -17,10 +17,12 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
 	"math/rand"
@@ -39,6 +41,7 @@ import (
 
 	"github.com/nats-io/jwt/v2"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
 	"github.com/nats-io/nuid"
 )
 
@@ -8372,3 +8375,3103 @@ func TestJetStreamClusterWorkQueueConsumerCreateRejectionNoOrphan(t *testing.T)
 		}
 	}
 }
+
+func TestJetStreamClusterStreamPreferredLeader(t *testing.T) {
+	old := preferredLeaderCheckInterval
+	preferredLeaderCheckInterval = 250 * time.Millisecond
+	defer func() { preferredLeaderCheckInterval = old }()
+
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, _ := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	preferred := c.servers[1].Name()
+	cfg := &StreamConfig{
+		Name:      "TEST",
+		Subjects:  []string{"foo"},
+		Storage:   FileStorage,
+		Replicas:  3,
+		Placement: &Placement{Preferred: preferred},
+	}
+	_, err := jsStreamCreate(t, nc, cfg)
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+
+	checkLeader := func(name string) {
+		t.Helper()
+		checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+			if sl := c.streamLeader(globalAccountName, "TEST"); sl == nil || sl.Name() != name {
+				return fmt.Errorf("expected stream leader to be %q, got %v", name, sl)
+			}
+			return nil
+		})
+	}
+	checkLeader(preferred)
+
+	// A step down moves leadership away, but it should come back.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	mset, err := sl.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	require_NoError(t, mset.raftNode().StepDown())
+	checkLeader(preferred)
+
+	// Fail the preferred server over, leadership has to go elsewhere.
+	sl.Shutdown()
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+	require_NotEqual(t, c.streamLeader(globalAccountName, "TEST").Name(), preferred)
+
+	// Once recovered, leadership should be re-homed to it.
+	rs := c.restartServer(sl)
+	c.waitOnServerCurrent(rs)
+	checkLeader(preferred)
+
+	// Changing the preferred server must not move the stream, only its leadership.
+	nc.Close()
+	nc, _ = jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+	preferred = c.servers[2].Name()
+	cfg.Placement.Preferred = preferred
+	_, err = jsStreamUpdate(t, nc, cfg)
+	require_NoError(t, err)
+	checkLeader(preferred)
+
+	// A preferred server outside of the peer set is rejected.
+	cfg.Placement.Preferred = "UNKNOWN"
+	_, err = jsStreamUpdate(t, nc, cfg)
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "not in peer set")
+
+	// Same on create for a server that can not be selected.
+	_, err = jsStreamCreate(t, nc, &StreamConfig{
+		Name:      "OTHER",
+		Subjects:  []string{"bar"},
+		Storage:   FileStorage,
+		Replicas:  3,
+		Placement: &Placement{Preferred: "UNKNOWN"},
+	})
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "preferred server not eligible")
+}
+
+func TestJetStreamClusterMaxConcurrentCatchups(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir:", "max_concurrent_catchups: 1, store_dir:", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	for _, s := range c.servers {
+		require_Equal(t, s.getOpts().JetStreamMaxConcurrentCatchups, 1)
+		require_Equal(t, cap(s.gcbSem), 1)
+	}
+
+	// Place all stream leaders on the same server.
+	leader, follower := c.servers[1], c.servers[2]
+	nc, js := jsClientConnect(t, leader)
+	defer nc.Close()
+
+	const numStreams = 8
+	for i := 0; i < numStreams; i++ {
+		_, err := jsStreamCreate(t, nc, &StreamConfig{
+			Name:      fmt.Sprintf("S%d", i),
+			Subjects:  []string{fmt.Sprintf("s.%d", i)},
+			Storage:   FileStorage,
+			Replicas:  3,
+			Placement: &Placement{Preferred: leader.Name()},
+		})
+		require_NoError(t, err)
+	}
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		for i := 0; i < numStreams; i++ {
+			if sl := c.streamLeader(globalAccountName, fmt.Sprintf("S%d", i)); sl != leader {
+				return fmt.Errorf("stream S%d leader is %v", i, sl)
+			}
+		}
+		return nil
+	})
+
+	// Take the follower down, add data and snapshot so that the follower
+	// needs a stream catchup for all streams when it comes back.
+	follower.Shutdown()
+	const numMsgs = 1000
+	payload := make([]byte, 1024)
+	for i := 0; i < numStreams; i++ {
+		subj := fmt.Sprintf("s.%d", i)
+		for j := 0; j < numMsgs; j++ {
+			_, err := js.PublishAsync(subj, payload)
+			require_NoError(t, err)
+		}
+		select {
+		case <-js.PublishAsyncComplete():
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Did not receive completion signal")
+		}
+	}
+	for i := 0; i < numStreams; i++ {
+		mset, err := leader.globalAccount().lookupStream(fmt.Sprintf("S%d", i))
+		require_NoError(t, err)
+		require_NoError(t, mset.raftNode().InstallSnapshot(mset.stateSnapshot(), false))
+	}
+
+	l := &captureNoticeLogger{}
+	leader.SetLogger(l, false, false)
+
+	// Occupy the only catchup slot, the follower must not make progress.
+	leader.gcbSem <- struct{}{}
+	follower = c.restartServer(follower)
+	streamMsgs := func(i int) uint64 {
+		mset, err := follower.globalAccount().lookupStream(fmt.Sprintf("S%d", i))
+		if err != nil {
+			return 0
+		}
+		return mset.state().Msgs
+	}
+	time.Sleep(time.Second)
+	for i := 0; i < numStreams; i++ {
+		require_Equal(t, streamMsgs(i), 0)
+	}
+
+	// Track how many catchups the leader runs concurrently.
+	var peak atomic.Int32
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if n := int32(len(leader.gcbSem)); n > peak.Load() {
+				peak.Store(n)
+			}
+			time.Sleep(50 * time.Microsecond)
+		}
+	}()
+
+	// Release the slot, catchups proceed one at a time.
+	<-leader.gcbSem
+	checkFor(t, 30*time.Second, 250*time.Millisecond, func() error {
+		for i := 0; i < numStreams; i++ {
+			if n := streamMsgs(i); n != numMsgs {
+				return fmt.Errorf("stream S%d has %d msgs on restarted server", i, n)
+			}
+		}
+		return nil
+	})
+	close(done)
+	wg.Wait()
+
+	require_True(t, peak.Load() <= 1)
+	require_Len(t, len(leader.gcbSem), 0)
+	var completed int
+	l.Lock()
+	for _, n := range l.notices {
+		if strings.HasPrefix(n, "Catchup for stream") && strings.Contains(n, "complete") {
+			completed++
+		}
+	}
+	l.Unlock()
+	require_Equal(t, completed, numStreams)
+}
+
+func TestJetStreamClusterMaxConcurrentSnapshots(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir:", "max_concurrent_snapshots: 2, store_dir:", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	for _, s := range c.servers {
+		require_Equal(t, s.getOpts().JetStreamMaxSnapshots, 2)
+		require_Equal(t, cap(s.snapSem), 2)
+	}
+
+	// Place all stream leaders on the same server.
+	leader := c.servers[1]
+	nc, js := jsClientConnect(t, leader)
+	defer nc.Close()
+
+	const numStreams = 8
+	for i := 0; i < numStreams; i++ {
+		_, err := jsStreamCreate(t, nc, &StreamConfig{
+			Name:      fmt.Sprintf("S%d", i),
+			Subjects:  []string{fmt.Sprintf("s.%d", i)},
+			Storage:   FileStorage,
+			Replicas:  3,
+			Placement: &Placement{Preferred: leader.Name()},
+		})
+		require_NoError(t, err)
+		_, err = js.Publish(fmt.Sprintf("s.%d", i), nil)
+		require_NoError(t, err)
+	}
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		for i := 0; i < numStreams; i++ {
+			if sl := c.streamLeader(globalAccountName, fmt.Sprintf("S%d", i)); sl != leader {
+				return fmt.Errorf("stream S%d leader is %v", i, sl)
+			}
+		}
+		return nil
+	})
+
+	// Occupy all snapshot slots, snapshots are then skipped without waiting.
+	leader.snapSem <- struct{}{}
+	leader.snapSem <- struct{}{}
+	for i := 0; i < numStreams; i++ {
+		mset, err := leader.globalAccount().lookupStream(fmt.Sprintf("S%d", i))
+		require_NoError(t, err)
+		errCh := make(chan error, 1)
+		go func() { errCh <- mset.raftNode().InstallSnapshot(mset.stateSnapshot(), true) }()
+		select {
+		case err := <-errCh:
+			require_Error(t, err, errSnapBusy)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Snapshot blocked on the snapshot limit")
+		}
+		n := mset.raftNode().(*raft)
+		n.RLock()
+		snapshotting := n.snapshotting
+		n.RUnlock()
+		require_False(t, snapshotting)
+	}
+
+	// The meta group is not limited.
+	mjs := leader.getJetStream()
+	snap, _, _, err := mjs.metaSnapshot()
+	require_NoError(t, err)
+	err = mjs.getMetaGroup().InstallSnapshot(snap, true)
+	require_True(t, err == nil || err == errNoSnapAvailable)
+
+	// Track how many snapshots the server writes concurrently.
+	var peak atomic.Int32
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if n := int32(len(leader.snapSem)); n > peak.Load() {
+				peak.Store(n)
+			}
+			time.Sleep(50 * time.Microsecond)
+		}
+	}()
+
+	// Release the slots and snapshot all streams at once, the ones that find
+	// all slots taken are skipped and succeed when retried.
+	<-leader.snapSem
+	<-leader.snapSem
+	errCh := make(chan error, numStreams)
+	for i := 0; i < numStreams; i++ {
+		mset, err := leader.globalAccount().lookupStream(fmt.Sprintf("S%d", i))
+		require_NoError(t, err)
+		go func() {
+			for {
+				err := mset.raftNode().InstallSnapshot(mset.stateSnapshot(), true)
+				if err != errSnapBusy {
+					errCh <- err
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+	}
+	for i := 0; i < numStreams; i++ {
+		select {
+		case err := <-errCh:
+			require_NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Only %d of %d snapshots completed", i, numStreams)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	require_True(t, peak.Load() <= 2)
+	require_Len(t, len(leader.snapSem), 0)
+}
+
+func TestJetStreamClusterMaxConcurrentSnapshotsRetried(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir:", "max_concurrent_snapshots: 1, store_dir:", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	const numStreams = 4
+	for i := 0; i < numStreams; i++ {
+		_, err := jsStreamCreate(t, nc, &StreamConfig{
+			Name:     fmt.Sprintf("S%d", i),
+			Subjects: []string{fmt.Sprintf("s.%d", i)},
+			Storage:  FileStorage,
+			Replicas: 3,
+		})
+		require_NoError(t, err)
+		_, err = js.AddConsumer(fmt.Sprintf("S%d", i), &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+		require_NoError(t, err)
+	}
+	c.waitOnAllCurrent()
+
+	// Occupy the snapshot slot of every server, and have every group ask for a
+	// snapshot after applying any entry.
+	var nodes []*raft
+	for _, s := range c.servers {
+		s.snapSem <- struct{}{}
+		for i := 0; i < numStreams; i++ {
+			mset, err := s.globalAccount().lookupStream(fmt.Sprintf("S%d", i))
+			require_NoError(t, err)
+			o := mset.lookupConsumer("C")
+			require_NotNil(t, o)
+			nodes = append(nodes, mset.raftNode().(*raft), o.raftNode().(*raft))
+		}
+	}
+	for _, n := range nodes {
+		n.Lock()
+		require_Equal(t, n.snapfile, _EMPTY_)
+		n.rto = time.Nanosecond
+		n.Unlock()
+	}
+	snapshotted := func() int {
+		var count int
+		for _, n := range nodes {
+			n.RLock()
+			if n.snapfile != _EMPTY_ {
+				count++
+			}
+			n.RUnlock()
+		}
+		return count
+	}
+
+	// Every group applies new entries, but the snapshots are skipped.
+	for i := 0; i < numStreams; i++ {
+		_, err := js.Publish(fmt.Sprintf("s.%d", i), nil)
+		require_NoError(t, err)
+		sub, err := js.PullSubscribe(fmt.Sprintf("s.%d", i), "C", nats.BindStream(fmt.Sprintf("S%d", i)))
+		require_NoError(t, err)
+		msgs, err := sub.Fetch(1, nats.MaxWait(2*time.Second))
+		require_NoError(t, err)
+		require_NoError(t, msgs[0].AckSync())
+	}
+	c.waitOnAllCurrent()
+	require_Equal(t, snapshotted(), 0)
+
+	// Once the slots are released, every group snapshots without any new entries,
+	// well before the next compaction interval.
+	for _, s := range c.servers {
+		<-s.snapSem
+	}
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		if count := snapshotted(); count != len(nodes) {
+			return fmt.Errorf("only %d of %d groups snapshotted", count, len(nodes))
+		}
+		return nil
+	})
+}
+
+func TestJetStreamClusterMaxApplyBacklog(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir:", "max_apply_backlog: 100, store_dir:", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	for _, s := range c.servers {
+		require_Equal(t, s.getOpts().JetStreamMaxApplyBacklog, 100)
+	}
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	streams := []string{"SLOW", "A", "B", "C"}
+	for _, name := range streams {
+		_, err := jsStreamCreate(t, nc, &StreamConfig{
+			Name:     name,
+			Subjects: []string{strings.ToLower(name)},
+			Storage:  FileStorage,
+			Replicas: 3,
+		})
+		require_NoError(t, err)
+	}
+
+	// Make applying to the slow stream on its leader deliberately slow,
+	// by repeatedly holding on to its store lock.
+	sl := c.streamLeader(globalAccountName, "SLOW")
+	mset, err := sl.globalAccount().lookupStream("SLOW")
+	require_NoError(t, err)
+	fs := mset.store.(*fileStore)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			fs.mu.Lock()
+			time.Sleep(50 * time.Millisecond)
+			fs.mu.Unlock()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// Returns the largest apply lag of a stream across all servers.
+	applyLag := func(name string) uint64 {
+		var lag uint64
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream(name)
+			require_NoError(t, err)
+			if _, commit, applied := mset.raftNode().Progress(); commit > applied {
+				lag = max(lag, commit-applied)
+			}
+		}
+		return lag
+	}
+
+	var slow []nats.PubAckFuture
+	for i := 0; i < 100; i++ {
+		var futures []nats.PubAckFuture
+		for j := 0; j < 10; j++ {
+			for _, name := range streams {
+				paf, err := js.PublishAsync(strings.ToLower(name), []byte("ok"))
+				require_NoError(t, err)
+				if name == "SLOW" {
+					slow = append(slow, paf)
+				} else {
+					futures = append(futures, paf)
+				}
+			}
+		}
+		// The other streams keep up while the slow one lags behind.
+		for _, paf := range futures {
+			select {
+			case <-paf.Ok():
+			case err := <-paf.Err():
+				t.Fatalf("Unexpected error: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Did not receive ack")
+			}
+		}
+		for _, name := range streams[1:] {
+			if lag := applyLag(name); lag > 10 {
+				t.Fatalf("Expected apply lag of stream %q to stay low, got %d", name, lag)
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	select {
+	case <-js.PublishAsyncComplete():
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Did not receive all acks")
+	}
+
+	// The slow stream pushed back on its publishers.
+	var rejected int
+	for _, paf := range slow {
+		select {
+		case <-paf.Ok():
+		case err := <-paf.Err():
+			var apiErr *nats.APIError
+			require_True(t, errors.As(err, &apiErr))
+			require_Equal(t, apiErr.ErrorCode, nats.ErrorCode(JSStreamTooManyRequests))
+			rejected++
+		}
+	}
+	require_True(t, rejected > 0)
+
+	si, err := js.StreamInfo("SLOW")
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, uint64(len(slow)-rejected))
+}
+
+func TestJetStreamClusterConsumerExportImport(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	for range 10 {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	_, err = js.AddStream(&nats.StreamConfig{Name: "COPY", Sources: []*nats.StreamSource{{Name: "TEST"}}, Replicas: 3})
+	require_NoError(t, err)
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		si, err := js.StreamInfo("COPY")
+		if err != nil {
+			return err
+		}
+		if si.State.Msgs != 10 {
+			return fmt.Errorf("expected 10 msgs, got %d", si.State.Msgs)
+		}
+		return nil
+	})
+
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+	require_NoError(t, err)
+	sub, err := js.PullSubscribe("foo", "C", nats.Bind("TEST", "C"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(5)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 5)
+	for _, m := range msgs[:3] {
+		require_NoError(t, m.AckSync())
+	}
+
+	req, err := json.Marshal(&JSApiConsumerExportRequest{State: true})
+	require_NoError(t, err)
+	msg, err := nc.Request(fmt.Sprintf(JSApiConsumerExportT, "TEST", "C"), req, 2*time.Second)
+	require_NoError(t, err)
+	var eresp JSApiConsumerExportResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &eresp))
+	require_True(t, eresp.Error == nil)
+	require_Equal(t, eresp.State.Delivered.Stream, 5)
+	require_Equal(t, eresp.State.AckFloor.Stream, 3)
+
+	// The state is applied by all replicas of the imported consumer.
+	req, err = json.Marshal(eresp.ConsumerExport)
+	require_NoError(t, err)
+	msg, err = nc.Request(fmt.Sprintf(JSApiConsumerImportT, "COPY", "C"), req, 5*time.Second)
+	require_NoError(t, err)
+	var cresp JSApiConsumerCreateResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &cresp))
+	require_True(t, cresp.Error == nil)
+	c.waitOnConsumerLeader(globalAccountName, "COPY", "C")
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("COPY")
+			if err != nil {
+				return err
+			}
+			o := mset.lookupConsumer("C")
+			if o == nil {
+				return errors.New("consumer not found")
+			}
+			state, err := o.store.State()
+			if err != nil {
+				return err
+			}
+			if state.Delivered.Stream != 5 || state.AckFloor.Stream != 3 || len(state.Pending) != 2 {
+				return fmt.Errorf("unexpected state on %s: %+v", s.Name(), state)
+			}
+		}
+		return nil
+	})
+
+	csub, err := js.PullSubscribe("foo", "C", nats.Bind("COPY", "C"))
+	require_NoError(t, err)
+	msgs, err = csub.Fetch(1)
+	require_NoError(t, err)
+	meta, err := msgs[0].Metadata()
+	require_NoError(t, err)
+	require_Equal(t, meta.Sequence.Stream, 6)
+}
+
+func TestJetStreamClusterAccountStreamReplicasPolicy(t *testing.T) {
+	conf := `
+		listen: 127.0.0.1:-1
+		server_name: %s
+		jetstream: {
+			store_dir: '%s',
+		}
+		cluster {
+			name: %s
+			listen: 127.0.0.1:%d
+			routes = [%s]
+		}
+		system_account: sys
+		no_auth_user: min
+		accounts {
+			sys { users = [ { user: sys, pass: sys } ] }
+			min {
+				jetstream: { min_stream_replicas: 3 }
+				users = [ { user: min, pass: min } ]
+			}
+			exact {
+				jetstream: { stream_replicas: 3 }
+				users = [ { user: exact, pass: exact } ]
+			}
+		}`
+	c := createJetStreamClusterWithTemplate(t, conf, "R3S", 3)
+	defer c.shutdown()
+
+	for _, user := range []string{"min", "exact"} {
+		t.Run(user, func(t *testing.T) {
+			nc, js := jsClientConnect(t, c.randomServer(), nats.UserInfo(user, user))
+			defer nc.Close()
+
+			// Defaults to R1, which is below the account policy.
+			_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+			require_Error(t, err)
+			require_Contains(t, err.Error(), "stream replicas not allowed")
+
+			_, err = js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+			require_NoError(t, err)
+
+			_, err = js.UpdateStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 1})
+			require_Error(t, err)
+			require_Contains(t, err.Error(), "stream replicas not allowed")
+		})
+	}
+}
+
+func TestJetStreamClusterStreamIOErrorPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy   IOErrorPolicy
+		aerPol   ApplyErrorPolicy
+		expected string
+	}{
+		{IOErrorStepDown, ApplyErrorHalt, "stepdown"},
+		{IOErrorReadOnly, ApplyErrorHalt, "halt"},
+		{IOErrorReadOnly, ApplyErrorSkip, "skip"},
+	} {
+		t.Run(test.policy.String()+"-"+test.expected, func(t *testing.T) {
+			c := createJetStreamClusterExplicit(t, "R3S", 3)
+			defer c.shutdown()
+			for _, s := range c.servers {
+				s.optsMu.Lock()
+				s.opts.JetStreamIOErrorPolicy = test.policy
+				s.opts.JetStreamRaft.ApplyErrorPolicy = test.aerPol
+				s.optsMu.Unlock()
+			}
+
+			nc, js := jsClientConnect(t, c.randomServer())
+			defer nc.Close()
+
+			_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+			require_NoError(t, err)
+			_, err = js.Publish("foo", nil)
+			require_NoError(t, err)
+
+			sl := c.streamLeader(globalAccountName, "TEST")
+			mset, err := sl.globalAccount().lookupStream("TEST")
+			require_NoError(t, err)
+			injectStreamIOError(t, mset, io.ErrShortWrite)
+
+			switch test.expected {
+			case "stepdown":
+				// The leader fails to apply, and moves leadership to a healthy replica.
+				_, err = js.Publish("foo", nil, nats.AckWait(250*time.Millisecond))
+				require_Error(t, err)
+				checkFor(t, 5*time.Second, 250*time.Millisecond, func() error {
+					if nl := c.streamLeader(globalAccountName, "TEST"); nl == nil || nl == sl {
+						return errors.New("stream leader did not move")
+					}
+					return nil
+				})
+				require_Error(t, mset.getWriteErr(), io.ErrShortWrite)
+				require_True(t, mset.raftNode().IsObserver())
+				checkFor(t, 5*time.Second, 250*time.Millisecond, func() error {
+					_, err := js.Publish("foo", nil)
+					return err
+				})
+			case "halt":
+				// The leader halts its group instead of diverging from it, so leadership
+				// moves to a healthy replica. Reads remain possible from the halted one.
+				_, err = js.Publish("foo", nil, nats.AckWait(250*time.Millisecond))
+				require_Error(t, err)
+				checkFor(t, 5*time.Second, 250*time.Millisecond, func() error {
+					if nl := c.streamLeader(globalAccountName, "TEST"); nl == nil || nl == sl {
+						return errors.New("stream leader did not move")
+					}
+					return nil
+				})
+				require_True(t, mset.isReadOnly())
+				require_Equal(t, mset.raftNode().State(), Closed)
+				checkFor(t, 5*time.Second, 250*time.Millisecond, func() error {
+					_, err := js.Publish("foo", nil)
+					return err
+				})
+				var smv StoreMsg
+				_, err = mset.store.LoadMsg(1, &smv)
+				require_NoError(t, err)
+			case "skip":
+				// When explicitly configured to skip entries that fail to apply, the leader
+				// stays in place and rejects any writes.
+				for range 2 {
+					_, err = js.Publish("foo", nil)
+					require_Error(t, err, NewJSStreamReadOnlyError(io.ErrShortWrite))
+				}
+				require_Equal(t, c.streamLeader(globalAccountName, "TEST"), sl)
+				require_False(t, mset.raftNode().IsObserver())
+				_, err = js.GetMsg("TEST", 1)
+				require_NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJetStreamClusterStreamAllowedPublishers(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "accounts {", `
+		accounts {
+			A {
+				jetstream: enabled
+				users = [ { user: "alice", pass: "pwd" }, { user: "bob", pass: "pwd" } ]
+			}
+	`, 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	nc, _ := jsClientConnect(t, c.randomServer(), nats.UserInfo("alice", "pwd"))
+	defer nc.Close()
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:              "TEST",
+		Subjects:          []string{"foo"},
+		Storage:           FileStorage,
+		Replicas:          1,
+		AllowedPublishers: []string{"alice"},
+	})
+	require_NoError(t, err)
+	c.waitOnStreamLeader("A", "TEST")
+
+	// Publishers connected to servers not hosting the stream are checked as well.
+	for _, s := range c.servers {
+		errCh := make(chan error, 1)
+		nca, jsa := jsClientConnect(t, s, nats.UserInfo("alice", "pwd"))
+		defer nca.Close()
+		ncb, jsb := jsClientConnect(t, s, nats.UserInfo("bob", "pwd"),
+			nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) { errCh <- err }))
+		defer ncb.Close()
+
+		_, err = jsa.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+
+		_, err = jsb.Publish("foo", []byte("nope"), nats.AckWait(250*time.Millisecond))
+		require_Error(t, err)
+		select {
+		case err := <-errCh:
+			require_Contains(t, err.Error(), "Permissions Violation for Publish")
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a permissions violation on %s", s)
+		}
+	}
+}
+
+func TestJetStreamClusterStreamRename(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:     "TEST",
+		Subjects: []string{"TEST.>", "bar"},
+		Storage:  FileStorage,
+		Replicas: 3,
+	})
+	require_NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("TEST.foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	require_NoError(t, js.DeleteMsg("TEST", 3))
+
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+	require_NoError(t, err)
+	sub, err := js.PullSubscribe(_EMPTY_, "C", nats.Bind("TEST", "C"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(5)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 5)
+	for _, m := range msgs {
+		require_NoError(t, m.AckSync())
+	}
+	require_NoError(t, sub.Unsubscribe())
+
+	rename := func(stream, name string) error {
+		t.Helper()
+		req, err := json.Marshal(&JSApiStreamRenameRequest{Name: name})
+		require_NoError(t, err)
+		rmsg, err := nc.Request(fmt.Sprintf(JSApiStreamRenameT, stream), req, 5*time.Second)
+		require_NoError(t, err)
+		var resp JSApiStreamRenameResponse
+		require_NoError(t, json.Unmarshal(rmsg.Data, &resp))
+		if resp.Error != nil {
+			return resp.Error
+		}
+		require_True(t, resp.Success)
+		return nil
+	}
+
+	// Renames that would collide or break references are rejected.
+	require_Error(t, rename("TEST", "A.B"), NewJSStreamRenameNotAllowedError(errors.New("invalid stream name \"A.B\"")))
+	require_Error(t, rename("MISSING", "NEW"), NewJSStreamNotFoundError())
+	_, err = js.AddStream(&nats.StreamConfig{Name: "OTHER", Subjects: []string{"NEW.foo"}, Replicas: 3})
+	require_NoError(t, err)
+	require_Error(t, rename("TEST", "OTHER"), NewJSStreamRenameNotAllowedError(errors.New("stream name already in use")))
+	require_Error(t, rename("TEST", "NEW"), NewJSStreamSubjectOverlapError())
+	require_NoError(t, js.DeleteStream("OTHER"))
+	_, err = js.AddStream(&nats.StreamConfig{Name: "S", Sources: []*nats.StreamSource{{Name: "TEST"}}, Replicas: 3})
+	require_NoError(t, err)
+	require_Error(t, rename("TEST", "NEW"), NewJSStreamRenameNotAllowedError(errors.New("stream is referenced by stream \"S\"")))
+	require_NoError(t, js.DeleteStream("S"))
+
+	require_NoError(t, rename("TEST", "NEW"))
+	c.waitOnStreamLeader(globalAccountName, "NEW")
+	c.waitOnConsumerLeader(globalAccountName, "NEW", "C")
+
+	_, err = js.StreamInfo("TEST")
+	require_Error(t, err, nats.ErrStreamNotFound)
+
+	checkState := func(msgs, lseq uint64, pending uint64) {
+		t.Helper()
+		checkFor(t, 5*time.Second, 200*time.Millisecond, func() error {
+			for _, s := range c.servers {
+				mset, err := s.globalAccount().lookupStream("NEW")
+				if err != nil {
+					return err
+				}
+				if state := mset.state(); state.Msgs != msgs || state.FirstSeq != 1 || state.LastSeq != lseq {
+					return fmt.Errorf("unexpected state on %s: %+v", s, state)
+				}
+				o := mset.lookupConsumer("C")
+				if o == nil {
+					return fmt.Errorf("consumer not found on %s", s)
+				}
+				// Only the consumer leader tracks the number pending.
+				if ci := o.info(); ci.AckFloor.Stream != 6 || o.isLeader() && ci.NumPending != pending {
+					return fmt.Errorf("unexpected consumer info on %s: %+v", s, ci)
+				}
+			}
+			return nil
+		})
+	}
+	checkState(9, 10, 4)
+
+	si, err := js.StreamInfo("NEW")
+	require_NoError(t, err)
+	require_True(t, reflect.DeepEqual(si.Config.Subjects, []string{"NEW.>", "bar"}))
+
+	pa, err := js.Publish("NEW.foo", []byte("ok"))
+	require_NoError(t, err)
+	require_Equal(t, pa.Stream, "NEW")
+	require_Equal(t, pa.Sequence, 11)
+	checkState(10, 11, 5)
+
+	// Everything is kept after a restart.
+	c.stopAll()
+	c.restartAll()
+	c.waitOnStreamLeader(globalAccountName, "NEW")
+	c.waitOnConsumerLeader(globalAccountName, "NEW", "C")
+	checkState(10, 11, 5)
+
+	nc, js = jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+	sub, err = js.PullSubscribe(_EMPTY_, "C", nats.Bind("NEW", "C"))
+	require_NoError(t, err)
+	msgs, err = sub.Fetch(5)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 5)
+	meta, err := msgs[0].Metadata()
+	require_NoError(t, err)
+	require_Equal(t, meta.Sequence.Stream, 7)
+	require_Equal(t, msgs[4].Subject, "NEW.foo")
+
+	// A rename failing before a quorum of the peers renamed their store is reverted, keeping the original.
+	for _, s := range c.servers[:2] {
+		sd := s.JetStreamConfig().StoreDir
+		require_NoError(t, os.MkdirAll(filepath.Join(sd, globalAccountName, streamsDir, "FAIL"), defaultDirPerms))
+	}
+	require_True(t, rename("NEW", "FAIL") != nil)
+	c.waitOnStreamLeader(globalAccountName, "NEW")
+	c.waitOnConsumerLeader(globalAccountName, "NEW", "C")
+	checkState(10, 11, 0)
+	_, err = js.StreamInfo("FAIL")
+	require_Error(t, err, nats.ErrStreamNotFound)
+}
+
+func TestJetStreamClusterStreamDeliverAfterCommit(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:               "TEST",
+		Subjects:           []string{"foo"},
+		Storage:            FileStorage,
+		Replicas:           3,
+		DeliverAfterCommit: true,
+	})
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "CONSUMER", AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+	require_NoError(t, err)
+	c.waitOnConsumerLeader(globalAccountName, "TEST", "CONSUMER")
+
+	// Move consumer leader to equal stream leader.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	if cl := c.consumerLeader(globalAccountName, "TEST", "CONSUMER"); cl != sl {
+		req := JSApiLeaderStepdownRequest{Placement: &Placement{Preferred: sl.Name()}}
+		data, err := json.Marshal(req)
+		require_NoError(t, err)
+		_, err = nc.Request(fmt.Sprintf(JSApiConsumerLeaderStepDownT, "TEST", "CONSUMER"), data, time.Second)
+		require_NoError(t, err)
+		c.waitOnConsumerLeader(globalAccountName, "TEST", "CONSUMER")
+		require_Equal(t, c.consumerLeader(globalAccountName, "TEST", "CONSUMER").Name(), sl.Name())
+	}
+
+	sub, err := js.PullSubscribe(_EMPTY_, "CONSUMER", nats.Bind("TEST", "CONSUMER"))
+	require_NoError(t, err)
+	defer sub.Drain()
+
+	// With all replicas healthy, messages are delivered.
+	_, err = js.Publish("foo", []byte("1"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(1, nats.MaxWait(2*time.Second))
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 1)
+	require_NoError(t, msgs[0].AckSync())
+
+	// Hold back storage on all followers.
+	var followers []RaftNode
+	for _, s := range c.servers {
+		if s == sl {
+			continue
+		}
+		mset, err := s.globalAccount().lookupStream("TEST")
+		require_NoError(t, err)
+		n := mset.raftNode()
+		require_NoError(t, n.PauseApply())
+		followers = append(followers, n)
+	}
+
+	// The message is committed and stored on the leader, but must not be delivered yet.
+	pa, err := js.Publish("foo", []byte("2"))
+	require_NoError(t, err)
+	require_Equal(t, pa.Sequence, 2)
+	_, err = sub.Fetch(1, nats.MaxWait(500*time.Millisecond))
+	require_Error(t, err, nats.ErrTimeout)
+
+	// Once one follower stores the message we have a quorum, and it's delivered.
+	followers[0].ResumeApply()
+	msgs, err = sub.Fetch(1, nats.MaxWait(2*time.Second))
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 1)
+	meta, err := msgs[0].Metadata()
+	require_NoError(t, err)
+	require_Equal(t, meta.Sequence.Stream, 2)
+	require_NoError(t, msgs[0].AckSync())
+	followers[1].ResumeApply()
+
+	// Move the consumer leader to a follower, which must also wait for the leader's signal.
+	var fs *Server
+	for _, s := range c.servers {
+		if s != sl {
+			fs = s
+			break
+		}
+	}
+	req := JSApiLeaderStepdownRequest{Placement: &Placement{Preferred: fs.Name()}}
+	data, err := json.Marshal(req)
+	require_NoError(t, err)
+	_, err = nc.Request(fmt.Sprintf(JSApiConsumerLeaderStepDownT, "TEST", "CONSUMER"), data, time.Second)
+	require_NoError(t, err)
+	c.waitOnConsumerLeader(globalAccountName, "TEST", "CONSUMER")
+	require_Equal(t, c.consumerLeader(globalAccountName, "TEST", "CONSUMER").Name(), fs.Name())
+
+	// All replicas hold back delivery, followers up to what the leader signaled.
+	_, err = js.Publish("foo", []byte("3"))
+	require_NoError(t, err)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("TEST")
+			if err != nil {
+				return err
+			}
+			if !mset.dac.Load() {
+				return fmt.Errorf("not holding back delivery on %s", s)
+			}
+			if qseq := mset.qseq.Load(); qseq != 3 {
+				return fmt.Errorf("expected quorum sequence 3 on %s, got %d", s, qseq)
+			}
+		}
+		return nil
+	})
+	msgs, err = sub.Fetch(1, nats.MaxWait(2*time.Second))
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 1)
+	meta, err = msgs[0].Metadata()
+	require_NoError(t, err)
+	require_Equal(t, meta.Sequence.Stream, 3)
+}
+
+func TestJetStreamClusterAccountMaxTotalConsumers(t *testing.T) {
+	tmpl := `
+	listen: 127.0.0.1:-1
+	server_name: %s
+	jetstream: {max_mem_store: 256MB, max_file_store: 2GB, store_dir: '%s'}
+
+	cluster {
+		name: %s
+		listen: 127.0.0.1:%d
+		routes = [%s]
+	}
+
+	accounts {
+		A {
+			jetstream {
+				max_streams: 2
+				max_total_consumers: 3
+			}
+			users = [ { user: "a", pass: "pwd" } ]
+		}
+		$SYS { users = [ { user: "admin", pass: "s3cr3t!" } ] }
+	}
+	`
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer(), nats.UserInfo("a", "pwd"))
+	defer nc.Close()
+
+	for _, name := range []string{"S1", "S2"} {
+		_, err := js.AddStream(&nats.StreamConfig{Name: name, Subjects: []string{name}, Replicas: 3})
+		require_NoError(t, err)
+	}
+	_, err := js.AddStream(&nats.StreamConfig{Name: "S3", Subjects: []string{"S3"}, Replicas: 3})
+	require_Error(t, err, NewJSMaximumStreamsLimitError())
+
+	for _, cc := range []struct{ stream, name string }{{"S1", "C1"}, {"S1", "C2"}, {"S2", "C3"}} {
+		_, err = js.AddConsumer(cc.stream, &nats.ConsumerConfig{Durable: cc.name, AckPolicy: nats.AckExplicitPolicy})
+		require_NoError(t, err)
+	}
+	_, err = js.AddConsumer("S2", &nats.ConsumerConfig{Durable: "C4", AckPolicy: nats.AckExplicitPolicy})
+	require_Error(t, err, NewJSMaximumTotalConsumersLimitError())
+
+	// Updating an existing consumer is still allowed.
+	_, err = js.UpdateConsumer("S2", &nats.ConsumerConfig{Durable: "C3", AckPolicy: nats.AckExplicitPolicy, Description: "updated"})
+	require_NoError(t, err)
+
+	// Once a consumer is removed we can create another one.
+	require_NoError(t, js.DeleteConsumer("S1", "C1"))
+	_, err = js.AddConsumer("S2", &nats.ConsumerConfig{Durable: "C4", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+}
+
+func TestJetStreamClusterServerEvacuate(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R5S", 5)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	for range 10 {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+	c.waitOnConsumerLeader(globalAccountName, "TEST", "C")
+
+	// Evacuate the stream leader, make sure it also hosts an R1 stream and is the meta leader.
+	target := c.streamLeader(globalAccountName, "TEST")
+	var r1 string
+	for i := 0; r1 == _EMPTY_; i++ {
+		require_True(t, i < 20)
+		name := fmt.Sprintf("R1_%d", i)
+		_, err = js.AddStream(&nats.StreamConfig{Name: name, Subjects: []string{name}, Replicas: 1})
+		require_NoError(t, err)
+		if c.streamLeader(globalAccountName, name) == target {
+			r1 = name
+		}
+	}
+	_, err = js.Publish(r1, nil)
+	require_NoError(t, err)
+
+	ncsys, err := nats.Connect(c.randomServer().ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+	require_NoError(t, err)
+	defer ncsys.Close()
+
+	if ml := c.leader(); ml != target {
+		req, err := json.Marshal(JSApiLeaderStepdownRequest{Placement: &Placement{Preferred: target.Name()}})
+		require_NoError(t, err)
+		_, err = ncsys.Request(JSApiLeaderStepDown, req, time.Second)
+		require_NoError(t, err)
+		c.waitOnLeader()
+		require_Equal(t, c.leader(), target)
+	}
+
+	evacuate := func(server string) *JSApiMetaServerEvacuateResponse {
+		t.Helper()
+		req, err := json.Marshal(JSApiMetaServerEvacuateRequest{Server: server})
+		require_NoError(t, err)
+		rmsg, err := ncsys.Request(JSApiServerEvacuate, req, 5*time.Second)
+		require_NoError(t, err)
+		var resp JSApiMetaServerEvacuateResponse
+		require_NoError(t, json.Unmarshal(rmsg.Data, &resp))
+		return &resp
+	}
+
+	// Unknown servers are rejected.
+	resp := evacuate("unknown")
+	require_NotNil(t, resp.Error)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSClusterServerNotMemberErr))
+
+	// One stream is moved at a time.
+	resp = evacuate(target.Name())
+	require_True(t, resp.Error == nil)
+	require_Len(t, len(resp.Streams), 2)
+	require_Equal(t, resp.Started, 1)
+	require_True(t, resp.Consumers > 0)
+	require_False(t, resp.Done)
+
+	// Repeating the request resumes the evacuation until it's done.
+	checkFor(t, 90*time.Second, 500*time.Millisecond, func() error {
+		if resp := evacuate(target.Name()); resp.Error != nil {
+			return resp.Error
+		} else if !resp.Done {
+			return fmt.Errorf("not done yet: %d streams and %d consumers left", len(resp.Streams), resp.Consumers)
+		}
+		return nil
+	})
+
+	// The server hosts nothing anymore, and stepped down as meta leader.
+	checkFor(t, 10*time.Second, 200*time.Millisecond, func() error {
+		if n := target.globalAccount().numStreams(); n > 0 {
+			return fmt.Errorf("still hosting %d streams", n)
+		}
+		if n := target.numRaftNodes(); n > 1 {
+			return fmt.Errorf("still hosting %d raft nodes", n)
+		}
+		return nil
+	})
+	c.waitOnLeader()
+	require_NotEqual(t, c.leader(), target)
+
+	// All assets relocated with their state.
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+	c.waitOnConsumerLeader(globalAccountName, "TEST", "C")
+	si, err := js.StreamInfo("TEST")
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 10)
+	require_Len(t, len(si.Cluster.Replicas), 2)
+	si, err = js.StreamInfo(r1)
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 1)
+	require_NotEqual(t, si.Cluster.Leader, target.Name())
+	ci, err := js.ConsumerInfo("TEST", "C")
+	require_NoError(t, err)
+	require_NotEqual(t, ci.Cluster.Leader, target.Name())
+}
+
+func TestJetStreamClusterValidateOnly(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "EXISTING", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+
+	// A feasible placement is accepted, but the stream is not created.
+	si, apiErr := addStreamPedanticWithError(t, nc, &StreamConfigRequest{
+		StreamConfig: StreamConfig{Name: "TEST", Subjects: []string{"bar"}, Storage: FileStorage, Replicas: 3},
+		ValidateOnly: true,
+	})
+	require_True(t, apiErr == nil)
+	require_Equal(t, si.Config.Replicas, 3)
+
+	// Placements that can't be satisfied are rejected.
+	_, apiErr = addStreamPedanticWithError(t, nc, &StreamConfigRequest{
+		StreamConfig: StreamConfig{Name: "TEST", Subjects: []string{"bar"}, Storage: FileStorage, Replicas: 3,
+			Placement: &Placement{Tags: []string{"missing"}}},
+		ValidateOnly: true,
+	})
+	require_True(t, apiErr != nil && apiErr.ErrCode == uint16(JSClusterNoPeersErrF))
+	_, apiErr = addStreamPedanticWithError(t, nc, &StreamConfigRequest{
+		StreamConfig: StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage, Replicas: 3},
+		ValidateOnly: true,
+	})
+	require_True(t, apiErr != nil && apiErr.ErrCode == uint16(JSStreamSubjectOverlapErr))
+
+	// Updates are checked, but not applied.
+	_, apiErr = updateStreamPedanticWithError(t, nc, &StreamConfigRequest{
+		StreamConfig: StreamConfig{Name: "EXISTING", Subjects: []string{"foo"}, Storage: FileStorage, Replicas: 1},
+		ValidateOnly: true,
+	})
+	require_True(t, apiErr == nil)
+	_, apiErr = updateStreamPedanticWithError(t, nc, &StreamConfigRequest{
+		StreamConfig: StreamConfig{Name: "EXISTING", Subjects: []string{"foo"}, Storage: FileStorage, Replicas: 3,
+			Placement: &Placement{Tags: []string{"missing"}}},
+		ValidateOnly: true,
+	})
+	require_True(t, apiErr != nil && apiErr.ErrCode == uint16(JSClusterNoPeersErrF))
+
+	// Consumers are checked, but not created.
+	ci, apiErr := addConsumerWithError(t, nc, &CreateConsumerRequest{
+		Stream:       "EXISTING",
+		Config:       ConsumerConfig{Durable: "C", AckPolicy: AckExplicit},
+		ValidateOnly: true,
+	})
+	require_True(t, apiErr == nil)
+	require_Equal(t, ci.Name, "C")
+	_, apiErr = addConsumerWithError(t, nc, &CreateConsumerRequest{
+		Stream:       "EXISTING",
+		Config:       ConsumerConfig{Durable: "C", AckPolicy: AckExplicit, Replicas: 5},
+		ValidateOnly: true,
+	})
+	require_True(t, apiErr != nil)
+
+	// Give any proposal time to be applied, nothing should have been created or changed.
+	time.Sleep(250 * time.Millisecond)
+	_, err = js.StreamInfo("TEST")
+	require_Error(t, err, nats.ErrStreamNotFound)
+	nsi, err := js.StreamInfo("EXISTING")
+	require_NoError(t, err)
+	require_Equal(t, nsi.Config.Replicas, 3)
+	_, err = js.ConsumerInfo("EXISTING", "C")
+	require_Error(t, err, nats.ErrConsumerNotFound)
+}
+
+func TestJetStreamClusterStreamSubjectSequence(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:            "TEST",
+		Subjects:        []string{"foo.*"},
+		Storage:         FileStorage,
+		Replicas:        3,
+		SubjectSequence: true,
+	})
+	require_NoError(t, err)
+
+	// Publish async so we have multiple proposals inflight for the same subject.
+	expected := map[string]uint64{}
+	publish := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			subj := fmt.Sprintf("foo.%d", i%3)
+			_, err := js.PublishAsync(subj, nil)
+			require_NoError(t, err)
+			expected[subj]++
+		}
+		select {
+		case <-js.PublishAsyncComplete():
+		case <-time.After(5 * time.Second):
+			t.Fatal("Did not receive completion signal")
+		}
+	}
+	checkSequences := func() {
+		t.Helper()
+		checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+			return checkState(t, c, globalAccountName, "TEST")
+		})
+		// Every replica must have stored the same gap-free per-subject sequences.
+		for _, s := range c.servers {
+			mset, err := s.GlobalAccount().lookupStream("TEST")
+			require_NoError(t, err)
+			var state StreamState
+			mset.store.FastState(&state)
+			last := map[string]uint64{}
+			var smv StoreMsg
+			for seq := state.FirstSeq; seq <= state.LastSeq; seq++ {
+				sm, err := mset.store.LoadMsg(seq, &smv)
+				require_NoError(t, err)
+				sseq := uint64(parseInt64(sliceHeader(JSSubjectSequence, sm.hdr)))
+				require_Equal(t, sseq, last[sm.subj]+1)
+				last[sm.subj] = sseq
+			}
+			require_True(t, reflect.DeepEqual(last, expected))
+		}
+	}
+
+	publish(300)
+	checkSequences()
+
+	// The per-subject sequences continue on a new leader.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	require_NoError(t, sl.JetStreamStepdownStream(globalAccountName, "TEST"))
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+	publish(300)
+	checkSequences()
+
+	// And after restarting the cluster.
+	nc.Close()
+	c.stopAll()
+	c.restartAll()
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+	nc, js = jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+	publish(300)
+	checkSequences()
+}
+
+func TestJetStreamClusterStreamSigningKey(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	kp, err := nkeys.CreateUser()
+	require_NoError(t, err)
+	pub, err := kp.PublicKey()
+	require_NoError(t, err)
+
+	_, err = jsStreamCreate(t, nc, &StreamConfig{
+		Name:       "TEST",
+		Subjects:   []string{"foo"},
+		Storage:    FileStorage,
+		Replicas:   3,
+		SigningKey: pub,
+	})
+	require_NoError(t, err)
+
+	publish := func(data []byte, sign bool) error {
+		m := nats.NewMsg("foo")
+		m.Data = data
+		if sign {
+			sig, err := kp.Sign([]byte("hello"))
+			require_NoError(t, err)
+			m.Header.Set(JSMsgSignature, base64.RawURLEncoding.EncodeToString(sig))
+		}
+		_, err := js.PublishMsg(m)
+		return err
+	}
+	require_NoError(t, publish([]byte("hello"), true))
+	err = publish([]byte("hello"), false)
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "message signature missing or invalid")
+	err = publish([]byte("tampered"), true)
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "message signature missing or invalid")
+
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		return checkState(t, c, globalAccountName, "TEST")
+	})
+	si, err := js.StreamInfo("TEST")
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 1)
+}
+
+func TestJetStreamClusterStreamAllowedHeaders(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	for _, action := range []DisallowedHeaderAction{RejectDisallowedHeaders, StripDisallowedHeaders} {
+		name := action.String()
+		_, err := jsStreamCreate(t, nc, &StreamConfig{
+			Name:              name,
+			Subjects:          []string{strings.ToLower(name)},
+			Storage:           FileStorage,
+			Replicas:          3,
+			AllowedHeaders:    []string{"Trace-Id"},
+			DisallowedHeaders: action,
+		})
+		require_NoError(t, err)
+	}
+
+	m := nats.NewMsg("reject")
+	m.Header.Set("Trace-Id", "1")
+	m.Header.Set("Secret", "password")
+	_, err := js.PublishMsg(m)
+	require_Error(t, err, NewJSStreamHeaderNotAllowedError("Secret"))
+
+	m.Subject = "strip"
+	pa, err := js.PublishMsg(m)
+	require_NoError(t, err)
+
+	// All replicas stored the message without the stripped header.
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		if err := checkState(t, c, globalAccountName, "Strip"); err != nil {
+			return err
+		}
+		return checkState(t, c, globalAccountName, "Reject")
+	})
+	for _, s := range c.servers {
+		mset, err := s.globalAccount().lookupStream("Strip")
+		require_NoError(t, err)
+		sm, err := mset.getMsg(pa.Sequence)
+		require_NoError(t, err)
+		require_Equal(t, string(sliceHeader("Trace-Id", sm.Header)), "1")
+		require_True(t, sliceHeader("Secret", sm.Header) == nil)
+	}
+	si, err := js.StreamInfo("Reject")
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 0)
+}
+
+func TestJetStreamClusterAutoBalanceLeaders(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir: '%s'}", "store_dir: '%s', auto_balance_interval: 250ms, auto_balance_threshold: 1}", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	const numStreams = 9
+	for i := 0; i < numStreams; i++ {
+		name := fmt.Sprintf("S%d", i)
+		_, err := js.AddStream(&nats.StreamConfig{Name: name, Replicas: 3})
+		require_NoError(t, err)
+		_, err = js.AddConsumer(name, &nats.ConsumerConfig{Durable: "C", Replicas: 3, AckPolicy: nats.AckExplicitPolicy})
+		require_NoError(t, err)
+	}
+
+	// Move all leadership to a single server.
+	target := c.servers[0]
+	data, err := json.Marshal(JSApiLeaderStepdownRequest{Placement: &Placement{Preferred: target.Name()}})
+	require_NoError(t, err)
+	stepdown := func(subj string) {
+		t.Helper()
+		resp, err := nc.Request(subj, data, 2*time.Second)
+		require_NoError(t, err)
+		var sdr JSApiStreamLeaderStepDownResponse
+		require_NoError(t, json.Unmarshal(resp.Data, &sdr))
+		require_True(t, sdr.Error == nil)
+	}
+	for i := 0; i < numStreams; i++ {
+		name := fmt.Sprintf("S%d", i)
+		if c.streamLeader(globalAccountName, name) != target {
+			stepdown(fmt.Sprintf(JSApiStreamLeaderStepDownT, name))
+			c.waitOnStreamLeader(globalAccountName, name)
+		}
+		if c.consumerLeader(globalAccountName, name, "C") != target {
+			stepdown(fmt.Sprintf(JSApiConsumerLeaderStepDownT, name, "C"))
+			c.waitOnConsumerLeader(globalAccountName, name, "C")
+		}
+	}
+
+	// Each server's fair share is a third of all groups, they should end up within the threshold of that.
+	const fairShare = 2 * numStreams / 3
+	checkFor(t, 10*time.Second, 250*time.Millisecond, func() error {
+		leaders := make(map[string]int)
+		for i := 0; i < numStreams; i++ {
+			name := fmt.Sprintf("S%d", i)
+			sl := c.streamLeader(globalAccountName, name)
+			cl := c.consumerLeader(globalAccountName, name, "C")
+			if sl == nil || cl == nil {
+				return fmt.Errorf("missing leader for %q", name)
+			}
+			leaders[sl.Name()]++
+			leaders[cl.Name()]++
+		}
+		for _, s := range c.servers {
+			if n := leaders[s.Name()]; n > fairShare+1 {
+				return fmt.Errorf("server %q leads %d, fair share is %d", s.Name(), n, fairShare)
+			}
+		}
+		return nil
+	})
+}
+
+func TestJetStreamClusterAssetPlacement(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+	c.waitOnConsumerLeader(globalAccountName, "TEST", "C")
+
+	checkPlacement := func(ap *AssetPlacement, leader *Server) {
+		t.Helper()
+		require_NotNil(t, ap)
+		require_Equal(t, ap.Cluster, "R3S")
+		require_Equal(t, ap.Leader, leader.Name())
+		require_Len(t, len(ap.Replicas), 3)
+		var leaders int
+		for _, s := range c.servers {
+			idx := slices.IndexFunc(ap.Replicas, func(rp *ReplicaPlacement) bool { return rp.Name == s.Name() })
+			require_True(t, idx >= 0)
+			rp := ap.Replicas[idx]
+			require_Equal(t, rp.ID, s.ID())
+			require_Equal(t, rp.Peer, s.NodeName())
+			require_False(t, rp.Offline)
+			if rp.Leader {
+				leaders++
+				require_Equal(t, rp.Name, leader.Name())
+			}
+		}
+		require_Equal(t, leaders, 1)
+	}
+
+	var sresp JSApiStreamPlacementResponse
+	resp, err := nc.Request(fmt.Sprintf(JSApiStreamPlacementT, "TEST"), nil, time.Second)
+	require_NoError(t, err)
+	require_NoError(t, json.Unmarshal(resp.Data, &sresp))
+	require_True(t, sresp.Error == nil)
+	checkPlacement(sresp.AssetPlacement, c.streamLeader(globalAccountName, "TEST"))
+
+	var cresp JSApiConsumerPlacementResponse
+	resp, err = nc.Request(fmt.Sprintf(JSApiConsumerPlacementT, "TEST", "C"), nil, time.Second)
+	require_NoError(t, err)
+	require_NoError(t, json.Unmarshal(resp.Data, &cresp))
+	require_True(t, cresp.Error == nil)
+	checkPlacement(cresp.AssetPlacement, c.consumerLeader(globalAccountName, "TEST", "C"))
+
+	// The new leader is reported after a stepdown.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	_, err = nc.Request(fmt.Sprintf(JSApiStreamLeaderStepDownT, "TEST"), nil, time.Second)
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+	nsl := c.streamLeader(globalAccountName, "TEST")
+	require_NotEqual(t, nsl, sl)
+	sresp = JSApiStreamPlacementResponse{}
+	resp, err = nc.Request(fmt.Sprintf(JSApiStreamPlacementT, "TEST"), nil, time.Second)
+	require_NoError(t, err)
+	require_NoError(t, json.Unmarshal(resp.Data, &sresp))
+	require_True(t, sresp.Error == nil)
+	checkPlacement(sresp.AssetPlacement, nsl)
+
+	// Unknown assets.
+	sresp = JSApiStreamPlacementResponse{}
+	resp, err = nc.Request(fmt.Sprintf(JSApiStreamPlacementT, "MISSING"), nil, time.Second)
+	require_NoError(t, err)
+	require_NoError(t, json.Unmarshal(resp.Data, &sresp))
+	require_NotNil(t, sresp.Error)
+	require_Equal(t, sresp.Error.ErrCode, uint16(JSStreamNotFoundErr))
+	cresp = JSApiConsumerPlacementResponse{}
+	resp, err = nc.Request(fmt.Sprintf(JSApiConsumerPlacementT, "TEST", "MISSING"), nil, time.Second)
+	require_NoError(t, err)
+	require_NoError(t, json.Unmarshal(resp.Data, &cresp))
+	require_NotNil(t, cresp.Error)
+	require_Equal(t, cresp.Error.ErrCode, uint16(JSConsumerNotFoundErr))
+}
+
+func TestJetStreamClusterAutoBalanceLeadersSkipsPreferred(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, _ := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	target := c.servers[0]
+	const numStreams = 3
+	for i := 0; i < numStreams; i++ {
+		name := fmt.Sprintf("S%d", i)
+		_, err := jsStreamCreate(t, nc, &StreamConfig{
+			Name:      name,
+			Storage:   FileStorage,
+			Replicas:  3,
+			Placement: &Placement{Preferred: target.Name()},
+		})
+		require_NoError(t, err)
+		c.waitOnStreamLeader(globalAccountName, name)
+		require_Equal(t, c.streamLeader(globalAccountName, name), target)
+	}
+
+	// The target leads more than its fair share, but those leaders were placed there on purpose.
+	target.getJetStream().balanceLeaders(0)
+	time.Sleep(500 * time.Millisecond)
+	for i := 0; i < numStreams; i++ {
+		name := fmt.Sprintf("S%d", i)
+		c.waitOnStreamLeader(globalAccountName, name)
+		require_Equal(t, c.streamLeader(globalAccountName, name), target)
+	}
+}
+
+func TestJetStreamClusterStreamMaxSubjects(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	for _, discardNew := range []bool{false, true} {
+		name := fmt.Sprintf("TEST-%v", discardNew)
+		_, err := jsStreamCreate(t, nc, &StreamConfig{
+			Name:               name,
+			Subjects:           []string{name + ".*"},
+			Storage:            FileStorage,
+			Replicas:           3,
+			MaxSubjects:        10,
+			DiscardNewSubjects: discardNew,
+		})
+		require_NoError(t, err)
+
+		// Publish async so we have multiple new subjects inflight.
+		for i := 0; i < 20; i++ {
+			_, err = js.PublishAsync(fmt.Sprintf("%s.%d", name, i), nil)
+			require_NoError(t, err)
+		}
+		select {
+		case <-js.PublishAsyncComplete():
+		case <-time.After(5 * time.Second):
+			t.Fatal("Did not receive completion signal")
+		}
+
+		// Either the first or the last subjects must be kept, on all replicas.
+		first := 0
+		if !discardNew {
+			first = 10
+		}
+		checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+			return checkState(t, c, globalAccountName, name)
+		})
+		for _, s := range c.servers {
+			mset, err := s.GlobalAccount().lookupStream(name)
+			require_NoError(t, err)
+			totals := mset.store.SubjectsTotals(">")
+			require_Len(t, len(totals), 10)
+			for i := first; i < first+10; i++ {
+				require_Equal(t, totals[fmt.Sprintf("%s.%d", name, i)], 1)
+			}
+		}
+	}
+}
+
+func TestJetStreamClusterStreamAllowSubjectOverlap(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{Name: "A", Subjects: []string{"foo.>"}, Storage: FileStorage, Replicas: 3, AllowSubjectOverlap: true})
+	require_NoError(t, err)
+	_, err = jsStreamCreate(t, nc, &StreamConfig{Name: "B", Subjects: []string{"foo.bar"}, Storage: FileStorage, Replicas: 1, AllowSubjectOverlap: true})
+	require_NoError(t, err)
+	_, err = jsStreamCreate(t, nc, &StreamConfig{Name: "C", Subjects: []string{"foo.*"}, Storage: FileStorage, Replicas: 3})
+	require_Error(t, err, NewJSStreamSubjectOverlapError())
+
+	_, err = js.Publish("foo.bar", nil)
+	require_NoError(t, err)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		for _, stream := range []string{"A", "B"} {
+			if err := checkState(t, c, globalAccountName, stream); err != nil {
+				return err
+			}
+			si, err := js.StreamInfo(stream)
+			if err != nil {
+				return err
+			}
+			if si.State.Msgs != 1 {
+				return fmt.Errorf("expected 1 message in %q, got %d", stream, si.State.Msgs)
+			}
+		}
+		return nil
+	})
+
+	// Only one of the streams acknowledges the publish.
+	inbox := nats.NewInbox()
+	sub, err := nc.SubscribeSync(inbox)
+	require_NoError(t, err)
+	defer sub.Unsubscribe()
+	require_NoError(t, nc.PublishRequest("foo.bar", inbox, nil))
+	msg, err := sub.NextMsg(time.Second)
+	require_NoError(t, err)
+	var pa JSPubAckResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &pa))
+	require_True(t, pa.Error == nil)
+	require_Equal(t, pa.Stream, "A")
+	_, err = sub.NextMsg(250 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	// The next overlapping stream acknowledges once the owner is deleted.
+	require_NoError(t, js.DeleteStream("A"))
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		pa, err := js.Publish("foo.bar", nil, nats.AckWait(250*time.Millisecond))
+		if err != nil {
+			return err
+		}
+		if pa.Stream != "B" {
+			return fmt.Errorf("expected ack from %q, got %q", "B", pa.Stream)
+		}
+		return nil
+	})
+}
+
+func TestJetStreamClusterConsumerMove(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{Name: "ORIG", Subjects: []string{"foo.>"}, Storage: FileStorage, Replicas: 3, AllowSubjectOverlap: true})
+	require_NoError(t, err)
+	_, err = jsStreamCreate(t, nc, &StreamConfig{Name: "NEW", Subjects: []string{"foo.>"}, Storage: FileStorage, Replicas: 3, AllowSubjectOverlap: true})
+	require_NoError(t, err)
+	for i := 1; i <= 10; i++ {
+		_, err := js.Publish("foo.bar", []byte(strconv.Itoa(i)))
+		require_NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	_, err = js.AddConsumer("ORIG", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+	require_NoError(t, err)
+	sub, err := js.PullSubscribe(_EMPTY_, "C", nats.Bind("ORIG", "C"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(3)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 3)
+	for _, m := range msgs {
+		require_NoError(t, m.AckSync())
+	}
+	require_NoError(t, sub.Unsubscribe())
+
+	req, err := json.Marshal(&JSApiConsumerMoveRequest{Stream: "NEW"})
+	require_NoError(t, err)
+	msg, err := nc.Request(fmt.Sprintf(JSApiConsumerMoveT, "ORIG", "C"), req, 5*time.Second)
+	require_NoError(t, err)
+	var resp JSApiConsumerCreateResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.ConsumerInfo.Stream, "NEW")
+	require_Equal(t, resp.ConsumerInfo.Config.Replicas, 3)
+
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		if _, err := js.ConsumerInfo("ORIG", "C"); err != nats.ErrConsumerNotFound {
+			return fmt.Errorf("expected consumer to be deleted from original stream, got %v", err)
+		}
+		return nil
+	})
+	c.waitOnConsumerLeader(globalAccountName, "NEW", "C")
+
+	sub, err = js.PullSubscribe(_EMPTY_, "C", nats.Bind("NEW", "C"))
+	require_NoError(t, err)
+	msgs, err = sub.Fetch(7)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 7)
+	for i, m := range msgs {
+		require_Equal(t, string(m.Data), strconv.Itoa(4+i))
+	}
+}
+
+func TestJetStreamClusterStreamScaleWithRevert(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R5S", 5)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+
+	scale := func(req JSApiStreamScaleRequest) *JSApiStreamUpdateResponse {
+		t.Helper()
+		b, err := json.Marshal(req)
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiStreamScaleT, "TEST"), b, 2*time.Second)
+		require_NoError(t, err)
+		var resp JSApiStreamUpdateResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+
+	checkReplicas := func(replicas int) {
+		t.Helper()
+		checkFor(t, 10*time.Second, 200*time.Millisecond, func() error {
+			si, err := js.StreamInfo("TEST")
+			if err != nil {
+				return err
+			}
+			if si.Config.Replicas != replicas {
+				return fmt.Errorf("expected %d replicas in config, got %d", replicas, si.Config.Replicas)
+			}
+			if si.Cluster == nil || len(si.Cluster.Replicas) != replicas-1 {
+				return fmt.Errorf("expected %d peers, got %+v", replicas-1, si.Cluster)
+			}
+			for _, pi := range si.Cluster.Replicas {
+				if !pi.Current {
+					return fmt.Errorf("peer %q not current", pi.Name)
+				}
+			}
+			if si.State.Msgs != 10 {
+				return fmt.Errorf("expected 10 msgs, got %d", si.State.Msgs)
+			}
+			return nil
+		})
+	}
+
+	// Reverting only makes sense when elevating.
+	resp := scale(JSApiStreamScaleRequest{Replicas: 1, RevertAfter: time.Second})
+	require_NotNil(t, resp.Error)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSStreamUpdateErrF))
+	resp = scale(JSApiStreamScaleRequest{Replicas: 7})
+	require_NotNil(t, resp.Error)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSStreamReplicasNotSupportedErr))
+
+	// Temporarily elevate to R5, all peers should catch up.
+	resp = scale(JSApiStreamScaleRequest{Replicas: 5, RevertAfter: 2 * time.Second})
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.Config.Replicas, 5)
+	checkReplicas(5)
+
+	// The revert should be pending in the assignment on all servers.
+	for _, s := range c.servers {
+		js, cc := s.getJetStreamCluster()
+		js.mu.RLock()
+		sa := cc.streams[globalAccountName]["TEST"]
+		revert := sa.ScaleRevert
+		js.mu.RUnlock()
+		require_NotNil(t, revert)
+		require_Equal(t, revert.Replicas, 3)
+	}
+
+	// Once elapsed, the meta leader returns the stream to R3.
+	checkReplicas(3)
+	for _, s := range c.servers {
+		js, cc := s.getJetStreamCluster()
+		checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+			js.mu.RLock()
+			defer js.mu.RUnlock()
+			if sa := cc.streams[globalAccountName]["TEST"]; sa.ScaleRevert != nil {
+				return fmt.Errorf("revert still pending on %s", s)
+			}
+			return nil
+		})
+	}
+
+	// A regular update without changing replicas keeps a pending revert,
+	// a permanent scale drops it.
+	resp = scale(JSApiStreamScaleRequest{Replicas: 5, RevertAfter: time.Hour})
+	require_True(t, resp.Error == nil)
+	_, err = js.UpdateStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo", "bar"}, Replicas: 5})
+	require_NoError(t, err)
+	ml := c.leader()
+	mjs, mcc := ml.getJetStreamCluster()
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		mjs.mu.RLock()
+		defer mjs.mu.RUnlock()
+		sa := mcc.streams[globalAccountName]["TEST"]
+		if len(sa.Config.Subjects) != 2 || sa.ScaleRevert == nil || sa.ScaleRevert.Replicas != 3 {
+			return fmt.Errorf("unexpected assignment: %+v", sa)
+		}
+		return nil
+	})
+
+	// The revert is scheduled on the meta leader, and picked up by a new one.
+	checkScheduled := func(mjs *jetStream, mcc *jetStreamCluster) error {
+		mjs.mu.RLock()
+		defer mjs.mu.RUnlock()
+		if mcc.srtmr == nil || mcc.srnext.IsZero() || time.Until(mcc.srnext) > time.Hour {
+			return fmt.Errorf("revert not scheduled: %v", mcc.srnext)
+		}
+		return nil
+	}
+	require_NoError(t, checkScheduled(mjs, mcc))
+	require_NoError(t, mjs.getMetaGroup().StepDown())
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		if nl := c.leader(); nl == nil || nl == ml {
+			return errors.New("no new meta leader yet")
+		}
+		return nil
+	})
+	ml = c.leader()
+	mjs, mcc = ml.getJetStreamCluster()
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error { return checkScheduled(mjs, mcc) })
+
+	resp = scale(JSApiStreamScaleRequest{Replicas: 5})
+	require_True(t, resp.Error == nil)
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		mjs.mu.RLock()
+		defer mjs.mu.RUnlock()
+		if sa := mcc.streams[globalAccountName]["TEST"]; sa.ScaleRevert != nil {
+			return errors.New("revert still pending")
+		}
+		return nil
+	})
+	checkReplicas(5)
+}
+
+func TestJetStreamClusterStreamWriteConcern(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, _ := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	for _, wc := range []WriteConcern{WriteConcernSafe, WriteConcernFast, WriteConcernStrict} {
+		name := strings.ToUpper(wc.String())
+		cfg, err := jsStreamCreate(t, nc, &StreamConfig{
+			Name:         name,
+			Subjects:     []string{strings.ToLower(name)},
+			Storage:      FileStorage,
+			Replicas:     3,
+			WriteConcern: wc,
+		})
+		require_NoError(t, err)
+		require_Equal(t, cfg.WriteConcern, wc)
+	}
+	c.waitOnAllCurrent()
+
+	// Check the write concern translates to the durability of the Raft log and store.
+	for _, wc := range []WriteConcern{WriteConcernSafe, WriteConcernFast, WriteConcernStrict} {
+		name := strings.ToUpper(wc.String())
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream(name)
+			require_NoError(t, err)
+			n := mset.raftNode().(*raft)
+			n.RLock()
+			dack := n.dack
+			n.RUnlock()
+			fs := mset.store.(*fileStore)
+			fs.mu.RLock()
+			syncAlways := fs.fcfg.SyncAlways
+			fs.mu.RUnlock()
+			if wc == WriteConcernStrict {
+				require_Equal(t, dack, 2)
+				require_True(t, syncAlways)
+			} else {
+				require_Equal(t, dack, 0)
+				require_False(t, syncAlways)
+			}
+		}
+	}
+
+	publish := func(subj string, wait time.Duration) (*PubAck, error) {
+		t.Helper()
+		msg, err := nc.Request(subj, []byte("ok"), wait)
+		if err != nil {
+			return nil, err
+		}
+		var resp JSPubAckResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.PubAck, nil
+	}
+
+	// With all replicas available, all write concerns are acknowledged.
+	for _, subj := range []string{"safe", "fast", "strict"} {
+		pa, err := publish(subj, 2*time.Second)
+		require_NoError(t, err)
+		require_Equal(t, pa.Sequence, 1)
+	}
+
+	// Stop the followers from storing anything.
+	lockFollowers := func(name string) []*raft {
+		var locked []*raft
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream(name)
+			require_NoError(t, err)
+			if n := mset.raftNode().(*raft); !n.Leader() {
+				n.Lock()
+				locked = append(locked, n)
+			}
+		}
+		require_Len(t, len(locked), 2)
+		return locked
+	}
+	unlock := func(locked []*raft) {
+		for _, n := range locked {
+			n.Unlock()
+		}
+	}
+
+	// The fast write concern acknowledges without waiting on the replicas,
+	// and exactly once.
+	locked := lockFollowers("FAST")
+	inbox := nats.NewInbox()
+	sub := natsSubSync(t, nc, inbox)
+	start := time.Now()
+	require_NoError(t, nc.PublishRequest("fast", inbox, []byte("ok")))
+	msg := natsNexMsg(t, sub, time.Second)
+	require_True(t, time.Since(start) < 500*time.Millisecond)
+	var resp JSPubAckResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.Sequence, 2)
+	unlock(locked)
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		return checkState(t, c, globalAccountName, "FAST")
+	})
+	_, err := sub.NextMsg(250 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+	mset, err := c.streamLeader(globalAccountName, "FAST").globalAccount().lookupStream("FAST")
+	require_NoError(t, err)
+	require_Equal(t, mset.state().Msgs, 2)
+
+	// The safe and strict write concerns wait for a quorum.
+	for _, name := range []string{"SAFE", "STRICT"} {
+		locked = lockFollowers(name)
+		_, err = publish(strings.ToLower(name), 500*time.Millisecond)
+		require_Error(t, err, nats.ErrTimeout)
+		unlock(locked)
+		checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+			return checkState(t, c, globalAccountName, name)
+		})
+		pa, err := publish(strings.ToLower(name), 2*time.Second)
+		require_NoError(t, err)
+		require_Equal(t, pa.Sequence, 3)
+	}
+
+	// The write concern can not be changed.
+	_, err = jsStreamUpdate(t, nc, &StreamConfig{Name: "SAFE", Subjects: []string{"safe"}, Storage: FileStorage, Replicas: 3, WriteConcern: WriteConcernStrict})
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "can not change write concern")
+
+	// Strict can not be combined with async persistence.
+	_, err = jsStreamCreate(t, nc, &StreamConfig{Name: "ASYNC", Storage: FileStorage, PersistMode: AsyncPersistMode, WriteConcern: WriteConcernStrict})
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "not supported with strict write concern")
+
+	var wc WriteConcern
+	require_Error(t, json.Unmarshal([]byte(`"bad"`), &wc))
+}
+
+func TestJetStreamClusterConsumerSnapshotRestoreState(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	for range 20 {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+	require_NoError(t, err)
+
+	sub, err := js.PullSubscribe("foo", "C", nats.Bind("TEST", "C"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(5)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 5)
+	for _, m := range msgs[:3] {
+		require_NoError(t, m.AckSync())
+	}
+
+	msg, err := nc.Request(fmt.Sprintf(JSApiConsumerSnapshotT, "TEST", "C"), nil, 2*time.Second)
+	require_NoError(t, err)
+	var sresp JSApiConsumerSnapshotResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &sresp))
+	require_True(t, sresp.Error == nil)
+
+	// Keep processing past the snapshot.
+	for _, m := range msgs[3:] {
+		require_NoError(t, m.AckSync())
+	}
+	msgs, err = sub.Fetch(10)
+	require_NoError(t, err)
+	for _, m := range msgs {
+		require_NoError(t, m.AckSync())
+	}
+
+	// The restore is applied by all replicas.
+	req, err := json.Marshal(&JSApiConsumerRestoreRequest{State: sresp.State})
+	require_NoError(t, err)
+	msg, err = nc.Request(fmt.Sprintf(JSApiConsumerRestoreT, "TEST", "C"), req, 5*time.Second)
+	require_NoError(t, err)
+	var rresp JSApiConsumerRestoreResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &rresp))
+	require_True(t, rresp.Error == nil)
+	require_Equal(t, rresp.Delivered.Stream, 5)
+	require_Equal(t, rresp.AckFloor.Stream, 3)
+	require_Equal(t, rresp.NumAckPending, 2)
+
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("TEST")
+			if err != nil {
+				return err
+			}
+			o := mset.lookupConsumer("C")
+			if o == nil {
+				return errors.New("consumer not found")
+			}
+			state, err := o.store.State()
+			if err != nil {
+				return err
+			}
+			if state.Delivered.Stream != 5 || state.AckFloor.Stream != 3 || len(state.Pending) != 2 {
+				return fmt.Errorf("unexpected state on %s: %+v", s.Name(), state)
+			}
+		}
+		return nil
+	})
+
+	// Delivery resumes right after the snapshotted position, also on a new leader.
+	msgs, err = sub.Fetch(1)
+	require_NoError(t, err)
+	meta, err := msgs[0].Metadata()
+	require_NoError(t, err)
+	require_Equal(t, meta.Sequence.Stream, 6)
+
+	_, err = nc.Request(fmt.Sprintf(JSApiConsumerLeaderStepDownT, "TEST", "C"), nil, time.Second)
+	require_NoError(t, err)
+	c.waitOnConsumerLeader(globalAccountName, "TEST", "C")
+	ci, err := js.ConsumerInfo("TEST", "C")
+	require_NoError(t, err)
+	require_Equal(t, ci.AckFloor.Stream, 3)
+
+	// Not proposed when a replica is on an older API level.
+	cl := c.consumerLeader(globalAccountName, "TEST", "C")
+	for _, s := range c.servers {
+		if s != cl {
+			cl.nodeToInfo.Store(s.NodeName(), nodeInfo{stats: &JetStreamStats{API: JetStreamAPIStats{Level: JSApiLevel - 1}}})
+			break
+		}
+	}
+	msg, err = nc.Request(fmt.Sprintf(JSApiConsumerRestoreT, "TEST", "C"), req, 5*time.Second)
+	require_NoError(t, err)
+	rresp = JSApiConsumerRestoreResponse{}
+	require_NoError(t, json.Unmarshal(msg.Data, &rresp))
+	require_NotNil(t, rresp.Error)
+	require_Equal(t, rresp.Error.ErrCode, uint16(JSClusterPeersApiLevelErr))
+}
+
+func TestJetStreamClusterOrphanedRaftGroupCleanup(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir: '%s'}", "store_dir: '%s', orphan_check_interval: 250ms}", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+	require_NoError(t, err)
+
+	// Simulate groups left behind by a failure, one running on all servers and
+	// one only left on disk, neither of which has an assignment.
+	var peers []string
+	for _, p := range c.leader().getJetStream().getMetaGroup().Peers() {
+		peers = append(peers, p.ID)
+	}
+	const running, onDisk = "S-R3F-ORPHAN", "C-R1F-ORPHAN"
+	groupsDir := func(s *Server) string {
+		return filepath.Join(s.getJetStream().config.StoreDir, s.SystemAccount().Name, defaultStoreDirName)
+	}
+	for _, s := range c.servers {
+		rg := &raftGroup{Name: running, Peers: peers, Storage: FileStorage}
+		_, err := s.getJetStream().createRaftGroup(globalAccountName, rg, false, FileStorage, pprofLabels{})
+		require_NoError(t, err)
+		require_NoError(t, os.MkdirAll(filepath.Join(groupsDir(s), onDisk), defaultDirPerms))
+	}
+
+	checkFor(t, 10*time.Second, 250*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			if s.lookupRaftNode(running) != nil {
+				return fmt.Errorf("orphaned group still running on %s", s.Name())
+			}
+			for _, group := range []string{running, onDisk} {
+				if _, err := os.Stat(filepath.Join(groupsDir(s), group)); err == nil {
+					return fmt.Errorf("orphaned group %q still on disk on %s", group, s.Name())
+				}
+			}
+		}
+		return nil
+	})
+
+	// The groups that are assigned are left alone.
+	for _, s := range c.servers {
+		mset, err := s.globalAccount().lookupStream("TEST")
+		require_NoError(t, err)
+		n := mset.raftNode()
+		require_NotNil(t, n)
+		require_True(t, s.lookupRaftNode(n.Group()) != nil)
+		o := mset.lookupConsumer("C")
+		require_NotNil(t, o)
+		require_True(t, s.lookupRaftNode(o.raftNode().Group()) != nil)
+	}
+	_, err = js.Publish("foo", nil)
+	require_NoError(t, err)
+	_, err = js.ConsumerInfo("TEST", "C")
+	require_NoError(t, err)
+
+	// The meta-leader only confirms groups without an assignment.
+	mset, err := c.randomServer().globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	snc, _ := jsClientConnect(t, c.randomServer(), nats.UserInfo("admin", "s3cr3t!"))
+	defer snc.Close()
+	req, err := json.Marshal([]string{mset.raftNode().Group(), running})
+	require_NoError(t, err)
+	msg, err := snc.Request(orphanGroupsSubj, req, 5*time.Second)
+	require_NoError(t, err)
+	var confirmed []string
+	require_NoError(t, json.Unmarshal(msg.Data, &confirmed))
+	require_Len(t, len(confirmed), 1)
+	require_Equal(t, confirmed[0], running)
+}
+
+func TestJetStreamClusterConcurrentExclusiveStreamCreate(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, _ := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	create := func(exclusive bool) *JSApiStreamCreateResponse {
+		req, err := json.Marshal(&StreamConfigRequest{
+			StreamConfig: StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3, Storage: FileStorage},
+			Exclusive:    exclusive,
+		})
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiStreamCreateT, "TEST"), req, 5*time.Second)
+		require_NoError(t, err)
+		var resp JSApiStreamCreateResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+
+	const numCreates = 10
+	var wg sync.WaitGroup
+	resps := make([]*JSApiStreamCreateResponse, numCreates)
+	for i := range numCreates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resps[i] = create(true)
+		}()
+	}
+	wg.Wait()
+
+	var created int
+	for _, resp := range resps {
+		if resp.Error == nil {
+			created++
+			continue
+		}
+		if resp.Error.ErrCode != uint16(JSStreamCreateInProgressErr) && resp.Error.ErrCode != uint16(JSStreamAlreadyExistsErr) {
+			t.Fatalf("Unexpected error: %+v", resp.Error)
+		}
+	}
+	require_Equal(t, created, 1)
+
+	// Once created, an exclusive create reports the stream exists.
+	resp := create(true)
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSStreamAlreadyExistsErr))
+
+	// Identical creates without exclusive are still idempotent.
+	resp = create(false)
+	require_True(t, resp.Error == nil)
+}
+
+func TestJetStreamClusterMsgReplicationStatus(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	_, err = js.Publish("foo", []byte("first"))
+	require_NoError(t, err)
+
+	getStatus := func(req *JSApiMsgGetRequest) *JSApiMsgReplicationResponse {
+		t.Helper()
+		b, err := json.Marshal(req)
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiMsgReplicationT, "TEST"), b, 2*time.Second)
+		require_NoError(t, err)
+		var resp JSApiMsgReplicationResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+
+	// Eventually all replicas have stored the first message.
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		resp := getStatus(&JSApiMsgGetRequest{Seq: 1})
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if resp.Replication.Stored != 3 {
+			return fmt.Errorf("expected 3 replicas to have stored the message, got %d", resp.Replication.Stored)
+		}
+		return nil
+	})
+
+	// Only a single message by sequence or last by subject is supported.
+	resp := getStatus(&JSApiMsgGetRequest{Seq: 1, LastFor: "foo"})
+	require_NotNil(t, resp.Error)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSBadRequestErr))
+
+	// Have a replica lag behind, and store another message.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	lagging := c.randomNonStreamLeader(globalAccountName, "TEST")
+	nc.Close()
+	lagging.Shutdown()
+	c.waitOnLeader()
+
+	nc, js = jsClientConnect(t, sl)
+	defer nc.Close()
+	_, err = js.Publish("foo", []byte("second"))
+	require_NoError(t, err)
+
+	resp = getStatus(&JSApiMsgGetRequest{LastFor: "foo"})
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.Message.Sequence, 2)
+	require_Equal(t, string(resp.Message.Data), "second")
+	require_True(t, resp.Replication.Committed)
+	require_Equal(t, resp.Replication.Stored, 2)
+	require_Len(t, len(resp.Replication.Replicas), 3)
+	for _, rs := range resp.Replication.Replicas {
+		require_Equal(t, rs.Leader, rs.Name == sl.Name())
+		require_Equal(t, rs.Stored, rs.Name != lagging.Name())
+	}
+
+	// The first message remains stored on all replicas.
+	resp = getStatus(&JSApiMsgGetRequest{Seq: 1})
+	require_True(t, resp.Error == nil)
+	require_Equal(t, string(resp.Message.Data), "first")
+	require_True(t, resp.Replication.Committed)
+	require_Equal(t, resp.Replication.Stored, 3)
+}
+
+func TestJetStreamClusterStreamUpdateMigrateStorage(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: nats.MemoryStorage, Replicas: 3})
+	require_NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("foo", []byte(fmt.Sprintf("msg-%d", i)))
+		require_NoError(t, err)
+	}
+
+	req, err := json.Marshal(&StreamConfigRequest{
+		StreamConfig:   StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage, Replicas: 3},
+		MigrateStorage: true,
+	})
+	require_NoError(t, err)
+	msg, err := nc.Request(fmt.Sprintf(JSApiStreamUpdateT, "TEST"), req, 2*time.Second)
+	require_NoError(t, err)
+	var resp JSApiStreamUpdateResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	require_True(t, resp.Error == nil)
+
+	// All replicas move their messages to file storage.
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("TEST")
+			if err != nil {
+				return err
+			}
+			mset.mu.RLock()
+			store := mset.store
+			mset.mu.RUnlock()
+			if st := store.Type(); st != FileStorage {
+				return fmt.Errorf("%s: expected file storage, got %v", s.Name(), st)
+			}
+			var state StreamState
+			store.FastState(&state)
+			if state.Msgs != 10 {
+				return fmt.Errorf("%s: expected 10 messages, got %d", s.Name(), state.Msgs)
+			}
+			// The raft log is moved over as well.
+			n := mset.raftNode().(*raft)
+			n.RLock()
+			wtype := n.wtype
+			n.RUnlock()
+			if wtype != FileStorage {
+				return fmt.Errorf("%s: expected file storage for the log, got %v", s.Name(), wtype)
+			}
+			if rg := mset.raftGroup(); rg.Storage != FileStorage {
+				return fmt.Errorf("%s: expected file storage for the group, got %v", s.Name(), rg.Storage)
+			}
+		}
+		return nil
+	})
+
+	_, err = js.Publish("foo", []byte("msg-10"))
+	require_NoError(t, err)
+	for seq := uint64(1); seq <= 11; seq++ {
+		m, err := js.GetMsg("TEST", seq)
+		require_NoError(t, err)
+		require_Equal(t, string(m.Data), fmt.Sprintf("msg-%d", seq-1))
+	}
+
+	// And back, the file based log is removed.
+	req, err = json.Marshal(&StreamConfigRequest{
+		StreamConfig:   StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: MemoryStorage, Replicas: 3},
+		MigrateStorage: true,
+	})
+	require_NoError(t, err)
+	msg, err = nc.Request(fmt.Sprintf(JSApiStreamUpdateT, "TEST"), req, 2*time.Second)
+	require_NoError(t, err)
+	resp = JSApiStreamUpdateResponse{}
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	require_True(t, resp.Error == nil)
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("TEST")
+			if err != nil {
+				return err
+			}
+			n := mset.raftNode().(*raft)
+			n.RLock()
+			wtype, sd := n.wtype, n.sd
+			n.RUnlock()
+			if wtype != MemoryStorage {
+				return fmt.Errorf("%s: expected memory storage for the log, got %v", s.Name(), wtype)
+			}
+			if _, err := os.Stat(filepath.Join(sd, msgDir)); err == nil {
+				return fmt.Errorf("%s: file based log was not removed", s.Name())
+			}
+		}
+		return nil
+	})
+	_, err = js.Publish("foo", []byte("msg-11"))
+	require_NoError(t, err)
+
+	// Not proposed when a replica is on an older API level.
+	ml := c.leader()
+	for _, s := range c.servers {
+		if s != ml {
+			ml.nodeToInfo.Store(s.NodeName(), nodeInfo{stats: &JetStreamStats{API: JetStreamAPIStats{Level: JSApiLevel - 1}}})
+			break
+		}
+	}
+	req, err = json.Marshal(&StreamConfigRequest{
+		StreamConfig:   StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage, Replicas: 3},
+		MigrateStorage: true,
+	})
+	require_NoError(t, err)
+	msg, err = nc.Request(fmt.Sprintf(JSApiStreamUpdateT, "TEST"), req, 2*time.Second)
+	require_NoError(t, err)
+	resp = JSApiStreamUpdateResponse{}
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	require_NotNil(t, resp.Error)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSClusterPeersApiLevelErr))
+}
+
+func TestJetStreamClusterMultiStreamSnapshot(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	for _, name := range []string{"ITEMS", "ORDERS"} {
+		_, err := js.AddStream(&nats.StreamConfig{
+			Name:     name,
+			Subjects: []string{strings.ToLower(name)},
+			Replicas: 3,
+		})
+		require_NoError(t, err)
+	}
+
+	stepdown := func(stream, preferred string) {
+		t.Helper()
+		data, err := json.Marshal(JSApiLeaderStepdownRequest{Placement: &Placement{Preferred: preferred}})
+		require_NoError(t, err)
+		// The preferred server may not be current yet, so retry the stepdown until it took over.
+		checkFor(t, 10*time.Second, 250*time.Millisecond, func() error {
+			if sl := c.streamLeader(globalAccountName, stream); sl != nil && sl.Name() == preferred {
+				return nil
+			}
+			nc.Request(fmt.Sprintf(JSApiStreamLeaderStepDownT, stream), data, time.Second)
+			return fmt.Errorf("stream leader is not %s yet", preferred)
+		})
+	}
+
+	snapshot := func(streams ...string) *JSApiStreamMultiSnapshotResponse {
+		t.Helper()
+		data, err := json.Marshal(&JSApiStreamMultiSnapshotRequest{
+			Streams:       streams,
+			DeliverPrefix: "backup",
+			ChunkSize:     1024,
+		})
+		require_NoError(t, err)
+		rmsg, err := nc.Request(JSApiStreamMultiSnapshot, data, 5*time.Second)
+		require_NoError(t, err)
+		var resp JSApiStreamMultiSnapshotResponse
+		require_NoError(t, json.Unmarshal(rmsg.Data, &resp))
+		return &resp
+	}
+
+	// The streams need to be led by the same server.
+	sl := c.streamLeader(globalAccountName, "ITEMS")
+	if c.streamLeader(globalAccountName, "ORDERS") == sl {
+		stepdown("ORDERS", c.randomNonStreamLeader(globalAccountName, "ITEMS").Name())
+	}
+	resp := snapshot("ITEMS", "ORDERS")
+	require_True(t, IsNatsErr(resp.Error, JSStreamMultiSnapshotLeaderErr))
+	stepdown("ORDERS", sl.Name())
+
+	// Every order refers to an item, which is always published first.
+	var published atomic.Uint64
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		publish := func(subj string, n uint64) {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				id := fmt.Sprintf("%s-%d", subj, n)
+				if _, err := js.Publish(subj, []byte(strconv.FormatUint(n, 10)), nats.MsgId(id)); err == nil {
+					return
+				}
+			}
+		}
+		for n := uint64(1); ; n++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			publish("items", n)
+			publish("orders", n)
+			published.Store(n)
+		}
+	}()
+	checkFor(t, 5*time.Second, 10*time.Millisecond, func() error {
+		if n := published.Load(); n < 100 {
+			return fmt.Errorf("only published %d", n)
+		}
+		return nil
+	})
+
+	var mu sync.Mutex
+	chunks := make(map[string][]byte)
+	received := make(chan string, 2)
+	for _, name := range []string{"ITEMS", "ORDERS"} {
+		sub, err := nc.Subscribe("backup."+name, func(m *nats.Msg) {
+			// EOF
+			if len(m.Data) == 0 {
+				received <- name
+				return
+			}
+			mu.Lock()
+			chunks[name] = append(chunks[name], m.Data...)
+			mu.Unlock()
+			m.Respond(nil)
+		})
+		require_NoError(t, err)
+		defer sub.Unsubscribe()
+	}
+	require_NoError(t, nc.Flush())
+
+	resp = snapshot("ITEMS", "ORDERS")
+	require_True(t, resp.Error == nil)
+	require_Len(t, len(resp.Snapshots), 2)
+	for range 2 {
+		select {
+		case <-received:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Did not receive the snapshots in time")
+		}
+	}
+
+	// The streams make progress again after the snapshot.
+	checkFor(t, 5*time.Second, 10*time.Millisecond, func() error {
+		if n, cut := published.Load(), resp.Snapshots[0].State.LastSeq; n < cut+100 {
+			return fmt.Errorf("only published %d, snapshot at %d", n, cut)
+		}
+		return nil
+	})
+	close(stop)
+	<-done
+
+	// Restore the streams from the snapshots.
+	for _, sn := range resp.Snapshots {
+		name := sn.Config.Name
+		require_Equal(t, sn.DeliverSubject, "backup."+name)
+		require_NoError(t, js.DeleteStream(name))
+
+		data, err := json.Marshal(&JSApiStreamRestoreRequest{Config: sn.Config, State: sn.State})
+		require_NoError(t, err)
+		rmsg, err := nc.Request(fmt.Sprintf(JSApiStreamRestoreT, name), data, 5*time.Second)
+		require_NoError(t, err)
+		var rresp JSApiStreamRestoreResponse
+		require_NoError(t, json.Unmarshal(rmsg.Data, &rresp))
+		require_True(t, rresp.Error == nil)
+
+		snap := chunks[name]
+		for len(snap) > 0 {
+			n := min(len(snap), 1024)
+			_, err = nc.Request(rresp.DeliverSubject, snap[:n], time.Second)
+			require_NoError(t, err)
+			snap = snap[n:]
+		}
+		rmsg, err = nc.Request(rresp.DeliverSubject, nil, 5*time.Second)
+		require_NoError(t, err)
+		rresp.Error = nil
+		require_NoError(t, json.Unmarshal(rmsg.Data, &rresp))
+		require_True(t, rresp.Error == nil)
+
+		si, err := js.StreamInfo(name)
+		require_NoError(t, err)
+		require_Equal(t, si.State.Msgs, sn.State.Msgs)
+		require_Equal(t, si.State.LastSeq, sn.State.LastSeq)
+	}
+
+	// The restored streams need to reflect the same point, each order has its item, and
+	// at most the item of the next order was captured.
+	last := func(stream, subj string) uint64 {
+		t.Helper()
+		rsm, err := js.GetLastMsg(stream, subj)
+		require_NoError(t, err)
+		n, err := strconv.ParseUint(string(rsm.Data), 10, 64)
+		require_NoError(t, err)
+		return n
+	}
+	items, orders := last("ITEMS", "items"), last("ORDERS", "orders")
+	if items != orders && items != orders+1 {
+		t.Fatalf("Inconsistent snapshots, last item %d and last order %d", items, orders)
+	}
+
+	// Bad requests.
+	resp = snapshot()
+	require_True(t, IsNatsErr(resp.Error, JSBadRequestErr))
+	resp = snapshot("ITEMS", "ITEMS")
+	require_True(t, IsNatsErr(resp.Error, JSBadRequestErr))
+	resp = snapshot("ITEMS", "UNKNOWN")
+	require_True(t, IsNatsErr(resp.Error, JSStreamNotFoundErr))
+	resp = snapshot("UNKNOWN")
+	require_True(t, IsNatsErr(resp.Error, JSStreamNotFoundErr))
+}
+
+func TestJetStreamClusterRaftOpts(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir: '%s'}", "store_dir: '%s', raft: {max_append_entry_decode_failures: 3, slow_replica_timeout: 2s, slow_replica_threshold: 4, slow_replica_action: demote, recovery_time_objective: 30s, max_log_size: 64MB, apply_error_retries: 2, apply_error_backoff: 50ms, apply_error_policy: skip}}", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+	c.waitOnConsumerLeader(globalAccountName, "TEST", "C")
+
+	for _, s := range c.servers {
+		mset, err := s.globalAccount().lookupStream("TEST")
+		require_NoError(t, err)
+		o := mset.lookupConsumer("C")
+		require_NotNil(t, o)
+		for _, n := range []*raft{mset.raftNode().(*raft), o.raftNode().(*raft), s.getJetStream().getMetaGroup().(*raft)} {
+			n.RLock()
+			aedfMax, srto, srmax, sra, rto, maxwal := n.aedfMax, n.srto, n.srmax, n.sra, n.rto, n.maxwal
+			aerMax, aerBo, aerPol := n.aerMax, n.aerBo, n.aerPol
+			n.RUnlock()
+			require_Equal(t, aedfMax, 3)
+			require_Equal(t, aerMax, 2)
+			require_Equal(t, aerBo, 50*time.Millisecond)
+			require_Equal(t, aerPol, ApplyErrorSkip)
+			if n.Group() == defaultMetaGroupName {
+				continue
+			}
+			require_Equal(t, srto, 2*time.Second)
+			require_Equal(t, srmax, 4)
+			require_Equal(t, sra, SlowReplicaDemote)
+			require_Equal(t, rto, 30*time.Second)
+			require_Equal(t, maxwal, 64*1024*1024)
+		}
+	}
+}
+
+func TestJetStreamClusterStreamConsumerLag(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	for range 10 {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+
+	// Consumers at different positions, led by other servers than the stream leader,
+	// which doesn't know how far they are along.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	for name, n := range map[string]int{"A": 0, "B": 4, "C": 10} {
+		_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: name, AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+		require_NoError(t, err)
+		c.waitOnConsumerLeader(globalAccountName, "TEST", name)
+		if c.consumerLeader(globalAccountName, "TEST", name) == sl {
+			_, err = nc.Request(fmt.Sprintf(JSApiConsumerLeaderStepDownT, "TEST", name), nil, time.Second)
+			require_NoError(t, err)
+			checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+				if cl := c.consumerLeader(globalAccountName, "TEST", name); cl == nil || cl == sl {
+					return errors.New("consumer leader not moved yet")
+				}
+				return nil
+			})
+		}
+		if n > 0 {
+			sub, err := js.PullSubscribe("foo", name, nats.Bind("TEST", name))
+			require_NoError(t, err)
+			msgs, err := sub.Fetch(n)
+			require_NoError(t, err)
+			require_Len(t, len(msgs), n)
+		}
+	}
+
+	resp, err := nc.Request(fmt.Sprintf(JSApiStreamConsumerLagT, "TEST"), nil, 5*time.Second)
+	require_NoError(t, err)
+	var lr JSApiStreamConsumerLagResponse
+	require_NoError(t, json.Unmarshal(resp.Data, &lr))
+	require_True(t, lr.Error == nil)
+	require_Equal(t, lr.Consumers, 3)
+	require_Equal(t, lr.MaxLag, 10)
+	require_Equal(t, lr.MaxLagConsumer, "A")
+	require_Equal(t, lr.TotalLag, 16)
+	require_Len(t, len(lr.Missing), 0)
+}
+
+func TestJetStreamClusterStreamRebuildTotals(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	for i := 0; i < 20; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	c.waitOnStreamCurrent(c.streamLeader(globalAccountName, "TEST"), globalAccountName, "TEST")
+
+	// Make the totals drift on every replica.
+	for _, s := range c.servers {
+		mset, err := s.globalAccount().lookupStream("TEST")
+		require_NoError(t, err)
+		fs := mset.store.(*fileStore)
+		fs.mu.Lock()
+		fs.state.Msgs += 10
+		fs.mu.Unlock()
+	}
+
+	// Peer API levels are learned from statsz, so wait until they are known.
+	var rr JSApiStreamRebuildTotalsResponse
+	checkFor(t, 10*time.Second, 250*time.Millisecond, func() error {
+		resp, err := nc.Request(fmt.Sprintf(JSApiStreamRebuildTotalsT, "TEST"), nil, time.Second)
+		if err != nil {
+			return err
+		}
+		rr = JSApiStreamRebuildTotalsResponse{}
+		require_NoError(t, json.Unmarshal(resp.Data, &rr))
+		if rr.Error != nil {
+			return rr.Error
+		}
+		return nil
+	})
+	require_Equal(t, rr.Msgs, 20)
+	require_Equal(t, rr.MsgsDelta, -10)
+
+	// All replicas rebuild, not just the leader.
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("TEST")
+			if err != nil {
+				return err
+			}
+			if msgs := mset.state().Msgs; msgs != 20 {
+				return fmt.Errorf("%s has %d msgs", s, msgs)
+			}
+		}
+		return nil
+	})
+
+	// Peers that are unknown or on an older API level can't apply the entry.
+	s := c.randomServer()
+	require_True(t, s.peersSupportApiLevel([]string{s.NodeName()}, JSApiLevel))
+	require_False(t, s.peersSupportApiLevel([]string{"UNKNOWN"}, JSApiLevel))
+	s.nodeToInfo.Store("OLD", nodeInfo{stats: &JetStreamStats{API: JetStreamAPIStats{Level: JSApiLevel - 1}}})
+	require_False(t, s.peersSupportApiLevel([]string{s.NodeName(), "OLD"}, JSApiLevel))
+	s.nodeToInfo.Store("NEW", nodeInfo{stats: &JetStreamStats{API: JetStreamAPIStats{Level: JSApiLevel}}})
+	require_True(t, s.peersSupportApiLevel([]string{s.NodeName(), "NEW"}, JSApiLevel))
+}
+
+func TestJetStreamClusterStreamSnapshotInstallProgress(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+
+	// Publish through the stream leader, so the client isn't connected to the server we shut down.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	nc.Close()
+	nc, js = jsClientConnect(t, sl)
+	defer nc.Close()
+
+	rs := c.randomNonStreamLeader(globalAccountName, "TEST")
+	rs.Shutdown()
+
+	const numMsgs = 100
+	for i := 0; i < numMsgs; i++ {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+
+	// Snapshot on the leader, so the restarted server needs to catchup based on it.
+	mset, err := sl.GlobalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	require_NoError(t, mset.raftNode().InstallSnapshot(mset.stateSnapshot(), false))
+
+	rs = c.restartServer(rs)
+	c.waitOnStreamCurrent(rs, globalAccountName, "TEST")
+
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		mset, err := rs.GlobalAccount().lookupStream("TEST")
+		if err != nil {
+			return err
+		}
+		n, ok := mset.raftNode().(*raft)
+		if !ok {
+			return errors.New("raft node not started yet")
+		}
+		sip := n.Stats().SnapshotInstall
+		if sip == nil || sip.State != SnapshotInstallComplete {
+			return fmt.Errorf("snapshot install not complete: %+v", sip)
+		}
+		// The messages caught up from the leader are reported.
+		if sip.Total != numMsgs || sip.Received != sip.Total {
+			return fmt.Errorf("unexpected snapshot install progress: %+v", sip)
+		}
+		return nil
+	})
+}
+
+func TestJetStreamClusterStreamChecksumPerReplica(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("foo", []byte(fmt.Sprintf("msg-%d", i)))
+		require_NoError(t, err)
+	}
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		return checkState(t, c, globalAccountName, "TEST")
+	})
+
+	checksum := func(peer string) *JSApiStreamChecksumResponse {
+		t.Helper()
+		data, err := json.Marshal(&JSApiStreamChecksumRequest{EndSeq: 10, Peer: peer})
+		require_NoError(t, err)
+		resp, err := nc.Request(fmt.Sprintf(JSApiStreamChecksumT, "TEST"), data, time.Second)
+		require_NoError(t, err)
+		var cr JSApiStreamChecksumResponse
+		require_NoError(t, json.Unmarshal(resp.Data, &cr))
+		require_True(t, cr.Error == nil)
+		return &cr
+	}
+
+	// Without a peer the leader answers, and every replica can be asked by name.
+	lr := checksum(_EMPTY_)
+	require_Equal(t, lr.Server, c.streamLeader(globalAccountName, "TEST").Name())
+	for _, s := range c.servers {
+		cr := checksum(s.Name())
+		require_Equal(t, cr.Server, s.Name())
+		require_Equal(t, cr.Msgs, 10)
+		require_Equal(t, cr.Checksum, lr.Checksum)
+	}
+
+	// Unknown peers don't answer.
+	data, err := json.Marshal(&JSApiStreamChecksumRequest{Peer: "UNKNOWN"})
+	require_NoError(t, err)
+	_, err = nc.Request(fmt.Sprintf(JSApiStreamChecksumT, "TEST"), data, 250*time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+}
+
+func TestJetStreamClusterRotateStoreDir(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	// Rotating is rejected when clustered, and the store directory is left as is.
+	s := c.randomServer()
+	odir := s.StoreDir()
+	ndir := t.TempDir()
+	require_Error(t, s.RotateJetStreamStoreDir(ndir))
+	require_Equal(t, s.StoreDir(), odir)
+	fis, err := os.ReadDir(ndir)
+	require_NoError(t, err)
+	require_Len(t, len(fis), 0)
+}
+
+func TestJetStreamClusterStreamReserveCapacity(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{
+		Name:     "TEST",
+		Subjects: []string{"foo"},
+		MaxMsgs:  10,
+		Discard:  nats.DiscardNew,
+		Replicas: 3,
+	})
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+
+	reserve := func(req *JSApiStreamReserveRequest) *JSApiStreamReserveResponse {
+		t.Helper()
+		b, err := json.Marshal(req)
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiStreamReserveT, "TEST"), b, 2*time.Second)
+		require_NoError(t, err)
+		var resp JSApiStreamReserveResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+	publish := func(id string) error {
+		m := nats.NewMsg("foo")
+		if id != _EMPTY_ {
+			m.Header.Set(JSReservationId, id)
+		}
+		m.Data = []byte("data")
+		_, err := js.PublishMsg(m)
+		return err
+	}
+	checkReservations := func(n int) {
+		t.Helper()
+		checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+			for _, s := range c.servers {
+				mset, err := s.globalAccount().lookupStream("TEST")
+				if err != nil {
+					return err
+				}
+				mset.mu.RLock()
+				rn := len(mset.reservations)
+				mset.mu.RUnlock()
+				if rn != n {
+					return fmt.Errorf("expected %d reservations on %s, got %d", n, s.Name(), rn)
+				}
+			}
+			return nil
+		})
+	}
+
+	for i := 0; i < 2; i++ {
+		require_NoError(t, publish(_EMPTY_))
+	}
+	resp := reserve(&JSApiStreamReserveRequest{Msgs: 5})
+	require_True(t, resp.Error == nil)
+	require_True(t, resp.ID != _EMPTY_)
+	id := resp.ID
+
+	// All replicas track the reservation.
+	checkReservations(1)
+	resp = reserve(&JSApiStreamReserveRequest{Msgs: 5})
+	require_NotNil(t, resp.Error)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSStreamReservationExceedsLimitsErr))
+	for i := 0; i < 3; i++ {
+		require_NoError(t, publish(_EMPTY_))
+	}
+	require_Error(t, publish(_EMPTY_), NewJSStreamStoreFailedError(ErrMaxMsgs))
+
+	// Publishes with the reservation succeed on all replicas, until it's used up.
+	for i := 0; i < 5; i++ {
+		require_NoError(t, publish(id))
+	}
+	checkReservations(0)
+	checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("TEST")
+			if err != nil {
+				return err
+			}
+			if msgs := mset.state().Msgs; msgs != 10 {
+				return fmt.Errorf("expected 10 messages on %s, got %d", s.Name(), msgs)
+			}
+		}
+		return nil
+	})
+
+	// The leader expires the reservation on all replicas.
+	require_NoError(t, js.PurgeStream("TEST"))
+	resp = reserve(&JSApiStreamReserveRequest{Msgs: 8, TTL: 250 * time.Millisecond})
+	require_True(t, resp.Error == nil)
+	checkReservations(0)
+	for i := 0; i < 10; i++ {
+		require_NoError(t, publish(_EMPTY_))
+	}
+
+	// A new leader takes over expiring the reservation.
+	require_NoError(t, js.PurgeStream("TEST"))
+	resp = reserve(&JSApiStreamReserveRequest{Msgs: 8, TTL: time.Second})
+	require_True(t, resp.Error == nil)
+	checkReservations(1)
+	sl := c.streamLeader(globalAccountName, "TEST")
+	mset, err := sl.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	require_NoError(t, mset.raftNode().StepDown())
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+	require_NotEqual(t, c.streamLeader(globalAccountName, "TEST"), sl)
+	checkReservations(0)
+
+	// Snapshots hold the reservations, so a replica recovering from its
+	// snapshot still knows about them once it becomes leader.
+	require_NoError(t, js.PurgeStream("TEST"))
+	resp = reserve(&JSApiStreamReserveRequest{Msgs: 8, TTL: time.Minute})
+	require_True(t, resp.Error == nil)
+	checkReservations(1)
+	for _, s := range c.servers {
+		mset, err := s.globalAccount().lookupStream("TEST")
+		require_NoError(t, err)
+		require_NoError(t, mset.raftNode().InstallSnapshot(mset.stateSnapshot(), true))
+	}
+	rs := c.randomNonStreamLeader(globalAccountName, "TEST")
+	rs.Shutdown()
+	rs = c.restartServer(rs)
+	c.waitOnServerCurrent(rs)
+	checkReservations(1)
+	checkFor(t, 10*time.Second, 250*time.Millisecond, func() error {
+		if sl := c.streamLeader(globalAccountName, "TEST"); sl == rs {
+			return nil
+		} else if sl != nil {
+			mset, err := sl.globalAccount().lookupStream("TEST")
+			if err != nil {
+				return err
+			}
+			mset.raftNode().StepDown()
+		}
+		c.waitOnStreamLeader(globalAccountName, "TEST")
+		return fmt.Errorf("restarted server is not the stream leader")
+	})
+	for i := 0; i < 2; i++ {
+		require_NoError(t, publish(_EMPTY_))
+	}
+	require_Error(t, publish(_EMPTY_), NewJSStreamStoreFailedError(ErrMaxMsgs))
+	require_NoError(t, publish(resp.ID))
+}
@@ -12358,3 +12358,1755 @@ func TestJetStreamConsumerStreamNumPendingClearedOnStepDown(t *testing.T) {
 		return nil
 	})
 }
+
+func TestJetStreamConsumerPushMaxInFlight(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	mset, err := s.GlobalAccount().addStream(&StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+
+	// Check validation.
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "PULL", AckPolicy: AckExplicit, MaxInFlight: 5})
+	require_Error(t, err, NewJSConsumerMaxInFlightRequiresPushError())
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "NOACK", DeliverSubject: "d", AckPolicy: AckNone, MaxInFlight: 5})
+	require_Error(t, err, NewJSConsumerMaxInFlightAckPolicyRequiredError())
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "NEG", DeliverSubject: "d", AckPolicy: AckExplicit, MaxInFlight: -1})
+	require_Error(t, err, NewJSConsumerMaxInFlightNegativeError())
+
+	const maxInFlight = 5
+	o, err := mset.addConsumer(&ConsumerConfig{
+		Durable:        "CONSUMER",
+		DeliverSubject: "deliver",
+		DeliverGroup:   "workers",
+		AckPolicy:      AckExplicit,
+		AckWait:        time.Minute,
+		MaxAckPending:  1000,
+		MaxInFlight:    maxInFlight,
+	})
+	require_NoError(t, err)
+
+	var subs []*nats.Subscription
+	for range 3 {
+		subs = append(subs, natsQueueSubSync(t, nc, "deliver", "workers"))
+	}
+	natsFlush(t, nc)
+
+	for range 50 {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+
+	received := func() int {
+		var total int
+		for _, sub := range subs {
+			n, _, _ := sub.Pending()
+			total += n
+		}
+		return total
+	}
+	checkReceived := func(expected int) {
+		t.Helper()
+		checkFor(t, 2*time.Second, 20*time.Millisecond, func() error {
+			if n := received(); n != expected {
+				return fmt.Errorf("expected %d received, got %d", expected, n)
+			}
+			return nil
+		})
+		// Make sure we don't receive any more.
+		time.Sleep(100 * time.Millisecond)
+		require_Equal(t, received(), expected)
+	}
+	checkReceived(maxInFlight)
+
+	fetch := func() []*nats.Msg {
+		t.Helper()
+		var msgs []*nats.Msg
+		for _, sub := range subs {
+			n, _, _ := sub.Pending()
+			for range n {
+				msgs = append(msgs, natsNexMsg(t, sub, time.Second))
+			}
+		}
+		return msgs
+	}
+
+	// Acking frees up slots across the group.
+	msgs := fetch()
+	require_Len(t, len(msgs), maxInFlight)
+	for _, m := range msgs[:2] {
+		require_NoError(t, m.AckSync())
+	}
+	checkReceived(2)
+
+	o.mu.RLock()
+	inFlight := len(o.pending) - len(o.rdq)
+	o.mu.RUnlock()
+	require_Equal(t, inFlight, maxInFlight)
+
+	// Nak'd messages are no longer in flight, so they can be redelivered.
+	msgs = fetch()
+	require_Len(t, len(msgs), 2)
+	for _, m := range msgs {
+		require_NoError(t, m.Nak())
+	}
+	checkReceived(2)
+}
+
+func TestJetStreamConsumerPushNoInterestPolicy(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	mset, err := s.GlobalAccount().addStream(&StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+
+	// Check validation.
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "PULL", AckPolicy: AckExplicit, NoInterestPolicy: NoInterestContinue})
+	require_Error(t, err, NewJSConsumerNoInterestPolicyRequiresPushError())
+
+	for range 10 {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+
+	advSub := natsSubSync(t, nc, JSAdvisoryConsumerNoInterestPre+".>")
+	natsFlush(t, nc)
+
+	checkDelivered := func(o *consumer, expected uint64) {
+		t.Helper()
+		checkFor(t, 2*time.Second, 20*time.Millisecond, func() error {
+			if delivered := o.info().Delivered.Stream; delivered != expected {
+				return fmt.Errorf("expected %d delivered, got %d", expected, delivered)
+			}
+			return nil
+		})
+	}
+
+	// Pausing without interest is the default.
+	paused, err := mset.addConsumer(&ConsumerConfig{Durable: "PAUSE", DeliverSubject: "d.pause", AckPolicy: AckNone})
+	require_NoError(t, err)
+	cont, err := mset.addConsumer(&ConsumerConfig{
+		Durable:          "CONTINUE",
+		DeliverSubject:   "d.continue",
+		AckPolicy:        AckNone,
+		NoInterestPolicy: NoInterestContinue,
+	})
+	require_NoError(t, err)
+
+	// Only the consumer configured to continue delivers without a subscriber.
+	checkDelivered(cont, 10)
+	time.Sleep(100 * time.Millisecond)
+	require_Equal(t, paused.info().Delivered.Stream, 0)
+
+	// Delivery resumes once a subscriber appears.
+	sub := natsSubSync(t, nc, "d.pause")
+	checkSubsPending(t, sub, 10)
+	checkDelivered(paused, 10)
+
+	// Losing interest sends an advisory when configured to continue, and delivery continues.
+	csub := natsSubSync(t, nc, "d.continue")
+	natsFlush(t, nc)
+	checkFor(t, time.Second, 20*time.Millisecond, func() error {
+		if !cont.isActive() {
+			return errors.New("consumer not active")
+		}
+		return nil
+	})
+	require_NoError(t, csub.Unsubscribe())
+	msg := natsNexMsg(t, advSub, time.Second)
+	require_Equal(t, msg.Subject, JSAdvisoryConsumerNoInterestPre+".TEST.CONTINUE")
+	var adv JSConsumerNoInterestAdvisory
+	require_NoError(t, json.Unmarshal(msg.Data, &adv))
+	require_Equal(t, adv.Type, JSConsumerNoInterestAdvisoryType)
+	require_Equal(t, adv.Stream, "TEST")
+	require_Equal(t, adv.Consumer, "CONTINUE")
+	require_Equal(t, adv.DeliverSubject, "d.continue")
+
+	require_NoError(t, sub.Unsubscribe())
+	natsFlush(t, nc)
+	for range 5 {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+	checkDelivered(cont, 15)
+	time.Sleep(100 * time.Millisecond)
+	require_Equal(t, paused.info().Delivered.Stream, 10)
+
+	// No advisory is sent for a consumer pausing without interest.
+	_, err = advSub.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+}
+
+func TestJetStreamConsumerRetentionSkipPolicy(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage, MaxMsgs: 10})
+	require_NoError(t, err)
+
+	// Check validation.
+	_, err = jsConsumerCreate(t, nc, "TEST", ConsumerConfig{Durable: "BAD", AckPolicy: AckExplicit, RetentionSkipSubject: "skips"}, false)
+	require_Error(t, err, NewJSConsumerRetentionSkipInvalidError(errors.New("subject requires a policy other than ignore")))
+	_, err = jsConsumerCreate(t, nc, "TEST", ConsumerConfig{
+		Durable:              "BAD",
+		AckPolicy:            AckExplicit,
+		RetentionSkipPolicy:  RetentionSkipAdvise,
+		RetentionSkipSubject: "skips.*",
+	}, false)
+	require_Error(t, err, NewJSConsumerRetentionSkipInvalidError(errors.New("subject must be a valid literal subject")))
+
+	pause := ConsumerConfig{Durable: "PAUSE", AckPolicy: AckExplicit, RetentionSkipPolicy: RetentionSkipPause, RetentionSkipSubject: "skips"}
+	for _, cfg := range []ConsumerConfig{
+		{Durable: "IGNORE", AckPolicy: AckExplicit},
+		{Durable: "ADVISE", AckPolicy: AckExplicit, RetentionSkipPolicy: RetentionSkipAdvise},
+		pause,
+	} {
+		_, err = jsConsumerCreate(t, nc, "TEST", cfg, false)
+		require_NoError(t, err)
+	}
+
+	subs := make(map[string]*nats.Subscription)
+	for _, name := range []string{"IGNORE", "ADVISE", "PAUSE"} {
+		sub, err := js.PullSubscribe(_EMPTY_, name, nats.Bind("TEST", name))
+		require_NoError(t, err)
+		subs[name] = sub
+	}
+	// Fetches the next message, returning its stream sequence.
+	next := func(name string) uint64 {
+		t.Helper()
+		msgs := fetchMsgs(t, subs[name], 1, time.Second)
+		require_NoError(t, msgs[0].AckSync())
+		meta, err := msgs[0].Metadata()
+		require_NoError(t, err)
+		return meta.Sequence.Stream
+	}
+
+	// Every consumer delivers the first couple of messages.
+	for range 5 {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+	for _, name := range []string{"IGNORE", "ADVISE", "PAUSE"} {
+		require_Equal(t, next(name), 1)
+		require_Equal(t, next(name), 2)
+	}
+
+	advSub := natsSubSync(t, nc, JSAdvisoryConsumerRetentionSkipPre+".>")
+	skipSub := natsSubSync(t, nc, "skips")
+	natsFlush(t, nc)
+
+	// Retention outruns the consumers, removing sequences 3 through 15.
+	for range 20 {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+
+	checkAdvisory := func(msg *nats.Msg, consumer string, paused bool) {
+		t.Helper()
+		var adv JSConsumerRetentionSkipAdvisory
+		require_NoError(t, json.Unmarshal(msg.Data, &adv))
+		require_Equal(t, adv.Type, JSConsumerRetentionSkipAdvisoryType)
+		require_Equal(t, adv.Stream, "TEST")
+		require_Equal(t, adv.Consumer, consumer)
+		require_Equal(t, adv.FirstSeq, 3)
+		require_Equal(t, adv.LastSeq, 15)
+		require_Equal(t, adv.Paused, paused)
+	}
+
+	// Skipping silently is the default.
+	require_Equal(t, next("IGNORE"), 16)
+	_, err = advSub.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	// Advising skips, and sends an advisory.
+	require_Equal(t, next("ADVISE"), 16)
+	msg := natsNexMsg(t, advSub, time.Second)
+	require_Equal(t, msg.Subject, JSAdvisoryConsumerRetentionSkipPre+".TEST.ADVISE")
+	checkAdvisory(msg, "ADVISE", false)
+
+	// Pausing holds at the removed messages, and also notifies on the configured subject.
+	_, err = subs["PAUSE"].Fetch(1, nats.MaxWait(250*time.Millisecond))
+	require_Error(t, err, nats.ErrTimeout)
+	msg = natsNexMsg(t, advSub, time.Second)
+	require_Equal(t, msg.Subject, JSAdvisoryConsumerRetentionSkipPre+".TEST.PAUSE")
+	checkAdvisory(msg, "PAUSE", true)
+	checkAdvisory(natsNexMsg(t, skipSub, time.Second), "PAUSE", true)
+
+	// Only notified once while holding.
+	_, err = subs["PAUSE"].Fetch(1, nats.MaxWait(250*time.Millisecond))
+	require_Error(t, err, nats.ErrTimeout)
+	_, err = advSub.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	// Updating the consumer resumes it past the removed messages.
+	pause.Description = "resumed"
+	_, err = jsConsumerCreate(t, nc, "TEST", pause, false)
+	require_NoError(t, err)
+	require_Equal(t, next("PAUSE"), 16)
+	_, err = advSub.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	// Filtered consumers only count removed messages that matched their filters.
+	_, err = jsStreamCreate(t, nc, &StreamConfig{Name: "FILTERED", Subjects: []string{"bar.*"}, Storage: FileStorage, MaxMsgs: 5})
+	require_NoError(t, err)
+	_, err = jsConsumerCreate(t, nc, "FILTERED", ConsumerConfig{
+		Durable:             "F",
+		AckPolicy:           AckExplicit,
+		FilterSubject:       "bar.a",
+		RetentionSkipPolicy: RetentionSkipAdvise,
+	}, false)
+	require_NoError(t, err)
+	sub, err := js.PullSubscribe("bar.a", "F", nats.Bind("FILTERED", "F"))
+	require_NoError(t, err)
+	subs["F"] = sub
+	publish := func(subj string, n int) {
+		t.Helper()
+		for range n {
+			_, err = js.Publish(subj, nil)
+			require_NoError(t, err)
+		}
+	}
+	publish("bar.a", 1)
+	require_Equal(t, next("F"), 1)
+
+	// Retention removing messages on other subjects is skipped silently.
+	publish("bar.b", 10)
+	publish("bar.a", 1)
+	require_Equal(t, next("F"), 12)
+	_, err = advSub.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	// But not when it removes one of ours.
+	publish("bar.a", 1)
+	publish("bar.b", 5)
+	publish("bar.a", 1)
+	require_Equal(t, next("F"), 19)
+	msg = natsNexMsg(t, advSub, time.Second)
+	require_Equal(t, msg.Subject, JSAdvisoryConsumerRetentionSkipPre+".FILTERED.F")
+	var adv JSConsumerRetentionSkipAdvisory
+	require_NoError(t, json.Unmarshal(msg.Data, &adv))
+	require_Equal(t, adv.FirstSeq, 13)
+	require_Equal(t, adv.LastSeq, 14)
+}
+
+func TestJetStreamConsumerReplayWindow(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo.*"}})
+	require_NoError(t, err)
+
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{
+		Durable:       "C",
+		FilterSubject: "foo.a",
+		AckPolicy:     nats.AckExplicitPolicy,
+	})
+	require_NoError(t, err)
+
+	pub := func(subj string, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			_, err := js.Publish(subj, []byte("ok"))
+			require_NoError(t, err)
+			_, err = js.Publish("foo.b", []byte("skip"))
+			require_NoError(t, err)
+		}
+	}
+
+	// Sequences 1-6 before the window, 7-12 inside it and 13-16 after it.
+	pub("foo.a", 3)
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	pub("foo.a", 3)
+	end := time.Now()
+	time.Sleep(50 * time.Millisecond)
+	pub("foo.a", 2)
+
+	// Give the live consumer some state of its own.
+	sub, err := js.PullSubscribe("foo.a", "C")
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(2)
+	require_NoError(t, err)
+	for _, m := range msgs {
+		require_NoError(t, m.AckSync())
+	}
+	before, err := js.ConsumerInfo("TEST", "C")
+	require_NoError(t, err)
+
+	replay := func(req *JSApiConsumerReplayRequest) *JSApiConsumerReplayResponse {
+		t.Helper()
+		b, err := json.Marshal(req)
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiConsumerReplayT, "TEST", "C"), b, time.Second)
+		require_NoError(t, err)
+		var resp JSApiConsumerReplayResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+
+	scratch := natsSubSync(t, nc, "scratch")
+	natsFlush(t, nc)
+
+	resp := replay(&JSApiConsumerReplayRequest{StartTime: start, EndTime: end, DeliverSubject: "scratch"})
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.Delivered, 3)
+	require_False(t, resp.Truncated)
+
+	for _, seq := range []uint64{7, 9, 11} {
+		m := natsNexMsg(t, scratch, time.Second)
+		require_Equal(t, m.Header.Get(JSStream), "TEST")
+		require_Equal(t, m.Header.Get(JSSubject), "foo.a")
+		require_Equal(t, m.Header.Get(JSSequence), strconv.FormatUint(seq, 10))
+		require_Equal(t, string(m.Data), "ok")
+	}
+	_, err = scratch.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	// The live consumer is untouched.
+	after, err := js.ConsumerInfo("TEST", "C")
+	require_NoError(t, err)
+	require_Equal(t, after.Delivered.Consumer, before.Delivered.Consumer)
+	require_Equal(t, after.Delivered.Stream, before.Delivered.Stream)
+	require_Equal(t, after.AckFloor.Stream, before.AckFloor.Stream)
+	require_Equal(t, after.NumPending, before.NumPending)
+	require_Equal(t, after.NumAckPending, 0)
+
+	// The replay stops at its limits.
+	resp = replay(&JSApiConsumerReplayRequest{StartTime: start, EndTime: end, DeliverSubject: "scratch", MaxMsgs: 2})
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.Delivered, 2)
+	require_True(t, resp.Truncated)
+	for _, seq := range []uint64{7, 9} {
+		m := natsNexMsg(t, scratch, time.Second)
+		require_Equal(t, m.Header.Get(JSSequence), strconv.FormatUint(seq, 10))
+	}
+	_, err = scratch.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	// Bad windows and deliver subjects are rejected.
+	resp = replay(&JSApiConsumerReplayRequest{StartTime: end, EndTime: start, DeliverSubject: "scratch"})
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSConsumerReplayInvalidWindowErr))
+
+	resp = replay(&JSApiConsumerReplayRequest{StartTime: start, EndTime: end, DeliverSubject: "scratch.*"})
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSConsumerInvalidDeliverSubject))
+
+	resp = replay(&JSApiConsumerReplayRequest{StartTime: start, EndTime: end, DeliverSubject: "foo.c"})
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSConsumerReplayDeliverSubjectCycleErr))
+
+	si, err := js.StreamInfo("TEST")
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 16)
+}
+
+func TestJetStreamConsumerReplayWindowPermissions(t *testing.T) {
+	conf := `
+	listen: 127.0.0.1:-1
+	server_name: %s
+	jetstream: {
+		store_dir: '%s',
+	}
+	cluster {
+		name: %s
+		listen: 127.0.0.1:%d
+		routes = [%s]
+	}
+	system_account: sys
+	no_auth_user: js
+	accounts {
+	  sys {
+	    users = [
+	      { user: sys, pass: sys }
+	    ]
+	  }
+	  js {
+	    jetstream = enabled
+	    users = [
+	      { user: js, pass: js }
+	      { user: limited, pass: limited, permissions: { publish: { deny: "secret" } } }
+	    ]
+	  }
+	}`
+	c := createJetStreamClusterWithTemplate(t, conf, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+	require_NoError(t, err)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	end := time.Now()
+	c.waitOnConsumerLeader("js", "TEST", "C")
+
+	secret := natsSubSync(t, nc, "secret")
+	allowed := natsSubSync(t, nc, "allowed")
+	natsFlush(t, nc)
+
+	// Permissions are checked by the server the requester is connected to, so check
+	// them on the consumer leader as well as on the followers.
+	for _, s := range c.servers {
+		lnc, _ := jsClientConnect(t, s, nats.UserInfo("limited", "limited"))
+		defer lnc.Close()
+
+		replay := func(deliver string) *JSApiConsumerReplayResponse {
+			t.Helper()
+			req, err := json.Marshal(&JSApiConsumerReplayRequest{StartTime: start, EndTime: end, DeliverSubject: deliver})
+			require_NoError(t, err)
+			rmsg, err := lnc.Request(fmt.Sprintf(JSApiConsumerReplayT, "TEST", "C"), req, 2*time.Second)
+			require_NoError(t, err)
+			var resp JSApiConsumerReplayResponse
+			require_NoError(t, json.Unmarshal(rmsg.Data, &resp))
+			return &resp
+		}
+
+		resp := replay("secret")
+		require_NotNil(t, resp.Error)
+		require_Equal(t, ErrorIdentifier(resp.Error.ErrCode), JSConsumerReplayNotPermittedErrF)
+
+		resp = replay("allowed")
+		require_True(t, resp.Error == nil)
+		require_Equal(t, resp.Delivered, 3)
+		for i := 0; i < 3; i++ {
+			natsNexMsg(t, allowed, time.Second)
+		}
+	}
+
+	_, err = secret.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+	_, err = allowed.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+}
+
+func TestJetStreamConsumerFirstDeliveryAdvisory(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+
+	asub := natsSubSync(t, nc, JSAdvisoryConsumerFirstDeliveryPre+".>")
+	natsFlush(t, nc)
+
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+
+	// Nothing until a message is delivered.
+	_, err = asub.NextMsg(100 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	for i := 0; i < 5; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+
+	sub, err := js.PullSubscribe("foo", "C")
+	require_NoError(t, err)
+	fetch := func(n int) {
+		t.Helper()
+		msgs, err := sub.Fetch(n, nats.MaxWait(time.Second))
+		require_NoError(t, err)
+		require_Len(t, len(msgs), n)
+		for _, m := range msgs {
+			require_NoError(t, m.AckSync())
+		}
+	}
+	fetch(2)
+	fetch(2)
+
+	checkAdvisory := func(cseq, sseq uint64) {
+		t.Helper()
+		msg := natsNexMsg(t, asub, time.Second)
+		require_Equal(t, msg.Subject, JSAdvisoryConsumerFirstDeliveryPre+".TEST.C")
+		var adv JSConsumerFirstDeliveryAdvisory
+		require_NoError(t, json.Unmarshal(msg.Data, &adv))
+		require_Equal(t, adv.Type, JSConsumerFirstDeliveryAdvisoryType)
+		require_Equal(t, adv.Stream, "TEST")
+		require_Equal(t, adv.Consumer, "C")
+		require_Equal(t, adv.ConsumerSeq, cseq)
+		require_Equal(t, adv.StreamSeq, sseq)
+		// Exactly once.
+		_, err := asub.NextMsg(250 * time.Millisecond)
+		require_Error(t, err, nats.ErrTimeout)
+	}
+	checkAdvisory(1, 1)
+
+	// After a restart the consumer signals its first delivery again.
+	sd := s.JetStreamConfig().StoreDir
+	nc.Close()
+	s.Shutdown()
+	s = RunJetStreamServerOnPort(-1, sd)
+	defer s.Shutdown()
+
+	nc, js = jsClientConnect(t, s)
+	defer nc.Close()
+	asub = natsSubSync(t, nc, JSAdvisoryConsumerFirstDeliveryPre+".>")
+	natsFlush(t, nc)
+
+	sub, err = js.PullSubscribe("foo", "C")
+	require_NoError(t, err)
+	fetch(1)
+	checkAdvisory(5, 5)
+}
+
+func TestJetStreamConsumerEstimate(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo.*", "bar"}})
+	require_NoError(t, err)
+
+	// 30 messages across 3 subjects, with a time marker in the middle.
+	var midTime time.Time
+	for i := 0; i < 30; i++ {
+		if i == 15 {
+			time.Sleep(10 * time.Millisecond)
+			midTime = time.Now()
+		}
+		subj := []string{"foo.a", "foo.b", "bar"}[i%3]
+		_, err = js.Publish(subj, []byte("ok"))
+		require_NoError(t, err)
+	}
+	// Remove a few so the first sequences are not trivial.
+	for _, seq := range []uint64{1, 2, 4} {
+		require_NoError(t, js.DeleteMsg("TEST", seq))
+	}
+
+	estimate := func(cfg *ConsumerConfig) *JSApiConsumerEstimateResponse {
+		t.Helper()
+		req, err := json.Marshal(&JSApiConsumerEstimateRequest{Config: *cfg})
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiConsumerEstimateT, "TEST"), req, time.Second)
+		require_NoError(t, err)
+		var resp JSApiConsumerEstimateResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+
+	for i, cfg := range []*ConsumerConfig{
+		{DeliverPolicy: DeliverAll},
+		{DeliverPolicy: DeliverAll, FilterSubject: "foo.a"},
+		{DeliverPolicy: DeliverAll, FilterSubject: "foo.*"},
+		{DeliverPolicy: DeliverAll, FilterSubjects: []string{"foo.b", "bar"}},
+		{DeliverPolicy: DeliverLast},
+		{DeliverPolicy: DeliverLast, FilterSubject: "foo.a"},
+		{DeliverPolicy: DeliverLastPerSubject, FilterSubject: "bar"},
+		{DeliverPolicy: DeliverLastPerSubject, FilterSubject: "foo.*"},
+		{DeliverPolicy: DeliverLastPerSubject, FilterSubjects: []string{"foo.a", "bar"}},
+		{DeliverPolicy: DeliverNew},
+		{DeliverPolicy: DeliverByStartSequence, OptStartSeq: 20},
+		{DeliverPolicy: DeliverByStartSequence, OptStartSeq: 20, FilterSubject: "bar"},
+		{DeliverPolicy: DeliverByStartTime, OptStartTime: &midTime},
+		{DeliverPolicy: DeliverByStartTime, OptStartTime: &midTime, FilterSubject: "foo.b"},
+		{DeliverPolicy: DeliverAll, FilterSubject: "baz"},
+	} {
+		t.Run(fmt.Sprintf("%d-%s", i, cfg.DeliverPolicy), func(t *testing.T) {
+			cfg.AckPolicy = AckExplicit
+			resp := estimate(cfg)
+			if resp.Error != nil {
+				t.Fatalf("Unexpected error: %+v", resp.Error)
+			}
+
+			// Nothing was created.
+			require_Equal(t, len(s.globalAccount().streams()[0].getPublicConsumers()), 0)
+
+			// Compare with a real consumer.
+			mset, err := s.globalAccount().lookupStream("TEST")
+			require_NoError(t, err)
+			ccfg := *cfg
+			ccfg.Durable = "C"
+			o, err := mset.addConsumer(&ccfg)
+			require_NoError(t, err)
+			defer o.delete()
+			ci := o.info()
+			require_Equal(t, resp.NumPending, ci.NumPending)
+
+			var first uint64
+			if ci.NumPending > 0 {
+				m, err := nc.Request(fmt.Sprintf(JSApiRequestNextT, "TEST", "C"), nil, time.Second)
+				require_NoError(t, err)
+				first, _, _, _, _ = ackReplyInfo(m.Reply)
+			}
+			require_Equal(t, resp.FirstSeq, first)
+		})
+	}
+
+	// Invalid configurations, and unknown streams, return errors.
+	resp := estimate(&ConsumerConfig{DeliverPolicy: DeliverLastPerSubject})
+	require_True(t, resp.Error != nil)
+	resp = estimate(&ConsumerConfig{DeliverPolicy: DeliverByStartSequence})
+	require_True(t, resp.Error != nil)
+
+	msg, err := nc.Request(fmt.Sprintf(JSApiConsumerEstimateT, "NOPE"), []byte(`{"config":{}}`), time.Second)
+	require_NoError(t, err)
+	var nresp JSApiConsumerEstimateResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &nresp))
+	require_True(t, nresp.Error != nil)
+	require_Equal(t, nresp.Error.ErrCode, uint16(JSStreamNotFoundErr))
+}
+
+func TestJetStreamConsumerHibernation(t *testing.T) {
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		jetstream: {store_dir: %q, consumer_hibernate_after: 250ms}
+	`, t.TempDir())))
+	s, opts := RunServerWithConfig(conf)
+	defer s.Shutdown()
+	require_Equal(t, opts.JetStreamConsumerHibernate, 250*time.Millisecond)
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+
+	mset, err := s.GlobalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+
+	// Consume and ack the first 3 messages on a bunch of durables.
+	const numDurables = 50
+	subs := make([]*nats.Subscription, 0, numDurables)
+	for i := 0; i < numDurables; i++ {
+		sub, err := js.PullSubscribe("foo", fmt.Sprintf("D%d", i))
+		require_NoError(t, err)
+		msgs, err := sub.Fetch(3)
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 3)
+		for _, m := range msgs {
+			require_NoError(t, m.AckSync())
+		}
+		subs = append(subs, sub)
+	}
+
+	// A push durable that consumed everything and lost interest.
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{
+		Durable:        "PUSH",
+		DeliverSubject: "push",
+		AckPolicy:      nats.AckExplicitPolicy,
+	})
+	require_NoError(t, err)
+	psub := natsSubSync(t, nc, "push")
+	for i := 0; i < 10; i++ {
+		m := natsNexMsg(t, psub, time.Second)
+		require_NoError(t, m.AckSync())
+	}
+	require_NoError(t, psub.Unsubscribe())
+
+	// Ephemerals never hibernate.
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+
+	checkHibernated := func(expected bool) {
+		t.Helper()
+		checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+			for _, o := range mset.getConsumers() {
+				if o.isHibernated() != (expected && o.isDurable()) {
+					return fmt.Errorf("consumer %q hibernated is %v", o.name, o.isHibernated())
+				}
+			}
+			return nil
+		})
+	}
+	checkHibernated(true)
+
+	o := mset.lookupConsumer("D0")
+	o.mu.RLock()
+	pending, qch := o.pending, o.qch
+	o.mu.RUnlock()
+	require_True(t, pending == nil)
+	require_True(t, qch != nil)
+
+	// Messages stored while hibernating.
+	for i := 0; i < 5; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+
+	// Pull requests wake the durables up and they resume where they left off.
+	for i, sub := range subs {
+		msgs, err := sub.Fetch(2)
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 2)
+		for j, m := range msgs {
+			meta, err := m.Metadata()
+			require_NoError(t, err)
+			require_Equal(t, meta.Sequence.Stream, uint64(4+j))
+			require_Equal(t, meta.Sequence.Consumer, uint64(4+j))
+			require_Equal(t, meta.NumDelivered, 1)
+			require_NoError(t, m.AckSync())
+		}
+		name := fmt.Sprintf("D%d", i)
+		require_False(t, mset.lookupConsumer(name).isHibernated())
+		ci, err := js.ConsumerInfo("TEST", name)
+		require_NoError(t, err)
+		require_Equal(t, ci.AckFloor.Stream, 5)
+		require_Equal(t, ci.NumPending, 10)
+		require_Equal(t, ci.NumAckPending, 0)
+	}
+
+	// Interest on the delivery subject wakes up the push durable.
+	psub = natsSubSync(t, nc, "push")
+	m := natsNexMsg(t, psub, time.Second)
+	meta, err := m.Metadata()
+	require_NoError(t, err)
+	require_Equal(t, meta.Sequence.Stream, 11)
+	require_NoError(t, m.AckSync())
+	pc := mset.lookupConsumer("PUSH")
+	require_False(t, pc.isHibernated())
+	for i := 12; i <= 15; i++ {
+		m = natsNexMsg(t, psub, time.Second)
+		meta, err = m.Metadata()
+		require_NoError(t, err)
+		require_Equal(t, meta.Sequence.Stream, uint64(i))
+		require_NoError(t, m.AckSync())
+	}
+
+	// Pending acks keep a consumer awake.
+	msgs, err := subs[0].Fetch(1)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 1)
+	time.Sleep(600 * time.Millisecond)
+	require_False(t, o.isHibernated())
+	require_NoError(t, msgs[0].AckSync())
+
+	// Once idle again all durables go back to sleep.
+	require_NoError(t, psub.Unsubscribe())
+	checkHibernated(true)
+}
+
+func TestJetStreamConsumerMsgHistory(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo.*"}})
+	require_NoError(t, err)
+	for _, subj := range []string{"foo.a", "foo.a", "foo.b", "foo.a", "foo.a", "foo.a"} {
+		_, err = js.Publish(subj, []byte("ok"))
+		require_NoError(t, err)
+	}
+
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{
+		Durable:       "C",
+		FilterSubject: "foo.a",
+		AckPolicy:     nats.AckExplicitPolicy,
+		MaxDeliver:    3,
+	})
+	require_NoError(t, err)
+
+	history := func(consumer string, seq uint64) *JSApiConsumerMsgHistoryResponse {
+		t.Helper()
+		req, err := json.Marshal(&JSApiConsumerMsgHistoryRequest{Seq: seq})
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiConsumerMsgHistoryT, "TEST", consumer), req, time.Second)
+		require_NoError(t, err)
+		var resp JSApiConsumerMsgHistoryResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+	checkState := func(seq uint64, state string, delivered uint64) *ConsumerMsgHistory {
+		t.Helper()
+		resp := history("C", seq)
+		require_True(t, resp.Error == nil)
+		require_Equal(t, resp.Stream, seq)
+		require_Equal(t, resp.State, state)
+		require_Equal(t, resp.Delivered, delivered)
+		return resp.ConsumerMsgHistory
+	}
+
+	sub, err := js.PullSubscribe("foo.a", "C", nats.Bind("TEST", "C"))
+	require_NoError(t, err)
+	fetch := func(seq uint64) *nats.Msg {
+		t.Helper()
+		msgs, err := sub.Fetch(1)
+		require_NoError(t, err)
+		meta, err := msgs[0].Metadata()
+		require_NoError(t, err)
+		require_Equal(t, meta.Sequence.Stream, seq)
+		return msgs[0]
+	}
+	nak := func(m *nats.Msg) {
+		t.Helper()
+		_, err := nc.Request(m.Reply, []byte("-NAK"), time.Second)
+		require_NoError(t, err)
+	}
+
+	// Nothing delivered yet.
+	checkState(1, ConsumerMsgUndelivered, 0)
+	checkState(3, ConsumerMsgFiltered, 0)
+
+	// Delivered and redelivered.
+	start := time.Now()
+	nak(fetch(1))
+	mh := checkState(1, ConsumerMsgPending, 1)
+	require_Equal(t, mh.Consumer, 1)
+	require_True(t, mh.LastDelivered != nil && !mh.LastDelivered.Before(start.Add(-time.Second)))
+	first := *mh.LastDelivered
+	time.Sleep(10 * time.Millisecond)
+	m := fetch(1)
+	mh = checkState(1, ConsumerMsgPending, 2)
+	// Pending keeps the consumer sequence of the first delivery.
+	require_Equal(t, mh.Consumer, 1)
+	require_True(t, mh.LastDelivered.After(first))
+
+	// Acked messages report their terminal state.
+	require_NoError(t, m.AckSync())
+	checkState(1, ConsumerMsgAcked, 0)
+
+	// Reaching the maximum deliveries keeps the count.
+	for i := 0; i < 3; i++ {
+		nak(fetch(2))
+	}
+	require_NoError(t, fetch(4).AckSync())
+	checkState(2, ConsumerMsgMaxDeliveries, 3)
+	checkState(4, ConsumerMsgAcked, 0)
+
+	// Removed from the stream before being delivered.
+	require_NoError(t, js.DeleteMsg("TEST", 5))
+	checkState(5, ConsumerMsgDeleted, 0)
+	checkState(6, ConsumerMsgUndelivered, 0)
+
+	// Consumers without acks only know if a message was delivered.
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "N", AckPolicy: nats.AckNonePolicy})
+	require_NoError(t, err)
+	nsub, err := js.PullSubscribe(_EMPTY_, "N", nats.Bind("TEST", "N"))
+	require_NoError(t, err)
+	_, err = nsub.Fetch(1)
+	require_NoError(t, err)
+	resp := history("N", 1)
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.State, ConsumerMsgDelivered)
+	resp = history("N", 2)
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.State, ConsumerMsgUndelivered)
+
+	// Sequences outside of the stream are an error.
+	for _, seq := range []uint64{0, 100} {
+		resp := history("C", seq)
+		require_True(t, resp.Error != nil)
+		require_Equal(t, resp.Error.ErrCode, uint16(JSConsumerMsgHistoryInvalidSeqErr))
+	}
+	resp = history("X", 1)
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSConsumerNotFoundErr))
+}
+
+func TestJetStreamConsumerExportImport(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo.*"}})
+	require_NoError(t, err)
+	for range 10 {
+		_, err = js.Publish("foo.a", []byte("ok"))
+		require_NoError(t, err)
+	}
+	// A copy of the stream with the same sequences.
+	_, err = js.AddStream(&nats.StreamConfig{Name: "COPY", Sources: []*nats.StreamSource{{Name: "TEST"}}})
+	require_NoError(t, err)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		si, err := js.StreamInfo("COPY")
+		if err != nil {
+			return err
+		}
+		if si.State.Msgs != 10 {
+			return fmt.Errorf("expected 10 msgs, got %d", si.State.Msgs)
+		}
+		return nil
+	})
+
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{
+		Durable:       "C",
+		FilterSubject: "foo.a",
+		AckPolicy:     nats.AckExplicitPolicy,
+	})
+	require_NoError(t, err)
+	sub, err := js.PullSubscribe("foo.a", "C", nats.Bind("TEST", "C"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(5)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 5)
+	// Ack the first three, the last two remain pending.
+	for _, m := range msgs[:3] {
+		require_NoError(t, m.AckSync())
+	}
+
+	export := func(state bool) *JSApiConsumerExportResponse {
+		t.Helper()
+		req, err := json.Marshal(&JSApiConsumerExportRequest{State: state})
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiConsumerExportT, "TEST", "C"), req, time.Second)
+		require_NoError(t, err)
+		var resp JSApiConsumerExportResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		require_True(t, resp.Error == nil)
+		return &resp
+	}
+	importConsumer := func(stream, consumer string, ce *ConsumerExport) *JSApiConsumerCreateResponse {
+		t.Helper()
+		req, err := json.Marshal(ce)
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiConsumerImportT, stream, consumer), req, time.Second)
+		require_NoError(t, err)
+		var resp JSApiConsumerCreateResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+
+	// Without state only the configuration is exported.
+	resp := export(false)
+	require_Equal(t, resp.Stream, "TEST")
+	require_Equal(t, resp.Config.Durable, "C")
+	require_Equal(t, resp.Config.FilterSubject, "foo.a")
+	require_True(t, resp.State == nil)
+
+	resp = export(true)
+	require_Equal(t, resp.State.Delivered, SequencePair{Consumer: 5, Stream: 5})
+	require_Equal(t, resp.State.AckFloor, SequencePair{Consumer: 3, Stream: 3})
+	require_Len(t, len(resp.State.Pending), 2)
+	require_True(t, resp.State.Pending[4] != nil && resp.State.Pending[5] != nil)
+
+	// Import onto the copy, it resumes from the same position.
+	cresp := importConsumer("COPY", "C", resp.ConsumerExport)
+	require_True(t, cresp.Error == nil)
+	require_Equal(t, cresp.Delivered.Stream, 5)
+	require_Equal(t, cresp.AckFloor.Stream, 3)
+	require_Equal(t, cresp.NumAckPending, 2)
+	require_Equal(t, cresp.NumPending, 5)
+
+	csub, err := js.PullSubscribe("foo.a", "C", nats.Bind("COPY", "C"))
+	require_NoError(t, err)
+	msgs, err = csub.Fetch(1)
+	require_NoError(t, err)
+	meta, err := msgs[0].Metadata()
+	require_NoError(t, err)
+	require_Equal(t, meta.Sequence.Stream, 6)
+	require_Equal(t, meta.Sequence.Consumer, 6)
+
+	// Importing over an existing consumer is not allowed.
+	cresp = importConsumer("COPY", "C", resp.ConsumerExport)
+	require_True(t, cresp.Error != nil)
+	require_Equal(t, cresp.Error.ErrCode, uint16(JSConsumerAlreadyExists))
+
+	// The state needs to be consistent.
+	bad := *resp.ConsumerExport
+	bad.State = &ConsumerState{
+		Delivered: SequencePair{Consumer: 2, Stream: 2},
+		AckFloor:  SequencePair{Consumer: 3, Stream: 3},
+	}
+	cresp = importConsumer("COPY", "D", &bad)
+	require_True(t, cresp.Error != nil)
+	require_Equal(t, cresp.Error.ErrCode, uint16(JSConsumerImportInvalidStateErrF))
+
+	// And can not be ahead of the stream.
+	bad.State = &ConsumerState{Delivered: SequencePair{Consumer: 20, Stream: 20}}
+	cresp = importConsumer("COPY", "D", &bad)
+	require_True(t, cresp.Error != nil)
+	require_Equal(t, cresp.Error.ErrCode, uint16(JSConsumerImportInvalidStateErrF))
+
+	// Imported under the name in the subject, without state from the start.
+	resp = export(false)
+	cresp = importConsumer("COPY", "D", resp.ConsumerExport)
+	require_True(t, cresp.Error == nil)
+	require_Equal(t, cresp.Name, "D")
+	require_Equal(t, cresp.Config.Durable, "D")
+	require_Equal(t, cresp.NumPending, 10)
+}
+
+func TestJetStreamConsumerPullRequests(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+
+	pullRequests := func(consumer string) *JSApiConsumerPullRequestsResponse {
+		t.Helper()
+		msg, err := nc.Request(fmt.Sprintf(JSApiConsumerPullRequestsT, "TEST", consumer), nil, time.Second)
+		require_NoError(t, err)
+		var resp JSApiConsumerPullRequestsResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		require_True(t, resp.Error == nil)
+		return &resp
+	}
+	fetch := func(req *JSApiConsumerGetNextRequest) (string, *nats.Subscription) {
+		t.Helper()
+		inbox := nats.NewInbox()
+		sub := natsSubSync(t, nc, inbox)
+		b, err := json.Marshal(req)
+		require_NoError(t, err)
+		require_NoError(t, nc.PublishRequest(fmt.Sprintf(JSApiRequestNextT, "TEST", "C"), inbox, b))
+		return inbox, sub
+	}
+
+	// No outstanding requests.
+	require_Len(t, len(pullRequests("C").Requests), 0)
+
+	r1, sub1 := fetch(&JSApiConsumerGetNextRequest{Batch: 3, Expires: 10 * time.Second})
+	r2, _ := fetch(&JSApiConsumerGetNextRequest{Batch: 2, MaxBytes: 1024, Expires: 500 * time.Millisecond})
+	r3, sub3 := fetch(&JSApiConsumerGetNextRequest{Batch: 1, Expires: 10 * time.Second, Heartbeat: time.Second})
+	require_NoError(t, nc.Flush())
+
+	reqs := pullRequests("C").Requests
+	require_Len(t, len(reqs), 3)
+	require_Equal(t, reqs[0].Reply, r1)
+	require_Equal(t, reqs[0].Account, globalAccountName)
+	require_Equal(t, reqs[0].Batch, 3)
+	require_Equal(t, reqs[0].PendingMsgs, 3)
+	require_Equal(t, reqs[0].Delivered, 0)
+	require_True(t, reqs[0].Expires != nil)
+	require_True(t, reqs[0].Expires.After(reqs[0].Received))
+	require_Equal(t, reqs[1].Reply, r2)
+	require_Equal(t, reqs[1].Batch, 2)
+	require_Equal(t, reqs[1].PendingBytes, 1024)
+	require_Equal(t, reqs[2].Reply, r3)
+	require_Equal(t, reqs[2].Heartbeat, time.Second)
+
+	// A delivered message is accounted to the first request, which is then requeued.
+	_, err = js.Publish("foo", nil)
+	require_NoError(t, err)
+	natsNexMsg(t, sub1, time.Second)
+	reqs = pullRequests("C").Requests
+	require_Len(t, len(reqs), 3)
+	require_Equal(t, reqs[2].Reply, r1)
+	require_Equal(t, reqs[2].Delivered, 1)
+	require_Equal(t, reqs[2].PendingMsgs, 2)
+
+	// Expired requests are no longer reported.
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		if n := len(pullRequests("C").Requests); n != 2 {
+			return fmt.Errorf("expected 2 pull requests, got %d", n)
+		}
+		return nil
+	})
+	reqs = pullRequests("C").Requests
+	require_Equal(t, reqs[0].Reply, r3)
+	require_Equal(t, reqs[1].Reply, r1)
+
+	// Satisfied requests are no longer reported.
+	_, err = js.Publish("foo", nil)
+	require_NoError(t, err)
+	natsNexMsg(t, sub3, time.Second)
+	reqs = pullRequests("C").Requests
+	require_Len(t, len(reqs), 1)
+	require_Equal(t, reqs[0].Reply, r1)
+
+	for range 2 {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+		natsNexMsg(t, sub1, time.Second)
+	}
+	require_Len(t, len(pullRequests("C").Requests), 0)
+
+	// Unknown consumers and non-empty requests report an error.
+	msg, err := nc.Request(fmt.Sprintf(JSApiConsumerPullRequestsT, "TEST", "NOPE"), nil, time.Second)
+	require_NoError(t, err)
+	var resp JSApiConsumerPullRequestsResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSConsumerNotFoundErr))
+
+	msg, err = nc.Request(fmt.Sprintf(JSApiConsumerPullRequestsT, "TEST", "C"), []byte(`{"x":1}`), time.Second)
+	require_NoError(t, err)
+	resp = JSApiConsumerPullRequestsResponse{}
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSNotEmptyRequestErr))
+}
+
+func TestJetStreamConsumerDeleteUnackedRetention(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	for _, test := range []struct {
+		title     string
+		retention time.Duration
+	}{
+		{"Release", 0},
+		{"Retain", 500 * time.Millisecond},
+	} {
+		t.Run(test.title, func(t *testing.T) {
+			mset, err := s.GlobalAccount().addStream(&StreamConfig{Name: test.title, Subjects: []string{"foo.*"}, Retention: InterestPolicy})
+			require_NoError(t, err)
+			defer mset.delete()
+
+			// Check validation.
+			_, err = mset.addConsumer(&ConsumerConfig{Durable: "NEG", AckPolicy: AckExplicit, UnackedRetention: -time.Second})
+			require_Error(t, err, NewJSConsumerUnackedRetentionNegativeError())
+
+			o, err := mset.addConsumer(&ConsumerConfig{Durable: "C", FilterSubject: "foo.a", AckPolicy: AckExplicit, UnackedRetention: test.retention})
+			require_NoError(t, err)
+			_, err = mset.addConsumer(&ConsumerConfig{Durable: "OTHER", AckPolicy: AckExplicit})
+			require_NoError(t, err)
+
+			for _, subj := range []string{"foo.a", "foo.a", "foo.b", "foo.a", "foo.b"} {
+				_, err = js.Publish(subj, nil)
+				require_NoError(t, err)
+			}
+
+			fetchAndAck := func(filter, consumer string, n int) {
+				t.Helper()
+				sub, err := js.PullSubscribe(filter, consumer, nats.Bind(test.title, consumer))
+				require_NoError(t, err)
+				defer sub.Unsubscribe()
+				msgs, err := sub.Fetch(n)
+				require_NoError(t, err)
+				require_Len(t, len(msgs), n)
+				for _, m := range msgs {
+					require_NoError(t, m.AckSync())
+				}
+			}
+			checkMsgs := func(expected uint64) {
+				t.Helper()
+				checkFor(t, 2*time.Second, 20*time.Millisecond, func() error {
+					if msgs := mset.state().Msgs; msgs != expected {
+						return fmt.Errorf("expected %d messages, got %d", expected, msgs)
+					}
+					return nil
+				})
+			}
+
+			// The other consumer acks everything, the filtered consumer only the first message.
+			fetchAndAck(_EMPTY_, "OTHER", 5)
+			fetchAndAck("foo.a", "C", 1)
+			checkMsgs(2)
+
+			require_NoError(t, o.delete())
+			if test.retention == 0 {
+				// Interest is released immediately.
+				checkMsgs(0)
+				return
+			}
+
+			// Unacked messages are retained for a while after deletion.
+			time.Sleep(test.retention / 2)
+			require_Equal(t, mset.state().Msgs, 2)
+
+			// Acks from other consumers don't remove retained messages.
+			_, err = js.Publish("foo.a", nil)
+			require_NoError(t, err)
+			fetchAndAck(_EMPTY_, "OTHER", 1)
+			checkMsgs(2)
+
+			// Once the retention period expires the messages are removed.
+			checkMsgs(0)
+		})
+	}
+}
+
+func TestJetStreamConsumerDeliverDedup(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	mset, err := s.GlobalAccount().addStream(&StreamConfig{Name: "TEST", Subjects: []string{"orders"}})
+	require_NoError(t, err)
+
+	// Check validation.
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "NOHDR", AckPolicy: AckExplicit, DeliverDedupWindow: time.Second})
+	require_Error(t, err, NewJSConsumerDeliverDedupInvalidError(errors.New("window requires a header")))
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "LONG", AckPolicy: AckExplicit, DeliverDedupHeader: "Order-Id", DeliverDedupWindow: 2 * JsMaxDeliverDedupWindow})
+	require_Error(t, err, NewJSConsumerDeliverDedupInvalidError(fmt.Errorf("window can not exceed %v", JsMaxDeliverDedupWindow)))
+
+	// The window defaults when only the header is set.
+	o, err := mset.addConsumer(&ConsumerConfig{Durable: "DEFAULT", AckPolicy: AckExplicit, DeliverDedupHeader: "Order-Id"})
+	require_NoError(t, err)
+	require_Equal(t, o.config().DeliverDedupWindow, JsDefaultDeliverDedupWindow)
+	require_NoError(t, o.delete())
+
+	const window = 500 * time.Millisecond
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "C", AckPolicy: AckExplicit, DeliverDedupHeader: "Order-Id", DeliverDedupWindow: window})
+	require_NoError(t, err)
+
+	publish := func(id string, data string) {
+		t.Helper()
+		m := nats.NewMsg("orders")
+		if id != _EMPTY_ {
+			m.Header.Set("Order-Id", id)
+		}
+		m.Data = []byte(data)
+		_, err := js.PublishMsg(m)
+		require_NoError(t, err)
+	}
+
+	sub, err := js.PullSubscribe("orders", "C", nats.Bind("TEST", "C"))
+	require_NoError(t, err)
+	defer sub.Unsubscribe()
+
+	fetch := func(expected ...string) {
+		t.Helper()
+		var got []string
+		for len(got) < len(expected) {
+			msgs, err := sub.Fetch(len(expected)-len(got), nats.MaxWait(time.Second))
+			require_NoError(t, err)
+			for _, m := range msgs {
+				got = append(got, string(m.Data))
+				require_NoError(t, m.AckSync())
+			}
+		}
+		require_Equal(t, strings.Join(got, ","), strings.Join(expected, ","))
+		// Nothing else should be delivered.
+		_, err := sub.Fetch(1, nats.MaxWait(250*time.Millisecond))
+		require_Error(t, err, nats.ErrTimeout)
+	}
+
+	// Logically duplicate messages are stored as distinct sequences, but only the first is delivered.
+	publish("1", "a")
+	publish("1", "b")
+	publish("2", "c")
+	publish(_EMPTY_, "d")
+	publish("1", "e")
+	publish("2", "f")
+	fetch("a", "c", "d")
+
+	ci, err := js.ConsumerInfo("TEST", "C")
+	require_NoError(t, err)
+	require_Equal(t, ci.NumPending, 0)
+	require_Equal(t, ci.NumAckPending, 0)
+
+	// Once outside of the window, the same id is delivered again.
+	time.Sleep(window)
+	publish("1", "g")
+	publish("1", "h")
+	fetch("g")
+
+	si, err := js.StreamInfo("TEST")
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 8)
+}
+
+func TestJetStreamConsumerSharedReadGroup(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo.*"}})
+	require_NoError(t, err)
+
+	// Two consumers with the same filter, and one with a different filter.
+	for _, name := range []string{"A", "B"} {
+		_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{
+			Durable:       name,
+			FilterSubject: "foo.a",
+			AckPolicy:     nats.AckExplicitPolicy,
+			AckWait:       250 * time.Millisecond,
+		})
+		require_NoError(t, err)
+	}
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", FilterSubject: "foo.b", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+
+	const numMsgs = 100
+	for i := 0; i < numMsgs; i++ {
+		_, err = js.Publish("foo.a", []byte(strconv.Itoa(i)))
+		require_NoError(t, err)
+		_, err = js.Publish("foo.b", nil)
+		require_NoError(t, err)
+	}
+
+	fetch := func(durable string, ack bool) {
+		t.Helper()
+		sub, err := js.PullSubscribe("foo.a", durable, nats.Bind("TEST", durable))
+		require_NoError(t, err)
+		defer sub.Drain()
+		for i := 0; i < numMsgs; {
+			msgs, err := sub.Fetch(10, nats.MaxWait(time.Second))
+			require_NoError(t, err)
+			for _, m := range msgs {
+				require_Equal(t, string(m.Data), strconv.Itoa(i))
+				if ack {
+					require_NoError(t, m.AckSync())
+				}
+				i++
+			}
+		}
+	}
+	fetch("A", true)
+	fetch("B", false)
+
+	mset, err := s.GlobalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	mset.rgMu.Lock()
+	rg := mset.rgs["foo.a"]
+	mset.rgMu.Unlock()
+	require_NotNil(t, rg)
+	rg.mu.Lock()
+	refs, loads, hits := rg.refs, rg.loads, rg.hits
+	rg.mu.Unlock()
+	require_Equal(t, refs, 2)
+	// The messages were only loaded from the store once, and shared with the other consumer.
+	require_Equal(t, loads, numMsgs)
+	require_Equal(t, hits, numMsgs)
+
+	// Ack state remains per consumer.
+	ci, err := js.ConsumerInfo("TEST", "A")
+	require_NoError(t, err)
+	require_Equal(t, ci.NumAckPending, 0)
+	require_Equal(t, ci.AckFloor.Stream, 2*numMsgs-1)
+	ci, err = js.ConsumerInfo("TEST", "B")
+	require_NoError(t, err)
+	require_Equal(t, ci.NumAckPending, numMsgs)
+	require_Equal(t, ci.AckFloor.Stream, 0)
+
+	// B gets its messages redelivered, A does not.
+	sub, err := js.PullSubscribe("foo.a", "B", nats.Bind("TEST", "B"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(1, nats.MaxWait(2*time.Second))
+	require_NoError(t, err)
+	meta, err := msgs[0].Metadata()
+	require_NoError(t, err)
+	require_Equal(t, meta.NumDelivered, 2)
+	require_Equal(t, string(msgs[0].Data), "0")
+	require_NoError(t, sub.Drain())
+
+	sub, err = js.PullSubscribe("foo.a", "A", nats.Bind("TEST", "A"))
+	require_NoError(t, err)
+	_, err = sub.Fetch(1, nats.MaxWait(500*time.Millisecond))
+	require_Error(t, err, nats.ErrTimeout)
+	require_NoError(t, sub.Drain())
+
+	// Removed messages are no longer shared.
+	require_NoError(t, js.DeleteMsg("TEST", 1))
+	rg.mu.Lock()
+	_, ok := rg.seqs[1]
+	rg.mu.Unlock()
+	require_False(t, ok)
+
+	// Deleting a consumer leaves the group.
+	require_NoError(t, js.DeleteConsumer("TEST", "A"))
+	rg.mu.Lock()
+	refs = rg.refs
+	rg.mu.Unlock()
+	require_Equal(t, refs, 1)
+}
+
+func TestJetStreamConsumerSharedReadGroupStoreChecks(t *testing.T) {
+	ms, err := newMemStore(&StreamConfig{Name: "TEST", Storage: MemoryStorage, Subjects: []string{"foo"}})
+	require_NoError(t, err)
+	defer ms.Stop()
+
+	for i := 0; i < 2; i++ {
+		_, _, err = ms.StoreMsg("foo", nil, []byte("hello"), 0)
+		require_NoError(t, err)
+	}
+
+	rg := &consumerReadGroup{refs: 2}
+	var smv StoreMsg
+	sm, err := ms.LoadMsg(1, &smv)
+	require_NoError(t, err)
+	rg.store(1, sm)
+	require_True(t, rg.load(ms, 1, &smv))
+	require_Equal(t, smv.seq, 1)
+
+	// A removal not yet signaled to the group is not served from it.
+	_, err = ms.RemoveMsg(1)
+	require_NoError(t, err)
+	require_False(t, rg.load(ms, 1, &smv))
+	require_Len(t, len(rg.reads), 0)
+	require_Equal(t, rg.bytes, 0)
+
+	// Reads are bounded by size, not just by count.
+	big := make([]byte, consumerReadGroupMaxBytes/2+1)
+	rg.store(2, &StoreMsg{subj: "foo", seq: 2, msg: big})
+	rg.store(3, &StoreMsg{subj: "foo", seq: 3, msg: big})
+	require_Len(t, len(rg.reads), 1)
+	require_Equal(t, rg.bytes, consumerReadGroupMaxBytes/2+1)
+	rg.store(4, &StoreMsg{subj: "foo", seq: 4, msg: make([]byte, consumerReadGroupMaxBytes+1)})
+	require_Len(t, len(rg.reads), 1)
+}
+
+func TestJetStreamConsumerSnapshotRestoreState(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+	for range 20 {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	for _, name := range []string{"C", "D"} {
+		_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: name, AckPolicy: nats.AckExplicitPolicy})
+		require_NoError(t, err)
+	}
+
+	snapshot := func(consumer string) *JSApiConsumerSnapshotResponse {
+		t.Helper()
+		msg, err := nc.Request(fmt.Sprintf(JSApiConsumerSnapshotT, "TEST", consumer), nil, time.Second)
+		require_NoError(t, err)
+		var resp JSApiConsumerSnapshotResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		require_True(t, resp.Error == nil)
+		return &resp
+	}
+	restore := func(consumer string, state []byte) *JSApiConsumerRestoreResponse {
+		t.Helper()
+		req, err := json.Marshal(&JSApiConsumerRestoreRequest{State: state})
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiConsumerRestoreT, "TEST", consumer), req, time.Second)
+		require_NoError(t, err)
+		var resp JSApiConsumerRestoreResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+
+	sub, err := js.PullSubscribe("foo", "C", nats.Bind("TEST", "C"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(5)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 5)
+	// Ack the first three, the last two remain pending.
+	for _, m := range msgs[:3] {
+		require_NoError(t, m.AckSync())
+	}
+
+	snap := snapshot("C")
+	require_Equal(t, snap.Stream, "TEST")
+	require_Equal(t, snap.Consumer, "C")
+	require_True(t, len(snap.State) > 0)
+
+	// Keep processing past the snapshot.
+	for _, m := range msgs[3:] {
+		require_NoError(t, m.AckSync())
+	}
+	msgs, err = sub.Fetch(5)
+	require_NoError(t, err)
+	for _, m := range msgs {
+		require_NoError(t, m.AckSync())
+	}
+	ci, err := js.ConsumerInfo("TEST", "C")
+	require_NoError(t, err)
+	require_Equal(t, ci.AckFloor.Stream, 10)
+
+	// Rewind to the snapshotted position.
+	resp := restore("C", snap.State)
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.Delivered.Stream, 5)
+	require_Equal(t, resp.AckFloor.Stream, 3)
+	require_Equal(t, resp.NumAckPending, 2)
+	require_Equal(t, resp.NumPending, 15)
+
+	// Delivery resumes right after the snapshotted position.
+	msgs, err = sub.Fetch(1)
+	require_NoError(t, err)
+	meta, err := msgs[0].Metadata()
+	require_NoError(t, err)
+	require_Equal(t, meta.Sequence.Stream, 6)
+	require_Equal(t, meta.Sequence.Consumer, 6)
+
+	// The snapshot is portable to another consumer.
+	resp = restore("D", snap.State)
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.Delivered.Stream, 5)
+	require_Equal(t, resp.AckFloor.Stream, 3)
+
+	// Restored state survives a restart.
+	sd := s.JetStreamConfig().StoreDir
+	nc.Close()
+	s.Shutdown()
+	s = RunJetStreamServerOnPort(-1, sd)
+	defer s.Shutdown()
+	nc, js = jsClientConnect(t, s)
+	defer nc.Close()
+	ci, err = js.ConsumerInfo("TEST", "D")
+	require_NoError(t, err)
+	require_Equal(t, ci.Delivered.Stream, 5)
+	require_Equal(t, ci.AckFloor.Stream, 3)
+
+	// A corrupt snapshot is rejected.
+	resp = restore("D", []byte("bad"))
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSConsumerRestoreFailedErrF))
+
+	// And so is a snapshot ahead of the stream.
+	resp = restore("D", encodeConsumerState(&ConsumerState{Delivered: SequencePair{Consumer: 30, Stream: 30}}))
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSConsumerRestoreFailedErrF))
+
+	// Unknown consumer.
+	resp = restore("X", snap.State)
+	require_True(t, resp.Error != nil)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSConsumerNotFoundErr))
+}
+
+func TestJetStreamConsumerDeliverBatch(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	mset, err := s.GlobalAccount().addStream(&StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+
+	// Check validation.
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "PULL", AckPolicy: AckExplicit, DeliverBatchInterval: time.Second})
+	require_Error(t, err, NewJSConsumerDeliverBatchInvalidError(errors.New("requires a push consumer")))
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "FC", DeliverSubject: "d", AckPolicy: AckExplicit, FlowControl: true, Heartbeat: time.Second, DeliverBatchInterval: time.Second})
+	require_Error(t, err, NewJSConsumerDeliverBatchInvalidError(errors.New("can not be used with flow control")))
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "ACKWAIT", DeliverSubject: "d", AckPolicy: AckExplicit, AckWait: time.Second, DeliverBatchInterval: time.Second})
+	require_Error(t, err, NewJSConsumerDeliverBatchInvalidError(errors.New("interval must be less than ack wait")))
+	_, err = mset.addConsumer(&ConsumerConfig{Durable: "NOINTERVAL", DeliverSubject: "d", AckPolicy: AckExplicit, DeliverBatchMaxMsgs: 10})
+	require_Error(t, err, NewJSConsumerDeliverBatchInvalidError(errors.New("max messages requires an interval")))
+
+	// The max messages defaults when only the interval is set.
+	o, err := mset.addConsumer(&ConsumerConfig{Durable: "DEFAULT", DeliverSubject: "d", AckPolicy: AckExplicit, DeliverBatchInterval: time.Second})
+	require_NoError(t, err)
+	require_Equal(t, o.config().DeliverBatchMaxMsgs, JsDefaultDeliverBatchMaxMsgs)
+	require_NoError(t, o.delete())
+
+	type frame struct {
+		subj, reply string
+		data        []byte
+	}
+	decodeBatch := func(m *nats.Msg) []frame {
+		t.Helper()
+		n, err := strconv.Atoi(m.Header.Get(JSDeliverBatchCount))
+		require_NoError(t, err)
+		var frames []frame
+		for buf := m.Data; len(buf) > 0; {
+			i := bytes.Index(buf, []byte(_CRLF_))
+			require_True(t, i > 0)
+			args := strings.Fields(string(buf[:i]))
+			require_Len(t, len(args), 4)
+			hdrLen, err := strconv.Atoi(args[2])
+			require_NoError(t, err)
+			totalLen, err := strconv.Atoi(args[3])
+			require_NoError(t, err)
+			buf = buf[i+2:]
+			frames = append(frames, frame{args[0], args[1], buf[hdrLen:totalLen]})
+			buf = buf[totalLen+2:]
+		}
+		require_Len(t, len(frames), n)
+		return frames
+	}
+
+	const interval = 250 * time.Millisecond
+	sub, err := nc.SubscribeSync("deliver")
+	require_NoError(t, err)
+	defer sub.Unsubscribe()
+	_, err = mset.addConsumer(&ConsumerConfig{
+		Durable:              "C",
+		DeliverSubject:       "deliver",
+		AckPolicy:            AckExplicit,
+		AckWait:              time.Second,
+		DeliverBatchInterval: interval,
+		DeliverBatchMaxMsgs:  5,
+	})
+	require_NoError(t, err)
+
+	publish := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			_, err := js.Publish("foo", []byte("msg"))
+			require_NoError(t, err)
+		}
+	}
+
+	// Fewer messages than the max are delivered together once the interval passes.
+	start := time.Now()
+	publish(3)
+	m, err := sub.NextMsg(time.Second)
+	require_NoError(t, err)
+	require_True(t, time.Since(start) >= interval-25*time.Millisecond)
+	first := decodeBatch(m)
+	require_Len(t, len(first), 3)
+	for i, f := range first {
+		require_Equal(t, f.subj, "foo")
+		require_Equal(t, string(f.data), "msg")
+		sseq, _, _, _, _ := ackReplyInfo(f.reply)
+		require_Equal(t, sseq, uint64(i+1))
+	}
+
+	// Reaching the max messages delivers right away, the remainder after the interval.
+	start = time.Now()
+	publish(7)
+	m, err = sub.NextMsg(time.Second)
+	require_NoError(t, err)
+	require_True(t, time.Since(start) < interval)
+	second := decodeBatch(m)
+	require_Len(t, len(second), 5)
+	m, err = sub.NextMsg(time.Second)
+	require_NoError(t, err)
+	require_Len(t, len(decodeBatch(m)), 2)
+
+	// Messages are acked individually.
+	for _, f := range append(first[:2:2], second...) {
+		require_NoError(t, nc.Publish(f.reply, []byte("+ACK")))
+	}
+	require_NoError(t, nc.Flush())
+	checkFor(t, time.Second, 50*time.Millisecond, func() error {
+		ci, err := js.ConsumerInfo("TEST", "C")
+		if err != nil {
+			return err
+		}
+		if ci.NumAckPending != 3 {
+			return fmt.Errorf("expected 3 pending acks, got %d", ci.NumAckPending)
+		}
+		return nil
+	})
+
+	// The unacked messages are redelivered in batches.
+	var seqs []uint64
+	for len(seqs) < 3 {
+		m, err = sub.NextMsg(2 * time.Second)
+		require_NoError(t, err)
+		for _, f := range decodeBatch(m) {
+			sseq, _, dc, _, _ := ackReplyInfo(f.reply)
+			require_Equal(t, dc, 2)
+			seqs = append(seqs, sseq)
+		}
+	}
+	slices.Sort(seqs)
+	require_Equal(t, fmt.Sprint(seqs), "[3 9 10]")
+}
@@ -74,6 +74,9 @@ const (
 	// JSClusterPeerNotMemberErr peer not a member
 	JSClusterPeerNotMemberErr ErrorIdentifier = 10040
 
+	// JSClusterPeersApiLevelErr not all peers support the required api level
+	JSClusterPeersApiLevelErr ErrorIdentifier = 10250
+
 	// JSClusterRequiredErr JetStream clustering support required
 	JSClusterRequiredErr ErrorIdentifier = 10010
 
@@ -131,9 +134,15 @@ const (
 	// JSConsumerCreateFilterSubjectMismatchErr Consumer create request did not match filtered subject from create subject
 	JSConsumerCreateFilterSubjectMismatchErr ErrorIdentifier = 10131
 
+	// JSConsumerDeliverBatchInvalidErrF consumer delivery batching is invalid: {err}
+	JSConsumerDeliverBatchInvalidErrF ErrorIdentifier = 10244
+
 	// JSConsumerDeliverCycleErr consumer deliver subject forms a cycle
 	JSConsumerDeliverCycleErr ErrorIdentifier = 10081
 
+	// JSConsumerDeliverDedupInvalidErrF consumer delivery dedup is invalid: {err}
+	JSConsumerDeliverDedupInvalidErrF ErrorIdentifier = 10236
+
 	// JSConsumerDeliverToWildcardsErr consumer deliver subject has wildcards
 	JSConsumerDeliverToWildcardsErr ErrorIdentifier = 10079
 
@@ -185,6 +194,9 @@ const (
 	// JSConsumerHBRequiresPushErr consumer idle heartbeat requires a push based consumer
 	JSConsumerHBRequiresPushErr ErrorIdentifier = 10088
 
+	// JSConsumerImportInvalidStateErrF consumer import state is invalid: {err}
+	JSConsumerImportInvalidStateErrF ErrorIdentifier = 10230
+
 	// JSConsumerInactiveThresholdExcess consumer inactive threshold exceeds system limit of {limit}
 	JSConsumerInactiveThresholdExcess ErrorIdentifier = 10153
 
@@ -209,6 +221,15 @@ const (
 	// JSConsumerMaxDeliverBackoffErr max deliver is required to be > length of backoff values
 	JSConsumerMaxDeliverBackoffErr ErrorIdentifier = 10116
 
+	// JSConsumerMaxInFlightAckPolicyRequiredErr consumer requires ack policy for max in flight
+	JSConsumerMaxInFlightAckPolicyRequiredErr ErrorIdentifier = 10225
+
+	// JSConsumerMaxInFlightNegativeErr consumer max in flight can not be negative
+	JSConsumerMaxInFlightNegativeErr ErrorIdentifier = 10224
+
+	// JSConsumerMaxInFlightRequiresPushErr consumer max in flight requires a push based consumer
+	JSConsumerMaxInFlightRequiresPushErr ErrorIdentifier = 10226
+
 	// JSConsumerMaxPendingAckExcessErrF consumer max ack pending exceeds system limit of {limit}
 	JSConsumerMaxPendingAckExcessErrF ErrorIdentifier = 10121
 
@@ -230,6 +251,12 @@ const (
 	// JSConsumerMetadataLengthErrF consumer metadata exceeds maximum size of {limit}
 	JSConsumerMetadataLengthErrF ErrorIdentifier = 10135
 
+	// JSConsumerMoveFailedErrF consumer move failed: {err}
+	JSConsumerMoveFailedErrF ErrorIdentifier = 10239
+
+	// JSConsumerMsgHistoryInvalidSeqErr consumer message history requires a valid stream sequence
+	JSConsumerMsgHistoryInvalidSeqErr ErrorIdentifier = 10229
+
 	// JSConsumerMultipleFiltersNotAllowed consumer with multiple subject filters cannot use subject based API
 	JSConsumerMultipleFiltersNotAllowed ErrorIdentifier = 10137
 
@@ -242,6 +269,9 @@ const (
 	// JSConsumerNameTooLongErrF consumer name is too long, maximum allowed is {max}
 	JSConsumerNameTooLongErrF ErrorIdentifier = 10102
 
+	// JSConsumerNoInterestPolicyRequiresPushErr consumer no interest policy requires a push based consumer
+	JSConsumerNoInterestPolicyRequiresPushErr ErrorIdentifier = 10237
+
 	// JSConsumerNotFoundErr consumer not found
 	JSConsumerNotFoundErr ErrorIdentifier = 10014
 
@@ -284,6 +314,15 @@ const (
 	// JSConsumerReplacementWithDifferentNameErr consumer replacement durable config not the same
 	JSConsumerReplacementWithDifferentNameErr ErrorIdentifier = 10106
 
+	// JSConsumerReplayDeliverSubjectCycleErr consumer replay deliver subject overlaps with stream subjects
+	JSConsumerReplayDeliverSubjectCycleErr ErrorIdentifier = 10228
+
+	// JSConsumerReplayInvalidWindowErr consumer replay requires a start time before the end time
+	JSConsumerReplayInvalidWindowErr ErrorIdentifier = 10227
+
+	// JSConsumerReplayNotPermittedErrF consumer replay not permitted: {err}
+	JSConsumerReplayNotPermittedErrF ErrorIdentifier = 10253
+
 	// JSConsumerReplayPolicyInvalidErr consumer replay policy invalid
 	JSConsumerReplayPolicyInvalidErr ErrorIdentifier = 10182
 
@@ -293,12 +332,21 @@ const (
 	// JSConsumerReplicasShouldMatchStream consumer config replicas must match interest retention stream's replicas
 	JSConsumerReplicasShouldMatchStream ErrorIdentifier = 10134
 
+	// JSConsumerRestoreFailedErrF consumer state restore failed: {err}
+	JSConsumerRestoreFailedErrF ErrorIdentifier = 10241
+
+	// JSConsumerRetentionSkipInvalidErrF consumer retention skip configuration invalid: {err}
+	JSConsumerRetentionSkipInvalidErrF ErrorIdentifier = 10240
+
 	// JSConsumerSmallHeartbeatErr consumer idle heartbeat needs to be >= 100ms
 	JSConsumerSmallHeartbeatErr ErrorIdentifier = 10083
 
 	// JSConsumerStoreFailedErrF error creating store for consumer: {err}
 	JSConsumerStoreFailedErrF ErrorIdentifier = 10104
 
+	// JSConsumerUnackedRetentionNegativeErr consumer unacked retention can not be negative
+	JSConsumerUnackedRetentionNegativeErr ErrorIdentifier = 10235
+
 	// JSConsumerWQConsumerNotDeliverAllErr consumer must be deliver all on workqueue stream
 	JSConsumerWQConsumerNotDeliverAllErr ErrorIdentifier = 10101
 
@@ -320,12 +368,21 @@ const (
 	// JSInvalidJSONErr invalid JSON: {err}
 	JSInvalidJSONErr ErrorIdentifier = 10025
 
+	// JSKVTransactionInvalidErrF key-value transaction is invalid: {err}
+	JSKVTransactionInvalidErrF ErrorIdentifier = 10247
+
+	// JSKVTransactionNotPermittedErrF key-value transaction not permitted: {err}
+	JSKVTransactionNotPermittedErrF ErrorIdentifier = 10252
+
 	// JSMaximumConsumersLimitErr maximum consumers limit reached
 	JSMaximumConsumersLimitErr ErrorIdentifier = 10026
 
 	// JSMaximumStreamsLimitErr maximum number of streams reached
 	JSMaximumStreamsLimitErr ErrorIdentifier = 10027
 
+	// JSMaximumTotalConsumersLimitErr maximum number of consumers for the account reached
+	JSMaximumTotalConsumersLimitErr ErrorIdentifier = 10234
+
 	// JSMemoryResourcesExceededErr insufficient memory resources available
 	JSMemoryResourcesExceededErr ErrorIdentifier = 10028
 
@@ -368,6 +425,9 @@ const (
 	// JSMessageSchedulesTimeZoneInvalidErr message schedules time zone is invalid
 	JSMessageSchedulesTimeZoneInvalidErr ErrorIdentifier = 10223
 
+	// JSMessageSignatureInvalidErr message signature missing or invalid
+	JSMessageSignatureInvalidErr ErrorIdentifier = 10238
+
 	// JSMessageTTLDisabledErr per-message TTL is disabled
 	JSMessageTTLDisabledErr ErrorIdentifier = 10166
 
@@ -509,12 +569,18 @@ const (
 	// JSStorageResourcesExceededErr insufficient storage resources available
 	JSStorageResourcesExceededErr ErrorIdentifier = 10047
 
+	// JSStreamAlreadyExistsErr stream already exists
+	JSStreamAlreadyExistsErr ErrorIdentifier = 10242
+
 	// JSStreamAssignmentErrF Generic stream assignment error string ({err})
 	JSStreamAssignmentErrF ErrorIdentifier = 10048
 
 	// JSStreamCreateErrF Generic stream creation error string ({err})
 	JSStreamCreateErrF ErrorIdentifier = 10049
 
+	// JSStreamCreateInProgressErr stream is already being created
+	JSStreamCreateInProgressErr ErrorIdentifier = 10243
+
 	// JSStreamDeleteErrF General stream deletion error string ({err})
 	JSStreamDeleteErrF ErrorIdentifier = 10050
 
@@ -539,6 +605,9 @@ const (
 	// JSStreamHeaderExceedsMaximumErr header size exceeds maximum allowed of 64k
 	JSStreamHeaderExceedsMaximumErr ErrorIdentifier = 10097
 
+	// JSStreamHeaderNotAllowedErrF message header {header} is not allowed
+	JSStreamHeaderNotAllowedErrF ErrorIdentifier = 10248
+
 	// JSStreamInfoMaxSubjectsErr subject details would exceed maximum allowed
 	JSStreamInfoMaxSubjectsErr ErrorIdentifier = 10117
 
@@ -584,6 +653,9 @@ const (
 	// JSStreamMsgDeleteFailedF Generic message deletion failure error string ({err})
 	JSStreamMsgDeleteFailedF ErrorIdentifier = 10057
 
+	// JSStreamMultiSnapshotLeaderErr streams of a multi-stream snapshot must all be led by the same server
+	JSStreamMultiSnapshotLeaderErr ErrorIdentifier = 10249
+
 	// JSStreamNameContainsPathSeparatorsErr Stream name can not contain path separators
 	JSStreamNameContainsPathSeparatorsErr ErrorIdentifier = 10128
 
@@ -608,12 +680,27 @@ const (
 	// JSStreamPurgeFailedF Generic stream purge failure error string ({err})
 	JSStreamPurgeFailedF ErrorIdentifier = 10110
 
+	// JSStreamReadOnlyErrF stream is read-only after storage error: {err}
+	JSStreamReadOnlyErrF ErrorIdentifier = 10232
+
+	// JSStreamRenameNotAllowedErrF stream rename not allowed: {err}
+	JSStreamRenameNotAllowedErrF ErrorIdentifier = 10233
+
+	// JSStreamReplicasNotAllowedErrF stream replicas not allowed: {err}
+	JSStreamReplicasNotAllowedErrF ErrorIdentifier = 10231
+
 	// JSStreamReplicasNotSupportedErr replicas > 1 not supported in non-clustered mode
 	JSStreamReplicasNotSupportedErr ErrorIdentifier = 10074
 
 	// JSStreamReplicasNotUpdatableErr Replicas configuration can not be updated
 	JSStreamReplicasNotUpdatableErr ErrorIdentifier = 10061
 
+	// JSStreamReservationExceedsLimitsErr stream capacity reservation exceeds limits
+	JSStreamReservationExceedsLimitsErr ErrorIdentifier = 10246
+
+	// JSStreamReservationInvalidErrF stream capacity reservation is invalid: {err}
+	JSStreamReservationInvalidErrF ErrorIdentifier = 10245
+
 	// JSStreamRestoreErrF restore failed: {err}
 	JSStreamRestoreErrF ErrorIdentifier = 10062
 
@@ -629,6 +716,9 @@ const (
 	// JSStreamSnapshotErrF snapshot failed: {err}
 	JSStreamSnapshotErrF ErrorIdentifier = 10064
 
+	// JSStreamStorageMigrationInProgressErr stream storage migration is in progress
+	JSStreamStorageMigrationInProgressErr ErrorIdentifier = 10251
+
 	// JSStreamStoreFailedF Generic error when storing a message failed ({err})
 	JSStreamStoreFailedF ErrorIdentifier = 10077
 
@@ -697,6 +787,7 @@ var (
 		JSClusterNotAvailErr:                         {Code: 503, ErrCode: 10008, Description: "JetStream system temporarily unavailable"},
 		JSClusterNotLeaderErr:                        {Code: 500, ErrCode: 10009, Description: "JetStream cluster can not handle request"},
 		JSClusterPeerNotMemberErr:                    {Code: 400, ErrCode: 10040, Description: "peer not a member"},
+		JSClusterPeersApiLevelErr:                    {Code: 412, ErrCode: 10250, Description: "not all peers support the required api level"},
 		JSClusterRequiredErr:                         {Code: 503, ErrCode: 10010, Description: "JetStream clustering support required"},
 		JSClusterServerMemberChangeInflightErr:       {Code: 400, ErrCode: 10202, Description: "cluster member change is in progress"},
 		JSClusterServerNotMemberErr:                  {Code: 400, ErrCode: 10044, Description: "server is not a member of the cluster"},
@@ -716,7 +807,9 @@ var (
 		JSConsumerCreateDurableAndNameMismatch:       {Code: 400, ErrCode: 10132, Description: "Consumer Durable and Name have to be equal if both are provided"},
 		JSConsumerCreateErrF:                         {Code: 500, ErrCode: 10012, Description: "{err}"},
 		JSConsumerCreateFilterSubjectMismatchErr:     {Code: 400, ErrCode: 10131, Description: "Consumer create request did not match filtered subject from create subject"},
+		JSConsumerDeliverBatchInvalidErrF:            {Code: 400, ErrCode: 10244, Description: "consumer delivery batching is invalid: {err}"},
 		JSConsumerDeliverCycleErr:                    {Code: 400, ErrCode: 10081, Description: "consumer deliver subject forms a cycle"},
+		JSConsumerDeliverDedupInvalidErrF:            {Code: 400, ErrCode: 10236, Description: "consumer delivery dedup is invalid: {err}"},
 		JSConsumerDeliverToWildcardsErr:              {Code: 400, ErrCode: 10079, Description: "consumer deliver subject has wildcards"},
 		JSConsumerDescriptionTooLongErrF:             {Code: 400, ErrCode: 10107, Description: "consumer description is too long, maximum allowed is {max}"},
 		JSConsumerDirectRequiresEphemeralErr:         {Code: 400, ErrCode: 10091, Description: "consumer direct requires an ephemeral consumer"},
@@ -734,6 +827,7 @@ var (
 		JSConsumerFCRequiresPushErr:                  {Code: 400, ErrCode: 10089, Description: "consumer flow control requires a push based consumer"},
 		JSConsumerFilterNotSubsetErr:                 {Code: 400, ErrCode: 10093, Description: "consumer filter subject is not a valid subset of the interest subjects"},
 		JSConsumerHBRequiresPushErr:                  {Code: 400, ErrCode: 10088, Description: "consumer idle heartbeat requires a push based consumer"},
+		JSConsumerImportInvalidStateErrF:             {Code: 400, ErrCode: 10230, Description: "consumer import state is invalid: {err}"},
 		JSConsumerInactiveThresholdExcess:            {Code: 400, ErrCode: 10153, Description: "consumer inactive threshold exceeds system limit of {limit}"},
 		JSConsumerInvalidDeliverSubject:              {Code: 400, ErrCode: 10112, Description: "invalid push consumer deliver subject"},
 		JSConsumerInvalidGroupNameErr:                {Code: 400, ErrCode: 10162, Description: "Valid priority group name must match A-Z, a-z, 0-9, -_/=)+ and may not exceed 16 characters"},
@@ -742,6 +836,9 @@ var (
 		JSConsumerInvalidResetErr:                    {Code: 400, ErrCode: 10204, Description: "invalid reset: {err}"},
 		JSConsumerInvalidSamplingErrF:                {Code: 400, ErrCode: 10095, Description: "failed to parse consumer sampling configuration: {err}"},
 		JSConsumerMaxDeliverBackoffErr:               {Code: 400, ErrCode: 10116, Description: "max deliver is required to be > length of backoff values"},
+		JSConsumerMaxInFlightAckPolicyRequiredErr:    {Code: 400, ErrCode: 10225, Description: "consumer requires ack policy for max in flight"},
+		JSConsumerMaxInFlightNegativeErr:             {Code: 400, ErrCode: 10224, Description: "consumer max in flight can not be negative"},
+		JSConsumerMaxInFlightRequiresPushErr:         {Code: 400, ErrCode: 10226, Description: "consumer max in flight requires a push based consumer"},
 		JSConsumerMaxPendingAckExcessErrF:            {Code: 400, ErrCode: 10121, Description: "consumer max ack pending exceeds system limit of {limit}"},
 		JSConsumerMaxPendingAckPolicyRequiredErr:     {Code: 400, ErrCode: 10082, Description: "consumer requires ack policy for max ack pending"},
 		JSConsumerMaxRequestBatchExceededF:           {Code: 400, ErrCode: 10125, Description: "consumer max request batch exceeds server limit of {limit}"},
@@ -749,10 +846,13 @@ var (
 		JSConsumerMaxRequestExpiresTooSmall:          {Code: 400, ErrCode: 10115, Description: "consumer max request expires needs to be >= 1ms"},
 		JSConsumerMaxWaitingNegativeErr:              {Code: 400, ErrCode: 10087, Description: "consumer max waiting needs to be positive"},
 		JSConsumerMetadataLengthErrF:                 {Code: 400, ErrCode: 10135, Description: "consumer metadata exceeds maximum size of {limit}"},
+		JSConsumerMoveFailedErrF:                     {Code: 400, ErrCode: 10239, Description: "consumer move failed: {err}"},
+		JSConsumerMsgHistoryInvalidSeqErr:            {Code: 400, ErrCode: 10229, Description: "consumer message history requires a valid stream sequence"},
 		JSConsumerMultipleFiltersNotAllowed:          {Code: 400, ErrCode: 10137, Description: "consumer with multiple subject filters cannot use subject based API"},
 		JSConsumerNameContainsPathSeparatorsErr:      {Code: 400, ErrCode: 10127, Description: "Consumer name can not contain path separators"},
 		JSConsumerNameExistErr:                       {Code: 400, ErrCode: 10013, Description: "consumer name already in use"},
 		JSConsumerNameTooLongErrF:                    {Code: 400, ErrCode: 10102, Description: "consumer name is too long, maximum allowed is {max}"},
+		JSConsumerNoInterestPolicyRequiresPushErr:    {Code: 400, ErrCode: 10237, Description: "consumer no interest policy requires a push based consumer"},
 		JSConsumerNotFoundErr:                        {Code: 404, ErrCode: 10014, Description: "consumer not found"},
 		JSConsumerOfflineErr:                         {Code: 500, ErrCode: 10119, Description: "consumer is offline"},
 		JSConsumerOfflineReasonErrF:                  {Code: 500, ErrCode: 10195, Description: "consumer is offline: {err}"},
@@ -767,11 +867,17 @@ var (
 		JSConsumerPushMaxWaitingErr:                  {Code: 400, ErrCode: 10080, Description: "consumer in push mode can not set max waiting"},
 		JSConsumerPushWithPriorityGroupErr:           {Code: 400, ErrCode: 10178, Description: "priority groups can not be used with push consumers"},
 		JSConsumerReplacementWithDifferentNameErr:    {Code: 400, ErrCode: 10106, Description: "consumer replacement durable config not the same"},
+		JSConsumerReplayDeliverSubjectCycleErr:       {Code: 400, ErrCode: 10228, Description: "consumer replay deliver subject overlaps with stream subjects"},
+		JSConsumerReplayInvalidWindowErr:             {Code: 400, ErrCode: 10227, Description: "consumer replay requires a start time before the end time"},
+		JSConsumerReplayNotPermittedErrF:             {Code: 403, ErrCode: 10253, Description: "consumer replay not permitted: {err}"},
 		JSConsumerReplayPolicyInvalidErr:             {Code: 400, ErrCode: 10182, Description: "consumer replay policy invalid"},
 		JSConsumerReplicasExceedsStream:              {Code: 400, ErrCode: 10126, Description: "consumer config replica count exceeds parent stream"},
 		JSConsumerReplicasShouldMatchStream:          {Code: 400, ErrCode: 10134, Description: "consumer config replicas must match interest retention stream's replicas"},
+		JSConsumerRestoreFailedErrF:                  {Code: 400, ErrCode: 10241, Description: "consumer state restore failed: {err}"},
+		JSConsumerRetentionSkipInvalidErrF:           {Code: 400, ErrCode: 10240, Description: "consumer retention skip configuration invalid: {err}"},
 		JSConsumerSmallHeartbeatErr:                  {Code: 400, ErrCode: 10083, Description: "consumer idle heartbeat needs to be >= 100ms"},
 		JSConsumerStoreFailedErrF:                    {Code: 500, ErrCode: 10104, Description: "error creating store for consumer: {err}"},
+		JSConsumerUnackedRetentionNegativeErr:        {Code: 400, ErrCode: 10235, Description: "consumer unacked retention can not be negative"},
 		JSConsumerWQConsumerNotDeliverAllErr:         {Code: 400, ErrCode: 10101, Description: "consumer must be deliver all on workqueue stream"},
 		JSConsumerWQConsumerNotUniqueErr:             {Code: 400, ErrCode: 10100, Description: "filtered consumer not unique on workqueue stream"},
 		JSConsumerWQMultipleUnfilteredErr:            {Code: 400, ErrCode: 10099, Description: "multiple non-filtered consumers not allowed on workqueue stream"},
@@ -779,8 +885,11 @@ var (
 		JSConsumerWithFlowControlNeedsHeartbeats:     {Code: 400, ErrCode: 10108, Description: "consumer with flow control also needs heartbeats"},
 		JSInsufficientResourcesErr:                   {Code: 503, ErrCode: 10023, Description: "insufficient resources"},
 		JSInvalidJSONErr:                             {Code: 400, ErrCode: 10025, Description: "invalid JSON: {err}"},
+		JSKVTransactionInvalidErrF:                   {Code: 400, ErrCode: 10247, Description: "key-value transaction is invalid: {err}"},
+		JSKVTransactionNotPermittedErrF:              {Code: 403, ErrCode: 10252, Description: "key-value transaction not permitted: {err}"},
 		JSMaximumConsumersLimitErr:                   {Code: 400, ErrCode: 10026, Description: "maximum consumers limit reached"},
 		JSMaximumStreamsLimitErr:                     {Code: 400, ErrCode: 10027, Description: "maximum number of streams reached"},
+		JSMaximumTotalConsumersLimitErr:              {Code: 400, ErrCode: 10234, Description: "maximum number of consumers for the account reached"},
 		JSMemoryResourcesExceededErr:                 {Code: 500, ErrCode: 10028, Description: "insufficient memory resources available"},
 		JSMessageCounterBrokenErr:                    {Code: 400, ErrCode: 10172, Description: "message counter is broken"},
 		JSMessageIncrDisabledErr:                     {Code: 400, ErrCode: 10168, Description: "message counters is disabled"},
@@ -795,6 +904,7 @@ var (
 		JSMessageSchedulesTTLInvalidErr:              {Code: 400, ErrCode: 10191, Description: "message schedules invalid per-message TTL"},
 		JSMessageSchedulesTargetInvalidErr:           {Code: 400, ErrCode: 10190, Description: "message schedules target is invalid"},
 		JSMessageSchedulesTimeZoneInvalidErr:         {Code: 400, ErrCode: 10223, Description: "message schedules time zone is invalid"},
+		JSMessageSignatureInvalidErr:                 {Code: 400, ErrCode: 10238, Description: "message signature missing or invalid"},
 		JSMessageTTLDisabledErr:                      {Code: 400, ErrCode: 10166, Description: "per-message TTL is disabled"},
 		JSMessageTTLInvalidErr:                       {Code: 400, ErrCode: 10165, Description: "invalid per-message TTL"},
 		JSMirrorConsumerRequiresAckFCErr:             {Code: 400, ErrCode: 10214, Description: "stream mirror consumer requires flow control ack policy"},
@@ -842,8 +952,10 @@ var (
 		JSSourceOverlappingSubjectFilters:            {Code: 400, ErrCode: 10147, Description: "source filters can not overlap"},
 		JSSourceWithMsgSchedulesErr:                  {Code: 400, ErrCode: 10187, Description: "stream source can not also schedule messages"},
 		JSStorageResourcesExceededErr:                {Code: 500, ErrCode: 10047, Description: "insufficient storage resources available"},
+		JSStreamAlreadyExistsErr:                     {Code: 400, ErrCode: 10242, Description: "stream already exists"},
 		JSStreamAssignmentErrF:                       {Code: 500, ErrCode: 10048, Description: "{err}"},
 		JSStreamCreateErrF:                           {Code: 500, ErrCode: 10049, Description: "{err}"},
+		JSStreamCreateInProgressErr:                  {Code: 400, ErrCode: 10243, Description: "stream is already being created"},
 		JSStreamDeleteErrF:                           {Code: 500, ErrCode: 10050, Description: "{err}"},
 		JSStreamDuplicateMessageConflict:             {Code: 409, ErrCode: 10158, Description: "duplicate message id is in process"},
 		JSStreamExpectedLastSeqPerSubjectInvalid:     {Code: 400, ErrCode: 10193, Description: "missing sequence for expected last sequence per subject"},
@@ -852,6 +964,7 @@ var (
 		JSStreamExternalDelPrefixOverlapsErrF:        {Code: 400, ErrCode: 10022, Description: "stream external delivery prefix {prefix} overlaps with stream subject {subject}"},
 		JSStreamGeneralErrorF:                        {Code: 500, ErrCode: 10051, Description: "{err}"},
 		JSStreamHeaderExceedsMaximumErr:              {Code: 400, ErrCode: 10097, Description: "header size exceeds maximum allowed of 64k"},
+		JSStreamHeaderNotAllowedErrF:                 {Code: 400, ErrCode: 10248, Description: "message header {header} is not allowed"},
 		JSStreamInfoMaxSubjectsErr:                   {Code: 500, ErrCode: 10117, Description: "subject details would exceed maximum allowed"},
 		JSStreamInvalidConfigF:                       {Code: 500, ErrCode: 10052, Description: "{err}"},
 		JSStreamInvalidErr:                           {Code: 500, ErrCode: 10096, Description: "stream not valid"},
@@ -867,6 +980,7 @@ var (
 		JSStreamMoveInProgressF:                      {Code: 400, ErrCode: 10124, Description: "stream move already in progress: {msg}"},
 		JSStreamMoveNotInProgress:                    {Code: 400, ErrCode: 10129, Description: "stream move not in progress"},
 		JSStreamMsgDeleteFailedF:                     {Code: 500, ErrCode: 10057, Description: "{err}"},
+		JSStreamMultiSnapshotLeaderErr:               {Code: 400, ErrCode: 10249, Description: "streams of a multi-stream snapshot must all be led by the same server"},
 		JSStreamNameContainsPathSeparatorsErr:        {Code: 400, ErrCode: 10128, Description: "Stream name can not contain path separators"},
 		JSStreamNameExistErr:                         {Code: 400, ErrCode: 10058, Description: "stream name already in use with a different configuration"},
 		JSStreamNameExistRestoreFailedErr:            {Code: 400, ErrCode: 10130, Description: "stream name already in use, cannot restore"},
@@ -875,13 +989,19 @@ var (
 		JSStreamOfflineErr:                           {Code: 500, ErrCode: 10118, Description: "stream is offline"},
 		JSStreamOfflineReasonErrF:                    {Code: 500, ErrCode: 10194, Description: "stream is offline: {err}"},
 		JSStreamPurgeFailedF:                         {Code: 500, ErrCode: 10110, Description: "{err}"},
+		JSStreamReadOnlyErrF:                         {Code: 503, ErrCode: 10232, Description: "stream is read-only after storage error: {err}"},
+		JSStreamRenameNotAllowedErrF:                 {Code: 400, ErrCode: 10233, Description: "stream rename not allowed: {err}"},
+		JSStreamReplicasNotAllowedErrF:               {Code: 400, ErrCode: 10231, Description: "stream replicas not allowed: {err}"},
 		JSStreamReplicasNotSupportedErr:              {Code: 500, ErrCode: 10074, Description: "replicas > 1 not supported in non-clustered mode"},
 		JSStreamReplicasNotUpdatableErr:              {Code: 400, ErrCode: 10061, Description: "Replicas configuration can not be updated"},
+		JSStreamReservationExceedsLimitsErr:          {Code: 400, ErrCode: 10246, Description: "stream capacity reservation exceeds limits"},
+		JSStreamReservationInvalidErrF:               {Code: 400, ErrCode: 10245, Description: "stream capacity reservation is invalid: {err}"},
 		JSStreamRestoreErrF:                          {Code: 500, ErrCode: 10062, Description: "restore failed: {err}"},
 		JSStreamRollupFailedF:                        {Code: 500, ErrCode: 10111, Description: "{err}"},
 		JSStreamSealedErr:                            {Code: 400, ErrCode: 10109, Description: "invalid operation on sealed stream"},
 		JSStreamSequenceNotMatchErr:                  {Code: 503, ErrCode: 10063, Description: "expected stream sequence does not match"},
 		JSStreamSnapshotErrF:                         {Code: 500, ErrCode: 10064, Description: "snapshot failed: {err}"},
+		JSStreamStorageMigrationInProgressErr:        {Code: 400, ErrCode: 10251, Description: "stream storage migration is in progress"},
 		JSStreamStoreFailedF:                         {Code: 503, ErrCode: 10077, Description: "{err}"},
 		JSStreamSubjectOverlapErr:                    {Code: 400, ErrCode: 10065, Description: "subjects overlap with an existing stream"},
 		JSStreamTemplateCreateErrF:                   {Code: 500, ErrCode: 10066, Description: "{err}"},
@@ -1169,6 +1289,16 @@ func NewJSClusterPeerNotMemberError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSClusterPeerNotMemberErr]
 }
 
+// NewJSClusterPeersApiLevelError creates a new JSClusterPeersApiLevelErr error: "not all peers support the required api level"
+func NewJSClusterPeersApiLevelError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSClusterPeersApiLevelErr]
+}
+
 // NewJSClusterRequiredError creates a new JSClusterRequiredErr error: "JetStream clustering support required"
 func NewJSClusterRequiredError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1365,6 +1495,22 @@ func NewJSConsumerCreateFilterSubjectMismatchError(opts ...ErrorOption) *ApiErro
 	return ApiErrors[JSConsumerCreateFilterSubjectMismatchErr]
 }
 
+// NewJSConsumerDeliverBatchInvalidError creates a new JSConsumerDeliverBatchInvalidErrF error: "consumer delivery batching is invalid: {err}"
+func NewJSConsumerDeliverBatchInvalidError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSConsumerDeliverBatchInvalidErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSConsumerDeliverCycleError creates a new JSConsumerDeliverCycleErr error: "consumer deliver subject forms a cycle"
 func NewJSConsumerDeliverCycleError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1375,6 +1521,22 @@ func NewJSConsumerDeliverCycleError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSConsumerDeliverCycleErr]
 }
 
+// NewJSConsumerDeliverDedupInvalidError creates a new JSConsumerDeliverDedupInvalidErrF error: "consumer delivery dedup is invalid: {err}"
+func NewJSConsumerDeliverDedupInvalidError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSConsumerDeliverDedupInvalidErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSConsumerDeliverToWildcardsError creates a new JSConsumerDeliverToWildcardsErr error: "consumer deliver subject has wildcards"
 func NewJSConsumerDeliverToWildcardsError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1551,6 +1713,22 @@ func NewJSConsumerHBRequiresPushError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSConsumerHBRequiresPushErr]
 }
 
+// NewJSConsumerImportInvalidStateError creates a new JSConsumerImportInvalidStateErrF error: "consumer import state is invalid: {err}"
+func NewJSConsumerImportInvalidStateError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSConsumerImportInvalidStateErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSConsumerInactiveThresholdExcessError creates a new JSConsumerInactiveThresholdExcess error: "consumer inactive threshold exceeds system limit of {limit}"
 func NewJSConsumerInactiveThresholdExcessError(limit interface{}, opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1655,6 +1833,36 @@ func NewJSConsumerMaxDeliverBackoffError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSConsumerMaxDeliverBackoffErr]
 }
 
+// NewJSConsumerMaxInFlightAckPolicyRequiredError creates a new JSConsumerMaxInFlightAckPolicyRequiredErr error: "consumer requires ack policy for max in flight"
+func NewJSConsumerMaxInFlightAckPolicyRequiredError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSConsumerMaxInFlightAckPolicyRequiredErr]
+}
+
+// NewJSConsumerMaxInFlightNegativeError creates a new JSConsumerMaxInFlightNegativeErr error: "consumer max in flight can not be negative"
+func NewJSConsumerMaxInFlightNegativeError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSConsumerMaxInFlightNegativeErr]
+}
+
+// NewJSConsumerMaxInFlightRequiresPushError creates a new JSConsumerMaxInFlightRequiresPushErr error: "consumer max in flight requires a push based consumer"
+func NewJSConsumerMaxInFlightRequiresPushError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSConsumerMaxInFlightRequiresPushErr]
+}
+
 // NewJSConsumerMaxPendingAckExcessError creates a new JSConsumerMaxPendingAckExcessErrF error: "consumer max ack pending exceeds system limit of {limit}"
 func NewJSConsumerMaxPendingAckExcessError(limit interface{}, opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1743,6 +1951,32 @@ func NewJSConsumerMetadataLengthError(limit interface{}, opts ...ErrorOption) *A
 	}
 }
 
+// NewJSConsumerMoveFailedError creates a new JSConsumerMoveFailedErrF error: "consumer move failed: {err}"
+func NewJSConsumerMoveFailedError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSConsumerMoveFailedErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
+// NewJSConsumerMsgHistoryInvalidSeqError creates a new JSConsumerMsgHistoryInvalidSeqErr error: "consumer message history requires a valid stream sequence"
+func NewJSConsumerMsgHistoryInvalidSeqError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSConsumerMsgHistoryInvalidSeqErr]
+}
+
 // NewJSConsumerMultipleFiltersNotAllowedError creates a new JSConsumerMultipleFiltersNotAllowed error: "consumer with multiple subject filters cannot use subject based API"
 func NewJSConsumerMultipleFiltersNotAllowedError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1789,6 +2023,16 @@ func NewJSConsumerNameTooLongError(max interface{}, opts ...ErrorOption) *ApiErr
 	}
 }
 
+// NewJSConsumerNoInterestPolicyRequiresPushError creates a new JSConsumerNoInterestPolicyRequiresPushErr error: "consumer no interest policy requires a push based consumer"
+func NewJSConsumerNoInterestPolicyRequiresPushError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSConsumerNoInterestPolicyRequiresPushErr]
+}
+
 // NewJSConsumerNotFoundError creates a new JSConsumerNotFoundErr error: "consumer not found"
 func NewJSConsumerNotFoundError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1935,6 +2179,42 @@ func NewJSConsumerReplacementWithDifferentNameError(opts ...ErrorOption) *ApiErr
 	return ApiErrors[JSConsumerReplacementWithDifferentNameErr]
 }
 
+// NewJSConsumerReplayDeliverSubjectCycleError creates a new JSConsumerReplayDeliverSubjectCycleErr error: "consumer replay deliver subject overlaps with stream subjects"
+func NewJSConsumerReplayDeliverSubjectCycleError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSConsumerReplayDeliverSubjectCycleErr]
+}
+
+// NewJSConsumerReplayInvalidWindowError creates a new JSConsumerReplayInvalidWindowErr error: "consumer replay requires a start time before the end time"
+func NewJSConsumerReplayInvalidWindowError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSConsumerReplayInvalidWindowErr]
+}
+
+// NewJSConsumerReplayNotPermittedError creates a new JSConsumerReplayNotPermittedErrF error: "consumer replay not permitted: {err}"
+func NewJSConsumerReplayNotPermittedError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSConsumerReplayNotPermittedErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSConsumerReplayPolicyInvalidError creates a new JSConsumerReplayPolicyInvalidErr error: "consumer replay policy invalid"
 func NewJSConsumerReplayPolicyInvalidError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1965,6 +2245,38 @@ func NewJSConsumerReplicasShouldMatchStreamError(opts ...ErrorOption) *ApiError
 	return ApiErrors[JSConsumerReplicasShouldMatchStream]
 }
 
+// NewJSConsumerRestoreFailedError creates a new JSConsumerRestoreFailedErrF error: "consumer state restore failed: {err}"
+func NewJSConsumerRestoreFailedError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSConsumerRestoreFailedErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
+// NewJSConsumerRetentionSkipInvalidError creates a new JSConsumerRetentionSkipInvalidErrF error: "consumer retention skip configuration invalid: {err}"
+func NewJSConsumerRetentionSkipInvalidError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSConsumerRetentionSkipInvalidErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSConsumerSmallHeartbeatError creates a new JSConsumerSmallHeartbeatErr error: "consumer idle heartbeat needs to be >= 100ms"
 func NewJSConsumerSmallHeartbeatError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1991,6 +2303,16 @@ func NewJSConsumerStoreFailedError(err error, opts ...ErrorOption) *ApiError {
 	}
 }
 
+// NewJSConsumerUnackedRetentionNegativeError creates a new JSConsumerUnackedRetentionNegativeErr error: "consumer unacked retention can not be negative"
+func NewJSConsumerUnackedRetentionNegativeError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSConsumerUnackedRetentionNegativeErr]
+}
+
 // NewJSConsumerWQConsumerNotDeliverAllError creates a new JSConsumerWQConsumerNotDeliverAllErr error: "consumer must be deliver all on workqueue stream"
 func NewJSConsumerWQConsumerNotDeliverAllError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2067,6 +2389,38 @@ func NewJSInvalidJSONError(err error, opts ...ErrorOption) *ApiError {
 	}
 }
 
+// NewJSKVTransactionInvalidError creates a new JSKVTransactionInvalidErrF error: "key-value transaction is invalid: {err}"
+func NewJSKVTransactionInvalidError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSKVTransactionInvalidErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
+// NewJSKVTransactionNotPermittedError creates a new JSKVTransactionNotPermittedErrF error: "key-value transaction not permitted: {err}"
+func NewJSKVTransactionNotPermittedError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSKVTransactionNotPermittedErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSMaximumConsumersLimitError creates a new JSMaximumConsumersLimitErr error: "maximum consumers limit reached"
 func NewJSMaximumConsumersLimitError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2087,6 +2441,16 @@ func NewJSMaximumStreamsLimitError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSMaximumStreamsLimitErr]
 }
 
+// NewJSMaximumTotalConsumersLimitError creates a new JSMaximumTotalConsumersLimitErr error: "maximum number of consumers for the account reached"
+func NewJSMaximumTotalConsumersLimitError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSMaximumTotalConsumersLimitErr]
+}
+
 // NewJSMemoryResourcesExceededError creates a new JSMemoryResourcesExceededErr error: "insufficient memory resources available"
 func NewJSMemoryResourcesExceededError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2227,6 +2591,16 @@ func NewJSMessageSchedulesTimeZoneInvalidError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSMessageSchedulesTimeZoneInvalidErr]
 }
 
+// NewJSMessageSignatureInvalidError creates a new JSMessageSignatureInvalidErr error: "message signature missing or invalid"
+func NewJSMessageSignatureInvalidError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSMessageSignatureInvalidErr]
+}
+
 // NewJSMessageTTLDisabledError creates a new JSMessageTTLDisabledErr error: "per-message TTL is disabled"
 func NewJSMessageTTLDisabledError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2757,6 +3131,16 @@ func NewJSStorageResourcesExceededError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSStorageResourcesExceededErr]
 }
 
+// NewJSStreamAlreadyExistsError creates a new JSStreamAlreadyExistsErr error: "stream already exists"
+func NewJSStreamAlreadyExistsError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSStreamAlreadyExistsErr]
+}
+
 // NewJSStreamAssignmentError creates a new JSStreamAssignmentErrF error: "{err}"
 func NewJSStreamAssignmentError(err error, opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2789,6 +3173,16 @@ func NewJSStreamCreateError(err error, opts ...ErrorOption) *ApiError {
 	}
 }
 
+// NewJSStreamCreateInProgressError creates a new JSStreamCreateInProgressErr error: "stream is already being created"
+func NewJSStreamCreateInProgressError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSStreamCreateInProgressErr]
+}
+
 // NewJSStreamDeleteError creates a new JSStreamDeleteErrF error: "{err}"
 func NewJSStreamDeleteError(err error, opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2893,6 +3287,22 @@ func NewJSStreamHeaderExceedsMaximumError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSStreamHeaderExceedsMaximumErr]
 }
 
+// NewJSStreamHeaderNotAllowedError creates a new JSStreamHeaderNotAllowedErrF error: "message header {header} is not allowed"
+func NewJSStreamHeaderNotAllowedError(header interface{}, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSStreamHeaderNotAllowedErrF]
+	args := e.toReplacerArgs([]interface{}{"{header}", header})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSStreamInfoMaxSubjectsError creates a new JSStreamInfoMaxSubjectsErr error: "subject details would exceed maximum allowed"
 func NewJSStreamInfoMaxSubjectsError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -3073,6 +3483,16 @@ func NewJSStreamMsgDeleteFailedError(err error, opts ...ErrorOption) *ApiError {
 	}
 }
 
+// NewJSStreamMultiSnapshotLeaderError creates a new JSStreamMultiSnapshotLeaderErr error: "streams of a multi-stream snapshot must all be led by the same server"
+func NewJSStreamMultiSnapshotLeaderError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSStreamMultiSnapshotLeaderErr]
+}
+
 // NewJSStreamNameContainsPathSeparatorsError creates a new JSStreamNameContainsPathSeparatorsErr error: "Stream name can not contain path separators"
 func NewJSStreamNameContainsPathSeparatorsError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -3165,6 +3585,54 @@ func NewJSStreamPurgeFailedError(err error, opts ...ErrorOption) *ApiError {
 	}
 }
 
+// NewJSStreamReadOnlyError creates a new JSStreamReadOnlyErrF error: "stream is read-only after storage error: {err}"
+func NewJSStreamReadOnlyError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSStreamReadOnlyErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
+// NewJSStreamRenameNotAllowedError creates a new JSStreamRenameNotAllowedErrF error: "stream rename not allowed: {err}"
+func NewJSStreamRenameNotAllowedError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSStreamRenameNotAllowedErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
+// NewJSStreamReplicasNotAllowedError creates a new JSStreamReplicasNotAllowedErrF error: "stream replicas not allowed: {err}"
+func NewJSStreamReplicasNotAllowedError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSStreamReplicasNotAllowedErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSStreamReplicasNotSupportedError creates a new JSStreamReplicasNotSupportedErr error: "replicas > 1 not supported in non-clustered mode"
 func NewJSStreamReplicasNotSupportedError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -3185,6 +3653,32 @@ func NewJSStreamReplicasNotUpdatableError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSStreamReplicasNotUpdatableErr]
 }
 
+// NewJSStreamReservationExceedsLimitsError creates a new JSStreamReservationExceedsLimitsErr error: "stream capacity reservation exceeds limits"
+func NewJSStreamReservationExceedsLimitsError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSStreamReservationExceedsLimitsErr]
+}
+
+// NewJSStreamReservationInvalidError creates a new JSStreamReservationInvalidErrF error: "stream capacity reservation is invalid: {err}"
+func NewJSStreamReservationInvalidError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSStreamReservationInvalidErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSStreamRestoreError creates a new JSStreamRestoreErrF error: "restore failed: {err}"
 func NewJSStreamRestoreError(err error, opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -3253,6 +3747,16 @@ func NewJSStreamSnapshotError(err error, opts ...ErrorOption) *ApiError {
 	}
 }
 
+// NewJSStreamStorageMigrationInProgressError creates a new JSStreamStorageMigrationInProgressErr error: "stream storage migration is in progress"
+func NewJSStreamStorageMigrationInProgressError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSStreamStorageMigrationInProgressErr]
+}
+
 // NewJSStreamStoreFailedError creates a new JSStreamStoreFailedF error: "{err}"
 func NewJSStreamStoreFailedError(err error, opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
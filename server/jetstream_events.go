@@ -15,7 +15,11 @@ package server
 
 import (
 	"encoding/json"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/nats-io/nuid"
 )
 
 // publishAdvisory sends the given advisory into the account. Returns true if
@@ -34,6 +38,11 @@ func (s *Server) publishAdvisory(acc *Account, subject string, adv any) bool {
 		return false
 	}
 
+	// Drop the advisory if the account is over its advisory rate limit.
+	if !acc.allowAdvisory(s, subject) {
+		return false
+	}
+
 	ej, err := json.Marshal(adv)
 	if err == nil {
 		err = s.sendInternalAccountMsg(acc, subject, ej)
@@ -78,6 +87,25 @@ type JSStreamActionAdvisory struct {
 
 const JSStreamActionAdvisoryType = "io.nats.jetstream.advisory.v1.stream_action"
 
+// JSStreamConfigChangeAdvisory indicates that the configuration of a stream was changed
+type JSStreamConfigChangeAdvisory struct {
+	TypedEvent
+	Stream  string               `json:"stream"`
+	Before  *StreamConfig        `json:"before"`
+	After   *StreamConfig        `json:"after"`
+	Changes []StreamConfigChange `json:"changes"`
+	Domain  string               `json:"domain,omitempty"`
+}
+
+// StreamConfigChange is a single changed field of a stream configuration, using its JSON name.
+type StreamConfigChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+const JSStreamConfigChangeAdvisoryType = "io.nats.jetstream.advisory.v1.stream_config_change"
+
 // JSConsumerActionAdvisory indicates that a consumer was created or deleted
 type JSConsumerActionAdvisory struct {
 	TypedEvent
@@ -101,6 +129,32 @@ type JSConsumerPauseAdvisory struct {
 
 const JSConsumerPauseAdvisoryType = "io.nats.jetstream.advisory.v1.consumer_pause"
 
+// JSConsumerNoInterestAdvisory indicates that a push consumer configured to continue
+// delivering without interest has no subscribers left on its deliver subject
+type JSConsumerNoInterestAdvisory struct {
+	TypedEvent
+	Stream         string `json:"stream"`
+	Consumer       string `json:"consumer"`
+	DeliverSubject string `json:"deliver_subject"`
+	Domain         string `json:"domain,omitempty"`
+}
+
+const JSConsumerNoInterestAdvisoryType = "io.nats.jetstream.advisory.v1.consumer_no_interest"
+
+// JSConsumerRetentionSkipAdvisory indicates that the stream's retention removed messages
+// a consumer did not deliver yet, and whether the consumer paused instead of skipping them
+type JSConsumerRetentionSkipAdvisory struct {
+	TypedEvent
+	Stream   string `json:"stream"`
+	Consumer string `json:"consumer"`
+	FirstSeq uint64 `json:"first_seq"`
+	LastSeq  uint64 `json:"last_seq"`
+	Paused   bool   `json:"paused"`
+	Domain   string `json:"domain,omitempty"`
+}
+
+const JSConsumerRetentionSkipAdvisoryType = "io.nats.jetstream.advisory.v1.consumer_retention_skip"
+
 // JSConsumerAckMetric is a metric published when a user acknowledges a message, the
 // number of these that will be published is dependent on SampleFrequency
 type JSConsumerAckMetric struct {
@@ -162,6 +216,20 @@ type JSConsumerDeliveryTerminatedAdvisory struct {
 // JSConsumerDeliveryTerminatedAdvisoryType is the schema type for JSConsumerDeliveryTerminatedAdvisory
 const JSConsumerDeliveryTerminatedAdvisoryType = "io.nats.jetstream.advisory.v1.terminated"
 
+// JSConsumerFirstDeliveryAdvisory is an advisory informing that a consumer
+// delivered its first message since it was created or restarted
+type JSConsumerFirstDeliveryAdvisory struct {
+	TypedEvent
+	Stream      string `json:"stream"`
+	Consumer    string `json:"consumer"`
+	ConsumerSeq uint64 `json:"consumer_seq"`
+	StreamSeq   uint64 `json:"stream_seq"`
+	Domain      string `json:"domain,omitempty"`
+}
+
+// JSConsumerFirstDeliveryAdvisoryType is the schema type for JSConsumerFirstDeliveryAdvisory
+const JSConsumerFirstDeliveryAdvisoryType = "io.nats.jetstream.advisory.v1.first_delivery"
+
 // JSSnapshotCreateAdvisory is an advisory sent after a snapshot is successfully started
 type JSSnapshotCreateAdvisory struct {
 	TypedEvent
@@ -252,6 +320,21 @@ type JSStreamQuorumLostAdvisory struct {
 	Domain   string      `json:"domain,omitempty"`
 }
 
+// JSStreamIOErrorAdvisoryType is sent when a stream encountered an I/O error on its store.
+const JSStreamIOErrorAdvisoryType = "io.nats.jetstream.advisory.v1.stream_io_error"
+
+// JSStreamIOErrorAdvisory indicates that a stream encountered an I/O error and
+// which action, according to the server's I/O error policy, was taken.
+type JSStreamIOErrorAdvisory struct {
+	TypedEvent
+	Account string `json:"account,omitempty"`
+	Stream  string `json:"stream"`
+	Server  string `json:"server"`
+	Domain  string `json:"domain,omitempty"`
+	Error   string `json:"error"`
+	Action  string `json:"action"`
+}
+
 // JSStreamBatchAbandonedAdvisoryType is sent when a stream's atomic batch is abandoned.
 const JSStreamBatchAbandonedAdvisoryType = "io.nats.jetstream.advisory.v1.stream_batch_abandoned"
 
@@ -265,6 +348,40 @@ type JSStreamBatchAbandonedAdvisory struct {
 	Reason  BatchAbandonReason `json:"reason"`
 }
 
+// JSStreamMsgsExpiringAdvisoryType is sent shortly before messages in a stream expire.
+const JSStreamMsgsExpiringAdvisoryType = "io.nats.jetstream.advisory.v1.stream_msgs_expiring"
+
+// JSStreamMsgsExpiringAdvisory indicates that messages will be removed, due to the stream's
+// MaxAge or their TTL, at the given times. Messages can also be removed before then for other reasons.
+type JSStreamMsgsExpiringAdvisory struct {
+	TypedEvent
+	Account  string                `json:"account,omitempty"`
+	Stream   string                `json:"stream"`
+	Domain   string                `json:"domain,omitempty"`
+	Messages []JSStreamExpiringMsg `json:"messages"`
+}
+
+// JSStreamExpiringMsg is a message that will expire at the given time.
+type JSStreamExpiringMsg struct {
+	Sequence uint64    `json:"seq"`
+	Expires  time.Time `json:"expires"`
+}
+
+// JSStreamPublishRejectedAdvisoryType is sent when a publish to a sealed or read-only stream is rejected.
+const JSStreamPublishRejectedAdvisoryType = "io.nats.jetstream.advisory.v1.stream_publish_rejected"
+
+// JSStreamPublishRejectedAdvisory indicates that a publish was rejected because the stream
+// is sealed or read-only, along with who attempted it.
+type JSStreamPublishRejectedAdvisory struct {
+	TypedEvent
+	Account string      `json:"account,omitempty"`
+	Stream  string      `json:"stream"`
+	Domain  string      `json:"domain,omitempty"`
+	Subject string      `json:"subject"`
+	Client  *ClientInfo `json:"client,omitempty"`
+	Error   *ApiError   `json:"error"`
+}
+
 type BatchAbandonReason string
 
 var (
@@ -364,3 +481,101 @@ type JSAPILimitReachedAdvisory struct {
 	Domain  string `json:"domain,omitempty"` // Domain the server belongs to
 	Dropped int64  `json:"dropped"`          // How many messages did we drop from the queue
 }
+
+// JSAdvisoryRateLimitedType is sent when advisories of an account were dropped due to its advisory rate limit.
+const JSAdvisoryRateLimitedType = "io.nats.jetstream.advisory.v1.rate_limited"
+
+// JSAdvisoryRateLimitedAdvisory summarizes the advisories of an account that were dropped
+// during the last second, because they exceeded the account's advisory rate limit.
+type JSAdvisoryRateLimitedAdvisory struct {
+	TypedEvent
+	Server  string `json:"server"`  // Server that dropped the advisories
+	Account string `json:"account"` // Account the advisories were dropped for
+	Limit   int    `json:"limit"`   // Maximum number of advisories per second
+	Dropped uint64 `json:"dropped"` // How many advisories were dropped
+}
+
+// Advisories that are always sent, regardless of the account's advisory rate limit.
+var criticalAdvisories = []string{
+	JSAdvisoryStreamQuorumLostPre,
+	JSAdvisoryConsumerQuorumLostPre,
+	JSAdvisoryStreamIOErrorPre,
+	JSAdvisoryServerOutOfStorage,
+	JSAdvisoryServerRemoved,
+	JSAdvisoryAPILimitReached,
+	JSAdvisoryDomainLeaderElected,
+	JSAdvisoryRateLimited,
+}
+
+// isCriticalAdvisory returns whether advisories on this subject are exempt from rate limiting.
+func isCriticalAdvisory(subject string) bool {
+	for _, pre := range criticalAdvisories {
+		if rest, ok := strings.CutPrefix(subject, pre); ok && (rest == _EMPTY_ || rest[0] == btsep) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowAdvisory returns whether an advisory on this subject can be sent
+// without exceeding the account's advisory rate limit.
+func (a *Account) allowAdvisory(s *Server, subject string) bool {
+	limit := int(a.madvr)
+	return limit <= 0 || isCriticalAdvisory(subject) || a.advl.allow(s, a, limit)
+}
+
+// advisoryLimiter rate limits the advisories published in an account. Advisories above the
+// limit are dropped, the number dropped is reported in a summary once the second is over.
+type advisoryLimiter struct {
+	mu      sync.Mutex
+	window  int64       // The second for which advisories are currently counted.
+	count   int         // The number of advisories sent in the current window.
+	dropped uint64      // The number of advisories dropped since the last summary.
+	tmr     *time.Timer // Sends the summary at the end of the window, set while dropping.
+}
+
+// allow returns whether an advisory can be sent now, counting it if so.
+func (al *advisoryLimiter) allow(s *Server, acc *Account, limit int) bool {
+	now := time.Now()
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if sec := now.Unix(); sec != al.window {
+		al.window, al.count = sec, 0
+	}
+	if al.count < limit {
+		al.count++
+		return true
+	}
+	al.dropped++
+	if al.tmr == nil {
+		al.tmr = time.AfterFunc(time.Unix(al.window+1, 0).Sub(now), func() {
+			al.sendSummary(s, acc, limit)
+		})
+	}
+	return false
+}
+
+// sendSummary sends an advisory with the number of advisories dropped.
+func (al *advisoryLimiter) sendSummary(s *Server, acc *Account, limit int) {
+	al.mu.Lock()
+	dropped := al.dropped
+	al.dropped, al.tmr = 0, nil
+	al.mu.Unlock()
+
+	if dropped == 0 || s.isShuttingDown() {
+		return
+	}
+	s.Debugf("Dropped %d advisories for account %q, above the limit of %d per second", dropped, acc.Name, limit)
+	s.publishAdvisory(acc, JSAdvisoryRateLimited, JSAdvisoryRateLimitedAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSAdvisoryRateLimitedType,
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Server:  s.Name(),
+		Account: acc.Name,
+		Limit:   limit,
+		Dropped: dropped,
+	})
+}
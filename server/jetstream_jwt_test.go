@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -1001,6 +1003,224 @@ func TestJetStreamJWTExpiredAccountNotCountedTowardLimits(t *testing.T) {
 	require_True(t, ai.Limits.MaxMemory == 7*1024*1024)
 }
 
+func TestJetStreamJWTDeletedAccountPolicy(t *testing.T) {
+	op, _ := nkeys.CreateOperator()
+	opPk, _ := op.PublicKey()
+	sk, _ := nkeys.CreateOperator()
+	skPk, _ := sk.PublicKey()
+	opClaim := jwt.NewOperatorClaims(opPk)
+	opClaim.SigningKeys.Add(skPk)
+	opJwt, err := opClaim.Encode(op)
+	require_NoError(t, err)
+	createAccountAndUser := func(pubKey, jwt1, creds1 *string) {
+		t.Helper()
+		kp, _ := nkeys.CreateAccount()
+		*pubKey, _ = kp.PublicKey()
+		claim := jwt.NewAccountClaims(*pubKey)
+		claim.Limits.JetStreamLimits = jwt.JetStreamLimits{MemoryStorage: 7 * 1024 * 1024, DiskStorage: 7 * 1024 * 1024, Streams: 10}
+		var err error
+		*jwt1, err = claim.Encode(sk)
+		require_NoError(t, err)
+
+		ukp, _ := nkeys.CreateUser()
+		seed, _ := ukp.Seed()
+		upub, _ := ukp.PublicKey()
+		uclaim := newJWTTestUserClaims()
+		uclaim.Subject = upub
+
+		ujwt1, err := uclaim.Encode(kp)
+		require_NoError(t, err)
+		*creds1 = genCredsFile(t, ujwt1, seed)
+	}
+	generateRequest := func(accs []string, kp nkeys.KeyPair) []byte {
+		t.Helper()
+		opk, _ := kp.PublicKey()
+		c := jwt.NewGenericClaims(opk)
+		c.Data["accounts"] = accs
+		cJwt, err := c.Encode(kp)
+		if err != nil {
+			t.Fatalf("Expected no error %v", err)
+		}
+		return []byte(cJwt)
+	}
+
+	for _, policy := range []AccountDeletePolicy{AccountDeleteDisable, AccountDeleteRefuse, AccountDeleteCascade} {
+		t.Run(policy.String(), func(t *testing.T) {
+			var syspub, sysjwt, sysCreds string
+			createAccountAndUser(&syspub, &sysjwt, &sysCreds)
+
+			dirSrv := t.TempDir()
+			conf := createConfFile(t, []byte(fmt.Sprintf(`
+				listen: 127.0.0.1:-1
+				operator: %s
+				jetstream: {max_mem_store: 10Mb, max_file_store: 10Mb, store_dir: "%s"}
+				system_account: %s
+				resolver: {
+					type: full
+					allow_delete: true
+					delete_policy: %s
+					dir: '%s'
+					timeout: "500ms"
+				}
+			`, opJwt, dirSrv, syspub, policy, dirSrv)))
+
+			s, _ := RunServerWithConfig(conf)
+			defer s.Shutdown()
+
+			updateJwt(t, s.ClientURL(), sysCreds, sysjwt, 1)
+
+			var apub, ajwt, aCreds string
+			createAccountAndUser(&apub, &ajwt, &aCreds)
+			updateJwt(t, s.ClientURL(), sysCreds, ajwt, 1)
+
+			ncA, jsA := jsClientConnect(t, s, nats.UserCredentials(aCreds), nats.MaxReconnects(-1))
+			defer ncA.Close()
+
+			_, err := jsA.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+			require_NoError(t, err)
+			_, err = jsA.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+			require_NoError(t, err)
+			_, err = jsA.Publish("foo", []byte("ok"))
+			require_NoError(t, err)
+
+			sdir := filepath.Join(s.JetStreamConfig().StoreDir, apub, streamsDir, "TEST")
+			_, err = os.Stat(sdir)
+			require_NoError(t, err)
+
+			nc := natsConnect(t, s.ClientURL(), nats.UserCredentials(sysCreds))
+			defer nc.Close()
+			deleteAccount := func() string {
+				t.Helper()
+				resp, err := nc.Request(accDeleteReqSubj, generateRequest([]string{apub}, sk), 5*time.Second)
+				require_NoError(t, err)
+				return string(resp.Data)
+			}
+
+			checkDisconnected := func() {
+				t.Helper()
+				checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+					if ncA.IsConnected() {
+						return errors.New("client still connected")
+					}
+					return nil
+				})
+			}
+
+			switch policy {
+			case AccountDeleteDisable:
+				// The account is gone, but its stream is left behind.
+				require_Contains(t, deleteAccount(), `"message":"deleted 1 accounts"`)
+				checkDisconnected()
+				_, err = os.Stat(sdir)
+				require_NoError(t, err)
+
+			case AccountDeleteRefuse:
+				resp := deleteAccount()
+				require_Contains(t, resp, "deleted 0 accounts, failed for 1", "still has 1 connections and 1 streams")
+				// Nothing happened to the account.
+				si, err := jsA.StreamInfo("TEST")
+				require_NoError(t, err)
+				require_Equal(t, si.State.Msgs, 1)
+
+				// Once the assets are gone the account can be deleted.
+				require_NoError(t, jsA.DeleteStream("TEST"))
+				ncA.Close()
+				checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+					if conns, _ := s.accountActiveAssets(apub); conns > 0 {
+						return fmt.Errorf("still %d connections", conns)
+					}
+					return nil
+				})
+				require_Contains(t, deleteAccount(), `"message":"deleted 1 accounts"`)
+
+			case AccountDeleteCascade:
+				require_Contains(t, deleteAccount(), `"message":"deleted 1 accounts"`)
+				checkDisconnected()
+				// The stream and its consumer are removed from disk.
+				_, err = os.Stat(sdir)
+				require_True(t, os.IsNotExist(err))
+				conns, streams := s.accountActiveAssets(apub)
+				require_Equal(t, conns, 0)
+				require_Equal(t, streams, 0)
+			}
+		})
+	}
+
+	// Check configuration errors.
+	for _, test := range []struct {
+		resolver string
+		err      string
+	}{
+		{"type: full, allow_delete: true, delete_policy: bad", "delete_policy must be one of"},
+		{"type: full, allow_delete: true, delete_policy: 1", "delete_policy must be a string"},
+		{"type: full, delete_policy: cascade", "delete_policy has no effect without delete"},
+		{"type: cache, delete_policy: cascade", "CACHE does not accept delete_policy"},
+	} {
+		conf := createConfFile(t, []byte(fmt.Sprintf(`
+			operator: %s
+			resolver: { %s, dir: '%s' }
+		`, opJwt, test.resolver, t.TempDir())))
+		_, err := ProcessConfigFile(conf)
+		require_Error(t, err)
+		require_Contains(t, err.Error(), test.err)
+	}
+
+	// Refusing counts the connections on the other servers of the cluster too.
+	var syspub, sysjwt, sysCreds string
+	createAccountAndUser(&syspub, &sysjwt, &sysCreds)
+	tmpl := `
+		listen: 127.0.0.1:-1
+		server_name: %s
+		operator: %s
+		system_account: %s
+		resolver: {
+			type: full
+			allow_delete: true
+			delete_policy: refuse
+			dir: '%s'
+			timeout: "500ms"
+		}
+		cluster: { name: C, listen: 127.0.0.1:-1, routes: [%s] }
+	`
+	s1, o1 := RunServerWithConfig(createConfFile(t, []byte(fmt.Sprintf(tmpl, "S1", opJwt, syspub, t.TempDir(), _EMPTY_))))
+	defer s1.Shutdown()
+	route := fmt.Sprintf("nats://127.0.0.1:%d", o1.Cluster.Port)
+	s2, _ := RunServerWithConfig(createConfFile(t, []byte(fmt.Sprintf(tmpl, "S2", opJwt, syspub, t.TempDir(), route))))
+	defer s2.Shutdown()
+	checkClusterFormed(t, s1, s2)
+
+	updateJwt(t, s1.ClientURL(), sysCreds, sysjwt, 2)
+	var apub, ajwt, aCreds string
+	createAccountAndUser(&apub, &ajwt, &aCreds)
+	updateJwt(t, s1.ClientURL(), sysCreds, ajwt, 2)
+
+	ncA := natsConnect(t, s2.ClientURL(), nats.UserCredentials(aCreds))
+	defer ncA.Close()
+
+	nc := natsConnect(t, s1.ClientURL(), nats.UserCredentials(sysCreds))
+	defer nc.Close()
+	resp, err := nc.Request(accDeleteReqSubj, generateRequest([]string{apub}, sk), 5*time.Second)
+	require_NoError(t, err)
+	require_Contains(t, string(resp.Data), "deleted 0 accounts, failed for 1", "still has 1 connections")
+	// Give both servers the chance to apply the request.
+	time.Sleep(2 * accountDeleteConnsWait)
+	for _, s := range []*Server{s1, s2} {
+		_, err = s.AccountResolver().(*DirAccResolver).LoadAcc(apub)
+		require_NoError(t, err)
+	}
+
+	ncA.Close()
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if conns := s1.accountClusterConnections(apub, 100*time.Millisecond); conns > 0 {
+			return fmt.Errorf("still %d connections", conns)
+		}
+		return nil
+	})
+	resp, err = nc.Request(accDeleteReqSubj, generateRequest([]string{apub}, sk), 5*time.Second)
+	require_NoError(t, err)
+	require_Contains(t, string(resp.Data), `"message":"deleted 1 accounts"`)
+}
+
 func TestJetStreamJWTExpiredAccountWorksAfterExpirationUpdated(t *testing.T) {
 	sysKp, spub := createKey(t)
 	sysClaim := jwt.NewAccountClaims(spub)
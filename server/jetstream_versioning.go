@@ -17,7 +17,7 @@ import "strconv"
 
 const (
 	// JSApiLevel is the maximum supported JetStream API level for this server.
-	JSApiLevel int = 4
+	JSApiLevel int = 5
 
 	JSRequiredLevelMetadataKey = "_nats.req.level"
 	JSServerVersionMetadataKey = "_nats.ver"
@@ -87,6 +87,66 @@ func setStaticStreamMetadata(cfg *StreamConfig) {
 		requires(4)
 	}
 
+	// Compression dictionaries were added in v2.15 and require API level 5.
+	if len(cfg.CompressionDict) > 0 {
+		requires(5)
+	}
+
+	// Allowed publishers were added in v2.15 and require API level 5.
+	if len(cfg.AllowedPublishers) > 0 {
+		requires(5)
+	}
+
+	// Delivery after commit was added in v2.15 and requires API level 5.
+	if cfg.DeliverAfterCommit {
+		requires(5)
+	}
+
+	// Per-subject sequences were added in v2.15 and require API level 5.
+	if cfg.SubjectSequence {
+		requires(5)
+	}
+
+	// Max subjects was added in v2.15 and requires API level 5.
+	if cfg.MaxSubjects > 0 || cfg.DiscardNewSubjects {
+		requires(5)
+	}
+
+	// Signed messages were added in v2.15 and require API level 5.
+	if cfg.SigningKey != _EMPTY_ {
+		requires(5)
+	}
+
+	// Overlapping subjects were added in v2.15 and require API level 5.
+	if cfg.AllowSubjectOverlap {
+		requires(5)
+	}
+
+	// Expiry advisories were added in v2.15 and require API level 5.
+	if cfg.ExpiryAdvisoryLead > 0 {
+		requires(5)
+	}
+
+	// Write concerns were added in v2.15 and require API level 5.
+	if cfg.WriteConcern != WriteConcernSafe {
+		requires(5)
+	}
+
+	// Allowed headers were added in v2.15 and require API level 5.
+	if len(cfg.AllowedHeaders) > 0 {
+		requires(5)
+	}
+
+	// Inclusive and count-limited duplicate windows were added in v2.15 and require API level 5.
+	if cfg.DuplicateWindowInclusive || cfg.DuplicateMaxIds > 0 {
+		requires(5)
+	}
+
+	// Reordering sourced messages was added in v2.15 and requires API level 5.
+	if cfg.SourcesReorderWindow > 0 {
+		requires(5)
+	}
+
 	cfg.Metadata[JSRequiredLevelMetadataKey] = strconv.Itoa(requiredApiLevel)
 }
 
@@ -168,6 +228,36 @@ func setStaticConsumerMetadata(cfg *ConsumerConfig) {
 		requires(4)
 	}
 
+	// Added in 2.15
+	if cfg.MaxInFlight > 0 {
+		requires(5)
+	}
+
+	// Added in 2.15
+	if cfg.UnackedRetention > 0 {
+		requires(5)
+	}
+
+	// Added in 2.15
+	if cfg.DeliverDedupHeader != _EMPTY_ {
+		requires(5)
+	}
+
+	// Added in 2.15
+	if cfg.NoInterestPolicy != NoInterestPause {
+		requires(5)
+	}
+
+	// Added in 2.15
+	if cfg.RetentionSkipPolicy != RetentionSkipIgnore {
+		requires(5)
+	}
+
+	// Added in 2.15
+	if cfg.DeliverBatchInterval > 0 {
+		requires(5)
+	}
+
 	cfg.Metadata[JSRequiredLevelMetadataKey] = strconv.Itoa(requiredApiLevel)
 }
 
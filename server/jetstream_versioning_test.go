@@ -112,6 +112,71 @@ func TestJetStreamSetStaticStreamMetadata(t *testing.T) {
 			cfg:              &StreamConfig{AllowBatchPublish: true},
 			expectedMetadata: metadataAtLevel("4"),
 		},
+		{
+			desc:             "CompressionDict",
+			cfg:              &StreamConfig{CompressionDict: []byte("dictionary")},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "AllowedPublishers",
+			cfg:              &StreamConfig{AllowedPublishers: []string{"alice"}},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "DeliverAfterCommit",
+			cfg:              &StreamConfig{DeliverAfterCommit: true},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "SubjectSequence",
+			cfg:              &StreamConfig{SubjectSequence: true},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "MaxSubjects",
+			cfg:              &StreamConfig{MaxSubjects: 10},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "SigningKey",
+			cfg:              &StreamConfig{SigningKey: "key"},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "AllowSubjectOverlap",
+			cfg:              &StreamConfig{AllowSubjectOverlap: true},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "ExpiryAdvisoryLead",
+			cfg:              &StreamConfig{ExpiryAdvisoryLead: time.Second},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "WriteConcern",
+			cfg:              &StreamConfig{WriteConcern: WriteConcernFast},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "AllowedHeaders",
+			cfg:              &StreamConfig{AllowedHeaders: []string{"Foo"}},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "DuplicateWindowInclusive",
+			cfg:              &StreamConfig{DuplicateWindowInclusive: true},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "DuplicateMaxIds",
+			cfg:              &StreamConfig{DuplicateMaxIds: 10},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "SourcesReorderWindow",
+			cfg:              &StreamConfig{SourcesReorderWindow: time.Second},
+			expectedMetadata: metadataAtLevel("5"),
+		},
 	} {
 		t.Run(test.desc, func(t *testing.T) {
 			setStaticStreamMetadata(test.cfg)
@@ -259,6 +324,36 @@ func TestJetStreamSetStaticConsumerMetadata(t *testing.T) {
 			cfg:              &ConsumerConfig{AckPolicy: AckFlowControl},
 			expectedMetadata: metadataAtLevel("4"),
 		},
+		{
+			desc:             "MaxInFlight",
+			cfg:              &ConsumerConfig{MaxInFlight: 10},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "UnackedRetention",
+			cfg:              &ConsumerConfig{UnackedRetention: time.Minute},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "DeliverDedupHeader",
+			cfg:              &ConsumerConfig{DeliverDedupHeader: "Dedup-Id", DeliverDedupWindow: time.Minute},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "NoInterestPolicy",
+			cfg:              &ConsumerConfig{NoInterestPolicy: NoInterestContinue},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "RetentionSkipPolicy",
+			cfg:              &ConsumerConfig{RetentionSkipPolicy: RetentionSkipAdvise},
+			expectedMetadata: metadataAtLevel("5"),
+		},
+		{
+			desc:             "DeliverBatchInterval",
+			cfg:              &ConsumerConfig{DeliverBatchInterval: time.Second},
+			expectedMetadata: metadataAtLevel("5"),
+		},
 	} {
 		t.Run(test.desc, func(t *testing.T) {
 			setStaticConsumerMetadata(test.cfg)
@@ -5492,6 +5492,53 @@ func TestJWTResponseThreshold(t *testing.T) {
 	})
 }
 
+func TestJWTJetStreamMaxTotalConsumersTag(t *testing.T) {
+	sysKp, syspub := createKey(t)
+	sysJwt := encodeClaim(t, jwt.NewAccountClaims(syspub), syspub)
+	sysCreds := newUser(t, sysKp)
+
+	accKp, accPub := createKey(t)
+	accClaim := jwt.NewAccountClaims(accPub)
+	accClaim.Name = "acc"
+	accClaim.Limits.JetStreamLimits = jwt.JetStreamLimits{DiskStorage: 1024 * 1024, Consumer: -1, Streams: -1}
+	accClaim.Tags.Add(jwtMaxTotalConsumersTag + ":1")
+	accJwt := encodeClaim(t, accClaim, accPub)
+	accCreds := newUser(t, accKp)
+
+	dirSrv := t.TempDir()
+	cf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		server_name: s1
+		jetstream: {max_mem_store: 256MB, max_file_store: 2GB, store_dir: '%s'}
+		operator: %s
+		system_account: %s
+		resolver: {
+			type: full
+			dir: '%s'
+		}
+	`, t.TempDir(), ojwt, syspub, dirSrv)))
+
+	s, _ := RunServerWithConfig(cf)
+	defer s.Shutdown()
+
+	updateJwt(t, s.ClientURL(), sysCreds, sysJwt, 1)
+	updateJwt(t, s.ClientURL(), sysCreds, accJwt, 1)
+
+	nc := natsConnect(t, s.ClientURL(), nats.UserCredentials(accCreds))
+	defer nc.Close()
+	js, err := nc.JetStream()
+	require_NoError(t, err)
+
+	for _, name := range []string{"S1", "S2"} {
+		_, err = js.AddStream(&nats.StreamConfig{Name: name, Subjects: []string{name}})
+		require_NoError(t, err)
+	}
+	_, err = js.AddConsumer("S1", &nats.ConsumerConfig{Durable: "C1", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+	_, err = js.AddConsumer("S2", &nats.ConsumerConfig{Durable: "C2", AckPolicy: nats.AckExplicitPolicy})
+	require_Error(t, err, NewJSMaximumTotalConsumersLimitError())
+}
+
 func TestJWTJetStreamTiers(t *testing.T) {
 	sysKp, syspub := createKey(t)
 	sysJwt := encodeClaim(t, jwt.NewAccountClaims(syspub), syspub)
@@ -6465,6 +6512,89 @@ func TestJWTMappings(t *testing.T) {
 	test("foo2", "bar2", true)
 }
 
+func TestJWTPinnedMappings(t *testing.T) {
+	sysKp, syspub := createKey(t)
+	sysJwt := encodeClaim(t, jwt.NewAccountClaims(syspub), syspub)
+	sysCreds := newUser(t, sysKp)
+
+	aKp, aPub := createKey(t)
+	aClaim := jwt.NewAccountClaims(aPub)
+	aClaim.AddMapping("foo", jwt.WeightedMapping{Subject: "bar"})
+	aClaim.AddMapping("shared", jwt.WeightedMapping{Subject: "from.jwt"})
+	aJwtMap := encodeClaim(t, aClaim, aPub)
+	aClaim.Mappings = nil
+	aJwtNoM := encodeClaim(t, aClaim, aPub)
+
+	dirSrv := t.TempDir()
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		operator: %s
+		system_account: %s
+		resolver: {
+			type: full
+			dir: '%s'
+		}
+		pinned_mappings: {
+			%s: {
+				shared: from.config
+				local: [{destination: local.dest, weight: 100%%}]
+			}
+		}
+	`, ojwt, syspub, dirSrv, aPub)))
+	srv, _ := RunServerWithConfig(conf)
+	defer srv.Shutdown()
+	updateJwt(t, srv.ClientURL(), sysCreds, sysJwt, 1) // update system account jwt
+
+	test := func(pub, sub string, fail bool) {
+		t.Helper()
+		nc := natsConnect(t, srv.ClientURL(), createUserCreds(t, srv, aKp))
+		defer nc.Close()
+		s, err := nc.SubscribeSync(sub)
+		require_NoError(t, err)
+		require_NoError(t, nc.Flush())
+		require_NoError(t, nc.Publish(pub, nil))
+		_, err = s.NextMsg(500 * time.Millisecond)
+		switch {
+		case fail && err == nil:
+			t.Fatal("expected error, got none")
+		case !fail && err != nil:
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// The JWT mappings apply, but the pinned ones take precedence.
+	require_Len(t, 1, updateJwt(t, srv.ClientURL(), sysCreds, aJwtMap, 1))
+	test("foo", "bar", false)
+	test("shared", "from.config", false)
+	test("shared", "from.jwt", true)
+	test("local", "local.dest", false)
+
+	// Removing the mappings from the JWT keeps the pinned ones.
+	require_Len(t, 1, updateJwt(t, srv.ClientURL(), sysCreds, aJwtNoM, 1))
+	test("foo", "bar", true)
+	test("shared", "from.config", false)
+	test("local", "local.dest", false)
+}
+
+func TestJWTPinnedMappingsConfigErrors(t *testing.T) {
+	_, aPub := createKey(t)
+	for _, test := range []struct {
+		name   string
+		config string
+	}{
+		{"not a map", `pinned_mappings: "foo"`},
+		{"invalid account", `pinned_mappings: { foo: { bar: baz } }`},
+		{"invalid subject", fmt.Sprintf(`pinned_mappings: { %s: { "foo..bar": baz } }`, aPub)},
+		{"invalid destination", fmt.Sprintf(`pinned_mappings: { %s: { "foo.*": "bar.{{wildcard(2)}}" } }`, aPub)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf := createConfFile(t, []byte(test.config))
+			_, err := ProcessConfigFile(conf)
+			require_Error(t, err)
+		})
+	}
+}
+
 func TestJWTOperatorPinnedAccounts(t *testing.T) {
 	kps, pubs, jwts := [4]nkeys.KeyPair{}, [4]string{}, [4]string{}
 	for i := 0; i < 4; i++ {
@@ -58,6 +58,15 @@ const (
 	// Prefix for loop detection subject
 	leafNodeLoopDetectionSubjectPrefix = "$LDS."
 
+	// Header holding the clusters a message passed through over leafnode connections.
+	LeafNodePathHdr = "Nats-Leaf-Path"
+
+	// Default max number of clusters in the path of a leafnode message.
+	leafNodeLoopMaxPathDefault = 16
+
+	// Advisories about looping leafnode messages are sent at most this often.
+	leafNodeLoopAdvisoryInterval = time.Second
+
 	// Path added to URL to indicate to WS server that the connection is a
 	// LEAF connection as opposed to a CLIENT.
 	leafNodeWSPath = "/leafnode"
@@ -117,6 +126,7 @@ type leafNodeCfg struct {
 	quitCh         chan struct{}
 	removed        bool
 	connInProgress bool
+	limited        bool // Not connecting since its account reached the maximum number of remotes.
 }
 
 // Check to see if this is a solicited leafnode. We do special processing for solicited.
@@ -196,12 +206,43 @@ func (s *Server) solicitLeafNodeRemotes(remotes []*RemoteLeafOpts) {
 		// the number of internal configuration matches the options' remote leaf
 		// configuration required for configuration reload.
 		remote := addRemote(r, r.LocalAccount == sysAccName)
-		if !r.Disabled {
+		if !r.Disabled && s.admitLeafNodeRemote(remote) {
 			s.connectToRemoteLeafNodeAsynchronously(remote, true)
 		}
 	}
 }
 
+// Returns whether this remote can connect without exceeding the maximum number of
+// remotes for its local account. If not, the remote is marked as limited so that it
+// is not counted against the others, and an error is reported.
+func (s *Server) admitLeafNodeRemote(remote *leafNodeCfg) bool {
+	max := s.getOpts().LeafNode.MaxRemotesPerAccount
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var active int
+	if max > 0 {
+		for r := range s.leafRemoteCfgs {
+			if r == remote {
+				continue
+			}
+			r.RLock()
+			if r.LocalAccount == remote.LocalAccount && !r.Disabled && !r.limited {
+				active++
+			}
+			r.RUnlock()
+		}
+	}
+	limited := max > 0 && active >= max
+	remote.Lock()
+	remote.limited = limited
+	remote.Unlock()
+	if limited {
+		s.Errorf("Not connecting leafnode remote %s, account %q reached its maximum of %d outbound leafnode connections",
+			remote.safeName(), remote.LocalAccount, max)
+	}
+	return !limited
+}
+
 // Ensure that leafnode is properly configured.
 func validateLeafNode(o *Options) error {
 	if err := validateLeafNodeAuthOptions(o); err != nil {
@@ -3254,6 +3295,24 @@ func (c *client) processInboundLeafMsg(msg []byte) {
 		return
 	}
 
+	// Drop messages the allowed publishers of a stream capturing this subject
+	// don't allow, without disconnecting the whole leafnode.
+	if !c.streamPublishAllowed(acc, subject) {
+		return
+	}
+
+	// Drop messages that would revisit our cluster, or passed through too many clusters.
+	c.pa.leafPath = nil
+	if srv.leafLoopMaxPath > 0 && c.pa.hdr > 0 {
+		if path := sliceHeader(LeafNodePathHdr, msg[:c.pa.hdr]); len(path) > 0 {
+			if srv.isLeafNodeLoop(path) {
+				srv.leafNodeLoopDetected(c, subject, string(path))
+				return
+			}
+			msg = c.takeLeafNodePath(path, msg)
+		}
+	}
+
 	// Match the subscriptions. We will use our own L1 map if
 	// it's still valid, avoiding contention on the shared sublist.
 	var r *SublistResult
@@ -3760,3 +3819,77 @@ func (s *Server) leafNodeFinishConnectProcess(c *client) {
 		}
 	}
 }
+
+// leafNodePathName returns the name this server adds to the path of leafnode messages,
+// the name of its cluster, or its own name if not clustered.
+func (s *Server) leafNodePathName() string {
+	if cn := s.cachedClusterName(); cn != _EMPTY_ {
+		return cn
+	}
+	return s.Name()
+}
+
+// isLeafNodeLoop returns whether a leafnode message with the given path already passed
+// through our cluster, or through too many clusters.
+func (s *Server) isLeafNodeLoop(path []byte) bool {
+	name, hops := s.leafNodePathName(), 0
+	for p := range strings.SplitSeq(bytesToString(path), ",") {
+		if hops++; p == name || hops >= s.leafLoopMaxPath {
+			return true
+		}
+	}
+	return false
+}
+
+// takeLeafNodePath removes the path header from a message received from a leafnode or route,
+// so that it's not delivered to local subscriptions or stored by JetStream. The path is kept
+// in the pub args instead, and set again when the message is sent to routes or leafnodes.
+func (c *client) takeLeafNodePath(path, msg []byte) []byte {
+	c.pa.leafPath = copyBytes(path)
+	return c.removeHeader(LeafNodePathHdr, msg)
+}
+
+// setLeafNodePathHeader adds our cluster to the path of a message sent to a leafnode connection.
+func (c *client) setLeafNodePathHeader(msg []byte) []byte {
+	name := c.srv.leafNodePathName()
+	path := c.pa.leafPath
+	// Messages routed within our cluster already have it.
+	if len(path) > 0 {
+		if last := path[bytes.LastIndexByte(path, ',')+1:]; bytesToString(last) == name {
+			return msg
+		}
+		return c.setHeader(LeafNodePathHdr, string(path)+","+name, msg)
+	}
+	return c.setHeader(LeafNodePathHdr, name, msg)
+}
+
+// leafNodeLoopDetected warns about and sends an advisory for a looping message
+// from leafnode connection c, which was dropped. Both are rate limited.
+func (s *Server) leafNodeLoopDetected(c *client, subject, path string) {
+	s.leafLoopDrops.Add(1)
+	now := time.Now()
+	last := s.leafLoopAdv.Load()
+	if now.UnixNano()-last < int64(leafNodeLoopAdvisoryInterval) || !s.leafLoopAdv.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	dropped := s.leafLoopDrops.Swap(0)
+	c.Warnf("Dropped %d looping message(s), last on %q in account %q with path %q", dropped, subject, c.acc.Name, path)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.eventsEnabled() {
+		return
+	}
+	m := LeafNodeLoopEventMsg{
+		TypedEvent: TypedEvent{
+			Type: LeafNodeLoopEventMsgType,
+			ID:   s.nextEventID(),
+			Time: now.UTC(),
+		},
+		Account: c.acc.Name,
+		Subject: subject,
+		Path:    strings.Split(path, ","),
+		Dropped: dropped,
+	}
+	s.sendInternalMsg(fmt.Sprintf(leafNodeLoopEventSubj, s.info.ID), _EMPTY_, &m.Server, &m)
+}
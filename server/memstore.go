@@ -14,6 +14,7 @@
 package server
 
 import (
+	"cmp"
 	crand "crypto/rand"
 	"encoding/binary"
 	"fmt"
@@ -48,6 +49,8 @@ type memStore struct {
 	receivedAny bool
 	ttls        *thw.HashWheel
 	scheduling  *MsgScheduling
+	ssq         subjectSequences
+	lrw         *subjectRecency
 	sdm         *SDMMeta
 }
 
@@ -71,6 +74,9 @@ func newMemStore(cfg *StreamConfig) (*memStore, error) {
 	if cfg.AllowMsgSchedules {
 		ms.scheduling = newMsgScheduling(ms.runMsgScheduling)
 	}
+	if cfg.SubjectSequence {
+		ms.ssq = make(subjectSequences)
+	}
 	if cfg.FirstSeq > 0 {
 		if _, err := ms.purge(cfg.FirstSeq); err != nil {
 			return nil, err
@@ -104,6 +110,11 @@ func (ms *memStore) UpdateConfig(cfg *StreamConfig) error {
 	} else if !cfg.AllowMsgSchedules && ms.scheduling != nil {
 		ms.scheduling = nil
 	}
+	if cfg.SubjectSequence && ms.ssq == nil {
+		ms.recoverSubjectSequenceState()
+	} else if !cfg.SubjectSequence && ms.ssq != nil {
+		ms.ssq = nil
+	}
 	// Limits checks and enforcement.
 	ms.enforceMsgLimit()
 	ms.enforceBytesLimit()
@@ -132,6 +143,7 @@ func (ms *memStore) UpdateConfig(cfg *StreamConfig) error {
 			return true
 		})
 	}
+	ms.enforceSubjectLimit(_EMPTY_)
 	ms.mu.Unlock()
 
 	if cfg.MaxAge != 0 || cfg.AllowMsgTTL {
@@ -190,6 +202,23 @@ func (ms *memStore) recoverMsgSchedulingState() {
 	}
 }
 
+// Lock should be held.
+func (ms *memStore) recoverSubjectSequenceState() {
+	ms.ssq = make(subjectSequences)
+	if ms.state.Msgs == 0 {
+		return
+	}
+
+	var (
+		seq uint64
+		smv StoreMsg
+		sm  *StoreMsg
+	)
+	for sm, seq, _ = ms.loadNextMsgLocked(fwcs, true, 0, &smv); sm != nil; sm, seq, _ = ms.loadNextMsgLocked(fwcs, true, seq+1, &smv) {
+		ms.ssq.track(sm.subj, sm.hdr)
+	}
+}
+
 // Stores a raw message with expected sequence number and timestamp.
 // Lock should be held.
 func (ms *memStore) storeRawMsg(subj string, hdr, msg []byte, seq uint64, ts, ttl int64, discardNewCheck bool) error {
@@ -236,6 +265,12 @@ func (ms *memStore) storeRawMsg(subj string, hdr, msg []byte, seq uint64, ts, tt
 			}
 		}
 	}
+	// Check if we are discarding new subjects when we reach the subject limit.
+	if discardNewCheck && ms.cfg.DiscardNewSubjects && ms.cfg.MaxSubjects > 0 && len(subj) > 0 && ss == nil {
+		if int64(ms.fss.Size()) >= ms.cfg.MaxSubjects {
+			return ErrMaxSubjects
+		}
+	}
 
 	if seq != ms.state.LastSeq+1 {
 		if seq > 0 {
@@ -292,6 +327,12 @@ func (ms *memStore) storeRawMsg(subj string, hdr, msg []byte, seq uint64, ts, tt
 	// Limits checks and enforcement.
 	ms.enforceMsgLimit()
 	ms.enforceBytesLimit()
+	ms.enforceSubjectLimit(subj)
+
+	// Per-subject sequence.
+	if ms.ssq != nil {
+		ms.ssq.track(subj, hdr)
+	}
 
 	// Per-message TTL.
 	if ms.ttls != nil && ttl > 0 {
@@ -452,6 +493,50 @@ func (ms *memStore) RegisterProcessJetStreamMsg(cb ProcessJetStreamMsgHandler) {
 	ms.mu.Unlock()
 }
 
+// LastSubjectSequence returns the highest per-subject sequence stored for the subject.
+func (ms *memStore) LastSubjectSequence(subject string) uint64 {
+	ms.mu.RLock()
+	if ms.ssq != nil {
+		seq := ms.ssq[subject]
+		ms.mu.RUnlock()
+		return seq
+	}
+	ms.mu.RUnlock()
+	return lastSubjectSequence(ms, subject)
+}
+
+// ExpiringMsgs returns the messages that will expire, either due to MaxAge or their TTL,
+// after from and up to and including to, ordered by sequence.
+func (ms *memStore) ExpiringMsgs(from, to time.Time) []ExpiringMsg {
+	expiring := make(map[uint64]int64)
+	ms.mu.RLock()
+	maxAge := ms.cfg.MaxAge
+	if ms.ttls != nil {
+		ms.ttls.Expiring(from.UnixNano(), to.UnixNano(), func(seq uint64, expires int64) {
+			expiring[seq] = expires
+		})
+	}
+	ms.mu.RUnlock()
+
+	if maxAge > 0 {
+		// Messages that expire in the window were stored MaxAge before it.
+		start := ms.GetSeqFromTime(from.Add(-maxAge).Add(time.Nanosecond))
+		end := ms.GetSeqFromTime(to.Add(-maxAge).Add(time.Nanosecond))
+		var smv StoreMsg
+		for seq := start; seq > 0 && seq < end; seq++ {
+			sm, nseq, err := ms.LoadNextMsg(fwcs, true, seq, &smv)
+			if err != nil || sm == nil || nseq >= end {
+				break
+			}
+			if expires := sm.ts + int64(maxAge); expiring[nseq] == 0 || expires < expiring[nseq] {
+				expiring[nseq] = expires
+			}
+			seq = nseq
+		}
+	}
+	return sortedExpiringMsgs(expiring)
+}
+
 // GetSeqFromTime looks for the first sequence number that has the message
 // with >= timestamp.
 func (ms *memStore) GetSeqFromTime(t time.Time) uint64 {
@@ -1090,6 +1175,71 @@ func (ms *memStore) enforcePerSubjectLimit(subj string, ss *SimpleState) {
 	}
 }
 
+// Will evict the least recently written subjects while we are above the max subjects limit,
+// unless we discard new subjects instead. The keep subject is never evicted.
+// Lock should be held.
+func (ms *memStore) enforceSubjectLimit(keep string) {
+	if ms.cfg.MaxSubjects <= 0 || ms.cfg.DiscardNewSubjects {
+		ms.lrw = nil
+		return
+	}
+	if ms.lrw == nil {
+		ms.rebuildSubjectRecency()
+	} else if keep != _EMPTY_ {
+		ms.lrw.touch(keep)
+	}
+	for n := ms.fss.Size(); int64(n) > ms.cfg.MaxSubjects; {
+		lrw := ms.lrw.oldest(keep)
+		if lrw == _EMPTY_ {
+			return
+		}
+		// Remove all of its messages.
+		bsubj := stringToBytes(lrw)
+		if _, ok := ms.fss.Find(bsubj); !ok {
+			// The subject lost all its messages by other means, like a purge.
+			ms.lrw.remove(lrw)
+			continue
+		}
+		for ss, ok := ms.fss.Find(bsubj); ok && ss.Msgs > 0; ss, ok = ms.fss.Find(bsubj) {
+			if ss.firstNeedsUpdate || ss.lastNeedsUpdate {
+				ms.recalculateForSubj(lrw, ss)
+			}
+			if !ms.removeMsg(ss.First, false) {
+				break
+			}
+		}
+		// Make sure we made progress.
+		if nn := ms.fss.Size(); nn < n {
+			n = nn
+		} else {
+			return
+		}
+	}
+}
+
+// Orders all subjects by their last message, oldest first.
+// Lock should be held.
+func (ms *memStore) rebuildSubjectRecency() {
+	type subjLast struct {
+		subj string
+		last uint64
+	}
+	subjs := make([]subjLast, 0, ms.fss.Size())
+	ms.fss.IterFast(func(bsubj []byte, ss *SimpleState) bool {
+		subj := bytesToString(bsubj)
+		if ss.lastNeedsUpdate {
+			ms.recalculateForSubj(subj, ss)
+		}
+		subjs = append(subjs, subjLast{subj, ss.Last})
+		return true
+	})
+	slices.SortFunc(subjs, func(a, b subjLast) int { return cmp.Compare(a.last, b.last) })
+	ms.lrw = newSubjectRecency()
+	for _, sl := range subjs {
+		ms.lrw.touch(sl.subj)
+	}
+}
+
 // Will check the msg limit and drop firstSeq msg if needed.
 // Lock should be held.
 func (ms *memStore) enforceMsgLimit() {
@@ -1717,6 +1867,33 @@ func (ms *memStore) SubjectForSeq(seq uint64) (string, error) {
 	return _EMPTY_, ErrStoreMsgNotFound
 }
 
+// MsgExists returns whether the message is still stored.
+func (ms *memStore) MsgExists(seq uint64) bool {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	_, ok := ms.msgs[seq]
+	return ok
+}
+
+// DeletedRanges calls f in order with the ranges of sequences no longer in the store, starting
+// from the one holding or following start, until f returns false.
+// The store is read locked, so f must not call into it.
+func (ms *memStore) DeletedRanges(start uint64, f func(first, last uint64) bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	// Everything below the first sequence was removed.
+	if ms.state.FirstSeq > 1 && start < ms.state.FirstSeq && !f(1, ms.state.FirstSeq-1) {
+		return
+	}
+	ms.dmap.Range(func(seq uint64) bool {
+		if seq < start {
+			return true
+		}
+		return f(seq, seq)
+	})
+}
+
 // LoadMsg will lookup the message by sequence number and return it if found.
 func (ms *memStore) LoadMsg(seq uint64, smp *StoreMsg) (*StoreMsg, error) {
 	return ms.loadMsgLocked(seq, smp, true)
@@ -2081,6 +2258,9 @@ func (ms *memStore) removeSeqPerSubject(subj string, seq uint64) {
 	ms.sdm.removeSeqAndSubject(seq, subj)
 	if ss.Msgs == 1 {
 		ms.fss.Delete(stringToBytes(subj))
+		if ms.lrw != nil {
+			ms.lrw.remove(subj)
+		}
 		return
 	}
 	ss.Msgs--
@@ -2397,6 +2577,26 @@ func (ms *memStore) EncodedStreamState(failed uint64) ([]byte, error) {
 	return b, nil
 }
 
+// RebuildTotals recounts the messages and bytes held and corrects the
+// totals, returning by how much they changed.
+func (ms *memStore) RebuildTotals() (int64, int64, error) {
+	ms.mu.Lock()
+	var bytes uint64
+	for _, sm := range ms.msgs {
+		bytes += memStoreMsgSize(sm.subj, sm.hdr, sm.msg)
+	}
+	msgs := uint64(len(ms.msgs))
+	md, bd := int64(msgs)-int64(ms.state.Msgs), int64(bytes)-int64(ms.state.Bytes)
+	ms.state.Msgs, ms.state.Bytes = msgs, bytes
+	cb := ms.scb
+	ms.mu.Unlock()
+
+	if cb != nil && (md != 0 || bd != 0) {
+		cb(md, bd, 0, _EMPTY_)
+	}
+	return md, bd, nil
+}
+
 // SyncDeleted will make sure this stream has same deleted state as dbs.
 func (ms *memStore) SyncDeleted(dbs DeleteBlocks) error {
 	if len(dbs) == 0 {
@@ -1728,3 +1728,41 @@ func Benchmark_MemStoreLoadNextMsgFiltered(b *testing.B) {
 		})
 	}
 }
+
+func TestMemStoreRebuildTotals(t *testing.T) {
+	ms, err := newMemStore(&StreamConfig{Name: "zzz", Subjects: []string{"foo.*"}, Storage: MemoryStorage})
+	require_NoError(t, err)
+	defer ms.Stop()
+
+	var cbMsgs, cbBytes int64
+	ms.RegisterStorageUpdates(func(md, bd int64, _ uint64, _ string) {
+		cbMsgs, cbBytes = cbMsgs+md, cbBytes+bd
+	})
+	for i := 0; i < 20; i++ {
+		_, _, err = ms.StoreMsg("foo.bar", nil, []byte("hello world"), 0)
+		require_NoError(t, err)
+	}
+	_, err = ms.RemoveMsg(5)
+	require_NoError(t, err)
+	before := ms.State()
+
+	ms.mu.Lock()
+	ms.state.Msgs, ms.state.Bytes = ms.state.Msgs-4, ms.state.Bytes+100
+	ms.mu.Unlock()
+	cbMsgs, cbBytes = 0, 0
+
+	md, bd, err := ms.RebuildTotals()
+	require_NoError(t, err)
+	require_Equal(t, md, 4)
+	require_Equal(t, bd, -100)
+	require_Equal(t, cbMsgs, md)
+	require_Equal(t, cbBytes, bd)
+	after := ms.State()
+	require_Equal(t, after.Msgs, before.Msgs)
+	require_Equal(t, after.Bytes, before.Bytes)
+
+	md, bd, err = ms.RebuildTotals()
+	require_NoError(t, err)
+	require_Equal(t, md, 0)
+	require_Equal(t, bd, 0)
+}
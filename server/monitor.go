@@ -980,6 +980,17 @@ type SubDetail struct {
 	Msgs       int64  `json:"msgs"`
 	Max        int64  `json:"max,omitempty"`
 	Cid        uint64 `json:"cid"`
+	// Only set if the account requires deliveries to be acknowledged.
+	DeliveryAcks *SubDeliveryAcks `json:"delivery_acks,omitempty"`
+}
+
+// SubDeliveryAcks reports the acknowledgment of the deliveries to a subscription.
+type SubDeliveryAcks struct {
+	Delivered   uint64 `json:"delivered"`
+	Acked       uint64 `json:"acked"`
+	Outstanding int    `json:"outstanding"`
+	// Outstanding deliveries that are no longer tracked, since the maximum was reached.
+	Untracked uint64 `json:"untracked,omitempty"`
 }
 
 // Subscription client should be locked and guaranteed to be present.
@@ -992,7 +1003,7 @@ func newSubDetail(sub *subscription) SubDetail {
 
 // For subs details under clients.
 func newClientSubDetail(sub *subscription) SubDetail {
-	return SubDetail{
+	sd := SubDetail{
 		Subject: string(sub.subject),
 		Queue:   string(sub.queue),
 		Sid:     string(sub.sid),
@@ -1000,6 +1011,15 @@ func newClientSubDetail(sub *subscription) SubDetail {
 		Max:     sub.max,
 		Cid:     sub.client.cid,
 	}
+	if da := sub.dack; da != nil {
+		sd.DeliveryAcks = &SubDeliveryAcks{
+			Delivered:   da.seq,
+			Acked:       da.acked,
+			Outstanding: len(da.pending),
+			Untracked:   da.untracked,
+		}
+	}
+	return sd
 }
 
 // Subsz returns a Subsz struct containing subjects statistics
@@ -2666,6 +2686,10 @@ func (reason ClosedState) String() string {
 		return "Proxy Not Trusted"
 	case ProxyRequired:
 		return "Proxy Required"
+	case AccountRequiresTLS:
+		return "Account Requires TLS"
+	case ClientBlocked:
+		return "Client Blocked"
 	}
 
 	return "Unknown State"
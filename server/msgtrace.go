@@ -254,6 +254,7 @@ const (
 	errMsgTraceSubClosed       = "Not delivered because subscription is closed"
 	errMsgTraceClientClosed    = "Not delivered because client is closed"
 	errMsgTraceAutoSubExceeded = "Not delivered because auto-unsubscribe exceeded"
+	errMsgTraceMaxInFlight     = "Not delivered because maximum in-flight requests reached"
 	errMsgTraceFastProdNoStall = "Not delivered because fast producer not stalled and consumer is slow"
 )
 
@@ -461,8 +462,9 @@ func (c *client) initMsgTrace() *msgTrace {
 			// No account destination, no tracing for external trace headers.
 			return nil
 		}
-		// Check sampling, but only from origin server.
-		if c.kind == CLIENT && !sample(sampling) {
+		// Check sampling, but only from origin server and if the message
+		// was not already selected by the account's sampling.
+		if c.kind == CLIENT && !c.pa.sampled && !sample(sampling) {
 			// Need to disable tracing so that if the message is routed, it won't
 			// trigger a trace there.
 			c.msgBuf = c.setHeader(MsgTraceDest, MsgTraceDestDisabled, c.msgBuf)
@@ -498,6 +500,45 @@ func (c *client) initMsgTrace() *msgTrace {
 	return c.pa.trace
 }
 
+// sampleMsgTrace is called for messages published by clients in accounts that
+// sample messages for tracing. If the message is selected, and does not already
+// carry trace headers, a sampled traceparent header is added so that the message
+// is traced to the account's destination on this and any remote server.
+func (c *client) sampleMsgTrace() bool {
+	if c.pa.hdr > 0 {
+		hdr := c.msgBuf[:c.pa.hdr]
+		if sliceHeader(MsgTraceDest, hdr) != nil || hasTraceParentHeader(hdr) {
+			return false
+		}
+	}
+	if !c.acc.sampleMsgTrace(bytesToString(c.pa.subject)) {
+		return false
+	}
+	tp := fmt.Sprintf("00-%016x%016x-%016x-01", rand.Uint64(), rand.Uint64(), rand.Uint64())
+	c.msgBuf = c.setHeader(traceParentHdr, tp, c.msgBuf)
+	return true
+}
+
+// hasTraceParentHeader returns true if the headers contain a traceparent key,
+// matched in a case insensitive way without allocating.
+func hasTraceParentHeader(hdr []byte) bool {
+	if !bytes.HasPrefix(hdr, stringToBytes(hdrLine)) {
+		return false
+	}
+	for i := len(hdrLine); i < len(hdr); {
+		nl := bytes.Index(hdr[i:], crLFAsBytes)
+		if nl < 0 {
+			nl = len(hdr) - i
+		}
+		line := hdr[i : i+nl]
+		if del := bytes.IndexByte(line, ':'); del > 0 && bytes.EqualFold(line[:del], traceParentHdrAsBytes) {
+			return true
+		}
+		i += nl + len(crLFAsBytes)
+	}
+	return false
+}
+
 func sample(sampling int) bool {
 	// Option parsing should ensure that sampling is [1..100], but consider
 	// any value outside of this range to be 100%.
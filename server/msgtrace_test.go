@@ -5477,3 +5477,205 @@ func TestMsgTraceAccDestWithSamplingJWTUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestMsgTraceAccountSampleEvery(t *testing.T) {
+	tmpl := `
+		port: -1
+		server_name: %s
+		accounts {
+			A {
+				users: [{user: a, password: pwd, permissions: {publish: {deny: "acc.dest"}}}]
+				msg_trace: {dest: "acc.dest", %s}
+			}
+		}
+		cluster {
+			port: -1
+			%s
+		}
+	`
+	conf1 := createConfFile(t, []byte(fmt.Sprintf(tmpl, "A", "sample_every: 4", _EMPTY_)))
+	s1, o1 := RunServerWithConfig(conf1)
+	defer s1.Shutdown()
+
+	routes := fmt.Sprintf("routes: [\"nats://127.0.0.1:%d\"]", o1.Cluster.Port)
+	conf2 := createConfFile(t, []byte(fmt.Sprintf(tmpl, "B", "sample_every: 4", routes)))
+	s2, _ := RunServerWithConfig(conf2)
+	defer s2.Shutdown()
+
+	checkClusterFormed(t, s1, s2)
+
+	nc2 := natsConnect(t, s2.ClientURL(), nats.UserInfo("a", "pwd"))
+	defer nc2.Close()
+	fooSub := natsSubSync(t, nc2, "foo.>")
+	natsFlush(t, nc2)
+
+	nc1 := natsConnect(t, s1.ClientURL(), nats.UserInfo("a", "pwd"))
+	defer nc1.Close()
+	traceSub := natsSubSync(t, nc1, "acc.dest")
+	natsFlush(t, nc1)
+
+	checkSubInterest(t, s1, "A", "foo.bar", time.Second)
+	checkSubInterest(t, s2, "A", "acc.dest", time.Second)
+
+	checkTraces := func(total, expected int, subj string) {
+		t.Helper()
+		var sampled int
+		for i := 0; i < total; i++ {
+			m := natsNexMsg(t, fooSub, time.Second)
+			require_Equal(t, m.Subject, subj)
+			if m.Header.Get(traceParentHdr) != _EMPTY_ {
+				sampled++
+			}
+		}
+		require_Equal(t, sampled, expected)
+
+		fromClient, fromRoute := 0, 0
+		for i := 0; i < 2*expected; i++ {
+			m := natsNexMsg(t, traceSub, time.Second)
+			var e MsgTraceEvent
+			require_NoError(t, json.Unmarshal(m.Data, &e))
+			ingress := e.Ingress()
+			require_True(t, ingress != nil)
+			require_Equal(t, ingress.Account, "A")
+			require_Equal(t, ingress.Subject, subj)
+			egress := e.Egresses()
+			require_Len(t, len(egress), 1)
+			switch ingress.Kind {
+			case CLIENT:
+				// Origin server routes the message to the subscriber's server.
+				fromClient++
+				require_Equal(t, e.Server.Name, "A")
+				require_Equal(t, egress[0].Kind, ROUTER)
+				require_Equal(t, egress[0].Name, "B")
+			case ROUTER:
+				fromRoute++
+				require_Equal(t, e.Server.Name, "B")
+				require_Equal(t, ingress.Name, "A")
+				require_Equal(t, egress[0].Kind, CLIENT)
+				require_Equal(t, egress[0].Subscription, "foo.>")
+			default:
+				t.Fatalf("Unexpected ingress: %+v", ingress)
+			}
+		}
+		require_Equal(t, fromClient, expected)
+		require_Equal(t, fromRoute, expected)
+		if m, err := traceSub.NextMsg(250 * time.Millisecond); err == nil {
+			t.Fatalf("Unexpected trace event: %s", m.Data)
+		}
+	}
+
+	// One message out of every 4, even though the publisher is not allowed
+	// to publish to the trace destination itself.
+	total := 100
+	for i := 0; i < total; i++ {
+		natsPub(t, nc1, "foo.bar", []byte("hello"))
+	}
+	checkTraces(total, total/4, "foo.bar")
+
+	// Messages that already carry trace headers are left alone.
+	msg := nats.NewMsg("foo.bar")
+	msg.Header.Set(traceParentHdr, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	for i := 0; i < 8; i++ {
+		require_NoError(t, nc1.PublishMsg(msg))
+	}
+	for i := 0; i < 8; i++ {
+		m := natsNexMsg(t, fooSub, time.Second)
+		require_Equal(t, m.Header.Get(traceParentHdr), msg.Header.Get(traceParentHdr))
+	}
+	if m, err := traceSub.NextMsg(250 * time.Millisecond); err == nil {
+		t.Fatalf("Unexpected trace event: %s", m.Data)
+	}
+
+	// Restrict sampling to some subjects, every matching message is traced.
+	reloadUpdateConfig(t, s1, conf1, fmt.Sprintf(tmpl, "A", "sample_subjects: [\"foo.sampled.>\"]", _EMPTY_))
+	reloadUpdateConfig(t, s2, conf2, fmt.Sprintf(tmpl, "B", "sample_subjects: [\"foo.sampled.>\"]", routes))
+	for i := 0; i < 10; i++ {
+		natsPub(t, nc1, "foo.bar", []byte("hello"))
+	}
+	checkTraces(10, 0, "foo.bar")
+	for i := 0; i < 10; i++ {
+		natsPub(t, nc1, "foo.sampled.1", []byte("hello"))
+	}
+	checkTraces(10, 10, "foo.sampled.1")
+
+	// Both combined.
+	reloadUpdateConfig(t, s1, conf1, fmt.Sprintf(tmpl, "A", "sample_subjects: \"foo.sampled.>\", sample_every: 5", _EMPTY_))
+	reloadUpdateConfig(t, s2, conf2, fmt.Sprintf(tmpl, "B", "sample_subjects: \"foo.sampled.>\", sample_every: 5", routes))
+	for i := 0; i < 20; i++ {
+		natsPub(t, nc1, "foo.bar", []byte("hello"))
+		natsPub(t, nc1, "foo.sampled.2", []byte("hello"))
+	}
+	var sampled int
+	for i := 0; i < 40; i++ {
+		m := natsNexMsg(t, fooSub, time.Second)
+		if m.Header.Get(traceParentHdr) != _EMPTY_ {
+			require_Equal(t, m.Subject, "foo.sampled.2")
+			sampled++
+		}
+	}
+	require_Equal(t, sampled, 4)
+	for i := 0; i < 2*sampled; i++ {
+		natsNexMsg(t, traceSub, time.Second)
+	}
+}
+
+func TestMsgTraceParseAccountSampling(t *testing.T) {
+	tmpl := `
+		port: -1
+		accounts {
+			A {
+				users: [{user: a, password: pwd}]
+				%s
+			}
+		}
+	`
+	for _, test := range []struct {
+		name     string
+		cfg      string
+		every    int
+		subjects []string
+		err      string
+	}{
+		{"every", `msg_trace: {dest: foo, sample_every: 10}`, 10, nil, _EMPTY_},
+		{"subject", `msg_trace: {dest: foo, sample_subject: "bar.>"}`, 0, []string{"bar.>"}, _EMPTY_},
+		{"subjects", `msg_trace: {dest: foo, sample_subjects: ["bar.>", "baz"], sample_every: 2}`, 2, []string{"bar.>", "baz"}, _EMPTY_},
+		{"no dest", `msg_trace: {sample_every: 10}`, 0, nil, _EMPTY_},
+		{"bad every", `msg_trace: {dest: foo, sample_every: 0}`, 0, nil, "needs to be a positive integer"},
+		{"bad subject", `msg_trace: {dest: foo, sample_subjects: ["bar..baz"]}`, 0, nil, "is not valid"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf := createConfFile(t, []byte(fmt.Sprintf(tmpl, test.cfg)))
+			o, err := ProcessConfigFile(conf)
+			if test.err != _EMPTY_ {
+				require_Error(t, err)
+				require_Contains(t, err.Error(), test.err)
+				return
+			}
+			require_NoError(t, err)
+			acc := o.Accounts[0]
+			require_Equal(t, acc.traceSampleEvery, test.every)
+			require_Equal(t, strings.Join(acc.traceSampleSubjects, ","), strings.Join(test.subjects, ","))
+			require_Equal(t, acc.hasTraceSampling(), test.every > 0 || len(test.subjects) > 0)
+		})
+	}
+}
+
+func TestMsgTraceHasTraceParentHeader(t *testing.T) {
+	for _, test := range []struct {
+		hdr      string
+		expected bool
+	}{
+		{"NATS/1.0\r\ntraceparent: 00-1-2-01\r\n\r\n", true},
+		{"NATS/1.0\r\nTraceParent: 00-1-2-01\r\n\r\n", true},
+		{"NATS/1.0\r\nfoo: bar\r\nTRACEPARENT: 00-1-2-01\r\n\r\n", true},
+		{"NATS/1.0\r\nfoo: traceparent\r\n\r\n", false},
+		{"NATS/1.0\r\ntraceparents: x\r\n\r\n", false},
+		{"NATS/1.0\r\n\r\n", false},
+		{"traceparent: 00-1-2-01\r\n\r\n", false},
+	} {
+		hdr := []byte(test.hdr)
+		require_Equal(t, hasTraceParentHeader(hdr), test.expected)
+		allocs := testing.AllocsPerRun(10, func() { hasTraceParentHeader(hdr) })
+		require_Equal(t, allocs, 0)
+	}
+}
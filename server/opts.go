@@ -87,6 +87,8 @@ type ClusterOpts struct {
 	MaxPingsOut       int                `json:"-"`
 	WriteDeadline     time.Duration      `json:"-"`
 	WriteTimeout      WriteTimeoutPolicy `json:"-"`
+	PendingSoftLimit  int64              `json:"-"`
+	PendingHardLimit  int64              `json:"-"`
 
 	// Not exported (used in tests)
 	resolver netResolver
@@ -141,24 +143,26 @@ func (c1 *CompressionOpts) equals(c2 *CompressionOpts) bool {
 // NOTE: This structure is no longer used for monitoring endpoints
 // and json tags are deprecated and may be removed in the future.
 type GatewayOpts struct {
-	Name              string               `json:"name"`
-	Host              string               `json:"addr,omitempty"`
-	Port              int                  `json:"port,omitempty"`
-	Username          string               `json:"-"`
-	Password          string               `json:"-"`
-	AuthTimeout       float64              `json:"auth_timeout,omitempty"`
-	TLSConfig         *tls.Config          `json:"-"`
-	TLSTimeout        float64              `json:"tls_timeout,omitempty"`
-	TLSMap            bool                 `json:"-"`
-	TLSCheckKnownURLs bool                 `json:"-"`
-	TLSPinnedCerts    PinnedCertSet        `json:"-"`
-	Advertise         string               `json:"advertise,omitempty"`
-	ConnectRetries    int                  `json:"connect_retries,omitempty"`
-	ConnectBackoff    bool                 `json:"connect_backoff,omitempty"`
-	Gateways          []*RemoteGatewayOpts `json:"gateways,omitempty"`
-	RejectUnknown     bool                 `json:"reject_unknown,omitempty"` // config got renamed to reject_unknown_cluster
-	WriteDeadline     time.Duration        `json:"-"`
-	WriteTimeout      WriteTimeoutPolicy   `json:"-"`
+	Name              string                      `json:"name"`
+	Host              string                      `json:"addr,omitempty"`
+	Port              int                         `json:"port,omitempty"`
+	Username          string                      `json:"-"`
+	Password          string                      `json:"-"`
+	AuthTimeout       float64                     `json:"auth_timeout,omitempty"`
+	TLSConfig         *tls.Config                 `json:"-"`
+	TLSTimeout        float64                     `json:"tls_timeout,omitempty"`
+	TLSMap            bool                        `json:"-"`
+	TLSCheckKnownURLs bool                        `json:"-"`
+	TLSPinnedCerts    PinnedCertSet               `json:"-"`
+	Advertise         string                      `json:"advertise,omitempty"`
+	ConnectRetries    int                         `json:"connect_retries,omitempty"`
+	ConnectBackoff    bool                        `json:"connect_backoff,omitempty"`
+	Gateways          []*RemoteGatewayOpts        `json:"gateways,omitempty"`
+	RejectUnknown     bool                        `json:"reject_unknown,omitempty"` // config got renamed to reject_unknown_cluster
+	WriteDeadline     time.Duration               `json:"-"`
+	WriteTimeout      WriteTimeoutPolicy          `json:"-"`
+	UnknownAccount    GatewayUnknownAccountPolicy `json:"unknown_account,omitempty"`
+	Permissions       *RoutePermissions           `json:"-"`
 
 	// Not exported, for tests.
 	resolver         netResolver
@@ -229,6 +233,20 @@ type LeafNodeOpts struct {
 	// east-west propagation.
 	IsolateLeafnodeInterest bool `json:"-"`
 
+	// MaxRemotesPerAccount caps the number of remotes, that is outbound leafnode
+	// connections, bound to the same local account. Excess remotes do not connect.
+	MaxRemotesPerAccount int `json:"-"`
+
+	// LoopDetection tags messages sent over leafnode connections with the clusters they passed
+	// through, and drops messages that would revisit a cluster, sending an advisory. This catches
+	// routing loops in misconfigured topologies, so it should be enabled on all of its servers.
+	LoopDetection bool `json:"-"`
+
+	// LoopDetectionMaxPath bounds the number of clusters, including the one it was published in,
+	// a message can reach over leafnode connections when loop detection is enabled. Messages
+	// exceeding it are dropped as well. If zero, the default of 16 is used.
+	LoopDetectionMaxPath int `json:"-"`
+
 	// Not exported, for tests.
 	resolver    netResolver
 	dialTimeout time.Duration
@@ -375,6 +393,34 @@ type JSTpmOpts struct {
 	Pcr         int
 }
 
+// JSRaftOpts are tuning options for the Raft groups of streams and consumers.
+type JSRaftOpts struct {
+	// MaxAppendEntryDecodeFailures is the number of consecutive append entries that can fail to
+	// decode before the replication subscriptions of a group are recreated.
+	MaxAppendEntryDecodeFailures int
+
+	// SlowReplicaTimeout, SlowReplicaThreshold and SlowReplicaAction configure how
+	// a leader handles followers that repeatedly time out storing entries.
+	SlowReplicaTimeout   time.Duration
+	SlowReplicaThreshold int
+	SlowReplicaAction    SlowReplicaAction
+
+	// RecoveryTimeObjective bounds how long replaying the log of a group on a
+	// cold restart should take, by snapshotting before it grows too large.
+	RecoveryTimeObjective time.Duration
+
+	// MaxLogSize caps the size of the log of a group, which gets a snapshot
+	// when getting close to it and refuses proposals once at the cap.
+	MaxLogSize uint64
+
+	// ApplyErrorRetries, ApplyErrorBackoff and ApplyErrorPolicy configure how
+	// committed entries that fail to apply are retried, and what happens once
+	// out of retries.
+	ApplyErrorRetries int
+	ApplyErrorBackoff time.Duration
+	ApplyErrorPolicy  ApplyErrorPolicy
+}
+
 // AuthCallout option used to map external AuthN to NATS based AuthZ.
 type AuthCallout struct {
 	// Must be a public account Nkey.
@@ -406,91 +452,109 @@ type Options struct {
 	TraceVerbose    bool   `json:"-"`
 
 	// TraceHeaders if true will only trace message headers, not the payload.
-	TraceHeaders               bool          `json:"-"`
-	NoLog                      bool          `json:"-"`
-	NoSigs                     bool          `json:"-"`
-	NoSublistCache             bool          `json:"-"`
-	NoHeaderSupport            bool          `json:"-"`
-	DisableShortFirstPing      bool          `json:"-"`
-	Logtime                    bool          `json:"-"`
-	LogtimeUTC                 bool          `json:"-"`
-	MaxConn                    int           `json:"max_connections"`
-	MaxSubs                    int           `json:"max_subscriptions,omitempty"`
-	MaxSubTokens               uint8         `json:"-"`
-	Nkeys                      []*NkeyUser   `json:"-"`
-	Users                      []*User       `json:"-"`
-	Accounts                   []*Account    `json:"-"`
-	NoAuthUser                 string        `json:"-"`
-	DefaultSentinel            string        `json:"-"`
-	SystemAccount              string        `json:"-"`
-	NoSystemAccount            bool          `json:"-"`
-	Username                   string        `json:"-"`
-	Password                   string        `json:"-"`
-	ProxyRequired              bool          `json:"-"`
-	ProxyProtocol              bool          `json:"-"`
-	Authorization              string        `json:"-"`
-	AuthCallout                *AuthCallout  `json:"-"`
-	PingInterval               time.Duration `json:"ping_interval"`
-	MaxPingsOut                int           `json:"ping_max"`
-	HTTPHost                   string        `json:"http_host"`
-	HTTPPort                   int           `json:"http_port"`
-	HTTPBasePath               string        `json:"http_base_path"`
-	HTTPSPort                  int           `json:"https_port"`
-	AuthTimeout                float64       `json:"auth_timeout"`
-	MaxControlLine             int32         `json:"max_control_line"`
-	MaxPayload                 int32         `json:"max_payload"`
-	MaxPending                 int64         `json:"max_pending"`
-	NoFastProducerStall        bool          `json:"-"`
-	Cluster                    ClusterOpts   `json:"cluster,omitempty"`
-	Gateway                    GatewayOpts   `json:"gateway,omitempty"`
-	LeafNode                   LeafNodeOpts  `json:"leaf,omitempty"`
-	JetStream                  bool          `json:"jetstream"`
-	NoJetStreamStrict          bool          `json:"-"` // Strict by default.
-	JetStreamMaxMemory         int64         `json:"-"`
-	JetStreamMaxStore          int64         `json:"-"`
-	JetStreamDomain            string        `json:"-"`
-	JetStreamExtHint           string        `json:"-"`
-	JetStreamKey               string        `json:"-"`
-	JetStreamOldKey            string        `json:"-"`
-	JetStreamCipher            StoreCipher   `json:"-"`
-	JetStreamUniqueTag         string
-	JetStreamLimits            JSLimitOpts
-	JetStreamTpm               JSTpmOpts
-	JetStreamMaxCatchup        int64
-	JetStreamRequestQueueLimit int64
-	JetStreamInfoQueueLimit    int64
-	JetStreamMetaCompact       uint64
-	JetStreamMetaCompactSize   uint64
-	JetStreamMetaCompactSync   bool
-	StreamMaxBufferedMsgs      int               `json:"-"`
-	StreamMaxBufferedSize      int64             `json:"-"`
-	StoreDir                   string            `json:"-"`
-	SyncInterval               time.Duration     `json:"-"`
-	SyncAlways                 bool              `json:"-"`
-	JsAccDefaultDomain         map[string]string `json:"-"` // account to domain name mapping
-	Websocket                  WebsocketOpts     `json:"-"`
-	MQTT                       MQTTOpts          `json:"-"`
-	ProfPort                   int               `json:"-"`
-	ProfBlockRate              int               `json:"-"`
-	PidFile                    string            `json:"-"`
-	PortsFileDir               string            `json:"-"`
-	LogFile                    string            `json:"-"`
-	LogSizeLimit               int64             `json:"-"`
-	LogMaxFiles                int64             `json:"-"`
-	Syslog                     bool              `json:"-"`
-	RemoteSyslog               string            `json:"-"`
-	Routes                     []*url.URL        `json:"-"`
-	RoutesStr                  string            `json:"-"`
-	TLSTimeout                 float64           `json:"tls_timeout"`
-	TLS                        bool              `json:"-"`
-	TLSVerify                  bool              `json:"-"`
-	TLSMap                     bool              `json:"-"`
-	TLSCert                    string            `json:"-"`
-	TLSKey                     string            `json:"-"`
-	TLSCaCert                  string            `json:"-"`
-	TLSConfig                  *tls.Config       `json:"-"`
-	TLSPinnedCerts             PinnedCertSet     `json:"-"`
-	TLSRateLimit               int64             `json:"-"`
+	TraceHeaders                   bool          `json:"-"`
+	NoLog                          bool          `json:"-"`
+	NoSigs                         bool          `json:"-"`
+	NoSublistCache                 bool          `json:"-"`
+	NoHeaderSupport                bool          `json:"-"`
+	DisableShortFirstPing          bool          `json:"-"`
+	Logtime                        bool          `json:"-"`
+	LogtimeUTC                     bool          `json:"-"`
+	MaxConn                        int           `json:"max_connections"`
+	MaxConnectRate                 int           `json:"max_connect_rate,omitempty"`
+	MaxConnectBacklog              int           `json:"max_connect_backlog,omitempty"`
+	MaxSubs                        int           `json:"max_subscriptions,omitempty"`
+	MaxSubTokens                   uint8         `json:"-"`
+	MaxSubSubjectLen               int           `json:"-"`
+	MaxSubsChurn                   int           `json:"max_subscription_churn,omitempty"`
+	MaxFanout                      int           `json:"max_fanout,omitempty"`
+	FanoutPolicy                   FanoutPolicy  `json:"fanout_policy,omitempty"`
+	Nkeys                          []*NkeyUser   `json:"-"`
+	Users                          []*User       `json:"-"`
+	Accounts                       []*Account    `json:"-"`
+	NoAuthUser                     string        `json:"-"`
+	DefaultSentinel                string        `json:"-"`
+	SystemAccount                  string        `json:"-"`
+	NoSystemAccount                bool          `json:"-"`
+	Username                       string        `json:"-"`
+	Password                       string        `json:"-"`
+	ProxyRequired                  bool          `json:"-"`
+	ProxyProtocol                  bool          `json:"-"`
+	Authorization                  string        `json:"-"`
+	AuthCallout                    *AuthCallout  `json:"-"`
+	PingInterval                   time.Duration `json:"ping_interval"`
+	MaxPingsOut                    int           `json:"ping_max"`
+	HTTPHost                       string        `json:"http_host"`
+	HTTPPort                       int           `json:"http_port"`
+	HTTPBasePath                   string        `json:"http_base_path"`
+	HTTPSPort                      int           `json:"https_port"`
+	AuthTimeout                    float64       `json:"auth_timeout"`
+	MaxControlLine                 int32         `json:"max_control_line"`
+	MaxPayload                     int32         `json:"max_payload"`
+	MaxPending                     int64         `json:"max_pending"`
+	NoFastProducerStall            bool          `json:"-"`
+	Cluster                        ClusterOpts   `json:"cluster,omitempty"`
+	Gateway                        GatewayOpts   `json:"gateway,omitempty"`
+	LeafNode                       LeafNodeOpts  `json:"leaf,omitempty"`
+	JetStream                      bool          `json:"jetstream"`
+	NoJetStreamStrict              bool          `json:"-"` // Strict by default.
+	JetStreamMaxMemory             int64         `json:"-"`
+	JetStreamMaxStore              int64         `json:"-"`
+	JetStreamDomain                string        `json:"-"`
+	JetStreamExtHint               string        `json:"-"`
+	JetStreamKey                   string        `json:"-"`
+	JetStreamOldKey                string        `json:"-"`
+	JetStreamCipher                StoreCipher   `json:"-"`
+	JetStreamUniqueTag             string
+	JetStreamLimits                JSLimitOpts
+	JetStreamTpm                   JSTpmOpts
+	JetStreamRaft                  JSRaftOpts
+	JetStreamMaxCatchup            int64
+	JetStreamMaxConcurrentCatchups int
+	JetStreamMaxRecoveries         int
+	JetStreamMaxSnapshots          int
+	JetStreamMaxApplyBacklog       int64
+	JetStreamBalanceInterval       time.Duration
+	JetStreamBalanceThreshold      int
+	JetStreamOrphanInterval        time.Duration
+	JetStreamRequestQueueLimit     int64
+	JetStreamInfoQueueLimit        int64
+	JetStreamConsumerHibernate     time.Duration
+	JetStreamTombstoneMaxAge       time.Duration
+	JetStreamIOErrorPolicy         IOErrorPolicy
+	JetStreamMetaCompact           uint64
+	JetStreamMetaCompactSize       uint64
+	JetStreamMetaCompactSync       bool
+	StreamMaxBufferedMsgs          int               `json:"-"`
+	StreamMaxBufferedSize          int64             `json:"-"`
+	StoreDir                       string            `json:"-"`
+	SyncInterval                   time.Duration     `json:"-"`
+	SyncAlways                     bool              `json:"-"`
+	JsAccDefaultDomain             map[string]string `json:"-"` // account to domain name mapping
+	Websocket                      WebsocketOpts     `json:"-"`
+	MQTT                           MQTTOpts          `json:"-"`
+	AccessLog                      AccessLogOpts     `json:"-"`
+	ProfPort                       int               `json:"-"`
+	ProfBlockRate                  int               `json:"-"`
+	PidFile                        string            `json:"-"`
+	PortsFileDir                   string            `json:"-"`
+	LogFile                        string            `json:"-"`
+	LogSizeLimit                   int64             `json:"-"`
+	LogMaxFiles                    int64             `json:"-"`
+	Syslog                         bool              `json:"-"`
+	RemoteSyslog                   string            `json:"-"`
+	Routes                         []*url.URL        `json:"-"`
+	RoutesStr                      string            `json:"-"`
+	TLSTimeout                     float64           `json:"tls_timeout"`
+	TLS                            bool              `json:"-"`
+	TLSVerify                      bool              `json:"-"`
+	TLSMap                         bool              `json:"-"`
+	TLSCert                        string            `json:"-"`
+	TLSKey                         string            `json:"-"`
+	TLSCaCert                      string            `json:"-"`
+	TLSConfig                      *tls.Config       `json:"-"`
+	TLSPinnedCerts                 PinnedCertSet     `json:"-"`
+	TLSRateLimit                   int64             `json:"-"`
 	// When set to true, the server will perform the TLS handshake before
 	// sending the INFO protocol. For clients that are not configured
 	// with a similar option, their connection will fail with some sort
@@ -509,16 +573,30 @@ type Options struct {
 	MaxClosedClients          int                `json:"-"`
 	LameDuckDuration          time.Duration      `json:"-"`
 	LameDuckGracePeriod       time.Duration      `json:"-"`
+	LameDuckRedirectURLs      []string           `json:"-"`
 
 	// MaxTracedMsgLen is the maximum printable length for traced messages.
 	MaxTracedMsgLen int `json:"-"`
 
+	// LoadHintsInterval is how often the load hints of this server are updated in the INFO
+	// protocol, and sent to clients that accept async INFO updates. This allows clients to
+	// prefer less loaded servers when reconnecting. If zero, no load hints are sent.
+	LoadHintsInterval time.Duration `json:"-"`
+
+	// ConnectBlocklist lists client identifiers that are rejected when connecting.
+	// Can be changed with a config reload, or at runtime with SetConnectBlocklist.
+	ConnectBlocklist ConnectBlocklistOpts `json:"-"`
+
 	// Operating a trusted NATS server
 	TrustedKeys              []string              `json:"-"`
 	TrustedOperators         []*jwt.OperatorClaims `json:"-"`
 	AccountResolver          AccountResolver       `json:"-"`
 	AccountResolverTLSConfig *tls.Config           `json:"-"`
 
+	// PinnedMappings are subject mappings per account public key that are merged with
+	// the mappings of the account JWT, and take precedence over them.
+	PinnedMappings map[string]map[string][]*MapDest `json:"-"`
+
 	// AlwaysEnableNonce will always present a nonce to new connections
 	// typically used by custom Authentication implementations who embeds
 	// the server and so not presented as a configuration option
@@ -1347,6 +1425,22 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 		if o.MaxConn = int(v.(int64)); o.MaxConn == 0 {
 			o.MaxConn = -1
 		}
+	case "max_connect_rate":
+		if n := v.(int64); n < 0 {
+			err := &configErr{tk, fmt.Sprintf("%s value can not be negative", k)}
+			*errors = append(*errors, err)
+			return
+		} else {
+			o.MaxConnectRate = int(n)
+		}
+	case "max_connect_backlog":
+		if n := v.(int64); n < 0 {
+			err := &configErr{tk, fmt.Sprintf("%s value can not be negative", k)}
+			*errors = append(*errors, err)
+			return
+		} else {
+			o.MaxConnectBacklog = int(n)
+		}
 	case "max_traced_msg_len":
 		o.MaxTracedMsgLen = int(v.(int64))
 	case "max_subscriptions", "max_subs":
@@ -1363,6 +1457,43 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 		} else {
 			o.MaxSubTokens = uint8(n)
 		}
+	case "max_sub_subject_len", "max_subscription_subject_length":
+		if n := v.(int64); n <= 0 {
+			err := &configErr{tk, fmt.Sprintf("%s value must be positive", k)}
+			*errors = append(*errors, err)
+			return
+		} else {
+			o.MaxSubSubjectLen = int(n)
+		}
+	case "max_subscription_churn", "max_subs_churn":
+		if n := v.(int64); n < 0 {
+			err := &configErr{tk, fmt.Sprintf("%s value can not be negative", k)}
+			*errors = append(*errors, err)
+			return
+		} else {
+			o.MaxSubsChurn = int(n)
+		}
+	case "max_fanout":
+		if n := v.(int64); n < 0 {
+			err := &configErr{tk, fmt.Sprintf("%s value can not be negative", k)}
+			*errors = append(*errors, err)
+			return
+		} else {
+			o.MaxFanout = int(n)
+		}
+	case "fanout_policy":
+		switch p, _ := v.(string); strings.ToLower(p) {
+		case "yield":
+			o.FanoutPolicy = FanoutYield
+		case "advise", "log":
+			o.FanoutPolicy = FanoutAdvise
+		case "reject":
+			o.FanoutPolicy = FanoutReject
+		default:
+			err := &configErr{tk, "fanout_policy must be 'yield', 'advise' or 'reject'"}
+			*errors = append(*errors, err)
+			return
+		}
 	case "ping_interval":
 		o.PingInterval = parseDuration("ping_interval", tk, v, errors, warnings)
 	case "ping_max":
@@ -1467,6 +1598,34 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 			return
 		}
 		o.LameDuckGracePeriod = dur
+	case "lame_duck_redirect_urls", "lame_duck_redirects":
+		urls, err := parseStringArray("lame_duck_redirect_urls", tk, &lt, v, errors)
+		if err != nil {
+			return
+		}
+		o.LameDuckRedirectURLs = urls[:0]
+		for _, u := range urls {
+			host, port, err := parseHostPort(u, DEFAULT_PORT)
+			if err != nil || host == _EMPTY_ {
+				err := &configErr{tk, fmt.Sprintf("invalid lame_duck_redirect_urls entry %q, expected host:port", u)}
+				*errors = append(*errors, err)
+				return
+			}
+			o.LameDuckRedirectURLs = append(o.LameDuckRedirectURLs, net.JoinHostPort(host, strconv.Itoa(port)))
+		}
+	case "load_hints_interval":
+		dur, err := time.ParseDuration(v.(string))
+		if err != nil {
+			err := &configErr{tk, fmt.Sprintf("error parsing load_hints_interval: %v", err)}
+			*errors = append(*errors, err)
+			return
+		}
+		if dur < 0 {
+			err := &configErr{tk, "invalid load_hints_interval, needs to be positive"}
+			*errors = append(*errors, err)
+			return
+		}
+		o.LoadHintsInterval = dur
 	case "operator", "operators", "roots", "root", "root_operators", "root_operator":
 		opFiles := []string{}
 		switch v := v.(type) {
@@ -1548,6 +1707,7 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 			del := false
 			hdel := false
 			hdel_set := false
+			delPolicySet := false
 			dir := _EMPTY_
 			dirType := _EMPTY_
 			limit := int64(0)
@@ -1591,6 +1751,27 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 					opts = append(opts, FetchTimeout(to))
 				}
 			}
+			if v, ok := v["delete_policy"]; err == nil && ok {
+				ptk, v := unwrapValue(v, &lt)
+				delPolicySet = true
+				pv, ok := v.(string)
+				if !ok {
+					*errors = append(*errors, &configErr{ptk, fmt.Sprintf("delete_policy must be a string, got %T", v)})
+					return
+				}
+				var policy AccountDeletePolicy
+				switch strings.ToLower(pv) {
+				case "disable":
+					policy = AccountDeleteDisable
+				case "refuse":
+					policy = AccountDeleteRefuse
+				case "cascade":
+					policy = AccountDeleteCascade
+				default:
+					err = fmt.Errorf("delete_policy must be one of disable, refuse or cascade, got %q", v)
+				}
+				opts = append(opts, DeletePolicy(policy))
+			}
 			if err != nil {
 				*errors = append(*errors, &configErr{tk, err.Error()})
 				return
@@ -1620,6 +1801,9 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 				if hdel_set {
 					*errors = append(*errors, &configErr{tk, "CACHE does not accept hard_delete"})
 				}
+				if delPolicySet {
+					*errors = append(*errors, &configErr{tk, "CACHE does not accept delete_policy"})
+				}
 				res, err = NewCacheDirAccResolver(dir, limit, ttl, opts...)
 			case "FULL":
 				checkDir()
@@ -1629,6 +1813,9 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 				if hdel_set && !del {
 					*errors = append(*errors, &configErr{tk, "hard_delete has no effect without delete"})
 				}
+				if delPolicySet && !del {
+					*errors = append(*errors, &configErr{tk, "delete_policy has no effect without delete"})
+				}
 				delete := NoDelete
 				if del {
 					if hdel {
@@ -1696,6 +1883,37 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 				o.resolverPreloads[key] = jwtstr
 			}
 		}
+	case "pinned_mappings":
+		mp, ok := v.(map[string]any)
+		if !ok {
+			err := &configErr{tk, "pinned mappings should be a map of account_public_key:mappings"}
+			*errors = append(*errors, err)
+			return
+		}
+		o.PinnedMappings = make(map[string]map[string][]*MapDest)
+		for key, mv := range mp {
+			tk, _ := unwrapValue(mv, &lt)
+			if !nkeys.IsValidPublicAccountKey(key) {
+				err := &configErr{tk, fmt.Sprintf("Not a valid public nkey for an account: %q", key)}
+				*errors = append(*errors, err)
+				continue
+			}
+			// The mappings are only applied once the account is resolved,
+			// so validate them against a scratch account.
+			acc, pm := NewAccount(key), make(map[string][]*MapDest)
+			add := func(src string, dests ...*MapDest) error {
+				if err := acc.AddWeightedMappings(src, dests...); err != nil {
+					return err
+				}
+				pm[src] = dests
+				return nil
+			}
+			if err := parseMappings(tk, add, errors); err != nil {
+				*errors = append(*errors, err)
+				continue
+			}
+			o.PinnedMappings[key] = pm
+		}
 	case "resolver_pinned_accounts":
 		switch v := v.(type) {
 		case string:
@@ -1777,6 +1995,16 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 			*errors = append(*errors, err)
 			return
 		}
+	case "access_log":
+		if err := parseAccessLog(tk, o, errors); err != nil {
+			*errors = append(*errors, err)
+			return
+		}
+	case "connect_blocklist":
+		if err := parseConnectBlocklist(tk, o, errors); err != nil {
+			*errors = append(*errors, err)
+			return
+		}
 	case "server_tags":
 		var err error
 		switch v := v.(type) {
@@ -1960,6 +2188,21 @@ func parseWriteDeadlinePolicy(tk token, v string, errors *[]error) WriteTimeoutP
 	}
 }
 
+func parseGatewayUnknownAccountPolicy(tk token, v any, errors *[]error) GatewayUnknownAccountPolicy {
+	switch p, _ := v.(string); strings.ToLower(p) {
+	case "drop":
+		return GatewayUnknownAccountDrop
+	case "log", "advise":
+		return GatewayUnknownAccountLog
+	case "reject":
+		return GatewayUnknownAccountReject
+	default:
+		err := &configErr{tk, "unknown_account must be 'drop', 'log' or 'reject'"}
+		*errors = append(*errors, err)
+		return GatewayUnknownAccountDrop
+	}
+}
+
 func trackExplicitVal(pm *map[string]bool, name string, val bool) {
 	m := *pm
 	if m == nil {
@@ -2138,6 +2381,10 @@ func parseCluster(v any, opts *Options, errors *[]error, warnings *[]error) erro
 			opts.Cluster.WriteDeadline = parseDuration("write_deadline", tk, mv, errors, warnings)
 		case "write_timeout":
 			opts.Cluster.WriteTimeout = parseWriteDeadlinePolicy(tk, mv.(string), errors)
+		case "pending_soft_limit":
+			opts.Cluster.PendingSoftLimit = mv.(int64)
+		case "pending_hard_limit":
+			opts.Cluster.PendingHardLimit = mv.(int64)
 		default:
 			if !tk.IsUsedVariable() {
 				err := &unknownConfigFieldErr{
@@ -2330,6 +2577,27 @@ func parseGateway(v any, o *Options, errors *[]error, warnings *[]error) error {
 			o.Gateway.WriteDeadline = parseDuration("write_deadline", tk, mv, errors, warnings)
 		case "write_timeout":
 			o.Gateway.WriteTimeout = parseWriteDeadlinePolicy(tk, mv.(string), errors)
+		case "unknown_account":
+			o.Gateway.UnknownAccount = parseGatewayUnknownAccountPolicy(tk, mv, errors)
+		case "permissions":
+			perms, err := parseUserPermissions(mv, errors)
+			if err != nil {
+				*errors = append(*errors, err)
+				continue
+			}
+			// Dynamic response permissions do not make sense here.
+			if perms.Response != nil {
+				err := &configErr{tk, "Gateway permissions do not support dynamic responses"}
+				*errors = append(*errors, err)
+				continue
+			}
+			// Import is what the gateways accept from remote clusters and export
+			// what they send to remote clusters, the parsing sets them into
+			// Publish and Subscribe respectively.
+			o.Gateway.Permissions = &RoutePermissions{
+				Import: perms.Publish,
+				Export: perms.Subscribe,
+			}
 		default:
 			if !tk.IsUsedVariable() {
 				err := &unknownConfigFieldErr{
@@ -2346,7 +2614,7 @@ func parseGateway(v any, o *Options, errors *[]error, warnings *[]error) error {
 	return nil
 }
 
-var dynamicJSAccountLimits = JetStreamAccountLimits{-1, -1, -1, -1, -1, -1, -1, false}
+var dynamicJSAccountLimits = JetStreamAccountLimits{-1, -1, -1, -1, -1, -1, -1, false, -1, false}
 var defaultJSAccountTiers = map[string]JetStreamAccountLimits{_EMPTY_: dynamicJSAccountLimits}
 
 // Parses jetstream account limits for an account. Simple setup with boolen is allowed, and we will
@@ -2372,7 +2640,7 @@ func parseJetStreamForAccount(v any, acc *Account, errors *[]error) error {
 			return &configErr{tk, fmt.Sprintf("Expected 'enabled' or 'disabled' for string value, got '%s'", vv)}
 		}
 	case map[string]any:
-		jsLimits := JetStreamAccountLimits{-1, -1, -1, -1, -1, -1, -1, false}
+		jsLimits := JetStreamAccountLimits{-1, -1, -1, -1, -1, -1, -1, false, -1, false}
 		for mk, mv := range vv {
 			tk, mv = unwrapValue(mv, &lt)
 			switch strings.ToLower(mk) {
@@ -2400,12 +2668,24 @@ func parseJetStreamForAccount(v any, acc *Account, errors *[]error) error {
 					return &configErr{tk, fmt.Sprintf("Expected a parseable size for %q, got %v", mk, mv)}
 				}
 				jsLimits.MaxConsumers = int(vv)
+			case "max_total_consumers", "total_consumers":
+				vv, ok := mv.(int64)
+				if !ok {
+					return &configErr{tk, fmt.Sprintf("Expected a parseable size for %q, got %v", mk, mv)}
+				}
+				jsLimits.MaxTotalConsumers = int(vv)
 			case "max_bytes_required", "max_stream_bytes", "max_bytes":
 				vv, ok := mv.(bool)
 				if !ok {
 					return &configErr{tk, fmt.Sprintf("Expected a parseable bool for %q, got %v", mk, mv)}
 				}
 				jsLimits.MaxBytesRequired = vv
+			case "discard_old", "evict_oldest":
+				vv, ok := mv.(bool)
+				if !ok {
+					return &configErr{tk, fmt.Sprintf("Expected a parseable bool for %q, got %v", mk, mv)}
+				}
+				jsLimits.DiscardOld = vv
 			case "mem_max_stream_bytes", "memory_max_stream_bytes":
 				vv, ok := mv.(int64)
 				if !ok {
@@ -2424,6 +2704,16 @@ func parseJetStreamForAccount(v any, acc *Account, errors *[]error) error {
 					return &configErr{tk, fmt.Sprintf("Expected a parseable size for %q, got %v", mk, mv)}
 				}
 				jsLimits.MaxAckPending = int(vv)
+			case "min_stream_replicas", "stream_replicas":
+				vv, ok := mv.(int64)
+				if !ok || vv < 1 || vv > StreamMaxReplicas {
+					return &configErr{tk, fmt.Sprintf("Expected replicas between 1 and %d for %q, got %v", StreamMaxReplicas, mk, mv)}
+				}
+				if strings.ToLower(mk) == "stream_replicas" {
+					acc.jsRepl = int(vv)
+				} else {
+					acc.jsMinRepl = int(vv)
+				}
 			case "cluster_traffic":
 				vv, ok := mv.(string)
 				if !ok {
@@ -2576,6 +2866,111 @@ func parseJetStreamLimitsBatch(v any, opts *Options, errors *[]error) error {
 	return nil
 }
 
+// apply sets the tuning options on the config of a stream or consumer Raft group.
+func (o *JSRaftOpts) apply(cfg *RaftConfig) {
+	cfg.MaxAppendEntryDecodeFailures = o.MaxAppendEntryDecodeFailures
+	cfg.SlowReplicaTimeout = o.SlowReplicaTimeout
+	cfg.SlowReplicaThreshold = o.SlowReplicaThreshold
+	cfg.SlowReplicaAction = o.SlowReplicaAction
+	cfg.RecoveryTimeObjective = o.RecoveryTimeObjective
+	cfg.MaxWALBytes = o.MaxLogSize
+	cfg.ApplyErrorRetries = o.ApplyErrorRetries
+	cfg.ApplyErrorBackoff = o.ApplyErrorBackoff
+	cfg.ApplyErrorPolicy = o.ApplyErrorPolicy
+}
+
+// Parse the tuning options for JetStream Raft groups.
+func parseJetStreamRaft(v any, opts *Options, errors *[]error, warnings *[]error) error {
+	var lt token
+	tk, v := unwrapValue(v, &lt)
+
+	opts.JetStreamRaft = JSRaftOpts{}
+
+	vv, ok := v.(map[string]any)
+	if !ok {
+		return &configErr{tk, fmt.Sprintf("Expected a map to define JetStream Raft options, got %T", v)}
+	}
+	for mk, mv := range vv {
+		tk, mv = unwrapValue(mv, &lt)
+		switch strings.ToLower(mk) {
+		case "max_append_entry_decode_failures":
+			n, ok := mv.(int64)
+			if !ok || n < 0 {
+				return &configErr{tk, fmt.Sprintf("Expected a non-negative number for %q, got %v", mk, mv)}
+			}
+			opts.JetStreamRaft.MaxAppendEntryDecodeFailures = int(n)
+		case "slow_replica_timeout":
+			d := parseDuration(mk, tk, mv, errors, warnings)
+			if d < 0 {
+				return &configErr{tk, fmt.Sprintf("Expected a non-negative duration for %q, got %v", mk, mv)}
+			}
+			opts.JetStreamRaft.SlowReplicaTimeout = d
+		case "slow_replica_threshold":
+			n, ok := mv.(int64)
+			if !ok || n < 0 {
+				return &configErr{tk, fmt.Sprintf("Expected a non-negative number for %q, got %v", mk, mv)}
+			}
+			opts.JetStreamRaft.SlowReplicaThreshold = int(n)
+		case "slow_replica_action":
+			action, _ := mv.(string)
+			switch strings.ToLower(action) {
+			case "alert":
+				opts.JetStreamRaft.SlowReplicaAction = SlowReplicaAlert
+			case "demote":
+				opts.JetStreamRaft.SlowReplicaAction = SlowReplicaDemote
+			default:
+				return &configErr{tk, fmt.Sprintf("Expected \"alert\" or \"demote\" for %q, got %v", mk, mv)}
+			}
+		case "recovery_time_objective":
+			d := parseDuration(mk, tk, mv, errors, warnings)
+			if d < 0 {
+				return &configErr{tk, fmt.Sprintf("Expected a non-negative duration for %q, got %v", mk, mv)}
+			}
+			opts.JetStreamRaft.RecoveryTimeObjective = d
+		case "max_log_size":
+			sz, err := getStorageSize(mv)
+			if err != nil || sz < 0 {
+				return &configErr{tk, fmt.Sprintf("Expected an absolute size for %q, got %v", mk, mv)}
+			}
+			opts.JetStreamRaft.MaxLogSize = uint64(sz)
+		case "apply_error_retries":
+			n, ok := mv.(int64)
+			if !ok || n < 0 {
+				return &configErr{tk, fmt.Sprintf("Expected a non-negative number for %q, got %v", mk, mv)}
+			}
+			opts.JetStreamRaft.ApplyErrorRetries = int(n)
+		case "apply_error_backoff":
+			d := parseDuration(mk, tk, mv, errors, warnings)
+			if d < 0 {
+				return &configErr{tk, fmt.Sprintf("Expected a non-negative duration for %q, got %v", mk, mv)}
+			}
+			opts.JetStreamRaft.ApplyErrorBackoff = d
+		case "apply_error_policy":
+			policy, _ := mv.(string)
+			switch strings.ToLower(policy) {
+			case "halt":
+				opts.JetStreamRaft.ApplyErrorPolicy = ApplyErrorHalt
+			case "skip":
+				opts.JetStreamRaft.ApplyErrorPolicy = ApplyErrorSkip
+			default:
+				return &configErr{tk, fmt.Sprintf("Expected \"halt\" or \"skip\" for %q, got %v", mk, mv)}
+			}
+		default:
+			if !tk.IsUsedVariable() {
+				err := &unknownConfigFieldErr{
+					field: mk,
+					configErr: configErr{
+						token: tk,
+					},
+				}
+				*errors = append(*errors, err)
+				continue
+			}
+		}
+	}
+	return nil
+}
+
 // Parse the JetStream TPM options.
 func parseJetStreamTPM(v interface{}, opts *Options, errors *[]error) error {
 	var lt token
@@ -2713,6 +3108,10 @@ func parseJetStream(v any, opts *Options, errors *[]error, warnings *[]error) er
 				if err := parseJetStreamTPM(tk, opts, errors); err != nil {
 					return err
 				}
+			case "raft":
+				if err := parseJetStreamRaft(tk, opts, errors, warnings); err != nil {
+					return err
+				}
 			case "unique_tag":
 				opts.JetStreamUniqueTag = strings.ToLower(strings.TrimSpace(mv.(string)))
 			case "max_outstanding_catchup":
@@ -2721,6 +3120,48 @@ func parseJetStream(v any, opts *Options, errors *[]error, warnings *[]error) er
 					return &configErr{tk, fmt.Sprintf("%s %s", strings.ToLower(mk), err)}
 				}
 				opts.JetStreamMaxCatchup = s
+			case "max_concurrent_catchups":
+				n, ok := mv.(int64)
+				if !ok {
+					return &configErr{tk, fmt.Sprintf("Expected a parseable number for %q, got %v", mk, mv)}
+				}
+				opts.JetStreamMaxConcurrentCatchups = int(n)
+			case "max_concurrent_recoveries":
+				n, ok := mv.(int64)
+				if !ok || n < 0 {
+					return &configErr{tk, fmt.Sprintf("Expected a non-negative number for %q, got %v", mk, mv)}
+				}
+				opts.JetStreamMaxRecoveries = int(n)
+			case "max_concurrent_snapshots":
+				n, ok := mv.(int64)
+				if !ok || n < 0 {
+					return &configErr{tk, fmt.Sprintf("Expected a non-negative number for %q, got %v", mk, mv)}
+				}
+				opts.JetStreamMaxSnapshots = int(n)
+			case "max_apply_backlog":
+				n, ok := mv.(int64)
+				if !ok || n < 0 {
+					return &configErr{tk, fmt.Sprintf("Expected a non-negative number for %q, got %v", mk, mv)}
+				}
+				opts.JetStreamMaxApplyBacklog = n
+			case "auto_balance_interval":
+				d := parseDuration(mk, tk, mv, errors, warnings)
+				if d < 0 {
+					return &configErr{tk, fmt.Sprintf("Expected a non-negative duration for %q, got %v", mk, mv)}
+				}
+				opts.JetStreamBalanceInterval = d
+			case "orphan_check_interval":
+				d := parseDuration(mk, tk, mv, errors, warnings)
+				if d < 0 {
+					return &configErr{tk, fmt.Sprintf("Expected a non-negative duration for %q, got %v", mk, mv)}
+				}
+				opts.JetStreamOrphanInterval = d
+			case "auto_balance_threshold":
+				n, ok := mv.(int64)
+				if !ok || n < 0 {
+					return &configErr{tk, fmt.Sprintf("Expected a non-negative number for %q, got %v", mk, mv)}
+				}
+				opts.JetStreamBalanceThreshold = int(n)
 			case "max_buffered_size":
 				s, err := getStorageSize(mv)
 				if err != nil {
@@ -2762,6 +3203,27 @@ func parseJetStream(v any, opts *Options, errors *[]error, warnings *[]error) er
 				opts.JetStreamMetaCompactSize = uint64(s)
 			case "meta_compact_sync":
 				opts.JetStreamMetaCompactSync = mv.(bool)
+			case "consumer_hibernate_after":
+				d := parseDuration(mk, tk, mv, errors, warnings)
+				if d < 0 {
+					return &configErr{tk, fmt.Sprintf("Expected a non-negative duration for %q, got %v", mk, mv)}
+				}
+				opts.JetStreamConsumerHibernate = d
+			case "tombstone_max_age":
+				d := parseDuration(mk, tk, mv, errors, warnings)
+				if d < 0 {
+					return &configErr{tk, fmt.Sprintf("Expected a non-negative duration for %q, got %v", mk, mv)}
+				}
+				opts.JetStreamTombstoneMaxAge = d
+			case "io_error_policy":
+				switch strings.ToLower(mv.(string)) {
+				case "stepdown", "step_down":
+					opts.JetStreamIOErrorPolicy = IOErrorStepDown
+				case "read_only", "readonly":
+					opts.JetStreamIOErrorPolicy = IOErrorReadOnly
+				default:
+					return &configErr{tk, fmt.Sprintf("Unknown I/O error policy: %q", mv)}
+				}
 			default:
 				if !tk.IsUsedVariable() {
 					err := &unknownConfigFieldErr{
@@ -2880,6 +3342,24 @@ func parseLeafNodes(v any, opts *Options, errors *[]error, warnings *[]error) er
 			opts.LeafNode.WriteDeadline = parseDuration("write_deadline", tk, mv, errors, warnings)
 		case "write_timeout":
 			opts.LeafNode.WriteTimeout = parseWriteDeadlinePolicy(tk, mv.(string), errors)
+		case "max_remotes_per_account":
+			max := int(mv.(int64))
+			if max < 0 {
+				err := &configErr{tk, fmt.Sprintf("invalid max_remotes_per_account of %d, needs to be positive", max)}
+				*errors = append(*errors, err)
+				continue
+			}
+			opts.LeafNode.MaxRemotesPerAccount = max
+		case "loop_detection":
+			opts.LeafNode.LoopDetection = mv.(bool)
+		case "loop_detection_max_path":
+			max := int(mv.(int64))
+			if max < 2 {
+				err := &configErr{tk, fmt.Sprintf("invalid loop_detection_max_path of %d, needs to be at least 2", max)}
+				*errors = append(*errors, err)
+				continue
+			}
+			opts.LeafNode.LoopDetectionMaxPath = max
 		default:
 			if !tk.IsUsedVariable() {
 				err := &unknownConfigFieldErr{
@@ -3369,11 +3849,12 @@ type importStream struct {
 }
 
 type importService struct {
-	acc   *Account
-	an    string
-	sub   string
-	to    string
-	share bool
+	acc     *Account
+	an      string
+	sub     string
+	to      string
+	share   bool
+	timeout time.Duration
 }
 
 // Checks if an account name is reserved.
@@ -3451,6 +3932,11 @@ func parseAccountMapDest(v any, tk token, errors *[]error) (*MapDest, *configErr
 
 // parseAccountMappings is called to parse account mappings.
 func parseAccountMappings(v any, acc *Account, errors *[]error) error {
+	return parseMappings(v, acc.AddWeightedMappings, errors)
+}
+
+// parseMappings parses subject mappings and hands each of them to add.
+func parseMappings(v any, add func(src string, dests ...*MapDest) error, errors *[]error) error {
 	var lt token
 	defer convertPanicToErrorList(&lt, errors)
 
@@ -3466,7 +3952,7 @@ func parseAccountMappings(v any, acc *Account, errors *[]error) error {
 
 		switch vv := v.(type) {
 		case string:
-			if err := acc.AddMapping(subj, v.(string)); err != nil {
+			if err := add(subj, NewMapDest(v.(string), 100)); err != nil {
 				err := &configErr{tk, fmt.Sprintf("Error adding mapping for %q to %q : %v", subj, v.(string), err)}
 				*errors = append(*errors, err)
 				continue
@@ -3483,7 +3969,7 @@ func parseAccountMappings(v any, acc *Account, errors *[]error) error {
 			}
 
 			// Now add them in..
-			if err := acc.AddWeightedMappings(subj, mappings...); err != nil {
+			if err := add(subj, mappings...); err != nil {
 				err := &configErr{tk, fmt.Sprintf("Error adding mapping for %q : %v", subj, err)}
 				*errors = append(*errors, err)
 				continue
@@ -3495,7 +3981,7 @@ func parseAccountMappings(v any, acc *Account, errors *[]error) error {
 				continue
 			}
 			// Now add it in..
-			if err := acc.AddWeightedMappings(subj, mdest); err != nil {
+			if err := add(subj, mdest); err != nil {
 				err := &configErr{tk, fmt.Sprintf("Error adding mapping for %q : %v", subj, err)}
 				*errors = append(*errors, err)
 				continue
@@ -3510,6 +3996,76 @@ func parseAccountMappings(v any, acc *Account, errors *[]error) error {
 	return nil
 }
 
+// parseAccountSchemas is called to parse the schemas used to validate
+// message payloads published to an account.
+func parseAccountSchemas(v any, acc *Account, errors *[]error) error {
+	var lt token
+	defer convertPanicToErrorList(&lt, errors)
+
+	tk, v := unwrapValue(v, &lt)
+	sl, ok := v.([]any)
+	if !ok {
+		return &configErr{tk, fmt.Sprintf("Expected account schemas to be an array, got %T", v)}
+	}
+	for _, sv := range sl {
+		tk, sv := unwrapValue(sv, &lt)
+		sm, ok := sv.(map[string]any)
+		if !ok {
+			*errors = append(*errors, &configErr{tk, fmt.Sprintf("Expected schema entry to be a map/struct, got %T", sv)})
+			continue
+		}
+		var (
+			subject, dlq string
+			schema       []byte
+			action       = SchemaReject
+			failed       bool
+		)
+		for k, v := range sm {
+			tk, v := unwrapValue(v, &lt)
+			switch strings.ToLower(k) {
+			case "subject":
+				subject = v.(string)
+			case "schema":
+				schema = []byte(v.(string))
+			case "schema_file":
+				b, err := os.ReadFile(v.(string))
+				if err != nil {
+					*errors = append(*errors, &configErr{tk, fmt.Sprintf("Error reading schema file: %v", err)})
+					failed = true
+					continue
+				}
+				schema = b
+			case "action", "on_failure":
+				a, err := parseSchemaAction(v.(string))
+				if err != nil {
+					*errors = append(*errors, &configErr{tk, err.Error()})
+					failed = true
+					continue
+				}
+				action = a
+			case "dlq", "dlq_subject":
+				dlq = v.(string)
+			default:
+				if !tk.IsUsedVariable() {
+					*errors = append(*errors, &configErr{tk, fmt.Sprintf("Unknown field %q parsing account schema", k)})
+					failed = true
+				}
+			}
+		}
+		if failed {
+			continue
+		}
+		if subject == _EMPTY_ || len(schema) == 0 {
+			*errors = append(*errors, &configErr{tk, "Account schema requires a subject and a schema"})
+			continue
+		}
+		if err := acc.addSchema(subject, schema, action, dlq); err != nil {
+			*errors = append(*errors, &configErr{tk, fmt.Sprintf("Error adding schema for %q: %v", subject, err)})
+		}
+	}
+	return nil
+}
+
 // parseAccountLimits is called to parse account limits in a server config.
 func parseAccountLimits(mv any, acc *Account, errors *[]error) error {
 	var lt token
@@ -3532,6 +4088,40 @@ func parseAccountLimits(mv any, acc *Account, errors *[]error) error {
 			acc.mpay = int32(mv.(int64))
 		case "max_leafnodes", "max_leafs":
 			acc.mleafs = int32(mv.(int64))
+		case "max_header_size", "max_hdr":
+			size := mv.(int64)
+			if size < 0 {
+				err := &configErr{tk, fmt.Sprintf("Invalid max_header_size %d, can not be negative", size)}
+				*errors = append(*errors, err)
+				continue
+			}
+			acc.mhdr = int32(size)
+		case "max_header_value_size", "max_hdr_value":
+			size := mv.(int64)
+			if size < 0 {
+				err := &configErr{tk, fmt.Sprintf("Invalid max_header_value_size %d, can not be negative", size)}
+				*errors = append(*errors, err)
+				continue
+			}
+			acc.mhdrval = int32(size)
+		case "max_advisory_rate", "max_advisories":
+			rate := mv.(int64)
+			if rate < 0 {
+				err := &configErr{tk, fmt.Sprintf("Invalid max_advisory_rate %d, can not be negative", rate)}
+				*errors = append(*errors, err)
+				continue
+			}
+			acc.madvr = int32(rate)
+		case "no_wildcard_subs":
+			acc.noWildcardSubs = mv.(bool)
+		case "min_wildcard_depth":
+			depth := mv.(int64)
+			if depth < 0 {
+				err := &configErr{tk, fmt.Sprintf("Invalid min_wildcard_depth %d, can not be negative", depth)}
+				*errors = append(*errors, err)
+				continue
+			}
+			acc.minWildcardDepth = int(depth)
 		default:
 			if !tk.IsUsedVariable() {
 				err := &configErr{tk, fmt.Sprintf("Unknown field %q parsing account limits", k)}
@@ -3543,6 +4133,83 @@ func parseAccountLimits(mv any, acc *Account, errors *[]error) error {
 	return nil
 }
 
+// parseAccountDeliveryAcks parses the delivery acks of an account, either a boolean
+// or a map with the maximum number of outstanding deliveries tracked per subscription.
+// Returns that maximum, or 0 if disabled.
+func parseAccountDeliveryAcks(tk token, mv any) (int64, error) {
+	switch v := mv.(type) {
+	case bool:
+		if v {
+			return DefaultDeliveryAcksMaxOutstanding, nil
+		}
+		return 0, nil
+	case map[string]any:
+		max := int64(DefaultDeliveryAcksMaxOutstanding)
+		for k, v := range v {
+			tk, mv := unwrapValue(v, nil)
+			switch strings.ToLower(k) {
+			case "max_outstanding":
+				n, ok := mv.(int64)
+				if !ok || n <= 0 {
+					return 0, &configErr{tk, fmt.Sprintf("Expected a positive number for %q, got %v", k, mv)}
+				}
+				max = n
+			default:
+				if !tk.IsUsedVariable() {
+					return 0, &configErr{tk, fmt.Sprintf("Unknown field %q parsing delivery acks", k)}
+				}
+			}
+		}
+		return max, nil
+	default:
+		return 0, &configErr{tk, fmt.Sprintf("Expected delivery_acks to be a boolean or a map, got %T", mv)}
+	}
+}
+
+// parseAccountSystemSubjects parses the account's policy for reserved subject
+// families, which are given as a single token starting with "$", e.g. "$SYS".
+func parseAccountSystemSubjects(mv any, acc *Account, errors *[]error) error {
+	var lt token
+	defer convertPanicToErrorList(&lt, errors)
+
+	tk, v := unwrapValue(mv, &lt)
+	am, ok := v.(map[string]any)
+	if !ok {
+		return &configErr{tk, fmt.Sprintf("Expected system subjects to be a map/struct, got %+v", v)}
+	}
+
+	for k, v := range am {
+		tk, mv = unwrapValue(v, &lt)
+		var families *[]string
+		switch strings.ToLower(k) {
+		case "allow":
+			families = &acc.sysSubjectsAllow
+		case "deny":
+			families = &acc.sysSubjectsDeny
+		default:
+			if !tk.IsUsedVariable() {
+				err := &configErr{tk, fmt.Sprintf("Unknown field %q parsing system subjects", k)}
+				*errors = append(*errors, err)
+			}
+			continue
+		}
+		strs, err := parseStringArray(k, tk, &lt, mv, errors)
+		if err != nil {
+			continue
+		}
+		for _, family := range strs {
+			if len(family) < 2 || family[0] != '$' || strings.ContainsAny(family, tsep+pwcs+fwcs) {
+				err := &configErr{tk, fmt.Sprintf("Invalid system subject family %q, expected a single token starting with '$'", family)}
+				*errors = append(*errors, err)
+				continue
+			}
+			*families = append(*families, family)
+		}
+	}
+
+	return nil
+}
+
 func parseAccountMsgTrace(mv any, topKey string, acc *Account) error {
 	processDest := func(tk token, k string, v any) error {
 		td, ok := v.(string)
@@ -3594,6 +4261,35 @@ func parseAccountMsgTrace(mv any, topKey string, acc *Account) error {
 				default:
 					return &configErr{tk, fmt.Sprintf("Trace destination sampling field %q should be an integer or a percentage, got %T", k, v)}
 				}
+			case "sample_every":
+				n, ok := v.(int64)
+				if !ok || n < 1 {
+					return &configErr{tk, fmt.Sprintf("Trace sample every value %v is invalid, needs to be a positive integer", v)}
+				}
+				acc.traceSampleEvery = int(n)
+			case "sample_subjects", "sample_subject":
+				var subjects []string
+				switch vv := v.(type) {
+				case string:
+					subjects = append(subjects, vv)
+				case []any:
+					for _, sv := range vv {
+						_, sv := unwrapValue(sv, &lt)
+						subj, ok := sv.(string)
+						if !ok {
+							return &configErr{tk, fmt.Sprintf("Trace sample subject should be a string, got %T", sv)}
+						}
+						subjects = append(subjects, subj)
+					}
+				default:
+					return &configErr{tk, fmt.Sprintf("Trace sample subjects field %q should be a string or an array, got %T", k, v)}
+				}
+				for _, subj := range subjects {
+					if !IsValidSubject(subj) {
+						return &configErr{tk, fmt.Sprintf("Trace sample subject %q is not valid", subj)}
+					}
+				}
+				acc.traceSampleSubjects = subjects
 			default:
 				if !tk.IsUsedVariable() {
 					return &configErr{tk, fmt.Sprintf("Unknown field %q parsing account message trace map/struct %q", k, topKey)}
@@ -3729,12 +4425,47 @@ func parseAccounts(v any, opts *Options, errors *[]error, warnings *[]error) err
 						*errors = append(*errors, err)
 						continue
 					}
+				case "schemas":
+					err := parseAccountSchemas(tk, acc, errors)
+					if err != nil {
+						*errors = append(*errors, err)
+						continue
+					}
 				case "limits":
 					err := parseAccountLimits(tk, acc, errors)
 					if err != nil {
 						*errors = append(*errors, err)
 						continue
 					}
+				case "system_subjects":
+					err := parseAccountSystemSubjects(tk, acc, errors)
+					if err != nil {
+						*errors = append(*errors, err)
+						continue
+					}
+				case "shadow_permissions":
+					shadow, ok := mv.(bool)
+					if !ok {
+						err := &configErr{tk, fmt.Sprintf("Expected shadow_permissions to be a boolean, got %T", mv)}
+						*errors = append(*errors, err)
+						continue
+					}
+					acc.shadowPerms.Store(shadow)
+				case "tls_required":
+					required, ok := mv.(bool)
+					if !ok {
+						err := &configErr{tk, fmt.Sprintf("Expected tls_required to be a boolean, got %T", mv)}
+						*errors = append(*errors, err)
+						continue
+					}
+					acc.tlsRequired.Store(required)
+				case "delivery_acks":
+					max, err := parseAccountDeliveryAcks(tk, mv)
+					if err != nil {
+						*errors = append(*errors, err)
+						continue
+					}
+					acc.deliveryAcks.Store(max)
 				case "msg_trace", "trace_dest":
 					if err := parseAccountMsgTrace(tk, k, acc); err != nil {
 						*errors = append(*errors, err)
@@ -3752,6 +4483,14 @@ func parseAccounts(v any, opts *Options, errors *[]error, warnings *[]error) err
 							&configErr{tk, "Trace destination sampling ignored since no destination was set"})
 						acc.traceDestSampling = 0
 					}
+					if acc.traceSampleEvery > 0 || len(acc.traceSampleSubjects) > 0 {
+						if acc.traceDest == _EMPTY_ {
+							*warnings = append(*warnings,
+								&configErr{tk, "Trace sample every and sample subjects ignored since no destination was set"})
+							acc.traceSampleEvery, acc.traceSampleSubjects = 0, nil
+						}
+						acc.traceSampling.Store(acc.traceDest != _EMPTY_)
+					}
 				default:
 					if !tk.IsUsedVariable() {
 						err := &unknownConfigFieldErr{
@@ -3926,6 +4665,13 @@ func parseAccounts(v any, opts *Options, errors *[]error, warnings *[]error) err
 			*errors = append(*errors, &configErr{tk, msg})
 			continue
 		}
+		if service.timeout != 0 {
+			if err := service.acc.SetServiceImportTimeout(ta, service.sub, service.timeout); err != nil {
+				msg := fmt.Sprintf("Error setting service import timeout %q: %v", service.sub, err)
+				*errors = append(*errors, &configErr{tk, msg})
+				continue
+			}
+		}
 	}
 
 	return nil
@@ -4344,6 +5090,7 @@ func parseImportStreamOrService(v any, errors *[]error) (*importStream, *importS
 		curService *importService
 		pre, to    string
 		share      bool
+		timeout    time.Duration
 		lt         token
 		atrc       bool
 		atrcSeen   bool
@@ -4427,6 +5174,7 @@ func parseImportStreamOrService(v any, errors *[]error) (*importStream, *importS
 				curService.to = subject
 			}
 			curService.share = share
+			curService.timeout = timeout
 		case "prefix":
 			pre = mv.(string)
 			if curStream != nil {
@@ -4450,6 +5198,28 @@ func parseImportStreamOrService(v any, errors *[]error) (*importStream, *importS
 			if curService != nil {
 				curService.share = share
 			}
+		case "timeout":
+			if curStream != nil {
+				err := &configErr{tk, "Detected timeout directive on a non-service"}
+				*errors = append(*errors, err)
+				continue
+			}
+			ts, ok := mv.(string)
+			if !ok {
+				err := &configErr{tk, fmt.Sprintf("Service import timeout should be a duration, got %T", mv)}
+				*errors = append(*errors, err)
+				continue
+			}
+			d, err := time.ParseDuration(ts)
+			if err != nil || d < 0 {
+				err := &configErr{tk, fmt.Sprintf("Invalid service import timeout %q", ts)}
+				*errors = append(*errors, err)
+				continue
+			}
+			timeout = d
+			if curService != nil {
+				curService.timeout = timeout
+			}
 		case "allow_trace":
 			if curService != nil {
 				err := &configErr{tk, "Detected allow_trace directive on a non-stream"}
@@ -4925,6 +5695,8 @@ func parseAllowResponses(v any, errors *[]error) *ResponsePermission {
 				*errors = append(*errors, err)
 				return nil
 			}
+		case "max_in_flight", "max_inflight":
+			rp.MaxInFlight = int(v.(int64))
 		default:
 			if !tk.IsUsedVariable() {
 				err := &configErr{tk, fmt.Sprintf("Unknown field %q parsing permissions", k)}
@@ -5436,6 +6208,95 @@ func parseStringArray(fieldName string, tk token, lt *token, mv any, errors *[]e
 	}
 }
 
+func parseAccessLog(v any, o *Options, errors *[]error) error {
+	var lt token
+	defer convertPanicToErrorList(&lt, errors)
+
+	tk, v := unwrapValue(v, &lt)
+	am, ok := v.(map[string]any)
+	if !ok {
+		return &configErr{tk, fmt.Sprintf("Expected access_log to be a map, got %T", v)}
+	}
+	for mk, mv := range am {
+		tk, mv = unwrapValue(mv, &lt)
+		switch strings.ToLower(mk) {
+		case "subjects", "subject":
+			subjects, err := parseStringArray("access_log subjects", tk, &lt, mv, errors)
+			if err != nil {
+				continue
+			}
+			for _, subj := range subjects {
+				if !IsValidSubject(subj) {
+					*errors = append(*errors, &configErr{tk, fmt.Sprintf("invalid access_log subject %q", subj)})
+				}
+			}
+			o.AccessLog.Subjects = subjects
+		case "file":
+			o.AccessLog.File = mv.(string)
+		case "max_rate":
+			o.AccessLog.MaxRate = int(mv.(int64))
+			if o.AccessLog.MaxRate < 0 {
+				*errors = append(*errors, &configErr{tk, "access_log max_rate can not be negative"})
+			}
+		default:
+			if !tk.IsUsedVariable() {
+				err := &unknownConfigFieldErr{
+					field: mk,
+					configErr: configErr{
+						token: tk,
+					},
+				}
+				*errors = append(*errors, err)
+			}
+		}
+	}
+	return nil
+}
+
+func parseConnectBlocklist(v any, o *Options, errors *[]error) error {
+	var lt token
+	defer convertPanicToErrorList(&lt, errors)
+
+	tk, v := unwrapValue(v, &lt)
+	bm, ok := v.(map[string]any)
+	if !ok {
+		return &configErr{tk, fmt.Sprintf("Expected connect_blocklist to be a map, got %T", v)}
+	}
+	for mk, mv := range bm {
+		tk, mv = unwrapValue(mv, &lt)
+		switch strings.ToLower(mk) {
+		case "names", "name":
+			names, err := parseStringArray("connect_blocklist names", tk, &lt, mv, errors)
+			if err != nil {
+				continue
+			}
+			o.ConnectBlocklist.Names = names
+		case "nkeys", "nkey", "users":
+			keys, err := parseStringArray("connect_blocklist nkeys", tk, &lt, mv, errors)
+			if err != nil {
+				continue
+			}
+			for _, key := range keys {
+				if !nkeys.IsValidPublicUserKey(key) {
+					*errors = append(*errors, &configErr{tk, fmt.Sprintf("invalid connect_blocklist user nkey %q", key)})
+				}
+			}
+			o.ConnectBlocklist.Nkeys = keys
+		default:
+			if !tk.IsUsedVariable() {
+				err := &unknownConfigFieldErr{
+					field: mk,
+					configErr: configErr{
+						token: tk,
+					},
+				}
+				*errors = append(*errors, err)
+			}
+		}
+	}
+	return nil
+}
+
 func parseWebsocket(v any, o *Options, errors *[]error, warnings *[]error) error {
 	var lt token
 	defer convertPanicToErrorList(&lt, errors)
@@ -5951,6 +6812,9 @@ func setBaselineOptions(opts *Options) {
 	if opts.MaxConn == 0 {
 		opts.MaxConn = DEFAULT_MAX_CONNECTIONS
 	}
+	if opts.MaxConnectRate > 0 && opts.MaxConnectBacklog == 0 {
+		opts.MaxConnectBacklog = DEFAULT_MAX_CONNECT_BACKLOG
+	}
 	if opts.PingInterval == 0 {
 		opts.PingInterval = DEFAULT_PING_INTERVAL
 	}
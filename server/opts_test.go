@@ -2809,6 +2809,13 @@ func TestParsingResponsePermissions(t *testing.T) {
 	conf = createConfFile(t, []byte(fmt.Sprintf(template, "max: -1", `ttl: "-1s"`)))
 	check(t, conf, "", -1, -1*time.Second)
 
+	// Check max in-flight
+	conf = createConfFile(t, []byte(fmt.Sprintf(template, "max_in_flight: 5", "")))
+	check(t, conf, "", DEFAULT_ALLOW_RESPONSE_MAX_MSGS, DEFAULT_ALLOW_RESPONSE_EXPIRATION)
+	opts, err := ProcessConfigFile(conf)
+	require_NoError(t, err)
+	require_Equal(t, opts.Users[0].Permissions.Response.MaxInFlight, 5)
+
 	// Check parsing errors
 	conf = createConfFile(t, []byte(fmt.Sprintf(template, "unknown_field: 123", "")))
 	check(t, conf, "Unknown field", 0, 0)
@@ -3299,6 +3306,62 @@ func TestMaxSubTokens(t *testing.T) {
 	}
 }
 
+func TestMaxSubSubjectLen(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		listen: 127.0.0.1:-1
+		max_sub_subject_len: 16
+	`))
+
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+	require_Equal(t, s.getOpts().MaxSubSubjectLen, 16)
+
+	nc, err := nats.Connect(s.ClientURL())
+	require_NoError(t, err)
+	defer nc.Close()
+
+	errs := make(chan error, 1)
+
+	nc.SetErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+		errs <- err
+	})
+
+	// Right at the limit is fine.
+	ok := strings.Repeat("a", 16)
+	sub, err := nc.SubscribeSync(ok)
+	require_NoError(t, err)
+	require_NoError(t, nc.Flush())
+
+	// Publishing is not subject to this limit.
+	long := strings.Repeat("a", 17)
+	require_NoError(t, nc.Publish(long, []byte("ok")))
+	require_NoError(t, nc.Publish(ok, []byte("ok")))
+	_, err = sub.NextMsg(time.Second)
+	require_NoError(t, err)
+
+	_, err = nc.SubscribeSync(long)
+	require_NoError(t, err)
+
+	select {
+	case e := <-errs:
+		if !strings.Contains(e.Error(), "exceeds limit of 16") {
+			t.Fatalf("Got wrong error: %v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Did not get the permissions error")
+	}
+
+	// The connection is still usable.
+	require_NoError(t, nc.Publish(ok, []byte("ok")))
+	_, err = sub.NextMsg(time.Second)
+	require_NoError(t, err)
+
+	conf = createConfFile(t, []byte(`max_sub_subject_len: 0`))
+	_, err = ProcessConfigFile(conf)
+	require_Error(t, err)
+	require_Contains(t, err.Error(), "must be positive")
+}
+
 func TestGetStorageSize(t *testing.T) {
 	tt := []struct {
 		input string
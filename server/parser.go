@@ -51,7 +51,9 @@ type pubArg struct {
 	hdr       int
 	psi       []*serviceImport
 	trace     *msgTrace
-	delivered bool // Only used for service imports
+	leafPath  []byte // Leafnode path taken from the header of a message from a leafnode or route
+	delivered bool   // Only used for service imports
+	sampled   bool   // Selected for tracing by the account's sampling
 }
 
 // Parser constants
@@ -498,13 +500,21 @@ func (c *client) parse(buf []byte) error {
 				c.msgBuf = buf[c.as : i+1]
 			}
 
+			// Check if the account selects this message for tracing.
+			if c.kind == CLIENT && c.in.flags.isSet(hasTraceSampling) {
+				c.pa.sampled = c.sampleMsgTrace()
+			}
 			var mt *msgTrace
 			if c.pa.hdr > 0 {
 				mt = c.initMsgTrace()
 			}
 			// Check for mappings.
 			if (c.kind == CLIENT || c.kind == LEAF) && c.in.flags.isSet(hasMappings) {
-				changed := c.selectMappedSubject()
+				var hdr []byte
+				if c.pa.hdr > 0 {
+					hdr = c.msgBuf[:c.pa.hdr]
+				}
+				changed := c.selectMappedSubjectWithHeader(hdr)
 				if changed {
 					if trace {
 						c.traceInOp("MAPPING", []byte(fmt.Sprintf("%s -> %s", c.pa.mapped, c.pa.subject)))
@@ -527,6 +537,7 @@ func (c *client) parse(buf []byte) error {
 			c.pa.reply, c.pa.hdr, c.pa.size, c.pa.szb, c.pa.hdb, c.pa.queues = nil, -1, 0, nil, nil, nil
 			c.pa.trace = nil
 			c.pa.delivered = false
+			c.pa.sampled = false
 			lmsg = false
 		case OP_A:
 			switch b {
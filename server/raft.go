@@ -14,17 +14,21 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"iter"
 	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -39,21 +43,31 @@ import (
 type RaftNode interface {
 	Propose(entry []byte) error
 	ProposeMulti(entries []*Entry) error
+	ProposeNoop() error
 	ForwardProposal(entry []byte) error
 	InstallSnapshot(snap []byte, force bool) error
 	CreateSnapshotCheckpoint(force bool) (RaftNodeCheckpoint, error)
+	Compact() error
 	SendSnapshot(snap []byte) error
 	NeedSnapshot() bool
+	ExceedsRecoveryTime() bool
+	ExceedsMaxWAL() bool
+	PinApplyWorker() (unpin func())
+	ConfigHistory() []ConfigChange
 	Applied(index uint64) (entries uint64, bytes uint64)
 	Processed(index uint64, applied uint64) (entries uint64, bytes uint64)
 	State() RaftState
 	Size() (entries, bytes uint64)
 	Progress() (index, commit, applied uint64)
+	MatchIndexes() (map[string]uint64, error)
+	Stats() RaftStats
 	Leader() bool
 	LeaderSince() *time.Time
 	Quorum() bool
 	Current() bool
 	Healthy() bool
+	Stable() bool
+	PendingEntries() []EntryInfo
 	Term() uint64
 	Leaderless() bool
 	GroupLeader() string
@@ -70,6 +84,7 @@ type RaftNode interface {
 	UpdateKnownPeers(knownPeers []string)
 	ProposeAddPeer(peer string) error
 	ProposeRemovePeer(peer string) error
+	ResyncPeer(id string) error
 	MembershipChangeInProgress() bool
 	AdjustClusterSize(csz int) error
 	AdjustBootClusterSize(csz int) error
@@ -77,6 +92,9 @@ type RaftNode interface {
 	ApplyQ() *ipQueue[*CommittedEntry]
 	PauseApply() error
 	ResumeApply()
+	ApplyFailed(index uint64, err error) (time.Duration, error)
+	TrackSnapshotCatchup(received, total uint64)
+	Quiesce() (resume func(), index uint64)
 	DrainAndReplaySnapshot() bool
 	LeadChangeC() <-chan bool
 	QuitC() <-chan struct{}
@@ -90,6 +108,8 @@ type RaftNode interface {
 	IsSystemAccount() bool
 	GetTrafficAccountName() string
 	GetWriteErr() error
+	ExportState(w io.Writer) error
+	MigrateLog(st StorageType) error
 }
 
 // RaftNodeCheckpoint is used as an alternative to a direct InstallSnapshot.
@@ -118,11 +138,153 @@ type WAL interface {
 	Delete(inline bool) error
 }
 
+// walSyncer is implemented by logs that can sync stored entries to disk on demand.
+// Logs that don't implement it are considered durable as soon as an entry is stored.
+type walSyncer interface {
+	Sync() error
+}
+
+// RaftStats holds runtime statistics for a Raft node.
+type RaftStats struct {
+	// ApplyQueueDepth is the number of committed entries that are queued
+	// up but not yet taken by the upper layer to be applied.
+	ApplyQueueDepth int `json:"apply_queue_depth"`
+	// CommitLatency is the distribution of the time between a proposal being
+	// submitted and it being committed, recorded while this node was leader.
+	// It does not include the time it takes the upper layer to apply it.
+	CommitLatency LatencyHistogram `json:"commit_latency"`
+	// ApplyTime is the moving average of the time it takes the upper
+	// layer to apply a single committed entry.
+	ApplyTime time.Duration `json:"apply_time"`
+	// ReplayTime is the estimated time it takes to replay the log since
+	// the last snapshot when restarting, based on the ApplyTime.
+	ReplayTime time.Duration `json:"replay_time"`
+	// ElectionsStarted is the number of elections this node started as a candidate.
+	ElectionsStarted uint64 `json:"elections_started"`
+	// ElectionsWon is the number of elections this node won and became leader.
+	ElectionsWon uint64 `json:"elections_won"`
+	// SplitVotes is the number of elections this node started that timed out
+	// without a winner, after which it started a new election.
+	SplitVotes uint64 `json:"split_votes"`
+	// VoteRequestsSent is the number of vote requests this node sent out.
+	VoteRequestsSent uint64 `json:"vote_requests_sent"`
+	// VoteRequestsReceived is the number of vote requests this node received.
+	VoteRequestsReceived uint64 `json:"vote_requests_received"`
+	// SnapshotInstall is the progress of the last snapshot received from
+	// the leader to catch up, if any was received since the node started.
+	SnapshotInstall *SnapshotInstallProgress `json:"snapshot_install,omitempty"`
+	// PinnedWorkers is the number of goroutines of this group, the run loop
+	// and the apply worker, that are currently pinned to their own OS thread.
+	PinnedWorkers int `json:"pinned_workers,omitempty"`
+}
+
+// SnapshotInstallState is the state of a snapshot that a follower received
+// from the leader to catch up.
+type SnapshotInstallState string
+
+const (
+	// SnapshotInstallReceiving means the snapshot is being received from the leader.
+	SnapshotInstallReceiving SnapshotInstallState = "receiving"
+	// SnapshotInstallApplying means the snapshot is stored and the upper layer is applying it.
+	SnapshotInstallApplying SnapshotInstallState = "applying"
+	// SnapshotInstallComplete means the upper layer applied the snapshot.
+	SnapshotInstallComplete SnapshotInstallState = "complete"
+	// SnapshotInstallFailed means the snapshot could not be stored.
+	SnapshotInstallFailed SnapshotInstallState = "failed"
+)
+
+// SnapshotInstallProgress is the progress of a follower installing a snapshot
+// received from the leader. The leader sends the snapshot itself as a single
+// append entry of Size bytes. The data it describes is then caught up from the
+// leader by the upper layer while applying, which reports Received out of Total.
+type SnapshotInstallProgress struct {
+	State SnapshotInstallState `json:"state"`
+	// Index is the last index covered by the snapshot.
+	Index uint64 `json:"index"`
+	// Size is the size of the snapshot in bytes.
+	Size uint64 `json:"size"`
+	// Received and Total are the messages caught up from the leader so far and in all.
+	Received uint64    `json:"received"`
+	Total    uint64    `json:"total"`
+	Started  time.Time `json:"started"`
+	Updated  time.Time `json:"updated"`
+}
+
+// LatencyHistogram is a distribution of latencies. Counts[i] is the number of
+// samples above Bounds[i-1] and at or below Bounds[i], the last count holds
+// the samples above all bounds.
+type LatencyHistogram struct {
+	Count  uint64          `json:"count"`
+	Sum    time.Duration   `json:"sum"`
+	Max    time.Duration   `json:"max"`
+	Bounds []time.Duration `json:"bounds"`
+	Counts []uint64        `json:"counts"`
+}
+
+var commitLatencyBounds = [...]time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// latencyStats records latencies into buckets defined by commitLatencyBounds.
+type latencyStats struct {
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+	counts [len(commitLatencyBounds) + 1]uint64
+}
+
+// Records the latency of each of the submitted times up to now.
+func (ls *latencyStats) record(now time.Time, submitted []int64) {
+	for _, ts := range submitted {
+		d := now.Sub(time.Unix(0, ts))
+		i, _ := slices.BinarySearch(commitLatencyBounds[:], d)
+		ls.counts[i]++
+		ls.count++
+		ls.sum += d
+		ls.max = max(ls.max, d)
+	}
+}
+
+func (ls *latencyStats) histogram() LatencyHistogram {
+	return LatencyHistogram{
+		Count:  ls.count,
+		Sum:    ls.sum,
+		Max:    ls.max,
+		Bounds: slices.Clone(commitLatencyBounds[:]),
+		Counts: slices.Clone(ls.counts[:]),
+	}
+}
+
+// EntryInfo describes a log entry without its data.
+type EntryInfo struct {
+	Index uint64      `json:"index"`
+	Term  uint64      `json:"term"`
+	Types []EntryType `json:"types,omitempty"`
+	// Acks is the number of peers, including the leader itself, that have
+	// stored the entry. Only reported by the leader.
+	Acks int `json:"acks,omitempty"`
+}
+
 type Peer struct {
 	ID      string
 	Current bool
 	Last    time.Time
 	Lag     uint64
+	Slow    bool // Timed out storing entries too many times in a row, only known by the leader
+	Learner bool // Demoted for being too slow, leadership is not transferred to it
 }
 
 type RaftState uint8
@@ -178,6 +340,8 @@ type raft struct {
 	removed map[string]time.Time           // Peers that were removed from the group
 	acks    map[uint64]map[string]struct{} // Append entry responses/acks, map of entry index -> peer ID
 	pae     map[uint64]*appendEntry        // Pending append entries
+	pts     map[uint64][]int64             // Submit times of proposals pending commit, by index
+	clat    latencyStats                   // Commit latency of proposals
 
 	elect  *time.Timer // Election timer, normally accessed via electTimer
 	etlr   time.Time   // Election timer last reset time, for unit tests only
@@ -223,6 +387,40 @@ type raft struct {
 	sq    *sendq        // Send queue for outbound RPC messages
 	aesub *subscription // Subscription for handleAppendEntry callbacks
 
+	aedfMax int               // Consecutive append entry decode failures before recreating subscriptions, 0 to only drop
+	qovr    int               // Quorum size override, only used when below a majority
+	srto    time.Duration     // Slow replica timeout, 0 to not track slow replicas
+	srmax   int               // Consecutive slow replica timeouts before taking action
+	sra     SlowReplicaAction // Action to take for slow replicas
+	rto     time.Duration     // Recovery time objective, 0 if not set
+	maxwal  uint64            // Maximum size of the log, 0 if not capped
+	walFull bool              // Whether the log is at its maximum size
+	dack    int               // Replicas that must have synced an entry before commit, 0 to ack on receipt
+	dsynced uint64            // Highest index the leader synced, when durable acks are required
+	dsc     chan struct{}     // Signals the leader to sync its log, when durable acks are required
+	cfgh    []ConfigChange    // Recent committed membership changes, oldest first
+	apst    time.Time         // Since when the upper layer has committed entries to apply
+	apdt    time.Duration     // Moving average of the time to apply a single entry
+	aedf    atomic.Int64      // Consecutive append entry decode failures
+	els     electionStats     // Election and vote request counters
+
+	nfault *atomic.Pointer[raftNetworkFault] // Injected network fault, nil unless enabled for testing
+
+	dworker bool         // Pin the run loop and apply worker to their own OS thread
+	pinned  atomic.Int32 // Number of goroutines currently pinned to their own OS thread
+
+	cgate func(index uint64, entries []*Entry) bool // Consulted before committing, leader only
+	cgto  time.Duration                             // How long to wait for the commit gate
+	cgidx uint64                                    // Index the commit gate is consulted for
+	cgok  bool                                      // Whether the commit gate opened for cgidx
+	cgc   chan struct{}                             // Signals the commit gate opened
+
+	aerMax  int              // Retries for an entry that failed to apply
+	aerBo   time.Duration    // Wait before the first retry of an entry that failed to apply
+	aerPol  ApplyErrorPolicy // What to do once out of retries
+	aerIdx  uint64           // Index of the entry that is failing to apply
+	aerFail int              // Times the entry at aerIdx failed to apply
+
 	wtv []byte // Term and vote to be written
 	wps []byte // Peer state to be written
 
@@ -231,6 +429,11 @@ type raft struct {
 
 	hcommit uint64 // The commit at the time that applies were paused
 
+	sip *SnapshotInstallProgress // Progress of the last snapshot received from the leader
+
+	quiesced int           // Outstanding quiesces, the leader buffers proposals while non-zero
+	qrc      chan struct{} // Closed when the last quiesce is resumed
+
 	prop  *ipQueue[*proposedEntry]       // Proposals
 	entry *ipQueue[*appendEntry]         // Append entries
 	resp  *ipQueue[*appendEntryResponse] // Append entries responses
@@ -257,6 +460,7 @@ type raft struct {
 type proposedEntry struct {
 	*Entry
 	reply string // Optional, to respond once proposal handled
+	ts    int64  // When the proposal was submitted
 }
 
 // catchupState structure that holds our subscription, and catchup term and index
@@ -273,9 +477,12 @@ type catchupState struct {
 
 // lps holds peer state of last time and last index replicated.
 type lps struct {
-	ts time.Time // Last timestamp
-	li uint64    // Last index replicated
-	kp bool      // Known peer
+	ts  time.Time // Last timestamp
+	li  uint64    // Last index replicated
+	kp  bool      // Known peer
+	sts time.Time // Since when the peer has not stored entries we sent, leader only
+	sto int       // Consecutive times the peer timed out storing entries, leader only
+	lrn bool      // Demoted to learner for being too slow, leader only
 }
 
 const (
@@ -284,10 +491,15 @@ const (
 	minCampaignTimeoutDefault      = 100 * time.Millisecond
 	maxCampaignTimeoutDefault      = 8 * minCampaignTimeoutDefault
 	hbIntervalDefault              = 1 * time.Second
+	slowReplicaThresholdDefault    = 3
+	commitGateTimeoutDefault       = 1 * time.Second
+	applyErrorBackoffDefault       = 100 * time.Millisecond
+	applyErrorBackoffMax           = 30 * time.Second
 	lostQuorumIntervalDefault      = hbIntervalDefault * 10 // 10 seconds
 	lostQuorumCheckIntervalDefault = hbIntervalDefault * 10 // 10 seconds
 	observerModeIntervalDefault    = 48 * time.Hour
 	peerRemoveTimeoutDefault       = 5 * time.Minute
+	snapBusyRetryDefault           = 1 * time.Second
 )
 
 var (
@@ -300,6 +512,7 @@ var (
 	lostQuorumCheck      = lostQuorumCheckIntervalDefault
 	observerModeInterval = observerModeIntervalDefault
 	peerRemoveTimeout    = peerRemoveTimeoutDefault
+	snapBusyRetry        = snapBusyRetryDefault
 )
 
 type RaftConfig struct {
@@ -318,33 +531,129 @@ type RaftConfig struct {
 	// We need to protect against losing state due to the new peers starting with an empty log.
 	// Therefore, these empty servers can't try to become leader until they at least have _some_ state.
 	ScaleUp bool
-}
+
+	// MaxAppendEntryDecodeFailures is the number of consecutive append entries that can fail
+	// to decode before our replication subscriptions are torn down and recreated, to recover
+	// from desynced framing. If zero, corrupt append entries are only dropped.
+	MaxAppendEntryDecodeFailures int
+
+	// QuorumSize overrides the number of nodes needed for both leader elections and
+	// commits. It is only used when smaller than a strict majority of the group, and
+	// requires UnsafeQuorum to be set as well. With a quorum below a majority, two
+	// leaders can be elected at the same time and committed entries can be lost, so
+	// this must only be used for groups where availability matters more than consistency.
+	QuorumSize   int
+	UnsafeQuorum bool
+
+	// SlowReplicaTimeout is how long a follower can take to store the entries sent by the
+	// leader before it counts as a timeout. Once a follower timed out SlowReplicaThreshold
+	// times in a row, SlowReplicaAction is taken. If zero, slow replicas are not tracked.
+	SlowReplicaTimeout   time.Duration
+	SlowReplicaThreshold int
+	SlowReplicaAction    SlowReplicaAction
+
+	// RecoveryTimeObjective bounds how long replaying the log on a cold restart should take.
+	// Replay time is estimated from the number of entries since the last snapshot and how
+	// long the upper layer takes to apply them, and ExceedsRecoveryTime reports when it is
+	// time to snapshot to stay within the objective. If zero, there is no objective.
+	RecoveryTimeObjective time.Duration
+
+	// MaxWALBytes caps the size of the log. ExceedsMaxWAL reports when the log is getting
+	// close to the cap, so the upper layer should install a snapshot to compact it. Once at
+	// the cap, proposals are refused until a snapshot brings the log back below it.
+	// If zero, the size of the log is not capped.
+	MaxWALBytes uint64
+
+	// DurableAckCount is the number of replicas, including the leader, that must have
+	// synced an entry to disk before it is committed. When set, followers only acknowledge
+	// entries after syncing their log, and the leader syncs its own log before counting itself.
+	// Commits still require a quorum, and the count is capped by the cluster size.
+	// If zero, followers acknowledge entries as soon as they are stored.
+	DurableAckCount int
+
+	// DedicatedWorker pins the run loop of a hot Raft group to its own OS thread, instead of
+	// letting the Go scheduler move it between threads, to improve cache locality. The upper
+	// layer's apply worker is pinned as well when it calls PinApplyWorker. Every pinned
+	// goroutine takes an OS thread out of the scheduler's pool, so only use this for a few groups.
+	DedicatedWorker bool
+
+	// CommitGate is consulted by the leader before the commit index advances to include the
+	// entries at index, so a layer on top can hold back their commit, e.g. to coordinate with an
+	// external two-phase step. Later entries wait for the gated one to be committed first.
+	// The entries are only committed once it returns true. Returning false, or not returning
+	// within CommitGateTimeout, holds back the commit and the gate is consulted again after
+	// the timeout. It's called from its own goroutine, so it never blocks the run loop.
+	CommitGate        func(index uint64, entries []*Entry) bool
+	CommitGateTimeout time.Duration
+
+	// ApplyErrorRetries is how many times the upper layer retries a committed entry it failed
+	// to apply, as reported to ApplyFailed, waiting ApplyErrorBackoff before the first retry
+	// and doubling the wait after each. Once out of retries, ApplyErrorPolicy is followed.
+	ApplyErrorRetries int
+	ApplyErrorBackoff time.Duration
+	ApplyErrorPolicy  ApplyErrorPolicy
+
+	// Test only, allows injecting network faults with setNetworkFault.
+	faults bool
+}
+
+// ApplyErrorPolicy is what happens to a committed entry the upper layer keeps failing to apply.
+type ApplyErrorPolicy int
+
+const (
+	// ApplyErrorHalt stops the node, so its state doesn't diverge from the rest of the group.
+	ApplyErrorHalt ApplyErrorPolicy = iota
+	// ApplyErrorSkip marks the entry as applied without it having been applied, and sends an advisory.
+	ApplyErrorSkip
+)
+
+// SlowReplicaAction is what a leader does about a follower that
+// repeatedly times out storing the entries it was sent.
+type SlowReplicaAction int
+
+const (
+	// SlowReplicaAlert only warns about the slow replica.
+	SlowReplicaAlert SlowReplicaAction = iota
+	// SlowReplicaDemote warns and demotes the slow replica to a learner. The leader
+	// will not transfer leadership to it, and reports it as such. Its acks are still
+	// counted towards commits, the quorum already does without the slowest replica.
+	// It is promoted again once it caught up.
+	SlowReplicaDemote
+)
 
 var (
-	errNotLeader         = errors.New("raft: not leader")
-	errAlreadyLeader     = errors.New("raft: already leader")
-	errNilCfg            = errors.New("raft: no config given")
-	errCorruptPeers      = errors.New("raft: corrupt peer state")
-	errEntryLoadFailed   = errors.New("raft: could not load entry from WAL")
-	errEntryStoreFailed  = errors.New("raft: could not store entry to WAL")
-	errNodeClosed        = errors.New("raft: node is closed")
-	errNodeRemoved       = errors.New("raft: peer was removed")
-	errBadSnapName       = errors.New("raft: snapshot name could not be parsed")
-	errNoSnapAvailable   = errors.New("raft: no snapshot available")
-	errSnapInProgress    = errors.New("raft: snapshot is already in progress")
-	errSnapAborted       = errors.New("raft: snapshot was aborted")
-	errCatchupsRunning   = errors.New("raft: snapshot can not be installed while catchups running")
-	errSnapshotCorrupt   = errors.New("raft: snapshot corrupt")
-	errTooManyPrefs      = errors.New("raft: stepdown requires at most one preferred new leader")
-	errNoPeerState       = errors.New("raft: no peerstate")
-	errAdjustBootCluster = errors.New("raft: can not adjust boot peer size on established group")
-	errLeaderLen         = fmt.Errorf("raft: leader should be exactly %d bytes", idLen)
-	errTooManyEntries    = errors.New("raft: append entry can contain a max of 64k entries")
-	errBadAppendEntry    = errors.New("raft: append entry corrupt")
-	errNoInternalClient  = errors.New("raft: no internal client")
-	errMembershipChange  = errors.New("raft: membership change in progress")
-	errRemoveLastNode    = errors.New("raft: cannot remove the last peer")
-	errPeerNotFound      = errors.New("raft: peer not found")
+	errNotLeader          = errors.New("raft: not leader")
+	errAlreadyLeader      = errors.New("raft: already leader")
+	errNilCfg             = errors.New("raft: no config given")
+	errCorruptPeers       = errors.New("raft: corrupt peer state")
+	errEntryLoadFailed    = errors.New("raft: could not load entry from WAL")
+	errEntryStoreFailed   = errors.New("raft: could not store entry to WAL")
+	errNodeClosed         = errors.New("raft: node is closed")
+	errNodeRemoved        = errors.New("raft: peer was removed")
+	errBadSnapName        = errors.New("raft: snapshot name could not be parsed")
+	errNoSnapAvailable    = errors.New("raft: no snapshot available")
+	errSnapInProgress     = errors.New("raft: snapshot is already in progress")
+	errSnapAborted        = errors.New("raft: snapshot was aborted")
+	errCatchupsRunning    = errors.New("raft: snapshot can not be installed while catchups running")
+	errSnapBusy           = errors.New("raft: too many snapshots in progress")
+	errSnapshotCorrupt    = errors.New("raft: snapshot corrupt")
+	errTooManyPrefs       = errors.New("raft: stepdown requires at most one preferred new leader")
+	errNoPeerState        = errors.New("raft: no peerstate")
+	errAdjustBootCluster  = errors.New("raft: can not adjust boot peer size on established group")
+	errUnsafeQuorum       = errors.New("raft: quorum size override requires unsafe quorum to be set")
+	errApplyHalted        = errors.New("raft: halted after failing to apply entry")
+	errLeaderLen          = fmt.Errorf("raft: leader should be exactly %d bytes", idLen)
+	errTooManyEntries     = errors.New("raft: append entry can contain a max of 64k entries")
+	errBadAppendEntry     = errors.New("raft: append entry corrupt")
+	errNoInternalClient   = errors.New("raft: no internal client")
+	errMembershipChange   = errors.New("raft: membership change in progress")
+	errRemoveLastNode     = errors.New("raft: cannot remove the last peer")
+	errPeerNotFound       = errors.New("raft: peer not found")
+	errResyncSelf         = errors.New("raft: can not resync self")
+	errWALFull            = errors.New("raft: log is at its maximum size")
+	errBadArchive         = errors.New("raft: archive corrupt")
+	errCompactInterrupted = errors.New("raft: log changed while compacting")
+	errLogNotEmpty        = errors.New("raft: log is not empty")
 )
 
 // This will bootstrap a raftNode by writing its config into the store directory.
@@ -426,6 +735,9 @@ func (s *Server) initRaftNode(accName string, cfg *RaftConfig, labels pprofLabel
 	if cfg == nil {
 		return nil, errNilCfg
 	}
+	if cfg.QuorumSize < 0 || (cfg.QuorumSize > 0 && !cfg.UnsafeQuorum) {
+		return nil, errUnsafeQuorum
+	}
 	s.mu.RLock()
 	if s.sys == nil {
 		s.mu.RUnlock()
@@ -446,6 +758,7 @@ func (s *Server) initRaftNode(accName string, cfg *RaftConfig, labels pprofLabel
 		peers:    make(map[string]*lps),
 		acks:     make(map[uint64]map[string]struct{}),
 		pae:      make(map[uint64]*appendEntry),
+		pts:      make(map[uint64][]int64),
 		s:        s,
 		js:       s.getJetStream(),
 		quit:     make(chan struct{}),
@@ -458,6 +771,36 @@ func (s *Server) initRaftNode(accName string, cfg *RaftConfig, labels pprofLabel
 		accName:  accName,
 		leadc:    make(chan bool, 32),
 		observer: cfg.Observer,
+		aedfMax:  cfg.MaxAppendEntryDecodeFailures,
+		qovr:     cfg.QuorumSize,
+		srto:     cfg.SlowReplicaTimeout,
+		srmax:    cfg.SlowReplicaThreshold,
+		sra:      cfg.SlowReplicaAction,
+		rto:      cfg.RecoveryTimeObjective,
+		maxwal:   cfg.MaxWALBytes,
+		dack:     cfg.DurableAckCount,
+		dsc:      make(chan struct{}, 1),
+		dworker:  cfg.DedicatedWorker,
+		cgate:    cfg.CommitGate,
+		cgto:     cfg.CommitGateTimeout,
+		aerMax:   cfg.ApplyErrorRetries,
+		aerBo:    cfg.ApplyErrorBackoff,
+		aerPol:   cfg.ApplyErrorPolicy,
+	}
+	if cfg.faults {
+		n.nfault = new(atomic.Pointer[raftNetworkFault])
+	}
+	if n.aerBo <= 0 {
+		n.aerBo = applyErrorBackoffDefault
+	}
+	if n.srmax <= 0 {
+		n.srmax = slowReplicaThresholdDefault
+	}
+	if n.cgate != nil {
+		n.cgc = make(chan struct{}, 1)
+		if n.cgto <= 0 {
+			n.cgto = commitGateTimeoutDefault
+		}
 	}
 
 	// Setup our internal subscriptions for proposals, votes and append entries.
@@ -580,6 +923,9 @@ func (s *Server) initRaftNode(accName string, cfg *RaftConfig, labels pprofLabel
 	}
 
 	n.debug("Started (cluster size %d, quorum %d)", n.csz, n.qn)
+	if n.qovr > 0 {
+		n.warn("Quorum size overridden to %d, elections and commits below a majority are NOT safe and can lose data", n.qovr)
+	}
 
 	// Check if we need to start in observer mode due to lame duck status.
 	// This will stop us from taking on the leader role when we're about to
@@ -754,7 +1100,11 @@ func (n *raft) outOfResources() bool {
 	if !n.track || js == nil {
 		return false
 	}
-	return js.limitsExceeded(n.wtype)
+	// The storage type can change when the log is migrated.
+	n.RLock()
+	wtype := n.wtype
+	n.RUnlock()
+	return js.limitsExceeded(wtype)
 }
 
 // Maps node names back to server names.
@@ -931,6 +1281,9 @@ func (n *raft) Propose(data []byte) error {
 		n.overrunCount++
 		return errNotLeader
 	}
+	if n.isWALFull() {
+		return errWALFull
+	}
 	n.prop.push(newProposedEntry(newEntry(EntryNormal, data), _EMPTY_))
 	return nil
 }
@@ -960,12 +1313,62 @@ func (n *raft) ProposeMulti(entries []*Entry) error {
 		n.overrunCount++
 		return errNotLeader
 	}
+	if n.isWALFull() {
+		return errWALFull
+	}
 	for _, e := range entries {
 		n.prop.push(newProposedEntry(e, _EMPTY_))
 	}
 	return nil
 }
 
+// How often we check whether a no-op entry was committed.
+const noopCommitCheckInterval = 5 * time.Millisecond
+
+// ProposeNoop appends an entry without state changes for the upper layer and waits for it to be committed.
+// Committing an entry from our current term confirms we are still the leader, and that all entries
+// from prior terms are committed as well. This should only be called on the leader.
+func (n *raft) ProposeNoop() error {
+	n.Lock()
+	if state := n.State(); state != Leader {
+		n.Unlock()
+		n.debug("No-op proposal ignored, not leader (state: %v)", state)
+		return errNotLeader
+	}
+	if werr := n.werr; werr != nil {
+		n.Unlock()
+		return werr
+	}
+	// Our current peer state is used as the no-op, it is not passed up when applied.
+	if err := n.sendAppendEntryLocked([]*Entry{{EntryPeerState, encodePeerState(n.currentPeerStateLocked())}}, true); err != nil {
+		n.Unlock()
+		return err
+	}
+	index, term := n.pindex, n.term
+	n.Unlock()
+
+	ticker := time.NewTicker(noopCommitCheckInterval)
+	defer ticker.Stop()
+	for {
+		n.RLock()
+		commit, cterm := n.commit, n.term
+		n.RUnlock()
+		switch state := n.State(); {
+		case state == Closed:
+			return errNodeClosed
+		case state != Leader || cterm != term:
+			return errNotLeader
+		case commit >= index:
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-n.quit:
+			return errNodeClosed
+		}
+	}
+}
+
 // isLeaderOverrun returns whether we are overrun and should step down due to continuously increasing
 // uncommitted or unapplied entries. If triggered, this means we're being severely overrun by
 // incoming proposals or the system is degraded such that it's too slow (or unable) to process them.
@@ -1091,7 +1494,7 @@ func (n *raft) AdjustBootClusterSize(csz int) error {
 	// Adjust the cluster size and the number of nodes needed to establish
 	// a quorum.
 	n.csz = csz
-	n.qn = n.csz/2 + 1
+	n.qn = n.quorumFor(n.csz)
 
 	return nil
 }
@@ -1114,7 +1517,7 @@ func (n *raft) AdjustClusterSize(csz int) error {
 	// Adjust the cluster size and the number of nodes needed to establish
 	// a quorum.
 	n.csz = csz
-	n.qn = n.csz/2 + 1
+	n.qn = n.quorumFor(n.csz)
 
 	n.sendPeerState()
 	return nil
@@ -1197,6 +1600,61 @@ func (n *raft) ResumeApply() {
 	}
 }
 
+// isQuiesced returns whether the leader is buffering proposals due to an outstanding quiesce.
+func (n *raft) isQuiesced() bool {
+	n.RLock()
+	defer n.RUnlock()
+	return n.quiesced > 0
+}
+
+// Upper bound on how long Quiesce waits for entries in flight to be committed and applied.
+const quiesceFlushTimeout = 5 * time.Second
+
+// Quiesce momentarily stops the group from making progress, so that an externally consistent
+// backup can be taken. On the leader, new proposals are buffered instead of being sent, and on
+// followers, new commits are held back from the upper layer. Once the entries that were already
+// in flight are committed and applied, the stable index is returned along with a function to
+// resume, which must be called when done. Waiting for entries in flight is bounded by
+// quiesceFlushTimeout, for example if the group lost quorum, in which case the index
+// applied at that time is returned.
+func (n *raft) Quiesce() (resume func(), index uint64) {
+	n.Lock()
+	var target uint64
+	if n.State() == Leader {
+		if n.quiesced++; n.quiesced == 1 {
+			n.qrc = make(chan struct{})
+		}
+		target = n.pindex
+		resume = func() {
+			n.Lock()
+			defer n.Unlock()
+			// Wakes up the leader to send what was buffered.
+			if n.quiesced--; n.quiesced == 0 {
+				close(n.qrc)
+				n.qrc = nil
+			}
+		}
+	} else {
+		n.pauseApplyLocked()
+		target = n.commit
+		resume = n.ResumeApply
+	}
+	n.Unlock()
+
+	deadline := time.Now().Add(quiesceFlushTimeout)
+	for {
+		n.RLock()
+		commit, applied, state := n.commit, n.applied, n.State()
+		n.RUnlock()
+		index = applied
+		if (commit >= target && applied >= commit) || state == Closed || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return sync.OnceFunc(resume), index
+}
+
 // DrainAndReplaySnapshot will drain the apply queue and replay the snapshot.
 // Our highest known commit will be preserved by pausing applies. The caller
 // should make sure to call ResumeApply() when handling the snapshot from the
@@ -1226,6 +1684,37 @@ func (n *raft) Applied(index uint64) (entries uint64, bytes uint64) {
 	return n.Processed(index, index)
 }
 
+// ApplyFailed must be called by the upper layer when it failed to apply the committed entry at
+// index. It returns how long to wait before trying to apply it again. Once out of retries, it
+// returns zero and follows the ApplyErrorPolicy. Either the entry is skipped, and marked as
+// applied, or the node is stopped and errApplyHalted is returned.
+func (n *raft) ApplyFailed(index uint64, err error) (time.Duration, error) {
+	n.Lock()
+	if index != n.aerIdx {
+		n.aerIdx, n.aerFail = index, 0
+	}
+	if n.aerFail++; n.aerFail <= n.aerMax {
+		backoff := min(n.aerBo<<(n.aerFail-1), applyErrorBackoffMax)
+		n.warn("Failed to apply entry %d, retrying in %v: %v", index, backoff, err)
+		n.Unlock()
+		return backoff, nil
+	}
+	n.aerIdx, n.aerFail = 0, 0
+	if n.aerPol == ApplyErrorHalt {
+		n.error("Failed to apply entry %d, halting: %v", index, err)
+		n.Unlock()
+		n.Stop()
+		return 0, errApplyHalted
+	}
+	n.warn("Failed to apply entry %d, skipping: %v", index, err)
+	group := n.group
+	n.Unlock()
+
+	n.Applied(index)
+	n.s.sendRaftApplySkippedEvent(group, index, err)
+	return 0, nil
+}
+
 // Processed is a callback that must be called by the upper layer when it
 // has processed the committed entries that it received from the apply queue,
 // but it (maybe) hasn't applied all the processed entries yet.
@@ -1251,8 +1740,12 @@ func (n *raft) Processed(index uint64, applied uint64) (entries uint64, bytes ui
 		applied = index
 	}
 	if applied > n.applied {
+		n.trackApplyTime(applied)
 		n.applied = applied
 	}
+	if sip := n.sip; sip != nil && sip.State == SnapshotInstallApplying && n.applied >= sip.Index {
+		sip.State, sip.Updated = SnapshotInstallComplete, time.Now().UTC()
+	}
 
 	// If it was set, and we reached the minimum processed index, reset and send signal to upper layer.
 	// We're not waiting for processed AND applied, because applying could take longer.
@@ -1281,6 +1774,125 @@ func (n *raft) Processed(index uint64, applied uint64) (entries uint64, bytes ui
 	return entries, bytes
 }
 
+// trackApplyTime updates the average time to apply an entry, given that the
+// upper layer has now applied up to the given index.
+// Lock should be held.
+func (n *raft) trackApplyTime(applied uint64) {
+	if n.apst.IsZero() {
+		return
+	}
+	now := time.Now()
+	d := now.Sub(n.apst) / time.Duration(applied-n.applied)
+	if n.apdt == 0 {
+		n.apdt = d
+	} else {
+		n.apdt += (d - n.apdt) / 8
+	}
+	// Only keep timing while there's more to apply, otherwise
+	// the upper layer is idle until the next commit.
+	if applied < n.commit {
+		n.apst = now
+	} else {
+		n.apst = time.Time{}
+	}
+}
+
+// Returns the estimated time to replay the log since the last snapshot.
+// Lock should be held.
+func (n *raft) replayTime() time.Duration {
+	if n.pindex <= n.papplied {
+		return 0
+	}
+	return time.Duration(n.pindex-n.papplied) * n.apdt
+}
+
+// ExceedsRecoveryTime returns true if replaying the log since the last snapshot is
+// estimated to take long enough to put the recovery time objective at risk, in which
+// case the upper layer should install a snapshot. We already ask for one at 3/4 of the
+// objective, since the log keeps growing while the snapshot is being created.
+func (n *raft) ExceedsRecoveryTime() bool {
+	n.RLock()
+	defer n.RUnlock()
+	return n.rto > 0 && n.replayTime() >= n.rto*3/4
+}
+
+// ExceedsMaxWAL returns true if the log is getting close to its maximum size, in which
+// case the upper layer should install a snapshot. Like for the recovery time objective
+// we already ask for one at 3/4 of the maximum, to not have to refuse proposals while
+// the snapshot is being created.
+func (n *raft) ExceedsMaxWAL() bool {
+	n.RLock()
+	defer n.RUnlock()
+	return n.maxwal > 0 && n.bytes >= n.maxwal*3/4
+}
+
+// PinApplyWorker locks the calling goroutine to its current OS thread if this group is
+// configured with a dedicated worker, and returns a function to unpin it again. The upper
+// layer calls this from the goroutine that takes entries from the apply queue, the run
+// loop pins itself. This is a no-op if the group doesn't use a dedicated worker.
+func (n *raft) PinApplyWorker() (unpin func()) {
+	return n.pinWorker()
+}
+
+// Locks the calling goroutine to its current OS thread if configured to use a
+// dedicated worker, returns a function to unlock it again.
+func (n *raft) pinWorker() func() {
+	if !n.dworker {
+		return func() {}
+	}
+	runtime.LockOSThread()
+	n.pinned.Add(1)
+	return func() {
+		n.pinned.Add(-1)
+		runtime.UnlockOSThread()
+	}
+}
+
+// ConfigChange is a committed membership change of a Raft group.
+type ConfigChange struct {
+	Type  EntryType `json:"type"` // EntryAddPeer or EntryRemovePeer
+	Peer  string    `json:"peer"`
+	Term  uint64    `json:"term"`
+	Index uint64    `json:"index"`
+	Time  time.Time `json:"time"`
+}
+
+// Maximum number of membership changes kept in the configuration history.
+const maxConfigHistory = 128
+
+// ConfigHistory returns the most recent committed membership changes, oldest first.
+// The history is kept in memory only, so after a restart it will only hold the changes
+// replayed from the log since the last snapshot.
+func (n *raft) ConfigHistory() []ConfigChange {
+	n.RLock()
+	defer n.RUnlock()
+	return slices.Clone(n.cfgh)
+}
+
+// Records a committed membership change in the configuration history.
+// Lock should be held.
+func (n *raft) recordConfigChange(t EntryType, peer string, term, index uint64) {
+	if len(n.cfgh) >= maxConfigHistory {
+		n.cfgh = slices.Delete(n.cfgh, 0, len(n.cfgh)-maxConfigHistory+1)
+	}
+	n.cfgh = append(n.cfgh, ConfigChange{Type: t, Peer: peer, Term: term, Index: index, Time: time.Now().UTC()})
+}
+
+// Returns whether the log is at its maximum size, and proposals should be refused.
+// Lock should be held.
+func (n *raft) isWALFull() bool {
+	if n.maxwal == 0 || n.bytes < n.maxwal {
+		n.walFull = false
+		return false
+	}
+	// Only warn once each time the log fills up.
+	if !n.walFull {
+		n.walFull = true
+		n.warn("Log at its maximum size of %s, refusing proposals until snapshot", friendlyBytes(n.maxwal))
+	}
+	return true
+}
+
 // For capturing data needed by snapshot.
 type snapshot struct {
 	lastTerm  uint64
@@ -1294,6 +1906,11 @@ const minSnapshotLen = 28
 // Encodes a snapshot into a buffer for storage.
 // Lock should be held.
 func (n *raft) encodeSnapshot(snap *snapshot) []byte {
+	return encodeSnapshot(n.hh, snap)
+}
+
+// Encodes a snapshot into a buffer for storage, using the given hash for its checksum.
+func encodeSnapshot(hh hash.Hash64, snap *snapshot) []byte {
 	if snap == nil {
 		return nil
 	}
@@ -1311,10 +1928,10 @@ func (n *raft) encodeSnapshot(snap *snapshot) []byte {
 	wi += len(snap.data)
 
 	// Now do the hash for the end.
-	n.hh.Reset()
-	n.hh.Write(buf[:wi])
+	hh.Reset()
+	hh.Write(buf[:wi])
 	var hb [highwayhash.Size64]byte
-	checksum := n.hh.Sum(hb[:0])
+	checksum := hh.Sum(hb[:0])
 	copy(buf[wi:], checksum)
 	wi += len(checksum)
 	return buf[:wi]
@@ -1335,6 +1952,13 @@ func (n *raft) SendSnapshot(data []byte) error {
 // all of the log entries up to and including index. This should not be called with
 // entries that have been applied to the FSM but have not been applied to the raft state.
 func (n *raft) InstallSnapshot(data []byte, force bool) error {
+	// Skip this snapshot if the server is already writing as many as it allows.
+	sem, ok := n.snapAcquireSlot()
+	if !ok {
+		return errSnapBusy
+	}
+	defer snapReleaseSlot(sem)
+
 	n.Lock()
 	defer n.Unlock()
 
@@ -1352,6 +1976,45 @@ func (n *raft) InstallSnapshot(data []byte, force bool) error {
 	return c.n.installSnapshot(snap)
 }
 
+// Takes a slot among the ones limiting the number of Raft snapshots this server writes
+// concurrently, so that many groups snapshotting at once don't starve normal disk I/O.
+// Never waits, a snapshot that finds all slots taken fails with errSnapBusy and should be
+// retried by the upper layer after snapBusyRetryDelay.
+// The meta group is never limited, its log must not grow behind stream and consumer snapshots.
+// Returns the semaphore to release the slot to, which is nil if not limited, and false
+// if all slots are taken.
+func (n *raft) snapAcquireSlot() (chan struct{}, bool) {
+	if n.group == defaultMetaGroupName {
+		return nil, true
+	}
+	s := n.s
+	s.snapMu.RLock()
+	sem := s.snapSem
+	s.snapMu.RUnlock()
+	if sem == nil {
+		return nil, true
+	}
+	select {
+	case sem <- struct{}{}:
+		return sem, true
+	default:
+		return nil, false
+	}
+}
+
+// snapBusyRetryDelay returns how long to wait before retrying a snapshot that failed with
+// errSnapBusy, jittered so the skipped groups don't all compete for the slots again at once.
+func snapBusyRetryDelay() time.Duration {
+	return snapBusyRetry + time.Duration(rand.Int63n(int64(snapBusyRetry)))
+}
+
+// Releases a slot obtained from snapAcquireSlot.
+func snapReleaseSlot(sem chan struct{}) {
+	if sem != nil {
+		<-sem
+	}
+}
+
 // Install the snapshot.
 // Lock should be held.
 func (n *raft) installSnapshot(snap *snapshot) error {
@@ -1524,6 +2187,16 @@ func (c *checkpoint) Abort() {
 // Lock should be held.
 func (c *checkpoint) InstallSnapshot(data []byte) (uint64, error) {
 	n := c.n
+	// Skip this snapshot if the server is already writing as many as it allows.
+	sem, ok := n.snapAcquireSlot()
+	if !ok {
+		n.Lock()
+		n.snapshotting = false
+		n.Unlock()
+		return 0, errSnapBusy
+	}
+	defer snapReleaseSlot(sem)
+
 	n.Lock()
 	defer n.Unlock()
 	if !n.snapshotting {
@@ -1600,6 +2273,316 @@ func (c *checkpoint) InstallSnapshot(data []byte) (uint64, error) {
 	return compacted, nil
 }
 
+// Compact defragments the log in a single pass. The last snapshot is verified and the log
+// is compacted up to it, after which the remaining entries are rewritten into fresh segments
+// and verified to load back. Upper layers should install a snapshot of their applied state
+// right before. Entries are copied without holding the lock, only the ones appended in the
+// meantime are copied while locked before the fresh segments are swapped in.
+func (n *raft) Compact() error {
+	n.Lock()
+	if n.State() == Closed {
+		n.Unlock()
+		return errNodeClosed
+	}
+	if n.snapshotting {
+		n.Unlock()
+		return errSnapInProgress
+	}
+	if werr := n.werr; werr != nil {
+		n.Unlock()
+		return werr
+	}
+	// Catchups load entries from the log, so we can't swap it out from under them.
+	if len(n.progress) > 0 {
+		n.Unlock()
+		return errCatchupsRunning
+	}
+
+	// Verify our snapshot before dropping the entries it covers.
+	if n.snapfile != _EMPTY_ {
+		snap, err := n.loadLastSnapshot()
+		if err != nil {
+			n.Unlock()
+			return err
+		}
+		if _, err := n.wal.Compact(snap.lastIndex + 1); err != nil {
+			n.setWriteErrLocked(err)
+			n.Unlock()
+			return err
+		}
+	}
+
+	// Memory based logs are left alone.
+	fs, ok := n.wal.(*fileStore)
+	if !ok {
+		n.Unlock()
+		return nil
+	}
+	var before StreamState
+	fs.FastState(&before)
+
+	// Hold off snapshots while the entries are copied.
+	n.snapshotting = true
+	n.Unlock()
+
+	nfs, err := n.stageWAL(fs, &before)
+
+	n.Lock()
+	defer n.Unlock()
+	n.snapshotting = false
+
+	tdir := filepath.Join(fs.fcfg.StoreDir, compactDir)
+	defer os.RemoveAll(tdir)
+	if err != nil {
+		return err
+	}
+	// A catchup that started while copying reads from the log we would stop.
+	if len(n.progress) > 0 {
+		nfs.Stop()
+		return errCatchupsRunning
+	}
+	if err = n.finishStagedWAL(fs, nfs, &before); err != nil {
+		nfs.Stop()
+		return err
+	}
+	return n.swapStagedWAL(fs, nfs, tdir)
+}
+
+// Directory within the log's store directory used to write fresh segments.
+const compactDir = "__compact__"
+
+// stageWAL copies all entries of the log into fresh segments within compactDir and
+// makes sure they can be decoded. Lock should not be held.
+func (n *raft) stageWAL(fs *fileStore, state *StreamState) (*fileStore, error) {
+	fs.mu.RLock()
+	fcfg, cfg, prf, oldprf := fs.fcfg, fs.cfg, fs.prf, fs.oldprf
+	fs.mu.RUnlock()
+
+	tdir := filepath.Join(fcfg.StoreDir, compactDir)
+	os.RemoveAll(tdir)
+
+	tcfg := fcfg
+	tcfg.StoreDir = tdir
+	nfs, err := newFileStoreWithCreated(tcfg, cfg.StreamConfig, cfg.Created, prf, oldprf)
+	if err != nil {
+		return nil, err
+	}
+	// Fresh segments need to start at our first entry.
+	if state.FirstSeq > 1 {
+		if _, err = nfs.Compact(state.FirstSeq); err != nil {
+			nfs.Stop()
+			return nil, err
+		}
+	}
+	if state.Msgs > 0 {
+		if err = copyWALEntries(fs, nfs, state.FirstSeq, state.LastSeq); err != nil {
+			nfs.Stop()
+			return nil, err
+		}
+	}
+	return nfs, nil
+}
+
+// finishStagedWAL copies the entries appended to the log since it was staged.
+// Bails if the log was swapped or truncated. Lock should be held.
+func (n *raft) finishStagedWAL(fs WAL, nfs StreamStore, before *StreamState) error {
+	if n.State() == Closed {
+		return errNodeClosed
+	}
+	if n.wal != fs {
+		return errCompactInterrupted
+	}
+	var now StreamState
+	fs.FastState(&now)
+	if now.FirstSeq != before.FirstSeq || now.LastSeq < before.LastSeq {
+		return errCompactInterrupted
+	}
+	if before.Msgs > 0 {
+		// A truncate replaces the last entry we copied, so make sure it's the same.
+		var osm, nsm StoreMsg
+		om, err := fs.LoadMsg(before.LastSeq, &osm)
+		if err != nil {
+			return err
+		}
+		nm, err := nfs.LoadMsg(before.LastSeq, &nsm)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(om.msg, nm.msg) {
+			return errCompactInterrupted
+		}
+	}
+	if now.LastSeq > before.LastSeq {
+		return copyWALEntries(fs, nfs, before.LastSeq+1, now.LastSeq)
+	}
+	return nil
+}
+
+// copyWALEntries copies the entries [first:last] from one log to the other,
+// making sure each of them can be decoded.
+func copyWALEntries(fs WAL, nfs StreamStore, first, last uint64) error {
+	var smv StoreMsg
+	for index := first; index <= last; index++ {
+		sm, err := fs.LoadMsg(index, &smv)
+		if err != nil {
+			return err
+		}
+		ae, err := decodeAppendEntry(sm.msg, nil, _EMPTY_)
+		if err != nil {
+			return err
+		}
+		ae.returnToPool()
+		if err = nfs.StoreRawMsg(sm.subj, sm.hdr, sm.msg, sm.seq, sm.ts, 0, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// swapStagedWAL swaps the fresh segments in for the current ones. Moving the staged
+// segments to compactedDir is the commit point, if we go down after it the swap is
+// finished when the log is opened again. Lock should be held.
+func (n *raft) swapStagedWAL(fs, nfs *fileStore, tdir string) error {
+	var before StreamState
+	nfs.FastState(&before)
+	if err := nfs.Stop(); err != nil {
+		return err
+	}
+
+	fs.mu.RLock()
+	fcfg, cfg, prf, oldprf := fs.fcfg, fs.cfg, fs.prf, fs.oldprf
+	fs.mu.RUnlock()
+
+	// From here on the current log is closed, so any failure is a write error.
+	if err := fs.Stop(); err != nil {
+		n.setWriteErrLocked(err)
+		return err
+	}
+	cdir := filepath.Join(fcfg.StoreDir, compactedDir)
+	os.RemoveAll(cdir)
+	if err := os.Rename(tdir, cdir); err != nil {
+		n.setWriteErrLocked(err)
+		return err
+	}
+	if err := swapCompactedMsgs(fcfg.StoreDir); err != nil {
+		n.setWriteErrLocked(err)
+		return err
+	}
+	wal, err := newFileStoreWithCreated(fcfg, cfg.StreamConfig, cfg.Created, prf, oldprf)
+	if err != nil {
+		n.setWriteErrLocked(err)
+		return err
+	}
+	n.wal = wal
+
+	var after StreamState
+	wal.FastState(&after)
+	if after.Msgs != before.Msgs || after.FirstSeq != before.FirstSeq || after.LastSeq != before.LastSeq {
+		err := fmt.Errorf("raft: log state mismatch after compact, expected [%d:%d] got [%d:%d]",
+			before.FirstSeq, before.LastSeq, after.FirstSeq, after.LastSeq)
+		n.setWriteErrLocked(err)
+		return err
+	}
+	n.bytes = after.Bytes
+	n.debug("Compacted log to %d entries [%d:%d]", after.Msgs, after.FirstSeq, after.LastSeq)
+	return nil
+}
+
+// MigrateLog moves our log into a new one of storage type st. Like Compact the entries
+// are copied without holding the lock, and the logs are swapped once the copy caught up.
+func (n *raft) MigrateLog(st StorageType) error {
+	n.Lock()
+	if n.State() == Closed {
+		n.Unlock()
+		return errNodeClosed
+	}
+	if n.wtype == st {
+		n.Unlock()
+		return nil
+	}
+	if n.snapshotting {
+		n.Unlock()
+		return errSnapInProgress
+	}
+	if werr := n.werr; werr != nil {
+		n.Unlock()
+		return werr
+	}
+	if len(n.progress) > 0 {
+		n.Unlock()
+		return errCatchupsRunning
+	}
+	wal := n.wal
+	var before StreamState
+	wal.FastState(&before)
+
+	// Hold off snapshots while the entries are copied.
+	n.snapshotting = true
+	n.Unlock()
+
+	nwal, err := n.newLog(st)
+	if err == nil && before.FirstSeq > 1 {
+		_, err = nwal.Compact(before.FirstSeq)
+	}
+	if err == nil && before.Msgs > 0 {
+		err = copyWALEntries(wal, nwal, before.FirstSeq, before.LastSeq)
+	}
+
+	n.Lock()
+	defer n.Unlock()
+	n.snapshotting = false
+
+	// A catchup that started while copying reads from the log we would remove.
+	if err == nil && len(n.progress) > 0 {
+		err = errCatchupsRunning
+	}
+	if err == nil {
+		err = n.finishStagedWAL(wal, nwal, &before)
+	}
+	if err != nil {
+		if nwal != nil {
+			n.removeLog(nwal)
+		}
+		return err
+	}
+	var after StreamState
+	nwal.FastState(&after)
+	n.wal, n.wtype, n.bytes = nwal, st, after.Bytes
+	n.removeLog(wal)
+	n.debug("Migrated log to %s storage with %d entries [%d:%d]", st, after.Msgs, after.FirstSeq, after.LastSeq)
+	return nil
+}
+
+// newLog creates an empty log of storage type st, a file based one is created
+// within our store directory.
+func (n *raft) newLog(st StorageType) (StreamStore, error) {
+	if st == MemoryStorage {
+		return newMemStore(&StreamConfig{Name: n.group, Storage: MemoryStorage})
+	}
+	s := n.s
+	opts := s.getOpts()
+	return newFileStoreWithCreated(
+		FileStoreConfig{StoreDir: n.sd, BlockSize: defaultMediumBlockSize, SyncAlways: opts.SyncAlways, SyncInterval: opts.SyncInterval, srv: s},
+		StreamConfig{Name: n.group, Storage: FileStorage},
+		time.Now().UTC(),
+		s.jsKeyGen(opts.JetStreamKey, n.group),
+		s.jsKeyGen(opts.JetStreamOldKey, n.group),
+	)
+}
+
+// removeLog stops and removes a log that is no longer used. A file based log shares
+// our store directory, so only its own files are removed.
+func (n *raft) removeLog(wal WAL) {
+	if wal.Type() == MemoryStorage {
+		wal.Delete(true)
+		return
+	}
+	wal.Stop()
+	for _, name := range []string{msgDir, purgeDir, consumerDir, JetStreamMetaFile, JetStreamMetaFileSum, JetStreamMetaFileKey} {
+		os.RemoveAll(filepath.Join(n.sd, name))
+	}
+}
+
 // NeedSnapshot returns true if it is necessary to try to install a snapshot, i.e.
 // after we have finished recovering/replaying at startup, on a regular interval or
 // as a part of cleaning up when shutting down.
@@ -1765,6 +2748,229 @@ func (n *raft) loadLastSnapshot() (*snapshot, error) {
 	return snap, nil
 }
 
+const (
+	raftArchiveMagic   = "NRGA"
+	raftArchiveVersion = 1
+	raftArchiveHdrLen  = len(raftArchiveMagic) + 1 + 8 + 8 + 8 + 4
+)
+
+// raftArchive is the state of a group as exported by ExportState.
+type raftArchive struct {
+	term    uint64
+	snap    *snapshot
+	entries []*appendEntry
+}
+
+// ExportState writes an archive of our state to w, made of our last snapshot and
+// all committed entries in our log past it, so a new group can be bootstrapped
+// with the same state, see bootstrapRaftNodeFromArchive.
+func (n *raft) ExportState(w io.Writer) error {
+	// Only hold the lock to capture what to export, the entries are streamed from
+	// the log after. If the log gets compacted from under us loading them will fail.
+	n.Lock()
+	if n.State() == Closed {
+		n.Unlock()
+		return errNodeClosed
+	}
+	term, commit, wal := n.term, n.commit, n.wal
+	snap, err := n.loadLastSnapshot()
+	n.Unlock()
+	if err != nil && err != errNoSnapAvailable {
+		return err
+	}
+	first := uint64(1)
+	if snap != nil {
+		first = snap.lastIndex + 1
+	}
+	var entries uint64
+	if commit >= first {
+		entries = commit - first + 1
+	}
+
+	// The checksum covers everything written before it.
+	h := sha256.New()
+	bw := bufio.NewWriter(io.MultiWriter(w, h))
+
+	var le = binary.LittleEndian
+	buf := make([]byte, raftArchiveHdrLen)
+	copy(buf, raftArchiveMagic)
+	wi := len(raftArchiveMagic)
+	buf[wi] = raftArchiveVersion
+	le.PutUint64(buf[wi+1:], term)
+	if snap != nil {
+		le.PutUint64(buf[wi+9:], snap.lastTerm)
+		le.PutUint64(buf[wi+17:], snap.lastIndex)
+		le.PutUint32(buf[wi+25:], uint32(len(snap.data)))
+		buf = append(buf, snap.data...)
+	}
+	buf = le.AppendUint64(buf, entries)
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+
+	var smp StoreMsg
+	for index := first; index <= commit; index++ {
+		sm, err := wal.LoadMsg(index, &smp)
+		if err != nil {
+			return fmt.Errorf("raft: could not load %d from WAL: %w", index, err)
+		}
+		buf = le.AppendUint32(buf[:0], uint32(len(sm.msg)))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+		if _, err := bw.Write(sm.msg); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	_, err = w.Write(h.Sum(nil))
+	return err
+}
+
+// decodeRaftArchive decodes an archive written by ExportState.
+func decodeRaftArchive(buf []byte) (*raftArchive, error) {
+	if len(buf) < raftArchiveHdrLen+8+sha256.Size || string(buf[:len(raftArchiveMagic)]) != raftArchiveMagic {
+		return nil, errBadArchive
+	}
+	hoff := len(buf) - sha256.Size
+	if sum := sha256.Sum256(buf[:hoff]); !bytes.Equal(sum[:], buf[hoff:]) {
+		return nil, errBadArchive
+	}
+	buf = buf[:hoff]
+
+	var le = binary.LittleEndian
+	ri := len(raftArchiveMagic)
+	if buf[ri] != raftArchiveVersion {
+		return nil, fmt.Errorf("raft: unsupported archive version %d", buf[ri])
+	}
+	ar := &raftArchive{term: le.Uint64(buf[ri+1:])}
+	if lindex := le.Uint64(buf[ri+17:]); lindex > 0 {
+		ar.snap = &snapshot{lastTerm: le.Uint64(buf[ri+9:]), lastIndex: lindex}
+	}
+	sl := int(le.Uint32(buf[ri+25:]))
+	ri = raftArchiveHdrLen
+	if len(buf)-ri < sl+8 {
+		return nil, errBadArchive
+	}
+	if ar.snap != nil {
+		ar.snap.data = buf[ri : ri+sl]
+	}
+	ri += sl
+	ne := le.Uint64(buf[ri:])
+	ri += 8
+	for i := uint64(0); i < ne; i++ {
+		if len(buf)-ri < 4 {
+			return nil, errBadArchive
+		}
+		el := int(le.Uint32(buf[ri:]))
+		ri += 4
+		if len(buf)-ri < el {
+			return nil, errBadArchive
+		}
+		ae, err := decodeAppendEntry(buf[ri:ri+el], nil, _EMPTY_)
+		if err != nil {
+			return nil, err
+		}
+		ar.entries = append(ar.entries, ae)
+		ri += el
+	}
+	if ri != len(buf) {
+		return nil, errBadArchive
+	}
+	return ar, nil
+}
+
+// This will bootstrap a raftNode from an archive written by ExportState, into an empty store directory and log.
+// The group starts out as a single-node group with ourselves as the only peer, so peers can be added afterwards.
+// Membership changes in the archived log belong to the original group, and are replaced by our own peer state.
+func (s *Server) bootstrapRaftNodeFromArchive(cfg *RaftConfig, r io.Reader) error {
+	if cfg == nil {
+		return errNilCfg
+	}
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	ar, err := decodeRaftArchive(buf)
+	if err != nil {
+		return err
+	}
+	var state StreamState
+	cfg.Log.FastState(&state)
+	if state.LastSeq > 0 {
+		return errLogNotEmpty
+	}
+	s.mu.RLock()
+	if s.sys == nil {
+		s.mu.RUnlock()
+		return ErrNoSysAccount
+	}
+	id := s.sys.shash[:idLen]
+	s.mu.RUnlock()
+
+	if err := s.bootstrapRaftNode(cfg, []string{id}, true); err != nil {
+		return err
+	}
+	ps := encodePeerState(&peerState{[]string{id}, 1, extUndetermined})
+
+	// Our log starts right after the snapshot, if there is one.
+	pindex := uint64(0)
+	if snap := ar.snap; snap != nil {
+		snapDir := filepath.Join(cfg.Store, snapshotsDir)
+		if err := os.MkdirAll(snapDir, defaultDirPerms); err != nil {
+			return fmt.Errorf("could not create snapshots directory - %v", err)
+		}
+		key := sha256.Sum256([]byte(cfg.Name))
+		hh, _ := highwayhash.NewDigest64(key[:])
+		snap.peerstate = ps
+		sfile := filepath.Join(snapDir, fmt.Sprintf(snapFileT, snap.lastTerm, snap.lastIndex))
+		if err := writeFileWithSync(sfile, encodeSnapshot(hh, snap), defaultFilePerms); err != nil {
+			return err
+		}
+		if _, err := cfg.Log.Compact(snap.lastIndex + 1); err != nil {
+			return err
+		}
+		pindex = snap.lastIndex
+	}
+
+	for i, ae := range ar.entries {
+		if ae.pindex != pindex {
+			return errBadArchive
+		}
+		entries := make([]*Entry, 0, len(ae.entries))
+		for _, e := range ae.entries {
+			switch e.Type {
+			case EntryNormal, EntryOldSnapshot, EntrySnapshot:
+				entries = append(entries, e)
+			default:
+				entries = append(entries, newEntry(EntryPeerState, ps))
+			}
+		}
+		// All archived entries were committed, so mark them as such to apply them when replayed.
+		// The commit of the first entry is taken as our starting point, so it can't include itself,
+		// it will be applied along with the next entry or once we become leader.
+		commit := ae.pindex + 1
+		if i == 0 {
+			commit = ae.pindex
+		}
+		nae := newAppendEntry(id, ae.term, commit, ae.pterm, ae.pindex, entries)
+		b, err := nae.encode(nil)
+		if err != nil {
+			return err
+		}
+		if _, _, err := cfg.Log.StoreMsg(_EMPTY_, nil, b, 0); err != nil {
+			return err
+		}
+		pindex++
+	}
+
+	var tv [termLen]byte
+	binary.LittleEndian.PutUint64(tv[:], ar.term)
+	return writeTermVote(cfg.Store, tv[:])
+}
+
 // Leader returns if we are the leader for our group.
 // We use an atomic here now vs acquiring the read lock.
 func (n *raft) Leader() bool {
@@ -1909,9 +3115,67 @@ func (n *raft) Healthy() bool {
 	if n == nil {
 		return false
 	}
-	n.Lock()
-	defer n.Unlock()
-	return n.isCurrent(true)
+	n.Lock()
+	defer n.Unlock()
+	return n.isCurrent(true)
+}
+
+// Stable returns if the group is fully quiesced, meaning we know of a leader,
+// every entry in our log has been committed and the upper layer has applied all
+// of them. Unlike Healthy, making forward progress is not enough.
+func (n *raft) Stable() bool {
+	if n == nil {
+		return false
+	}
+	n.Lock()
+	defer n.Unlock()
+	if n.commit != n.applied {
+		return false
+	}
+	if n.leader == noLeader || n.pindex != n.commit || n.prop.len() > 0 {
+		return false
+	}
+	return n.isCurrent(false)
+}
+
+// PendingEntries returns metadata for the entries in our log that are beyond
+// our commit index, meaning they have been stored but are not committed yet.
+func (n *raft) PendingEntries() []EntryInfo {
+	if n == nil {
+		return nil
+	}
+	n.RLock()
+	defer n.RUnlock()
+	if n.pindex <= n.commit {
+		return nil
+	}
+	isLeader := n.State() == Leader
+	infos := make([]EntryInfo, 0, n.pindex-n.commit)
+	for index := n.commit + 1; index <= n.pindex; index++ {
+		ae, loaded := n.pae[index], false
+		if ae == nil {
+			var err error
+			if ae, err = n.loadEntry(index); err != nil {
+				continue
+			}
+			loaded = true
+		}
+		ei := EntryInfo{Index: index, Term: ae.term, Types: make([]EntryType, 0, len(ae.entries))}
+		for _, e := range ae.entries {
+			ei.Types = append(ei.Types, e.Type)
+		}
+		if isLeader {
+			// Count the leader if it's still part of membership, same as tryCommit.
+			if ei.Acks = len(n.acks[index]); n.peers[n.ID()] != nil {
+				ei.Acks++
+			}
+		}
+		if loaded {
+			ae.returnToPool()
+		}
+		infos = append(infos, ei)
+	}
+	return infos
 }
 
 // HadPreviousLeader indicates if this group ever had a leader.
@@ -1945,7 +3209,7 @@ func (n *raft) Leaderless() bool {
 func (n *raft) selectNextLeader() string {
 	nextLeader, hli := noLeader, uint64(0)
 	for peer, ps := range n.peers {
-		if peer == n.id || ps.li <= hli {
+		if peer == n.id || ps.lrn || ps.li <= hli {
 			continue
 		}
 		hli = ps.li
@@ -1988,7 +3252,7 @@ func (n *raft) StepDown(preferred ...string) error {
 	// If we have a preferred check it first.
 	if maybeLeader != noLeader {
 		var isHealthy bool
-		if ps, ok := n.peers[maybeLeader]; ok {
+		if ps, ok := n.peers[maybeLeader]; ok && !ps.lrn {
 			si, ok := n.s.nodeToInfo.Load(maybeLeader)
 			isHealthy = ok && !si.(nodeInfo).offline && time.Since(ps.ts) < hbInterval*3
 		}
@@ -2001,7 +3265,7 @@ func (n *raft) StepDown(preferred ...string) error {
 	// Make sure not ourselves.
 	if maybeLeader == noLeader {
 		for peer, ps := range n.peers {
-			if peer == n.id {
+			if peer == n.id || ps.lrn {
 				continue
 			}
 			si, ok := n.s.nodeToInfo.Load(peer)
@@ -2091,6 +3355,96 @@ func (n *raft) Progress() (index, commit, applied uint64) {
 	return n.pindex, n.commit, n.applied
 }
 
+// setDurableAckCount changes the number of replicas that must have synced
+// an entry before it is committed, see RaftConfig.DurableAckCount.
+func (n *raft) setDurableAckCount(dack int) {
+	n.Lock()
+	defer n.Unlock()
+	n.dack = dack
+	if dack > 0 {
+		// Our log needs to be synced before we count ourselves again.
+		select {
+		case n.dsc <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// MatchIndexes returns the highest log index each peer has acknowledged,
+// keyed by peer ID, as tracked by the leader. Our own entry is our last index.
+// Returns an error if we are not the leader.
+func (n *raft) MatchIndexes() (map[string]uint64, error) {
+	n.RLock()
+	defer n.RUnlock()
+	if n.State() != Leader {
+		return nil, errNotLeader
+	}
+	mi := make(map[string]uint64, len(n.peers))
+	for id, ps := range n.peers {
+		if id == n.id {
+			mi[id] = n.pindex
+		} else {
+			mi[id] = ps.li
+		}
+	}
+	return mi, nil
+}
+
+// Stats returns the current runtime statistics for this node.
+func (n *raft) Stats() RaftStats {
+	n.RLock()
+	defer n.RUnlock()
+	return RaftStats{
+		ApplyQueueDepth: n.apply.len(),
+		CommitLatency:   n.clat.histogram(),
+		ApplyTime:       n.apdt,
+		ReplayTime:      n.replayTime(),
+
+		ElectionsStarted:     n.els.started,
+		ElectionsWon:         n.els.won,
+		SplitVotes:           n.els.splits,
+		VoteRequestsSent:     n.els.vsent,
+		VoteRequestsReceived: n.els.vrecv,
+
+		SnapshotInstall: n.snapshotInstall(),
+		PinnedWorkers:   int(n.pinned.Load()),
+	}
+}
+
+// Returns a copy of the progress of the last snapshot received from the leader,
+// or nil if none was received. Lock should be held.
+func (n *raft) snapshotInstall() *SnapshotInstallProgress {
+	if n.sip == nil {
+		return nil
+	}
+	sip := *n.sip
+	return &sip
+}
+
+// Updates the progress of installing the leader's snapshot. Lock should be held.
+func (n *raft) trackSnapshotInstall(state SnapshotInstallState) {
+	n.sip.State, n.sip.Updated = state, time.Now().UTC()
+}
+
+// TrackSnapshotCatchup is called by the upper layer while it catches up the data
+// described by the snapshot it's applying from the leader.
+func (n *raft) TrackSnapshotCatchup(received, total uint64) {
+	n.Lock()
+	defer n.Unlock()
+	if sip := n.sip; sip != nil && sip.State == SnapshotInstallApplying {
+		sip.Received, sip.Total, sip.Updated = received, total, time.Now().UTC()
+	}
+}
+
+// electionStats counts elections and vote requests over the lifetime of the node.
+type electionStats struct {
+	started uint64 // Elections started as candidate
+	won     uint64 // Elections won
+	splits  uint64 // Elections that timed out without a winner
+	vsent   uint64 // Vote requests sent
+	vrecv   uint64 // Vote requests received
+}
+
 // Size returns number of entries and total bytes for our WAL.
 func (n *raft) Size() (entries uint64, bytes uint64) {
 	n.RLock()
@@ -2149,6 +3503,8 @@ func (n *raft) Peers() []*Peer {
 			Current: current,
 			Last:    ps.ts,
 			Lag:     lag,
+			Slow:    n.srto > 0 && ps.sto >= n.srmax,
+			Learner: ps.lrn,
 		}
 		peers = append(peers, p)
 	}
@@ -2284,7 +3640,7 @@ func (n *raft) Reset() {
 
 	// Reset peer set to just ourselves; a new leader will fold us back into
 	// the cluster's membership view via processPeerState.
-	n.peers = map[string]*lps{n.id: {time.Time{}, 0, true}}
+	n.peers = map[string]*lps{n.id: {kp: true}}
 	n.removed = nil
 	n.adjustClusterSizeAndQuorum()
 
@@ -2332,9 +3688,61 @@ func (n *raft) subscribe(subject string, cb msgHandler) (*subscription, error) {
 	if n.c == nil {
 		return nil, errNoInternalClient
 	}
+	if n.nfault != nil {
+		cb = n.faultInbound(cb)
+	}
 	return n.s.systemSubscribe(subject, _EMPTY_, false, n.c, cb)
 }
 
+// raftNetworkFault describes artificial latency and loss applied to all of a node's
+// Raft traffic, in both directions. This is only meant for chaos testing.
+type raftNetworkFault struct {
+	delay  time.Duration // Added to every message
+	jitter time.Duration // Random extra delay up to this, messages may be reordered
+	drop   float64       // Probability of dropping a message, between 0 and 1
+}
+
+// setNetworkFault injects the given fault into our send and receive paths,
+// or removes it when nil. Can be changed at any time, but only if faults
+// were enabled in the config, so that production paths don't pay for them.
+func (n *raft) setNetworkFault(f *raftNetworkFault) {
+	if n.nfault == nil {
+		panic("raft: network faults not enabled")
+	}
+	n.nfault.Store(f)
+}
+
+// apply drops the message or calls deliver, after any delay.
+func (f *raftNetworkFault) apply(deliver func()) {
+	if f.drop > 0 && rand.Float64() < f.drop {
+		return
+	}
+	delay := f.delay
+	if f.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(f.jitter)))
+	}
+	if delay <= 0 {
+		deliver()
+		return
+	}
+	time.AfterFunc(delay, deliver)
+}
+
+// faultInbound wraps a subscription callback so injected network faults
+// also apply to the messages we receive.
+func (n *raft) faultInbound(cb msgHandler) msgHandler {
+	return func(sub *subscription, c *client, acc *Account, subject, reply string, msg []byte) {
+		f := n.nfault.Load()
+		if f == nil {
+			cb(sub, c, acc, subject, reply, msg)
+			return
+		}
+		// The message buffer is reused by the caller once we return.
+		msg = copyBytes(msg)
+		f.apply(func() { cb(sub, c, acc, subject, reply, msg) })
+	}
+}
+
 // Lock should be held.
 func (n *raft) unsubscribe(sub *subscription) {
 	if n.c != nil && sub != nil {
@@ -2414,6 +3822,9 @@ func (n *raft) run() {
 	defer s.grWG.Done()
 	defer n.wg.Done()
 
+	// Keep a hot group on the same OS thread for cache locality, if configured.
+	defer n.pinWorker()()
+
 	// We want to wait for some routing to be enabled, so we will wait for
 	// at least a route, leaf or gateway connection to be established before
 	// starting the run loop.
@@ -2729,13 +4140,13 @@ var pePool = sync.Pool{
 // Create a new proposedEntry.
 func newProposedEntry(entry *Entry, reply string) *proposedEntry {
 	pe := pePool.Get().(*proposedEntry)
-	pe.Entry, pe.reply = entry, reply
+	pe.Entry, pe.reply, pe.ts = entry, reply, time.Now().UnixNano()
 	return pe
 }
 
 // Will return this proosed entry.
 func (pe *proposedEntry) returnToPool() {
-	pe.Entry, pe.reply = nil, _EMPTY_
+	pe.Entry, pe.reply, pe.ts = nil, _EMPTY_, 0
 	pePool.Put(pe)
 }
 
@@ -3059,7 +4470,7 @@ func (n *raft) addPeer(peer string) {
 	if lp, ok := n.peers[peer]; !ok {
 		// We are not tracking this one automatically so we need
 		// to bump cluster size.
-		n.peers[peer] = &lps{time.Time{}, 0, true}
+		n.peers[peer] = &lps{kp: true}
 	} else {
 		// Mark as added.
 		lp.kp = true
@@ -3162,7 +4573,22 @@ func (n *raft) runAsLeader() {
 	lq := time.NewTicker(lostQuorumCheck)
 	defer lq.Stop()
 
+	// Only check for slow replicas if a timeout was configured.
+	var srC <-chan time.Time
+	if n.srto > 0 {
+		sr := time.NewTicker(n.srto)
+		defer sr.Stop()
+		srC = sr.C
+	}
+
 	for n.State() == Leader {
+		// Proposals are buffered while quiesced.
+		n.RLock()
+		propc, qrc := n.prop.ch, n.qrc
+		n.RUnlock()
+		if qrc != nil {
+			propc = nil
+		}
 		select {
 		case <-n.s.quitCh:
 			return
@@ -3174,10 +4600,22 @@ func (n *raft) runAsLeader() {
 				n.processAppendEntryResponse(ar)
 			}
 			n.resp.recycle(&ars)
-		case <-n.prop.ch:
+		case <-qrc:
+			// Resumed, buffered proposals are picked up on the next iteration.
+		case <-propc:
+			// We could have been quiesced while waiting, leave them buffered
+			// and signal again so they are picked up once resumed.
+			if n.isQuiesced() {
+				select {
+				case n.prop.ch <- struct{}{}:
+				default:
+				}
+				continue
+			}
 			const maxBatch = 256 * 1024
 			const maxEntries = 512
 			var entries []*Entry
+			var submitted []int64
 
 			es, sz := n.prop.pop(), 0
 			for _, b := range es {
@@ -3186,20 +4624,21 @@ func (n *raft) runAsLeader() {
 					continue
 				}
 				entries = append(entries, b.Entry)
+				submitted = append(submitted, b.ts)
 				// Increment size.
 				sz += len(b.Data) + 1
 				// If below thresholds go ahead and send.
 				if sz < maxBatch && len(entries) < maxEntries {
 					continue
 				}
-				n.sendAppendEntry(entries)
+				n.sendProposals(entries, submitted)
 				// Reset our sz and entries.
 				// We need to re-create `entries` because there is a reference
 				// to it in the node's pae map.
-				sz, entries = 0, nil
+				sz, entries, submitted = 0, nil, nil
 			}
 			if len(entries) > 0 {
-				n.sendAppendEntry(entries)
+				n.sendProposals(entries, submitted)
 			}
 			// Respond to any proposals waiting for a confirmation.
 			for _, pe := range es {
@@ -3219,6 +4658,16 @@ func (n *raft) runAsLeader() {
 				n.stepdown(noLeader)
 				return
 			}
+		case <-srC:
+			n.checkSlowReplicas()
+		case <-n.cgc:
+			if n.retryCommit() && n.prop.len() == 0 {
+				n.sendHeartbeat()
+			}
+		case <-n.dsc:
+			if n.syncLeaderWAL() && n.prop.len() == 0 {
+				n.sendHeartbeat()
+			}
 		case <-n.votes.ch:
 			// Because of drain() it is possible that we get nil from popOne().
 			vresp, ok := n.votes.popOne()
@@ -3256,6 +4705,49 @@ func (n *raft) Quorum() bool {
 	return false
 }
 
+// checkSlowReplicas counts a timeout for every follower that did not store the
+// entries we sent it in time, and takes the slow replica action once a follower
+// timed out too many times in a row.
+func (n *raft) checkSlowReplicas() {
+	n.Lock()
+	defer n.Unlock()
+
+	now := time.Now()
+	for id, ps := range n.peers {
+		if id == n.id || ps.sts.IsZero() || now.Sub(ps.sts) < n.srto {
+			continue
+		}
+		// Start timing the next timeout from here.
+		ps.sts = now
+		if ps.sto++; ps.sto != n.srmax {
+			continue
+		}
+		switch n.sra {
+		case SlowReplicaDemote:
+			n.warn("Replica %q timed out storing entries %d times in a row, demoting to learner", id, ps.sto)
+			ps.lrn = true
+		default:
+			n.warn("Replica %q timed out storing entries %d times in a row", id, ps.sto)
+		}
+	}
+}
+
+// trackReplicaStored updates the slow replica tracking of a peer
+// that has stored entries up to the given index.
+// Lock should be held.
+func (n *raft) trackReplicaStored(peer string, ps *lps, index uint64) {
+	if index < n.pindex {
+		// Still behind, but made progress. The next entry
+		// was sent no later than now.
+		ps.sts = time.Now()
+		return
+	}
+	if ps.lrn {
+		n.debug("Replica %q caught up, no longer a learner", peer)
+	}
+	ps.sts, ps.sto, ps.lrn = time.Time{}, 0, false
+}
+
 func (n *raft) lostQuorum() bool {
 	n.RLock()
 	defer n.RUnlock()
@@ -3510,6 +5002,42 @@ func (n *raft) catchupFollower(ar *appendEntryResponse) {
 	})
 }
 
+// ResyncPeer resets the replication progress we track for the given peer and cancels
+// any running catchup for it. A heartbeat is sent right after, which prompts the peer
+// to report its actual position and request a new catchup from there if it is behind.
+// Only valid on the leader.
+func (n *raft) ResyncPeer(id string) error {
+	n.Lock()
+	if n.State() != Leader {
+		n.Unlock()
+		return errNotLeader
+	}
+	if id == n.id {
+		n.Unlock()
+		return errResyncSelf
+	}
+	ps := n.peers[id]
+	if ps == nil {
+		n.Unlock()
+		return errPeerNotFound
+	}
+	n.debug("Resyncing peer %q, resetting last replicated index %d", id, ps.li)
+	ps.li = 0
+	if q, ok := n.progress[id]; ok {
+		n.debug("Will cancel existing entry for catching up %q", id)
+		delete(n.progress, id)
+		if len(n.progress) == 0 {
+			n.progress = nil
+		}
+		// Signal the catchup to finish.
+		q.push(n.pindex + 1)
+	}
+	n.Unlock()
+
+	n.sendHeartbeat()
+	return nil
+}
+
 func (n *raft) loadEntry(index uint64) (*appendEntry, error) {
 	var smp StoreMsg
 	sm, err := n.wal.LoadMsg(index, &smp)
@@ -3533,6 +5061,10 @@ func (n *raft) applyCommit(index uint64) error {
 	if n.State() == Leader {
 		delete(n.acks, index)
 	}
+	if submitted, ok := n.pts[index]; ok {
+		n.clat.record(time.Now(), submitted)
+		delete(n.pts, index)
+	}
 
 	ae := n.pae[index]
 	if ae == nil {
@@ -3567,6 +5099,9 @@ func (n *raft) applyCommit(index uint64) error {
 		// states). In which case the upper layer will just call down with
 		// Applied() with no further action.
 		n.apply.push(newCommittedEntry(index, committed))
+		if n.apst.IsZero() {
+			n.apst = time.Now()
+		}
 		// Place back in the pool.
 		ae.returnToPool()
 	}()
@@ -3603,6 +5138,7 @@ func (n *raft) applyCommit(index uint64) error {
 			peers.LoadOrStore(newPeer, newPeer)
 
 			n.addPeer(newPeer)
+			n.recordConfigChange(e.Type, newPeer, ae.term, index)
 
 			// We pass these up as well.
 			committed = append(committed, e)
@@ -3615,6 +5151,7 @@ func (n *raft) applyCommit(index uint64) error {
 			n.debug("Removing peer %q", peer)
 
 			n.removePeer(peer)
+			n.recordConfigChange(e.Type, peer, ae.term, index)
 
 			// Remove from string intern map.
 			peers.Delete(peer)
@@ -3641,15 +5178,23 @@ func (n *raft) applyCommit(index uint64) error {
 // Lock should be held.
 func (n *raft) tryCommit(index uint64) (bool, error) {
 	acks := len(n.acks[index])
-	// Count the leader if it's still part of membership
-	if n.peers[n.ID()] != nil {
+	// Count the leader if it's still part of membership,
+	// and has synced the entry if durable acks are required.
+	if n.peers[n.ID()] != nil && (n.dack == 0 || index <= n.dsynced) {
 		acks += 1
 	}
 	if acks < n.qn {
 		return false, nil
 	}
+	// If durable acks are required, every ack counted above was sent after a sync.
+	if n.dack > 0 && acks < min(n.dack, n.csz) {
+		return false, nil
+	}
 	// We have a quorum
 	for i := n.commit + 1; i <= index; i++ {
+		if n.cgate != nil && !n.commitGateOpen(i) {
+			return false, nil
+		}
 		if err := n.applyCommit(i); err != nil {
 			if err != errNodeClosed && err != errNodeRemoved {
 				n.error("Got an error applying commit for %d: %v", i, err)
@@ -3660,6 +5205,126 @@ func (n *raft) tryCommit(index uint64) (bool, error) {
 	return true, nil
 }
 
+// commitGateOpen returns whether the commit gate allows committing index. If it wasn't
+// consulted for index yet, it is from its own goroutine and the commit is held back until
+// it opens. Lock should be held.
+func (n *raft) commitGateOpen(index uint64) bool {
+	if n.cgidx == index {
+		if !n.cgok {
+			return false
+		}
+		n.cgidx, n.cgok = 0, false
+		return true
+	}
+
+	// Copy the entries, the append entry is returned to the pool once committed.
+	var entries []*Entry
+	ae := n.pae[index]
+	if ae == nil {
+		var err error
+		if ae, err = n.loadEntry(index); err != nil {
+			return false
+		}
+		defer ae.returnToPool()
+	}
+	for _, e := range ae.entries {
+		entries = append(entries, newEntry(e.Type, copyBytes(e.Data)))
+	}
+
+	n.cgidx, n.cgok = index, false
+	gate, timeout, term := n.cgate, n.cgto, n.term
+	go func() {
+		// Whether the gate is still consulted for index, as we're leader of the same term.
+		current := func(ok bool) bool {
+			n.Lock()
+			defer n.Unlock()
+			if n.cgidx != index || n.term != term || n.State() != Leader {
+				return false
+			}
+			n.cgok = ok
+			return true
+		}
+		// Only a single call of the gate is in flight for index. When it doesn't return
+		// before the timeout it's waited on further, instead of consulting the gate again.
+		done, inflight := make(chan bool, 1), true
+		go func() { done <- gate(index, entries) }()
+		for {
+			var ok bool
+			select {
+			case ok = <-done:
+				inflight = false
+			case <-time.After(timeout):
+			case <-n.quit:
+				return
+			}
+			if !current(ok) {
+				return
+			}
+			if ok {
+				select {
+				case n.cgc <- struct{}{}:
+				default:
+				}
+				return
+			}
+			if inflight {
+				continue
+			}
+			// Consult the gate again after the timeout.
+			select {
+			case <-time.After(timeout):
+			case <-n.quit:
+				return
+			}
+			if !current(false) {
+				return
+			}
+			inflight = true
+			go func() { done <- gate(index, entries) }()
+		}
+	}()
+	return false
+}
+
+// syncLeaderWAL syncs our log outside of the lock when durable acks are required,
+// after which the leader counts itself towards the acks of the entries stored so far.
+// Returns whether anything was committed.
+func (n *raft) syncLeaderWAL() bool {
+	n.RLock()
+	index, wal := n.pindex, n.wal
+	n.RUnlock()
+
+	if err := n.syncWAL(wal); err != nil {
+		n.Lock()
+		n.setWriteErrLocked(err)
+		n.Unlock()
+		return false
+	}
+	n.Lock()
+	if index > n.dsynced {
+		n.dsynced = index
+	}
+	n.Unlock()
+	return n.retryCommit()
+}
+
+// retryCommit commits up to the highest index that has a quorum, once the commit gate opened.
+// Returns whether anything was committed.
+func (n *raft) retryCommit() bool {
+	n.Lock()
+	defer n.Unlock()
+	if n.State() != Leader {
+		return false
+	}
+	commit := n.commit
+	for index := n.pindex; index > n.commit; index-- {
+		if ok, err := n.tryCommit(index); ok || err != nil {
+			break
+		}
+	}
+	return n.commit > commit
+}
+
 // Used to track a success response. Returns true if the
 // response was tracked, false if the response was ignored
 // (the response is old, the index is already committed, ...)
@@ -3675,6 +5340,9 @@ func (n *raft) trackResponse(ar *appendEntryResponse) bool {
 	// Update peer's last index.
 	if ps != nil && ar.index > ps.li {
 		ps.li = ar.index
+		if n.srto > 0 {
+			n.trackReplicaStored(ar.peer, ps, ar.index)
+		}
 	}
 
 	// If we are tracking this peer as a catchup follower, update that here.
@@ -3720,7 +5388,7 @@ func (n *raft) adjustClusterSizeAndQuorum() {
 		}
 	}
 	n.csz = ncsz
-	n.qn = n.csz/2 + 1
+	n.qn = n.quorumFor(n.csz)
 
 	if ncsz > pcsz {
 		n.debug("Expanding our clustersize: %d -> %d", pcsz, ncsz)
@@ -3796,6 +5464,10 @@ func (n *raft) runAsCandidate() {
 		case <-n.quit:
 			return
 		case <-elect.C:
+			// Nobody won this election, start a new one.
+			n.Lock()
+			n.els.splits++
+			n.Unlock()
 			n.switchToCandidate()
 			return
 		case <-n.votes.ch:
@@ -3855,12 +5527,38 @@ func (n *raft) runAsCandidate() {
 func (n *raft) handleAppendEntry(sub *subscription, c *client, _ *Account, _, reply string, msg []byte) {
 	msg = copyBytes(msg)
 	if ae, err := decodeAppendEntry(msg, sub, reply); err == nil {
+		if n.aedf.Load() > 0 {
+			n.aedf.Store(0)
+		}
 		// Push to the new entry channel. From here one of the worker
 		// goroutines (runAsLeader, runAsFollower, runAsCandidate) will
 		// pick it up.
 		n.entry.push(ae)
 	} else {
 		n.warn("AppendEntry failed to be placed on internal channel: corrupt entry")
+		n.RLock()
+		aedfMax := n.aedfMax
+		n.RUnlock()
+		if fails := n.aedf.Add(1); aedfMax > 0 && fails >= int64(aedfMax) {
+			n.aedf.Store(0)
+			n.resetAppendEntrySubs(fails)
+		}
+	}
+}
+
+// resetAppendEntrySubs will tear down and recreate our internal subscriptions
+// after too many consecutive append entries failed to decode.
+func (n *raft) resetAppendEntrySubs(fails int64) {
+	n.Lock()
+	defer n.Unlock()
+	if n.State() == Closed {
+		return
+	}
+	n.warn("Resetting replication subscriptions after %d consecutive corrupt append entries", fails)
+	// Clearing this forces the subscriptions and internal client to be recreated.
+	n.aesub = nil
+	if err := n.recreateInternalSubsLocked(); err != nil {
+		n.warn("Error recreating replication subscriptions: %v", err)
 	}
 }
 
@@ -4354,6 +6052,15 @@ func (n *raft) processAppendEntry(ae *appendEntry, sub *subscription) {
 				return
 			}
 
+			now := time.Now().UTC()
+			n.sip = &SnapshotInstallProgress{
+				State:   SnapshotInstallReceiving,
+				Index:   ae.pindex,
+				Size:    uint64(len(ae.entries[0].Data)),
+				Started: now,
+				Updated: now,
+			}
+
 			// Inherit state from appendEntry with the leader's snapshot.
 			hadPreviousSnapshot := n.snapfile != _EMPTY_
 
@@ -4365,6 +6072,7 @@ func (n *raft) processAppendEntry(ae *appendEntry, sub *subscription) {
 			}
 			// Install the leader's snapshot as our own.
 			if err := n.installSnapshot(snap); err != nil {
+				n.trackSnapshotInstall(SnapshotInstallFailed)
 				n.setWriteErrLocked(err)
 				n.Unlock()
 				return
@@ -4380,6 +6088,7 @@ func (n *raft) processAppendEntry(ae *appendEntry, sub *subscription) {
 				n.sendCatchupSignal()
 			}
 			// Now send snapshot to upper levels. Only send the snapshot, not the peerstate entry.
+			n.trackSnapshotInstall(SnapshotInstallApplying)
 			n.apply.push(newCommittedEntry(n.commit, ae.entries[:1]))
 			if hadPreviousSnapshot {
 				// Signal catchup only after we've sent the snapshot. That ensures the upper-layer processes the snapshot
@@ -4447,7 +6156,7 @@ CONTINUE:
 			if newPeer := string(e.Data); len(newPeer) == idLen {
 				// Track directly, but wait for commit to be official
 				if _, ok := n.peers[newPeer]; !ok {
-					n.peers[newPeer] = &lps{time.Time{}, 0, false}
+					n.peers[newPeer] = &lps{}
 				}
 				// Store our peer in our global peer map for all peers.
 				peers.LoadOrStore(newPeer, newPeer)
@@ -4488,10 +6197,21 @@ CONTINUE:
 	if sub != nil && isNew {
 		ar = newAppendEntryResponse(n.pterm, n.pindex, n.id, true)
 	}
+	durable, wal := n.dack > 0, n.wal
 	n.Unlock()
 
 	// Success. Send our response.
 	if ar != nil {
+		// If durable acks are required, only ack once the entry is synced to disk.
+		if durable {
+			if err := n.syncWAL(wal); err != nil {
+				n.Lock()
+				n.setWriteErrLocked(err)
+				n.Unlock()
+				arPool.Put(ar)
+				return
+			}
+		}
 		n.sendRPC(aeReply, _EMPTY_, ar.encode(arbuf))
 		arPool.Put(ar)
 	}
@@ -4515,7 +6235,7 @@ func (n *raft) processPeerState(ps *peerState) {
 	// Update our version of peers to that of the leader. Calculate
 	// the number of nodes needed to establish a quorum.
 	n.csz = ps.clusterSize
-	n.qn = n.csz/2 + 1
+	n.qn = n.quorumFor(n.csz)
 
 	old := n.peers
 	n.peers = make(map[string]*lps)
@@ -4525,7 +6245,7 @@ func (n *raft) processPeerState(ps *peerState) {
 			n.peers[peer] = lp
 			delete(old, peer)
 		} else {
-			n.peers[peer] = &lps{time.Time{}, 0, true}
+			n.peers[peer] = &lps{kp: true}
 		}
 		// If we were on the removed list reverse that here.
 		if n.removed != nil {
@@ -4625,6 +6345,30 @@ func (ae *appendEntry) shouldStore() bool {
 	return ae != nil && len(ae.entries) > 0
 }
 
+// syncWAL syncs the stored entries of wal to disk, if the log supports it. The log can be
+// swapped by a compaction or migration while syncing, which moved its entries to the new log,
+// so a failure to sync the old one is harmless and the new one is synced instead.
+// Lock should not be held.
+func (n *raft) syncWAL(wal WAL) error {
+	for {
+		ws, ok := wal.(walSyncer)
+		if !ok {
+			return nil
+		}
+		err := ws.Sync()
+		if err == nil {
+			return nil
+		}
+		n.RLock()
+		cur := n.wal
+		n.RUnlock()
+		if cur == wal {
+			return err
+		}
+		wal = cur
+	}
+}
+
 // Store our append entry to our WAL.
 // lock should be held.
 func (n *raft) storeToWAL(ae *appendEntry) error {
@@ -4678,6 +6422,23 @@ func (n *raft) sendAppendEntry(entries []*Entry) {
 	n.sendAppendEntryLocked(entries, true)
 }
 
+// sendProposals sends the proposed entries and keeps track of when they
+// were submitted to measure how long it takes to commit them.
+func (n *raft) sendProposals(entries []*Entry, submitted []int64) {
+	n.Lock()
+	defer n.Unlock()
+	index := n.pindex + 1
+	if err := n.sendAppendEntryLocked(entries, true); err != nil {
+		return
+	}
+	// A single node group commits right away.
+	if n.commit >= index {
+		n.clat.record(time.Now(), submitted)
+	} else {
+		n.pts[index] = submitted
+	}
+}
+
 // Returns nil if an appendEntry was appended to our WAL and sent to followers,
 // an error otherwise.
 func (n *raft) sendAppendEntryLocked(entries []*Entry, checkLeader bool) error {
@@ -4704,10 +6465,25 @@ func (n *raft) sendAppendEntryLocked(entries []*Entry, checkLeader bool) error {
 		}
 		n.active = time.Now()
 		n.cachePendingEntry(ae)
+		// Followers that were up to date now have entries to store.
+		if n.srto > 0 {
+			for id, ps := range n.peers {
+				if id != n.id && ps.sts.IsZero() {
+					ps.sts = n.active
+				}
+			}
+		}
 	}
 	n.sendRPC(n.asubj, n.areply, ae.buf)
 	if !shouldStore {
 		ae.returnToPool()
+	} else if n.dack > 0 {
+		// The leader only counts itself towards the durable acks once synced,
+		// which is done outside of the lock.
+		select {
+		case n.dsc <- struct{}{}:
+		default:
+		}
 	}
 	if n.csz == 1 {
 		n.tryCommit(n.pindex)
@@ -5071,6 +6847,7 @@ func (n *raft) processVoteRequest(vr *voteRequest) error {
 	n.debug("Received a voteRequest %+v", vr)
 
 	n.Lock()
+	n.els.vrecv++
 
 	vresp := &voteResponse{n.term, n.id, false, n.pindex == 0}
 	defer n.debug("Sending a voteResponse %+v -> %q", vresp, vr.reply)
@@ -5148,6 +6925,7 @@ func (n *raft) requestVote() {
 	n.writeTermVote()
 	vr := voteRequest{n.term, n.pterm, n.pindex, n.id, _EMPTY_}
 	subj, reply := n.vsubj, n.vreply
+	n.els.vsent++
 	n.Unlock()
 
 	n.debug("Sending out voteRequest %+v", vr)
@@ -5158,20 +6936,39 @@ func (n *raft) requestVote() {
 
 func (n *raft) sendRPC(subject, reply string, msg []byte) {
 	if n.sq != nil {
+		if n.nfault == nil {
+			n.sq.send(subject, reply, nil, msg)
+			return
+		}
+		if f := n.nfault.Load(); f != nil {
+			// Buffers may be reused once we return, so copy for delayed sends.
+			sq, msg := n.sq, copyBytes(msg)
+			f.apply(func() { sq.send(subject, reply, nil, msg) })
+			return
+		}
 		n.sq.send(subject, reply, nil, msg)
 	}
 }
 
 func (n *raft) sendReply(subject string, msg []byte) {
-	if n.sq != nil {
-		n.sq.send(subject, _EMPTY_, nil, msg)
-	}
+	n.sendRPC(subject, _EMPTY_, msg)
 }
 
 func (n *raft) wonElection(votes int) bool {
 	return votes >= n.quorumNeeded()
 }
 
+// Returns the number of nodes needed for a quorum in a group of the given size.
+// The quorum size override is only used when it is below a majority.
+// Lock should be held.
+func (n *raft) quorumFor(csz int) int {
+	qn := csz/2 + 1
+	if n.qovr > 0 && n.qovr < qn {
+		return n.qovr
+	}
+	return qn
+}
+
 // Return the quorum size for a given cluster config.
 func (n *raft) quorumNeeded() int {
 	n.RLock()
@@ -5263,6 +7060,14 @@ func (n *raft) switchToFollowerLocked(leader string) {
 	if len(n.acks) > 0 {
 		n.acks = make(map[uint64]map[string]struct{})
 	}
+	// Proposals are only timed by the leader that received them.
+	if len(n.pts) > 0 {
+		n.pts = make(map[uint64][]int64)
+	}
+	// Same for slow replicas, a new leader starts tracking from scratch.
+	for _, ps := range n.peers {
+		ps.sts, ps.sto, ps.lrn = time.Time{}, 0, false
+	}
 	n.updateLeader(leader)
 	n.switchState(Follower)
 }
@@ -5294,6 +7099,7 @@ func (n *raft) switchToCandidate() {
 	// Increment the term.
 	n.term++
 	n.vote = noVote
+	n.els.started++
 	// Reset quorum paused. If it was previously set, we checked above that we've applied all committed entries.
 	n.quorumPaused = false
 	// Clear current Leader.
@@ -5311,9 +7117,14 @@ func (n *raft) switchToLeader() {
 
 	n.debug("Switching to leader")
 
+	n.els.won++
 	n.lxfer = false
 	n.updateLeader(n.id)
 	n.switchState(Leader)
+	// The commit gate needs to be consulted again for entries of this term.
+	n.cgidx, n.cgok = 0, false
+	// Our log needs to be synced again for entries of this term.
+	n.dsynced = 0
 
 	// To send out our initial peer state.
 	// In our implementation this is equivalent to sending a NOOP-entry upon becoming leader.
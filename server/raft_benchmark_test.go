@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -121,3 +122,73 @@ func BenchmarkWriteTermVote(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkNRGHotGroupDedicatedWorker(b *testing.B) {
+	const batch = 100
+
+	for _, dedicated := range []bool{false, true} {
+		b.Run(fmt.Sprintf("dedicated=%v", dedicated), func(b *testing.B) {
+			c := createJetStreamClusterExplicit(b, "R3S", 3)
+			defer c.shutdown()
+
+			var rg smGroup
+			peers := serverPeerNames(c.servers)
+			for _, s := range c.servers {
+				cfg := &RaftConfig{Name: "HOT", Store: b.TempDir(), Log: c.createWAL("HOT", FileStorage), DedicatedWorker: dedicated}
+				rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+			}
+			leader := rg.waitOnLeader().(*stateAdder)
+
+			// Keep the scheduler busy with other work, like other groups would on a busy server.
+			quit := make(chan struct{})
+			var wg sync.WaitGroup
+			for range max(runtime.GOMAXPROCS(0)/2, 1) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						select {
+						case <-quit:
+							return
+						default:
+							runtime.Gosched()
+						}
+					}
+				}()
+			}
+			defer func() {
+				close(quit)
+				wg.Wait()
+			}()
+
+			// Measure the throughput of every batch of proposals, to see how stable it is.
+			var minRate, maxRate float64
+			b.ResetTimer()
+			for total := 0; total < b.N; {
+				n := min(batch, b.N-total)
+				start := time.Now()
+				for range n {
+					leader.proposeDelta(1)
+				}
+				total += n
+				for leader.total() < int64(total) {
+					time.Sleep(10 * time.Microsecond)
+				}
+				rate := float64(n) / time.Since(start).Seconds()
+				if minRate == 0 || rate < minRate {
+					minRate = rate
+				}
+				maxRate = max(maxRate, rate)
+			}
+			b.StopTimer()
+
+			b.ReportMetric(minRate, "min-entries/s")
+			b.ReportMetric(maxRate, "max-entries/s")
+			if elapsed := b.Elapsed(); elapsed > 0 {
+				b.ReportMetric(float64(b.N)/elapsed.Seconds(), "entries/s")
+			}
+			clat := leader.node().Stats().CommitLatency
+			b.ReportMetric(float64(clat.Max.Microseconds()), "max-commit-latency-us")
+		})
+	}
+}
@@ -97,6 +97,13 @@ func (sg smGroup) nonLeader() stateMachine {
 	return nil
 }
 
+// Inject the network fault into all nodes, nil removes it.
+func (sg smGroup) setNetworkFault(f *raftNetworkFault) {
+	for _, sm := range sg {
+		sm.node().(*raft).setNetworkFault(f)
+	}
+}
+
 // Take out the lock on all nodes.
 func (sg smGroup) lockAll() {
 	for _, sm := range sg {
@@ -143,6 +150,28 @@ func (c *cluster) createRaftGroupEx(name string, numMembers int, smf smFactory,
 	return c.createRaftGroupWithPeers(name, servers[:numMembers], smf, st)
 }
 
+// Same as createRaftGroup, but network faults can be injected with setNetworkFault.
+func (c *cluster) createFaultyRaftGroup(name string, numMembers int, smf smFactory) smGroup {
+	c.t.Helper()
+	if numMembers > len(c.servers) {
+		c.t.Fatalf("Members > Peers: %d vs  %d", numMembers, len(c.servers))
+	}
+	servers := append([]*Server{}, c.servers...)
+	rand.Shuffle(len(servers), func(i, j int) { servers[i], servers[j] = servers[j], servers[i] })
+
+	var sg smGroup
+	peers := serverPeerNames(servers[:numMembers])
+	for _, s := range servers[:numMembers] {
+		cfg := &RaftConfig{
+			Name:   name,
+			Store:  c.t.TempDir(),
+			Log:    c.createWAL(name, FileStorage),
+			faults: true}
+		sg = append(sg, c.createStateMachine(s, cfg, peers, smf))
+	}
+	return sg
+}
+
 func (c *cluster) createWAL(name string, st StorageType) WAL {
 	c.t.Helper()
 	var err error
@@ -236,6 +265,7 @@ func smLoop(sm stateMachine) {
 	// Wait group used to allow waiting until we exit from here.
 	wg.Add(1)
 	defer wg.Done()
+	defer n.PinApplyWorker()()
 
 	for {
 		select {
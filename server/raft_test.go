@@ -16,6 +16,7 @@ package server
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -27,6 +28,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -452,7 +454,7 @@ func TestNRGSwitchStateClearsQueues(t *testing.T) {
 	require_Equal(t, n.prop.len(), 0)
 	require_Equal(t, n.resp.len(), 0)
 
-	n.prop.push(&proposedEntry{&Entry{}, _EMPTY_})
+	n.prop.push(&proposedEntry{&Entry{}, _EMPTY_, 0})
 	n.resp.push(&appendEntryResponse{})
 	require_Equal(t, n.prop.len(), 1)
 	require_Equal(t, n.resp.len(), 1)
@@ -2959,6 +2961,70 @@ func TestNRGSnapshotCatchup(t *testing.T) {
 	t.Run("with-restart", func(t *testing.T) { test(t, true) })
 }
 
+func TestNRGSnapshotInstallProgress(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	rg.waitOnLeader()
+
+	l := rg.leader().(*stateAdder)
+	var s1 *stateAdder
+	for _, sm := range rg {
+		if sm != l {
+			s1 = sm.(*stateAdder)
+			break
+		}
+	}
+	require_True(t, s1.node().Stats().SnapshotInstall == nil)
+
+	// Stop one non-leader server.
+	s1.stop()
+	l.proposeDelta(10)
+	rg.waitOnTotal(t, 10)
+
+	// Install a large snapshot, padded after the total, so the stopped server needs to catchup based on it.
+	data := make([]byte, binary.MaxVarintLen64+2*1024*1024)
+	binary.PutVarint(data, 10)
+	require_NoError(t, l.node().InstallSnapshot(data, false))
+
+	// Propose a new entry so the stopped server is behind the snapshot.
+	l.proposeDelta(10)
+	rg.waitOnTotal(t, 20)
+
+	s1.restart()
+
+	rank := map[SnapshotInstallState]int{
+		SnapshotInstallReceiving: 1,
+		SnapshotInstallApplying:  2,
+		SnapshotInstallComplete:  3,
+	}
+	var last SnapshotInstallProgress
+	checkFor(t, 10*time.Second, time.Millisecond, func() error {
+		sip := s1.node().Stats().SnapshotInstall
+		if sip == nil {
+			return errors.New("no snapshot install yet")
+		}
+		if sip.State == SnapshotInstallFailed {
+			t.Fatalf("Snapshot install failed: %+v", sip)
+		}
+		// Progress should only ever move forward.
+		if rank[sip.State] < rank[last.State] || sip.Received < last.Received || sip.Updated.Before(last.Updated) {
+			t.Fatalf("Snapshot install progress went backwards from %+v to %+v", last, *sip)
+		}
+		last = *sip
+		if sip.State != SnapshotInstallComplete {
+			return fmt.Errorf("snapshot install is %s", sip.State)
+		}
+		return nil
+	})
+	require_Equal(t, last.Size, uint64(len(data)))
+	require_Equal(t, last.Received, last.Total)
+	require_True(t, last.Index > 0)
+	require_False(t, last.Started.After(last.Updated))
+	rg.waitOnTotal(t, 20)
+}
+
 func TestNRGSnapshotRecovery(t *testing.T) {
 	n, cleanup := initSingleMemRaftNode(t)
 	defer cleanup()
@@ -6235,7 +6301,7 @@ func TestNRGReset(t *testing.T) {
 
 	// Add another peer in addition to ourselves.
 	other := nats1
-	n.peers[other] = &lps{time.Time{}, 0, true}
+	n.peers[other] = &lps{kp: true}
 	n.adjustClusterSizeAndQuorum()
 	n.updateLeader(other)
 
@@ -6250,7 +6316,7 @@ func TestNRGReset(t *testing.T) {
 	n.snapfile = sfile
 
 	// Push something onto each queue so we can verify they are drained.
-	_, err := n.prop.push(&proposedEntry{&Entry{}, _EMPTY_})
+	_, err := n.prop.push(&proposedEntry{&Entry{}, _EMPTY_, 0})
 	require_NoError(t, err)
 	_, err = n.entry.push(&appendEntry{})
 	require_NoError(t, err)
@@ -6404,3 +6470,1731 @@ func TestNRGBootstrapExpectedClusterSize(t *testing.T) {
 		})
 	}
 }
+
+func TestNRGResyncPeer(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+	require_NotNil(t, leader)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	ln := leader.node().(*raft)
+	sm := rg.nonLeader().(*stateAdder)
+	fid := sm.node().ID()
+
+	// Only the leader can resync, and only known peers.
+	require_Error(t, sm.node().ResyncPeer(ln.ID()), errNotLeader)
+	require_Error(t, ln.ResyncPeer(ln.ID()), errResyncSelf)
+	require_Error(t, ln.ResyncPeer("ABCDEFGH"), errPeerNotFound)
+
+	peerLag := func() (bool, uint64) {
+		for _, p := range ln.Peers() {
+			if p.ID == fid {
+				return p.Current, p.Lag
+			}
+		}
+		t.Fatalf("Peer %q not found", fid)
+		return false, 0
+	}
+
+	// Stop the follower and let it fall behind.
+	sm.stop()
+	for range 100 {
+		leader.proposeDelta(1)
+	}
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if total := leader.total(); total != 101 {
+			return fmt.Errorf("leader total %d not yet at 101", total)
+		}
+		return nil
+	})
+
+	// Artificially stall our progress tracking for the follower, claiming it's up-to-date
+	// and that a catchup is already in progress.
+	ln.Lock()
+	ln.peers[fid].li = ln.pindex
+	if ln.progress == nil {
+		ln.progress = make(map[string]*ipQueue[uint64])
+	}
+	ln.progress[fid] = newIPQueue[uint64](ln.s, "stalled")
+	ln.Unlock()
+	current, lag := peerLag()
+	require_True(t, current)
+	require_Equal(t, lag, 0)
+
+	// Resync should reset what we track and cancel the stalled catchup.
+	require_NoError(t, ln.ResyncPeer(fid))
+	ln.RLock()
+	_, ok := ln.progress[fid]
+	li := ln.peers[fid].li
+	ln.RUnlock()
+	require_False(t, ok)
+	require_Equal(t, li, 0)
+	current, lag = peerLag()
+	require_False(t, current)
+	require_True(t, lag > 0)
+
+	// Once the follower is back it should be caught up and tracked properly again.
+	sm.restart()
+	rg.waitOnTotal(t, 101)
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		if current, lag := peerLag(); !current || lag != 0 {
+			return fmt.Errorf("peer not current yet, lag is %d", lag)
+		}
+		return nil
+	})
+}
+
+func TestNRGStatsApplyQueueDepth(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createMemRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+	require_NotNil(t, leader)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	sm := rg.nonLeader().(*stateAdder)
+	n := sm.node()
+	require_Equal(t, n.Stats().ApplyQueueDepth, 0)
+
+	// Simulate a slow apply by blocking the state machine, the driver will
+	// be stuck applying and committed entries will pile up in the apply queue.
+	sm.Lock()
+	locked := true
+	defer func() {
+		if locked {
+			sm.Unlock()
+		}
+	}()
+	waitCommitted := func(total int64) {
+		t.Helper()
+		checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+			if lt := leader.total(); lt != total {
+				return fmt.Errorf("leader total %d not yet at %d", lt, total)
+			}
+			lindex, _, _ := leader.node().Progress()
+			if _, commit, _ := n.Progress(); commit != lindex {
+				return fmt.Errorf("follower commit %d not yet at %d", commit, lindex)
+			}
+			return nil
+		})
+	}
+	// The first one will be taken by the driver, which then blocks applying it.
+	leader.proposeDelta(1)
+	waitCommitted(2)
+	last := n.Stats().ApplyQueueDepth
+	for i := range 5 {
+		leader.proposeDelta(1)
+		waitCommitted(int64(i + 3))
+		checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+			if depth := n.Stats().ApplyQueueDepth; depth <= last {
+				return fmt.Errorf("apply queue depth %d did not grow beyond %d on iteration %d", depth, last, i)
+			}
+			return nil
+		})
+		last = n.Stats().ApplyQueueDepth
+	}
+	sm.Unlock()
+	locked = false
+
+	rg.waitOnTotal(t, 7)
+	checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+		if depth := n.Stats().ApplyQueueDepth; depth != 0 {
+			return fmt.Errorf("apply queue depth still %d", depth)
+		}
+		return nil
+	})
+}
+
+func TestNRGStatsCommitLatency(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createMemRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+	require_NotNil(t, leader)
+
+	checkHistogram := func(h LatencyHistogram, count uint64) {
+		t.Helper()
+		require_Equal(t, h.Count, count)
+		require_Len(t, len(h.Counts), len(h.Bounds)+1)
+		var total uint64
+		for _, c := range h.Counts {
+			total += c
+		}
+		require_Equal(t, total, count)
+		if count > 0 {
+			require_True(t, h.Max > 0 && h.Sum >= h.Max)
+			require_True(t, h.Sum <= time.Duration(count)*h.Max)
+		}
+	}
+
+	// Propose a batch, every proposal is timed by the leader.
+	for range 100 {
+		leader.proposeDelta(1)
+	}
+	rg.waitOnTotal(t, 100)
+	h := leader.node().Stats().CommitLatency
+	checkHistogram(h, 100)
+	// Nothing should be anywhere close to the last bucket.
+	require_Equal(t, h.Counts[len(h.Counts)-1], 0)
+	require_True(t, h.Max < time.Second)
+	for _, sm := range rg.followers() {
+		checkHistogram(sm.node().Stats().CommitLatency, 0)
+	}
+
+	// Commit latency does not include the time spent applying,
+	// so blocking the leader's state machine does not affect it.
+	n := leader.node()
+	leader.Lock()
+	locked := true
+	defer func() {
+		if locked {
+			leader.Unlock()
+		}
+	}()
+	n.Propose([]byte{2})
+	checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+		if count := n.Stats().CommitLatency.Count; count != 101 {
+			return fmt.Errorf("expected 101 samples, got %d", count)
+		}
+		return nil
+	})
+	leader.Unlock()
+	locked = false
+	rg.waitOnTotal(t, 101)
+
+	// Slow followers show up in the commit latency.
+	for _, sm := range rg.followers() {
+		rn := sm.node().(*raft)
+		rn.Lock()
+		time.AfterFunc(100*time.Millisecond, rn.Unlock)
+	}
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 102)
+	h = n.Stats().CommitLatency
+	checkHistogram(h, 102)
+	require_True(t, h.Max >= 50*time.Millisecond)
+}
+
+func TestNRGAppendEntryDecodeFailuresResetSubs(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createMemRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+	require_NotNil(t, leader)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	ln := leader.node().(*raft)
+	var followers []*raft
+	for _, sm := range rg.followers() {
+		followers = append(followers, sm.node().(*raft))
+	}
+	require_Len(t, len(followers), 2)
+
+	aesub := func(n *raft) *subscription {
+		n.RLock()
+		defer n.RUnlock()
+		return n.aesub
+	}
+	corrupt := func(n *raft, count int) {
+		sub := aesub(n)
+		for range count {
+			n.handleAppendEntry(sub, nil, nil, _EMPTY_, _EMPTY_, []byte("corrupt"))
+		}
+	}
+
+	// Without a threshold corrupt entries are only dropped.
+	dropper := followers[0]
+	osub := aesub(dropper)
+	corrupt(dropper, 10)
+	require_True(t, aesub(dropper) == osub)
+
+	// With a threshold only consecutive failures count.
+	fn := followers[1]
+	fn.Lock()
+	fn.aedfMax = 3
+	fn.Unlock()
+	osub = aesub(fn)
+	corrupt(fn, 2)
+	ln.RLock()
+	hb, err := ln.buildAppendEntry(nil).encode(nil)
+	ln.RUnlock()
+	require_NoError(t, err)
+	fn.handleAppendEntry(osub, nil, nil, _EMPTY_, _EMPTY_, hb)
+	require_Equal(t, fn.aedf.Load(), 0)
+	corrupt(fn, 2)
+	require_True(t, aesub(fn) == osub)
+
+	// Hitting the threshold recreates the subscriptions.
+	corrupt(fn, 1)
+	nsub := aesub(fn)
+	require_NotNil(t, nsub)
+	require_True(t, nsub != osub)
+	require_Equal(t, fn.aedf.Load(), 0)
+
+	// Replication to the reset follower still works.
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 2)
+}
+
+func TestNRGStable(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createMemRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+
+	checkStable := func() {
+		t.Helper()
+		checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+			for _, sm := range rg {
+				if !sm.node().Stable() {
+					return fmt.Errorf("%s not stable", sm.server())
+				}
+			}
+			return nil
+		})
+	}
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+	checkStable()
+
+	// Burst of proposals while the followers can't respond.
+	locked := rg.lockFollowers()
+	for i := 0; i < 100; i++ {
+		leader.proposeDelta(1)
+	}
+	checkFor(t, time.Second, 10*time.Millisecond, func() error {
+		if index, commit, _ := leader.node().Progress(); index <= commit {
+			return fmt.Errorf("no uncommitted entries yet")
+		}
+		return nil
+	})
+	// Still healthy since we are the leader, but not stable.
+	require_True(t, leader.node().Healthy())
+	require_False(t, leader.node().Stable())
+	for _, sm := range locked {
+		sm.node().(*raft).Unlock()
+	}
+
+	// Once everything is committed and applied, the group quiesces.
+	rg.waitOnTotal(t, 101)
+	checkStable()
+
+	// A follower that has paused applies is not stable.
+	follower := rg.nonLeader().node()
+	require_NoError(t, follower.PauseApply())
+	leader.proposeDelta(1)
+	checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+		if index, _, applied := follower.Progress(); index <= applied {
+			return fmt.Errorf("entry not received yet")
+		}
+		return nil
+	})
+	require_False(t, follower.Stable())
+	follower.ResumeApply()
+	rg.waitOnTotal(t, 102)
+	checkStable()
+
+	// Committed but not yet applied is never stable, regardless of the rest.
+	n := leader.node().(*raft)
+	n.Lock()
+	n.applied--
+	n.Unlock()
+	require_False(t, n.Stable())
+	n.Lock()
+	n.applied++
+	n.Unlock()
+	require_True(t, n.Stable())
+}
+
+func TestNRGPendingEntries(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createMemRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	checkNoPending := func() {
+		t.Helper()
+		checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+			for _, sm := range rg {
+				if pe := sm.node().PendingEntries(); len(pe) > 0 {
+					return fmt.Errorf("%s has %d pending entries", sm.server(), len(pe))
+				}
+			}
+			return nil
+		})
+	}
+	checkNoPending()
+
+	// Without the followers there is no quorum, so nothing gets committed.
+	locked := rg.lockFollowers()
+	unlocked := false
+	unlock := func() {
+		if !unlocked {
+			unlocked = true
+			for _, sm := range locked {
+				sm.node().(*raft).Unlock()
+			}
+		}
+	}
+	defer unlock()
+
+	_, commit, _ := leader.node().Progress()
+	term := leader.node().Term()
+	for i := 0; i < 5; i++ {
+		leader.proposeDelta(1)
+	}
+	// Proposals may be batched in the same append entry.
+	var pe []EntryInfo
+	checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+		pe = leader.node().PendingEntries()
+		var entries int
+		for _, ei := range pe {
+			entries += len(ei.Types)
+		}
+		if entries != 5 {
+			return fmt.Errorf("expected 5 pending entries, got %d", entries)
+		}
+		return nil
+	})
+	index, _, _ := leader.node().Progress()
+	require_Equal(t, uint64(len(pe)), index-commit)
+	for i, ei := range pe {
+		require_Equal(t, ei.Index, commit+1+uint64(i))
+		require_Equal(t, ei.Term, term)
+		for _, et := range ei.Types {
+			require_Equal(t, et, EntryNormal)
+		}
+		require_Equal(t, ei.Acks, 1)
+	}
+	// Entries stay pending for as long as there is no quorum.
+	time.Sleep(250 * time.Millisecond)
+	require_Len(t, len(leader.node().PendingEntries()), len(pe))
+
+	unlock()
+	rg.waitOnTotal(t, 6)
+	checkNoPending()
+}
+
+func TestNRGQuorumSizeOverride(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R5S", 5)
+	defer c.shutdown()
+
+	// The override must be explicitly marked as unsafe.
+	_, err := c.servers[0].initRaftNode(globalAccountName, &RaftConfig{
+		Name: "BAD", Store: t.TempDir(), Log: c.createWAL("BAD", MemoryStorage), QuorumSize: 2,
+	}, pprofLabels{})
+	require_Error(t, err, errUnsafeQuorum)
+
+	// A quorum of 2 in a group of 5 is NOT safe. Two partitions of two nodes each
+	// could elect their own leader and commit diverging entries, and an entry that
+	// was committed by only two nodes is lost if both of them fail. The tradeoff is
+	// that the group keeps making progress with most of its nodes down.
+	peers := serverPeerNames(c.servers)
+	var rg smGroup
+	for _, s := range c.servers {
+		cfg := &RaftConfig{
+			Name:         "TEST",
+			Store:        t.TempDir(),
+			Log:          c.createWAL("TEST", FileStorage),
+			QuorumSize:   2,
+			UnsafeQuorum: true,
+		}
+		rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+	}
+	leader := rg.waitOnLeader()
+	n := leader.node().(*raft)
+	require_Equal(t, n.quorumNeeded(), 2)
+
+	// The override is never used when a majority is smaller.
+	n.RLock()
+	require_Equal(t, n.quorumFor(2), 2)
+	require_Equal(t, n.quorumFor(3), 2)
+	require_Equal(t, n.quorumFor(9), 2)
+	n.RUnlock()
+
+	leader.(*stateAdder).proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	// Stop three of the followers, a strict majority would need three nodes.
+	followers := rg.followers()
+	for _, sm := range followers[:3] {
+		sm.stop()
+	}
+	leader.(*stateAdder).proposeDelta(2)
+	rg.waitOnTotal(t, 3)
+
+	// With the leader gone as well, bringing back one of the stopped followers
+	// is enough to elect a new leader and commit.
+	leader.stop()
+	followers[0].restart()
+	live := smGroup{followers[0], followers[3]}
+	leader = live.waitOnLeader()
+	require_True(t, leader != nil)
+	leader.(*stateAdder).proposeDelta(3)
+	live.waitOnTotal(t, 6)
+}
+
+func TestNRGSlowReplicaPolicy(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	for _, test := range []struct {
+		name   string
+		action SlowReplicaAction
+	}{
+		{"Alert", SlowReplicaAlert},
+		{"Demote", SlowReplicaDemote},
+	} {
+		action := test.action
+		t.Run(test.name, func(t *testing.T) {
+			name := "TEST_" + test.name
+			peers := serverPeerNames(c.servers)
+			var rg smGroup
+			for _, s := range c.servers {
+				cfg := &RaftConfig{
+					Name:                 name,
+					Store:                t.TempDir(),
+					Log:                  c.createWAL(name, MemoryStorage),
+					SlowReplicaTimeout:   50 * time.Millisecond,
+					SlowReplicaThreshold: 3,
+					SlowReplicaAction:    action,
+				}
+				rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+			}
+			defer func() {
+				for _, sm := range rg {
+					sm.stop()
+				}
+			}()
+			leader := rg.waitOnLeader().(*stateAdder)
+			leader.proposeDelta(1)
+			rg.waitOnTotal(t, 1)
+
+			followers := rg.followers()
+			slow, other := followers[0].node().(*raft), followers[1]
+			checkPeer := func(isSlow, isLearner bool) {
+				t.Helper()
+				checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+					for _, p := range leader.node().Peers() {
+						if p.ID != slow.ID() {
+							continue
+						}
+						if p.Slow != isSlow || p.Learner != isLearner {
+							return fmt.Errorf("expected slow=%v learner=%v, got slow=%v learner=%v",
+								isSlow, isLearner, p.Slow, p.Learner)
+						}
+						return nil
+					}
+					return errors.New("slow replica not found")
+				})
+			}
+
+			// Block the follower from storing anything, it will time out
+			// every time the leader checks until the threshold is reached.
+			slow.Lock()
+			locked := true
+			defer func() {
+				if locked {
+					slow.Unlock()
+				}
+			}()
+			leader.proposeDelta(2)
+			checkPeer(true, action == SlowReplicaDemote)
+
+			// The group keeps committing without the slow replica.
+			leader.proposeDelta(3)
+			smGroup{leader, other}.waitOnTotal(t, 6)
+
+			// A learner is never picked as the next leader, but its acks still count towards commits.
+			total := int64(10)
+			if action == SlowReplicaDemote {
+				ln := leader.node().(*raft)
+				ln.RLock()
+				next := ln.selectNextLeader()
+				ln.RUnlock()
+				require_NotEqual(t, next, slow.ID())
+
+				// Hold back the other follower, so there's something left to commit.
+				on := other.node().(*raft)
+				on.Lock()
+				ln.RLock()
+				commit := ln.commit
+				ln.RUnlock()
+				waitOnIndex := func(index uint64) bool {
+					for start := time.Now(); time.Since(start) < 2*time.Second; time.Sleep(20 * time.Millisecond) {
+						ln.RLock()
+						pindex := ln.pindex
+						ln.RUnlock()
+						if pindex >= index {
+							return true
+						}
+					}
+					return false
+				}
+				// Proposals are batched, so wait for each to be appended separately.
+				leader.proposeDelta(1)
+				stored := waitOnIndex(commit + 1)
+				leader.proposeDelta(1)
+				stored = stored && waitOnIndex(commit+2)
+				total += 2
+				ln.Lock()
+				counted := ln.trackResponse(&appendEntryResponse{term: ln.term, index: commit + 1, peer: slow.ID(), success: true})
+				isLearner := ln.peers[slow.ID()].lrn
+				ln.Unlock()
+				on.Unlock()
+				require_True(t, stored)
+				require_True(t, counted)
+				require_True(t, isLearner)
+			}
+
+			// Once it caught up it's no longer considered slow.
+			slow.Unlock()
+			locked = false
+			leader.proposeDelta(4)
+			rg.waitOnTotal(t, total)
+			checkPeer(false, false)
+		})
+	}
+}
+
+func TestNRGRecoveryTimeObjective(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	const rto = 200 * time.Millisecond
+	peers := serverPeerNames(c.servers)
+	var rg smGroup
+	for _, s := range c.servers {
+		cfg := &RaftConfig{
+			Name:                  "TEST",
+			Store:                 t.TempDir(),
+			Log:                   c.createWAL("TEST", MemoryStorage),
+			RecoveryTimeObjective: rto,
+		}
+		rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+	}
+	leader := rg.waitOnLeader().(*stateAdder)
+	n := leader.node()
+
+	// Propose while applies are slowed down by holding the state machine's lock.
+	var total int64
+	slowPropose := func() {
+		t.Helper()
+		leader.Lock()
+		leader.n.ForwardProposal([]byte{2}) // Varint for 1.
+		time.Sleep(20 * time.Millisecond)
+		leader.Unlock()
+		total++
+		checkFor(t, 2*time.Second, 5*time.Millisecond, func() error {
+			if sum := leader.total(); sum != total {
+				return fmt.Errorf("expected %d, got %d", total, sum)
+			}
+			return nil
+		})
+	}
+
+	// The log grows until replaying it is estimated to put the objective at risk.
+	for !n.ExceedsRecoveryTime() {
+		require_True(t, total < 100)
+		slowPropose()
+	}
+	stats := n.Stats()
+	require_True(t, stats.ApplyTime >= 5*time.Millisecond)
+	require_True(t, stats.ReplayTime >= rto*3/4)
+
+	// A snapshot brings the replay time back down.
+	leader.snapshot(t)
+	require_False(t, n.ExceedsRecoveryTime())
+	require_Equal(t, n.Stats().ReplayTime, 0)
+
+	// Snapshotting whenever asked keeps the replay time bounded.
+	var snaps int
+	for range 50 {
+		slowPropose()
+		if n.ExceedsRecoveryTime() {
+			leader.snapshot(t)
+			snaps++
+		}
+		require_True(t, n.Stats().ReplayTime < rto)
+	}
+	require_True(t, snaps > 1)
+}
+
+func TestNRGMaxWALBytes(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	const maxWAL = 4 * 1024
+	peers := serverPeerNames(c.servers)
+	var rg smGroup
+	for _, s := range c.servers {
+		cfg := &RaftConfig{
+			Name:        "TEST",
+			Store:       t.TempDir(),
+			Log:         c.createWAL("TEST", MemoryStorage),
+			MaxWALBytes: maxWAL,
+		}
+		rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+	}
+	leader := rg.waitOnLeader().(*stateAdder)
+	n := leader.node()
+
+	var total int64
+	propose := func() error {
+		t.Helper()
+		if err := n.Propose([]byte{2}); err != nil { // Varint for 1.
+			return err
+		}
+		total++
+		checkFor(t, 2*time.Second, 5*time.Millisecond, func() error {
+			if sum := leader.total(); sum != total {
+				return fmt.Errorf("expected %d, got %d", total, sum)
+			}
+			return nil
+		})
+		return nil
+	}
+	walBytes := func() uint64 {
+		_, nb := n.Size()
+		return nb
+	}
+
+	// The log grows until it gets close to the cap.
+	for !n.ExceedsMaxWAL() {
+		require_True(t, total < 1000)
+		require_NoError(t, propose())
+	}
+	require_True(t, walBytes() >= maxWAL*3/4)
+	require_True(t, walBytes() < maxWAL)
+
+	// Without a snapshot, proposals are refused once at the cap.
+	var err error
+	for err == nil {
+		require_True(t, total < 1000)
+		err = propose()
+	}
+	require_Error(t, err, errWALFull)
+	require_True(t, walBytes() >= maxWAL)
+
+	// A forced snapshot compacts the log below the cap, and proposals are accepted again.
+	leader.snapshot(t)
+	require_False(t, n.ExceedsMaxWAL())
+	require_True(t, walBytes() < maxWAL*3/4)
+	require_NoError(t, propose())
+
+	// Snapshotting whenever asked keeps the log below the cap.
+	for range 100 {
+		require_NoError(t, propose())
+		if n.ExceedsMaxWAL() {
+			leader.snapshot(t)
+		}
+		require_True(t, walBytes() < maxWAL)
+	}
+	rg.waitOnTotal(t, total)
+}
+
+func TestNRGElectionStats(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createMemRaftGroup("TEST", 3, newStateAdder)
+	rg.waitOnLeader()
+
+	totals := func() RaftStats {
+		var ts RaftStats
+		for _, sm := range rg {
+			st := sm.node().Stats()
+			ts.ElectionsStarted += st.ElectionsStarted
+			ts.ElectionsWon += st.ElectionsWon
+			ts.SplitVotes += st.SplitVotes
+			ts.VoteRequestsSent += st.VoteRequestsSent
+			ts.VoteRequestsReceived += st.VoteRequestsReceived
+		}
+		return ts
+	}
+
+	// Electing the initial leader took at least one election.
+	before := totals()
+	require_Equal(t, before.ElectionsWon, 1)
+	require_True(t, before.ElectionsStarted >= before.ElectionsWon)
+	require_True(t, before.VoteRequestsSent >= before.ElectionsWon)
+	require_True(t, before.VoteRequestsReceived >= before.ElectionsWon)
+
+	// Every stepdown results in a new election being won.
+	for range 3 {
+		leader := rg.waitOnLeader()
+		require_NoError(t, leader.node().StepDown())
+		checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+			if nl := rg.leader(); nl == nil || nl == leader {
+				return errors.New("no new leader elected")
+			}
+			return nil
+		})
+	}
+	after := totals()
+	require_Equal(t, after.ElectionsWon, before.ElectionsWon+3)
+	require_True(t, after.ElectionsStarted >= before.ElectionsStarted+3)
+	require_True(t, after.VoteRequestsSent >= before.VoteRequestsSent+3)
+	require_True(t, after.VoteRequestsReceived >= before.VoteRequestsReceived+3)
+
+	// Without its followers, a candidate can not win and its elections time out.
+	leader := rg.waitOnLeader()
+	for _, sm := range rg.followers() {
+		sm.stop()
+	}
+	n := leader.node()
+	before = n.Stats()
+	require_NoError(t, n.StepDown())
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		if st := n.Stats(); st.SplitVotes < before.SplitVotes+1 {
+			return fmt.Errorf("expected a split vote, got %d", st.SplitVotes)
+		}
+		return nil
+	})
+	after = n.Stats()
+	require_Equal(t, after.ElectionsWon, before.ElectionsWon)
+	// Every split vote is followed by a new election.
+	require_True(t, after.ElectionsStarted-before.ElectionsStarted > after.SplitVotes-before.SplitVotes)
+	require_True(t, after.VoteRequestsSent > before.VoteRequestsSent)
+}
+
+func TestNRGCompact(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	// Use small blocks so the log spreads across many segments.
+	var rg smGroup
+	peers := serverPeerNames(c.servers)
+	for _, s := range c.servers {
+		fs, err := newFileStore(
+			FileStoreConfig{StoreDir: t.TempDir(), BlockSize: 1024, AsyncFlush: false, SyncInterval: 5 * time.Minute},
+			StreamConfig{Name: "TEST", Storage: FileStorage})
+		require_NoError(t, err)
+		cfg := &RaftConfig{Name: "TEST", Store: t.TempDir(), Log: fs}
+		rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+	}
+	leader := rg.waitOnLeader()
+	follower := rg.nonLeader()
+	fn := follower.node().(*raft)
+
+	var expected int64
+	propose := func() {
+		t.Helper()
+		index, _, _ := leader.node().Progress()
+		leader.(*stateAdder).proposeDelta(1)
+		expected++
+		checkFor(t, 5*time.Second, 10*time.Millisecond, func() error {
+			if findex, _, _ := fn.Progress(); findex <= index {
+				return fmt.Errorf("follower at %d, expected %d", findex, index+1)
+			}
+			return nil
+		})
+	}
+	segments := func(n *raft) int {
+		n.RLock()
+		defer n.RUnlock()
+		return n.wal.(*fileStore).numMsgBlocks()
+	}
+
+	// Churn the group with a couple of snapshots.
+	for i := range 100 {
+		propose()
+		if i%25 == 24 {
+			rg.waitOnTotal(t, expected)
+			for _, sm := range rg {
+				sm.(*stateAdder).snapshot(t)
+			}
+		}
+	}
+	rg.waitOnTotal(t, expected)
+
+	// Learn how many entries fit in a segment.
+	for blks := segments(fn); segments(fn) == blks; {
+		propose()
+	}
+	var perSegment int
+	for blks := segments(fn); segments(fn) == blks; perSegment++ {
+		propose()
+	}
+	// Fill up the last segment but one entry.
+	for range perSegment - 2 {
+		propose()
+	}
+	rg.waitOnTotal(t, expected)
+
+	// A lagging snapshot leaves the log spread across two segments,
+	// while the remaining entries easily fit in one.
+	require_NoError(t, follower.node().PauseApply())
+	propose()
+	propose()
+	follower.(*stateAdder).snapshot(t)
+	follower.node().ResumeApply()
+	rg.waitOnTotal(t, expected)
+
+	compact := func(n *raft) int {
+		t.Helper()
+		index, commit, applied := n.Progress()
+		blks := segments(n)
+		require_NoError(t, n.Compact())
+		nindex, ncommit, napplied := n.Progress()
+		require_Equal(t, nindex, index)
+		require_Equal(t, ncommit, commit)
+		require_Equal(t, napplied, applied)
+		return blks - segments(n)
+	}
+
+	// Compact the follower first, then the leader.
+	require_True(t, compact(fn) > 0)
+	require_True(t, compact(leader.node().(*raft)) >= 0)
+	rg.waitOnTotal(t, expected)
+
+	// The group keeps working.
+	for range 10 {
+		propose()
+	}
+	rg.waitOnTotal(t, expected)
+
+	// And recovers from the compacted log after a restart.
+	for _, sm := range rg {
+		sm.stop()
+	}
+	for _, sm := range rg {
+		sm.restart()
+	}
+	rg.waitOnLeader()
+	rg.waitOnTotal(t, expected)
+}
+
+func TestNRGConfigHistory(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createMemRaftGroup("TEST", 3, newStateAdder)
+	rg.waitOnLeader()
+
+	leader := rg.leader().node().(*raft)
+	require_Len(t, len(leader.ConfigHistory()), 0)
+
+	// Membership changes are proposed asynchronously, wait for each to be committed.
+	waitOnHistory := func(n RaftNode, changes int) []ConfigChange {
+		t.Helper()
+		var hist []ConfigChange
+		checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+			if hist = n.ConfigHistory(); len(hist) != changes {
+				return fmt.Errorf("%s: expected %d changes, got %d", n.ID(), changes, len(hist))
+			}
+			return nil
+		})
+		return hist
+	}
+
+	peer := rg.nonLeader().node().ID()
+	require_NoError(t, leader.ProposeRemovePeer(peer))
+	waitOnHistory(leader, 1)
+	require_NoError(t, leader.ProposeAddPeer(peer))
+	hist := waitOnHistory(leader, 2)
+
+	// Each change is recorded with the index and term of the entry that committed it.
+	for i, et := range []EntryType{EntryRemovePeer, EntryAddPeer} {
+		cc := hist[i]
+		require_Equal(t, cc.Type, et)
+		require_Equal(t, cc.Peer, peer)
+		require_False(t, cc.Time.IsZero())
+		ae, err := leader.loadEntry(cc.Index)
+		require_NoError(t, err)
+		require_Equal(t, ae.term, cc.Term)
+		require_Len(t, len(ae.entries), 1)
+		require_Equal(t, ae.entries[0].Type, et)
+		require_Equal(t, string(ae.entries[0].Data), peer)
+	}
+	require_True(t, hist[0].Index < hist[1].Index)
+
+	// The remaining follower records the same changes.
+	for _, r := range rg {
+		n := r.node()
+		if n == RaftNode(leader) || n.ID() == peer {
+			continue
+		}
+		for i, cc := range waitOnHistory(n, 2) {
+			cc.Time = hist[i].Time
+			require_Equal(t, cc, hist[i])
+		}
+	}
+}
+
+func TestNRGProposeNoop(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createMemRaftGroup("TEST", 3, newStateAdder)
+	rg.waitOnLeader()
+
+	// Leave an entry from the current term behind, stored by all followers.
+	ol := rg.leader()
+	pindex, _, _ := ol.node().Progress()
+	ol.(*stateAdder).proposeDelta(22)
+	checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+		for _, r := range rg {
+			if index, _, _ := r.node().Progress(); index <= pindex {
+				return fmt.Errorf("%s: entry not stored yet", r.node().ID())
+			}
+		}
+		return nil
+	})
+	require_NoError(t, ol.node().StepDown())
+	rg.waitOnLeader()
+	nl := rg.leader().node().(*raft)
+	require_NotEqual(t, nl.ID(), ol.node().ID())
+
+	for _, f := range rg.followers() {
+		require_Error(t, f.node().ProposeNoop(), errNotLeader)
+	}
+
+	term := nl.Term()
+	index, _, _ := nl.Progress()
+	require_NoError(t, nl.ProposeNoop())
+
+	// The no-op is appended in the new leader's term, and committed when we return.
+	_, commit, _ := nl.Progress()
+	require_True(t, commit > index)
+	ae, err := nl.loadEntry(index + 1)
+	require_NoError(t, err)
+	require_Equal(t, ae.term, term)
+	require_Len(t, len(ae.entries), 1)
+	require_Equal(t, ae.entries[0].Type, EntryPeerState)
+
+	// With prior entries committed, reads will observe them once applied.
+	checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+		if total := rg.leader().(*stateAdder).total(); total != 22 {
+			return fmt.Errorf("expected total of 22, got %d", total)
+		}
+		return nil
+	})
+}
+
+// syncGatedWAL reports entries being synced separately from them being stored,
+// and can hold syncs back to simulate a slow disk.
+type syncGatedWAL struct {
+	WAL
+	mu     sync.Mutex
+	stored uint64        // Last index stored
+	synced uint64        // Last index synced
+	gate   chan struct{} // If set, syncs block until it's closed
+}
+
+func (w *syncGatedWAL) StoreMsg(subj string, hdr, msg []byte, ttl int64) (uint64, int64, error) {
+	seq, ts, err := w.WAL.StoreMsg(subj, hdr, msg, ttl)
+	if err == nil {
+		w.mu.Lock()
+		w.stored = seq
+		w.mu.Unlock()
+	}
+	return seq, ts, err
+}
+
+func (w *syncGatedWAL) Sync() error {
+	w.mu.Lock()
+	gate, stored := w.gate, w.stored
+	w.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	if err := w.WAL.(walSyncer).Sync(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.synced = max(w.synced, stored)
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *syncGatedWAL) hold() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.gate = make(chan struct{})
+}
+
+func (w *syncGatedWAL) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gate != nil {
+		close(w.gate)
+		w.gate = nil
+	}
+}
+
+func (w *syncGatedWAL) indexes() (stored, synced uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stored, w.synced
+}
+
+func TestNRGDurableAckCount(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	var rg smGroup
+	wals := make(map[*Server]*syncGatedWAL)
+	peers := serverPeerNames(c.servers)
+	for _, s := range c.servers {
+		wal := &syncGatedWAL{WAL: c.createWAL("TEST", FileStorage)}
+		wals[s] = wal
+		cfg := &RaftConfig{Name: "TEST", Store: t.TempDir(), Log: wal, DurableAckCount: 3}
+		rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+	}
+	defer func() {
+		for _, wal := range wals {
+			wal.release()
+		}
+	}()
+
+	leader := rg.waitOnLeader()
+	leader.(*stateAdder).proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	// Hold back all syncs, the next entry can be stored but not acked.
+	for _, wal := range wals {
+		wal.hold()
+	}
+	ln := leader.node().(*raft)
+	ln.RLock()
+	index := ln.pindex + 1
+	ln.RUnlock()
+	leader.(*stateAdder).proposeDelta(21)
+
+	// The entry is received by all replicas.
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		for s, wal := range wals {
+			if stored, _ := wal.indexes(); stored < index {
+				return fmt.Errorf("%s stored up to %d, expected %d", s, stored, index)
+			}
+		}
+		return nil
+	})
+
+	// Once the leader and one follower synced, we'd normally have a quorum,
+	// but we need all three durable acks.
+	follower := rg.nonLeader()
+	wals[leader.server()].release()
+	wals[follower.server()].release()
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		for _, sm := range []stateMachine{leader, follower} {
+			if _, synced := wals[sm.server()].indexes(); synced < index {
+				return fmt.Errorf("%s synced up to %d, expected %d", sm.server(), synced, index)
+			}
+		}
+		return nil
+	})
+	time.Sleep(250 * time.Millisecond)
+	ln.RLock()
+	commit := ln.commit
+	ln.RUnlock()
+	require_True(t, commit < index)
+	for _, sm := range rg {
+		require_Equal(t, sm.(*stateAdder).total(), 1)
+	}
+
+	// The last sync completes the durable acks and the entry gets committed.
+	for _, wal := range wals {
+		wal.release()
+	}
+	rg.waitOnTotal(t, 22)
+}
+
+func TestNRGDurableAckCountSwapLog(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	var rg smGroup
+	peers := serverPeerNames(c.servers)
+	for _, s := range c.servers {
+		cfg := &RaftConfig{Name: "TEST", Store: t.TempDir(), Log: c.createWAL("TEST", FileStorage), DurableAckCount: 3}
+		rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+	}
+	leader := rg.waitOnLeader()
+
+	// Keep proposing, so entries are synced while the logs are swapped.
+	var expected atomic.Int64
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-quit:
+				return
+			default:
+				leader.(*stateAdder).proposeDelta(1)
+				expected.Add(1)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	// The log can be busy, but swapping it must never leave a write error behind.
+	swapErr := func(err error) {
+		t.Helper()
+		if err != nil && err != errCatchupsRunning && err != errSnapInProgress && err != errCompactInterrupted {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	for range 5 {
+		for _, sm := range rg {
+			n := sm.node().(*raft)
+			swapErr(n.Compact())
+			swapErr(n.MigrateLog(MemoryStorage))
+			swapErr(n.MigrateLog(FileStorage))
+		}
+	}
+	close(quit)
+	wg.Wait()
+
+	rg.waitOnTotal(t, expected.Load())
+	for _, sm := range rg {
+		n := sm.node().(*raft)
+		n.RLock()
+		werr := n.werr
+		n.RUnlock()
+		require_NoError(t, werr)
+	}
+}
+
+func TestNRGQuiesce(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	rg.waitOnLeader()
+	leader := rg.leader().(*stateAdder)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	resume, index := leader.node().Quiesce()
+	_, commit, applied := leader.node().Progress()
+	require_Equal(t, applied, index)
+	require_Equal(t, commit, index)
+
+	// Proposals are buffered while quiesced.
+	for i := 0; i < 10; i++ {
+		leader.proposeDelta(2)
+	}
+	time.Sleep(250 * time.Millisecond)
+	for _, sm := range rg {
+		require_Equal(t, sm.(*stateAdder).total(), 1)
+	}
+	// The state read at the returned index is stable.
+	_, commit, applied = leader.node().Progress()
+	require_Equal(t, commit, index)
+	require_Equal(t, applied, index)
+	require_Equal(t, leader.total(), 1)
+
+	// Once resumed, the buffered proposals are committed.
+	resume()
+	rg.waitOnTotal(t, 21)
+	// Resuming again has no effect.
+	resume()
+
+	// Followers hold back commits from the upper layer while quiesced.
+	follower := rg.nonLeader().(*stateAdder)
+	resume, index = follower.node().Quiesce()
+	_, _, applied = follower.node().Progress()
+	require_Equal(t, applied, index)
+	leader.proposeDelta(1)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if total := leader.total(); total != 22 {
+			return fmt.Errorf("expected total of 22, got %d", total)
+		}
+		return nil
+	})
+	require_Equal(t, follower.total(), 21)
+	resume()
+	rg.waitOnTotal(t, 22)
+}
+
+func TestNRGNetworkFaultLatency(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createFaultyRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+	term := leader.node().Term()
+
+	// Well below the election timeout, this should only slow things down.
+	rg.setNetworkFault(&raftNetworkFault{delay: 20 * time.Millisecond, jitter: 20 * time.Millisecond})
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		leader.proposeDelta(1)
+	}
+	rg.waitOnTotal(t, 20)
+	// A round trip to the followers and back is needed to commit.
+	require_True(t, time.Since(start) >= 40*time.Millisecond)
+
+	// Messages may have been reordered, but no election should have happened.
+	require_True(t, leader.node().Leader())
+	for _, sm := range rg {
+		require_Equal(t, sm.node().Term(), term)
+	}
+
+	// Some loss on top of that, replication should still converge.
+	rg.setNetworkFault(&raftNetworkFault{delay: 10 * time.Millisecond, drop: 0.2})
+	for i := 0; i < 20; i++ {
+		leader.proposeDelta(1)
+	}
+	rg.setNetworkFault(nil)
+	// Make sure there is an entry to replicate without any loss.
+	leader = rg.waitOnLeader().(*stateAdder)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 41)
+}
+
+func TestNRGNetworkFaultIsolatedLeader(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createFaultyRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+	term := leader.node().Term()
+
+	// Dropping everything on the leader cuts it off from the rest of the group,
+	// so the followers should elect a new leader within the election timeout.
+	leader.node().(*raft).setNetworkFault(&raftNetworkFault{drop: 1})
+	start := time.Now()
+	checkFor(t, 2*maxElectionTimeout, 50*time.Millisecond, func() error {
+		for _, sm := range rg {
+			if sm != leader && sm.node().Leader() {
+				return nil
+			}
+		}
+		return errors.New("no new leader yet")
+	})
+	require_True(t, time.Since(start) >= minElectionTimeout/2)
+
+	var nl *stateAdder
+	for _, sm := range rg {
+		if sm != leader && sm.node().Leader() {
+			nl = sm.(*stateAdder)
+		}
+	}
+	require_NotNil(t, nl)
+	require_True(t, nl.node().Term() > term)
+	nl.proposeDelta(1)
+
+	// The isolated node can't make progress.
+	time.Sleep(250 * time.Millisecond)
+	require_Equal(t, leader.total(), 1)
+
+	// Once healed it should step down, if it had not already, and catch up.
+	leader.node().(*raft).setNetworkFault(nil)
+	rg.waitOnTotal(t, 2)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if leader.node().Leader() {
+			return errors.New("old leader did not step down")
+		}
+		return nil
+	})
+}
+
+func TestNRGMatchIndexes(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+
+	// Only the leader tracks what its peers acknowledged.
+	_, err := rg.nonLeader().node().MatchIndexes()
+	require_Error(t, err, errNotLeader)
+
+	checkMatchIndexes := func() {
+		t.Helper()
+		checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+			index, _, _ := leader.node().Progress()
+			mi, err := leader.node().MatchIndexes()
+			if err != nil {
+				return err
+			}
+			if len(mi) != 3 {
+				return fmt.Errorf("expected 3 peers, got %d", len(mi))
+			}
+			for id, li := range mi {
+				if li != index {
+					return fmt.Errorf("expected peer %q at index %d, got %d", id, index, li)
+				}
+			}
+			return nil
+		})
+	}
+
+	for i := 0; i < 10; i++ {
+		leader.proposeDelta(1)
+	}
+	rg.waitOnTotal(t, 10)
+	checkMatchIndexes()
+
+	// A follower that can't store entries falls behind.
+	follower := rg.nonLeader()
+	fn := follower.node().(*raft)
+	fn.Lock()
+	for i := 0; i < 10; i++ {
+		leader.proposeDelta(1)
+	}
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		index, commit, _ := leader.node().Progress()
+		if commit != index {
+			return fmt.Errorf("commit %d not yet at index %d", commit, index)
+		}
+		mi, err := leader.node().MatchIndexes()
+		if err != nil {
+			return err
+		}
+		if li := mi[fn.ID()]; li >= index {
+			return fmt.Errorf("expected locked follower behind %d, got %d", index, li)
+		}
+		for id, li := range mi {
+			if id != fn.ID() && li != index {
+				return fmt.Errorf("expected peer %q at index %d, got %d", id, index, li)
+			}
+		}
+		return nil
+	})
+	fn.Unlock()
+
+	// Once it catches up its match index does too.
+	rg.waitOnTotal(t, 20)
+	checkMatchIndexes()
+}
+
+func TestNRGExportImportState(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+
+	// Populate the group, with part of its state in a snapshot and the rest in its log.
+	for i := 1; i <= 10; i++ {
+		leader.proposeDelta(int64(i))
+	}
+	rg.waitOnTotal(t, 55)
+	leader.snapshot(t)
+	for i := 1; i <= 5; i++ {
+		leader.proposeDelta(100)
+		rg.waitOnTotal(t, int64(55+100*i))
+	}
+
+	var buf bytes.Buffer
+	require_NoError(t, leader.node().ExportState(&buf))
+	_, commit, _ := leader.node().Progress()
+
+	// A corrupt archive is rejected.
+	s := c.servers[0]
+	newCfg := func() *RaftConfig {
+		return &RaftConfig{Name: "CLONE", Store: t.TempDir(), Log: c.createWAL("CLONE", FileStorage)}
+	}
+	corrupt := bytes.Clone(buf.Bytes())
+	corrupt[len(corrupt)/2]++
+	require_Error(t, s.bootstrapRaftNodeFromArchive(newCfg(), bytes.NewReader(corrupt)), errBadArchive)
+
+	// Import into a fresh node, which starts out as a single-node group.
+	cfg := newCfg()
+	require_NoError(t, s.bootstrapRaftNodeFromArchive(cfg, bytes.NewReader(buf.Bytes())))
+	n, err := s.startRaftNode(globalAccountName, cfg, pprofLabels{})
+	require_NoError(t, err)
+	clone := newStateAdder(s, cfg, n).(*stateAdder)
+	go smLoop(clone)
+	defer clone.stop()
+
+	clones := smGroup{clone}
+	clones.waitOnTotal(t, 555)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if _, _, applied := n.Progress(); applied < commit {
+			return fmt.Errorf("expected applied to reach %d, got %d", commit, applied)
+		}
+		return nil
+	})
+	clones.waitOnLeader()
+	require_Equal(t, n.ClusterSize(), 1)
+	require_Len(t, len(n.Peers()), 1)
+
+	// The clone continues on its own.
+	clone.proposeDelta(45)
+	clones.waitOnTotal(t, 600)
+	rg.waitOnTotal(t, 555)
+
+	// Can only import into an empty log.
+	require_Error(t, s.bootstrapRaftNodeFromArchive(cfg, &buf), errLogNotEmpty)
+}
+
+func TestNRGDedicatedWorker(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	var rg smGroup
+	peers := serverPeerNames(c.servers)
+	for _, s := range c.servers {
+		cfg := &RaftConfig{Name: "TEST", Store: t.TempDir(), Log: c.createWAL("TEST", FileStorage), DedicatedWorker: true}
+		rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+	}
+	other := c.createRaftGroup("OTHER", 3, newStateAdder)
+
+	leader := rg.waitOnLeader()
+	for range 100 {
+		leader.(*stateAdder).proposeDelta(1)
+	}
+	rg.waitOnTotal(t, 100)
+
+	// Both the run loop and the apply worker are pinned to their own thread.
+	for _, sm := range rg {
+		require_Equal(t, sm.node().Stats().PinnedWorkers, 2)
+	}
+
+	// A group without a dedicated worker isn't pinned.
+	other.waitOnLeader().(*stateAdder).proposeDelta(1)
+	other.waitOnTotal(t, 1)
+	for _, sm := range other {
+		require_Equal(t, sm.node().Stats().PinnedWorkers, 0)
+	}
+
+	// Once stopped, the threads are released again.
+	for _, sm := range rg {
+		sm.stop()
+		require_Equal(t, sm.node().Stats().PinnedWorkers, 0)
+	}
+}
+
+func TestNRGCommitGate(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	const delayed, vetoed, slow = 100, 200, 300
+	release, releaseSlow := make(chan struct{}), make(chan struct{})
+	var allow atomic.Bool
+	var vetoes, slowCalls atomic.Int32
+	gate := func(index uint64, entries []*Entry) bool {
+		for _, e := range entries {
+			if e.Type != EntryNormal {
+				continue
+			}
+			switch delta, _ := binary.Varint(e.Data); delta {
+			case delayed:
+				<-release
+			case slow:
+				slowCalls.Add(1)
+				<-releaseSlow
+			case vetoed:
+				if !allow.Load() {
+					vetoes.Add(1)
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	var rg smGroup
+	peers := serverPeerNames(c.servers)
+	for _, s := range c.servers {
+		cfg := &RaftConfig{
+			Name: "TEST", Store: t.TempDir(), Log: c.createWAL("TEST", FileStorage),
+			CommitGate: gate, CommitGateTimeout: 5 * time.Second,
+		}
+		rg = append(rg, c.createStateMachine(s, cfg, peers, newStateAdder))
+	}
+	leader := rg.waitOnLeader().(*stateAdder)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	// The marked entry isn't committed while the gate holds it back, nor anything after it.
+	leader.proposeDelta(delayed)
+	leader.proposeDelta(1)
+	time.Sleep(250 * time.Millisecond)
+	for _, sm := range rg {
+		require_Equal(t, sm.(*stateAdder).total(), 1)
+	}
+	// The run loop isn't blocked meanwhile.
+	leader.node().(*raft).RLock()
+	pindex, commit := leader.node().(*raft).pindex, leader.node().(*raft).commit
+	leader.node().(*raft).RUnlock()
+	require_True(t, leader.node().Leader())
+	require_True(t, pindex > commit)
+
+	// Once the gate releases, everything is committed.
+	close(release)
+	rg.waitOnTotal(t, delayed+2)
+
+	// A vetoed entry is retried after the timeout, until the gate allows it.
+	leader.node().(*raft).Lock()
+	leader.node().(*raft).cgto = 50 * time.Millisecond
+	leader.node().(*raft).Unlock()
+	leader.proposeDelta(vetoed)
+	checkFor(t, 2*time.Second, 25*time.Millisecond, func() error {
+		if v := vetoes.Load(); v < 3 {
+			return fmt.Errorf("expected the gate to be consulted again, vetoed %d times", v)
+		}
+		return nil
+	})
+	for _, sm := range rg {
+		require_Equal(t, sm.(*stateAdder).total(), delayed+2)
+	}
+	allow.Store(true)
+	rg.waitOnTotal(t, delayed+vetoed+2)
+
+	// A gate that doesn't return before the timeout is waited on, not consulted again.
+	leader.proposeDelta(slow)
+	time.Sleep(250 * time.Millisecond)
+	require_Equal(t, slowCalls.Load(), 1)
+	for _, sm := range rg {
+		require_Equal(t, sm.(*stateAdder).total(), delayed+vetoed+2)
+	}
+	close(releaseSlow)
+	rg.waitOnTotal(t, delayed+vetoed+slow+2)
+	require_Equal(t, slowCalls.Load(), 1)
+}
+
+// Adder state machine that fails to apply entries as long as fail returns true for them.
+type failingAdder struct {
+	*stateAdder
+	fail func(delta int64) bool
+}
+
+func (a *failingAdder) applyEntry(ce *CommittedEntry) {
+	if ce != nil {
+		for _, e := range ce.Entries {
+			if e.Type != EntryNormal {
+				continue
+			}
+			delta, _ := binary.Varint(e.Data)
+			for a.fail(delta) {
+				backoff, err := a.node().ApplyFailed(ce.Index, fmt.Errorf("can't apply %d", delta))
+				if err != nil || backoff == 0 {
+					// Either halted, or skipped and marked as applied.
+					return
+				}
+				time.Sleep(backoff)
+			}
+		}
+	}
+	a.stateAdder.applyEntry(ce)
+}
+
+func TestNRGApplyErrorPolicy(t *testing.T) {
+	const failing = 100
+	for name, policy := range map[string]ApplyErrorPolicy{"Skip": ApplyErrorSkip, "Halt": ApplyErrorHalt} {
+		t.Run(name, func(t *testing.T) {
+			c := createJetStreamClusterExplicit(t, "R3S", 3)
+			defer c.shutdown()
+
+			nc := natsConnect(t, c.servers[0].ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+			defer nc.Close()
+			sub := natsSubSync(t, nc, fmt.Sprintf(raftApplySkippedSubj, "*"))
+			natsFlush(t, nc)
+
+			// Only the first server fails to apply, a few times, or always if marked as failing.
+			var attempts atomic.Int32
+			fail := func(delta int64) bool {
+				switch delta {
+				case failing:
+					attempts.Add(1)
+					return true
+				case failing + 1:
+					return attempts.Add(1) <= 2
+				}
+				return false
+			}
+			var rg smGroup
+			peers := serverPeerNames(c.servers)
+			for i, s := range c.servers {
+				cfg := &RaftConfig{
+					Name: "TEST", Store: t.TempDir(), Log: c.createWAL("TEST", FileStorage),
+					ApplyErrorRetries: 2, ApplyErrorBackoff: 10 * time.Millisecond, ApplyErrorPolicy: policy,
+				}
+				rg = append(rg, c.createStateMachine(s, cfg, peers, func(s *Server, cfg *RaftConfig, n RaftNode) stateMachine {
+					sa := newStateAdder(s, cfg, n).(*stateAdder)
+					if i > 0 {
+						return &failingAdder{sa, func(int64) bool { return false }}
+					}
+					return &failingAdder{sa, fail}
+				}))
+			}
+			waitOnTotals := func(t *testing.T, expected ...int64) {
+				t.Helper()
+				checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+					for i, sm := range rg {
+						if total := sm.(*failingAdder).total(); total != expected[i] {
+							return fmt.Errorf("adder %d has wrong total: %d vs %d", i, total, expected[i])
+						}
+					}
+					return nil
+				})
+			}
+			leader := rg.waitOnLeader()
+			leader.(*failingAdder).proposeDelta(failing + 1)
+			waitOnTotals(t, failing+1, failing+1, failing+1)
+
+			// An entry that succeeds within the retries doesn't trigger the policy.
+			require_Equal(t, attempts.Load(), 3)
+			_, err := sub.NextMsg(100 * time.Millisecond)
+			require_Error(t, err, nats.ErrTimeout)
+
+			leader = rg.waitOnLeader()
+			leader.(*failingAdder).proposeDelta(failing)
+			if policy == ApplyErrorSkip {
+				// The entry is skipped on the first server, which keeps applying later entries.
+				msg := natsNexMsg(t, sub, 2*time.Second)
+				var adv RaftApplySkippedEventMsg
+				require_NoError(t, json.Unmarshal(msg.Data, &adv))
+				require_Equal(t, adv.Type, RaftApplySkippedEventMsgType)
+				require_Equal(t, adv.Server.Name, c.servers[0].Name())
+				require_Equal(t, adv.Group, "TEST")
+				require_Equal(t, adv.Error, fmt.Sprintf("can't apply %d", failing))
+				require_Equal(t, attempts.Load(), 6)
+
+				leader = rg.waitOnLeader()
+				leader.(*failingAdder).proposeDelta(1)
+				waitOnTotals(t, failing+2, 2*failing+2, 2*failing+2)
+				n := rg[0].node()
+				_, commit, applied := n.Progress()
+				require_Equal(t, applied, commit)
+			} else {
+				// The first server halts, the others keep going.
+				checkFor(t, 2*time.Second, 25*time.Millisecond, func() error {
+					if state := rg[0].node().State(); state != Closed {
+						return fmt.Errorf("expected node to be closed, got %v", state)
+					}
+					return nil
+				})
+				require_Equal(t, attempts.Load(), 6)
+				require_Equal(t, rg[0].(*failingAdder).total(), failing+1)
+				_, err = sub.NextMsg(100 * time.Millisecond)
+				require_Error(t, err, nats.ErrTimeout)
+
+				leader = rg.waitOnLeader()
+				leader.(*failingAdder).proposeDelta(1)
+				checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+					for _, sm := range rg[1:] {
+						if total := sm.(*failingAdder).total(); total != 2*failing+2 {
+							return fmt.Errorf("wrong total: %d", total)
+						}
+					}
+					return nil
+				})
+			}
+		})
+	}
+}
@@ -369,6 +369,33 @@ func (u *tagsOption) IsStatszChange() bool {
 	return true
 }
 
+// connectBlocklistOption implements the option interface for the `connect_blocklist` setting.
+type connectBlocklistOption struct {
+	noopOption
+	newValue ConnectBlocklistOpts
+}
+
+// Apply the setting by replacing the blocklist, clients that are already connected are not affected.
+func (c *connectBlocklistOption) Apply(server *Server) {
+	server.SetConnectBlocklist(c.newValue)
+	server.Noticef("Reloaded: connect_blocklist")
+}
+
+// accessLogOption implements the option interface for the `access_log` setting.
+type accessLogOption struct {
+	noopOption
+	newValue AccessLogOpts
+}
+
+// Apply the setting by restarting the access log with the new subjects, file and rate.
+func (a *accessLogOption) Apply(server *Server) {
+	if err := server.reloadAccessLog(); err != nil {
+		server.Errorf("Failed to reload access_log: %v", err)
+		return
+	}
+	server.Noticef("Reloaded: access_log")
+}
+
 // metadataOption implements the option interface for the `metadata` setting.
 type metadataOption struct {
 	noopOption // Not authOption because this is a no-op; will be reloaded with options.
@@ -442,6 +469,9 @@ func (c *clusterOption) Apply(s *Server) {
 				r.mu.Unlock()
 				return
 			}
+			// The configured mode takes precedence over the one that would be
+			// restored after recovering from the pending soft limit.
+			r.route.pendPrevComp = _EMPTY_
 			// We need to close the route if it had compression "off" or the new
 			// mode is compression "off", or if the new mode is "accept", because
 			// these require negotiation.
@@ -1201,7 +1231,9 @@ func (l *leafNodeOption) Apply(s *Server) {
 	}
 	// Start the ones that have been enabled.
 	for _, r := range enable {
-		s.connectToRemoteLeafNodeAsynchronously(r, true)
+		if s.admitLeafNodeRemote(r) {
+			s.connectToRemoteLeafNodeAsynchronously(r, true)
+		}
 	}
 	// Finally, deal with the ones that have been added.
 	if len(l.added) > 0 {
@@ -1563,7 +1595,8 @@ func imposeOrder(value any) error {
 		slices.Sort(value.AllowedOrigins)
 	case string, bool, uint8, uint16, uint64, int, int32, int64, time.Duration, float64, nil, LeafNodeOpts, ClusterOpts, *tls.Config, PinnedCertSet,
 		*URLAccResolver, *MemAccResolver, *DirAccResolver, *CacheDirAccResolver, Authentication, MQTTOpts, jwt.TagList,
-		*OCSPConfig, map[string]string, map[string]bool, JSLimitOpts, StoreCipher, *OCSPResponseCacheConfig, *ProxiesConfig, WriteTimeoutPolicy:
+		*OCSPConfig, map[string]string, map[string]bool, JSLimitOpts, StoreCipher, *OCSPResponseCacheConfig, *ProxiesConfig, WriteTimeoutPolicy, FanoutPolicy, IOErrorPolicy, AccessLogOpts, ConnectBlocklistOpts, JSRaftOpts,
+		map[string]map[string][]*MapDest:
 		// explicitly skipped types
 	case *AuthCallout:
 	case JSTpmOpts:
@@ -1671,6 +1704,10 @@ func (s *Server) diffOptions(newOpts *Options) ([]option, error) {
 			diffOpts = append(diffOpts, &usernameOption{})
 		case "password":
 			diffOpts = append(diffOpts, &passwordOption{})
+		case "accesslog":
+			diffOpts = append(diffOpts, &accessLogOption{newValue: newValue.(AccessLogOpts)})
+		case "connectblocklist":
+			diffOpts = append(diffOpts, &connectBlocklistOption{newValue: newValue.(ConnectBlocklistOpts)})
 		case "tags":
 			diffOpts = append(diffOpts, &tagsOption{})
 		case "metadata":
@@ -1862,6 +1899,10 @@ func (s *Server) diffOptions(newOpts *Options) ([]option, error) {
 			}
 		case "jetstreammetacompact", "jetstreammetacompactsize", "jetstreammetacompactsync":
 			// Allowed at runtime but monitorCluster looks at s.opts directly, so no further work needed here.
+		case "jetstreamioerrorpolicy":
+			// Allowed at runtime, streams look at s.opts when an I/O error occurs.
+		case "jetstreammaxrecoveries":
+			// Allowed at runtime, but only used when recovering on startup.
 		case "websocket":
 			// Similar to gateways
 			tmpOld := oldValue.(WebsocketOpts)
@@ -7409,3 +7409,27 @@ func TestJetStreamReloadMaxMemAndStore(t *testing.T) {
 	require_Equal(t, cfg.MaxMemory, 512*1024*1024)
 	require_Equal(t, cfg.MaxStore, 512*1024*1024)
 }
+
+func TestConfigReloadConnectBlocklist(t *testing.T) {
+	s, _, conf := runReloadServerWithContent(t, []byte(`
+		listen: "127.0.0.1:-1"
+		connect_blocklist { names: ["bad"] }
+	`))
+	defer s.Shutdown()
+
+	_, err := nats.Connect(s.ClientURL(), nats.Name("bad"))
+	require_Error(t, err)
+	nc := natsConnect(t, s.ClientURL(), nats.Name("good"))
+	nc.Close()
+
+	changeCurrentConfigContentWithNewContent(t, conf, []byte(`
+		listen: "127.0.0.1:-1"
+		connect_blocklist { names: ["good"] }
+	`))
+	require_NoError(t, s.Reload())
+
+	nc = natsConnect(t, s.ClientURL(), nats.Name("bad"))
+	nc.Close()
+	_, err = nats.Connect(s.ClientURL(), nats.Name("good"))
+	require_Error(t, err)
+}
@@ -91,6 +91,13 @@ type route struct {
 	// the creation of the next after receiving a PONG, ensuring
 	// that authentication did not fail.
 	startNewRoute *routeInfo
+	// Snapshot of the configured pending bytes soft and hard limits.
+	pendSoft int64
+	pendHard int64
+	// Set when the pending bytes went over the soft limit. While set,
+	// pendPrevComp holds the compression mode to restore on recovery.
+	pendSoftHit  bool
+	pendPrevComp string
 }
 
 // This contains the information required to create a new route.
@@ -489,6 +496,14 @@ func (c *client) processInboundRoutedMsg(msg []byte) {
 	acc.stats.rt.inBytes += int64(size)
 	acc.stats.Unlock()
 
+	// The path of a message from a leafnode is only passed on to leafnodes.
+	c.pa.leafPath = nil
+	if c.srv.leafLoopMaxPath > 0 && c.pa.hdr > 0 {
+		if path := sliceHeader(LeafNodePathHdr, msg[:c.pa.hdr]); len(path) > 0 {
+			msg = c.takeLeafNodePath(path, msg)
+		}
+	}
+
 	// Check for no interest, short circuit if so.
 	// This is the fanout scale.
 	if len(r.psubs)+len(r.qsubs) > 0 {
@@ -1018,19 +1033,25 @@ func (s *Server) sendAsyncInfoToClients(regCli, wsCli bool) {
 	info := s.copyInfo()
 
 	for _, c := range s.clients {
-		c.mu.Lock()
-		// Here, we are going to send only to the clients that are fully
-		// registered (server has received CONNECT and first PING). For
-		// clients that are not at this stage, this will happen in the
-		// processing of the first PING (see client.processPing)
-		if ((regCli && !c.isWebsocket()) || (wsCli && c.isWebsocket())) &&
-			c.opts.Protocol >= ClientProtoInfo &&
-			c.flags.isSet(firstPongSent) {
-			// sendInfo takes care of checking if the connection is still
-			// valid or not, so don't duplicate tests here.
-			c.enqueueProto(c.generateClientInfoJSON(info, true))
-		}
-		c.mu.Unlock()
+		c.sendAsyncInfo(info, regCli, wsCli)
+	}
+}
+
+// sendAsyncInfo sends the INFO protocol to this client if it accepts
+// async INFO updates. Client lock should not be held.
+func (c *client) sendAsyncInfo(info Info, regCli, wsCli bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Here, we are going to send only to the clients that are fully
+	// registered (server has received CONNECT and first PING). For
+	// clients that are not at this stage, this will happen in the
+	// processing of the first PING (see client.processPing)
+	if ((regCli && !c.isWebsocket()) || (wsCli && c.isWebsocket())) &&
+		c.opts.Protocol >= ClientProtoInfo &&
+		c.flags.isSet(firstPongSent) {
+		// sendInfo takes care of checking if the connection is still
+		// valid or not, so don't duplicate tests here.
+		c.enqueueProto(c.generateClientInfoJSON(info, true))
 	}
 }
 
@@ -1929,12 +1950,60 @@ func (c *client) sendRouteSubOrUnSubProtos(subs []*subscription, isSubProto, tra
 	c.enqueueProto(buf)
 }
 
+// Checks the route's pending bytes against the configured soft and hard
+// limits. Crossing the soft limit switches the route to the best
+// compression level if compression is in use, crossing the hard limit
+// closes the route. Returns true if the route has been closed.
+// Lock is held on entry.
+func (c *client) checkRoutePendingLimits(size int) bool {
+	r := c.route
+	if r.pendHard > 0 && c.out.pb > r.pendHard {
+		c.out.pb -= int64(size)
+		atomic.AddInt64(&c.srv.slowConsumers, 1)
+		c.srv.scStats.routes.Add(1)
+		c.Noticef("Slow Route Detected: Pending hard limit of %d exceeded", r.pendHard)
+		c.markConnAsClosed(SlowConsumerPendingBytes)
+		return true
+	}
+	if r.pendSoft > 0 && !r.pendSoftHit && c.out.pb > r.pendSoft {
+		r.pendSoftHit = true
+		if needsCompression(r.compression) && r.compression != CompressionS2Best {
+			r.pendPrevComp = r.compression
+			r.compression = CompressionS2Best
+			c.out.cw = s2.NewWriter(nil, s2WriterOptions(CompressionS2Best)...)
+			c.Noticef("Route pending soft limit of %d exceeded, switching compression from %q to %q",
+				r.pendSoft, r.pendPrevComp, CompressionS2Best)
+		} else {
+			c.Noticef("Route pending soft limit of %d exceeded", r.pendSoft)
+		}
+	}
+	return false
+}
+
+// Invoked after pending bytes have been flushed to check if a route that
+// went over its soft pending limit has recovered, in which case the
+// previous compression mode is restored.
+// Lock is held on entry.
+func (c *client) checkRoutePendingRecovered() {
+	r := c.route
+	if c.out.pb >= r.pendSoft/2 {
+		return
+	}
+	r.pendSoftHit = false
+	if r.pendPrevComp != _EMPTY_ {
+		r.compression, r.pendPrevComp = r.pendPrevComp, _EMPTY_
+		c.out.cw = s2.NewWriter(nil, s2WriterOptions(r.compression)...)
+	}
+	c.Noticef("Route pending bytes back under soft limit of %d", r.pendSoft)
+}
+
 func (s *Server) createRoute(conn net.Conn, rURL *url.URL, rtype RouteType, gossipMode byte, accName string) *client {
 	// Snapshot server options.
 	opts := s.getOpts()
 
 	didSolicit := rURL != nil
 	r := &route{routeType: rtype, didSolicit: didSolicit, poolIdx: -1, gossipMode: gossipMode}
+	r.pendSoft, r.pendHard = opts.Cluster.PendingSoftLimit, opts.Cluster.PendingHardLimit
 
 	c := &client{srv: s, nc: conn, opts: ClientOpts{}, kind: ROUTER, msubs: -1, mpay: -1, route: r, start: time.Now()}
 
@@ -4786,6 +4786,110 @@ func TestRouteSlowConsumerRecover(t *testing.T) {
 	wg.Wait()
 }
 
+func TestRoutePendingSoftAndHardLimits(t *testing.T) {
+	tmpl := `
+		listen: "127.0.0.1:-1"
+		server_name: "%s"
+		cluster {
+			name: "local"
+			listen: "127.0.0.1:-1"
+			pool_size: -1
+			compression: s2_fast
+			pending_soft_limit: 64KB
+			pending_hard_limit: 1MB
+			%s
+		}
+	`
+	conf1 := createConfFile(t, []byte(fmt.Sprintf(tmpl, "A", _EMPTY_)))
+	s1, o1 := RunServerWithConfig(conf1)
+	defer s1.Shutdown()
+	require_Equal(t, o1.Cluster.PendingSoftLimit, 64*1024)
+	require_Equal(t, o1.Cluster.PendingHardLimit, 1024*1024)
+
+	conf2 := createConfFile(t, []byte(fmt.Sprintf(tmpl, "B",
+		fmt.Sprintf("routes: [\"nats://127.0.0.1:%d\"]", o1.Cluster.Port))))
+	s2, _ := RunServerWithConfig(conf2)
+	defer s2.Shutdown()
+
+	checkClusterFormed(t, s1, s2)
+
+	l := &captureNoticeLogger{}
+	s1.SetLogger(l, false, false)
+
+	var rc *client
+	s1.mu.RLock()
+	for _, cl := range s1.routes {
+		rc = cl[0]
+	}
+	s1.mu.RUnlock()
+	require_NotNil(t, rc)
+
+	// Flood the route while holding its lock so that nothing is flushed.
+	chunk := bytes.Repeat([]byte("PING\r\n"), 1024)
+	rc.mu.Lock()
+	require_Equal(t, rc.route.compression, CompressionS2Fast)
+	var softAt, hardAt int64
+	for i := 0; i < 1000 && !rc.isClosed(); i++ {
+		rc.queueOutbound(chunk)
+		if softAt == 0 && rc.route.pendSoftHit {
+			softAt = rc.out.pb
+			require_Equal(t, rc.route.compression, CompressionS2Best)
+			require_False(t, rc.isClosed())
+		}
+		if rc.isClosed() {
+			hardAt = rc.out.pb + int64(len(chunk))
+		}
+	}
+	rc.mu.Unlock()
+
+	// The soft action must have been taken well before the route was closed.
+	require_True(t, softAt > 64*1024)
+	require_True(t, hardAt > 1024*1024)
+	require_True(t, softAt < hardAt)
+	require_Equal(t, s1.NumSlowConsumersRoutes(), 1)
+
+	l.Lock()
+	notices := append([]string(nil), l.notices...)
+	l.Unlock()
+	softIdx, hardIdx := -1, -1
+	for i, n := range notices {
+		if softIdx < 0 && strings.Contains(n, "pending soft limit of 65536 exceeded") {
+			softIdx = i
+		} else if hardIdx < 0 && strings.Contains(n, "Pending hard limit of 1048576 exceeded") {
+			hardIdx = i
+		}
+	}
+	if softIdx < 0 || hardIdx < 0 || softIdx > hardIdx {
+		t.Fatalf("Expected soft limit notice before hard limit one, got %q", notices)
+	}
+
+	// Check that the route reconnects.
+	checkClusterFormed(t, s1, s2)
+}
+
+func TestRoutePendingLimitsConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		soft int64
+		hard int64
+		err  string
+	}{
+		{"negative soft", -1, 0, "cannot be negative"},
+		{"negative hard", 0, -1, "cannot be negative"},
+		{"soft equal hard", 1024, 1024, "must be lower than"},
+		{"soft above hard", 2048, 1024, "must be lower than"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			o := DefaultOptions()
+			o.Cluster.PendingSoftLimit = test.soft
+			o.Cluster.PendingHardLimit = test.hard
+			_, err := NewServer(o)
+			require_Error(t, err)
+			require_Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
 func TestRouteNoLeakOnAuthTimeout(t *testing.T) {
 	opts := DefaultOptions()
 	opts.Cluster.Username = "foo"
@@ -0,0 +1,328 @@
+// Copyright 2026 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// SchemaErrorHdr is set on messages that failed schema validation and
+	// were flagged or sent to a dead letter subject. It holds the reason.
+	SchemaErrorHdr = "Nats-Schema-Error"
+	// SchemaSubjectHdr holds the original subject of a message that was
+	// sent to a dead letter subject after failing schema validation.
+	SchemaSubjectHdr = "Nats-Schema-Subject"
+)
+
+// SchemaAction determines what happens to a message that fails validation.
+type SchemaAction int
+
+const (
+	// SchemaReject drops the message and reports an error to the publisher.
+	SchemaReject SchemaAction = iota
+	// SchemaDeadLetter publishes the message to a dead letter subject instead.
+	SchemaDeadLetter
+	// SchemaFlag delivers the message as is with the SchemaErrorHdr set.
+	SchemaFlag
+)
+
+func (a SchemaAction) String() string {
+	switch a {
+	case SchemaReject:
+		return "reject"
+	case SchemaDeadLetter:
+		return "dlq"
+	case SchemaFlag:
+		return "flag"
+	}
+	return "unknown"
+}
+
+func parseSchemaAction(s string) (SchemaAction, error) {
+	switch strings.ToLower(s) {
+	case "reject":
+		return SchemaReject, nil
+	case "dlq", "dead_letter":
+		return SchemaDeadLetter, nil
+	case "flag":
+		return SchemaFlag, nil
+	}
+	return SchemaReject, fmt.Errorf("unknown schema failure action %q", s)
+}
+
+// subjectSchema is a schema registered for a subject in an account.
+type subjectSchema struct {
+	subject string
+	schema  *jsonSchema
+	action  SchemaAction
+	dlq     string
+}
+
+// jsonSchema is the subset of JSON Schema we support for payload validation.
+type jsonSchema struct {
+	Type                 jsonSchemaTypes        `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// jsonSchemaTypes allows "type" to be either a single string or a list.
+type jsonSchemaTypes []string
+
+func (t *jsonSchemaTypes) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = jsonSchemaTypes{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = l
+	return nil
+}
+
+var jsonSchemaKnownTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// parseJSONSchema parses and compiles a JSON schema document.
+func parseJSONSchema(b []byte) (*jsonSchema, error) {
+	var js jsonSchema
+	if err := json.Unmarshal(b, &js); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	if err := js.compile(); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	return &js, nil
+}
+
+func (js *jsonSchema) compile() error {
+	for _, t := range js.Type {
+		if !slices.Contains(jsonSchemaKnownTypes, t) {
+			return fmt.Errorf("unknown type %q", t)
+		}
+	}
+	if js.Pattern != _EMPTY_ {
+		re, err := regexp.Compile(js.Pattern)
+		if err != nil {
+			return fmt.Errorf("bad pattern %q: %v", js.Pattern, err)
+		}
+		js.re = re
+	}
+	for _, p := range js.Properties {
+		if p == nil {
+			continue
+		}
+		if err := p.compile(); err != nil {
+			return err
+		}
+	}
+	if js.Items != nil {
+		return js.Items.compile()
+	}
+	return nil
+}
+
+// validatePayload checks that the payload is a JSON document that
+// conforms to the schema.
+func (js *jsonSchema) validatePayload(payload []byte) error {
+	var v any
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return errors.New("payload is not valid JSON")
+	}
+	return js.validate(v, "$")
+}
+
+func (js *jsonSchema) validate(v any, path string) error {
+	if len(js.Type) > 0 && !slices.ContainsFunc(js.Type, func(t string) bool { return jsonSchemaIsType(v, t) }) {
+		return fmt.Errorf("%s: expected %s", path, strings.Join(js.Type, " or "))
+	}
+	if len(js.Enum) > 0 && !slices.ContainsFunc(js.Enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		return fmt.Errorf("%s: value not in enum", path)
+	}
+	switch vv := v.(type) {
+	case float64:
+		if js.Minimum != nil && vv < *js.Minimum {
+			return fmt.Errorf("%s: %v is less than minimum %v", path, vv, *js.Minimum)
+		}
+		if js.Maximum != nil && vv > *js.Maximum {
+			return fmt.Errorf("%s: %v is greater than maximum %v", path, vv, *js.Maximum)
+		}
+	case string:
+		n := len([]rune(vv))
+		if js.MinLength != nil && n < *js.MinLength {
+			return fmt.Errorf("%s: length %d is less than minLength %d", path, n, *js.MinLength)
+		}
+		if js.MaxLength != nil && n > *js.MaxLength {
+			return fmt.Errorf("%s: length %d is greater than maxLength %d", path, n, *js.MaxLength)
+		}
+		if js.re != nil && !js.re.MatchString(vv) {
+			return fmt.Errorf("%s: does not match pattern %q", path, js.Pattern)
+		}
+	case []any:
+		if js.MinItems != nil && len(vv) < *js.MinItems {
+			return fmt.Errorf("%s: %d items is less than minItems %d", path, len(vv), *js.MinItems)
+		}
+		if js.MaxItems != nil && len(vv) > *js.MaxItems {
+			return fmt.Errorf("%s: %d items is greater than maxItems %d", path, len(vv), *js.MaxItems)
+		}
+		if js.Items != nil {
+			for i, e := range vv {
+				if err := js.Items.validate(e, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, r := range js.Required {
+			if _, ok := vv[r]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, r)
+			}
+		}
+		for k, e := range vv {
+			p, ok := js.Properties[k]
+			if !ok {
+				if js.AdditionalProperties != nil && !*js.AdditionalProperties {
+					return fmt.Errorf("%s: unexpected property %q", path, k)
+				}
+				continue
+			}
+			if p == nil {
+				continue
+			}
+			if err := p.validate(e, path+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonSchemaIsType(v any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return false
+}
+
+// addSchema registers a schema for messages published to the subject.
+// The first registered schema matching a subject is the one used.
+func (a *Account) addSchema(subject string, schema []byte, action SchemaAction, dlq string) error {
+	if !IsValidSubject(subject) {
+		return ErrBadSubject
+	}
+	js, err := parseJSONSchema(schema)
+	if err != nil {
+		return err
+	}
+	if action == SchemaDeadLetter {
+		if dlq == _EMPTY_ {
+			return errors.New("dead letter subject required")
+		}
+		if !IsValidPublishSubject(dlq) {
+			return fmt.Errorf("dead letter subject %q is not a valid publish subject", dlq)
+		}
+		if subjectIsSubsetMatch(dlq, subject) {
+			return fmt.Errorf("dead letter subject %q can not match schema subject %q", dlq, subject)
+		}
+	}
+	a.mu.Lock()
+	a.schemas = append(a.schemas, &subjectSchema{subject, js, action, dlq})
+	a.hasSchemas.Store(true)
+	a.mu.Unlock()
+	return nil
+}
+
+// schemaForSubject returns the schema registered for the subject, if any.
+func (a *Account) schemaForSubject(subject string) *subjectSchema {
+	if !a.hasSchemas.Load() {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, ss := range a.schemas {
+		if subjectIsSubsetMatch(subject, ss.subject) {
+			return ss
+		}
+	}
+	return nil
+}
+
+// checkSchema validates the payload of an inbound client message against the
+// schema registered for its subject. It returns the message to process, which
+// may have been modified, and false if the message should be dropped.
+// Should only be called from the inbound go routine.
+func (c *client) checkSchema(acc *Account, msg []byte) ([]byte, bool) {
+	ss := acc.schemaForSubject(bytesToString(c.pa.subject))
+	if ss == nil {
+		return msg, true
+	}
+	var payload []byte
+	if start := max(c.pa.hdr, 0); start <= c.pa.size && c.pa.size <= len(msg) {
+		payload = msg[start:c.pa.size]
+	}
+	err := ss.schema.validatePayload(payload)
+	if err == nil {
+		return msg, true
+	}
+	c.Debugf("Schema validation failed for %q (%s): %v", c.pa.subject, ss.action, err)
+	switch ss.action {
+	case SchemaReject:
+		c.pubLimitViolation(c.pa.subject, fmt.Sprintf("schema validation failed: %v", err), nil)
+		return msg, false
+	case SchemaDeadLetter:
+		msg = c.setHeader(SchemaSubjectHdr, string(c.pa.subject), msg)
+		c.pa.subject = []byte(ss.dlq)
+	}
+	return c.setHeader(SchemaErrorHdr, err.Error(), msg), true
+}
@@ -0,0 +1,216 @@
+// Copyright 2026 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestSchemaValidation(t *testing.T) {
+	schema, err := parseJSONSchema([]byte(`{
+		"type": "object",
+		"required": ["id", "qty"],
+		"additionalProperties": false,
+		"properties": {
+			"id": {"type": "string", "pattern": "^[a-z]+-[0-9]+$"},
+			"qty": {"type": "integer", "minimum": 1, "maximum": 10},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string", "minLength": 1}},
+			"kind": {"enum": ["a", "b"]},
+			"note": {"type": ["string", "null"], "maxLength": 5}
+		}
+	}`))
+	require_NoError(t, err)
+
+	for _, test := range []struct {
+		payload string
+		err     string
+	}{
+		{`{"id":"ab-1","qty":1}`, _EMPTY_},
+		{`{"id":"ab-1","qty":10,"tags":["x","y"],"kind":"b","note":null}`, _EMPTY_},
+		{`{"id":"ab-1","qty":2,"note":"hello"}`, _EMPTY_},
+		{`not json`, "payload is not valid JSON"},
+		{``, "payload is not valid JSON"},
+		{`[]`, "$: expected object"},
+		{`{"id":"ab-1"}`, `$: missing required property "qty"`},
+		{`{"id":"AB","qty":1}`, `$.id: does not match pattern`},
+		{`{"id":"ab-1","qty":1.5}`, "$.qty: expected integer"},
+		{`{"id":"ab-1","qty":0}`, "$.qty: 0 is less than minimum 1"},
+		{`{"id":"ab-1","qty":11}`, "$.qty: 11 is greater than maximum 10"},
+		{`{"id":"ab-1","qty":1,"tags":["x","y","z"]}`, "$.tags: 3 items is greater than maxItems 2"},
+		{`{"id":"ab-1","qty":1,"tags":["x",""]}`, "$.tags[1]: length 0 is less than minLength 1"},
+		{`{"id":"ab-1","qty":1,"kind":"c"}`, "$.kind: value not in enum"},
+		{`{"id":"ab-1","qty":1,"note":"too long"}`, "$.note: length 8 is greater than maxLength 5"},
+		{`{"id":"ab-1","qty":1,"note":1}`, "$.note: expected string or null"},
+		{`{"id":"ab-1","qty":1,"extra":true}`, `$: unexpected property "extra"`},
+	} {
+		err := schema.validatePayload([]byte(test.payload))
+		if test.err == _EMPTY_ {
+			require_NoError(t, err)
+		} else if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("Expected error %q for %q, got %v", test.err, test.payload, err)
+		}
+	}
+
+	for _, bad := range []string{
+		`{"type": "thing"}`,
+		`{"type": 1}`,
+		`{"pattern": "("}`,
+		`{"properties": {"a": {"type": "nope"}}}`,
+		`{"items": {"pattern": "["}}`,
+		`not json`,
+	} {
+		_, err := parseJSONSchema([]byte(bad))
+		require_Error(t, err)
+	}
+}
+
+func TestSchemaFailureActions(t *testing.T) {
+	schema := `{"type":"object","required":["id"],"properties":{"id":{"type":"integer"}}}`
+	sf := filepath.Join(t.TempDir(), "schema.json")
+	require_NoError(t, os.WriteFile(sf, []byte(schema), 0644))
+
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		accounts {
+			A {
+				users: [{user: a, password: pwd}]
+				schemas: [
+					{subject: "orders.reject.>", schema: '%s', action: reject}
+					{subject: "orders.dlq.>", schema_file: "%s", action: dlq, dlq: "dead.orders"}
+					{subject: "orders.flag.>", schema: '%s', action: flag}
+				]
+			}
+			B {
+				users: [{user: b, password: pwd}]
+			}
+		}
+	`, schema, filepath.ToSlash(sf), schema)))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	errCh := make(chan error, 10)
+	nc, err := nats.Connect(s.ClientURL(), nats.UserInfo("a", "pwd"),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			errCh <- err
+		}))
+	require_NoError(t, err)
+	defer nc.Close()
+
+	sub := natsSubSync(t, nc, "orders.>")
+	dead := natsSubSync(t, nc, "dead.>")
+	natsFlush(t, nc)
+
+	valid, invalid := []byte(`{"id":1}`), []byte(`{"id":"one"}`)
+
+	// Valid payloads go through untouched for all actions.
+	for _, subj := range []string{"orders.reject.1", "orders.dlq.1", "orders.flag.1", "orders.other"} {
+		natsPub(t, nc, subj, valid)
+		m := natsNexMsg(t, sub, time.Second)
+		require_Equal(t, m.Subject, subj)
+		require_Equal(t, string(m.Data), string(valid))
+		require_Equal(t, m.Header.Get(SchemaErrorHdr), _EMPTY_)
+	}
+
+	// Subjects without a schema are not validated.
+	natsPub(t, nc, "orders.other", invalid)
+	m := natsNexMsg(t, sub, time.Second)
+	require_Equal(t, m.Header.Get(SchemaErrorHdr), _EMPTY_)
+
+	// Rejected messages are dropped and the publisher receives an error.
+	natsPub(t, nc, "orders.reject.1", invalid)
+	select {
+	case err := <-errCh:
+		require_Contains(t, err.Error(), "permissions violation", "schema validation failed", `orders.reject.1`, "$.id: expected integer")
+	case <-time.After(2 * time.Second):
+		t.Fatal("Did not get schema violation error")
+	}
+	if m, err := sub.NextMsg(100 * time.Millisecond); err == nil {
+		t.Fatalf("Rejected message was delivered: %+v", m)
+	}
+	// The connection is still usable.
+	require_True(t, nc.IsConnected())
+
+	// Dead lettered messages are moved to the configured subject.
+	natsPub(t, nc, "orders.dlq.1", invalid)
+	m = natsNexMsg(t, dead, time.Second)
+	require_Equal(t, m.Subject, "dead.orders")
+	require_Equal(t, string(m.Data), string(invalid))
+	require_Equal(t, m.Header.Get(SchemaSubjectHdr), "orders.dlq.1")
+	require_Contains(t, m.Header.Get(SchemaErrorHdr), "$.id: expected integer")
+	if m, err := sub.NextMsg(100 * time.Millisecond); err == nil {
+		t.Fatalf("Dead lettered message was delivered on original subject: %+v", m)
+	}
+
+	// Flagged messages are delivered with the error header, existing headers are kept.
+	msg := nats.NewMsg("orders.flag.1")
+	msg.Header.Set("X-Test", "ok")
+	msg.Data = invalid
+	require_NoError(t, nc.PublishMsg(msg))
+	m = natsNexMsg(t, sub, time.Second)
+	require_Equal(t, m.Subject, "orders.flag.1")
+	require_Equal(t, string(m.Data), string(invalid))
+	require_Equal(t, m.Header.Get("X-Test"), "ok")
+	require_Contains(t, m.Header.Get(SchemaErrorHdr), "$.id: expected integer")
+
+	// Non JSON payloads fail too.
+	natsPub(t, nc, "orders.flag.1", []byte("hello"))
+	m = natsNexMsg(t, sub, time.Second)
+	require_Equal(t, m.Header.Get(SchemaErrorHdr), "payload is not valid JSON")
+
+	// Schemas are per account.
+	ncb := natsConnect(t, s.ClientURL(), nats.UserInfo("b", "pwd"))
+	defer ncb.Close()
+	subb := natsSubSync(t, ncb, "orders.>")
+	natsFlush(t, ncb)
+	natsPub(t, ncb, "orders.reject.1", invalid)
+	m = natsNexMsg(t, subb, time.Second)
+	require_Equal(t, string(m.Data), string(invalid))
+	require_Equal(t, m.Header.Get(SchemaErrorHdr), _EMPTY_)
+}
+
+func TestSchemaConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{"not an array", `schemas: {subject: foo}`, "Expected account schemas to be an array"},
+		{"missing schema", `schemas: [{subject: foo}]`, "requires a subject and a schema"},
+		{"missing subject", `schemas: [{schema: '{}'}]`, "requires a subject and a schema"},
+		{"bad subject", `schemas: [{subject: "foo..bar", schema: '{}'}]`, "invalid subject"},
+		{"bad schema", `schemas: [{subject: foo, schema: '{"type":"thing"}'}]`, `unknown type "thing"`},
+		{"bad action", `schemas: [{subject: foo, schema: '{}', action: drop}]`, `unknown schema failure action "drop"`},
+		{"missing dlq", `schemas: [{subject: foo, schema: '{}', action: dlq}]`, "dead letter subject required"},
+		{"bad dlq", `schemas: [{subject: foo, schema: '{}', action: dlq, dlq: "bar.*"}]`, "not a valid publish subject"},
+		{"looping dlq", `schemas: [{subject: "foo.>", schema: '{}', action: dlq, dlq: "foo.dead"}]`, "can not match schema subject"},
+		{"missing file", `schemas: [{subject: foo, schema_file: "/does/not/exist.json"}]`, "Error reading schema file"},
+		{"unknown field", `schemas: [{subject: foo, schema: '{}', color: red}]`, `Unknown field "color"`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf := createConfFile(t, []byte(fmt.Sprintf(`
+				accounts { A { %s } }
+			`, test.schema)))
+			_, err := ProcessConfigFile(conf)
+			require_Error(t, err)
+			require_Contains(t, err.Error(), test.err)
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"math/rand"
 	"net"
 	"net/http"
@@ -48,6 +49,7 @@ import (
 	"github.com/klauspost/compress/s2"
 	"github.com/nats-io/jwt/v2"
 	"github.com/nats-io/nats-server/v2/logger"
+	"github.com/nats-io/nats-server/v2/server/pse"
 	"github.com/nats-io/nkeys"
 	"github.com/nats-io/nuid"
 )
@@ -137,6 +139,9 @@ type Info struct {
 	IsSystemAccount   bool     `json:"acc_is_sys,omitempty"`     // Indicates if the account is a system account.
 	JSApiLevel        int      `json:"api_lvl,omitempty"`
 
+	// Load hints for clients balancing across servers, only set when configured.
+	LoadHints *LoadHints `json:"load_hints,omitempty"`
+
 	// Route Specific
 	Import        *SubjectPermission `json:"import,omitempty"`
 	Export        *SubjectPermission `json:"export,omitempty"`
@@ -157,6 +162,7 @@ type Info struct {
 	GatewayCmdPayload []byte   `json:"gateway_cmd_payload,omitempty"` // Command payload when needed
 	GatewayNRP        bool     `json:"gateway_nrp,omitempty"`         // Uses new $GNR. prefix for mapped replies
 	GatewayIOM        bool     `json:"gateway_iom,omitempty"`         // Indicate that all accounts will be switched to InterestOnly mode "right away"
+	GatewayASC        bool     `json:"gateway_asc,omitempty"`         // Supports being told once all accounts have been switched to InterestOnly mode
 
 	// LeafNode Specific
 	LeafNodeURLs []string `json:"leafnode_urls,omitempty"` // LeafNode URLs that the server can reconnect to.
@@ -164,6 +170,14 @@ type Info struct {
 	XKey string `json:"xkey,omitempty"` // Public server's x25519 key.
 }
 
+// LoadHints describe the load of a server, so that clients
+// can prefer less loaded servers when reconnecting.
+type LoadHints struct {
+	Connections    int     `json:"connections"`
+	MaxConnections int     `json:"max_connections,omitempty"`
+	CPU            float64 `json:"cpu"`
+}
+
 // Server is our main struct.
 type Server struct {
 	// Fields accessed with atomic operations need to be 64-bit aligned
@@ -239,6 +253,11 @@ type Server struct {
 	leafNodeEnabled    bool
 	leafDisableConnect bool // Used in test only
 	leafNoCluster      bool // Indicate that this server has only remotes and no cluster defined
+	leafLoopMaxPath    int  // Max clusters in the path of a leafnode message, zero if loop detection is disabled
+
+	// Looping leafnode messages dropped since the last advisory, and when it was sent.
+	leafLoopDrops atomic.Uint64
+	leafLoopAdv   atomic.Int64
 
 	quitCh           chan struct{}
 	startupComplete  chan struct{}
@@ -356,12 +375,24 @@ type Server struct {
 	rateLimitLogging   sync.Map
 	rateLimitLoggingCh chan time.Duration
 
+	// Subject access log, if configured.
+	accessLog atomic.Pointer[accessLog]
+
+	connBlocklist atomic.Pointer[connectBlocklist]
+
 	// Total outstanding catchup bytes in flight.
 	gcbMu     sync.RWMutex
 	gcbOut    int64
 	gcbOutMax int64 // Taken from JetStreamMaxCatchup or defaultMaxTotalCatchupOutBytes
 	// A global chanel to kick out stalled catchup sequences.
 	gcbKick chan struct{}
+	// Limits the number of catchups running concurrently as leader,
+	// nil if not limited. Taken from JetStreamMaxConcurrentCatchups.
+	gcbSem chan struct{}
+	// Limits the number of Raft snapshots written concurrently, nil if not limited.
+	// Taken from JetStreamMaxSnapshots.
+	snapMu  sync.RWMutex
+	snapSem chan struct{}
 
 	// Total outbound syncRequests
 	syncOutSem chan struct{}
@@ -803,6 +834,15 @@ func NewServer(opts *Options) (*Server, error) {
 		opts.Cluster.Name = opts.ServerName
 	}
 
+	if opts.LeafNode.LoopDetection {
+		s.leafLoopMaxPath = opts.LeafNode.LoopDetectionMaxPath
+		if s.leafLoopMaxPath <= 0 {
+			s.leafLoopMaxPath = leafNodeLoopMaxPathDefault
+		}
+	}
+
+	s.connBlocklist.Store(newConnectBlocklist(&opts.ConnectBlocklist))
+
 	if opts.Cluster.Name != _EMPTY_ {
 		// Also place into mapping cn with cnMu lock.
 		s.cnMu.Lock()
@@ -992,6 +1032,60 @@ func (s *Server) logRejectedTLSConns() {
 	}
 }
 
+// Returns the CPU usage of the process used for load hints.
+// Variable so that tests can simulate load.
+var loadHintsCPU = func() float64 {
+	var pcpu float64
+	var rss, vss int64
+	pse.ProcUsage(&pcpu, &rss, &vss)
+	return pcpu
+}
+
+func (s *Server) updateLoadHintsLoop(interval time.Duration) {
+	defer s.grWG.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.quitCh:
+			return
+		case <-t.C:
+			s.updateLoadHints()
+		}
+	}
+}
+
+// updateLoadHints samples the load of this server and, if it changed, updates
+// the INFO and sends it to all clients that accept async INFO updates.
+func (s *Server) updateLoadHints() {
+	cpu := math.Round(loadHintsCPU()*10) / 10
+	maxConn := s.getOpts().MaxConn
+
+	s.mu.Lock()
+	lh := &LoadHints{Connections: len(s.clients), MaxConnections: maxConn, CPU: cpu}
+	if olh := s.info.LoadHints; olh != nil && *olh == *lh {
+		s.mu.Unlock()
+		return
+	}
+	s.info.LoadHints = lh
+	// If there are no clients supporting async INFO protocols, we are done.
+	if s.cproto == 0 || s.isShuttingDown() {
+		s.mu.Unlock()
+		return
+	}
+	info := s.copyInfo()
+	clients := make([]*client, 0, len(s.clients))
+	for _, c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	// Send outside of the server lock, this can be a lot of clients.
+	for _, c := range clients {
+		c.sendAsyncInfo(info, true, true)
+	}
+}
+
 // clusterName returns our cluster name which could be dynamic.
 func (s *Server) ClusterName() string {
 	s.mu.RLock()
@@ -1099,6 +1193,12 @@ func validateCluster(o *Options) error {
 	if err := validatePinnedCerts(o.Cluster.TLSPinnedCerts); err != nil {
 		return fmt.Errorf("cluster: %v", err)
 	}
+	if o.Cluster.PendingSoftLimit < 0 || o.Cluster.PendingHardLimit < 0 {
+		return fmt.Errorf("cluster: pending limits cannot be negative")
+	}
+	if soft, hard := o.Cluster.PendingSoftLimit, o.Cluster.PendingHardLimit; soft > 0 && hard > 0 && soft >= hard {
+		return fmt.Errorf("cluster: pending soft limit (%d) must be lower than pending hard limit (%d)", soft, hard)
+	}
 	// Check that cluster name if defined matches any gateway name.
 	// Note that we have already verified that the gateway name does not have spaces.
 	if o.Gateway.Name != _EMPTY_ && o.Gateway.Name != o.Cluster.Name {
@@ -2290,6 +2390,12 @@ func (s *Server) Start() {
 
 	s.startRateLimitLogExpiration()
 
+	// Start the subject access log, if configured.
+	if err := s.startAccessLog(); err != nil {
+		s.Fatalf("Can't start access log: %v", err)
+		return
+	}
+
 	// Pprof http endpoint for the profiler.
 	if opts.ProfPort != 0 {
 		s.StartProfiler()
@@ -2537,6 +2643,11 @@ func (s *Server) Start() {
 		s.startGoRoutine(s.logRejectedTLSConns)
 	}
 
+	if opts.LoadHintsInterval > 0 {
+		s.updateLoadHints()
+		s.startGoRoutine(func() { s.updateLoadHintsLoop(opts.LoadHintsInterval) })
+	}
+
 	// We've finished starting up.
 	close(s.startupComplete)
 
@@ -2812,7 +2923,16 @@ func (s *Server) AcceptLoop(clr chan struct{}) {
 	s.clientConnectURLs = s.getClientConnectURLs()
 	s.listener = l
 
-	go s.acceptConnections(l, "Client", func(conn net.Conn) { s.createClient(conn) },
+	createFunc := func(conn net.Conn) { s.createClient(conn) }
+	// If the connect rate is limited, connections are held in a backlog and
+	// created at a smoothed pace, so that reconnect storms can be absorbed.
+	if opts.MaxConnectRate > 0 {
+		backlog := make(chan net.Conn, opts.MaxConnectBacklog)
+		createFunc = func(conn net.Conn) { s.queueClientConnect(backlog, conn) }
+		s.startGoRoutine(func() { s.smoothClientConnects(backlog, opts.MaxConnectRate) })
+	}
+
+	go s.acceptConnections(l, "Client", createFunc,
 		func(_ error) bool {
 			if s.isLameDuckMode() {
 				// Signal that we are not accepting new clients
@@ -2887,6 +3007,68 @@ func (s *Server) acceptConnections(l net.Listener, acceptName string, createFunc
 	s.done <- true
 }
 
+// queueClientConnect places an accepted client connection in the connect backlog.
+// If the backlog is full, the connection is closed right away.
+func (s *Server) queueClientConnect(backlog chan net.Conn, conn net.Conn) {
+	if s.isShuttingDown() {
+		conn.Close()
+		return
+	}
+	select {
+	case backlog <- conn:
+	default:
+		conn.Close()
+		s.RateLimitWarnf("Rejecting client connections, connect backlog of %d is full", cap(backlog))
+	}
+}
+
+// smoothClientConnects creates the client connections held in the backlog,
+// no faster than the given number of connections per second.
+func (s *Server) smoothClientConnects(backlog chan net.Conn, rate int) {
+	defer s.grWG.Done()
+
+	interval := max(time.Second/time.Duration(rate), time.Microsecond)
+	var last time.Time
+	for {
+		select {
+		case <-s.quitCh:
+			s.drainClientConnects(backlog)
+			return
+		case conn := <-backlog:
+			if wait := interval - time.Since(last); wait > 0 {
+				select {
+				case <-s.quitCh:
+					conn.Close()
+					s.drainClientConnects(backlog)
+					return
+				case <-time.After(wait):
+				}
+			}
+			last = time.Now()
+			if !s.startGoRoutine(func() {
+				s.reloadMu.RLock()
+				s.createClient(conn)
+				s.reloadMu.RUnlock()
+				s.grWG.Done()
+			}) {
+				conn.Close()
+			}
+		}
+	}
+}
+
+// drainClientConnects closes any connection still waiting in the backlog.
+func (s *Server) drainClientConnects(backlog chan net.Conn) {
+	for {
+		select {
+		case conn := <-backlog:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
 // This function sets the server's info Host/Port based on server Options.
 // Note that this function may be called during config reload, this is why
 // Host/Port may be reset to original Options if the ClientAdvertise option
@@ -3245,6 +3427,10 @@ func (s *Server) createClientEx(conn net.Conn, inProcess bool) *client {
 		last:  now,
 		iproc: inProcess,
 	}
+	if opts.MaxSubsChurn > 0 {
+		c.churn = newRateCounter(int64(opts.MaxSubsChurn))
+	}
+	c.mfan, c.fpol = opts.MaxFanout, opts.FanoutPolicy
 
 	c.registerWithAccount(s.globalAccount())
 
@@ -4562,11 +4748,17 @@ func (s *Server) sendLDMToClients() {
 	// Reset content first.
 	s.info.ClientConnectURLs = s.info.ClientConnectURLs[:0]
 	s.info.WSConnectURLs = s.info.WSConnectURLs[:0]
-	// Only add the other nodes if we are allowed to.
-	if !s.getOpts().Cluster.NoAdvertise {
+	opts := s.getOpts()
+	// Redirect clients to the preferred servers if configured, otherwise only
+	// add the other nodes if we are allowed to.
+	if len(opts.LameDuckRedirectURLs) > 0 {
+		s.info.ClientConnectURLs = append(s.info.ClientConnectURLs, opts.LameDuckRedirectURLs...)
+	} else if !opts.Cluster.NoAdvertise {
 		for url := range s.clientConnectURLsMap {
 			s.info.ClientConnectURLs = append(s.info.ClientConnectURLs, url)
 		}
+	}
+	if !opts.Cluster.NoAdvertise {
 		for url := range s.websocket.connectURLsMap {
 			s.info.WSConnectURLs = append(s.info.WSConnectURLs, url)
 		}
@@ -4747,6 +4939,9 @@ func (s *Server) LDMClientByID(id uint64) error {
 	}
 	info := s.copyInfo()
 	info.LameDuckMode = true
+	if redirects := s.getOpts().LameDuckRedirectURLs; len(redirects) > 0 {
+		info.ClientConnectURLs = redirects
+	}
 	s.mu.RUnlock()
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -23,6 +23,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/url"
 	"os"
@@ -1288,6 +1289,52 @@ func TestLameDuckModeInfo(t *testing.T) {
 	wg.Wait()
 }
 
+func TestLameDuckModeRedirect(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		listen: 127.0.0.1:-1
+		lame_duck_redirect_urls: ["127.0.0.1:4333", "other.example.com"]
+	`))
+	o := LoadConfig(conf)
+	require_True(t, slices.Equal(o.LameDuckRedirectURLs, []string{"127.0.0.1:4333", "other.example.com:4222"}))
+
+	o.LameDuckDuration = time.Second
+	testSetLDMGracePeriod(o, 500*time.Millisecond)
+	s := RunServer(o)
+	defer s.Shutdown()
+
+	ldmCh := make(chan struct{}, 1)
+	closedCh := make(chan time.Time, 1)
+	nc, err := nats.Connect(s.ClientURL(), nats.NoReconnect(),
+		nats.LameDuckModeHandler(func(_ *nats.Conn) { ldmCh <- struct{}{} }),
+		nats.ClosedHandler(func(_ *nats.Conn) { closedCh <- time.Now() }))
+	require_NoError(t, err)
+	defer nc.Close()
+	require_True(t, len(nc.DiscoveredServers()) == 0)
+
+	start := time.Now()
+	go s.lameDuckMode()
+
+	// The client is advised of the lame duck mode, with the redirect URLs
+	// instead of the ones of the other servers.
+	select {
+	case <-ldmCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Did not get the lame duck mode advisory")
+	}
+	discovered := nc.DiscoveredServers()
+	slices.Sort(discovered)
+	require_True(t, slices.Equal(discovered, []string{"nats://127.0.0.1:4333", "nats://other.example.com:4222"}))
+
+	// The connection is only closed after the grace period.
+	select {
+	case closed := <-closedCh:
+		require_True(t, closed.Sub(start) >= 500*time.Millisecond)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Connection was not closed")
+	}
+	s.WaitForShutdown()
+}
+
 func TestServerValidateGatewaysOptions(t *testing.T) {
 	baseOpt := testDefaultOptionsForGateway("A")
 	u, _ := url.Parse("host:5222")
@@ -2420,3 +2467,162 @@ func TestBuildinfoFormatRevision(t *testing.T) {
 		})
 	}
 }
+
+func TestServerLoadHintsInInfo(t *testing.T) {
+	var cpu atomic.Uint64
+	cpu.Store(math.Float64bits(10))
+	orgCPU := loadHintsCPU
+	loadHintsCPU = func() float64 { return math.Float64frombits(cpu.Load()) }
+	defer func() { loadHintsCPU = orgCPU }()
+
+	opts := DefaultOptions()
+	opts.LoadHintsInterval = 25 * time.Millisecond
+	s := RunServer(opts)
+	defer s.Shutdown()
+
+	c, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", opts.Port))
+	require_NoError(t, err)
+	defer c.Close()
+	client := bufio.NewReaderSize(c, maxBufSize)
+
+	waitForLoadHints := func(check func(lh *LoadHints) bool) {
+		t.Helper()
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		defer c.SetReadDeadline(time.Time{})
+		for {
+			l, err := client.ReadString('\n')
+			require_NoError(t, err)
+			if !strings.HasPrefix(l, "INFO ") {
+				continue
+			}
+			var info Info
+			require_NoError(t, json.Unmarshal([]byte(l[5:]), &info))
+			if info.LoadHints != nil && check(info.LoadHints) {
+				return
+			}
+		}
+	}
+
+	// The initial INFO already contains the hints.
+	waitForLoadHints(func(lh *LoadHints) bool {
+		require_Equal(t, lh.CPU, 10)
+		require_Equal(t, lh.MaxConnections, opts.MaxConn)
+		return true
+	})
+	_, err = c.Write([]byte("CONNECT {\"protocol\":1,\"verbose\":false}\r\nPING\r\n"))
+	require_NoError(t, err)
+
+	// Updates are sent as the load changes.
+	cpu.Store(math.Float64bits(75.5))
+	waitForLoadHints(func(lh *LoadHints) bool { return lh.CPU == 75.5 })
+
+	for range 3 {
+		nc := natsConnect(t, s.ClientURL())
+		defer nc.Close()
+	}
+	waitForLoadHints(func(lh *LoadHints) bool { return lh.Connections == 4 })
+
+	// Nothing is sent while the load doesn't change.
+	c.SetReadDeadline(time.Now().Add(10 * opts.LoadHintsInterval))
+	for {
+		l, err := client.ReadString('\n')
+		if err != nil {
+			require_True(t, errors.Is(err, os.ErrDeadlineExceeded))
+			break
+		}
+		require_False(t, strings.HasPrefix(l, "INFO "))
+	}
+	c.SetReadDeadline(time.Time{})
+
+	// Without an interval there are no hints.
+	s.Shutdown()
+	s = RunServer(DefaultOptions())
+	defer s.Shutdown()
+	require_True(t, s.copyInfo().LoadHints == nil)
+}
+
+func TestServerMaxConnectRate(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MaxConnectRate = 50
+	opts.MaxConnectBacklog = 200
+	s := RunServer(opts)
+	defer s.Shutdown()
+
+	nc := natsConnect(t, s.ClientURL())
+	defer nc.Close()
+
+	// readInfo connects and returns whether the server sent its INFO protocol.
+	readInfo := func() bool {
+		c, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", opts.Port))
+		if err != nil {
+			return false
+		}
+		defer c.Close()
+		c.SetReadDeadline(time.Now().Add(10 * time.Second))
+		l, err := bufio.NewReader(c).ReadString('\n')
+		return err == nil && strings.HasPrefix(l, "INFO ")
+	}
+
+	// Simulate a reconnect storm, connections are accepted at a smoothed rate.
+	const storm = 100
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	start := time.Now()
+	for range storm {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if readInfo() {
+				accepted.Add(1)
+			}
+		}()
+	}
+
+	// The server remains responsive to existing clients in the meantime.
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for stop := false; !stop; {
+		select {
+		case <-done:
+			stop = true
+		case <-time.After(100 * time.Millisecond):
+			require_NoError(t, nc.FlushTimeout(time.Second))
+		}
+	}
+	require_Equal(t, accepted.Load(), storm)
+	if elapsed := time.Since(start); elapsed < 1500*time.Millisecond {
+		t.Fatalf("Expected connections to be accepted at a smoothed rate, took %v", elapsed)
+	}
+
+	// When the backlog is full, connections are rejected right away.
+	s.Shutdown()
+	opts.MaxConnectRate = 1
+	opts.MaxConnectBacklog = 2
+	s = RunServer(opts)
+	defer s.Shutdown()
+
+	var rejected atomic.Int32
+	accepted.Store(0)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if readInfo() {
+				accepted.Add(1)
+			} else {
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := rejected.Load(); n < 5 {
+		t.Fatalf("Expected connections to be rejected, got %d", n)
+	}
+	// One created right away, one waiting to be created, and the backlog.
+	if n := accepted.Load(); n == 0 || n > 4 {
+		t.Fatalf("Expected at most 4 connections to be accepted, got %d", n)
+	}
+}
@@ -14,11 +14,14 @@
 package server
 
 import (
+	"cmp"
+	"container/list"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"time"
 	"unsafe"
@@ -50,6 +53,8 @@ var (
 	ErrMaxBytes = errors.New("maximum bytes exceeded")
 	// ErrMaxMsgsPerSubject is returned when we have discard new as a policy and we reached the message limit per subject.
 	ErrMaxMsgsPerSubject = errors.New("maximum messages per subject exceeded")
+	// ErrMaxSubjects is returned when we discard new subjects and we reached the subject limit.
+	ErrMaxSubjects = errors.New("maximum subjects exceeded")
 	// ErrStoreSnapshotInProgress is returned when RemoveMsg or EraseMsg is called
 	// while a snapshot is in progress.
 	ErrStoreSnapshotInProgress = errors.New("snapshot in progress")
@@ -97,6 +102,7 @@ type StreamStore interface {
 	SkipMsgs(seq uint64, num uint64) error
 	FlushAllPending() error
 	LoadMsg(seq uint64, sm *StoreMsg) (*StoreMsg, error)
+	MsgExists(seq uint64) bool
 	LoadNextMsg(filter string, wc bool, start uint64, smp *StoreMsg) (sm *StoreMsg, skip uint64, err error)
 	LoadNextMsgMulti(sl *gsl.SimpleSublist, start uint64, smp *StoreMsg) (sm *StoreMsg, skip uint64, err error)
 	LoadLastMsg(subject string, sm *StoreMsg) (*StoreMsg, error)
@@ -109,6 +115,8 @@ type StreamStore interface {
 	Compact(seq uint64) (uint64, error)
 	Truncate(seq uint64) error
 	GetSeqFromTime(t time.Time) uint64
+	ExpiringMsgs(from, to time.Time) []ExpiringMsg
+	LastSubjectSequence(subject string) uint64
 	FilteredState(seq uint64, subject string) (SimpleState, error)
 	SubjectsState(filterSubject string) map[string]SimpleState
 	SubjectsTotals(filterSubject string) map[string]uint64
@@ -120,7 +128,9 @@ type StreamStore interface {
 	State() StreamState
 	FastState(*StreamState)
 	EncodedStreamState(failed uint64) (enc []byte, err error)
+	DeletedRanges(start uint64, f func(first, last uint64) bool)
 	SyncDeleted(dbs DeleteBlocks) error
+	RebuildTotals() (msgs, bytes int64, err error)
 	Type() StorageType
 	RegisterStorageUpdates(StorageUpdateHandler)
 	RegisterStorageRemoveMsg(StorageRemoveMsgHandler)
@@ -136,6 +146,53 @@ type StreamStore interface {
 	ResetState()
 }
 
+// copyStoreMsgs copies all messages from ost into the empty store nst, preserving
+// sequences, timestamps and any gaps.
+func copyStoreMsgs(ost, nst StreamStore) error {
+	var state StreamState
+	ost.FastState(&state)
+
+	// The new store needs to start at our first sequence.
+	if state.FirstSeq > 1 {
+		if _, err := nst.Compact(state.FirstSeq); err != nil {
+			return err
+		}
+	}
+	return copyStoreMsgRange(ost, nst, state.FirstSeq, state.LastSeq)
+}
+
+// copyStoreMsgRange copies the messages [first:last] from ost into nst, whose last
+// sequence needs to be first-1, preserving sequences, timestamps and any gaps.
+func copyStoreMsgRange(ost, nst StreamStore, first, last uint64) error {
+	var smv StoreMsg
+	for seq, lseq := first, first-1; seq <= last; {
+		sm, _, err := ost.LoadNextMsg(fwcs, true, seq, &smv)
+		if err == ErrStoreEOF || err == nil && sm.seq > last {
+			break
+		} else if err != nil {
+			return err
+		}
+		if sm.seq > lseq+1 {
+			if err = nst.SkipMsgs(lseq+1, sm.seq-lseq-1); err != nil {
+				return err
+			}
+		}
+		ttl, _ := getMessageTTL(sm.hdr)
+		if err = nst.StoreRawMsg(sm.subj, sm.hdr, sm.msg, sm.seq, sm.ts, ttl, false); err != nil {
+			return err
+		}
+		seq, lseq = sm.seq+1, sm.seq
+	}
+	var nstate StreamState
+	nst.FastState(&nstate)
+	if last > nstate.LastSeq {
+		if err := nst.SkipMsgs(nstate.LastSeq+1, last-nstate.LastSeq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // RetentionPolicy determines how messages in a set are retained.
 type RetentionPolicy int
 
@@ -160,6 +217,16 @@ const (
 	DiscardNew
 )
 
+// IOErrorPolicy determines how a stream reacts when its store encounters an I/O error.
+type IOErrorPolicy int
+
+const (
+	// IOErrorStepDown (default) moves leadership of a replicated stream to a healthy replica.
+	IOErrorStepDown IOErrorPolicy = iota
+	// IOErrorReadOnly keeps the stream available for reads but rejects all new writes.
+	IOErrorReadOnly
+)
+
 // StreamState is information about the given stream.
 type StreamState struct {
 	Msgs        uint64            `json:"messages"`
@@ -530,6 +597,17 @@ func (dp DiscardPolicy) String() string {
 	}
 }
 
+func (p IOErrorPolicy) String() string {
+	switch p {
+	case IOErrorStepDown:
+		return "stepdown"
+	case IOErrorReadOnly:
+		return "read_only"
+	default:
+		return "unknown"
+	}
+}
+
 func (dp DiscardPolicy) MarshalJSON() ([]byte, error) {
 	switch dp {
 	case DiscardOld:
@@ -746,6 +824,146 @@ func isClusterResetErr(err error) bool {
 	return err == errLastSeqMismatch || err == ErrStoreEOF || err == errFirstSequenceMismatch || errors.Is(err, errCatchupAbortedNoLeader) || err == errCatchupTooManyRetries || err == errAlreadyLeader
 }
 
+// ExpiringMsg is a message that will expire, due to MaxAge or its TTL, at the given time in Unix nanoseconds.
+type ExpiringMsg struct {
+	Seq     uint64
+	Expires int64
+}
+
+// sortedExpiringMsgs returns the expiry times by sequence as expiring messages, ordered by sequence.
+func sortedExpiringMsgs(expiring map[uint64]int64) []ExpiringMsg {
+	if len(expiring) == 0 {
+		return nil
+	}
+	msgs := make([]ExpiringMsg, 0, len(expiring))
+	for seq, expires := range expiring {
+		msgs = append(msgs, ExpiringMsg{seq, expires})
+	}
+	slices.SortFunc(msgs, func(a, b ExpiringMsg) int { return cmp.Compare(a.Seq, b.Seq) })
+	return msgs
+}
+
+// subjectSequences tracks the highest Nats-Subject-Sequence stored per subject.
+// Unlike the header of the last message, it is kept when messages are deleted,
+// purged or expire, so the per-subject sequence never goes back.
+type subjectSequences map[string]uint64
+
+// Track the per-subject sequence found in the message headers, if any.
+func (ssq subjectSequences) track(subj string, hdr []byte) {
+	if len(hdr) == 0 {
+		return
+	}
+	seq := parseInt64(sliceHeader(JSSubjectSequence, hdr))
+	if seq <= 0 {
+		return
+	}
+	if cur, ok := ssq[subj]; !ok {
+		// The subject could be backed by a reused buffer, so copy it.
+		ssq[copyString(subj)] = uint64(seq)
+	} else if uint64(seq) > cur {
+		ssq[subj] = uint64(seq)
+	}
+}
+
+// Encode the per-subject sequences, highSeq identifies up to which
+// stream sequence they are valid.
+func (ssq subjectSequences) encode(highSeq uint64) []byte {
+	b := make([]byte, 0, 1+2*binary.MaxVarintLen64+len(ssq)*(2*binary.MaxVarintLen64+16))
+	b = append(b, 1) // Magic version
+	b = binary.AppendUvarint(b, highSeq)
+	b = binary.AppendUvarint(b, uint64(len(ssq)))
+	for subj, seq := range ssq {
+		b = binary.AppendUvarint(b, uint64(len(subj)))
+		b = append(b, subj...)
+		b = binary.AppendUvarint(b, seq)
+	}
+	return b
+}
+
+// Decode the per-subject sequences into ssq. Returns the high seq number they are valid up to.
+func (ssq subjectSequences) decode(b []byte) (uint64, error) {
+	if len(b) < 1 {
+		return 0, io.ErrShortBuffer
+	}
+	if b[0] != 1 {
+		return 0, errors.New("invalid subject sequence state version")
+	}
+	b = b[1:]
+	next := func() (uint64, error) {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		b = b[n:]
+		return v, nil
+	}
+	highSeq, err := next()
+	if err != nil {
+		return 0, err
+	}
+	count, err := next()
+	if err != nil {
+		return 0, err
+	}
+	for i := uint64(0); i < count; i++ {
+		l, err := next()
+		if err != nil {
+			return 0, err
+		}
+		if uint64(len(b)) < l {
+			return 0, io.ErrUnexpectedEOF
+		}
+		subj := string(b[:l])
+		b = b[l:]
+		seq, err := next()
+		if err != nil {
+			return 0, err
+		}
+		ssq[subj] = seq
+	}
+	return highSeq, nil
+}
+
+// subjectRecency keeps subjects in the order they were last written to, so that the
+// least recently written subject can be found without walking all subjects.
+type subjectRecency struct {
+	ll *list.List
+	m  map[string]*list.Element
+}
+
+func newSubjectRecency() *subjectRecency {
+	return &subjectRecency{ll: list.New(), m: make(map[string]*list.Element)}
+}
+
+// Marks the subject as the most recently written one.
+func (sr *subjectRecency) touch(subj string) {
+	if e, ok := sr.m[subj]; ok {
+		sr.ll.MoveToBack(e)
+		return
+	}
+	// The subject could be backed by a reused buffer, so copy it.
+	subj = copyString(subj)
+	sr.m[subj] = sr.ll.PushBack(subj)
+}
+
+// Forgets about the subject, when it no longer has any messages.
+func (sr *subjectRecency) remove(subj string) {
+	if e, ok := sr.m[subj]; ok {
+		sr.ll.Remove(e)
+		delete(sr.m, subj)
+	}
+}
+
+// Returns the least recently written subject, skipping the keep subject.
+func (sr *subjectRecency) oldest(keep string) string {
+	for e := sr.ll.Front(); e != nil; e = e.Next() {
+		if subj := e.Value.(string); subj != keep {
+			return subj
+		}
+	}
+	return _EMPTY_
+}
+
 // Copy all fields.
 func (smo *StoreMsg) copy(sm *StoreMsg) {
 	if sm.buf != nil {
@@ -16,10 +16,15 @@ package server
 import (
 	"archive/tar"
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"maps"
 	"math"
 	"math/big"
 	"math/rand"
@@ -35,7 +40,9 @@ import (
 
 	"github.com/antithesishq/antithesis-sdk-go/assert"
 	"github.com/klauspost/compress/s2"
+	"github.com/minio/highwayhash"
 	"github.com/nats-io/nats-server/v2/server/gsl"
+	"github.com/nats-io/nkeys"
 	"github.com/nats-io/nuid"
 )
 
@@ -45,6 +52,14 @@ type StreamConfigRequest struct {
 	// This is not part of the StreamConfig, because its scoped to request,
 	// and not to the stream itself.
 	Pedantic bool `json:"pedantic,omitempty"`
+	// ValidateOnly runs all checks of the create or update request without applying it.
+	ValidateOnly bool `json:"validate_only,omitempty"`
+	// Exclusive makes a create fail if the stream already exists, or is being created
+	// by a concurrent request, even if the configuration is the same.
+	Exclusive bool `json:"exclusive,omitempty"`
+	// MigrateStorage allows an update to change the storage type of the stream.
+	// All messages are copied into a new store of the requested type.
+	MigrateStorage bool `json:"migrate_storage,omitempty"`
 }
 
 // StreamConfig will determine the name, subjects and retention policy
@@ -85,6 +100,18 @@ type StreamConfig struct {
 	// Allow KV like semantics to also discard new on a per subject basis
 	DiscardNewPer bool `json:"discard_new_per_subject,omitempty"`
 
+	// MaxSubjects limits the number of distinct subjects in the stream. When a message on a new
+	// subject would exceed it, the least recently written subject is evicted with all of its messages,
+	// unless DiscardNewSubjects is set in which case the message is rejected.
+	MaxSubjects int64 `json:"max_subjects,omitempty"`
+
+	// DiscardNewSubjects rejects messages on new subjects once MaxSubjects is reached.
+	DiscardNewSubjects bool `json:"discard_new_subjects,omitempty"`
+
+	// RejectedPublishAdvisory sends an advisory with the publisher's info when a publish
+	// is rejected because the stream is sealed or read-only. Advisories are throttled.
+	RejectedPublishAdvisory bool `json:"rejected_publish_advisory,omitempty"`
+
 	// Optional qualifiers. These can not be modified after set to true.
 
 	// Sealed will seal a stream so no messages can get out or in.
@@ -110,6 +137,11 @@ type StreamConfig struct {
 	// subject delete markers.
 	SubjectDeleteMarkerTTL time.Duration `json:"subject_delete_marker_ttl,omitempty"`
 
+	// ExpiryAdvisoryLead is how long before messages expire, due to MaxAge or their TTL,
+	// an advisory is sent with their sequence range, so they can be archived in time.
+	// If zero, no such advisories are sent.
+	ExpiryAdvisoryLead time.Duration `json:"expiry_advisory_lead,omitempty"`
+
 	// AllowMsgCounter allows a stream to use (only) counter CRDTs.
 	AllowMsgCounter bool `json:"allow_msg_counter,omitempty"`
 
@@ -125,6 +157,63 @@ type StreamConfig struct {
 	// AllowBatchPublish allows fast batch publishing into the stream.
 	AllowBatchPublish bool `json:"allow_batched,omitempty"`
 
+	// CompressionDict is sample content used to build a dictionary for S2 compression
+	// of the stream's message blocks. It can not be changed once the stream is created.
+	CompressionDict []byte `json:"compression_dict,omitempty"`
+
+	// DeliverAfterCommit holds back delivery to consumers of a replicated stream until
+	// messages have been stored by a quorum of its replicas, not only by the leader.
+	DeliverAfterCommit bool `json:"deliver_after_commit,omitempty"`
+
+	// WriteConcern determines when a publish to the stream is acknowledged, trading
+	// durability for latency. It can not be changed once the stream is created.
+	WriteConcern WriteConcern `json:"write_concern,omitempty"`
+
+	// AllowedPublishers restricts which client identities can publish to the stream's subjects.
+	// Entries are user names, nkeys or JWT user public keys, or "tag:<tag>" to match a JWT user tag.
+	AllowedPublishers []string `json:"allowed_publishers,omitempty"`
+
+	// SubjectSequence stamps each message with a sequence that is monotonic per subject,
+	// in the Nats-Subject-Sequence header. The store keeps the last sequence per subject,
+	// so it does not go back when messages are deleted, purged or expire.
+	SubjectSequence bool `json:"subject_sequence,omitempty"`
+
+	// SigningKey is a public nkey that must have signed the payload of each message published
+	// to the stream. The signature is given in the Nats-Msg-Signature header, base64 URL encoded.
+	// Unsigned messages or those with an invalid signature are rejected.
+	SigningKey string `json:"signing_key,omitempty"`
+
+	// AllowSubjectOverlap allows the stream's subjects to overlap with those of other streams
+	// that allow it as well. Messages published to overlapping subjects are stored in all of them,
+	// but only the one with the lowest name acknowledges them. The acknowledgement, or the error if
+	// that stream rejects the message, says nothing about whether the other streams stored it.
+	AllowSubjectOverlap bool `json:"allow_subject_overlap,omitempty"`
+
+	// DuplicateWindowInclusive makes a message id that was seen exactly the duplicate window ago
+	// still count as a duplicate. By default the window is exclusive, and it isn't anymore.
+	DuplicateWindowInclusive bool `json:"duplicate_window_inclusive,omitempty"`
+
+	// DuplicateMaxIds limits duplicate detection to the most recent message ids. When an id is
+	// tracked beyond this count, the oldest one is forgotten, even if it's still in the duplicate
+	// window. Without a duplicate window, message ids are only limited by count. After a restart,
+	// only the ids of this many of the last messages are recovered. If zero, there is no limit.
+	DuplicateMaxIds int64 `json:"duplicate_max_ids,omitempty"`
+
+	// AllowedHeaders restricts which headers can be stored with messages published to the stream.
+	// Header names are matched case-insensitively. Headers prefixed with "Nats-" are always allowed,
+	// since JetStream relies on those. If empty, all headers are allowed.
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
+
+	// DisallowedHeaders determines whether a message with headers that are not allowed is
+	// rejected, or stored with those headers stripped.
+	DisallowedHeaders DisallowedHeaderAction `json:"disallowed_headers,omitempty"`
+
+	// SourcesReorderWindow holds back messages sourced from other streams for up to the given
+	// window, so messages from different sources are stored in the order of their original
+	// timestamps. Messages from the same source always keep their order. If zero, sourced
+	// messages are stored as they arrive.
+	SourcesReorderWindow time.Duration `json:"sources_reorder_window,omitempty"`
+
 	// Metadata is additional metadata for the Stream.
 	Metadata map[string]string `json:"metadata,omitempty"`
 }
@@ -156,6 +245,12 @@ func (cfg *StreamConfig) clone() *StreamConfig {
 		rePublish := *cfg.RePublish
 		clone.RePublish = &rePublish
 	}
+	if cfg.AllowedPublishers != nil {
+		clone.AllowedPublishers = slices.Clone(cfg.AllowedPublishers)
+	}
+	if cfg.AllowedHeaders != nil {
+		clone.AllowedHeaders = slices.Clone(cfg.AllowedHeaders)
+	}
 	if cfg.Metadata != nil {
 		clone.Metadata = make(map[string]string, len(cfg.Metadata))
 		for k, v := range cfg.Metadata {
@@ -165,6 +260,101 @@ func (cfg *StreamConfig) clone() *StreamConfig {
 	return &clone
 }
 
+// Prefix for allowed publisher entries that match a JWT user tag instead of an identity.
+const allowedPublisherTagPrefix = "tag:"
+
+// streamPublishers is used to check client publishes to the subjects of a
+// stream against its allowed publishers.
+type streamPublishers struct {
+	stream   string
+	subjects []string
+	allowed  []string
+}
+
+// allows returns whether the given identity or any of the JWT user tags is allowed.
+func (sp *streamPublishers) allows(user string, tags []string) bool {
+	for _, p := range sp.allowed {
+		if tag, isTag := strings.CutPrefix(p, allowedPublisherTagPrefix); isTag {
+			if slices.Contains(tags, tag) {
+				return true
+			}
+		} else if user != _EMPTY_ && p == user {
+			return true
+		}
+	}
+	return false
+}
+
+// streamPublishersIndex holds the streams of an account that restrict their
+// publishers, indexed by their subjects.
+type streamPublishersIndex struct {
+	mu      sync.Mutex
+	streams map[string]*streamPublishers
+	sl      *gsl.GenericSublist[*streamPublishers]
+	count   atomic.Int32
+}
+
+// setStreamPublishers indexes the stream if it restricts its publishers, replacing
+// any previous entry for it.
+func (a *Account) setStreamPublishers(cfg *StreamConfig) {
+	if cfg == nil {
+		return
+	}
+	idx := a.streamPubs.Load()
+	if idx == nil {
+		if len(cfg.AllowedPublishers) == 0 {
+			return
+		}
+		a.streamPubs.CompareAndSwap(nil, &streamPublishersIndex{
+			streams: make(map[string]*streamPublishers),
+			sl:      gsl.NewSublist[*streamPublishers](),
+		})
+		idx = a.streamPubs.Load()
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(cfg.Name)
+	if len(cfg.AllowedPublishers) == 0 {
+		return
+	}
+	subjects := cfg.Subjects
+	if len(subjects) == 0 && cfg.Mirror == nil && len(cfg.Sources) == 0 {
+		subjects = []string{cfg.Name}
+	}
+	sp := &streamPublishers{
+		stream:   cfg.Name,
+		subjects: slices.Clone(subjects),
+		allowed:  slices.Clone(cfg.AllowedPublishers),
+	}
+	for _, subj := range sp.subjects {
+		idx.sl.Insert(subj, sp)
+	}
+	idx.streams[cfg.Name] = sp
+	idx.count.Add(1)
+}
+
+// removeStreamPublishers stops checking publishes against the allowed publishers of the stream.
+func (a *Account) removeStreamPublishers(stream string) {
+	if idx := a.streamPubs.Load(); idx != nil {
+		idx.mu.Lock()
+		idx.removeLocked(stream)
+		idx.mu.Unlock()
+	}
+}
+
+// Lock should be held.
+func (idx *streamPublishersIndex) removeLocked(stream string) {
+	sp, ok := idx.streams[stream]
+	if !ok {
+		return
+	}
+	for _, subj := range sp.subjects {
+		idx.sl.Remove(subj, sp)
+	}
+	delete(idx.streams, stream)
+	idx.count.Add(-1)
+}
+
 type StreamConsumerLimits struct {
 	InactiveThreshold time.Duration `json:"inactive_threshold,omitempty"`
 	MaxAckPending     int           `json:"max_ack_pending,omitempty"`
@@ -240,6 +430,137 @@ func (wc *PersistModeType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// WriteConcern determines when a publish to a stream is acknowledged.
+type WriteConcern int
+
+const (
+	// WriteConcernSafe acknowledges a publish once the message has been stored by
+	// a quorum of the stream's replicas. This is the default.
+	WriteConcernSafe WriteConcern = iota
+	// WriteConcernFast acknowledges a publish as soon as the stream leader accepted the message,
+	// before its replicas stored it. Messages could be lost if the leader fails before that.
+	// Batched publishes are still acknowledged once stored by a quorum.
+	WriteConcernFast
+	// WriteConcernStrict acknowledges a publish once the message has been synced to disk
+	// by a quorum of the stream's replicas. The stream's store syncs every write as well.
+	WriteConcernStrict
+)
+
+const (
+	writeConcernSafeJSONString   = `"safe"`
+	writeConcernFastJSONString   = `"fast"`
+	writeConcernStrictJSONString = `"strict"`
+)
+
+var (
+	writeConcernSafeJSONBytes   = []byte(writeConcernSafeJSONString)
+	writeConcernFastJSONBytes   = []byte(writeConcernFastJSONString)
+	writeConcernStrictJSONBytes = []byte(writeConcernStrictJSONString)
+)
+
+func (wc WriteConcern) String() string {
+	switch wc {
+	case WriteConcernSafe:
+		return "Safe"
+	case WriteConcernFast:
+		return "Fast"
+	case WriteConcernStrict:
+		return "Strict"
+	default:
+		return "Unknown Write Concern"
+	}
+}
+
+func (wc WriteConcern) MarshalJSON() ([]byte, error) {
+	switch wc {
+	case WriteConcernSafe:
+		return writeConcernSafeJSONBytes, nil
+	case WriteConcernFast:
+		return writeConcernFastJSONBytes, nil
+	case WriteConcernStrict:
+		return writeConcernStrictJSONBytes, nil
+	default:
+		return nil, fmt.Errorf("can not marshal %v", wc)
+	}
+}
+
+func (wc *WriteConcern) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case writeConcernSafeJSONString, `""`:
+		*wc = WriteConcernSafe
+	case writeConcernFastJSONString:
+		*wc = WriteConcernFast
+	case writeConcernStrictJSONString:
+		*wc = WriteConcernStrict
+	default:
+		return fmt.Errorf("can not unmarshal %q", data)
+	}
+	return nil
+}
+
+// DisallowedHeaderAction determines what happens to messages with headers not allowed by the stream.
+type DisallowedHeaderAction int
+
+const (
+	// RejectDisallowedHeaders rejects the message. This is the default.
+	RejectDisallowedHeaders DisallowedHeaderAction = iota
+	// StripDisallowedHeaders stores the message without the headers that are not allowed.
+	StripDisallowedHeaders
+)
+
+const (
+	rejectDisallowedHeadersJSONString = `"reject"`
+	stripDisallowedHeadersJSONString  = `"strip"`
+)
+
+var (
+	rejectDisallowedHeadersJSONBytes = []byte(rejectDisallowedHeadersJSONString)
+	stripDisallowedHeadersJSONBytes  = []byte(stripDisallowedHeadersJSONString)
+)
+
+func (ha DisallowedHeaderAction) String() string {
+	switch ha {
+	case RejectDisallowedHeaders:
+		return "Reject"
+	case StripDisallowedHeaders:
+		return "Strip"
+	default:
+		return "Unknown Disallowed Header Action"
+	}
+}
+
+func (ha DisallowedHeaderAction) MarshalJSON() ([]byte, error) {
+	switch ha {
+	case RejectDisallowedHeaders:
+		return rejectDisallowedHeadersJSONBytes, nil
+	case StripDisallowedHeaders:
+		return stripDisallowedHeadersJSONBytes, nil
+	default:
+		return nil, fmt.Errorf("can not marshal %v", ha)
+	}
+}
+
+func (ha *DisallowedHeaderAction) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case rejectDisallowedHeadersJSONString, `""`:
+		*ha = RejectDisallowedHeaders
+	case stripDisallowedHeadersJSONString:
+		*ha = StripDisallowedHeaders
+	default:
+		return fmt.Errorf("can not unmarshal %q", data)
+	}
+	return nil
+}
+
+// durableAckCount returns the number of replicas that must have synced a message
+// to disk before it is committed, as required by the write concern.
+func (cfg *StreamConfig) durableAckCount() int {
+	if cfg.WriteConcern == WriteConcernStrict && cfg.Replicas > 1 {
+		return cfg.Replicas/2 + 1
+	}
+	return 0
+}
+
 // JSPubAckResponse is a formal response to a publish operation.
 type JSPubAckResponse struct {
 	Error *ApiError `json:"error,omitempty"`
@@ -500,6 +821,10 @@ type stream struct {
 	ddarr     []*ddentry              // The dedupe array.
 	ddindex   int                     // The dedupe index.
 	ddtmr     *time.Timer             // The dedupe timer.
+	eatmr     *time.Timer             // The timer to check for messages about to expire.
+	eahz      time.Time               // Messages expiring up to this time were already advised.
+	rpadv     atomic.Int64            // Time (unix nanos) the last rejected publish advisory was sent.
+	migrating bool                    // Indicates the messages are being moved to a new storage type.
 	qch       chan struct{}           // The quit channel.
 	mqch      chan struct{}           // The monitor's quit channel.
 	active    bool                    // Indicates that there are active internal subscriptions (for the subject filters)
@@ -507,6 +832,9 @@ type stream struct {
 	closed atomic.Bool // Set to true when stop() is called on the stream.
 	cisrun atomic.Bool // Indicates one checkInterestState is already running.
 
+	// Subjects of the overlapping streams with a lower name, which acknowledge publishes instead.
+	ovackd atomic.Pointer[[]string]
+
 	// Mirror
 	mirror              *sourceInfo
 	mirrorConsumerSetup *time.Timer
@@ -517,9 +845,15 @@ type stream struct {
 	sourcesConsumerSetup *time.Timer
 	smsgs                *ipQueue[*inMsg] // Intra-process queue for all incoming sourced messages.
 
+	// Sourced messages held back to be stored in timestamp order, see SourcesReorderWindow.
+	sreorder []*sourceReorderMsg
+
 	// Indicates we have direct/sourcing consumers.
 	sourcingConsumers int
 
+	// Interest retained on behalf of deleted consumers that did not acknowledge all messages.
+	retained map[string]*retainedInterest
+
 	// For input subject transform.
 	itr *subjectTransform
 
@@ -533,6 +867,10 @@ type stream struct {
 	sigq  *ipQueue[*cMsg]                // Intra-process queue for the messages to signal to the consumers.
 	csl   *gsl.GenericSublist[*consumer] // Consumer subscription list.
 
+	// For consumers sharing their reads from the store.
+	rgMu sync.Mutex
+	rgs  map[string]*consumerReadGroup // Read groups by the consumers' filter subjects.
+
 	// Leader will store seq/msgTrace in clustering mode. Used in applyStreamEntries
 	// to know if trace event should be sent after processing.
 	mt map[uint64]*msgTrace
@@ -548,6 +886,12 @@ type stream struct {
 	catchup   atomic.Bool       // Used to signal we are in catchup mode.
 	catchups  map[string]uint64 // The number of messages that need to be caught per peer.
 	syncSub   *subscription     // Internal subscription for sync messages (on "$JSC.SYNC").
+	storedSub *subscription     // Internal subscription for replicas reporting their stored sequence, leader only.
+	quorumSub *subscription     // Internal subscription for the quorum sequence signaled by the leader, followers only.
+	stored    map[string]uint64 // The last stored sequence reported per replica, leader only.
+	dac       atomic.Bool       // Whether consumer delivery waits for a quorum of replicas to store messages.
+	qseq      atomic.Uint64     // The last sequence stored by a quorum of replicas.
+	seqIdx    []seqIndex        // The Raft log index at which recent last sequences were applied, oldest first.
 	infoSub   *subscription     // Internal subscription for stream info requests.
 	clMu      sync.Mutex        // The mutex for clseq and clfs.
 	clseq     uint64            // The current last seq being proposed to the NRG layer.
@@ -556,6 +900,13 @@ type stream struct {
 	uch       chan struct{}     // The channel to signal updates to the monitor routine.
 	inMonitor bool              // True if the monitor routine has been started.
 	werr      error             // If a write error was encountered, and if so what error.
+	wro       bool              // If the write error made the stream read-only, per the I/O error policy at the time.
+
+	// Capacity reserved for bulk publishes, keyed by reservation id.
+	reservations map[string]*capacityReservation
+
+	// Subject counts in subject order, kept while paging through them.
+	scc *subjectCountsCache
 
 	inflight                    map[string]*inflightSubjectRunningTotal // Inflight message sizes per subject.
 	inflightTransform           map[uint64]string                       // Inflight message's optional transformed subject.
@@ -586,6 +937,7 @@ type inflightSubjectRunningTotal struct {
 	bytes    uint64 // Running total of inflight bytes for inflight messages.
 	ops      uint64 // Inflight operations, i.e. inflight messages for this subject. If this reaches zero, we can remove the running total.
 	schedule bool   // Marks whether the last message is a schedule.
+	subjSeq  uint64 // The per-subject sequence assigned to the last message, if enabled.
 }
 
 // msgCounterRunningTotal stores a running total and a number of inflight
@@ -652,12 +1004,16 @@ const (
 	JSScheduleRollup          = "Nats-Schedule-Rollup"
 	JSScheduleTarget          = "Nats-Schedule-Target"
 	JSScheduleSource          = "Nats-Schedule-Source"
+	JSSubjectSequence         = "Nats-Subject-Sequence"
+	JSMsgSignature            = "Nats-Msg-Signature"
+	JSReservationId           = "Nats-Reservation-Id"
 )
 
 // Headers for published KV messages.
 var (
-	KVOperation           = "KV-Operation"
-	KVOperationValuePurge = []byte("PURGE")
+	KVOperation            = "KV-Operation"
+	KVOperationValueDelete = []byte("DEL")
+	KVOperationValuePurge  = []byte("PURGE")
 )
 
 // Headers for scheduled messages.
@@ -707,24 +1063,32 @@ const StreamMaxReplicas = 5
 
 // AddStream adds a stream for the given account.
 func (a *Account) addStream(config *StreamConfig) (*stream, error) {
-	return a.addStreamWithAssignment(config, nil, nil, false, false)
+	return a.addStreamWithAssignment(config, nil, nil, false, false, false, false)
 }
 
 // recoverStream recovers a stream from disk for the given account.
 func (a *Account) recoverStream(config *StreamConfig) (*stream, error) {
-	return a.addStreamWithAssignment(config, nil, nil, false, true)
+	return a.addStreamWithAssignment(config, nil, nil, false, true, false, false)
 }
 
 // AddStreamWithStore adds a stream for the given account with custome store config options.
 func (a *Account) addStreamWithStore(config *StreamConfig, fsConfig *FileStoreConfig) (*stream, error) {
-	return a.addStreamWithAssignment(config, fsConfig, nil, false, false)
+	return a.addStreamWithAssignment(config, fsConfig, nil, false, false, false, false)
+}
+
+func (a *Account) addStreamPedantic(config *StreamConfig, pedantic, exclusive bool) (*stream, error) {
+	return a.addStreamWithAssignment(config, nil, nil, pedantic, false, false, exclusive)
 }
 
-func (a *Account) addStreamPedantic(config *StreamConfig, pedantic bool) (*stream, error) {
-	return a.addStreamWithAssignment(config, nil, nil, pedantic, false)
+// checkAddStream runs all checks of adding a stream for the given account, without creating it.
+func (a *Account) checkAddStream(config *StreamConfig, pedantic, exclusive bool) error {
+	_, err := a.addStreamWithAssignment(config, nil, nil, pedantic, false, true, exclusive)
+	return err
 }
 
-func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileStoreConfig, sa *streamAssignment, pedantic, recovering bool) (*stream, error) {
+// addStreamWithAssignment adds the stream, or returns the existing one if the config is the same.
+// If exclusive, it fails if the stream already exists or is being created concurrently.
+func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileStoreConfig, sa *streamAssignment, pedantic, recovering, validateOnly, exclusive bool) (*stream, error) {
 	s, jsa, err := a.checkForJetStream()
 	if err != nil {
 		return nil, err
@@ -761,6 +1125,10 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 	v, loaded := jsa.inflight.LoadOrStore(cfg.Name, swg)
 	wg := v.(*sync.WaitGroup)
 	if loaded {
+		if exclusive {
+			swg.Done()
+			return nil, NewJSStreamCreateInProgressError()
+		}
 		wg.Wait()
 		// This waitgroup is "thrown away" (since there was an existing one).
 		swg.Done()
@@ -776,6 +1144,9 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 	jsa.mu.Lock()
 	if mset, ok := jsa.streams[cfg.Name]; ok {
 		jsa.mu.Unlock()
+		if exclusive {
+			return nil, NewJSStreamAlreadyExistsError()
+		}
 		// Check to see if configs are same.
 		ocfg := mset.config()
 
@@ -876,12 +1247,17 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 	}
 
 	// Check for overlapping subjects with other streams.
-	// These are not allowed for now.
-	if jsa.subjectsOverlap(cfg.Subjects, nil) {
+	// These are only allowed if all streams involved allow them.
+	if jsa.subjectsOverlap(cfg.Subjects, cfg.AllowSubjectOverlap, nil) {
 		jsa.mu.Unlock()
 		return nil, NewJSStreamSubjectOverlapError()
 	}
 
+	if validateOnly {
+		jsa.mu.Unlock()
+		return nil, nil
+	}
+
 	// Setup the internal clients.
 	c := s.createInternalJetStreamClient()
 	ic := s.createInternalJetStreamClient()
@@ -977,20 +1353,7 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 			mset.autoTuneFileStorageBlockSize(fsCfg)
 		}
 	}
-	fsCfg.StoreDir = storeDir
-	// Grab configured sync interval.
-	fsCfg.SyncInterval = s.getOpts().SyncInterval
-	fsCfg.SyncAlways = s.getOpts().SyncAlways
-	fsCfg.Compression = config.Compression
-	// Async flushing is only allowed if the stream has a sync log backing it.
-	fsCfg.AsyncFlush = !fsCfg.SyncAlways && config.Replicas > 1
-
-	// Async persist mode opts in to async flushing,
-	// sync always would also be disabled if it was configured.
-	if config.PersistMode == AsyncPersistMode {
-		fsCfg.SyncAlways = false
-		fsCfg.AsyncFlush = true
-	}
+	s.configureStreamFileStore(fsCfg, storeDir, config)
 	if err := mset.setupStore(fsCfg); err != nil {
 		mset.stop(true, false)
 		return nil, NewJSStreamStoreFailedError(err)
@@ -1017,6 +1380,7 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 	mset.ddMu.Lock()
 	mset.rebuildDedupe()
 	mset.ddMu.Unlock()
+	mset.setupExpiryAdvisories()
 	mset.mu.Unlock()
 
 	// Set our stream assignment if in clustered mode.
@@ -1070,6 +1434,14 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 	jsa.streams[cfg.Name] = mset
 	jsa.mu.Unlock()
 
+	// When clustered this is done when processing the stream assignment.
+	if !isClustered {
+		a.setStreamPublishers(cfg)
+		a.setOverlapStream(cfg)
+	} else if cfg.AllowSubjectOverlap {
+		a.updateOverlapAckOwners()
+	}
+
 	return mset, nil
 }
 
@@ -1175,6 +1547,11 @@ func (mset *stream) setStreamAssignment(sa *streamAssignment) {
 	mset.node = node
 	if mset.node != nil {
 		mset.node.UpdateKnownPeers(peers)
+		// The write concern determines how durable entries need to be before they are committed.
+		// Follows the replicas, so do this for every assignment.
+		if n, ok := mset.node.(*raft); ok && sa.Config != nil {
+			n.setDurableAckCount(sa.Config.durableAckCount())
+		}
 	}
 
 	// Setup our info sub here as well for all stream members. This is now by design.
@@ -1260,6 +1637,7 @@ func (mset *stream) setLeader(isLeader bool) error {
 		// TODO(dlc) - Original design was that all in sync members of the group would do DQ.
 		if mset.isClustered() {
 			mset.startClusterSubs()
+			mset.setupDeliverAfterCommit(true)
 		}
 
 		// Setup subscriptions if we were not already the leader.
@@ -1272,6 +1650,9 @@ func (mset *stream) setLeader(isLeader bool) error {
 			return err
 		}
 
+		// Expire the reservations the former leader was tracking.
+		mset.setReservationTimers(true)
+
 		// Reset any inflight fast batches. We were likely a follower before and need
 		// to send an ack to the publishers so they know we're still there.
 		if mset.batches != nil {
@@ -1293,10 +1674,14 @@ func (mset *stream) setLeader(isLeader bool) error {
 
 		// Stop responding to sync requests.
 		mset.stopClusterSubs()
+		// Keep holding back delivery, now signaled by the new leader.
+		mset.setupDeliverAfterCommit(false)
 		// Unsubscribe from direct stream.
 		mset.unsubscribeToStream(false, false)
 		// Clear catchup state
 		mset.clearAllCatchupPeers()
+		// The new leader expires the reservations.
+		mset.setReservationTimers(false)
 	}
 	mset.store.ResetState()
 	mset.mu.Unlock()
@@ -1321,6 +1706,61 @@ func (mset *stream) stopClusterSubs() {
 		mset.srv.sysUnsubscribe(mset.syncSub)
 		mset.syncSub = nil
 	}
+	mset.stopDeliverAfterCommit()
+}
+
+// setupDeliverAfterCommit will start tracking the sequence stored by a quorum of replicas,
+// which gates delivery to consumers when the stream delivers after commit. The leader
+// tracks what replicas stored and signals the quorum sequence, which followers subscribe to.
+// Lock should be held.
+func (mset *stream) setupDeliverAfterCommit(isLeader bool) {
+	if !mset.cfg.DeliverAfterCommit || mset.cfg.Replicas <= 1 || mset.node == nil || mset.sa == nil || mset.closed.Load() {
+		mset.stopDeliverAfterCommit()
+		return
+	}
+	if isLeader {
+		if mset.quorumSub != nil {
+			mset.srv.sysUnsubscribe(mset.quorumSub)
+			mset.quorumSub = nil
+		}
+		if mset.storedSub == nil {
+			mset.stored = make(map[string]uint64)
+			// Only what we have stored ourselves is known to be committed at this point.
+			mset.qseq.Store(mset.lseq)
+			subj := fmt.Sprintf(clusterStreamReportT, mset.sa.Group.Name)
+			mset.storedSub, _ = mset.srv.systemSubscribe(subj, _EMPTY_, false, mset.sysc, mset.handleClusterStoredSeq)
+			// Let followers know where we are.
+			var msg [8]byte
+			binary.LittleEndian.PutUint64(msg[:], mset.qseq.Load())
+			mset.srv.sendInternalMsgLocked(fmt.Sprintf(clusterStreamStoredT, mset.sa.Group.Name), _EMPTY_, nil, msg[:])
+		}
+	} else {
+		if mset.storedSub != nil {
+			mset.srv.sysUnsubscribe(mset.storedSub)
+			mset.storedSub = nil
+		}
+		mset.stored = nil
+		if mset.quorumSub == nil {
+			subj := fmt.Sprintf(clusterStreamStoredT, mset.sa.Group.Name)
+			mset.quorumSub, _ = mset.srv.systemSubscribe(subj, _EMPTY_, false, mset.sysc, mset.handleClusterQuorumSeq)
+		}
+	}
+	mset.dac.Store(true)
+}
+
+// stopDeliverAfterCommit stops holding back delivery to consumers.
+// Lock should be held.
+func (mset *stream) stopDeliverAfterCommit() {
+	mset.dac.Store(false)
+	if mset.storedSub != nil {
+		mset.srv.sysUnsubscribe(mset.storedSub)
+		mset.storedSub = nil
+	}
+	if mset.quorumSub != nil {
+		mset.srv.sysUnsubscribe(mset.quorumSub)
+		mset.quorumSub = nil
+	}
+	mset.stored = nil
 }
 
 // account gets the account for this stream.
@@ -1417,14 +1857,8 @@ func (mset *stream) autoTuneFileStorageBlockSize(fsCfg *FileStoreConfig) {
 // headers and msgId in them. Would need signaling from the storage layer.
 // mset.mu and mset.ddMu locks should be held.
 func (mset *stream) rebuildDedupe() {
-	duplicates := mset.cfg.Duplicates
-	if duplicates <= 0 {
-		return
-	}
-
-	// We have some messages. Lookup starting sequence by duplicate time window.
-	sseq := mset.store.GetSeqFromTime(time.Now().Add(-duplicates))
-	if sseq == 0 {
+	duplicates, maxIds := mset.cfg.Duplicates, mset.cfg.DuplicateMaxIds
+	if duplicates <= 0 && maxIds <= 0 {
 		return
 	}
 
@@ -1432,6 +1866,21 @@ func (mset *stream) rebuildDedupe() {
 	var state StreamState
 	mset.store.FastState(&state)
 
+	// We have some messages. Lookup starting sequence by duplicate time window,
+	// or if only limited by count, we look at that many of the last messages.
+	var sseq uint64
+	if duplicates > 0 {
+		sseq = mset.store.GetSeqFromTime(time.Now().Add(-duplicates))
+	} else if state.Msgs > 0 {
+		sseq = state.FirstSeq
+		if state.LastSeq-state.FirstSeq >= uint64(maxIds) {
+			sseq = state.LastSeq - uint64(maxIds) + 1
+		}
+	}
+	if sseq == 0 {
+		return
+	}
+
 	for seq := sseq; seq <= state.LastSeq; seq++ {
 		sm, err := mset.store.LoadMsg(seq, &smv)
 		if err != nil {
@@ -1480,6 +1929,7 @@ func (mset *stream) sendCreateAdvisory() {
 	name := mset.cfg.Name
 	outq := mset.outq
 	srv := mset.srv
+	acc := mset.acc
 	mset.mu.RUnlock()
 
 	if outq == nil {
@@ -1504,7 +1954,9 @@ func (mset *stream) sendCreateAdvisory() {
 	}
 
 	subj := JSAdvisoryStreamCreatedPre + "." + name
-	outq.sendMsg(subj, j)
+	if acc.allowAdvisory(srv, subj) {
+		outq.sendMsg(subj, j)
+	}
 }
 
 func (mset *stream) sendDeleteAdvisoryLocked() {
@@ -1526,7 +1978,9 @@ func (mset *stream) sendDeleteAdvisoryLocked() {
 	j, err := json.Marshal(m)
 	if err == nil {
 		subj := JSAdvisoryStreamDeletedPre + "." + mset.cfg.Name
-		mset.outq.sendMsg(subj, j)
+		if mset.acc.allowAdvisory(mset.srv, subj) {
+			mset.outq.sendMsg(subj, j)
+		}
 	}
 }
 
@@ -1549,8 +2003,100 @@ func (mset *stream) sendUpdateAdvisoryLocked() {
 	j, err := json.Marshal(m)
 	if err == nil {
 		subj := JSAdvisoryStreamUpdatedPre + "." + mset.cfg.Name
-		mset.outq.sendMsg(subj, j)
+		if mset.acc.allowAdvisory(mset.srv, subj) {
+			mset.outq.sendMsg(subj, j)
+		}
+	}
+}
+
+func (mset *stream) sendConfigChangeAdvisoryLocked(ocfg, cfg *StreamConfig) {
+	if mset.outq == nil {
+		return
+	}
+	changes, err := streamConfigChanges(ocfg, cfg)
+	if err != nil || len(changes) == 0 {
+		return
+	}
+
+	m := JSStreamConfigChangeAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSStreamConfigChangeAdvisoryType,
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Stream:  cfg.Name,
+		Before:  ocfg,
+		After:   cfg,
+		Changes: changes,
+		Domain:  mset.srv.getOpts().JetStreamDomain,
+	}
+
+	j, err := json.Marshal(m)
+	if err == nil {
+		subj := JSAdvisoryStreamConfigChangedPre + "." + cfg.Name
+		if mset.acc.allowAdvisory(mset.srv, subj) {
+			mset.outq.sendMsg(subj, j)
+		}
+	}
+}
+
+// streamConfigChanges returns the fields that differ between both configurations, sorted by their JSON name.
+func streamConfigChanges(ocfg, cfg *StreamConfig) ([]StreamConfigChange, error) {
+	var before, after map[string]json.RawMessage
+	for _, c := range []struct {
+		cfg *StreamConfig
+		m   *map[string]json.RawMessage
+	}{{ocfg, &before}, {cfg, &after}} {
+		b, err := json.Marshal(c.cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, c.m); err != nil {
+			return nil, err
+		}
+	}
+	var changes []StreamConfigChange
+	for field, ov := range before {
+		if nv, ok := after[field]; !ok || !bytes.Equal(ov, nv) {
+			changes = append(changes, StreamConfigChange{Field: field, Old: ov, New: nv})
+		}
+	}
+	for field, nv := range after {
+		if _, ok := before[field]; !ok {
+			changes = append(changes, StreamConfigChange{Field: field, New: nv})
+		}
+	}
+	slices.SortFunc(changes, func(a, b StreamConfigChange) int { return strings.Compare(a.Field, b.Field) })
+	return changes, nil
+}
+
+func (mset *stream) sendStreamIOErrorAdvisory(err error, policy IOErrorPolicy) {
+	if mset == nil {
+		return
+	}
+	s := mset.srv
+	stream, acc := mset.name(), mset.account()
+	subj := JSAdvisoryStreamIOErrorPre + "." + stream
+	adv := &JSStreamIOErrorAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSStreamIOErrorAdvisoryType,
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Stream: stream,
+		Server: s.Name(),
+		Domain: s.getOpts().JetStreamDomain,
+		Error:  err.Error(),
+		Action: policy.String(),
 	}
+
+	// Send to the user's account if not the system account.
+	if acc != s.SystemAccount() {
+		s.publishAdvisory(acc, subj, adv)
+	}
+	// Now do system level one. Place account info in adv, and nil account means system.
+	adv.Account = acc.GetName()
+	s.publishAdvisory(nil, subj, adv)
 }
 
 func (mset *stream) sendStreamBatchAbandonedAdvisory(batchId string, reason BatchAbandonReason) {
@@ -1581,51 +2127,302 @@ func (mset *stream) sendStreamBatchAbandonedAdvisory(batchId string, reason Batc
 	s.publishAdvisory(nil, subj, adv)
 }
 
-// Created returns created time.
-func (mset *stream) createdTime() time.Time {
-	mset.mu.RLock()
-	created := mset.created
-	mset.mu.RUnlock()
-	return created
-}
+// Upper bound on the interval between checks for messages about to expire.
+// Expiring messages are coalesced per check, which limits the rate
+// of advisories for streams where many messages expire.
+const maxExpiryAdvisoryInterval = time.Second
 
-// Internal to allow creation time to be restored.
-func (mset *stream) setCreatedTime(created time.Time) {
-	mset.mu.Lock()
-	mset.created = created
-	mset.mu.Unlock()
+// Upper bound on the messages reported in a single expiry advisory.
+const maxExpiryAdvisoryMsgs = 1024
+
+// expiryAdvisoryInterval returns the interval between checks for messages about to expire.
+// Checking at least twice per lead time ensures advisories go out well before messages expire.
+func expiryAdvisoryInterval(lead time.Duration) time.Duration {
+	return min(lead/2, maxExpiryAdvisoryInterval)
 }
 
-// subjectsOverlap to see if these subjects overlap with existing subjects.
-// Use only for non-clustered JetStream
-// RLock minimum should be held.
-func (jsa *jsAccount) subjectsOverlap(subjects []string, self *stream) bool {
-	for _, mset := range jsa.streams {
-		if self != nil && mset == self {
-			continue
-		}
-		for _, subj := range mset.cfg.Subjects {
-			for _, tsubj := range subjects {
-				if SubjectsCollide(tsubj, subj) {
-					return true
-				}
-			}
-		}
+// setupExpiryAdvisories starts or stops checking for messages about to expire,
+// depending on whether an expiry advisory lead time is configured.
+// Lock should be held.
+func (mset *stream) setupExpiryAdvisories() {
+	lead := mset.cfg.ExpiryAdvisoryLead
+	if lead <= 0 {
+		if mset.eatmr != nil {
+			mset.eatmr.Stop()
+			mset.eatmr = nil
+		}
+		mset.eahz = time.Time{}
+		return
+	}
+	if mset.eatmr == nil {
+		mset.eatmr = time.AfterFunc(0, mset.checkExpiringMsgs)
+	} else {
+		mset.eatmr.Reset(0)
 	}
-	return false
 }
 
-// StreamDefaultDuplicatesWindow default duplicates window.
-const StreamDefaultDuplicatesWindow = 2 * time.Minute
-
-func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic bool) (StreamConfig, *ApiError) {
-	lim := &s.getOpts().JetStreamLimits
+// checkExpiringMsgs sends an advisory for the messages that will expire within the
+// expiry advisory lead time and were not advised yet. All replicas keep track of what
+// was advised, so a new leader continues where the previous one left off.
+func (mset *stream) checkExpiringMsgs() {
+	mset.mu.Lock()
+	if mset.eatmr == nil || mset.closed.Load() {
+		mset.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	from, to := mset.eahz, now.Add(mset.cfg.ExpiryAdvisoryLead)
+	if from.IsZero() || from.Before(now) {
+		from = now
+	}
+	mset.eahz = to
+	mset.eatmr.Reset(expiryAdvisoryInterval(mset.cfg.ExpiryAdvisoryLead))
+	store, isLeader := mset.store, mset.isLeader()
+	mset.mu.Unlock()
 
-	if config == nil {
-		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration invalid"))
+	if !isLeader || store == nil || !to.After(from) {
+		return
 	}
-	if !isValidAssetName(config.Name) {
-		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("stream name is required and can not contain '.', '*', '>', '\\', '/'"))
+	expiring := store.ExpiringMsgs(from, to)
+	for len(expiring) > 0 {
+		n := min(len(expiring), maxExpiryAdvisoryMsgs)
+		mset.sendStreamMsgsExpiringAdvisory(expiring[:n])
+		expiring = expiring[n:]
+	}
+}
+
+func (mset *stream) sendStreamMsgsExpiringAdvisory(expiring []ExpiringMsg) {
+	if mset == nil {
+		return
+	}
+	s := mset.srv
+	stream, acc := mset.name(), mset.account()
+	subj := JSAdvisoryStreamMsgsExpiringPre + "." + stream
+	adv := &JSStreamMsgsExpiringAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSStreamMsgsExpiringAdvisoryType,
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Stream:   stream,
+		Domain:   s.getOpts().JetStreamDomain,
+		Messages: make([]JSStreamExpiringMsg, 0, len(expiring)),
+	}
+	for _, em := range expiring {
+		adv.Messages = append(adv.Messages, JSStreamExpiringMsg{Sequence: em.Seq, Expires: time.Unix(0, em.Expires).UTC()})
+	}
+
+	// Send to the user's account if not the system account.
+	if acc != s.SystemAccount() {
+		s.publishAdvisory(acc, subj, adv)
+	}
+	// Now do system level one. Place account info in adv, and nil account means system.
+	adv.Account = acc.GetName()
+	s.publishAdvisory(nil, subj, adv)
+}
+
+// Minimum interval between rejected publish advisories for a stream.
+const rejectedPublishAdvisoryInterval = time.Second
+
+func (mset *stream) sendStreamPublishRejectedAdvisory(c *client, hdr []byte, subject string, apiErr *ApiError) {
+	if mset == nil {
+		return
+	}
+	// Throttle, a publisher retrying against a sealed stream should not flood the advisory subject.
+	now := time.Now()
+	last := mset.rpadv.Load()
+	if now.UnixNano()-last < int64(rejectedPublishAdvisoryInterval) || !mset.rpadv.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	// Prefer the publisher's info carried in the header, since the message may have
+	// reached us through a route, gateway or leafnode. Only a direct client is known as is.
+	var ci *ClientInfo
+	if len(hdr) > 0 {
+		if cis := sliceHeader(ClientInfoHdr, hdr); len(cis) > 0 {
+			var hci ClientInfo
+			if err := json.Unmarshal(cis, &hci); err == nil {
+				ci = &hci
+			}
+		}
+	}
+	if ci == nil && c != nil && c.kind == CLIENT {
+		ci = c.getClientInfo(true)
+	}
+
+	s := mset.srv
+	stream, acc := mset.name(), mset.account()
+	subj := JSAdvisoryStreamPublishRejectedPre + "." + stream
+	adv := &JSStreamPublishRejectedAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSStreamPublishRejectedAdvisoryType,
+			ID:   nuid.Next(),
+			Time: now.UTC(),
+		},
+		Stream:  stream,
+		Domain:  s.getOpts().JetStreamDomain,
+		Subject: subject,
+		Client:  ci,
+		Error:   apiErr,
+	}
+
+	// Send to the user's account if not the system account.
+	if acc != s.SystemAccount() {
+		s.publishAdvisory(acc, subj, adv)
+	}
+	// Now do system level one. Place account info in adv, and nil account means system.
+	adv.Account = acc.GetName()
+	s.publishAdvisory(nil, subj, adv)
+}
+
+// Created returns created time.
+func (mset *stream) createdTime() time.Time {
+	mset.mu.RLock()
+	created := mset.created
+	mset.mu.RUnlock()
+	return created
+}
+
+// Internal to allow creation time to be restored.
+func (mset *stream) setCreatedTime(created time.Time) {
+	mset.mu.Lock()
+	mset.created = created
+	mset.mu.Unlock()
+}
+
+// subjectsOverlap to see if these subjects overlap with existing subjects.
+// If allowOverlap is set, streams that allow overlap as well are skipped.
+// Use only for non-clustered JetStream
+// RLock minimum should be held.
+func (jsa *jsAccount) subjectsOverlap(subjects []string, allowOverlap bool, self *stream) bool {
+	for _, mset := range jsa.streams {
+		if self != nil && mset == self {
+			continue
+		}
+		if allowOverlap && mset.cfg.AllowSubjectOverlap {
+			continue
+		}
+		for _, subj := range mset.cfg.Subjects {
+			for _, tsubj := range subjects {
+				if SubjectsCollide(tsubj, subj) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// overlapStreamsIndex holds the subjects of the streams of an account that allow
+// subject overlap, indexed by stream name.
+type overlapStreamsIndex struct {
+	mu      sync.Mutex
+	streams map[string][]string
+}
+
+// setOverlapStream indexes the stream if it allows subject overlap, replacing any
+// previous entry for it, and updates which publishes the local streams acknowledge.
+func (a *Account) setOverlapStream(cfg *StreamConfig) {
+	if cfg == nil {
+		return
+	}
+	idx := a.overlapStreams.Load()
+	if idx == nil {
+		if !cfg.AllowSubjectOverlap {
+			return
+		}
+		a.overlapStreams.CompareAndSwap(nil, &overlapStreamsIndex{streams: make(map[string][]string)})
+		idx = a.overlapStreams.Load()
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	_, had := idx.streams[cfg.Name]
+	delete(idx.streams, cfg.Name)
+	if cfg.AllowSubjectOverlap {
+		idx.streams[cfg.Name] = slices.Clone(cfg.Subjects)
+	} else if !had {
+		return
+	}
+	a.updateOverlapAckOwnersLocked(idx)
+}
+
+// removeOverlapStream stops considering the stream when deciding which overlapping stream acknowledges a publish.
+func (a *Account) removeOverlapStream(stream string) {
+	if idx := a.overlapStreams.Load(); idx != nil {
+		idx.mu.Lock()
+		defer idx.mu.Unlock()
+		if _, ok := idx.streams[stream]; ok {
+			delete(idx.streams, stream)
+			a.updateOverlapAckOwnersLocked(idx)
+		}
+	}
+}
+
+// updateOverlapAckOwners recomputes which publishes the local streams acknowledge,
+// for when one was added after its stream was indexed.
+func (a *Account) updateOverlapAckOwners() {
+	if idx := a.overlapStreams.Load(); idx != nil {
+		idx.mu.Lock()
+		a.updateOverlapAckOwnersLocked(idx)
+		idx.mu.Unlock()
+	}
+}
+
+// updateOverlapAckOwnersLocked stores with each local stream that allows overlap the subjects
+// of the overlapping streams with a lower name, since those acknowledge publishes on them.
+// Index lock should be held, to store the results of concurrent updates in order.
+func (a *Account) updateOverlapAckOwnersLocked(idx *overlapStreamsIndex) {
+	a.mu.RLock()
+	jsa := a.js
+	a.mu.RUnlock()
+	if jsa == nil {
+		return
+	}
+	jsa.mu.RLock()
+	streams := maps.Clone(jsa.streams)
+	jsa.mu.RUnlock()
+
+	for name, mset := range streams {
+		if _, ok := idx.streams[name]; !ok {
+			mset.ovackd.Store(nil)
+			continue
+		}
+		var subjects []string
+		for oname, osubjects := range idx.streams {
+			if oname < name {
+				subjects = append(subjects, osubjects...)
+			}
+		}
+		if len(subjects) == 0 {
+			mset.ovackd.Store(nil)
+		} else {
+			mset.ovackd.Store(&subjects)
+		}
+	}
+}
+
+// isOverlapAckOwner returns whether this stream acknowledges a message on a subject it
+// may share with other streams that allow overlapping subjects. That is the stream with
+// the lowest name of all that match the subject.
+func (mset *stream) isOverlapAckOwner(subject string) bool {
+	subjects := mset.ovackd.Load()
+	if subjects == nil {
+		return true
+	}
+	return !slices.ContainsFunc(*subjects, func(subj string) bool { return subjectIsSubsetMatch(subject, subj) })
+}
+
+// StreamDefaultDuplicatesWindow default duplicates window.
+const StreamDefaultDuplicatesWindow = 2 * time.Minute
+
+func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic bool) (StreamConfig, *ApiError) {
+	lim := &s.getOpts().JetStreamLimits
+
+	if config == nil {
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration invalid"))
+	}
+	if !isValidAssetName(config.Name) {
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("stream name is required and can not contain '.', '*', '>', '\\', '/'"))
 	}
 	if len(config.Name) > JSMaxNameLen {
 		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("stream name is too long, maximum allowed is %d", JSMaxNameLen))
@@ -1662,6 +2459,27 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		return cfg, NewJSStreamInvalidConfigError(fmt.Errorf("invalid storage type"))
 	}
 
+	if len(cfg.CompressionDict) > 0 {
+		if cfg.Storage != FileStorage || cfg.Compression != S2Compression {
+			return cfg, NewJSStreamInvalidConfigError(fmt.Errorf("compression dictionary requires file storage with s2 compression"))
+		}
+		if l := len(cfg.CompressionDict); l < s2.MinDictSize || l > s2.MaxDictSize {
+			return cfg, NewJSStreamInvalidConfigError(fmt.Errorf("compression dictionary must be between %d and %d bytes", s2.MinDictSize, s2.MaxDictSize))
+		}
+	}
+
+	for _, p := range cfg.AllowedPublishers {
+		if tag, isTag := strings.CutPrefix(p, allowedPublisherTagPrefix); p == _EMPTY_ || (isTag && tag == _EMPTY_) {
+			return cfg, NewJSStreamInvalidConfigError(fmt.Errorf("allowed publishers can not contain empty entries"))
+		}
+	}
+
+	for _, h := range cfg.AllowedHeaders {
+		if h == _EMPTY_ || strings.ContainsAny(h, ": \t\r\n") {
+			return cfg, NewJSStreamInvalidConfigError(fmt.Errorf("allowed header %q is not a valid header name", h))
+		}
+	}
+
 	if cfg.Replicas == 0 {
 		cfg.Replicas = 1
 	}
@@ -1708,7 +2526,11 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("max age needs to be >= 100ms"))
 	}
 
-	if cfg.Duplicates == 0 && cfg.Mirror == nil && len(cfg.Sources) == 0 {
+	if cfg.DuplicateMaxIds < 0 {
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("duplicate max ids can not be negative"))
+	}
+	// Without a duplicate window, message ids can be limited by count only.
+	if cfg.Duplicates == 0 && cfg.DuplicateMaxIds == 0 && cfg.Mirror == nil && len(cfg.Sources) == 0 {
 		maxWindow := StreamDefaultDuplicatesWindow
 		if lim.Duplicates > 0 && maxWindow > lim.Duplicates {
 			if pedantic {
@@ -1770,6 +2592,19 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		}
 	}
 
+	if cfg.MaxSubjects < 0 {
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("max subjects can not be negative"))
+	}
+	if cfg.DiscardNewSubjects && cfg.MaxSubjects == 0 {
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("discard new subjects requires max subjects > 0"))
+	}
+
+	if cfg.SigningKey != _EMPTY_ {
+		if _, err := nkeys.FromPublicKey(cfg.SigningKey); err != nil {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("invalid signing key: %v", err))
+		}
+	}
+
 	if cfg.SubjectDeleteMarkerTTL > 0 {
 		if cfg.SubjectDeleteMarkerTTL < time.Second {
 			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("subject delete marker TTL must be at least 1 second"))
@@ -1790,6 +2625,18 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("subject delete marker TTL must not be negative"))
 	}
 
+	if cfg.SourcesReorderWindow < 0 {
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("sources reorder window must not be negative"))
+	} else if cfg.SourcesReorderWindow > 0 && len(cfg.Sources) == 0 {
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("sources reorder window requires sources"))
+	}
+
+	if cfg.ExpiryAdvisoryLead < 0 {
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("expiry advisory lead time must not be negative"))
+	} else if cfg.ExpiryAdvisoryLead > 0 && cfg.MaxAge == 0 && !cfg.AllowMsgTTL {
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("expiry advisory lead time requires max age or message TTLs"))
+	}
+
 	if cfg.AllowMsgSchedules {
 		if cfg.Discard == DiscardNew {
 			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("message scheduling cannot use discard new"))
@@ -1812,6 +2659,9 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		if cfg.AllowAtomicPublish {
 			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("async persist mode is not supported with atomic batch publish"))
 		}
+		if cfg.WriteConcern == WriteConcernStrict {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("async persist mode is not supported with strict write concern"))
+		}
 	}
 
 	getStream := func(streamName string) (bool, StreamConfig) {
@@ -1865,6 +2715,15 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		if cfg.AllowMsgSchedules {
 			return StreamConfig{}, NewJSMirrorWithMsgSchedulesError()
 		}
+		if cfg.SubjectSequence {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("stream mirrors can not use subject sequences"))
+		}
+		if cfg.SigningKey != _EMPTY_ {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("stream mirrors can not require message signatures"))
+		}
+		if len(cfg.AllowedHeaders) > 0 {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("stream mirrors can not restrict message headers"))
+		}
 		if c := cfg.Mirror.Consumer; c != nil {
 			if !isValidAssetName(c.Name) {
 				return StreamConfig{}, NewJSMirrorDurableConsumerCfgInvalidError()
@@ -2232,11 +3091,6 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 	if cfg.Placement != nil && reflect.DeepEqual(cfg.Placement, &Placement{}) {
 		cfg.Placement = nil
 	}
-	// For now don't allow preferred server in placement.
-	if cfg.Placement != nil && cfg.Placement.Preferred != _EMPTY_ {
-		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("preferred server not permitted in placement"))
-	}
-
 	return cfg, nil
 }
 
@@ -2258,7 +3112,7 @@ func (mset *stream) fileStoreConfig() (FileStoreConfig, error) {
 }
 
 // Do not hold jsAccount or jetStream lock
-func (jsa *jsAccount) configUpdateCheck(old, new *StreamConfig, s *Server, pedantic bool) (*StreamConfig, error) {
+func (jsa *jsAccount) configUpdateCheck(old, new *StreamConfig, s *Server, pedantic, migrateStorage bool) (*StreamConfig, error) {
 	cfg, apiErr := s.checkStreamCfg(new, jsa.acc(), pedantic)
 	if apiErr != nil {
 		return nil, apiErr
@@ -2268,8 +3122,8 @@ func (jsa *jsAccount) configUpdateCheck(old, new *StreamConfig, s *Server, pedan
 	if cfg.Name != old.Name {
 		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration name must match original"))
 	}
-	// Can't change storage types.
-	if cfg.Storage != old.Storage {
+	// Can't change storage types, unless asked to migrate the messages.
+	if cfg.Storage != old.Storage && !migrateStorage {
 		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration update can not change storage type"))
 	}
 	// Can only change retention from limits to interest or back, not to/from work queue for now.
@@ -2325,6 +3179,15 @@ func (jsa *jsAccount) configUpdateCheck(old, new *StreamConfig, s *Server, pedan
 		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration update can not change persist mode"))
 	}
 
+	if old.WriteConcern != cfg.WriteConcern {
+		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration update can not change write concern"))
+	}
+
+	// Existing blocks need the same dictionary to be decoded.
+	if !bytes.Equal(old.CompressionDict, cfg.CompressionDict) {
+		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration update can not change compression dictionary"))
+	}
+
 	// Do some adjustments for being sealed.
 	// Pedantic mode will allow those changes to be made, as they are deterministic and important to get a sealed stream.
 	if cfg.Sealed {
@@ -2394,18 +3257,15 @@ func (jsa *jsAccount) configUpdateCheck(old, new *StreamConfig, s *Server, pedan
 
 // Update will allow certain configuration properties of an existing stream to be updated.
 func (mset *stream) update(config *StreamConfig) error {
-	return mset.updateWithAdvisory(config, true, false)
-}
-
-func (mset *stream) updatePedantic(config *StreamConfig, pedantic bool) error {
-	return mset.updateWithAdvisory(config, true, pedantic)
+	return mset.updateWithAdvisory(config, true, false, false)
 }
 
-// Update will allow certain configuration properties of an existing stream to be updated.
-func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool, pedantic bool) error {
+// checkUpdate runs all checks of updating the stream with the given config, without applying it.
+// Returns the current config and the one the stream would be updated to.
+func (mset *stream) checkUpdate(config *StreamConfig, pedantic, migrateStorage bool) (StreamConfig, *StreamConfig, error) {
 	_, jsa, err := mset.acc.checkForJetStream()
 	if err != nil {
-		return err
+		return StreamConfig{}, nil, err
 	}
 
 	mset.mu.RLock()
@@ -2413,9 +3273,9 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 	s := mset.srv
 	mset.mu.RUnlock()
 
-	cfg, err := mset.jsa.configUpdateCheck(&ocfg, config, s, pedantic)
+	cfg, err := mset.jsa.configUpdateCheck(&ocfg, config, s, pedantic, migrateStorage)
 	if err != nil {
-		return NewJSStreamInvalidConfigError(err, Unless(err))
+		return ocfg, nil, NewJSStreamInvalidConfigError(err, Unless(err))
 	}
 
 	// In the event that some of the stream-level limits have changed, yell appropriately
@@ -2443,17 +3303,39 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 		if len(errorConsumers) > 0 {
 			// TODO(nat): Return a parsable error so that we can surface something
 			// sensible through the JS API.
-			return fmt.Errorf("change to limits violates consumers: %s", strings.Join(errorConsumers, ", "))
+			return ocfg, nil, fmt.Errorf("change to limits violates consumers: %s", strings.Join(errorConsumers, ", "))
 		}
 	}
 
 	jsa.mu.RLock()
-	if jsa.subjectsOverlap(cfg.Subjects, mset) {
+	if jsa.subjectsOverlap(cfg.Subjects, cfg.AllowSubjectOverlap, mset) {
 		jsa.mu.RUnlock()
-		return NewJSStreamSubjectOverlapError()
+		return ocfg, nil, NewJSStreamSubjectOverlapError()
 	}
 	jsa.mu.RUnlock()
 
+	return ocfg, cfg, nil
+}
+
+// Update will allow certain configuration properties of an existing stream to be updated.
+// If migrateStorage is set a change of the storage type will move all messages to a new store.
+func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory, pedantic, migrateStorage bool) error {
+	_, jsa, err := mset.acc.checkForJetStream()
+	if err != nil {
+		return err
+	}
+	ocfg, cfg, err := mset.checkUpdate(config, pedantic, migrateStorage)
+	if err != nil {
+		return err
+	}
+	if cfg.Storage != ocfg.Storage {
+		if err := mset.migrateStorage(cfg.Storage); err != nil {
+			return err
+		}
+		// The storage type is switched once the messages have been moved over.
+		cfg.Storage = ocfg.Storage
+	}
+
 	mset.mu.Lock()
 	if mset.active {
 		// Check for mirror promotion.
@@ -2653,6 +3535,7 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 			jsa.updateUsage(mset.tier, mset.stype, -int64(reported))
 			jsa.updateUsage(targetTier, mset.stype, int64(reported))
 			mset.tier = targetTier
+			jsa.trackStore(cfg, targetTier, mset.store)
 		}
 		// else in case the new tier does not exist (say on move), keep the old tier around
 		// a subsequent update to an existing tier will then move from existing past tier to existing new tier
@@ -2692,6 +3575,21 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 	mset.cfg = *cfg
 	mset.cfgMu.Unlock()
 
+	// Sealing the stream or denying deletes stops evicting its oldest messages.
+	if ocfg.Sealed != cfg.Sealed || ocfg.DenyDelete != cfg.DenyDelete {
+		jsa.trackStore(cfg, mset.tier, mset.store)
+	}
+
+	// Start or stop checking for messages about to expire.
+	if ocfg.ExpiryAdvisoryLead != cfg.ExpiryAdvisoryLead {
+		mset.setupExpiryAdvisories()
+	}
+
+	// Start or stop holding back delivery until a quorum of replicas stored messages.
+	if ocfg.DeliverAfterCommit != cfg.DeliverAfterCommit && mset.isClustered() {
+		mset.setupDeliverAfterCommit(mset.isLeader())
+	}
+
 	// If we're changing retention and haven't errored because of consumer
 	// replicas by now, whip through and update the consumer retention.
 	if ocfg.Retention != cfg.Retention {
@@ -2718,6 +3616,7 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 	// If we are the leader never suppress update advisory, simply send.
 	if mset.isLeader() && sendAdvisory {
 		mset.sendUpdateAdvisoryLocked()
+		mset.sendConfigChangeAdvisoryLocked(&ocfg, cfg)
 	}
 	mset.mu.Unlock()
 
@@ -2740,6 +3639,11 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 
 	mset.store.UpdateConfig(cfg)
 
+	if js != nil && !js.isClustered() {
+		mset.acc.setStreamPublishers(cfg)
+		mset.acc.setOverlapStream(cfg)
+	}
+
 	return nil
 }
 
@@ -4195,6 +5099,16 @@ func (mset *stream) processAllSourceMsgs() {
 	t := time.NewTicker(sourceHealthCheckInterval)
 	defer t.Stop()
 
+	// Fires when the next message held back for reordering is due.
+	rt := time.NewTimer(time.Hour)
+	rt.Stop()
+	defer rt.Stop()
+	flushReordered := func() {
+		if next := mset.flushSourceReorderBuffer(); next > 0 {
+			rt.Reset(next)
+		}
+	}
+
 	// When we detect we are no longer leader, we will cleanup.
 	// Should always return right after this is called.
 	cleanUp := func() {
@@ -4206,6 +5120,8 @@ func (mset *stream) processAllSourceMsgs() {
 		mset.smsgs.drain()
 		mset.smsgs.unregister()
 		mset.smsgs = nil
+		// Messages held back were not stored yet, the next leader will source them again.
+		mset.sreorder = nil
 	}
 
 	for {
@@ -4229,6 +5145,9 @@ func (mset *stream) processAllSourceMsgs() {
 				im.returnToPool()
 			}
 			msgs.recycle(&ims)
+			flushReordered()
+		case <-rt.C:
+			flushReordered()
 		case <-t.C:
 			// If we are no longer leader bail.
 			if !mset.IsLeader() {
@@ -4349,7 +5268,7 @@ func (mset *stream) processInboundSourceMsg(si *sourceInfo, m *inMsg) bool {
 		return !needsRetry
 	}
 
-	sseq, dseq, dc, _, pending := ackReplyInfo(m.rply)
+	sseq, dseq, dc, ts, pending := ackReplyInfo(m.rply)
 
 	if dc > 1 {
 		mset.mu.Unlock()
@@ -4388,7 +5307,7 @@ func (mset *stream) processInboundSourceMsg(si *sourceInfo, m *inMsg) bool {
 	} else {
 		si.lag = pending - 1
 	}
-	node := mset.node
+	node, window := mset.node, mset.cfg.SourcesReorderWindow
 	mset.mu.Unlock()
 
 	hdr, msg := m.hdr, m.msg
@@ -4419,12 +5338,27 @@ func (mset *stream) processInboundSourceMsg(si *sourceInfo, m *inMsg) bool {
 		}
 	}
 
+	// Hold the message back if sourced messages need to be stored in timestamp order.
+	if window > 0 {
+		mset.mu.Lock()
+		mset.holdSourceMsg(&sourceReorderMsg{si: si, subj: m.subj, hdr: hdr, msg: msg, sseq: sseq, dseq: dseq, ts: ts, held: time.Now()})
+		mset.mu.Unlock()
+		return true
+	}
+
+	return mset.storeSourceMsg(si, node, m.subj, hdr, msg, sseq, osseq, odseq)
+}
+
+// Stores a message sourced from the given source. If it can't be stored, the source
+// is reset to the given sequences and its consumer is recreated from the message.
+// Returns false if the consumer needs to be recreated.
+func (mset *stream) storeSourceMsg(si *sourceInfo, node RaftNode, subj string, hdr, msg []byte, sseq, osseq, odseq uint64) bool {
 	var err error
 	// If we are clustered we need to propose this message to the underlying raft group.
 	if node != nil {
-		err = mset.processClusteredInboundMsg(m.subj, _EMPTY_, hdr, msg, nil, true)
+		err = mset.processClusteredInboundMsg(subj, _EMPTY_, hdr, msg, nil, true)
 	} else {
-		err = mset.processJetStreamMsg(m.subj, _EMPTY_, hdr, msg, 0, 0, nil, true, true)
+		err = mset.processJetStreamMsg(subj, _EMPTY_, hdr, msg, 0, 0, nil, true, true)
 	}
 	if err != nil {
 		s := mset.srv
@@ -4438,7 +5372,7 @@ func (mset *stream) processInboundSourceMsg(si *sourceInfo, m *inMsg) bool {
 			// Can happen temporarily all the time during normal operations when the sourcing stream is discard new
 			// (example use case is for sourcing into a work queue)
 			// TODO - Maybe improve sourcing to WQ with limit and new to use flow control rather than re-creating the consumer.
-			discardNew := errors.Is(err, ErrMaxMsgs) || errors.Is(err, ErrMaxBytes) || errors.Is(err, ErrMaxMsgsPerSubject)
+			discardNew := errors.Is(err, ErrMaxMsgs) || errors.Is(err, ErrMaxBytes) || errors.Is(err, ErrMaxMsgsPerSubject) || errors.Is(err, ErrMaxSubjects)
 
 			// Log some warning for errors.
 			if !discardNew && !errors.Is(err, errLastSeqMismatch) && !errors.Is(err, errMsgIdDuplicate) {
@@ -4466,6 +5400,67 @@ func (mset *stream) processInboundSourceMsg(si *sourceInfo, m *inMsg) bool {
 	return true
 }
 
+// sourceReorderMsg is a sourced message held back so messages
+// from different sources can be stored in timestamp order.
+type sourceReorderMsg struct {
+	si   *sourceInfo
+	subj string
+	hdr  []byte
+	msg  []byte
+	sseq uint64    // Sequence in the source stream
+	dseq uint64    // Delivery sequence of the source consumer
+	ts   int64     // Timestamp in the source stream
+	held time.Time // When the message was held back
+}
+
+// Maximum number of sourced messages held back for reordering. Once reached,
+// the oldest messages are stored without waiting for the reorder window.
+const sourceReorderMaxPending = 10_000
+
+// Adds a sourced message to the reorder buffer, which is kept in timestamp order.
+// Messages are never placed before those from the same source, so the source's
+// last stored sequence can still be used to resume sourcing.
+// Lock should be held.
+func (mset *stream) holdSourceMsg(rm *sourceReorderMsg) {
+	i := len(mset.sreorder)
+	for i > 0 && mset.sreorder[i-1].ts > rm.ts && mset.sreorder[i-1].si != rm.si {
+		i--
+	}
+	mset.sreorder = slices.Insert(mset.sreorder, i, rm)
+}
+
+// Stores the sourced messages that were held back for the reorder window, in timestamp
+// order. Returns how long until the next message is due, or zero if none are held back.
+func (mset *stream) flushSourceReorderBuffer() time.Duration {
+	for {
+		mset.mu.Lock()
+		if len(mset.sreorder) == 0 {
+			mset.mu.Unlock()
+			return 0
+		}
+		rm := mset.sreorder[0]
+		// If the window was removed, or we hold back too many, store right away.
+		if window := mset.cfg.SourcesReorderWindow; window > 0 && len(mset.sreorder) <= sourceReorderMaxPending {
+			if wait := window - time.Since(rm.held); wait > 0 {
+				mset.mu.Unlock()
+				return wait
+			}
+		}
+		mset.sreorder[0] = nil
+		mset.sreorder = mset.sreorder[1:]
+		node := mset.node
+		mset.mu.Unlock()
+
+		if !mset.storeSourceMsg(rm.si, node, rm.subj, rm.hdr, rm.msg, rm.sseq, rm.sseq-1, rm.dseq-1) {
+			// The source will be consumed again starting at this message, so we drop
+			// what we held back for it, those messages will be delivered again.
+			mset.mu.Lock()
+			mset.sreorder = slices.DeleteFunc(mset.sreorder, func(o *sourceReorderMsg) bool { return o.si == rm.si })
+			mset.mu.Unlock()
+		}
+	}
+}
+
 // Generate a new (2.10) style source header (stream name, sequence number, source filter, source destination transform).
 func (si *sourceInfo) genSourceHeader(orig, reply string) string {
 	var b strings.Builder
@@ -5134,33 +6129,138 @@ func (mset *stream) unsubscribe(sub *subscription) {
 	mset.client.processUnsub(sub.sid)
 }
 
-func (mset *stream) setupStore(fsCfg *FileStoreConfig) error {
-	mset.mu.Lock()
-	switch mset.cfg.Storage {
-	case MemoryStorage:
-		ms, err := newMemStore(&mset.cfg)
-		if err != nil {
-			mset.mu.Unlock()
+// How long to wait for the response to a JetStream API request sent by a stream.
+const streamApiRequestTimeout = 5 * time.Second
+
+// apiRequest sends a JetStream API request from the stream's account and waits for the response.
+// Lock should not be held.
+func (mset *stream) apiRequest(subject string, req, resp any) error {
+	respCh := make(chan []byte, 1)
+	reply := infoReplySubject()
+	sub, err := mset.subscribeInternal(reply, func(_ *subscription, c *client, _ *Account, _, _ string, rmsg []byte) {
+		_, msg := c.msgParts(rmsg)
+		select {
+		case respCh <- copyBytes(msg):
+		default:
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer mset.unsubscribe(sub)
+
+	var b []byte
+	if req != nil {
+		if b, err = json.Marshal(req); err != nil {
 			return err
 		}
-		mset.store = ms
-	case FileStorage:
-		s := mset.srv
-		prf := s.jsKeyGen(s.getOpts().JetStreamKey, mset.acc.Name)
-		if prf != nil {
-			// We are encrypted here, fill in correct cipher selection.
-			fsCfg.Cipher = s.getOpts().JetStreamCipher
+	}
+	mset.outq.send(newJSPubMsg(subject, _EMPTY_, reply, nil, b, nil, 0))
+
+	timeout := time.NewTimer(streamApiRequestTimeout)
+	defer timeout.Stop()
+	select {
+	case msg := <-respCh:
+		return json.Unmarshal(msg, resp)
+	case <-timeout.C:
+		return errReqTimeout
+	case <-mset.srv.quitCh:
+		return errReqSrvExit
+	}
+}
+
+// startTimeAfter returns the start time for delivering the messages stored after seq.
+// Another stream storing the same messages will have stored them at slightly different
+// times, so we pick the time halfway between the message at seq and the one after it.
+func (mset *stream) startTimeAfter(seq uint64) time.Time {
+	var after, next int64
+	var smv StoreMsg
+	if sm, err := mset.store.LoadMsg(seq, &smv); err == nil && sm != nil {
+		after = sm.ts
+	}
+	if sm, _, err := mset.store.LoadNextMsg(fwcs, true, seq+1, &smv); err == nil && sm != nil {
+		next = sm.ts
+	}
+	switch {
+	case after > 0 && next > 0:
+		return time.Unix(0, after+(next-after)/2).UTC()
+	case next > 0:
+		return time.Unix(0, next).UTC()
+	case after > 0:
+		return time.Unix(0, after+1).UTC()
+	}
+	// Neither is stored anymore, start after the last message.
+	var state StreamState
+	mset.store.FastState(&state)
+	if state.LastTime.IsZero() {
+		return time.Now().UTC()
+	}
+	return state.LastTime.Add(time.Nanosecond)
+}
+
+// configureStreamFileStore fills in the file store configuration for a stream with config cfg, stored in storeDir.
+func (s *Server) configureStreamFileStore(fsCfg *FileStoreConfig, storeDir string, cfg *StreamConfig) {
+	opts := s.getOpts()
+	fsCfg.StoreDir = storeDir
+	// Grab configured sync interval.
+	fsCfg.SyncInterval = opts.SyncInterval
+	fsCfg.SyncAlways = opts.SyncAlways
+	fsCfg.TombstoneMaxAge = opts.JetStreamTombstoneMaxAge
+	fsCfg.Compression = cfg.Compression
+	// Async flushing is only allowed if the stream has a sync log backing it.
+	fsCfg.AsyncFlush = !fsCfg.SyncAlways && cfg.Replicas > 1
+
+	// Async persist mode opts in to async flushing,
+	// sync always would also be disabled if it was configured.
+	if cfg.PersistMode == AsyncPersistMode {
+		fsCfg.SyncAlways = false
+		fsCfg.AsyncFlush = true
+	}
+	// The strict write concern syncs every write.
+	if cfg.WriteConcern == WriteConcernStrict {
+		fsCfg.SyncAlways = true
+		fsCfg.AsyncFlush = false
+	}
+}
+
+func (mset *stream) setupStore(fsCfg *FileStoreConfig) error {
+	mset.mu.Lock()
+	store, err := mset.newStore(&mset.cfg, fsCfg)
+	if err != nil {
+		mset.mu.Unlock()
+		return err
+	}
+	mset.store = store
+	mset.registerStoreCallbacks()
+	mset.mu.Unlock()
+
+	return nil
+}
+
+// newStore creates a store for our messages with the storage type of cfg.
+// Lock should be held.
+func (mset *stream) newStore(cfg *StreamConfig, fsCfg *FileStoreConfig) (StreamStore, error) {
+	switch cfg.Storage {
+	case MemoryStorage:
+		return newMemStore(cfg)
+	case FileStorage:
+		s := mset.srv
+		prf := s.jsKeyGen(s.getOpts().JetStreamKey, mset.acc.Name)
+		if prf != nil {
+			// We are encrypted here, fill in correct cipher selection.
+			fsCfg.Cipher = s.getOpts().JetStreamCipher
 		}
 		oldprf := s.jsKeyGen(s.getOpts().JetStreamOldKey, mset.acc.Name)
-		cfg := *fsCfg
-		cfg.srv = s
-		fs, err := newFileStoreWithCreated(cfg, mset.cfg, mset.created, prf, oldprf)
-		if err != nil {
-			mset.mu.Unlock()
-			return err
-		}
-		mset.store = fs
+		fcfg := *fsCfg
+		fcfg.srv = s
+		return newFileStoreWithCreated(fcfg, *cfg, mset.created, prf, oldprf)
 	}
+	return nil, NewJSStreamInvalidConfigError(fmt.Errorf("invalid storage type"))
+}
+
+// registerStoreCallbacks hooks up our store to account for its usage, and to remove and process messages.
+// Lock should be held.
+func (mset *stream) registerStoreCallbacks() {
 	// This will fire the callback but we do not require the lock since md will be 0 here.
 	mset.store.RegisterStorageUpdates(mset.storeUpdates)
 	mset.store.RegisterStorageRemoveMsg(func(seq uint64) {
@@ -5184,9 +6284,9 @@ func (mset *stream) setupStore(fsCfg *FileStoreConfig) error {
 			mset.processJetStreamMsg(im.subj, im.rply, im.hdr, im.msg, 0, 0, im.mt, true, true)
 		}
 	})
-	mset.mu.Unlock()
-
-	return nil
+	if mset.jsa != nil {
+		mset.jsa.trackStore(&mset.cfg, mset.tier, mset.store)
+	}
 }
 
 // Called for any updates to the underlying stream. We pass through the bytes to the
@@ -5194,6 +6294,13 @@ func (mset *stream) setupStore(fsCfg *FileStoreConfig) error {
 // for removals.
 // Lock should not be held.
 func (mset *stream) storeUpdates(md, bd int64, seq uint64, subj string) {
+	// Make sure removed messages are no longer shared by consumer read groups.
+	if md == -1 && seq > 0 {
+		mset.invalidateReadGroups(seq)
+	} else if md < 0 {
+		mset.invalidateReadGroups(0)
+	}
+
 	// If we have a single negative update then we will process our consumers for stream pending.
 	// Purge and Store handled separately inside individual calls.
 	if md == -1 && seq > 0 && subj != _EMPTY_ {
@@ -5239,7 +6346,27 @@ func (mset *stream) checkMsgId(id string) *ddentry {
 	if id == _EMPTY_ || len(mset.ddmap) == 0 {
 		return nil
 	}
-	return mset.ddmap[id]
+	return mset.checkMsgIdAt(id, time.Now().UnixNano())
+}
+
+// checkMsgIdAt will check for duplicates of a message with the given timestamp.
+// An id is only a duplicate within the duplicate window, even if it wasn't purged yet.
+// mset.ddMu lock should be held.
+func (mset *stream) checkMsgIdAt(id string, ts int64) *ddentry {
+	dde := mset.ddmap[id]
+	if dde == nil {
+		return nil
+	}
+	mset.cfgMu.RLock()
+	window, inclusive := int64(mset.cfg.Duplicates), mset.cfg.DuplicateWindowInclusive
+	mset.cfgMu.RUnlock()
+	// Staged ids have no timestamp yet, those are always duplicates.
+	if window > 0 && dde.ts > 0 {
+		if age := ts - dde.ts; age > window || (age == window && !inclusive) {
+			return nil
+		}
+	}
+	return dde
 }
 
 // Will purge the entries that are past the window.
@@ -5247,16 +6374,28 @@ func (mset *stream) checkMsgId(id string) *ddentry {
 func (mset *stream) purgeMsgIds() {
 	now := time.Now().UnixNano()
 	mset.cfgMu.RLock()
-	tmrNext := mset.cfg.Duplicates
+	tmrNext, maxIds, inclusive := mset.cfg.Duplicates, mset.cfg.DuplicateMaxIds, mset.cfg.DuplicateWindowInclusive
 	mset.cfgMu.RUnlock()
 	window := int64(tmrNext)
 
 	mset.ddMu.Lock()
 	defer mset.ddMu.Unlock()
 
+	// If only limited by count, ids don't expire.
+	if window <= 0 && maxIds > 0 {
+		if mset.ddtmr != nil {
+			mset.ddtmr.Stop()
+			mset.ddtmr = nil
+		}
+		return
+	}
+
 	for i, dde := range mset.ddarr[mset.ddindex:] {
-		if now-dde.ts >= window {
-			delete(mset.ddmap, dde.id)
+		if age := now - dde.ts; age > window || (age == window && !inclusive) {
+			// The id could have been stored again after it expired.
+			if mset.ddmap[dde.id] == dde {
+				delete(mset.ddmap, dde.id)
+			}
 		} else {
 			mset.ddindex += i
 			// Check if we should garbage collect here if we are 1/3 total size.
@@ -5301,7 +6440,8 @@ func (mset *stream) storeMsgId(dde *ddentry) {
 // mset.ddMu lock should be held.
 func (mset *stream) storeMsgIdLocked(dde *ddentry) {
 	// Zero means disabled.
-	if mset.cfg.Duplicates <= 0 {
+	duplicates, maxIds := mset.cfg.Duplicates, mset.cfg.DuplicateMaxIds
+	if duplicates <= 0 && maxIds <= 0 {
 		return
 	}
 
@@ -5310,8 +6450,26 @@ func (mset *stream) storeMsgIdLocked(dde *ddentry) {
 	}
 	mset.ddmap[dde.id] = dde
 	mset.ddarr = append(mset.ddarr, dde)
-	if mset.ddtmr == nil {
-		mset.ddtmr = time.AfterFunc(mset.cfg.Duplicates, mset.purgeMsgIds)
+	if mset.ddtmr == nil && duplicates > 0 {
+		mset.ddtmr = time.AfterFunc(duplicates, mset.purgeMsgIds)
+	}
+
+	// Forget the oldest ids if we track more than allowed.
+	if maxIds > 0 && int64(len(mset.ddmap)) > maxIds {
+		for int64(len(mset.ddmap)) > maxIds && mset.ddindex < len(mset.ddarr) {
+			odde := mset.ddarr[mset.ddindex]
+			mset.ddarr[mset.ddindex] = nil
+			mset.ddindex++
+			// The id could have been stored again since.
+			if mset.ddmap[odde.id] == odde {
+				delete(mset.ddmap, odde.id)
+			}
+		}
+		// Check if we should garbage collect here if we are 1/3 total size.
+		if cap(mset.ddarr) > 3*(len(mset.ddarr)-mset.ddindex) {
+			mset.ddarr = append([]*ddentry(nil), mset.ddarr[mset.ddindex:]...)
+			mset.ddindex = 0
+		}
 	}
 }
 
@@ -5339,6 +6497,100 @@ func getExpectedLastSeq(hdr []byte) (uint64, bool) {
 	return uint64(parseInt64(bseq)), true
 }
 
+// verifyMsgSignature returns whether the message payload is signed by the given
+// public nkey, with the signature in the Nats-Msg-Signature header.
+func verifyMsgSignature(signingKey string, hdr, msg []byte) bool {
+	sig := sliceHeader(JSMsgSignature, hdr)
+	if len(sig) == 0 {
+		return false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(bytesToString(sig))
+	if err != nil {
+		return false
+	}
+	pub, err := nkeys.FromPublicKey(signingKey)
+	if err != nil {
+		return false
+	}
+	return pub.Verify(msg, raw) == nil
+}
+
+// filterAllowedHeaders checks the message headers against the allowed header names.
+// Headers that are not allowed are stripped, or if they should be rejected the name
+// of the first one is returned.
+func filterAllowedHeaders(hdr []byte, allowed []string, action DisallowedHeaderAction) ([]byte, string) {
+	if len(allowed) == 0 || len(hdr) == 0 {
+		return hdr, _EMPTY_
+	}
+	crlf := []byte(_CRLF_)
+	// Skip over the status line.
+	start := bytes.Index(hdr, crlf)
+	if start < 0 {
+		return hdr, _EMPTY_
+	}
+	start += len(crlf)
+
+	// Only copied once a header needs to be stripped.
+	var nhdr []byte
+	for start < len(hdr) {
+		end := bytes.Index(hdr[start:], crlf)
+		// Either the final empty line or malformed.
+		if end <= 0 {
+			break
+		}
+		next := start + end + len(crlf)
+		name, _, _ := bytes.Cut(hdr[start:next], []byte{':'})
+		if isAllowedHeader(bytesToString(name), allowed) {
+			if nhdr != nil {
+				nhdr = append(nhdr, hdr[start:next]...)
+			}
+		} else if action == RejectDisallowedHeaders {
+			return hdr, string(name)
+		} else if nhdr == nil {
+			nhdr = append([]byte(nil), hdr[:start]...)
+		}
+		start = next
+	}
+	if nhdr == nil {
+		return hdr, _EMPTY_
+	}
+	if nhdr = append(nhdr, hdr[start:]...); len(nhdr) <= len(emptyHdrLine) {
+		return nil, _EMPTY_
+	}
+	return nhdr, _EMPTY_
+}
+
+// isAllowedHeader returns whether the header name is in the allowed list, or used by JetStream itself.
+func isAllowedHeader(name string, allowed []string) bool {
+	const reserved = "Nats-"
+	if len(name) >= len(reserved) && strings.EqualFold(name[:len(reserved)], reserved) {
+		return true
+	}
+	return slices.ContainsFunc(allowed, func(h string) bool { return strings.EqualFold(h, name) })
+}
+
+// lastSubjectSequence returns the per-subject sequence of the last message stored for the subject.
+// Used by the stores when they don't track per-subject sequences themselves.
+func lastSubjectSequence(store StreamStore, subject string) uint64 {
+	var smv StoreMsg
+	sm, err := store.LoadLastMsg(subject, &smv)
+	if err != nil || sm == nil {
+		return 0
+	}
+	if seq := parseInt64(sliceHeader(JSSubjectSequence, sm.hdr)); seq > 0 {
+		return uint64(seq)
+	}
+	return 0
+}
+
+// setSubjectSequence sets the per-subject sequence header, replacing any existing one.
+func setSubjectSequence(hdr []byte, seq uint64) []byte {
+	if len(hdr) == 0 {
+		return genHeader(nil, JSSubjectSequence, strconv.FormatUint(seq, 10))
+	}
+	return setHeader(JSSubjectSequence, strconv.FormatUint(seq, 10), hdr)
+}
+
 // Fast lookup of rollups.
 func getRollup(hdr []byte) string {
 	r := getHeader(JSMsgRollup, hdr)
@@ -6095,6 +7347,23 @@ func (mset *stream) getDirectRequest(req *JSApiMsgGetRequest, reply string) {
 func (mset *stream) processInboundJetStreamMsg(_ *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	hdr, msg := c.msgParts(copyBytes(rmsg)) // Need to copy.
 	hdr = removeHeaderStatusIfPresent(hdr)
+
+	// The publish will be rejected when processed, but only here do we know who attempted it.
+	mset.cfgMu.RLock()
+	advise, sealed, overlap := mset.cfg.RejectedPublishAdvisory, mset.cfg.Sealed, mset.cfg.AllowSubjectOverlap
+	mset.cfgMu.RUnlock()
+	// Only one of the streams storing a message on an overlapping subject acknowledges it,
+	// so the others drop the reply and don't report their own rejections to the publisher.
+	if overlap && reply != _EMPTY_ && !mset.isOverlapAckOwner(subject) {
+		reply = _EMPTY_
+	}
+	if advise {
+		if sealed {
+			mset.sendStreamPublishRejectedAdvisory(c, hdr, subject, NewJSStreamSealedError())
+		} else if mset.isReadOnly() {
+			mset.sendStreamPublishRejectedAdvisory(c, hdr, subject, NewJSStreamReadOnlyError(mset.getWriteErr()))
+		}
+	}
 	if mt, traceOnly := c.isMsgTraceEnabled(); mt != nil {
 		// If message is delivered, we need to disable the message trace headers
 		// to prevent a trace event to be generated when a stored message
@@ -6178,6 +7447,8 @@ func (mset *stream) processJetStreamMsgWithBatch(subject, reply string, hdr, msg
 	numConsumers := len(mset.consumers)
 	interestRetention := mset.cfg.Retention == InterestPolicy
 	allowMsgCounter, allowMsgSchedules := mset.cfg.AllowMsgCounter, mset.cfg.AllowMsgSchedules
+	subjectSeq, signingKey := mset.cfg.SubjectSequence, mset.cfg.SigningKey
+	allowedHeaders, disallowedHeaders := mset.cfg.AllowedHeaders, mset.cfg.DisallowedHeaders
 	allowRollupPurge := mset.cfg.AllowRollup && !mset.cfg.DenyPurge
 	// Snapshot if we are the leader and if we can respond.
 	isLeader, isSealed := mset.isLeaderNodeState(), mset.cfg.Sealed
@@ -6226,6 +7497,45 @@ func (mset *stream) processJetStreamMsgWithBatch(subject, reply string, hdr, msg
 		return ApiErrors[JSStreamSealedErr]
 	}
 
+	// Bail here if a storage error made us read-only.
+	if werr := mset.readOnlyErr(); canConsistencyCheck && werr != nil {
+		err := NewJSStreamReadOnlyError(werr)
+		if canRespond && outq != nil {
+			resp.PubAck = &PubAck{Stream: name}
+			resp.Error = err
+			b, _ := json.Marshal(resp)
+			outq.sendMsg(reply, b)
+		}
+		return err
+	}
+
+	// Bail here if the message is not signed by the stream's signing key.
+	if canConsistencyCheck && signingKey != _EMPTY_ && !sourced && !verifyMsgSignature(signingKey, hdr, msg) {
+		apiErr := NewJSMessageSignatureInvalidError()
+		if canRespond && outq != nil {
+			resp.PubAck = &PubAck{Stream: name}
+			resp.Error = apiErr
+			b, _ := json.Marshal(resp)
+			outq.sendMsg(reply, b)
+		}
+		return apiErr
+	}
+
+	// Strip or reject headers that are not allowed.
+	if canConsistencyCheck && len(allowedHeaders) > 0 && !sourced {
+		var header string
+		if hdr, header = filterAllowedHeaders(hdr, allowedHeaders, disallowedHeaders); header != _EMPTY_ {
+			apiErr := NewJSStreamHeaderNotAllowedError(header)
+			if canRespond && outq != nil {
+				resp.PubAck = &PubAck{Stream: name}
+				resp.Error = apiErr
+				b, _ := json.Marshal(resp)
+				outq.sendMsg(reply, b)
+			}
+			return apiErr
+		}
+	}
+
 	var buf [256]byte
 	pubAck := append(buf[:0], mset.pubAck...)
 
@@ -6792,6 +8102,11 @@ func (mset *stream) processJetStreamMsgWithBatch(subject, reply string, hdr, msg
 		}
 	}
 
+	// Stamp the next per-subject sequence.
+	if canConsistencyCheck && subjectSeq && !isMirror {
+		hdr = setSubjectSequence(hdr, store.LastSubjectSequence(subject)+1)
+	}
+
 	// Check to see if we are over the max msg size.
 	// Subtract to prevent against overflows.
 	if canConsistencyCheck && maxMsgSize >= 0 && (len(hdr) > maxMsgSize || len(msg) > maxMsgSize-len(hdr)) {
@@ -6914,10 +8229,40 @@ func (mset *stream) processJetStreamMsgWithBatch(subject, reply string, hdr, msg
 		}
 	}
 
+	// Publishes using a capacity reservation draw from it, others need to leave the reserved capacity available.
+	var rsv *capacityReservation
+	var rsvSz uint64
+	if len(mset.reservations) > 0 {
+		if stype == MemoryStorage {
+			rsvSz = memStoreMsgSize(subject, hdr, msg)
+		} else {
+			rsvSz = fileStoreMsgSize(subject, hdr, msg)
+		}
+		if rsv = mset.reservations[string(sliceHeader(JSReservationId, hdr))]; rsv != nil && !rsv.covers(rsvSz) {
+			rsv = nil
+		}
+		if rsv == nil {
+			if err := mset.checkReservedCapacity(rsvSz); err != nil {
+				s.RateLimitDebugf("JetStream failed to store a msg on stream '%s > %s': %v", accName, name, err)
+				bumpCLFS()
+				if canRespond {
+					resp.PubAck = &PubAck{Stream: name}
+					resp.Error = NewJSStreamStoreFailedError(err, Unless(err))
+					response, _ = json.Marshal(resp)
+					outq.sendMsg(reply, response)
+				}
+				return err
+			}
+		}
+	}
+
 	// If clustered this was already checked and we do not want to check here and possibly introduce skew.
 	// Don't error and log if we're tracing when clustered.
-	if !isClustered {
-		if exceeded, err := jsa.wouldExceedLimits(stype, mset.tier, mset.cfg.Replicas, subject, hdr, msg); exceeded {
+	// Reserved bytes were already checked against the account limits, reserved messages were not.
+	// The account limits may allow evicting the oldest messages to make room.
+	if !isClustered && (rsv == nil || !rsv.byBytes) {
+		if exceeded, err := jsa.wouldExceedLimits(stype, mset.tier, mset.cfg.Replicas, subject, hdr, msg); exceeded &&
+			(err != nil || !jsa.evictOldest(stype, mset.tier, mset.cfg.Replicas, subject, hdr, msg)) {
 			if err == nil {
 				err = NewJSAccountResourcesExceededError()
 			}
@@ -6974,7 +8319,7 @@ func (mset *stream) processJetStreamMsgWithBatch(subject, reply string, hdr, msg
 		}
 
 		switch err {
-		case ErrMaxMsgs, ErrMaxBytes, ErrMaxMsgsPerSubject, ErrMsgTooLarge:
+		case ErrMaxMsgs, ErrMaxBytes, ErrMaxMsgsPerSubject, ErrMaxSubjects, ErrMsgTooLarge:
 			s.RateLimitDebugf("JetStream failed to store a msg on stream '%s > %s': %v", accName, name, err)
 		case ErrStoreClosed:
 		default:
@@ -6983,6 +8328,13 @@ func (mset *stream) processJetStreamMsgWithBatch(subject, reply string, hdr, msg
 			// the stream if it was replicated.
 			s.RateLimitErrorf("JetStream failed to store a msg on stream '%s > %s': %v", accName, name, err)
 			mset.setWriteErrLocked(err)
+			// If we are now read-only let the user know, since we'll remain the leader.
+			if werr := mset.readOnlyErr(); werr != nil && canRespond {
+				resp.PubAck = &PubAck{Stream: name}
+				resp.Error = NewJSStreamReadOnlyError(werr)
+				response, _ = json.Marshal(resp)
+				outq.sendMsg(reply, response)
+			}
 			return err
 		}
 
@@ -6997,6 +8349,10 @@ func (mset *stream) processJetStreamMsgWithBatch(subject, reply string, hdr, msg
 		return err
 	}
 
+	if rsv != nil {
+		mset.useReservation(rsv, rsvSz)
+	}
+
 	// Check for preAcks and the need to clear it.
 	if mset.hasAllPreAcks(seq, subject) {
 		mset.clearAllPreAcks(seq)
@@ -7142,6 +8498,7 @@ func (mset *stream) processJetStreamAtomicBatchMsg(batchId, subject, reply strin
 	s, js, jsa, r, tierName, outq, node := mset.srv, mset.js, mset.jsa, mset.cfg.Replicas, mset.tier, mset.outq, mset.node
 	maxMsgSize, lseq := int(mset.cfg.MaxMsgSize), mset.lseq
 	isLeader, isClustered, isSealed, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules, allowAtomicPublish := mset.isLeader(), mset.isClustered(), mset.cfg.Sealed, mset.cfg.AllowRollup, mset.cfg.DenyPurge, mset.cfg.AllowMsgTTL, mset.cfg.AllowMsgCounter, mset.cfg.AllowMsgSchedules, mset.cfg.AllowAtomicPublish
+	subjectSeq := mset.cfg.SubjectSequence
 	mset.mu.RUnlock()
 
 	// If message tracing (with message delivery), we will need to send the
@@ -7461,7 +8818,7 @@ func (mset *stream) processJetStreamAtomicBatchMsg(batchId, subject, reply strin
 			return errorOnUnsupported(JSExpectedLastMsgId)
 		}
 
-		if bhdr, bmsg, _, apiErr, err = checkMsgHeadersPreClusteredProposal(diff, mset, csubj, bsubj, bhdr, bmsg, false, name, jsa, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules, discard, discardNewPer, maxMsgSize, maxMsgs, maxMsgsPer, maxBytes); err != nil {
+		if bhdr, bmsg, _, apiErr, err = checkMsgHeadersPreClusteredProposal(diff, mset, csubj, bsubj, bhdr, bmsg, false, name, jsa, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules, subjectSeq, discard, discardNewPer, maxMsgSize, maxMsgs, maxMsgsPer, maxBytes); err != nil {
 			rollback()
 			b.cleanupLocked(batchId, batches)
 			batches.mu.Unlock()
@@ -7552,6 +8909,7 @@ func (mset *stream) processJetStreamFastBatchMsg(batch *FastBatch, subject, repl
 	s, js, jsa, st, r, tierName, outq, node := mset.srv, mset.js, mset.jsa, mset.cfg.Storage, mset.cfg.Replicas, mset.tier, mset.outq, mset.node
 	maxMsgSize, lseq := int(mset.cfg.MaxMsgSize), mset.lseq
 	isLeader, isClustered, isSealed, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules, allowBatchPublish := mset.isLeader(), mset.isClustered(), mset.cfg.Sealed, mset.cfg.AllowRollup, mset.cfg.DenyPurge, mset.cfg.AllowMsgTTL, mset.cfg.AllowMsgCounter, mset.cfg.AllowMsgSchedules, mset.cfg.AllowBatchPublish
+	subjectSeq := mset.cfg.SubjectSequence
 
 	// Apply the input subject transform if any
 	csubject := subject
@@ -7808,7 +9166,7 @@ func (mset *stream) processJetStreamFastBatchMsg(batch *FastBatch, subject, repl
 		err    error
 	)
 	diff := &batchStagedDiff{}
-	if hdr, msg, dseq, apiErr, err = checkMsgHeadersPreClusteredProposal(diff, mset, csubject, subject, hdr, msg, false, name, jsa, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules, discard, discardNewPer, maxMsgSize, maxMsgs, maxMsgsPer, maxBytes); err != nil {
+	if hdr, msg, dseq, apiErr, err = checkMsgHeadersPreClusteredProposal(diff, mset, csubject, subject, hdr, msg, false, name, jsa, allowRollup, denyPurge, allowTTL, allowMsgCounter, allowMsgSchedules, subjectSeq, discard, discardNewPer, maxMsgSize, maxMsgs, maxMsgsPer, maxBytes); err != nil {
 		mset.clMu.Unlock()
 
 		// If the message is a duplicate, and we have no pending messages, we should check if we need to
@@ -8224,81 +9582,719 @@ func (mset *stream) resetAndWaitOnConsumers() {
 	}
 }
 
-// Internal function to delete a stream.
-func (mset *stream) delete() error {
-	if mset == nil {
-		return nil
+// checkStreamRename checks if the stream with config cfg can be renamed to name,
+// and returns the config for the renamed stream. Subjects whose first token is
+// the stream name, like the default subject, are renamed along with the stream.
+func (s *Server) checkStreamRename(cfg *StreamConfig, name string) (*StreamConfig, *ApiError) {
+	if !isValidAssetName(name) || len(name) > JSMaxNameLen {
+		return nil, NewJSStreamRenameNotAllowedError(fmt.Errorf("invalid stream name %q", name))
 	}
-	return mset.stop(true, true)
+	if name == cfg.Name {
+		return nil, NewJSStreamRenameNotAllowedError(errors.New("stream already has this name"))
+	}
+	if cfg.Storage != FileStorage {
+		return nil, NewJSStreamRenameNotAllowedError(errors.New("requires file storage"))
+	}
+	if s.getOpts().JetStreamKey != _EMPTY_ {
+		return nil, NewJSStreamRenameNotAllowedError(errors.New("not supported for encrypted streams"))
+	}
+	ncfg := cfg.clone()
+	ncfg.Name = name
+	ncfg.Subjects = slices.Clone(cfg.Subjects)
+	for i, subj := range ncfg.Subjects {
+		if tokenAt(subj, 1) == cfg.Name {
+			ncfg.Subjects[i] = name + subj[len(cfg.Name):]
+		}
+	}
+	return ncfg, nil
 }
 
-// Internal function to stop or delete the stream.
-func (mset *stream) stop(deleteFlag, advisory bool) error {
-	mset.mu.RLock()
-	js, jsa, name, offlineReason := mset.js, mset.jsa, mset.cfg.Name, mset.offlineReason
-	mset.mu.RUnlock()
-
-	if jsa == nil {
-		return NewJSNotEnabledForAccountError()
+// streamReferences returns true if cfg mirrors or sources from the named stream
+// in its own account.
+func streamReferences(cfg *StreamConfig, name string) bool {
+	if m := cfg.Mirror; m != nil && m.Name == name && m.External == nil {
+		return true
+	}
+	for _, ssi := range cfg.Sources {
+		if ssi != nil && ssi.Name == name && ssi.External == nil {
+			return true
+		}
 	}
+	return false
+}
 
-	isShuttingDown := js.isShuttingDown()
+// capacityReservation is capacity of the stream reserved for publishes carrying its id in the
+// JSReservationId header. Other publishes can not use it, until it expires or is used up.
+// When clustered, reservations are replicated so every replica checks publishes the same way.
+type capacityReservation struct {
+	id      string
+	msgs    uint64 // Messages remaining, if reserving messages.
+	bytes   uint64 // Bytes remaining, if reserving bytes.
+	byMsgs  bool
+	byBytes bool
+	rf      int // Replicas the account storage was reserved for.
+	expires time.Time
+	tmr     *time.Timer // Expires the reservation, only running on the leader when clustered.
+}
 
-	// Remove from our account map first.
-	jsa.mu.Lock()
-	// Preserve in the account if it's marked offline, to have it remain queryable.
-	if deleteFlag || offlineReason == _EMPTY_ {
-		delete(jsa.streams, name)
+// covers returns whether the reservation has capacity left for a message of size sz.
+func (r *capacityReservation) covers(sz uint64) bool {
+	return (!r.byMsgs || r.msgs > 0) && (!r.byBytes || r.bytes >= sz)
+}
+
+// reservedCapacity returns the messages and bytes reserved for bulk publishes.
+// Lock should be held.
+func (mset *stream) reservedCapacity() (msgs, bytes uint64) {
+	for _, r := range mset.reservations {
+		msgs += r.msgs
+		bytes += r.bytes
 	}
-	accName := jsa.account.Name
-	jsa.mu.Unlock()
+	return msgs, bytes
+}
 
-	// Kick monitor and collect consumers first.
+// reserveCapacity reserves msgs and bytes of the stream and account limits until expires, for
+// publishes setting id in the JSReservationId header. When clustered, this is applied by every
+// replica once replicated, and the account limits were already checked by the leader.
+func (mset *stream) reserveCapacity(id string, msgs, bytes uint64, expires time.Time) error {
 	mset.mu.Lock()
+	defer mset.mu.Unlock()
 
-	// Mark closed.
-	mset.closed.Store(true)
-
-	// Both flags set mean a delete where we are the stream leader.
-	// Try to clean up any consumers used for sourcing (if one wasn't provided to us).
-	if deleteFlag && advisory {
-		if mset.cfg.Mirror != nil && mset.cfg.Mirror.Consumer == nil {
-			mset.tryDeleteMirrorConsumer(mset.cfg.Mirror)
+	if mset.closed.Load() {
+		return NewJSStreamNotFoundError()
+	}
+	// Only when discarding new messages would publishes fail once the stream limits are reached.
+	if mset.cfg.Discard == DiscardNew {
+		var state StreamState
+		mset.store.FastState(&state)
+		rmsgs, rbytes := mset.reservedCapacity()
+		if msgs > 0 && mset.cfg.MaxMsgs > 0 && state.Msgs+rmsgs+msgs > uint64(mset.cfg.MaxMsgs) {
+			return NewJSStreamReservationExceedsLimitsError()
 		}
-		for _, s := range mset.cfg.Sources {
-			if s.Consumer == nil {
-				id := mset.createSourcingConsumerHash(s, mset.cfg.Sources)
-				mset.tryDeleteSourceConsumer(id, s)
-			}
+		if bytes > 0 && mset.cfg.MaxBytes > 0 && state.Bytes+rbytes+bytes > uint64(mset.cfg.MaxBytes) {
+			return NewJSStreamReservationExceedsLimitsError()
 		}
 	}
+	clustered := mset.isClustered()
+	if bytes > 0 && !mset.jsa.reserveStorage(mset.tier, mset.stype, mset.cfg.Replicas, int64(bytes), clustered) {
+		return NewJSStreamReservationExceedsLimitsError()
+	}
 
-	// Signal to the monitor loop.
-	// Can't use qch here.
-	if mset.mqch != nil {
-		close(mset.mqch)
-		mset.mqch = nil
+	r := &capacityReservation{id: id, msgs: msgs, bytes: bytes, byMsgs: msgs > 0, byBytes: bytes > 0, rf: mset.cfg.Replicas, expires: expires}
+	if mset.reservations == nil {
+		mset.reservations = make(map[string]*capacityReservation)
 	}
+	mset.reservations[r.id] = r
+	if !clustered || mset.node.Leader() {
+		mset.startReservationTimer(r)
+	}
+	return nil
+}
 
-	// Stop responding to sync requests.
-	mset.stopClusterSubs()
-	// Unsubscribe from direct stream.
-	mset.unsubscribeToStream(true, isShuttingDown)
+// setReservations replaces the reservations with the ones held by a stream snapshot being applied.
+func (mset *stream) setReservations(rsvs []*streamReservation) {
+	mset.mu.Lock()
+	defer mset.mu.Unlock()
 
-	// Our info sub if we spun it up.
-	if mset.infoSub != nil {
-		mset.srv.sysUnsubscribe(mset.infoSub)
-		mset.infoSub = nil
+	if len(mset.reservations) == 0 && len(rsvs) == 0 {
+		return
 	}
-
-	// Clean up consumers.
-	var obs []*consumer
-	for _, o := range mset.consumers {
-		obs = append(obs, o)
+	for _, r := range mset.reservations {
+		mset.releaseReservation(r)
 	}
-	// Preserve the consumers if it's marked offline, to have them remain queryable.
-	if deleteFlag || offlineReason == _EMPTY_ {
-		mset.clsMu.Lock()
+	isLeader := mset.isLeader()
+	for _, sr := range rsvs {
+		r := &capacityReservation{id: sr.ID, msgs: sr.Msgs, bytes: sr.Bytes, byMsgs: sr.Msgs > 0, byBytes: sr.Bytes > 0, rf: mset.cfg.Replicas, expires: sr.Expires}
+		if r.bytes > 0 {
+			mset.jsa.reserveStorage(mset.tier, mset.stype, r.rf, int64(r.bytes), true)
+		}
+		if mset.reservations == nil {
+			mset.reservations = make(map[string]*capacityReservation)
+		}
+		mset.reservations[r.id] = r
+		if isLeader {
+			mset.startReservationTimer(r)
+		}
+	}
+}
+
+// startReservationTimer expires the reservation once its time is up. When clustered, only the leader
+// runs the timer and proposes the release, so that all replicas release it at the same point in the log.
+// Lock should be held.
+func (mset *stream) startReservationTimer(r *capacityReservation) {
+	if r.tmr != nil {
+		return
+	}
+	r.tmr = time.AfterFunc(time.Until(r.expires), func() {
+		mset.mu.Lock()
+		defer mset.mu.Unlock()
+		if mset.reservations[r.id] != r {
+			return
+		}
+		if mset.isClustered() {
+			mset.node.Propose(encodeStreamReservation(releaseReservationOp, &streamReservation{Stream: mset.cfg.Name, ID: r.id}))
+			return
+		}
+		mset.releaseReservation(r)
+	})
+}
+
+// setReservationTimers starts the expiry timers of all reservations when becoming
+// leader, and stops them when no longer leader.
+// Lock should be held.
+func (mset *stream) setReservationTimers(isLeader bool) {
+	for _, r := range mset.reservations {
+		if isLeader {
+			mset.startReservationTimer(r)
+		} else if r.tmr != nil {
+			r.tmr.Stop()
+			r.tmr = nil
+		}
+	}
+}
+
+// checkReservedCapacity checks if a message of size sz, not using a reservation,
+// would use capacity reserved for others.
+// Lock should be held.
+func (mset *stream) checkReservedCapacity(sz uint64) error {
+	if mset.cfg.Discard != DiscardNew {
+		return nil
+	}
+	var state StreamState
+	mset.store.FastState(&state)
+	rmsgs, rbytes := mset.reservedCapacity()
+	if rmsgs > 0 && mset.cfg.MaxMsgs > 0 && state.Msgs+rmsgs >= uint64(mset.cfg.MaxMsgs) {
+		return ErrMaxMsgs
+	}
+	if rbytes > 0 && mset.cfg.MaxBytes > 0 && state.Bytes+rbytes+sz > uint64(mset.cfg.MaxBytes) {
+		return ErrMaxBytes
+	}
+	return nil
+}
+
+// hasBytesReservation returns whether the message carries the id of a reservation holding bytes,
+// which were already checked against the account limits.
+// Lock should be held.
+func (mset *stream) hasBytesReservation(hdr []byte) bool {
+	if len(mset.reservations) == 0 {
+		return false
+	}
+	r := mset.reservations[string(sliceHeader(JSReservationId, hdr))]
+	return r != nil && r.byBytes
+}
+
+// useReservation draws a stored message of size sz from the reservation.
+// Lock should be held.
+func (mset *stream) useReservation(r *capacityReservation, sz uint64) {
+	if r.byMsgs {
+		r.msgs--
+	}
+	if r.byBytes {
+		r.bytes -= sz
+		// The stored message now counts towards the account usage instead.
+		mset.jsa.reserveStorage(mset.tier, mset.stype, r.rf, -int64(sz), true)
+	}
+	if (r.byMsgs && r.msgs == 0) || (r.byBytes && r.bytes == 0) {
+		mset.releaseReservation(r)
+	}
+}
+
+// releaseReservationID releases whatever capacity is left in the reservation with the given id, if any.
+func (mset *stream) releaseReservationID(id string) {
+	mset.mu.Lock()
+	defer mset.mu.Unlock()
+	if r := mset.reservations[id]; r != nil {
+		mset.releaseReservation(r)
+	}
+}
+
+// releaseReservation releases whatever capacity is left in the reservation.
+// Lock should be held.
+func (mset *stream) releaseReservation(r *capacityReservation) {
+	if r.tmr != nil {
+		r.tmr.Stop()
+	}
+	delete(mset.reservations, r.id)
+	if r.bytes > 0 {
+		mset.jsa.reserveStorage(mset.tier, mset.stype, r.rf, -int64(r.bytes), true)
+	}
+	r.msgs, r.bytes = 0, 0
+}
+
+// migrateStorage moves all messages and consumer states of the stream into a new store of storage type st.
+// Messages are copied in the background while the stream keeps going, and the stores are swapped
+// once the copy has caught up. The stream's raft log is moved over after that.
+// Lock should not be held.
+func (mset *stream) migrateStorage(st StorageType) error {
+	// The account lock can't be taken under the stream lock, so grab the store directory first.
+	jsa := mset.jsa
+	jsa.mu.RLock()
+	jsaDir := jsa.storeDir
+	jsa.mu.RUnlock()
+
+	mset.mu.Lock()
+	if mset.migrating {
+		// Already on its way to the other storage type.
+		sameType := st != mset.stype
+		mset.mu.Unlock()
+		if sameType {
+			return nil
+		}
+		return NewJSStreamStorageMigrationInProgressError()
+	}
+	s := mset.srv
+	ncfg := mset.cfg
+	ncfg.Storage = st
+	fsCfg := &FileStoreConfig{}
+	if st == FileStorage {
+		mset.autoTuneFileStorageBlockSize(fsCfg)
+		storeDir := filepath.Join(jsaDir, streamsDir, ncfg.Name)
+		s.configureStreamFileStore(fsCfg, storeDir, &ncfg)
+	}
+	nst, err := mset.newStore(&ncfg, fsCfg)
+	if err != nil {
+		mset.mu.Unlock()
+		return NewJSStreamStoreFailedError(err)
+	}
+	mset.migrating = true
+	mset.mu.Unlock()
+
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+		if err := mset.copyToStore(nst); err != nil {
+			s.Warnf("Stream '%s > %s' could not be migrated to %s storage: %v", mset.accName(), ncfg.Name, st, err)
+			nst.Delete(true)
+			mset.mu.Lock()
+			mset.migrating = false
+			mset.mu.Unlock()
+			return
+		}
+		mset.migrateRaftLog(st)
+	})
+	return nil
+}
+
+// Number of messages left to copy at which a storage migration swaps the stores
+// under the stream's lock, instead of doing another pass without it.
+const migrateStorageMaxFinal = 1024
+
+// copyToStore copies all messages of the stream into the empty store nst, and swaps it
+// in for the current one. Only the final catch up is done under the stream's lock.
+// Lock should not be held.
+func (mset *stream) copyToStore(nst StreamStore) error {
+	mset.mu.RLock()
+	ost := mset.store
+	mset.mu.RUnlock()
+
+	var state StreamState
+	ost.FastState(&state)
+	// An empty store that never held messages reports a first sequence of 0.
+	var copied uint64
+	if state.FirstSeq > 1 {
+		if _, err := nst.Compact(state.FirstSeq); err != nil {
+			return err
+		}
+		copied = state.FirstSeq - 1
+	}
+	for pass := 0; ; pass++ {
+		if mset.closed.Load() {
+			return errStreamClosed
+		}
+		if state.LastSeq < copied {
+			return errors.New("stream was truncated while copying")
+		}
+		if state.LastSeq-copied <= migrateStorageMaxFinal || pass >= 3 {
+			break
+		}
+		if err := copyStoreMsgRange(ost, nst, copied+1, state.LastSeq); err != nil {
+			return err
+		}
+		copied = state.LastSeq
+		ost.FastState(&state)
+	}
+
+	mset.mu.Lock()
+	if mset.closed.Load() || mset.store != ost {
+		mset.mu.Unlock()
+		return errStreamClosed
+	}
+	// Catch up with what happened while copying.
+	if err := mset.finishCopyToStore(ost, nst, copied); err != nil {
+		mset.mu.Unlock()
+		return err
+	}
+	ocfg := mset.cfg
+	ncfg := ocfg
+	ncfg.Storage = nst.Type()
+	if err := nst.UpdateConfig(&ncfg); err != nil {
+		mset.mu.Unlock()
+		return err
+	}
+	ost.RegisterStorageUpdates(nil)
+	ost.RegisterStorageRemoveMsg(nil)
+	ost.RegisterProcessJetStreamMsg(nil)
+
+	// Move our usage over to the new storage type.
+	jsa, js := mset.jsa, mset.js
+	_, reported, _ := ost.Utilization()
+	jsa.updateUsage(mset.tier, mset.stype, -int64(reported))
+	mset.stype, mset.store = ncfg.Storage, nst
+	// A file store will report the bytes it already holds once registered.
+	mset.registerStoreCallbacks()
+	if ncfg.Storage == MemoryStorage {
+		_, reported, _ = nst.Utilization()
+		jsa.updateUsage(mset.tier, ncfg.Storage, int64(reported))
+	}
+	mset.mu.Unlock()
+
+	// Move the reservation over to the new storage type.
+	if js != nil {
+		js.releaseStreamResources(&ocfg)
+		js.reserveStreamResources(&ncfg)
+	}
+
+	// Now move the consumer states over.
+	for _, o := range mset.getConsumers() {
+		o.mu.Lock()
+		if ocs := o.store; ocs != nil {
+			if ncs, err := nst.ConsumerStore(o.name, o.created, &o.cfg); err != nil {
+				mset.srv.Warnf("Error moving consumer '%s > %s > %s' to new storage: %v", mset.accName(), ncfg.Name, o.name, err)
+			} else {
+				if state, err := ocs.State(); err == nil {
+					ncs.Update(state)
+				}
+				ocs.Stop()
+				o.store = ncs
+			}
+		}
+		o.mu.Unlock()
+	}
+	err := ost.Delete(false)
+
+	// Only report the new storage type once the old store is gone, since migrating back
+	// to a file store would otherwise share its directory with the one being deleted.
+	mset.mu.Lock()
+	mset.cfgMu.Lock()
+	mset.cfg.Storage = ncfg.Storage
+	mset.cfgMu.Unlock()
+	mset.migrating = false
+	mset.mu.Unlock()
+	return err
+}
+
+// finishCopyToStore brings nst, holding a copy of ost up to sequence copied, up to date with ost.
+// Lock should be held.
+func (mset *stream) finishCopyToStore(ost, nst StreamStore, copied uint64) error {
+	var state, nstate StreamState
+	ost.FastState(&state)
+	if state.LastSeq < copied {
+		return errors.New("stream was truncated while copying")
+	}
+	nst.FastState(&nstate)
+	if state.FirstSeq > nstate.FirstSeq {
+		if _, err := nst.Compact(state.FirstSeq); err != nil {
+			return err
+		}
+		nst.FastState(&nstate)
+	}
+	// Remove what was deleted after being copied.
+	type seqRange struct{ first, last uint64 }
+	var removed []seqRange
+	ost.DeletedRanges(state.FirstSeq, func(first, last uint64) bool {
+		if first > copied {
+			return false
+		}
+		removed = append(removed, seqRange{first, min(last, copied)})
+		return true
+	})
+	var smv StoreMsg
+	for _, r := range removed {
+		for seq := r.first; seq <= r.last; {
+			sm, _, err := nst.LoadNextMsg(fwcs, true, seq, &smv)
+			if err != nil || sm.seq > r.last {
+				break
+			}
+			if _, err = nst.RemoveMsg(sm.seq); err != nil {
+				return err
+			}
+			seq = sm.seq + 1
+		}
+	}
+	return copyStoreMsgRange(ost, nst, max(copied, nstate.LastSeq)+1, state.LastSeq)
+}
+
+// migrateRaftLog moves the stream's raft log to storage type st, and retries for a while
+// if the log is busy with a snapshot or catchups.
+// Lock should not be held.
+func (mset *stream) migrateRaftLog(st StorageType) {
+	const (
+		migrateRaftLogInterval = time.Second
+		migrateRaftLogAttempts = 30
+	)
+	s := mset.srv
+	for i := 0; i < migrateRaftLogAttempts; i++ {
+		node := mset.raftNode()
+		if node == nil || mset.closed.Load() {
+			return
+		}
+		err := node.MigrateLog(st)
+		if err == nil {
+			return
+		}
+		if err != errSnapInProgress && err != errCatchupsRunning && err != errCompactInterrupted {
+			s.Warnf("Stream '%s > %s' could not migrate its raft log to %s storage: %v", mset.accName(), mset.name(), st, err)
+			return
+		}
+		select {
+		case <-s.quitCh:
+			return
+		case <-time.After(migrateRaftLogInterval):
+		}
+	}
+	s.Warnf("Stream '%s > %s' gave up migrating its raft log to %s storage", mset.accName(), mset.name(), st)
+}
+
+// renameStore stops the stream and rewrites its store for the renamed stream ncfg.
+// The stream and its returned consumers need to be created again afterwards.
+func (mset *stream) renameStore(ncfg *StreamConfig) ([]*FileConsumerInfo, error) {
+	mset.mu.RLock()
+	js, ocfg, created := mset.js, mset.cfg, mset.created
+	fs, ok := mset.store.(*fileStore)
+	mset.mu.RUnlock()
+	if !ok {
+		return nil, errors.New("stream rename requires file storage")
+	}
+	fs.mu.RLock()
+	fcfg := fs.fcfg
+	fs.mu.RUnlock()
+
+	var consumers []*FileConsumerInfo
+	for _, o := range mset.getPublicConsumers() {
+		o.mu.RLock()
+		consumers = append(consumers, &FileConsumerInfo{Created: o.created, Name: o.name, ConsumerConfig: o.cfg})
+		o.mu.RUnlock()
+	}
+
+	if err := mset.stop(false, false); err != nil {
+		return nil, err
+	}
+	// Resources are reserved again when the stream is recreated.
+	js.releaseStreamResources(&ocfg)
+
+	err := renameFileStore(fcfg, FileStreamInfo{Created: created, StreamConfig: ocfg}, FileStreamInfo{Created: created, StreamConfig: *ncfg}, consumers)
+	return consumers, err
+}
+
+// rename will rename a non-clustered stream to the config ncfg, moving its messages
+// and consumers over. If the store could not be renamed the stream is recovered
+// under its old name.
+func (mset *stream) rename(ncfg *StreamConfig) (*stream, error) {
+	mset.mu.RLock()
+	acc, ocfg, created := mset.acc, mset.cfg, mset.created
+	mset.mu.RUnlock()
+
+	cfg := ncfg
+	consumers, rerr := mset.renameStore(ncfg)
+	if rerr != nil {
+		if !mset.closed.Load() {
+			return nil, rerr
+		}
+		cfg = &ocfg
+	}
+	nmset, err := acc.recoverStream(cfg)
+	if err != nil {
+		return nil, err
+	}
+	nmset.setCreatedTime(created)
+	nmset.recreateConsumers(consumers, "stream rename")
+	return nmset, rerr
+}
+
+// recreateConsumers adds the consumers again after the stream was recreated by op.
+func (mset *stream) recreateConsumers(consumers []*FileConsumerInfo, op string) {
+	for _, cfg := range consumers {
+		isEphemeral := !isDurableConsumer(&cfg.ConsumerConfig)
+		if isEphemeral {
+			// Recreate as a durable and switch, same as when recovering from disk.
+			cfg.ConsumerConfig.Durable = cfg.Name
+		}
+		o, err := mset.addConsumerWithAssignment(&cfg.ConsumerConfig, _EMPTY_, nil, true, ActionCreateOrUpdate, false, false)
+		if err != nil {
+			mset.srv.Warnf("Error adding consumer '%s > %s > %s' after %s: %v", mset.accName(), mset.name(), cfg.Name, op, err)
+			continue
+		}
+		if isEphemeral {
+			o.switchToEphemeral()
+		}
+		o.setCreatedTime(cfg.Created)
+	}
+}
+
+// relocateStore stops the stream and copies its store into the account's store directory adir.
+// The stream and its returned consumers need to be created again afterwards.
+func (mset *stream) relocateStore(adir string) ([]*FileConsumerInfo, error) {
+	mset.mu.RLock()
+	js, cfg := mset.js, mset.cfg
+	fs, ok := mset.store.(*fileStore)
+	mset.mu.RUnlock()
+	if !ok {
+		return nil, errors.New("stream relocation requires file storage")
+	}
+	fs.mu.RLock()
+	odir := fs.fcfg.StoreDir
+	fs.mu.RUnlock()
+	ndir := filepath.Join(adir, streamsDir, cfg.Name)
+	if _, err := os.Stat(ndir); err == nil {
+		return nil, fmt.Errorf("store directory for stream %q already exists", cfg.Name)
+	}
+
+	var consumers []*FileConsumerInfo
+	for _, o := range mset.getPublicConsumers() {
+		o.mu.RLock()
+		consumers = append(consumers, &FileConsumerInfo{Created: o.created, Name: o.name, ConsumerConfig: o.cfg})
+		o.mu.RUnlock()
+	}
+
+	if err := mset.stop(false, false); err != nil {
+		return nil, err
+	}
+	// Resources are reserved again when the stream is recreated.
+	js.releaseStreamResources(&cfg)
+
+	if err := copyStoreDir(odir, ndir, nil); err != nil {
+		os.RemoveAll(ndir)
+		return consumers, err
+	}
+	return consumers, nil
+}
+
+// relocate will move a non-clustered file based stream into the account's current store
+// directory, copying its files over. If the files could not be copied the account's previous
+// store directory oadir is restored, and the stream is recovered from there.
+func (mset *stream) relocate(oadir string) (*stream, error) {
+	mset.mu.RLock()
+	acc, jsa, cfg, created := mset.acc, mset.jsa, mset.cfg, mset.created
+	mset.mu.RUnlock()
+
+	jsa.mu.RLock()
+	adir := jsa.storeDir
+	jsa.mu.RUnlock()
+
+	consumers, rerr := mset.relocateStore(adir)
+	if rerr != nil {
+		if !mset.closed.Load() {
+			return nil, rerr
+		}
+		jsa.mu.Lock()
+		jsa.storeDir = oadir
+		jsa.mu.Unlock()
+	}
+	nmset, err := acc.recoverStream(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	nmset.setCreatedTime(created)
+	nmset.recreateConsumers(consumers, "stream relocation")
+	return nmset, rerr
+}
+
+// Internal function to delete a stream.
+func (mset *stream) delete() error {
+	if mset == nil {
+		return nil
+	}
+	return mset.stop(true, true)
+}
+
+// Internal function to stop or delete the stream.
+func (mset *stream) stop(deleteFlag, advisory bool) error {
+	mset.mu.RLock()
+	js, jsa, name, offlineReason := mset.js, mset.jsa, mset.cfg.Name, mset.offlineReason
+	mset.mu.RUnlock()
+
+	if jsa == nil {
+		return NewJSNotEnabledForAccountError()
+	}
+
+	isShuttingDown := js.isShuttingDown()
+
+	// Remove from our account map first.
+	jsa.mu.Lock()
+	// Preserve in the account if it's marked offline, to have it remain queryable.
+	removed := deleteFlag || offlineReason == _EMPTY_
+	if removed {
+		delete(jsa.streams, name)
+	}
+	acc := jsa.account
+	accName := acc.Name
+	jsa.mu.Unlock()
+
+	// Another overlapping stream acknowledges publishes in its place now.
+	// When clustered this is done when processing the stream removal.
+	if removed && !js.isClustered() {
+		acc.removeOverlapStream(name)
+	}
+
+	// Stop checking publishes against the allowed publishers of a deleted stream.
+	// When clustered this is done when processing the stream removal.
+	if deleteFlag && !js.isClustered() {
+		acc.removeStreamPublishers(name)
+	}
+
+	// Kick monitor and collect consumers first.
+	mset.mu.Lock()
+
+	// Mark closed.
+	mset.closed.Store(true)
+
+	// Release any reserved capacity.
+	for _, r := range mset.reservations {
+		mset.releaseReservation(r)
+	}
+	if mset.scc != nil {
+		mset.scc.timer.Stop()
+		mset.scc = nil
+	}
+
+	// Both flags set mean a delete where we are the stream leader.
+	// Try to clean up any consumers used for sourcing (if one wasn't provided to us).
+	if deleteFlag && advisory {
+		if mset.cfg.Mirror != nil && mset.cfg.Mirror.Consumer == nil {
+			mset.tryDeleteMirrorConsumer(mset.cfg.Mirror)
+		}
+		for _, s := range mset.cfg.Sources {
+			if s.Consumer == nil {
+				id := mset.createSourcingConsumerHash(s, mset.cfg.Sources)
+				mset.tryDeleteSourceConsumer(id, s)
+			}
+		}
+	}
+
+	// Signal to the monitor loop.
+	// Can't use qch here.
+	if mset.mqch != nil {
+		close(mset.mqch)
+		mset.mqch = nil
+	}
+
+	// Stop responding to sync requests.
+	mset.stopClusterSubs()
+	// Unsubscribe from direct stream.
+	mset.unsubscribeToStream(true, isShuttingDown)
+
+	// Our info sub if we spun it up.
+	if mset.infoSub != nil {
+		mset.srv.sysUnsubscribe(mset.infoSub)
+		mset.infoSub = nil
+	}
+
+	// Clean up consumers.
+	var obs []*consumer
+	for _, o := range mset.consumers {
+		obs = append(obs, o)
+	}
+	// Preserve the consumers if it's marked offline, to have them remain queryable.
+	if deleteFlag || offlineReason == _EMPTY_ {
+		mset.clsMu.Lock()
 		mset.consumers, mset.cList, mset.csl = nil, nil, nil
 		mset.clsMu.Unlock()
 	}
@@ -8353,6 +10349,15 @@ func (mset *stream) stop(deleteFlag, advisory bool) error {
 		}
 	}
 
+	// Stop any timers releasing interest retained for deleted consumers.
+	mset.stopRetainedInterest()
+
+	// Stop checking for messages about to expire.
+	if mset.eatmr != nil {
+		mset.eatmr.Stop()
+		mset.eatmr = nil
+	}
+
 	// Cleanup duplicate timer if running.
 	mset.ddMu.Lock()
 	if mset.ddtmr != nil {
@@ -8380,6 +10385,9 @@ func (mset *stream) stop(deleteFlag, advisory bool) error {
 	// Snapshot store.
 	store := mset.store
 	c := mset.client
+	if store != nil {
+		jsa.untrackStore(name, store)
+	}
 
 	// Clustered cleanup.
 	mset.mu.Unlock()
@@ -8472,6 +10480,259 @@ func (mset *stream) getPublicConsumers() []*consumer {
 	return obs
 }
 
+// deliveredSeqs returns the last delivered stream sequence of all public consumers
+// hosted on this server, keyed by consumer name.
+func (mset *stream) deliveredSeqs() map[string]uint64 {
+	obs := mset.getPublicConsumers()
+	delivered := make(map[string]uint64, len(obs))
+	for _, o := range obs {
+		o.mu.RLock()
+		name, sseq := o.name, o.sseq
+		o.mu.RUnlock()
+		if sseq > 0 {
+			sseq--
+		}
+		delivered[name] = sseq
+	}
+	return delivered
+}
+
+// consumerLag returns the max and total lag of the consumers, given their last delivered stream
+// sequence keyed by name, where lag is how far that is behind the stream's last sequence.
+func (mset *stream) consumerLag(deliveredSeqs map[string]uint64) (count int, maxLag, totalLag uint64, maxConsumer string) {
+	var state StreamState
+	mset.store.FastState(&state)
+
+	names := slices.Sorted(maps.Keys(deliveredSeqs))
+	for _, name := range names {
+		delivered := deliveredSeqs[name]
+		var lag uint64
+		if state.LastSeq > delivered {
+			lag = state.LastSeq - delivered
+		}
+		count++
+		totalLag += lag
+		if lag > maxLag || maxConsumer == _EMPTY_ {
+			maxLag, maxConsumer = lag, name
+		}
+	}
+	return count, maxLag, totalLag, maxConsumer
+}
+
+// peekMsgs returns the metadata of up to limit messages matching the filter, starting at start.
+// If more messages match, the sequence to continue from is returned as well.
+func (mset *stream) peekMsgs(filter string, start uint64, limit int) ([]*StreamPeekMsg, uint64) {
+	if filter == _EMPTY_ {
+		filter = fwcs
+	}
+	wc := subjectHasWildcard(filter)
+
+	// Ensure this read request is isolated and doesn't interleave with writes.
+	mset.mu.RLock()
+	defer mset.mu.RUnlock()
+
+	var smv StoreMsg
+	msgs := make([]*StreamPeekMsg, 0)
+	for seq := start; ; {
+		sm, _, err := mset.store.LoadNextMsg(filter, wc, seq, &smv)
+		if err != nil {
+			return msgs, 0
+		}
+		if len(msgs) == limit {
+			return msgs, sm.seq
+		}
+		msgs = append(msgs, &StreamPeekMsg{
+			Sequence: sm.seq,
+			Subject:  sm.subj,
+			Size:     len(sm.hdr) + len(sm.msg),
+			Time:     time.Unix(0, sm.ts).UTC(),
+		})
+		seq = sm.seq + 1
+	}
+}
+
+// newStreamChecksumHash returns the hash for a stream checksum algorithm, nil if unknown.
+func newStreamChecksumHash(alg string) hash.Hash {
+	switch alg {
+	case StreamChecksumSHA256:
+		return sha256.New()
+	case StreamChecksumHighwayHash:
+		// Use a fixed key, so checksums can be compared across servers.
+		var key [highwayhash.Size]byte
+		hh, _ := highwayhash.New64(key[:])
+		return hh
+	}
+	return nil
+}
+
+// checksum writes the sequence, subject, headers and data of the messages from start up to
+// and including end into the hash, and returns the range covered and the number of messages.
+// Fields are length prefixed, so bytes shifting between them changes the checksum.
+func (mset *stream) checksum(start, end uint64, hh hash.Hash) (first, last, msgs uint64) {
+	mset.mu.RLock()
+	store := mset.store
+	mset.mu.RUnlock()
+
+	var state StreamState
+	store.FastState(&state)
+	first, last = max(start, state.FirstSeq), state.LastSeq
+	if end > 0 {
+		last = min(end, last)
+	}
+
+	var smv StoreMsg
+	var b [8]byte
+	for seq := first; seq <= last; {
+		sm, _, err := store.LoadNextMsg(fwcs, true, seq, &smv)
+		if err != nil || sm.seq > last {
+			break
+		}
+		binary.BigEndian.PutUint64(b[:], sm.seq)
+		hh.Write(b[:])
+		for _, field := range [][]byte{stringToBytes(sm.subj), sm.hdr, sm.msg} {
+			binary.BigEndian.PutUint64(b[:], uint64(len(field)))
+			hh.Write(b[:])
+			hh.Write(field)
+		}
+		msgs++
+		seq = sm.seq + 1
+	}
+	return first, last, msgs
+}
+
+// gaps returns up to limit ranges of sequences between start and end that are no longer in the
+// stream, based on the store's delete tracking. If more gaps follow, the sequence to continue
+// from is returned as well.
+func (mset *stream) gaps(start, end uint64, limit int) ([]*StreamGap, uint64) {
+	mset.mu.RLock()
+	store := mset.store
+	mset.mu.RUnlock()
+
+	var state StreamState
+	store.FastState(&state)
+	start = max(start, 1)
+	if end == 0 || end > state.LastSeq {
+		end = state.LastSeq
+	}
+
+	gaps := make([]*StreamGap, 0)
+	var next uint64
+	// Walk the removed sequences from the cursor onward, merging adjacent ones into a range.
+	// Stops once past the end, or when the limit is hit.
+	store.DeletedRanges(start, func(first, last uint64) bool {
+		if first > end {
+			return false
+		}
+		first, last = max(first, start), min(last, end)
+		if first > last {
+			return true
+		}
+		if n := len(gaps); n > 0 && first <= gaps[n-1].Last+1 {
+			gaps[n-1].Last = max(gaps[n-1].Last, last)
+			return true
+		}
+		if len(gaps) == limit {
+			next = first
+			return false
+		}
+		gaps = append(gaps, &StreamGap{First: first, Last: last})
+		return true
+	})
+	return gaps, next
+}
+
+// subjectCounts returns the number of messages for each subject matching filter, based on
+// the store's per-subject state. With a depth set, subjects are aggregated by their prefix
+// of up to depth tokens. The total number of matching messages is returned as well.
+func (mset *stream) subjectCounts(filter string, depth int) (map[string]uint64, uint64) {
+	st := mset.store.SubjectsTotals(filter)
+	var total uint64
+	for _, n := range st {
+		total += n
+	}
+	if depth == 0 {
+		return st, total
+	}
+	counts := make(map[string]uint64)
+	for subj, n := range st {
+		// Find the end of the prefix, the whole subject if it has less tokens.
+		end, tokens := len(subj), 0
+		for i := 0; i < len(subj); i++ {
+			if subj[i] == btsep {
+				if tokens++; tokens == depth {
+					end = i
+					break
+				}
+			}
+		}
+		counts[subj[:end]] += n
+	}
+	return counts, total
+}
+
+// subjectCountsCacheTTL is how long subject counts are kept for paging through them.
+const subjectCountsCacheTTL = 30 * time.Second
+
+// subjectCountsCache holds the subject counts for a filter and depth in subject order, so
+// paging through them doesn't need to collect and sort them again for every page. These
+// are valid as long as the stream's first and last sequence and number of messages are.
+type subjectCountsCache struct {
+	filter string
+	depth  int
+	first  uint64
+	last   uint64
+	msgs   uint64
+	subjs  []string
+	counts map[string]uint64
+	total  uint64
+	timer  *time.Timer
+}
+
+// sortedSubjectCounts returns the subject counts like subjectCounts, along with the subjects in
+// order. The counts that were already collected can be passed in, otherwise these are reused
+// from an earlier call if nothing changed since.
+func (mset *stream) sortedSubjectCounts(filter string, depth int, counts map[string]uint64, total uint64) ([]string, map[string]uint64, uint64) {
+	var state StreamState
+	mset.store.FastState(&state)
+
+	mset.mu.Lock()
+	if c := mset.scc; counts == nil && c != nil && c.filter == filter && c.depth == depth &&
+		c.first == state.FirstSeq && c.last == state.LastSeq && c.msgs == state.Msgs {
+		c.timer.Reset(subjectCountsCacheTTL)
+		mset.mu.Unlock()
+		return c.subjs, c.counts, c.total
+	}
+	mset.mu.Unlock()
+
+	if counts == nil {
+		counts, total = mset.subjectCounts(filter, depth)
+	}
+	c := &subjectCountsCache{
+		filter: filter,
+		depth:  depth,
+		first:  state.FirstSeq,
+		last:   state.LastSeq,
+		msgs:   state.Msgs,
+		subjs:  slices.Sorted(maps.Keys(counts)),
+		counts: counts,
+		total:  total,
+	}
+	c.timer = time.AfterFunc(subjectCountsCacheTTL, func() {
+		mset.mu.Lock()
+		if mset.scc == c {
+			mset.scc = nil
+		}
+		mset.mu.Unlock()
+	})
+	mset.mu.Lock()
+	if mset.scc != nil {
+		mset.scc.timer.Stop()
+	}
+	mset.scc = c
+	mset.mu.Unlock()
+	return c.subjs, c.counts, c.total
+}
+
 // This returns all consumers that are DIRECT.
 func (mset *stream) getDirectConsumers() []*consumer {
 	mset.clsMu.RLock()
@@ -8523,6 +10784,12 @@ func (mset *stream) checkInterestState() {
 		o.mu.RUnlock()
 		asflr = min(asflr, chkflr)
 	}
+	// Don't remove messages that are retained for deleted consumers.
+	mset.mu.RLock()
+	for _, ri := range mset.retained {
+		asflr = min(asflr, ri.floor+1)
+	}
+	mset.mu.RUnlock()
 
 	mset.cfgMu.RLock()
 	rp := mset.cfg.Retention
@@ -8787,10 +11054,96 @@ func (mset *stream) checkForInterestWithSubject(seq uint64, subj string, obs *co
 			return true
 		}
 	}
+	// Deleted consumers might still retain interest in messages they did not acknowledge.
+	if mset.hasRetainedInterest(seq, subj) {
+		return true
+	}
 	mset.clearAllPreAcks(seq)
 	return false
 }
 
+// retainedInterest is the interest of a deleted consumer in messages it did not acknowledge,
+// which is held on to until its retention period expires.
+type retainedInterest struct {
+	floor   uint64      // The ack floor of the consumer, messages above it are retained.
+	last    uint64      // The last sequence of the stream when the consumer was deleted.
+	filters []string    // The subject filters of the consumer, if empty all subjects are retained.
+	timer   *time.Timer // Releases the interest once the retention period expires.
+}
+
+// retainInterest will retain messages above floor up to our last sequence that match
+// the filters of the deleted consumer, for the duration of the retention period.
+func (mset *stream) retainInterest(name string, floor uint64, filters []string, retention time.Duration) {
+	mset.mu.Lock()
+	defer mset.mu.Unlock()
+	if mset.closed.Load() {
+		return
+	}
+	if ri := mset.retained[name]; ri != nil {
+		ri.timer.Stop()
+	}
+	if mset.retained == nil {
+		mset.retained = make(map[string]*retainedInterest)
+	}
+	ri := &retainedInterest{floor: floor, last: mset.lseq, filters: filters}
+	ri.timer = time.AfterFunc(retention, func() { mset.releaseInterest(name, ri) })
+	mset.retained[name] = ri
+}
+
+// releaseInterest will release the retained interest of a deleted consumer,
+// and remove the messages that no longer have interest.
+func (mset *stream) releaseInterest(name string, ri *retainedInterest) {
+	mset.mu.Lock()
+	if mset.retained[name] != ri {
+		mset.mu.Unlock()
+		return
+	}
+	delete(mset.retained, name)
+	if len(mset.retained) == 0 {
+		mset.retained = nil
+	}
+	closed := mset.closed.Load()
+	mset.mu.Unlock()
+
+	if !closed {
+		mset.cleanupNoInterestMessages(ri.floor, nil)
+	}
+}
+
+// hasRetainedInterest returns whether a deleted consumer retains interest in this sequence.
+// Lock should be held.
+func (mset *stream) hasRetainedInterest(seq uint64, subj string) bool {
+	for _, ri := range mset.retained {
+		if seq <= ri.floor || seq > ri.last {
+			continue
+		}
+		if len(ri.filters) == 0 {
+			return true
+		}
+		if subj == _EMPTY_ {
+			var err error
+			if subj, err = mset.store.SubjectForSeq(seq); err != nil {
+				return false
+			}
+		}
+		for _, filter := range ri.filters {
+			if subjectIsSubsetMatch(subj, filter) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stopRetainedInterest will stop the timers of all retained interest.
+// Lock should be held.
+func (mset *stream) stopRetainedInterest() {
+	for _, ri := range mset.retained {
+		ri.timer.Stop()
+	}
+	mset.retained = nil
+}
+
 // Check if we have a pre-registered ack for this sequence.
 // Write lock should be held.
 func (mset *stream) hasPreAck(o *consumer, seq uint64) bool {
@@ -8976,10 +11329,107 @@ func (mset *stream) snapshot(deadline time.Duration, checkMsgs, includeConsumers
 	return store.Snapshot(deadline, checkMsgs, includeConsumers)
 }
 
+// snapshotStreams takes snapshots of the given streams at a consistent point, returned in the same order.
+// The Raft groups of the streams are quiesced, or the streams locked if they have none, until all the
+// snapshots are staged to disk, so none of the streams makes progress in between.
+func snapshotStreams(msets []*stream, includeConsumers bool) (_ []*SnapshotResult, err error) {
+	if len(msets) == 0 {
+		return nil, nil
+	}
+	// Resolve the staging directory up front, the account lock can't be taken while the streams are locked.
+	jsa := msets[0].jsa
+	jsa.mu.RLock()
+	sdir := filepath.Join(jsa.storeDir, snapsDir)
+	jsa.mu.RUnlock()
+	if err := os.MkdirAll(sdir, defaultDirPerms); err != nil {
+		return nil, err
+	}
+
+	// Pin the streams in a stable order, in case of concurrent requests for overlapping streams.
+	pinned := slices.Clone(msets)
+	slices.SortFunc(pinned, func(a, b *stream) int { return strings.Compare(a.name(), b.name()) })
+	for _, mset := range pinned {
+		mset.mu.RLock()
+		node := mset.node
+		mset.mu.RUnlock()
+		if node != nil {
+			resume, _ := node.Quiesce()
+			defer resume()
+		} else {
+			mset.mu.Lock()
+			defer mset.mu.Unlock()
+		}
+	}
+
+	srs := make([]*SnapshotResult, 0, len(msets))
+	defer func() {
+		if err != nil {
+			for _, sr := range srs {
+				sr.Reader.Close()
+			}
+		}
+	}()
+	for _, mset := range msets {
+		sr, serr := mset.stageSnapshot(sdir, includeConsumers)
+		if serr != nil {
+			return nil, serr
+		}
+		srs = append(srs, sr)
+	}
+	return srs, nil
+}
+
+// stageSnapshot takes a snapshot of the stream and writes it out to a staging file in sdir,
+// which is removed once the returned reader is closed.
+func (mset *stream) stageSnapshot(sdir string, includeConsumers bool) (*SnapshotResult, error) {
+	sr, err := mset.snapshot(0, false, includeConsumers)
+	if err != nil {
+		return nil, err
+	}
+	defer sr.Reader.Close()
+
+	f, err := os.CreateTemp(sdir, "staged-")
+	if err != nil {
+		return nil, err
+	}
+	staged := &stagedSnapshot{f}
+	if _, err = io.Copy(f, sr.Reader); err == nil {
+		if serr := <-sr.errCh; serr != _EMPTY_ {
+			err = errors.New(serr)
+		}
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		staged.Close()
+		return nil, err
+	}
+	// The snapshot is complete at this point, so no errors are reported anymore.
+	return &SnapshotResult{staged, sr.State, nil}, nil
+}
+
+// stagedSnapshot is a snapshot staged to disk, the file is removed on close.
+type stagedSnapshot struct {
+	*os.File
+}
+
+func (s *stagedSnapshot) Close() error {
+	err := s.File.Close()
+	os.Remove(s.Name())
+	return err
+}
+
 const snapsDir = "__snapshots__"
 
 // RestoreStream will restore a stream from a snapshot.
 func (a *Account) RestoreStream(ncfg *StreamConfig, r io.Reader) (*stream, error) {
+	return a.restoreStream(ncfg, r, nil)
+}
+
+// restoreStream restores a stream from a snapshot. If creset is not nil the
+// consumers in the snapshot are recreated without their saved state.
+func (a *Account) restoreStream(ncfg *StreamConfig, r io.Reader, creset *ConsumerRestoreReset) (*stream, error) {
 	if ncfg == nil {
 		return nil, errors.New("nil config on stream restore")
 	}
@@ -9159,6 +11609,14 @@ func (a *Account) RestoreStream(ncfg *StreamConfig, r io.Reader) (*stream, error
 			// the consumer can reconnect. We will create it as a durable and switch it.
 			cfg.ConsumerConfig.Durable = ofi.Name()
 		}
+		if creset != nil {
+			// Drop the saved state so that we start over from the configured position.
+			if err := os.Remove(filepath.Join(odir, ofi.Name(), consumerState)); err != nil && !os.IsNotExist(err) {
+				mset.stop(true, false)
+				return nil, fmt.Errorf("error restoring consumer [%q]: %v", ofi.Name(), err)
+			}
+			creset.apply(&cfg.ConsumerConfig)
+		}
 		obs, err := mset.addConsumer(&cfg.ConsumerConfig)
 		if err != nil {
 			mset.stop(true, false)
@@ -9306,6 +11764,15 @@ func (mset *stream) setWriteErrLocked(err error) {
 		"err":     err,
 	})
 
+	policy := mset.srv.getOpts().JetStreamIOErrorPolicy
+	mset.wro = policy == IOErrorReadOnly
+	go mset.sendStreamIOErrorAdvisory(err, policy)
+
+	// In read-only mode we keep our leadership and continue to serve reads,
+	// new writes will be rejected.
+	if policy == IOErrorReadOnly {
+		return
+	}
 	// If stream is replicated, put it in observer mode to make sure another server can pick it up.
 	if node := mset.node; node != nil {
 		node.StepDown()
@@ -9313,6 +11780,22 @@ func (mset *stream) setWriteErrLocked(err error) {
 	}
 }
 
+// isReadOnly returns whether a write error made the stream read-only.
+func (mset *stream) isReadOnly() bool {
+	mset.mu.RLock()
+	defer mset.mu.RUnlock()
+	return mset.readOnlyErr() != nil
+}
+
+// readOnlyErr returns the write error if it made the stream read-only.
+// Lock should be held.
+func (mset *stream) readOnlyErr() error {
+	if !mset.wro {
+		return nil
+	}
+	return mset.werr
+}
+
 // getWriteErr returns the write error stored in the stream (if any).
 func (mset *stream) getWriteErr() error {
 	mset.mu.RLock()
@@ -37,8 +37,16 @@ var (
 	leftMappingFunctionRegEx           = regexp.MustCompile(`{{\s*[lL]eft\s*\((.*)\)\s*}}`)
 	rightMappingFunctionRegEx          = regexp.MustCompile(`{{\s*[rR]ight\s*\((.*)\)\s*}}`)
 	randomMappingFunctionRegEx         = regexp.MustCompile(`{{\s*[rR]andom\s*\((.*)\)\s*}}`)
+	headerMappingFunctionRegEx         = regexp.MustCompile(`{{\s*[hH]eader\s*\((.*)\)\s*}}`)
 )
 
+// Default token used by the header mapping function when the header is absent
+// or its value can not be safely used as a subject token.
+const headerMappingDefaultToken = "_"
+
+// Maximum length of a header value that will be used as a subject token.
+const headerMappingMaxValueLen = 256
+
 // Enum for the subject mapping subjectTransform function types
 const (
 	NoTransform int16 = iota
@@ -53,6 +61,7 @@ const (
 	Left
 	Right
 	Random
+	Header
 )
 
 // Transforms for arbitrarily mapping subjects from one to another for maps, tees and filters.
@@ -162,7 +171,7 @@ func NewSubjectTransformWithStrict(src, dest string, strict bool) (*subjectTrans
 	} else {
 		// no wildcards used in the source: check that no transform functions are used in the destination
 		for _, token := range dtokens {
-			tranformType, _, transfomArgInt, _, err := indexPlaceHolders(token)
+			tranformType, _, transfomArgInt, transformArgString, err := indexPlaceHolders(token)
 			if err != nil {
 				return nil, err
 			}
@@ -177,6 +186,11 @@ func NewSubjectTransformWithStrict(src, dest string, strict bool) (*subjectTrans
 				dtokMappingFunctionTokenIndexes = append(dtokMappingFunctionTokenIndexes, []int{})
 				dtokMappingFunctionIntArgs = append(dtokMappingFunctionIntArgs, transfomArgInt)
 				dtokMappingFunctionStringArgs = append(dtokMappingFunctionStringArgs, _EMPTY_)
+			} else if tranformType == Header {
+				dtokMappingFunctionTypes = append(dtokMappingFunctionTypes, Header)
+				dtokMappingFunctionTokenIndexes = append(dtokMappingFunctionTokenIndexes, []int{})
+				dtokMappingFunctionIntArgs = append(dtokMappingFunctionIntArgs, -1)
+				dtokMappingFunctionStringArgs = append(dtokMappingFunctionStringArgs, transformArgString)
 			} else {
 				return nil, &mappingDestinationErr{token, ErrMappingDestinationIndexOutOfRange}
 			}
@@ -367,6 +381,27 @@ func indexPlaceHolders(token string) (int16, []int, int32, string, error) {
 				return Random, []int{}, int32(mappingFunctionIntArg), _EMPTY_, nil
 			}
 
+			// Header(name, default)
+			// The string argument holds the header name and the default token separated by a space,
+			// neither of which can contain spaces themselves.
+			args = getMappingFunctionArgs(headerMappingFunctionRegEx, token)
+			if args != nil {
+				if len(args) < 1 || strings.TrimSpace(args[0]) == _EMPTY_ {
+					return BadTransform, []int{}, -1, _EMPTY_, &mappingDestinationErr{token, ErrMappingDestinationNotEnoughArgs}
+				}
+				if len(args) > 2 {
+					return BadTransform, []int{}, -1, _EMPTY_, &mappingDestinationErr{token, ErrMappingDestinationTooManyArgs}
+				}
+				name, def := strings.TrimSpace(args[0]), headerMappingDefaultToken
+				if len(args) == 2 {
+					def = strings.TrimSpace(args[1])
+				}
+				if strings.ContainsAny(name, " \t:") || !isSafeHeaderMappingToken(def) {
+					return BadTransform, []int{}, -1, _EMPTY_, &mappingDestinationErr{token, ErrMappingDestinationInvalidArg}
+				}
+				return Header, []int{}, -1, name + " " + def, nil
+			}
+
 			return BadTransform, []int{}, -1, _EMPTY_, &mappingDestinationErr{token, ErrUnknownMappingDestinationFunction}
 		}
 	}
@@ -478,8 +513,33 @@ func (tr *subjectTransform) getHashPartition(key []byte, numBuckets int) string
 	return strconv.Itoa(int(h.Sum32() % uint32(numBuckets)))
 }
 
+// isSafeHeaderMappingToken returns true if the value can be used as a single literal
+// subject token, which prevents header values from injecting wildcards or extra tokens.
+func isSafeHeaderMappingToken[T string | []byte](v T) bool {
+	if len(v) == 0 || len(v) > headerMappingMaxValueLen {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; c {
+		case btsep, pwc, fwc, ' ', '\t', '\r', '\n':
+			return false
+		default:
+			if c < 0x20 || c == 0x7f {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // Do a subjectTransform on the subject to the dest subject.
 func (tr *subjectTransform) TransformTokenizedSubject(tokens []string) string {
+	return tr.transformTokenizedSubjectWithHeader(tokens, nil)
+}
+
+// Do a subjectTransform on the subject to the dest subject, using the
+// message headers for any header mapping functions.
+func (tr *subjectTransform) transformTokenizedSubjectWithHeader(tokens []string, hdr []byte) string {
 	if len(tr.dtokmftypes) == 0 {
 		return tr.dest
 	}
@@ -614,6 +674,13 @@ func (tr *subjectTransform) TransformTokenizedSubject(tokens []string) string {
 				}
 			case Random:
 				b.WriteString(tr.getRandomPartition(int(tr.dtokmfintargs[i])))
+			case Header:
+				name, def, _ := strings.Cut(tr.dtokmfstringargs[i], " ")
+				if v := sliceHeader(name, hdr); isSafeHeaderMappingToken(v) {
+					b.Write(v)
+				} else {
+					b.WriteString(def)
+				}
 			}
 		}
 
@@ -259,3 +259,42 @@ func TestSubjectTransformDoesntPanicTransformingMissingToken(t *testing.T) {
 	require_NoError(t, err)
 	require_Equal(t, tr.TransformTokenizedSubject([]string{"foo"}), "one.two.")
 }
+
+func TestSubjectTransformHeaderMapping(t *testing.T) {
+	for _, dest := range []string{"events.{{header()}}.>", "events.{{header(Region, a, b)}}.>", "events.{{header(Re gion)}}.>", "events.{{header(Region, *)}}.>"} {
+		if _, err := NewSubjectTransform("events.>", dest); !errors.Is(err, ErrInvalidMappingDestination) {
+			t.Fatalf("Expected an error for dest=%q, got %v", dest, err)
+		}
+	}
+	// Can not be used for imports since it is not reversible.
+	_, err := NewSubjectTransformStrict("events.*", "events.{{header(Region)}}.$1")
+	require_Error(t, err)
+
+	tr, err := NewSubjectTransform("events.>", "events.{{header(Region)}}.>")
+	require_NoError(t, err)
+	tr2, err := NewSubjectTransform("orders", "orders.{{ Header(Region, unknown) }}")
+	require_NoError(t, err)
+
+	for _, test := range []struct {
+		hdr      string
+		expected string
+	}{
+		{"", "events._.foo.bar"},
+		{"NATS/1.0\r\nRegion: eu\r\n\r\n", "events.eu.foo.bar"},
+		{"NATS/1.0\r\nOther: eu\r\n\r\n", "events._.foo.bar"},
+		{"NATS/1.0\r\nRegion: eu.west\r\n\r\n", "events._.foo.bar"},
+		{"NATS/1.0\r\nRegion: >\r\n\r\n", "events._.foo.bar"},
+		{"NATS/1.0\r\nRegion: *\r\n\r\n", "events._.foo.bar"},
+		{"NATS/1.0\r\nRegion: e u\r\n\r\n", "events._.foo.bar"},
+		{"NATS/1.0\r\nRegion:\r\n\r\n", "events._.foo.bar"},
+	} {
+		var hdr []byte
+		if test.hdr != _EMPTY_ {
+			hdr = []byte(test.hdr)
+		}
+		require_Equal(t, tr.transformTokenizedSubjectWithHeader(tokenizeSubject("events.foo.bar"), hdr), test.expected)
+	}
+
+	require_Equal(t, tr2.TransformTokenizedSubject(nil), "orders.unknown")
+	require_Equal(t, tr2.transformTokenizedSubjectWithHeader(nil, []byte("NATS/1.0\r\nRegion: us\r\n\r\n")), "orders.us")
+}
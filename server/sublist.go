@@ -1288,7 +1288,8 @@ func ValidateMapping(src string, dest string) error {
 				!sliceFromLeftMappingFunctionRegEx.MatchString(t) &&
 				!sliceFromRightMappingFunctionRegEx.MatchString(t) &&
 				!splitMappingFunctionRegEx.MatchString(t) &&
-				!randomMappingFunctionRegEx.MatchString(t) {
+				!randomMappingFunctionRegEx.MatchString(t) &&
+				!headerMappingFunctionRegEx.MatchString(t) {
 				return &mappingDestinationErr{t, ErrUnknownMappingDestinationFunction}
 			} else {
 				continue
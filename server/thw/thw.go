@@ -180,6 +180,29 @@ func (hw *HashWheel) expireTasks(ts int64, callback func(seq uint64, expires int
 	hw.lowest = globalLowest
 }
 
+// Expiring calls the callback for all tasks expiring after from, up to and including to.
+// Only the slots covering that window are visited, so this is cheap for short windows.
+func (hw *HashWheel) Expiring(from, to int64, callback func(seq uint64, expires int64)) {
+	if to <= from || hw.lowest > to {
+		return
+	}
+	start, end := from/tickDuration, to/tickDuration
+	if end-start >= wheelSize {
+		end = start + wheelSize - 1
+	}
+	for tick := start; tick <= end; tick++ {
+		s := hw.wheel[tick&wheelMask]
+		if s == nil || s.lowest > to {
+			continue
+		}
+		for seq, expires := range s.entries {
+			if expires > from && expires <= to {
+				callback(seq, expires)
+			}
+		}
+	}
+}
+
 // GetNextExpiration returns the earliest expiration time before the given time.
 // Returns math.MaxInt64 if no expirations exist before the specified time.
 func (hw *HashWheel) GetNextExpiration(before int64) int64 {
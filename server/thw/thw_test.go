@@ -194,6 +194,48 @@ func TestHashWheelNextExpiration(t *testing.T) {
 	require_Equal(t, empty.GetNextExpiration(now.Add(1*time.Second).UnixNano()), math.MaxInt64)
 }
 
+func TestHashWheelExpiring(t *testing.T) {
+	hw := NewHashWheel()
+	now := time.Now()
+
+	seqs := map[uint64]int64{
+		1: now.Add(time.Second).UnixNano(),
+		2: now.Add(2 * time.Second).UnixNano(),
+		3: now.Add(3 * time.Second).UnixNano(),
+		// Lands in the same slot as seq 1, one full wheel later.
+		4: now.Add(time.Second + wheelSize*time.Duration(tickDuration)).UnixNano(),
+	}
+	for seq, expires := range seqs {
+		require_NoError(t, hw.Add(seq, expires))
+	}
+
+	expiring := func(from, to time.Time) map[uint64]int64 {
+		found := make(map[uint64]int64)
+		hw.Expiring(from.UnixNano(), to.UnixNano(), func(seq uint64, expires int64) {
+			found[seq] = expires
+		})
+		return found
+	}
+
+	found := expiring(now, now.Add(2*time.Second))
+	require_Equal(t, len(found), 2)
+	require_Equal(t, found[1], seqs[1])
+	require_Equal(t, found[2], seqs[2])
+
+	// The start of the window is exclusive, the end inclusive.
+	found = expiring(time.Unix(0, seqs[1]), time.Unix(0, seqs[3]))
+	require_Equal(t, len(found), 2)
+	require_Equal(t, found[2], seqs[2])
+	require_Equal(t, found[3], seqs[3])
+
+	// Nothing expires in an empty or earlier window.
+	require_Equal(t, len(expiring(now, now)), 0)
+	require_Equal(t, len(expiring(now.Add(-time.Hour), now)), 0)
+
+	// Tasks are not removed.
+	require_Equal(t, hw.Count(), uint64(len(seqs)))
+}
+
 func TestHashWheelStress(t *testing.T) {
 	hw := NewHashWheel()
 	now := time.Now()
@@ -374,6 +374,12 @@ func parallelTaskQueue(mp int) chan<- func() {
 	} else {
 		mp = max(rmp, mp)
 	}
+	return fixedTaskQueue(mp)
+}
+
+// fixedTaskQueue is like parallelTaskQueue, but starts exactly mp goroutines
+// even if that is less than GOMAXPROCS. The passed in mp must be > 0.
+func fixedTaskQueue(mp int) chan<- func() {
 	tq := make(chan func(), mp)
 	for range mp {
 		go func() {
@@ -220,6 +220,33 @@ func TestVersionAtLeast(t *testing.T) {
 	}
 }
 
+func TestFixedTaskQueue(t *testing.T) {
+	tq := fixedTaskQueue(2)
+	defer close(tq)
+
+	var mu sync.Mutex
+	var running, maxRunning int
+	var wg sync.WaitGroup
+	wg.Add(20)
+	for range 20 {
+		tq <- func() {
+			defer wg.Done()
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}
+	}
+	wg.Wait()
+	if maxRunning != 2 {
+		t.Fatalf("Expected at most 2 tasks to run concurrently, got %d", maxRunning)
+	}
+}
+
 func BenchmarkParseInt(b *testing.B) {
 	b.SetBytes(1)
 	n := "12345678"